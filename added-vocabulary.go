@@ -2,13 +2,19 @@ package tokenizer
 
 import (
 	"fmt"
+	"hash/fnv"
 	"log"
 	"regexp"
+	"slices"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"unicode"
+	"unicode/utf8"
 
-	"github.com/sugarme/regexpset"
 	"github.com/season-studio/tokenizer/normalizer"
+	"github.com/season-studio/tokenizer/util"
+	"github.com/sugarme/regexpset"
 )
 
 // AddedToken represents a token added by the user on top of the
@@ -123,54 +129,47 @@ func (at AddedToken) SetNormalized(normalized bool) (retVal AddedToken) {
 //
 // NOTE. normalizer input is optional
 func (at AddedToken) GetPattern(n normalizer.Normalizer) (retVal string) {
-	var reStr string // regular expression pattern
+	content := at.matchContent(n)
+	reStr := regexp.QuoteMeta(content) // regular expression pattern
 
-	if at.SingleWord {
+	if at.SingleWord && len(content) > 0 {
 		var firstB, lastB string
-		runes := []rune(at.Content)
-		firstChar := runes[0]
-		lastChar := runes[len(runes)-1]
-		if isWordCharacter(firstChar) {
+		runes := []rune(content)
+		if isWordCharacter(runes[0]) {
 			firstB = `\b`
-		} else {
-			firstB = ``
 		}
-		if isWordCharacter(lastChar) {
+		if isWordCharacter(runes[len(runes)-1]) {
 			lastB = `\b`
-		} else {
-			lastB = ``
-		}
-
-		// normalize the content
-		content := normalizer.NewNormalizedFrom(at.Content)
-		var normalized string
-		if n != nil {
-			normalizedString, err := n.Normalize(content)
-			if err != nil {
-				log.Fatal(err)
-			}
-			normalized = normalizedString.GetNormalized()
-		} else { // don't have a normalizer, just use content as is
-			normalized = at.Content
 		}
 
-		reStr = fmt.Sprintf("%v%v%v", firstB, regexp.QuoteMeta(normalized), lastB)
-
-	} else {
-		reStr = regexp.QuoteMeta(at.Content)
+		reStr = fmt.Sprintf("%v%v%v", firstB, reStr, lastB)
 	}
 
-	if at.LStrip && at.RStrip {
-		reStr = fmt.Sprintf("(\\s)?%v(\\s)?", reStr)
-	} else if at.LStrip {
-		reStr = fmt.Sprintf("(\\s)?%v", reStr)
-	} else if at.RStrip {
-		reStr = fmt.Sprintf("%v(\\s)?", reStr)
+	// lstrip and rstrip take all the whitespaces on their side.
+	if at.LStrip {
+		reStr = `\s*` + reStr
+	}
+	if at.RStrip {
+		reStr = reStr + `\s*`
 	}
 
 	return reStr
 }
 
+// matchContent returns the content matched by the pattern of the token.
+// Normalized tokens match against their normalized content.
+func (at AddedToken) matchContent(n normalizer.Normalizer) string {
+	if !at.Normalized || n == nil {
+		return at.Content
+	}
+
+	normalizedString, err := n.Normalize(normalizer.NewNormalizedFrom(at.Content))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return normalizedString.GetNormalized()
+}
+
 func isWordCharacter(r rune) bool {
 	if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || unicode.IsControl(r) || unicode.IsPunct(r) {
 		return true
@@ -180,8 +179,31 @@ func isWordCharacter(r rune) bool {
 
 // matchingSet is a set of regular expression string
 type matchingSet struct {
-	regexSet regexpset.RegexpSet
-	ids      []int
+	regexSet    regexpset.RegexpSet
+	ids         []int
+	contents    []string // contents matched by the patterns, see `matchRegions`
+	fingerprint uint64   // identifies patterns and ids, used as part of match cache keys
+}
+
+// DefaultMatchCacheCapacity is the default number of segments whose added-token
+// matches are cached by an AddedVocabulary.
+const DefaultMatchCacheCapacity = 256
+
+// maxCachedSegmentLength is the byte length of the longest region whose
+// matches are cached.
+const maxCachedSegmentLength = 256
+
+// matchKey identifies the cached matches of a region.
+type matchKey struct {
+	segment     uint64 // hash of the segment
+	fingerprint uint64 // fingerprint of the matchingSet
+}
+
+// matchEntry holds the cached matches of a region. A copy of the region is kept
+// to guard against hash collisions.
+type matchEntry struct {
+	segment string
+	splits  []idOffsets
 }
 
 // AddedVocabulary is a vocabulary built on top of the Model
@@ -215,10 +237,16 @@ type AddedVocabulary struct {
 	splitRe matchingSet
 	// A struct containing all the normalized patterns used to split on AddedTokens
 	splitNormalizedRe matchingSet
+	// Caches the matches found in the regions of the input around the added token
+	// contents (see `matchRegions`), keyed by region hash and matcher fingerprint.
+	// It is emptied whenever the added tokens change. A nil cache means disabled,
+	// it is swapped atomically by SetMatchCacheCapacity.
+	matchCache *atomic.Pointer[util.LRU[matchKey, matchEntry]]
 }
 
 func NewAddedVocabulary() (retVal AddedVocabulary) {
-	return AddedVocabulary{
+	retVal = AddedVocabulary{
+		matchCache:        new(atomic.Pointer[util.LRU[matchKey, matchEntry]]),
 		addedTokenMap:     make(map[string]int, 0),
 		addedTokenMapR:    make(map[int]string, 0),
 		addedTokens:       []AddedToken{},
@@ -227,6 +255,36 @@ func NewAddedVocabulary() (retVal AddedVocabulary) {
 		splitRe:           matchingSet{},
 		splitNormalizedRe: matchingSet{},
 	}
+	retVal.matchCache.Store(util.NewLRU[matchKey, matchEntry](DefaultMatchCacheCapacity))
+
+	return retVal
+}
+
+// SetMatchCacheCapacity sets the number of regions whose added-token matches
+// are cached. The regions are the added token contents found in the input
+// with the whitespaces around them (i.e. " <|user|> " between turns), up to
+// 256 bytes, and the text between them is known to hold no match. Both the
+// non-normalized and the normalized tokens are found this way, so that
+// sliding-window workloads which re-encode overlapping text reuse their
+// matches. A capacity <= 0 disables the cache. It is safe to call while
+// encoding.
+func (av *AddedVocabulary) SetMatchCacheCapacity(capacity int) {
+	if av.matchCache == nil {
+		av.matchCache = new(atomic.Pointer[util.LRU[matchKey, matchEntry]])
+	}
+	if capacity <= 0 {
+		av.matchCache.Store(nil)
+		return
+	}
+	av.matchCache.Store(util.NewLRU[matchKey, matchEntry](capacity))
+}
+
+// loadMatchCache returns the match cache, nil if disabled.
+func (av *AddedVocabulary) loadMatchCache() *util.LRU[matchKey, matchEntry] {
+	if av.matchCache == nil {
+		return nil
+	}
+	return av.matchCache.Load()
 }
 
 // Len returns size of the additional vocabulary
@@ -270,13 +328,8 @@ func (av *AddedVocabulary) IsSpecialToken(token string) bool {
 // Add some special tokens to the vocabulary
 // It returns number of added tokens
 func (av *AddedVocabulary) AddSpecialTokens(tokens []AddedToken, model Model, normalizer normalizer.Normalizer) (retVal int) {
-
 	for _, tok := range tokens {
-		_, isExist := av.specialTokensSet[tok.Content]
-		if tok.Content != "" && !isExist {
-			av.specialTokens = append(av.specialTokens, tok)
-			av.specialTokensSet[tok.Content] = true
-		}
+		av.addSpecialToken(tok)
 	}
 
 	// Then we delegate to `add_tokens`, that will take care of refreshing added tokens too.
@@ -285,36 +338,142 @@ func (av *AddedVocabulary) AddSpecialTokens(tokens []AddedToken, model Model, no
 
 // Add some tokens to the vocabulary
 // It returns number of added tokens
+//
+// Tokens already in the model vocabulary keep their model id and are not
+// counted, but the input is still split on them.
 func (av *AddedVocabulary) AddTokens(tokens []AddedToken, model Model, normalizer normalizer.Normalizer) (retVal int) {
-
-	ignored := 0
+	added := 0
 	for _, token := range tokens {
-		if token.Content == "" {
-			ignored++
-			continue
+		if av.addToken(token, -1, model) {
+			added++
 		}
+	}
 
-		var id int
-		if i, ok := av.TokenToId(token.Content, model); ok {
-			ignored++
-			id = i
-		} else {
-			id = model.GetVocabSize() + len(av.addedTokenMap)
-			av.addedTokenMap[token.Content] = id
+	av.refreshAddedTokens(model, normalizer)
 
-			if _, ok := av.specialTokensSet[token.Content]; !ok {
-				av.addedTokens = append(av.addedTokens, token)
-			}
+	// return the number of added tokens
+	return added
+}
+
+// AddTokensWithId adds tokens with the ids they were given, i.e. in the
+// `added_tokens` of a `tokenizer.json` file. A token already in the model
+// vocabulary keeps its model id and a token whose id is already taken gets
+// the next free id. It returns the number of added tokens.
+func (av *AddedVocabulary) AddTokensWithId(tokens []AddedTokenWithId, model Model, normalizer normalizer.Normalizer) (retVal int) {
+	added := 0
+	for _, tok := range tokens {
+		if tok.Special {
+			av.addSpecialToken(tok.Token)
+		}
+		if av.addToken(tok.Token, tok.Id, model) {
+			added++
 		}
+	}
+
+	av.refreshAddedTokens(model, normalizer)
+
+	return added
+}
+
+// addSpecialToken marks the token as special.
+func (av *AddedVocabulary) addSpecialToken(token AddedToken) {
+	if _, isExist := av.specialTokensSet[token.Content]; token.Content == "" || isExist {
+		return
+	}
 
+	av.specialTokens = append(av.specialTokens, token)
+	av.specialTokensSet[token.Content] = true
+
+	// A classic token becoming special is only kept once.
+	for i, tok := range av.addedTokens {
+		if tok.Content == token.Content {
+			av.addedTokens = append(av.addedTokens[:i:i], av.addedTokens[i+1:]...)
+			break
+		}
+	}
+}
+
+// addToken adds a single token, with the given id if >= 0 and free. It
+// reports whether the token got a new id.
+func (av *AddedVocabulary) addToken(token AddedToken, id int, model Model) bool {
+	if token.Content == "" {
+		return false
+	}
+
+	_, isSpecial := av.specialTokensSet[token.Content]
+	if !isSpecial && !av.hasAddedToken(token.Content) {
+		av.addedTokens = append(av.addedTokens, token)
+	}
+
+	if i, ok := av.TokenToId(token.Content, model); ok {
 		// Update the current revert operation
+		av.addedTokenMapR[i] = token.Content
+		return false
+	}
+
+	if _, taken := av.IdToToken(id, model); id < 0 || taken {
+		id = av.nextId(model)
+	}
+	av.addedTokenMap[token.Content] = id
+	av.addedTokenMapR[id] = token.Content
+
+	return true
+}
+
+// syncWithModel updates the ids of the added tokens after the vocabulary of
+// the model has been edited. Tokens now in the model vocabulary take their model
+// id and tokens whose id is now taken by a model token get the next free id.
+func (av *AddedVocabulary) syncWithModel(model Model, normalizer normalizer.Normalizer) {
+	tokens := append(av.specialTokens[:len(av.specialTokens):len(av.specialTokens)], av.addedTokens...)
+	ids := av.addedTokenMap
+	av.addedTokenMap = make(map[string]int, len(ids))
+	av.addedTokenMapR = make(map[int]string, len(ids))
+
+	var moved []AddedToken
+	for _, token := range tokens {
+		if id, ok := model.TokenToId(token.Content); ok {
+			av.addedTokenMapR[id] = token.Content
+			continue
+		}
+		id, ok := ids[token.Content]
+		if _, taken := av.IdToToken(id, model); !ok || taken {
+			moved = append(moved, token)
+			continue
+		}
+		av.addedTokenMap[token.Content] = id
+		av.addedTokenMapR[id] = token.Content
+	}
+	for _, token := range moved {
+		id := av.nextId(model)
+		av.addedTokenMap[token.Content] = id
 		av.addedTokenMapR[id] = token.Content
 	}
 
 	av.refreshAddedTokens(model, normalizer)
+}
 
-	// return the number of added tokens
-	return len(tokens) - ignored
+// hasAddedToken reports whether content is one of the classic added tokens.
+func (av *AddedVocabulary) hasAddedToken(content string) bool {
+	for _, tok := range av.addedTokens {
+		if tok.Content == content {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextId returns the id following both the model vocabulary and the added
+// tokens.
+func (av *AddedVocabulary) nextId(model Model) int {
+	id := model.GetVocabSize()
+	for _, i := range av.addedTokenMap {
+		if i >= id {
+			id = i + 1
+		}
+	}
+
+	return id
 }
 
 type tokenId struct {
@@ -329,6 +488,7 @@ type tokenId struct {
 func (av *AddedVocabulary) refreshAddedTokens(model Model, normalizer normalizer.Normalizer) {
 	var normIds, nnormIds []int
 	var normPatterns, nnormPatterns []string
+	var normContents, nnormContents []string
 	tokens := append(av.specialTokens, av.addedTokens...)
 	for _, token := range tokens {
 		id, ok := av.TokenToId(token.Content, model)
@@ -337,12 +497,15 @@ func (av *AddedVocabulary) refreshAddedTokens(model Model, normalizer normalizer
 		}
 
 		pattern := token.GetPattern(normalizer)
+		content := token.matchContent(normalizer)
 		if token.Normalized {
 			normIds = append(normIds, id)
 			normPatterns = append(normPatterns, pattern)
+			normContents = append(normContents, content)
 		} else {
 			nnormIds = append(nnormIds, id)
 			nnormPatterns = append(nnormPatterns, pattern)
+			nnormContents = append(nnormContents, content)
 		}
 	}
 
@@ -355,8 +518,21 @@ func (av *AddedVocabulary) refreshAddedTokens(model Model, normalizer normalizer
 		log.Fatal(err)
 	}
 
-	av.splitNormalizedRe = matchingSet{*normSet, normIds}
-	av.splitRe = matchingSet{*nnormSet, nnormIds}
+	av.splitNormalizedRe = matchingSet{*normSet, normIds, normContents, fingerprint(normPatterns, normIds)}
+	av.splitRe = matchingSet{*nnormSet, nnormIds, nnormContents, fingerprint(nnormPatterns, nnormIds)}
+
+	if cache := av.loadMatchCache(); cache != nil {
+		cache.Clear()
+	}
+}
+
+// fingerprint hashes patterns and their ids of a matchingSet.
+func fingerprint(patterns []string, ids []int) uint64 {
+	h := fnv.New64a()
+	for i, p := range patterns {
+		fmt.Fprintf(h, "%d:%s\x00", ids[i], p)
+	}
+	return h.Sum64()
 }
 
 type idOffsets struct {
@@ -364,22 +540,143 @@ type idOffsets struct {
 	offsets []int
 }
 
-// helper functions to sort idOffsets
-// By implement sort interface of package sort
+// findCachedMatches finds the matches of a sentence as `findMatches`, those of
+// its `matchRegions` being served from the match cache if enabled.
+func (av *AddedVocabulary) findCachedMatches(sentence string, splitRe matchingSet) (retVal []idOffsets) {
+	av.eachCachedMatch(sentence, splitRe, func(id, start, end int) error {
+		retVal = append(retVal, idOffsets{id, []int{start, end}})
+		return nil
+	})
+
+	return retVal
+}
+
+// eachCachedMatch calls fn with the id and offsets of each split of
+// `findCachedMatches`, in order, without building them. It stops at the first
+// error of fn and returns it.
+func (av *AddedVocabulary) eachCachedMatch(sentence string, splitRe matchingSet, fn func(id, start, end int) error) error {
+	var (
+		buf     [8][2]int
+		regions [][2]int
+		ok      bool
+	)
+	cache := av.loadMatchCache()
+	if cache != nil {
+		regions, ok = matchRegions(buf[:0], sentence, splitRe.contents)
+	}
+	if !ok {
+		for _, m := range av.findMatches(sentence, splitRe) {
+			if err := fn(m.id, m.offsets[0], m.offsets[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The unmatched splits of the regions and of the text between them are
+	// joined, as `findMatches` only splits the input on matches.
+	start, n := 0, 0
+	for _, region := range regions {
+		for _, m := range av.regionMatches(cache, sentence[region[0]:region[1]], splitRe) {
+			if m.id == -1 {
+				continue
+			}
+			mStart, mEnd := region[0]+m.offsets[0], region[0]+m.offsets[1]
+			if start < mStart {
+				if err := fn(-1, start, mStart); err != nil {
+					return err
+				}
+				n++
+			}
+			if err := fn(m.id, mStart, mEnd); err != nil {
+				return err
+			}
+			n++
+			start = mEnd
+		}
+	}
+	if start < len(sentence) || n == 0 {
+		return fn(-1, start, len(sentence))
+	}
+
+	return nil
+}
+
+// regionMatches returns the `findMatches` result of region, cached if the
+// region is short enough. It must not be modified.
+func (av *AddedVocabulary) regionMatches(cache *util.LRU[matchKey, matchEntry], region string, splitRe matchingSet) []idOffsets {
+	if len(region) > maxCachedSegmentLength {
+		return av.findMatches(region, splitRe)
+	}
 
-// byStart sort by offset.Start
-type byStart []idOffsets
+	h := fnv.New64a()
+	h.Write([]byte(region))
+	key := matchKey{h.Sum64(), splitRe.fingerprint}
+	if entry, ok := cache.Get(key); ok && entry.segment == region {
+		return entry.splits
+	}
 
-func (s byStart) Len() int           { return len(s) }
-func (s byStart) Less(i, j int) bool { return s[i].offsets[0] < s[j].offsets[0] }
-func (s byStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+	splits := av.findMatches(region, splitRe)
+	cache.Add(key, matchEntry{strings.Clone(region), splits})
 
-// byId sort by id
-type byId []idOffsets
+	return splits
+}
 
-func (bi byId) Len() int           { return len(bi) }
-func (bi byId) Less(i, j int) bool { return bi[i].id < bi[j].id }
-func (bi byId) Swap(i, j int)      { bi[i], bi[j] = bi[j], bi[i] }
+// matchRegions returns the sorted, disjoint byte ranges of sentence holding all
+// the matches of patterns built from contents by `GetPattern`: each occurrence
+// of a content, extended over the whitespaces an lstrip or rstrip pattern
+// takes, and by one more char on each side for the word boundaries of single
+// word patterns. The text outside of them holds no match, and the matches of a
+// region do not depend on the text around it. It is false if a content is
+// empty, as its pattern may match anywhere. The regions are appended to dst.
+func matchRegions(dst [][2]int, sentence string, contents []string) (regions [][2]int, ok bool) {
+	regions = dst
+	for _, content := range contents {
+		if content == "" {
+			return nil, false
+		}
+		for i := 0; i < len(sentence); {
+			j := strings.Index(sentence[i:], content)
+			if j < 0 {
+				break
+			}
+			start, end := i+j, i+j+len(content)
+			for start > 0 && isRegexSpace(sentence[start-1]) {
+				start--
+			}
+			for end < len(sentence) && isRegexSpace(sentence[end]) {
+				end++
+			}
+			if start > 0 {
+				_, size := utf8.DecodeLastRuneInString(sentence[:start])
+				start -= size
+			}
+			if end < len(sentence) {
+				_, size := utf8.DecodeRuneInString(sentence[end:])
+				end += size
+			}
+			regions = append(regions, [2]int{start, end})
+			i += j + 1
+		}
+	}
+
+	slices.SortFunc(regions, func(a, b [2]int) int { return a[0] - b[0] })
+	merged := regions[:0]
+	for _, region := range regions {
+		if n := len(merged); n > 0 && region[0] <= merged[n-1][1] {
+			merged[n-1][1] = max(merged[n-1][1], region[1])
+			continue
+		}
+		merged = append(merged, region)
+	}
+
+	return merged, true
+}
+
+// isRegexSpace reports whether b is matched by `\s` in Go regexps.
+func isRegexSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\f' || b == '\r'
+}
 
 // findMatches finds any AddedToken in the given sentence, using the provided MatchingSet.
 // This method returns a list "splits", each of them being a pair of Offsets
@@ -404,42 +701,45 @@ func (av *AddedVocabulary) findMatches(sentence string, splitRe matchingSet) (re
 	}
 
 	// Sort id-offsets by start then by pattern id
-	sort.Sort(byStart(ioPairs))
-	sort.Sort(byId(ioPairs))
+	sort.Slice(ioPairs, func(i, j int) bool {
+		if ioPairs[i].offsets[0] != ioPairs[j].offsets[0] {
+			return ioPairs[i].offsets[0] < ioPairs[j].offsets[0]
+		}
+		return ioPairs[i].id < ioPairs[j].id
+	})
 
-	// Select the matches, if they overlap, keep them
+	// Select the matches, if they overlap, keep the one with the lowest pattern id
 	var (
-		i              int         = 0
 		currentOffsets int         = 0
 		splits         []idOffsets = make([]idOffsets, 0)
 	)
 
-	for i < len(ioPairs) {
-		ioPair := ioPairs[i]
-
+	for i, ioPair := range ioPairs {
 		// current match is before the current offset, skip it
 		if ioPair.offsets[0] < currentOffsets {
-			i++
 			continue
 		}
 
-		// Find out whether having overlapping neighbours.
-		// If so, keep the one with lowest Idx. All other will be skipped
-		// because `currentOffsets` will have been increased.
-		if i+1 < len(ioPairs) {
-			overlapPairs := ioPairs[i:]
-			sort.Sort(byId(overlapPairs))
-			lowestPair := overlapPairs[0] // lowest Id one
-			splits = append(splits, lowestPair)
-			currentOffsets = ioPair.offsets[1]
-			i++
+		// Among the matches starting before this one ends, keep the one with the
+		// lowest pattern id. All the others are skipped as `currentOffsets` moves
+		// past them.
+		lowest := ioPair
+		for _, next := range ioPairs[i+1:] {
+			if next.offsets[0] >= ioPair.offsets[1] {
+				break
+			}
+			if next.id < lowest.id {
+				lowest = next
+			}
+		}
+
+		// An empty match would never advance.
+		if lowest.offsets[0] == lowest.offsets[1] {
 			continue
 		}
 
-		// Not found overlap neighbours. Just apply itself
-		splits = append(splits, ioPair)
-		currentOffsets = ioPair.offsets[1]
-		i++
+		splits = append(splits, lowest)
+		currentOffsets = lowest.offsets[1]
 	}
 
 	// Also, insert the splits in-between added tokens, to split the entire string
@@ -475,7 +775,7 @@ type SplitIdx struct {
 // NOTE.The list of IDs have the exact same number of elements as the Iterator.
 func (av *AddedVocabulary) splitWithIndices(sentence *normalizer.NormalizedString, splitRe matchingSet) []SplitIdx {
 
-	ioPairs := av.findMatches(sentence.GetNormalized(), splitRe)
+	ioPairs := av.findCachedMatches(sentence.GetNormalized(), splitRe)
 
 	var splits []SplitIdx
 
@@ -1,8 +1,10 @@
 package tokenizer_test
 
 import (
-	// "fmt"
+	"fmt"
+	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/season-studio/tokenizer"
@@ -31,6 +33,10 @@ func (mm ModelMock) Tokenize(sequence string) (retVal []tokenizer.Token, err err
 	return // not implement
 }
 
+func (mm ModelMock) TokenizeWord(word string, offsetsBase int) (retVal []tokenizer.Token, err error) {
+	return // not implement
+}
+
 func (mm ModelMock) IdToToken(id int) (retVal string, ok bool) {
 	retVal, ok = mm.vocabR[id]
 	return
@@ -228,3 +234,230 @@ func TestOptionUseCases(t *testing.T) {
 		t.Errorf("Got %+v\n", got)
 	}
 }
+
+func TestExtractAddedTokens_OrderAndStrip(t *testing.T) {
+	model := newModelMock([]string{"hello"}, []int{0})
+	vocab := tokenizer.NewAddedVocabulary()
+
+	vocab.AddSpecialTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("[CLS]", true),
+		tokenizer.NewAddedToken("<mask>", true, tokenizer.WithLStrip(true)),
+		tokenizer.NewAddedToken("[SEP]", true),
+	}, model, nil)
+	// Tokens of the model vocabulary keep their id but still split the input.
+	got := vocab.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("hello", false)}, model, nil)
+	if got != 0 {
+		t.Errorf("want 0 new tokens, got %v", got)
+	}
+
+	result := vocab.ExtractAndNormalize("[SEP] sayhello   <mask> [CLS]", nil)
+
+	type tokenid struct {
+		token string
+		ids   []int
+	}
+
+	var splits []tokenid
+	for _, pretok := range result.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+		var tokIds []int
+		for _, tok := range pretok.Tokens {
+			tokIds = append(tokIds, tok.Id)
+		}
+		splits = append(splits, tokenid{pretok.Value, tokIds})
+	}
+
+	want := []tokenid{
+		{"[SEP]", []int{3}},
+		{" say", nil},
+		{"hello", []int{0}},
+		// lstrip takes all the whitespaces on the left
+		{"   <mask>", []int{2}},
+		{" ", nil},
+		{"[CLS]", []int{1}},
+	}
+
+	if !reflect.DeepEqual(want, splits) {
+		t.Errorf("Want %+v\n", want)
+		t.Errorf("Got %+v\n", splits)
+	}
+}
+
+func TestAddTokensWithId(t *testing.T) {
+	model := newModelMock([]string{"a", "b"}, []int{0, 1})
+	vocab := tokenizer.NewAddedVocabulary()
+
+	got := vocab.AddTokensWithId([]tokenizer.AddedTokenWithId{
+		{Id: 0, Special: true, Token: tokenizer.NewAddedToken("a", true)},
+		{Id: 5, Special: true, Token: tokenizer.NewAddedToken("<s>", true)},
+		{Id: 3, Special: false, Token: tokenizer.NewAddedToken("<new>", false)},
+		// Already taken ids fall back to the next free id.
+		{Id: 1, Special: false, Token: tokenizer.NewAddedToken("<other>", false)},
+	}, model, nil)
+
+	if got != 3 {
+		t.Errorf("want 3 new tokens, got %v", got)
+	}
+
+	wantIds := map[string]int{"a": 0, "<s>": 5, "<new>": 3, "<other>": 6}
+	for tok, want := range wantIds {
+		if id, ok := vocab.TokenToId(tok, model); !ok || id != want {
+			t.Errorf("%q: want id %v, got %v (%v)", tok, want, id, ok)
+		}
+	}
+
+	if !vocab.IsSpecialToken("a") || !vocab.IsSpecialToken("<s>") || vocab.IsSpecialToken("<new>") {
+		t.Errorf("want only %q and %q special", "a", "<s>")
+	}
+
+	// A classic token becoming special is split on once.
+	vocab.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<new>", true)}, model, nil)
+	result := vocab.ExtractAndNormalize("x<new>y", nil)
+	var ids []int
+	for _, pretok := range result.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+		for _, tok := range pretok.Tokens {
+			ids = append(ids, tok.Id)
+		}
+	}
+	if !reflect.DeepEqual([]int{3}, ids) || !vocab.IsSpecialToken("<new>") {
+		t.Errorf("want special %q with id 3, got %v", "<new>", ids)
+	}
+}
+
+func getAddedTokensTokenizer(cacheCapacity int) *tokenizer.Tokenizer {
+	tk := getOfflineByteLevelBPE()
+	tk.SetAddedTokenCacheCapacity(cacheCapacity)
+	tk.AddSpecialTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("<|user|>", true, tokenizer.WithLStrip(true), tokenizer.WithRStrip(true)),
+		tokenizer.NewAddedToken("<|bot|>", true, tokenizer.WithRStrip(true)),
+		tokenizer.NewAddedToken("[SEP]", true, tokenizer.WithLStrip(true)),
+	})
+	tk.AddTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("hello", false, tokenizer.WithSingleWord(true)),
+	})
+
+	return tk
+}
+
+func TestAddedVocabulary_MatchCache(t *testing.T) {
+	cached := getAddedTokensTokenizer(16)
+	uncached := getAddedTokensTokenizer(0)
+
+	pieces := []string{"<|user|>", " <|bot|> ", " [SEP]", "hello", " hello ", "say hello", "hellos", " ", "  ", "abc", "é🚀", "<|endoftext|>",
+		"\t<|user|>\n", "<|bot|><|bot|>", "x[SEP]y", ",hello.", "é<|user|>é", "\r\n"}
+	rng := rand.New(rand.NewSource(42))
+	for n := 0; n < 500; n++ {
+		var sb strings.Builder
+		for j := rng.Intn(8); j >= 0; j-- {
+			sb.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		input := sb.String()
+
+		// Encode twice so that the second pass is served from the cache.
+		for i := 0; i < 2; i++ {
+			want, err := uncached.EncodeSingle(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := cached.EncodeSingle(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Fatalf("input %q: want %v, got %v", input, want, got)
+			}
+		}
+	}
+}
+
+func TestAddedVocabulary_MatchCacheInvalidation(t *testing.T) {
+	tk := getAddedTokensTokenizer(16)
+
+	input := "hi <|new|> there"
+	before, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<|new|>", true)})
+	after, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := tk.TokenToId("<|new|>")
+	if reflect.DeepEqual(before.Ids, after.Ids) {
+		t.Errorf("want new token id %v in encoding, still got %v", id, after.Ids)
+	}
+}
+
+// BenchmarkAddedVocabulary_SlidingWindow extracts added tokens from windows of
+// special-token delimited turns sliding over a stream, as in sliding-window
+// batching: each window is new, its turns were seen by the previous windows.
+func BenchmarkAddedVocabulary_SlidingWindow(b *testing.B) {
+	turn := func(i int) string {
+		return fmt.Sprintf("<|user|> hello turn %d, how are you doing today? <|bot|> fine [SEP]", i)
+	}
+	const window = 8
+
+	model := newModelMock([]string{"a"}, []int{0})
+	n := normalizer.Lowercase()
+	for _, capacity := range []int{0, tokenizer.DefaultMatchCacheCapacity} {
+		b.Run(fmt.Sprintf("cache=%d", capacity), func(b *testing.B) {
+			vocab := tokenizer.NewAddedVocabulary()
+			vocab.SetMatchCacheCapacity(capacity)
+			vocab.AddSpecialTokens([]tokenizer.AddedToken{
+				tokenizer.NewAddedToken("<|user|>", true, tokenizer.WithLStrip(true), tokenizer.WithRStrip(true)),
+				tokenizer.NewAddedToken("<|bot|>", true, tokenizer.WithRStrip(true)),
+				tokenizer.NewAddedToken("[SEP]", true, tokenizer.WithLStrip(true)),
+			}, model, n)
+			vocab.AddTokens([]tokenizer.AddedToken{
+				tokenizer.NewAddedToken("hello", false, tokenizer.WithSingleWord(true)),
+			}, model, n)
+
+			turns := make([]string, window)
+			for i := range turns {
+				turns[i] = turn(i)
+			}
+
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				vocab.ExtractAndNormalize(strings.Join(turns, ""), n)
+				turns = append(turns[1:], turn(k+window))
+			}
+		})
+	}
+}
+
+// BenchmarkAddedVocabulary_StripSpecialTokens extracts lstrip and rstrip
+// special tokens, which are not normalized, from long turns of a sliding
+// window.
+func BenchmarkAddedVocabulary_StripSpecialTokens(b *testing.B) {
+	turn := func(i int) string {
+		return fmt.Sprintf(" <|user|> %s turn %d <|bot|> %s [SEP] ", strings.Repeat("how are you doing today? ", 8), i, strings.Repeat("fine, thanks. ", 8))
+	}
+	const window = 8
+
+	model := newModelMock([]string{"a"}, []int{0})
+	for _, capacity := range []int{0, tokenizer.DefaultMatchCacheCapacity} {
+		b.Run(fmt.Sprintf("cache=%d", capacity), func(b *testing.B) {
+			vocab := tokenizer.NewAddedVocabulary()
+			vocab.SetMatchCacheCapacity(capacity)
+			vocab.AddSpecialTokens([]tokenizer.AddedToken{
+				tokenizer.NewAddedToken("<|user|>", true, tokenizer.WithLStrip(true), tokenizer.WithRStrip(true)),
+				tokenizer.NewAddedToken("<|bot|>", true, tokenizer.WithLStrip(true), tokenizer.WithRStrip(true)),
+				tokenizer.NewAddedToken("[SEP]", true, tokenizer.WithLStrip(true)),
+			}, model, nil)
+
+			turns := make([]string, window)
+			for i := range turns {
+				turns[i] = turn(i)
+			}
+
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				vocab.ExtractAndNormalize(strings.Join(turns, ""), nil)
+				turns = append(turns[1:], turn(k+window))
+			}
+		})
+	}
+}
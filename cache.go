@@ -0,0 +1,264 @@
+package tokenizer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"sort"
+
+	"github.com/season-studio/tokenizer/util"
+)
+
+// Counter names emitted to a MetricsSink by the encode cache.
+const (
+	MetricEncodeCacheHit  = "encode_cache_hit"
+	MetricEncodeCacheMiss = "encode_cache_miss"
+)
+
+// Cache is the encode-level cache of a Tokenizer (see `Tokenizer.WithCache`).
+// Keys hash the input text, the encode options and a fingerprint of the
+// tokenizer configuration, so that a cache can be shared between processes
+// running the same tokenizer, i.e. backed by Redis. Encodings of models with
+// dropout vary between calls and are not cached.
+//
+// Implementations must be safe for concurrent use. The tokenizer copies
+// encodings before `Put` and after `Get`, so implementations can store and
+// return them as-is and callers can freely modify the encodings they get.
+type Cache interface {
+	Get(key uint64) (Encoding, bool)
+	Put(key uint64, enc Encoding)
+}
+
+// LRUCache is the built-in Cache: a size-capped LRU split into shards to reduce
+// lock contention.
+type LRUCache struct {
+	shards []*util.LRU[uint64, Encoding]
+}
+
+var _ Cache = new(LRUCache)
+
+// DefaultCacheShards is the number of shards of `NewLRUCache`.
+const DefaultCacheShards = 16
+
+// NewLRUCache creates a LRUCache holding at most about `capacity` encodings.
+// An optional number of shards can be given, default to `DefaultCacheShards`.
+func NewLRUCache(capacity int, shardsOpt ...int) *LRUCache {
+	n := DefaultCacheShards
+	if len(shardsOpt) > 0 && shardsOpt[0] > 0 {
+		n = shardsOpt[0]
+	}
+
+	shardCapacity := (capacity + n - 1) / n
+	c := &LRUCache{shards: make([]*util.LRU[uint64, Encoding], n)}
+	for i := range c.shards {
+		c.shards[i] = util.NewLRU[uint64, Encoding](shardCapacity)
+	}
+
+	return c
+}
+
+func (c *LRUCache) shard(key uint64) *util.LRU[uint64, Encoding] {
+	return c.shards[key%uint64(len(c.shards))]
+}
+
+// Get implements Cache.
+func (c *LRUCache) Get(key uint64) (Encoding, bool) {
+	return c.shard(key).Get(key)
+}
+
+// Put implements Cache.
+func (c *LRUCache) Put(key uint64, enc Encoding) {
+	c.shard(key).Add(key, enc)
+}
+
+// Len returns the number of cached encodings.
+func (c *LRUCache) Len() int {
+	var n int
+	for _, s := range c.shards {
+		n += s.Len()
+	}
+
+	return n
+}
+
+// WithCache sets the encode-level cache. A nil cache disables caching (default).
+func (t *Tokenizer) WithCache(cache Cache) {
+	t.configure("WithCache")
+	t.cache = cache
+}
+
+func (t *Tokenizer) GetCache() Cache {
+	return t.cache
+}
+
+// cacheKey hashes the encode input and options with the configuration
+// fingerprint.
+func (t *Tokenizer) cacheKey(input EncodeInput, addSpecialTokens bool, offsetType OffsetType, o *EncodeOpts) uint64 {
+	h := fnv.New64a()
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], t.configFingerprint())
+	h.Write(buf[:])
+	fmt.Fprintf(h, "%v|%v|", addSpecialTokens, offsetType)
+	if o.OverrideTruncation {
+		data, _ := json.Marshal(o.Truncation)
+		fmt.Fprintf(h, "trunc:%s|", data)
+	}
+
+	switch in := input.(type) {
+	case Single:
+		writeSequence(h, in.Sentence)
+	case Dual:
+		writeSequence(h, in.Sentence)
+		writeSequence(h, in.Pair)
+	}
+
+	return h.Sum64()
+}
+
+// writeSequence writes the input type and length prefixed strings of seq to h.
+func writeSequence(h hash.Hash, seq InputSequence) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(seq.inputType))
+	h.Write(buf[:])
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(seq.input)))
+	h.Write(buf[:])
+	for _, s := range seq.input {
+		binary.LittleEndian.PutUint64(buf[:], uint64(len(s)))
+		h.Write(buf[:])
+		h.Write([]byte(s))
+	}
+}
+
+// configFingerprint returns a hash of everything affecting encoding results: the
+// types and exported settings of the pipeline components, the serialized model,
+// the added vocabulary and truncation/padding. It is computed once and reset when
+// the configuration changes through Tokenizer methods.
+func (t *Tokenizer) configFingerprint() uint64 {
+	t.fingerprintMu.Lock()
+	defer t.fingerprintMu.Unlock()
+
+	if t.fingerprint != nil {
+		return *t.fingerprint
+	}
+
+	h := fnv.New64a()
+	for _, c := range []interface{}{t.normalizer, t.preTokenizer, t.postProcessor, t.trunc, t.padding} {
+		fmt.Fprintf(h, "%T|", c)
+		if data, err := json.Marshal(c); err == nil {
+			h.Write(data)
+		}
+	}
+
+	if t.model != nil {
+		fmt.Fprintf(h, "%T|", t.model)
+		// The serialized model holds its whole configuration, i.e. the merges
+		// and unknown token, falling back to its vocab.
+		if data, err := json.Marshal(t.model); err == nil {
+			h.Write(data)
+		} else {
+			vocab := t.model.GetVocab()
+			toks := make([]string, 0, len(vocab))
+			for tok := range vocab {
+				toks = append(toks, tok)
+			}
+			sort.Strings(toks)
+			for _, tok := range toks {
+				fmt.Fprintf(h, "%q:%v,", tok, vocab[tok])
+			}
+		}
+	}
+
+	fmt.Fprintf(h, "|%v|%v", t.addedVocabulary.splitRe.fingerprint, t.addedVocabulary.splitNormalizedRe.fingerprint)
+
+	sum := h.Sum64()
+	t.fingerprint = &sum
+
+	return sum
+}
+
+// resetFingerprint must be called whenever the configuration changes.
+func (t *Tokenizer) resetFingerprint() {
+	t.fingerprintMu.Lock()
+	t.fingerprint = nil
+	t.fingerprintMu.Unlock()
+}
+
+// cachedEncode returns the cached encoding for the input if any, otherwise
+// encodes it with encodeFn and caches the result.
+func (t *Tokenizer) cachedEncode(input EncodeInput, addSpecialTokens bool, offsetType OffsetType, o *EncodeOpts, encodeFn func() (*Encoding, error)) (*Encoding, error) {
+	if t.cache == nil || t.hasModelDropout() {
+		return encodeFn()
+	}
+
+	key := t.cacheKey(input, addSpecialTokens, offsetType, o)
+	if enc, ok := t.cache.Get(key); ok {
+		if t.metrics != nil {
+			t.metrics.Add(MetricEncodeCacheHit, 1)
+		}
+		enc = copyEncoding(enc)
+		return &enc, nil
+	}
+	if t.metrics != nil {
+		t.metrics.Add(MetricEncodeCacheMiss, 1)
+	}
+
+	enc, err := encodeFn()
+	if err != nil {
+		return nil, err
+	}
+	t.cache.Put(key, copyEncoding(*enc))
+
+	return enc, nil
+}
+
+// hasModelDropout returns whether the model skips merges at random, its
+// encodings varying between calls.
+func (t *Tokenizer) hasModelDropout() bool {
+	dm, ok := t.model.(DropoutModel)
+
+	return ok && dm.GetDropout() != 0
+}
+
+// copyEncoding deep copies an Encoding, keeping nil and empty slices as they are.
+func copyEncoding(e Encoding) Encoding {
+	out := e
+	out.Ids = copyInts(e.Ids)
+	out.TypeIds = copyInts(e.TypeIds)
+	if e.Tokens != nil {
+		out.Tokens = append([]string{}, e.Tokens...)
+	}
+	if e.Offsets != nil {
+		out.Offsets = make([][]int, len(e.Offsets))
+		for i, o := range e.Offsets {
+			out.Offsets[i] = copyInts(o)
+		}
+	}
+	out.SpecialTokenMask = copyInts(e.SpecialTokenMask)
+	out.AttentionMask = copyInts(e.AttentionMask)
+	out.Words = copyInts(e.Words)
+	if e.Overflowing != nil {
+		out.Overflowing = make([]Encoding, len(e.Overflowing))
+		for i, o := range e.Overflowing {
+			out.Overflowing[i] = copyEncoding(o)
+		}
+	}
+	if e.SequenceRanges != nil {
+		out.SequenceRanges = make(map[int]Range, len(e.SequenceRanges))
+		for k, r := range e.SequenceRanges {
+			out.SequenceRanges[k] = copyInts(r)
+		}
+	}
+
+	return out
+}
+
+func copyInts(s []int) []int {
+	if s == nil {
+		return nil
+	}
+
+	return append([]int{}, s...)
+}
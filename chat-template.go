@@ -0,0 +1,214 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ChatMessage is a message of a conversation formatted by a chat template.
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatTemplateFunc is a function callable from a chat template, i.e. a
+// `strftime_now` variable.
+type ChatTemplateFunc func(args ...interface{}) (interface{}, error)
+
+// ChatTemplate is a Jinja chat template, as shipped by the `chat_template` entry
+// of `tokenizer_config.json`. It is rendered like transformers does with
+// `trim_blocks` and `lstrip_blocks` enabled.
+//
+// The supported subset of Jinja covers the chat templates of the Hub:
+// expressions with filters and tests, `if`, `for` with `loop` variables,
+// `set` with `namespace()`, `break`/`continue` and whitespace control. Besides
+// `namespace` and `range`, a `raise_exception(message)` function aborts the
+// rendering with the given message. Mappings are iterated in key order.
+type ChatTemplate struct {
+	source string
+	nodes  []jnode
+	vars   map[string]interface{}
+}
+
+// NewChatTemplate parses a Jinja chat template.
+func NewChatTemplate(source string) (*ChatTemplate, error) {
+	nodes, err := parseJinjaTemplate(source)
+	if err != nil {
+		return nil, fmt.Errorf("Parse chat template error: %w", err)
+	}
+
+	return &ChatTemplate{
+		source: source,
+		nodes:  nodes,
+		vars:   make(map[string]interface{}),
+	}, nil
+}
+
+// Source returns the Jinja source of the template.
+func (ct *ChatTemplate) Source() string {
+	return ct.source
+}
+
+// WithVariable sets a variable available to every rendering of the template,
+// i.e. "bos_token". Values are either a ChatTemplateFunc or are converted with
+// their JSON encoding.
+func (ct *ChatTemplate) WithVariable(name string, value interface{}) error {
+	v, err := toChatValue(value)
+	if err != nil {
+		return fmt.Errorf("Chat template variable %q error: %w", name, err)
+	}
+	ct.vars[name] = v
+
+	return nil
+}
+
+// GetVariables returns the variables set with `WithVariable`.
+func (ct *ChatTemplate) GetVariables() map[string]interface{} {
+	vars := make(map[string]interface{}, len(ct.vars))
+	for k, v := range ct.vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// Render renders the template with the given variables on top of the ones set
+// with `WithVariable`. See `WithVariable` for the accepted values.
+func (ct *ChatTemplate) Render(vars map[string]interface{}) (string, error) {
+	converted := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		cv, err := toChatValue(v)
+		if err != nil {
+			return "", fmt.Errorf("Chat template variable %q error: %w", k, err)
+		}
+		converted[k] = cv
+	}
+
+	out, err := renderJinja(ct.nodes, newJcontext(ct.vars, converted))
+	if err != nil {
+		return "", fmt.Errorf("Render chat template error: %w", err)
+	}
+
+	return out, nil
+}
+
+// toChatValue converts a Go value to a template value.
+func toChatValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, bool, int, float64, string:
+		return v, nil
+	case ChatTemplateFunc:
+		return v, nil
+	case func(args ...interface{}) (interface{}, error):
+		return ChatTemplateFunc(v), nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return fromJSONValue(v), nil
+}
+
+// fromJSONValue converts the numbers of a value decoded with `UseNumber` to int
+// or float64.
+func fromJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := strconv.Atoi(v.String()); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = fromJSONValue(item)
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = fromJSONValue(item)
+		}
+	}
+	return value
+}
+
+// ChatOpts are the per-call options of `Tokenizer.ApplyChatTemplate` and
+// `Tokenizer.EncodeChat`, see `DefaultChatOpts`.
+type ChatOpts struct {
+	Variables map[string]interface{} // extra template variables
+}
+
+// ChatOpt sets a per-call option of `Tokenizer.ApplyChatTemplate`.
+type ChatOpt func(o *ChatOpts)
+
+// WithVariableChatOpt sets a template variable for one rendering, i.e.
+// "tools" or "date_string". See `ChatTemplate.WithVariable`.
+func WithVariableChatOpt(name string, value interface{}) ChatOpt {
+	return func(o *ChatOpts) {
+		o.Variables[name] = value
+	}
+}
+
+// DefaultChatOpts returns the options of a call without ChatOpt: no extra
+// template variables.
+func DefaultChatOpts() *ChatOpts {
+	return &ChatOpts{
+		Variables: make(map[string]interface{}),
+	}
+}
+
+// WithChatTemplate sets the chat template used by `ApplyChatTemplate`.
+func (t *Tokenizer) WithChatTemplate(template *ChatTemplate) {
+	t.configure("WithChatTemplate")
+	t.chatTemplate = template
+}
+
+// GetChatTemplate returns the chat template of the tokenizer, nil if it has none.
+func (t *Tokenizer) GetChatTemplate() *ChatTemplate {
+	return t.chatTemplate
+}
+
+// ApplyChatTemplate formats a conversation into a prompt with the chat template
+// of the tokenizer. The template is given the `messages` and
+// `add_generation_prompt` variables. If `addGenerationPrompt` is true, the
+// template ends the prompt with the tokens starting an assistant message.
+func (t *Tokenizer) ApplyChatTemplate(messages []ChatMessage, addGenerationPrompt bool, opts ...ChatOpt) (string, error) {
+	if t.chatTemplate == nil {
+		return "", fmt.Errorf("Apply chat template error: tokenizer has no chat template")
+	}
+
+	o := DefaultChatOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+	vars := make(map[string]interface{}, len(o.Variables)+2)
+	for k, v := range o.Variables {
+		vars[k] = v
+	}
+	if messages == nil {
+		messages = []ChatMessage{}
+	}
+	vars["messages"] = messages
+	vars["add_generation_prompt"] = addGenerationPrompt
+
+	return t.chatTemplate.Render(vars)
+}
+
+// EncodeChat formats a conversation with `ApplyChatTemplate` and encodes the
+// prompt. Special tokens are not added as the template already holds them.
+func (t *Tokenizer) EncodeChat(messages []ChatMessage, addGenerationPrompt bool, opts ...ChatOpt) (*Encoding, error) {
+	prompt, err := t.ApplyChatTemplate(messages, addGenerationPrompt, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.EncodeSingle(prompt, false)
+}
@@ -0,0 +1,220 @@
+package tokenizer_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+)
+
+const (
+	chatMLTemplate = `{% for message in messages %}{{'<|im_start|>' + message['role'] + '\n' + message['content'] + '<|im_end|>' + '\n'}}{% endfor %}{% if add_generation_prompt %}{{ '<|im_start|>assistant\n' }}{% endif %}`
+
+	llama2Template = `{% if messages[0]['role'] == 'system' %}{% set loop_messages = messages[1:] %}{% set system_message = messages[0]['content'] %}{% else %}{% set loop_messages = messages %}{% set system_message = false %}{% endif %}{% for message in loop_messages %}{% if (message['role'] == 'user') != (loop.index0 % 2 == 0) %}{{ raise_exception('Conversation roles must alternate user/assistant/user/assistant/...') }}{% endif %}{% if loop.index0 == 0 and system_message != false %}{% set content = '<<SYS>>\n' + system_message + '\n<</SYS>>\n\n' + message['content'] %}{% else %}{% set content = message['content'] %}{% endif %}{% if message['role'] == 'user' %}{{ bos_token + '[INST] ' + content.strip() + ' [/INST]' }}{% elif message['role'] == 'assistant' %}{{ ' '  + content.strip() + ' ' + eos_token }}{% endif %}{% endfor %}`
+
+	zephyrTemplate = `{% for message in messages %}
+{% if message['role'] == 'user' %}
+{{ '<|user|>\n' + message['content'] + eos_token }}
+{% elif message['role'] == 'assistant' %}
+{{ '<|assistant|>\n'  + message['content'] + eos_token }}
+{% endif %}
+{% if loop.last and add_generation_prompt %}
+{{ '<|assistant|>' }}
+{% endif %}
+{% endfor %}`
+
+	llama3Template = `{{- bos_token }}
+{%- set ns = namespace(system='') %}
+{%- for message in messages if message.role == 'system' %}
+    {%- set ns.system = message.content | trim %}
+{%- endfor %}
+{%- if ns.system %}
+    {{- '<|start_header_id|>system<|end_header_id|>\n\n' + ns.system + '<|eot_id|>' }}
+{%- endif %}
+{%- for message in messages %}
+    {%- if message.role != 'system' %}
+        {{- '<|start_header_id|>' ~ message.role ~ '<|end_header_id|>\n\n' ~ message.content | trim ~ '<|eot_id|>' }}
+    {%- endif %}
+{%- endfor %}
+{%- if add_generation_prompt %}
+    {{- '<|start_header_id|>assistant<|end_header_id|>\n\n' }}
+{%- endif %}
+`
+)
+
+var chatConversation = []tokenizer.ChatMessage{
+	{Role: "system", Content: "You are helpful."},
+	{Role: "user", Content: "Hi"},
+	{Role: "assistant", Content: " Hello! "},
+	{Role: "user", Content: "Bye"},
+}
+
+func newChatTokenizer(t *testing.T, source string) *tokenizer.Tokenizer {
+	t.Helper()
+
+	template, err := tokenizer.NewChatTemplate(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{"bos_token": "<s>", "eos_token": "</s>"} {
+		if err := template.WithVariable(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tk := getOfflineByteLevelBPE()
+	tk.WithChatTemplate(template)
+
+	return tk
+}
+
+func TestApplyChatTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		messages []tokenizer.ChatMessage
+		want     string
+	}{
+		{
+			name:     "chatml",
+			template: chatMLTemplate,
+			messages: chatConversation[1:3],
+			want:     "<|im_start|>user\nHi<|im_end|>\n<|im_start|>assistant\n Hello! <|im_end|>\n<|im_start|>assistant\n",
+		},
+		{
+			name:     "llama2",
+			template: llama2Template,
+			messages: chatConversation,
+			want:     "<s>[INST] <<SYS>>\nYou are helpful.\n<</SYS>>\n\nHi [/INST] Hello! </s><s>[INST] Bye [/INST]",
+		},
+		{
+			name:     "zephyr",
+			template: zephyrTemplate,
+			messages: chatConversation[1:2],
+			want:     "<|user|>\nHi</s>\n<|assistant|>\n",
+		},
+		{
+			name:     "llama3",
+			template: llama3Template,
+			messages: chatConversation[:3],
+			want:     "<s><|start_header_id|>system<|end_header_id|>\n\nYou are helpful.<|eot_id|><|start_header_id|>user<|end_header_id|>\n\nHi<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\nHello!<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tk := newChatTokenizer(t, tt.template)
+		got, err := tk.ApplyChatTemplate(tt.messages, true)
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v: want %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestApplyChatTemplate_Errors(t *testing.T) {
+	tk := newChatTokenizer(t, llama2Template)
+	_, err := tk.ApplyChatTemplate(chatConversation[2:], false)
+	if err == nil || !strings.Contains(err.Error(), "Conversation roles must alternate") {
+		t.Errorf("want raise_exception error, got %v", err)
+	}
+
+	if _, err := getOfflineByteLevelBPE().ApplyChatTemplate(chatConversation, false); err == nil {
+		t.Errorf("want no chat template error, got nil")
+	}
+
+	for _, source := range []string{
+		"{% for m in messages %}{{ m }}",
+		"{% if true %}{% endfor %}",
+		"{{ 'unclosed }}",
+		"{{ messages[ }}",
+		"{% macro m() %}{% endmacro %}",
+	} {
+		if _, err := tokenizer.NewChatTemplate(source); err == nil {
+			t.Errorf("%q: want parse error, got nil", source)
+		}
+	}
+
+	_, err = tokenizer.NewChatTemplate("line 1\n{% if x %}\n{% endfor %}")
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("want error at line 3, got %v", err)
+	}
+}
+
+func TestChatTemplate_Render(t *testing.T) {
+	tests := []struct {
+		source string
+		vars   map[string]interface{}
+		want   string
+	}{
+		{source: "{{ 1 + 2 * 3 }} {{ 7 // 2 }} {{ -7 % 3 }} {{ 1 / 2 }} {{ 2 ** 3 }}", want: "7 3 2 0.5 8"},
+		{source: "{{ 2 ** 62 }} {{ (-2) ** 63 }} {{ (-1) ** 1000001 }} {{ 2 ** -1 }}", want: "4611686018427387904 -9223372036854775808 -1 0.5"},
+		{source: "{{ range(100000)|length }} {{ range(5, -99995, -1)|length }}", want: "100000 100000"},
+		{source: "{{ 'a' ~ 1 ~ none ~ true }} {{ 'ab' * 2 }} {{ [1] + [2] }}", want: "a1NoneTrue abab [1, 2]"},
+		{source: "{{ x.a if x is defined else 'no' }} {{ y is not defined }}", vars: map[string]interface{}{"x": map[string]int{"a": 1}}, want: "1 True"},
+		{source: "{{ 'b' in 'abc' }} {{ 2 not in [1, 2] }} {{ 'k' in {'k': 1} }}", want: "True False True"},
+		{source: "{{ 3 > 2 > 1 }} {{ 1 < 3 > 2 }} {{ 1 < 2 < 2 }} {{ 1 == 1 in [1] }} {{ (3 > 2) > 1 }}", want: "True True False True False"},
+		{source: "{% for x in items %}{{ loop.index }}{{ x }}{% if not loop.last %},{% endif %}{% else %}empty{% endfor %}", vars: map[string]interface{}{"items": []string{"a", "b"}}, want: "1a,2b"},
+		{source: "{% for x in [] %}{{ x }}{% else %}empty{% endfor %}", want: "empty"},
+		{source: "{% for k, v in d.items() %}{{ k }}={{ v }};{% endfor %}", vars: map[string]interface{}{"d": map[string]int{"b": 2, "a": 1}}, want: "a=1;b=2;"},
+		{source: "{% for i in range(10) %}{% if i == 3 %}{% break %}{% endif %}{% if i is odd %}{% continue %}{% endif %}{{ i }}{% endfor %}", want: "02"},
+		{source: "{{ 'abcdef'[1:4] }} {{ [1, 2, 3][::-1] }} {{ [1, 2, 3][-1] }}", want: "bcd [3, 2, 1] 3"},
+		{source: "{{ ' Ab c '|trim|upper }} {{ 'x,y'.split(',')|join('-') }} {{ [1, 2]|length }} {{ 'hello world'|title }}", want: "AB C x-y 2 Hello World"},
+		{source: "{{ {'b': [1, 'x\"'], 'a': none}|tojson }}", want: `{"a": null, "b": [1, "x\""]}`},
+		{source: "{{ missing|default('d') }} {{ ''|default('e', true) }}", want: "d e"},
+		{source: "{% set greeting %}Hi {{ name }}{% endset %}{{ greeting }}!", vars: map[string]interface{}{"name": "Bob"}, want: "Hi Bob!"},
+		{source: "{{ now('%Y') }}", vars: map[string]interface{}{"now": func(args ...interface{}) (interface{}, error) { return "2024", nil }}, want: "2024"},
+		{source: "  {% if true %}\n    yes\n  {% endif %}\nend", want: "    yes\nend"},
+		{source: "a  {{- ' b ' -}}  c {# comment #}\nd", want: "a b c d"},
+	}
+
+	for _, tt := range tests {
+		template, err := tokenizer.NewChatTemplate(tt.source)
+		if err != nil {
+			t.Errorf("%q: %v", tt.source, err)
+			continue
+		}
+		got, err := template.Render(tt.vars)
+		if err != nil {
+			t.Errorf("%q: %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: want %q, got %q", tt.source, tt.want, got)
+		}
+	}
+
+	for _, source := range []string{"{{ x.y.z }}", "{{ 1 + 'a' }}", "{{ 1 // 0 }}", "{{ x|unknown }}", "{% break %}", "{{ 2 ** 100 }}", "{{ range(100001) }}", "{{ range(-9223372036854775807, 9223372036854775807, 2) }}", "{{ 'a' * 100000000000 }}", "{{ 'ab' * 9223372036854775807 }}"} {
+		template, err := tokenizer.NewChatTemplate(source)
+		if err != nil {
+			t.Errorf("%q: %v", source, err)
+			continue
+		}
+		if _, err := template.Render(nil); err == nil {
+			t.Errorf("%q: want render error, got nil", source)
+		}
+	}
+}
+
+func TestEncodeChat(t *testing.T) {
+	tk := getWordLevelBert(t)
+	template, err := tokenizer.NewChatTemplate("[CLS] {% for m in messages %}{{ m.content }} [SEP] {% endfor %}{{ extra }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk.WithChatTemplate(template)
+
+	messages := []tokenizer.ChatMessage{{Role: "user", Content: "a b"}, {Role: "assistant", Content: "c"}}
+	en, err := tk.EncodeChat(messages, false, tokenizer.WithVariableChatOpt("extra", "z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The template holds the special tokens, the post-processor does not add
+	// them again.
+	want := []string{"[CLS]", "a", "b", "[SEP]", "c", "[SEP]", "z"}
+	if !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want %q, got %q", want, en.Tokens)
+	}
+}
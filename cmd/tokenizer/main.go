@@ -0,0 +1,411 @@
+// Command tokenizer encodes, decodes, counts and inspects with a tokenizer from
+// the command line.
+//
+// Usage:
+//
+//	tokenizer <command> [flags] [file ...]
+//
+// The commands are:
+//
+//	encode   encode each input line to ids, tokens and offsets
+//	decode   decode each input line of ids, separated by spaces or commas
+//	count    print the number of tokens of each input line
+//	inspect  describe the tokenizer pipeline and the SHA-256 of its file
+//
+// The tokenizer is loaded with -tokenizer from a `tokenizer.json`, a
+// SentencePiece `.model` or a GGUF `.gguf` file, or with -hub from a Hugging
+// Face Hub model ID. Inputs are read from the given files, or stdin, one line
+// at a time. Results are written as JSON lines (-format json) or as
+// tab-separated values (-format tsv), i.e.
+//
+//	echo "Hello world" | tokenizer encode -hub bert-base-uncased -format tsv
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+const usage = `Usage: tokenizer <command> [flags] [file ...]
+
+Commands:
+  encode   encode each input line to ids, tokens and offsets
+  decode   decode each input line of ids, separated by spaces or commas
+  count    print the number of tokens of each input line
+  inspect  describe the tokenizer pipeline and the SHA-256 of its file
+
+Run 'tokenizer <command> -h' for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// options are the flags shared by all commands.
+type options struct {
+	tokenizer     string
+	hub           string
+	format        string
+	specialTokens bool
+	skipSpecial   bool
+}
+
+// run runs the command line and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	cmd := args[0]
+	commands := map[string]func(*tokenizer.Tokenizer, *options, io.Reader, *bufio.Writer) error{
+		"encode":  encode,
+		"decode":  decode,
+		"count":   count,
+		"inspect": nil,
+	}
+	fn, ok := commands[cmd]
+	if !ok {
+		fmt.Fprintf(stderr, "tokenizer: unknown command %q\n\n%s", cmd, usage)
+		return 2
+	}
+
+	o := &options{}
+	fs := flag.NewFlagSet("tokenizer "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&o.tokenizer, "tokenizer", "", "path of a tokenizer.json, SentencePiece .model or GGUF .gguf file")
+	fs.StringVar(&o.hub, "hub", "", "Hugging Face Hub model ID, i.e. bert-base-uncased")
+	fs.StringVar(&o.format, "format", "json", "output format: json or tsv")
+	if cmd == "encode" || cmd == "count" {
+		fs.BoolVar(&o.specialTokens, "special", true, "add the special tokens of the post-processor")
+	}
+	if cmd == "decode" {
+		fs.BoolVar(&o.skipSpecial, "skip-special", false, "skip special tokens")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if o.format != "json" && o.format != "tsv" {
+		fmt.Fprintf(stderr, "tokenizer: invalid format %q, want json or tsv\n", o.format)
+		return 2
+	}
+
+	tk, sum, err := load(o)
+	if err != nil {
+		fmt.Fprintf(stderr, "tokenizer: %v\n", err)
+		return 1
+	}
+
+	w := bufio.NewWriter(stdout)
+	if cmd == "encode" && o.format == "tsv" {
+		fmt.Fprintln(w, encodeTSVHeader)
+	}
+	if cmd == "inspect" {
+		err = inspect(tk, sum, o, w)
+	} else {
+		err = forEachInput(fs.Args(), stdin, func(r io.Reader) error {
+			return fn(tk, o, r, w)
+		})
+	}
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "tokenizer: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// load loads the tokenizer of the -tokenizer or -hub flag. It also returns the
+// SHA-256 (hex) of the -tokenizer file, "" for -hub.
+func load(o *options) (*tokenizer.Tokenizer, string, error) {
+	switch {
+	case o.tokenizer != "" && o.hub != "":
+		return nil, "", errors.New("-tokenizer and -hub are exclusive")
+	case o.hub != "":
+		tk, err := pretrained.FromHub(o.hub)
+		return tk, "", err
+	case o.tokenizer == "":
+		return nil, "", errors.New("missing -tokenizer or -hub")
+	}
+
+	var (
+		tk  *tokenizer.Tokenizer
+		err error
+	)
+	switch strings.ToLower(filepath.Ext(o.tokenizer)) {
+	case ".gguf":
+		tk, err = pretrained.FromGGUF(o.tokenizer)
+	case ".model":
+		tk, err = pretrained.FromSentencePieceFile(o.tokenizer)
+	default:
+		return pretrained.FromFileWithSHA256(o.tokenizer)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	sum, err := fileSHA256(o.tokenizer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return tk, sum, nil
+}
+
+// fileSHA256 returns the SHA-256 (hex) of a file.
+func fileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// forEachInput calls fn with each file, or stdin if there are none. "-" is
+// stdin too.
+func forEachInput(files []string, stdin io.Reader, fn func(r io.Reader) error) error {
+	if len(files) == 0 {
+		return fn(stdin)
+	}
+
+	for _, file := range files {
+		if file == "-" {
+			if err := fn(stdin); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		err = fn(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%v: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// forEachLine calls fn with each line of r, without its line ending, and its
+// 1-based number.
+func forEachLine(r io.Reader, fn func(line string, n int) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	n := 0
+	for s.Scan() {
+		n++
+		if err := fn(strings.TrimSuffix(s.Text(), "\r"), n); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+
+	return s.Err()
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// tsvEscape escapes the chars which would break a TSV field.
+var tsvEscape = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+const encodeTSVHeader = "line\tindex\tid\ttoken\tstart\tend"
+
+type encodeOutput struct {
+	Ids     []int    `json:"ids"`
+	Tokens  []string `json:"tokens"`
+	Offsets [][]int  `json:"offsets"`
+	TypeIds []int    `json:"type_ids"`
+}
+
+// encode writes the encoding of each line: a JSON object per line, or a TSV
+// row per token with the line number, token index, id, token and offsets (see
+// `encodeTSVHeader`).
+func encode(tk *tokenizer.Tokenizer, o *options, r io.Reader, w *bufio.Writer) error {
+	return forEachLine(r, func(line string, n int) error {
+		en, err := tk.EncodeSingle(line, o.specialTokens)
+		if err != nil {
+			return err
+		}
+
+		if o.format == "json" {
+			return writeJSON(w, encodeOutput{Ids: en.Ids, Tokens: en.Tokens, Offsets: en.Offsets, TypeIds: en.TypeIds})
+		}
+		for i, id := range en.Ids {
+			fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%d\t%d\n", n, i, id, tsvEscape.Replace(en.Tokens[i]), en.Offsets[i][0], en.Offsets[i][1])
+		}
+		return nil
+	})
+}
+
+// decode writes the text of each line of ids, which may be a JSON array.
+func decode(tk *tokenizer.Tokenizer, o *options, r io.Reader, w *bufio.Writer) error {
+	return forEachLine(r, func(line string, n int) error {
+		ids, err := parseIds(line)
+		if err != nil {
+			return err
+		}
+
+		text := tk.Decode(ids, o.skipSpecial)
+		if o.format == "json" {
+			return writeJSON(w, struct {
+				Text string `json:"text"`
+			}{text})
+		}
+		_, err = fmt.Fprintln(w, tsvEscape.Replace(text))
+		return err
+	})
+}
+
+// parseIds parses ids separated by spaces or commas, optionally within
+// brackets.
+func parseIds(line string) ([]int, error) {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	ids := make([]int, len(fields))
+	for i, f := range fields {
+		id, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", f)
+		}
+		ids[i] = id
+	}
+
+	return ids, nil
+}
+
+// count writes the number of tokens of each line.
+func count(tk *tokenizer.Tokenizer, o *options, r io.Reader, w *bufio.Writer) error {
+	return forEachLine(r, func(line string, n int) error {
+		en, err := tk.EncodeSingle(line, o.specialTokens)
+		if err != nil {
+			return err
+		}
+
+		if o.format == "json" {
+			return writeJSON(w, struct {
+				Count int `json:"count"`
+			}{en.Len()})
+		}
+		_, err = fmt.Fprintln(w, en.Len())
+		return err
+	})
+}
+
+type inspectOutput struct {
+	Model           string   `json:"model"`
+	VocabSize       int      `json:"vocab_size"`
+	AddedTokens     int      `json:"added_tokens"`
+	SpecialTokens   []string `json:"special_tokens"`
+	Normalizer      string   `json:"normalizer"`
+	PreTokenizer    string   `json:"pre_tokenizer"`
+	PostProcessor   string   `json:"post_processor"`
+	Decoder         string   `json:"decoder"`
+	Truncation      bool     `json:"truncation"`
+	Padding         bool     `json:"padding"`
+	HasChatTemplate bool     `json:"chat_template"`
+	SHA256          string   `json:"sha256"` // of the -tokenizer file, "" for -hub
+}
+
+// inspect writes the components of the tokenizer pipeline and the SHA-256 sum
+// of its file.
+func inspect(tk *tokenizer.Tokenizer, sum string, o *options, w *bufio.Writer) error {
+	special := tk.GetSpecialTokens()
+	sort.Strings(special)
+	if special == nil {
+		special = []string{}
+	}
+
+	out := inspectOutput{
+		Model:           typeName(tk.GetModel()),
+		VocabSize:       tk.GetVocabSize(false),
+		AddedTokens:     tk.GetVocabSize(true) - tk.GetVocabSize(false),
+		SpecialTokens:   special,
+		Normalizer:      typeName(tk.GetNormalizer()),
+		PreTokenizer:    typeName(tk.GetPreTokenizer()),
+		PostProcessor:   typeName(tk.GetPostProcessor()),
+		Decoder:         typeName(tk.GetDecoder()),
+		Truncation:      tk.GetTruncation() != nil,
+		Padding:         tk.GetPadding() != nil,
+		HasChatTemplate: tk.GetChatTemplate() != nil,
+		SHA256:          sum,
+	}
+	if o.format == "json" {
+		return writeJSON(w, out)
+	}
+
+	fields := [][2]string{
+		{"model", out.Model},
+		{"vocab_size", strconv.Itoa(out.VocabSize)},
+		{"added_tokens", strconv.Itoa(out.AddedTokens)},
+		{"special_tokens", tsvEscape.Replace(strings.Join(out.SpecialTokens, " "))},
+		{"normalizer", out.Normalizer},
+		{"pre_tokenizer", out.PreTokenizer},
+		{"post_processor", out.PostProcessor},
+		{"decoder", out.Decoder},
+		{"truncation", strconv.FormatBool(out.Truncation)},
+		{"padding", strconv.FormatBool(out.Padding)},
+		{"chat_template", strconv.FormatBool(out.HasChatTemplate)},
+		{"sha256", out.SHA256},
+	}
+	for _, f := range fields {
+		fmt.Fprintf(w, "%s\t%s\n", f[0], f[1])
+	}
+
+	return nil
+}
+
+// typeName returns the type name of a pipeline component, "" if nil.
+func typeName(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return ""
+	}
+
+	return strings.TrimPrefix(fmt.Sprintf("%T", v), "*")
+}
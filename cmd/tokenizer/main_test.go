@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model/wordlevel"
+	"github.com/season-studio/tokenizer/pretokenizer"
+	"github.com/season-studio/tokenizer/processor"
+)
+
+// writeTokenizer saves a word level BERT-like tokenizer and returns its path.
+func writeTokenizer(t *testing.T) string {
+	vocab := map[string]int{"[UNK]": 0, "[CLS]": 1, "[SEP]": 2, "hello": 3, "world": 4, "tab\there": 5}
+	wl, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(wl)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+	tk.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 2}, processor.PostToken{Value: "[CLS]", Id: 1}))
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[CLS]", true), tokenizer.NewAddedToken("[SEP]", true)})
+
+	file := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := tk.Save(file, false); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestRun(t *testing.T) {
+	file := writeTokenizer(t)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := fmt.Sprintf("%x", sha256.Sum256(data))
+	input := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(input, []byte("world\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		want  string
+	}{
+		{
+			"encode json", []string{"encode", "-tokenizer", file}, "hello world\nfoo\n",
+			`{"ids":[1,3,4,2],"tokens":["[CLS]","hello","world","[SEP]"],"offsets":[[0,0],[0,5],[6,11],[0,0]],"type_ids":[0,0,0,0]}` + "\n" +
+				`{"ids":[1,0,2],"tokens":["[CLS]","foo","[SEP]"],"offsets":[[0,0],[0,3],[0,0]],"type_ids":[0,0,0]}` + "\n",
+		},
+		{
+			"encode tsv files", []string{"encode", "-tokenizer", file, "-format", "tsv", "-special=false", "-", input}, "hello\r\n",
+			"line\tindex\tid\ttoken\tstart\tend\n1\t0\t3\thello\t0\t5\n1\t0\t4\tworld\t0\t5\n",
+		},
+		{
+			"decode", []string{"decode", "-tokenizer", file, "-skip-special"}, "1 3 4 2\n[3, 5]\n\n",
+			`{"text":"hello world"}` + "\n" + `{"text":"hello tab\there"}` + "\n" + `{"text":""}` + "\n",
+		},
+		{
+			"decode tsv", []string{"decode", "-tokenizer", file, "-format", "tsv"}, "1,3,5\n",
+			"[CLS] hello tab\\there\n",
+		},
+		{
+			"count", []string{"count", "-tokenizer", file, "-format", "tsv"}, "hello world\n\nhello\n",
+			"4\n2\n3\n",
+		},
+		{
+			"inspect", []string{"inspect", "-tokenizer", file, "-format", "tsv"}, "",
+			"model\twordlevel.WordLevel\nvocab_size\t6\nadded_tokens\t0\nspecial_tokens\t[CLS] [SEP]\nnormalizer\t\n" +
+				"pre_tokenizer\tpretokenizer.WhitespaceSplit\npost_processor\tprocessor.BertProcessing\ndecoder\t\n" +
+				"truncation\tfalse\npadding\tfalse\nchat_template\tfalse\nsha256\t" + sum + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr); code != 0 {
+			t.Errorf("%s: want exit code 0, got %d: %s", tt.name, code, stderr.String())
+			continue
+		}
+		if got := stdout.String(); got != tt.want {
+			t.Errorf("%s: want\n%q\ngot\n%q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	file := writeTokenizer(t)
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+		want  string
+	}{
+		{"no command", nil, "", 2, "Usage"},
+		{"unknown command", []string{"split"}, "", 2, `unknown command "split"`},
+		{"format", []string{"encode", "-tokenizer", file, "-format", "xml"}, "", 2, `invalid format "xml"`},
+		{"no tokenizer", []string{"count"}, "", 1, "missing -tokenizer or -hub"},
+		{"missing file", []string{"count", "-tokenizer", filepath.Join(t.TempDir(), "none.json")}, "", 1, "none.json"},
+		{"ids", []string{"decode", "-tokenizer", file}, "1 2\n3 x\n", 1, `line 2: invalid id "x"`},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("%s: want exit code %d, got %d", tt.name, tt.code, code)
+		}
+		if !strings.Contains(stderr.String(), tt.want) {
+			t.Errorf("%s: want error containing %q, got %q", tt.name, tt.want, stderr.String())
+		}
+	}
+}
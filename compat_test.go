@@ -0,0 +1,113 @@
+package tokenizer_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+// compatDir holds the encoding fixtures: one directory per tokenizer with its
+// `tokenizer.json` and its `expected.json` encodings. The expectations are
+// written by hand, not generated by the Python `tokenizers` library, so they
+// only guard against regressions and do not show compatibility with it until
+// they are regenerated, see testdata/compat/README.md.
+const compatDir = "testdata/compat"
+
+// compatCase is an entry of `expected.json`. Offsets are in chars, as in
+// the Python library.
+type compatCase struct {
+	Input             string   `json:"input"`
+	Pair              *string  `json:"pair"`
+	AddSpecialTokens  bool     `json:"add_special_tokens"`
+	Ids               []int    `json:"ids"`
+	Tokens            []string `json:"tokens"`
+	Offsets           [][]int  `json:"offsets"`
+	TypeIds           []int    `json:"type_ids"`
+	AttentionMask     []int    `json:"attention_mask"`
+	SpecialTokensMask []int    `json:"special_tokens_mask"`
+}
+
+// compatKnownFailures are the cases, by fixture and input, which do not match
+// yet, with the reason. They are reported, but do not fail the test until they
+// pass.
+var compatKnownFailures = map[string]string{}
+
+// TestCompat checks the encodings of the fixtures of compatDir.
+func TestCompat(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join(compatDir, "*", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Fatalf("no fixtures in %s", compatDir)
+	}
+
+	for _, file := range dirs {
+		dir := filepath.Dir(file)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			tk, err := pretrained.FromFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, "expected.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cases []compatCase
+			if err := json.Unmarshal(data, &cases); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, c := range cases {
+				input := tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(c.Input))
+				name := c.Input
+				if c.Pair != nil {
+					input = tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence(c.Input), tokenizer.NewInputSequence(*c.Pair))
+					name += " | " + *c.Pair
+				}
+
+				en, err := tk.EncodeCharOffsets(input, c.AddSpecialTokens)
+				if err != nil {
+					t.Errorf("%q: %v", name, err)
+					continue
+				}
+
+				var diffs []string
+				fields := []struct {
+					name      string
+					want, got interface{}
+				}{
+					{"ids", c.Ids, en.Ids},
+					{"tokens", c.Tokens, en.Tokens},
+					{"offsets", c.Offsets, en.Offsets},
+					{"type ids", c.TypeIds, en.TypeIds},
+					{"attention mask", c.AttentionMask, en.AttentionMask},
+					{"special tokens mask", c.SpecialTokensMask, en.SpecialTokenMask},
+				}
+				for _, f := range fields {
+					if !reflect.DeepEqual(f.want, f.got) {
+						diffs = append(diffs, fmt.Sprintf("want %s %v, got %v", f.name, f.want, f.got))
+					}
+				}
+
+				reason, known := compatKnownFailures[filepath.Base(dir)+"/"+name]
+				switch {
+				case known && len(diffs) > 0:
+					t.Logf("%q: known failure (%s): %s", name, reason, strings.Join(diffs, "; "))
+				case known:
+					t.Errorf("%q: passes, remove it from the known failures", name)
+				case len(diffs) > 0:
+					t.Errorf("%q: %s", name, strings.Join(diffs, "; "))
+				}
+			}
+		})
+	}
+}
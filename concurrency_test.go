@@ -0,0 +1,198 @@
+package tokenizer_test
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model/bpe"
+	"github.com/season-studio/tokenizer/normalizer"
+	"github.com/season-studio/tokenizer/pretokenizer"
+	"github.com/season-studio/tokenizer/processor"
+)
+
+// concurrentTokenizers returns tokenizers covering the stateful parts of the
+// pipeline: model caches, dropout, added-token matching, the encode cache,
+// truncation, padding and intra-document parallelism.
+func concurrentTokenizers(t *testing.T) map[string]*tokenizer.Tokenizer {
+	tks := map[string]*tokenizer.Tokenizer{
+		"byte-level": getOfflineByteLevelBPE(),
+		"wordlevel":  getWordLevelBert(t),
+	}
+
+	single, err := processor.NewTemplateFromOne("<s> $A </s>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := processor.NewTemplateFromOne("<s> $A </s> $B:1 </s>:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	specialTokens := processor.NewTokensFrom([]processor.SpecialToken{
+		*processor.NewSpecialTokenFrom("<s>", 1),
+		*processor.NewSpecialTokenFrom("</s>", 2),
+	})
+	uni := getNFKCUnigram(t)
+	uni.WithPreTokenizer(pretokenizer.NewMetaspace("▁", true))
+	uni.WithPostProcessor(processor.NewTemplateProcessing(single, pair, specialTokens))
+	tks["unigram"] = uni
+
+	cached := getOfflineByteLevelBPE()
+	cached.WithPostProcessor(processor.NewRobertaProcessing(processor.PostToken{Value: "</s>", Id: 2}, processor.PostToken{Value: "<s>", Id: 0}, true, false))
+	cached.WithCache(tokenizer.NewLRUCache(16))
+	cached.WithMetricsSink(tokenizer.NewMemoryMetricsSink())
+	tks["cached"] = cached
+
+	padded := getWordLevelBert(t)
+	padded.WithTruncation(&tokenizer.TruncationParams{MaxLength: 12, Strategy: tokenizer.LongestFirst, Stride: 2})
+	padded.WithPadding(&tokenizer.PaddingParams{Strategy: *tokenizer.NewPaddingStrategy(tokenizer.WithFixed(16)), PadToken: "[UNK]"})
+	tks["truncated-padded"] = padded
+
+	parallel := getOfflineByteLevelBPE()
+	parallel.WithIntraDocParallelism(4)
+	parallel.WithNormalizer(normalizer.NewNFC())
+	tks["intra-doc"] = parallel
+
+	vocab := map[string]int{"<unk>": 0, "a": 1, "b": 2, "ab": 3, " ": 4}
+	merges := bpe.Merges{{C1: 1, C2: 2}: {Rank: 0, NewId: 3}}
+	model := bpe.NewBPE(vocab, merges)
+	dropout := float32(0.5)
+	model.Dropout = &dropout
+	unk := "<unk>"
+	model.UnkToken = &unk
+	dropped := tokenizer.NewTokenizer(model)
+	dropped.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+	tks["dropout"] = dropped
+
+	return tks
+}
+
+// TestTokenizer_Concurrent checks that encoding and decoding from many
+// goroutines gives the results of a serial run. Run it with `-race`.
+func TestTokenizer_Concurrent(t *testing.T) {
+	inputs := []string{
+		"hello world",
+		"a b c d e f g h i j",
+		"<custom> héllo<|endoftext|> ab ab",
+		"x",
+		"",
+		genDocument(300),
+	}
+
+	for name, tk := range concurrentTokenizers(t) {
+		type result struct {
+			ids     []int
+			offsets [][]int
+			decoded string
+		}
+		run := func(input, pair string) (result, error) {
+			var (
+				en  *tokenizer.Encoding
+				err error
+			)
+			if pair == "" {
+				en, err = tk.EncodeSingle(input, true)
+			} else {
+				en, err = tk.EncodePair(input, pair, true)
+			}
+			if err != nil {
+				return result{}, err
+			}
+			return result{en.Ids, en.Offsets, tk.Decode(en.Ids, true)}, nil
+		}
+
+		want := make([]result, len(inputs))
+		for i, input := range inputs {
+			res, err := run(input, inputs[(i+1)%len(inputs)])
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+			want[i] = res
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 16)
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for n := 0; n < 10; n++ {
+					i := (g + n) % len(inputs)
+					got, err := run(inputs[i], inputs[(i+1)%len(inputs)])
+					if err != nil {
+						errs <- err
+						return
+					}
+					// Dropout makes the ids vary between runs.
+					if name != "dropout" && !reflect.DeepEqual(want[i], got) {
+						errs <- fmt.Errorf("input %d: want %v, got %v", i, want[i], got)
+						return
+					}
+				}
+
+				batch := make([]tokenizer.EncodeInput, len(inputs))
+				for i, input := range inputs {
+					batch[i] = tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(input))
+				}
+				encodings, err := tk.EncodeBatch(batch, false)
+				if err != nil {
+					errs <- err
+					return
+				}
+				ids := make([][]int, len(encodings))
+				for i := range encodings {
+					ids[i] = encodings[i].Ids
+				}
+				tk.DecodeBatch(ids, false)
+			}(g)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+}
+
+// blockingPreTokenizer blocks pre-tokenization until `release` is closed.
+type blockingPreTokenizer struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingPreTokenizer) PreTokenize(pretok *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
+	close(p.started)
+	<-p.release
+	return pretok, nil
+}
+
+func TestTokenizer_ConfigureWhileEncoding(t *testing.T) {
+	tk := getOfflineByteLevelBPE()
+	pretok := &blockingPreTokenizer{started: make(chan struct{}), release: make(chan struct{})}
+	tk.WithPreTokenizer(pretok)
+
+	done := make(chan error)
+	go func() {
+		_, err := tk.EncodeSingle("hello")
+		done <- err
+	}()
+	<-pretok.started
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("want panic configuring the tokenizer while encoding, got none")
+			}
+		}()
+		tk.WithPadding(nil)
+	}()
+
+	close(pretok.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// Configuring is allowed again once the encoding is done.
+	tk.WithPadding(nil)
+}
@@ -0,0 +1,221 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/season-studio/tokenizer/normalizer"
+)
+
+// TokenCounter is implemented by models which count the tokens of a sequence
+// faster than tokenizing it, without building the tokens. See
+// `Tokenizer.CountTokens`.
+type TokenCounter interface {
+	// CountTokens returns the number of tokens of `Tokenize(sequence)`.
+	CountTokens(sequence string) (int, error)
+}
+
+// StringPreTokenizer is implemented by pre-tokenizers which split a plain
+// string, without tracking the alignments of a PreTokenizedString.
+type StringPreTokenizer interface {
+	// PreTokenizeString appends the pre-tokens of s, as `PreTokenize` would
+	// split it, to dst and returns the extended slice, or false if it cannot.
+	PreTokenizeString(dst []string, s string) ([]string, bool)
+}
+
+// CountTokens returns the number of tokens of the input, i.e. to check that a
+// prompt fits in the context window of a model. It is the length of
+// `EncodeSingle(input, addSpecialTokens)` before truncation and padding, but
+// builds no encoding: offsets, word ids and special tokens are skipped and,
+// if the model is a TokenCounter (as BPE and Unigram), so are its tokens.
+//
+// On the plain string path, it only allocates for the regexp matches of the
+// pre-tokenizer (one per pre-token with `ByteLevel`), the byte-level strings
+// and the words missing from the model cache. It does not allocate without
+// regexp pre-tokenizer once the words are cached.
+//
+// Params:
+// - input: the sequence string to count the tokens of
+// - addSpecialTokensOpt: optional (default = false) whether to count the
+// special tokens added by the post-processor
+func (t *Tokenizer) CountTokens(input string, addSpecialTokensOpt ...bool) (int, error) {
+	t.inUse.Add(1)
+	defer t.inUse.Add(-1)
+
+	if t.model == nil {
+		return 0, fmt.Errorf("Tokenizer.CountTokens() failed: there's no 'Tokenizer Model' setup.")
+	}
+
+	// Invalid UTF-8 bytes are encoded as U+FFFD, see
+	// `normalizer.NewNormalizedFrom`.
+	if !utf8.ValidString(input) {
+		input = string([]rune(input))
+	}
+
+	if count, ok, err := t.countString(input); ok || err != nil {
+		return count + t.countSpecialTokens(addSpecialTokensOpt...), err
+	}
+
+	pretokenized := t.addedVocabulary.ExtractAndNormalize(input, t.normalizer)
+	if t.preTokenizer != nil {
+		var err error
+		pretokenized, err = t.doPreTokenize(pretokenized)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for _, split := range pretokenized.splits {
+		if split.tokens != nil {
+			// Added tokens
+			count += len(split.tokens)
+			continue
+		}
+
+		n, err := t.countWord(split.normalized.GetNormalized())
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+
+	return count + t.countSpecialTokens(addSpecialTokensOpt...), nil
+}
+
+// countString counts the tokens of the input on plain strings, without
+// alignments. It returns false if the normalizer is not a StringNormalizer or
+// the pre-tokenizer is not a StringPreTokenizer.
+func (t *Tokenizer) countString(input string) (int, bool, error) {
+	var sn normalizer.StringNormalizer
+	if t.normalizer != nil {
+		var ok bool
+		if sn, ok = t.normalizer.(normalizer.StringNormalizer); !ok {
+			return 0, false, nil
+		}
+	}
+	var sp StringPreTokenizer
+	if t.preTokenizer != nil {
+		var ok bool
+		if sp, ok = t.preTokenizer.(StringPreTokenizer); !ok {
+			return 0, false, nil
+		}
+	}
+
+	av := &t.addedVocabulary
+	words := preTokensPool.Get().(*[]string)
+	defer func() {
+		clear(*words)
+		*words = (*words)[:0]
+		preTokensPool.Put(words)
+	}()
+
+	count := 0
+	err := av.eachCachedMatch(input, av.splitRe, func(id, start, end int) error {
+		if id != -1 {
+			count++
+			return nil
+		}
+		normalized := input[start:end]
+		if normalized == "" {
+			return nil
+		}
+		if sn != nil {
+			var ok bool
+			if normalized, ok = sn.NormalizeString(normalized); !ok {
+				return errNoStringPath
+			}
+		}
+
+		return av.eachCachedMatch(normalized, av.splitNormalizedRe, func(id, start, end int) error {
+			if id != -1 {
+				count++
+				return nil
+			}
+			split := normalized[start:end]
+			if split == "" {
+				return nil
+			}
+			if sp == nil {
+				n, err := t.countWord(split)
+				count += n
+				return err
+			}
+
+			var ok bool
+			if *words, ok = sp.PreTokenizeString((*words)[:0], split); !ok {
+				return errNoStringPath
+			}
+			for _, word := range *words {
+				n, err := t.countWord(word)
+				if err != nil {
+					return err
+				}
+				count += n
+			}
+			return nil
+		})
+	})
+	switch {
+	case err == errNoStringPath:
+		return 0, false, nil
+	case err != nil:
+		return 0, true, err
+	}
+
+	return count, true, nil
+}
+
+// errNoStringPath stops `countString` when a normalizer or pre-tokenizer
+// cannot process a plain string.
+var errNoStringPath = errors.New("no plain string path")
+
+// preTokensPool holds the pre-token buffers of `countString`.
+var preTokensPool = sync.Pool{
+	New: func() interface{} { return new([]string) },
+}
+
+// countWord counts the tokens of a pre-token.
+func (t *Tokenizer) countWord(word string) (int, error) {
+	if counter, ok := t.model.(TokenCounter); ok {
+		return counter.CountTokens(word)
+	}
+	toks, err := t.model.Tokenize(word)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(toks), nil
+}
+
+// countSpecialTokens returns the number of special tokens added by the
+// post-processor to a single sequence, 0 unless addSpecialTokensOpt is true.
+func (t *Tokenizer) countSpecialTokens(addSpecialTokensOpt ...bool) int {
+	if len(addSpecialTokensOpt) > 0 && addSpecialTokensOpt[0] && t.postProcessor != nil {
+		return t.postProcessor.AddedTokens(false)
+	}
+
+	return 0
+}
+
+// CountTokensBatch counts the tokens of each input concurrently, see
+// `CountTokens`. It returns the error of the first failing input.
+func (t *Tokenizer) CountTokensBatch(inputs []string, addSpecialTokensOpt ...bool) ([]int, error) {
+	counts := make([]int, len(inputs))
+
+	err := t.runBatch(len(inputs), func(i int) error {
+		n, err := t.CountTokens(inputs[i], addSpecialTokensOpt...)
+		if err != nil {
+			return fmt.Errorf("CountTokensBatch error at input %d: %w", i, err)
+		}
+		counts[i] = n
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,124 @@
+package tokenizer_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+func TestCountTokens(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join(compatDir, "*", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range dirs {
+		dir := filepath.Dir(file)
+		tk, err := pretrained.FromFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "expected.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cases []compatCase
+		if err := json.Unmarshal(data, &cases); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, c := range cases {
+			if c.Pair != nil {
+				continue
+			}
+			for _, addSpecialTokens := range []bool{false, true} {
+				en, err := tk.EncodeSingle(c.Input, addSpecialTokens)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := tk.CountTokens(c.Input, addSpecialTokens)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != en.Len() {
+					t.Errorf("%s %q (special tokens %v): want %d tokens, got %d", filepath.Base(dir), c.Input, addSpecialTokens, en.Len(), got)
+				}
+			}
+		}
+	}
+
+	// Added tokens and a model which is not a TokenCounter.
+	tk := getWordLevelBert(t)
+	tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<x>", false)})
+	inputs := []string{"a b<x>c", "", "x y z"}
+	got, err := tk.CountTokensBatch(inputs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []int
+	for _, input := range inputs {
+		en, err := tk.EncodeSingle(input, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, en.Len())
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func BenchmarkCountTokens(b *testing.B) {
+	tk, err := pretrained.FromFile(filepath.Join(compatDir, "gpt2-bytelevel", "tokenizer.json"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	text := strings.Repeat("Hello world! ", 256)
+
+	b.Run("EncodeSingle", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := tk.EncodeSingle(text); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("CountTokens", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := tk.CountTokens(text); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCountTokens_Allocs(t *testing.T) {
+	// Without regex pre-tokenizer, the cached words and added tokens are
+	// counted without allocating.
+	tk := getOfflineByteLevelBPE()
+	tk.WithPreTokenizer(nil)
+	input := "Hello<custom>world<|endoftext|>"
+	want, err := tk.CountTokens(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want != 12 {
+		t.Errorf("want 12 tokens, got %d", want)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if got, err := tk.CountTokens(input); err != nil || got != want {
+			t.Fatalf("want %d tokens, got %d (%v)", want, got, err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("want no allocations, got %v", allocs)
+	}
+}
@@ -0,0 +1,98 @@
+package tokenizer
+
+import (
+	"strings"
+)
+
+// decodeState decodes tokens incrementally: each new token gives the text it
+// adds to the decoded text of the previous ones. As HuggingFace `DecodeStream`
+// does, that text is the difference between the decoded texts of a window of
+// tokens with and without the new ones, so that decoders joining or stripping
+// tokens (i.e. WordPiece "##" and Metaspace "▁") see their context. A text
+// ending with U+FFFD, i.e. an incomplete char of byte-level or byte fallback
+// tokens, is held back until the next tokens complete it.
+type decodeState struct {
+	tokenizer *Tokenizer
+	tokens    []string
+	prefix    int // start of the window of tokens, the context of the new ones
+	read      int // end of the tokens whose text was given
+}
+
+// step adds a token and returns the text it completes, false if none yet.
+func (d *decodeState) step(token string) (string, bool) {
+	d.tokens = append(d.tokens, token)
+
+	return d.next(false)
+}
+
+// flush returns the text of the held back tokens, incomplete chars included,
+// false if none.
+func (d *decodeState) flush() (string, bool) {
+	if d.read == len(d.tokens) {
+		return "", false
+	}
+
+	return d.next(true)
+}
+
+func (d *decodeState) next(flush bool) (string, bool) {
+	prefixText := d.tokenizer.decodeTokens(d.tokens[d.prefix:d.read])
+	newText := d.tokenizer.decodeTokens(d.tokens[d.prefix:])
+	if len(newText) <= len(prefixText) || (!flush && strings.HasSuffix(newText, "�")) {
+		return "", false
+	}
+
+	// The tokens before the new window are not needed anymore.
+	d.tokens = d.tokens[d.read:]
+	d.prefix, d.read = 0, len(d.tokens)
+
+	return newText[len(prefixText):], true
+}
+
+// DecodeWithOffsets decodes the given ids as `Decode` does and returns the
+// [start, end) byte span of the decoded text contributed by each id, i.e. for
+// streaming servers to align text deltas on generated ids. A char made of
+// several byte-level or byte fallback tokens is contributed by the one
+// completing it, the others get empty spans, as do skipped ids. The spans are
+// those of the deltas of a `DecodeStream`.
+//
+// `DecodeOpts.CleanUpTokenizationSpaces` is not applied as it would move text
+// across spans.
+func (t *Tokenizer) DecodeWithOffsets(ids []int, opts ...DecodeOpt) (string, [][]int) {
+	t.inUse.Add(1)
+	defer t.inUse.Add(-1)
+
+	o := DefaultDecodeOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	unkToken := t.unkToken(o)
+	state := &decodeState{tokenizer: t}
+	var (
+		text strings.Builder
+		last = -1 // index of the last decoded id
+	)
+	spans := make([][]int, len(ids))
+	for i, id := range ids {
+		start := text.Len()
+		if tok, ok := t.decodeToken(id, unkToken, o.SkipSpecialTokens); ok {
+			last = i
+			if delta, ok := state.step(tok); ok {
+				text.WriteString(delta)
+			}
+		}
+		spans[i] = []int{start, text.Len()}
+	}
+
+	// Incomplete chars at the end belong to the last decoded id.
+	if delta, ok := state.flush(); ok {
+		text.WriteString(delta)
+		spans[last][1] = text.Len()
+		for _, span := range spans[last+1:] {
+			span[0], span[1] = text.Len(), text.Len()
+		}
+	}
+
+	return text.String(), spans
+}
@@ -0,0 +1,80 @@
+package tokenizer_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/decoder"
+	"github.com/season-studio/tokenizer/model/unigram"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+func TestDecodeWithOffsets(t *testing.T) {
+	byteLevel := getOfflineByteLevelBPE()
+	emoji, err := byteLevel.EncodeSingle("Hi 😁<|endoftext|>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pieces := []unigram.TokenScore{{Token: "<unk>", Score: 0}, {Token: "a", Score: -1}, {Token: "<0xC3>", Score: -5}, {Token: "<0xA9>", Score: -5}}
+	m, err := unigram.New(pieces, unigram.WithUnkID(0), unigram.WithByteFallback(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byteFallback := tokenizer.NewTokenizer(m)
+	byteFallback.WithDecoder(decoder.NewSequence([]tokenizer.Decoder{decoder.NewByteFallback(), decoder.NewFuse()}))
+
+	metaspace, err := pretrained.FromFile(filepath.Join(compatDir, "t5-unigram", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		tk        *tokenizer.Tokenizer
+		ids       []int
+		opts      []tokenizer.DecodeOpt
+		wantText  string
+		wantSpans [][]int
+	}{
+		{
+			// The bytes of the emoji are contributed by its last token.
+			"byte-level", byteLevel, emoji.Ids, nil,
+			"Hi 😁<|endoftext|>",
+			[][]int{{0, 1}, {1, 2}, {2, 3}, {3, 3}, {3, 3}, {3, 3}, {3, 7}, {7, 20}},
+		},
+		{
+			"byte-level skip special tokens", byteLevel, emoji.Ids, []tokenizer.DecodeOpt{tokenizer.WithSkipSpecialTokensDecodeOpt(true)},
+			"Hi 😁",
+			[][]int{{0, 1}, {1, 2}, {2, 3}, {3, 3}, {3, 3}, {3, 3}, {3, 7}, {7, 7}},
+		},
+		{
+			// An incomplete char at the end belongs to the last decoded token.
+			"byte-level incomplete", byteLevel, append(emoji.Ids[:5:5], 1000), nil,
+			"Hi �",
+			[][]int{{0, 1}, {1, 2}, {2, 3}, {3, 3}, {3, 6}, {6, 6}},
+		},
+		{
+			"byte fallback", byteFallback, []int{1, 2, 3, 1}, nil,
+			"aéa",
+			[][]int{{0, 1}, {1, 1}, {1, 3}, {3, 4}},
+		},
+		{
+			"metaspace", metaspace, []int{2, 3, 1}, nil,
+			"hello world</s>",
+			[][]int{{0, 5}, {5, 11}, {11, 15}},
+		},
+	}
+
+	for _, tt := range tests {
+		text, spans := tt.tk.DecodeWithOffsets(tt.ids, tt.opts...)
+		if text != tt.wantText {
+			t.Errorf("%s: want %q, got %q", tt.name, tt.wantText, text)
+		}
+		if !reflect.DeepEqual(tt.wantSpans, spans) {
+			t.Errorf("%s: want spans %v, got %v", tt.name, tt.wantSpans, spans)
+		}
+	}
+}
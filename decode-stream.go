@@ -0,0 +1,67 @@
+package tokenizer
+
+// DecodeStream decodes the ids of a generation loop one at a time. Each step
+// gives the text added by the new id and holds back incomplete chars of
+// byte-level and byte fallback tokens, so that an emoji or a CJK char split
+// over several tokens is given once complete instead of as U+FFFD.
+//
+//	stream := tk.NewDecodeStream(true)
+//	for id := range generated {
+//		if delta, ok := stream.Step(id); ok {
+//			fmt.Print(delta)
+//		}
+//	}
+//	if delta, ok := stream.Flush(); ok {
+//		fmt.Print(delta)
+//	}
+//
+// The concatenated deltas are the `Decode` text of the ids. A DecodeStream is
+// not safe for concurrent use; use one per generation.
+type DecodeStream struct {
+	tokenizer         *Tokenizer
+	skipSpecialTokens bool
+	unkToken          *string
+	state             decodeState
+}
+
+// NewDecodeStream creates a DecodeStream, skipping the special tokens if
+// skipSpecialTokens. As with `Decode`, unknown ids are rendered as the model
+// `unk` token unless `WithDropUnknownDecodeOpt`; spaces are not cleaned up.
+func (t *Tokenizer) NewDecodeStream(skipSpecialTokens bool, opts ...DecodeOpt) *DecodeStream {
+	o := DefaultDecodeOpts()
+	o.SkipSpecialTokens = skipSpecialTokens
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &DecodeStream{
+		tokenizer:         t,
+		skipSpecialTokens: o.SkipSpecialTokens,
+		unkToken:          t.unkToken(o),
+		state:             decodeState{tokenizer: t},
+	}
+}
+
+// Step decodes the next id and returns the text it completes, false if none:
+// the id is skipped, or its text is held back until the next ids complete a
+// char.
+func (s *DecodeStream) Step(id int) (delta string, ok bool) {
+	s.tokenizer.inUse.Add(1)
+	defer s.tokenizer.inUse.Add(-1)
+
+	tok, ok := s.tokenizer.decodeToken(id, s.unkToken, s.skipSpecialTokens)
+	if !ok {
+		return "", false
+	}
+
+	return s.state.step(tok)
+}
+
+// Flush returns the text held back at the end of the ids, incomplete chars
+// given as U+FFFD, false if none.
+func (s *DecodeStream) Flush() (delta string, ok bool) {
+	s.tokenizer.inUse.Add(1)
+	defer s.tokenizer.inUse.Add(-1)
+
+	return s.state.flush()
+}
@@ -0,0 +1,100 @@
+package tokenizer_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+// streamDeltas steps a new DecodeStream over ids and returns the delta of each
+// step, "" if none, followed by the flushed one.
+func streamDeltas(t *testing.T, tk *tokenizer.Tokenizer, ids []int, skipSpecialTokens bool) []string {
+	t.Helper()
+
+	stream := tk.NewDecodeStream(skipSpecialTokens)
+	var deltas []string
+	for _, id := range ids {
+		delta, ok := stream.Step(id)
+		if ok != (delta != "") {
+			t.Errorf("step %d: want ok with a delta, got %v with %q", len(deltas), ok, delta)
+		}
+		deltas = append(deltas, delta)
+	}
+	delta, _ := stream.Flush()
+
+	return append(deltas, delta)
+}
+
+func TestDecodeStream(t *testing.T) {
+	byteLevel := getOfflineByteLevelBPE()
+	en, err := byteLevel.EncodeSingle("Hi 😁<|endoftext|>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The emoji is given once its 4 byte tokens are decoded.
+	got := streamDeltas(t, byteLevel, en.Ids, false)
+	if want := []string{"H", "i", " ", "", "", "", "😁", "<|endoftext|>", ""}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	got = streamDeltas(t, byteLevel, en.Ids, true)
+	if want := []string{"H", "i", " ", "", "", "", "😁", "", ""}; !reflect.DeepEqual(want, got) {
+		t.Errorf("skip special tokens: want %q, got %q", want, got)
+	}
+
+	// An incomplete char is only given by Flush.
+	got = streamDeltas(t, byteLevel, en.Ids[:5], false)
+	if want := []string{"H", "i", " ", "", "", "�"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("incomplete: want %q, got %q", want, got)
+	}
+
+	// Metaspace strips the space of the first token only.
+	metaspace, err := pretrained.FromFile(filepath.Join(compatDir, "t5-unigram", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = streamDeltas(t, metaspace, []int{2, 3, 2, 1}, true)
+	if want := []string{"hello", " world", " hello", "", ""}; !reflect.DeepEqual(want, got) {
+		t.Errorf("metaspace: want %q, got %q", want, got)
+	}
+}
+
+func TestDecodeStream_UnknownId(t *testing.T) {
+	tk := pretrained.BertBaseUncased()
+	id, _ := tk.TokenToId("hello")
+
+	if got, want := streamDeltas(t, tk, []int{id, 1 << 30}, true), []string{"hello", " [UNK]", ""}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	stream := tk.NewDecodeStream(true, tokenizer.WithDropUnknownDecodeOpt(true))
+	if delta, ok := stream.Step(1 << 30); ok {
+		t.Errorf("drop unknown: want no delta, got %q", delta)
+	}
+}
+
+func TestDecodeStream_Decode(t *testing.T) {
+	// The deltas of a long generation add up to its decoded text.
+	byteLevel := getOfflineByteLevelBPE()
+	text := strings.Repeat("Go 日本語 😁👍🏽 <custom> ok? ", 50)
+	en, err := byteLevel.EncodeSingle(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, skip := range []bool{false, true} {
+		deltas := streamDeltas(t, byteLevel, en.Ids, skip)
+		if got, want := strings.Join(deltas, ""), byteLevel.Decode(en.Ids, skip); got != want {
+			t.Errorf("skip %v: want %q, got %q", skip, want, got)
+		}
+		for i, delta := range deltas {
+			if strings.Contains(delta, "�") {
+				t.Errorf("skip %v: step %d: want complete chars, got %q", skip, i, delta)
+			}
+		}
+	}
+}
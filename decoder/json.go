@@ -0,0 +1,85 @@
+package decoder
+
+import (
+	"encoding/json"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/util"
+)
+
+// This file implements json.Marshaler for all decoders so that they are
+// serialized as in a HuggingFace `tokenizer.json` file.
+
+type typeOnly struct {
+	Type string `json:"type"`
+}
+
+var (
+	_ json.Marshaler = new(BpeDecoder)
+	_ json.Marshaler = new(ByteFallback)
+	_ json.Marshaler = new(CTC)
+	_ json.Marshaler = new(Fuse)
+	_ json.Marshaler = new(Sequence)
+	_ json.Marshaler = new(Strip)
+	_ json.Marshaler = new(WordPieceDecoder)
+)
+
+// MarshalJSON implements json.Marshaler.
+func (bd *BpeDecoder) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type   string `json:"type"`
+		Suffix string `json:"suffix"`
+	}{"BPEDecoder", bd.suffix})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d *ByteFallback) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(typeOnly{"ByteFallback"})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c *CTC) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type               string `json:"type"`
+		PadToken           string `json:"pad_token"`
+		WordDelimiterToken string `json:"word_delimiter_token"`
+		Cleanup            bool   `json:"cleanup"`
+	}{"CTC", c.PadToken, c.WordDelimiterToken, c.Cleanup})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (f *Fuse) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(typeOnly{"Fuse"})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Sequence) MarshalJSON() ([]byte, error) {
+	decs := s.decoders
+	if decs == nil {
+		decs = []tokenizer.Decoder{}
+	}
+
+	return util.MarshalJSON(struct {
+		Type     string              `json:"type"`
+		Decoders []tokenizer.Decoder `json:"decoders"`
+	}{"Sequence", decs})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Strip) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type    string `json:"type"`
+		Content string `json:"content"`
+		Start   int    `json:"start"`
+		Stop    int    `json:"stop"`
+	}{"Strip", s.Content, s.Start, s.Stop})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (wd *WordPieceDecoder) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type    string `json:"type"`
+		Prefix  string `json:"prefix"`
+		Cleanup bool   `json:"cleanup"`
+	}{"WordPiece", wd.prefix, wd.cleanup})
+}
@@ -0,0 +1,75 @@
+package tokenizer
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/season-studio/tokenizer/util"
+)
+
+// DropoutModel is implemented by models which can skip merges at random, as
+// BPE-dropout (https://arxiv.org/abs/1910.13267) to augment training data. See
+// `WithDropoutEncodeOpt`.
+type DropoutModel interface {
+	// WithDropout returns a copy of the model skipping each merge with
+	// probability dropout, in [0, 1], drawn from src or from the source of the
+	// model if src is nil. src is safe for concurrent use.
+	WithDropout(dropout float32, src rand.Source) (Model, error)
+	// GetDropout returns the dropout of the model, 0 if none.
+	GetDropout() float32
+}
+
+// WithDropoutEncodeOpt encodes the input with the given merges dropout instead
+// of the model one. The model must be a DropoutModel (i.e. BPE). The random
+// numbers are drawn from the source set by `WithRandSource`, if any, and the
+// encoding is not cached.
+func WithDropoutEncodeOpt(dropout float32) EncodeOpt {
+	return func(o *EncodeOpts) {
+		o.Dropout = dropout
+	}
+}
+
+// WithRandSource sets the source of the random numbers of the dropout set by
+// `WithDropoutEncodeOpt`, i.e. a seeded `rand.NewSource` for reproducible data
+// augmentation. The source is locked as encodings run concurrently; the
+// numbers drawn by each encoding are only reproducible when encoding serially,
+// without `WithIntraDocParallelism`. The source of the model by default.
+func (t *Tokenizer) WithRandSource(src rand.Source) {
+	t.configure("WithRandSource")
+	t.randSource = util.NewLockedSource(src)
+}
+
+// GetRandSource returns the source set by `WithRandSource`, locked for
+// concurrent use, nil if none.
+func (t *Tokenizer) GetRandSource() rand.Source {
+	return t.randSource
+}
+
+// dropoutModel returns the model of the tokenizer with the given dropout.
+func (t *Tokenizer) dropoutModel(dropout float32) (Model, error) {
+	dm, ok := t.model.(DropoutModel)
+	if !ok {
+		return nil, fmt.Errorf("Tokenizer.Encode() failed: model %T does not support dropout", t.model)
+	}
+
+	return dm.WithDropout(dropout, t.randSource)
+}
+
+// EncodeWithDropout encodes a single sequence as `EncodeSingle`, skipping each
+// merge of the model with probability dropout. Encoding the same input again
+// gives another segmentation, as BPE-dropout does for data augmentation.
+//
+// Params:
+// - input: the sequence string to be tokenized
+// - dropout: the probability to skip a merge, in [0, 1]
+// - addSpecialTokensOpt: optional (default = false) whether adding special tokens
+func (t *Tokenizer) EncodeWithDropout(input string, dropout float32, addSpecialTokensOpt ...bool) (*Encoding, error) {
+	addSpecialTokens := false
+	if len(addSpecialTokensOpt) > 0 {
+		addSpecialTokens = addSpecialTokensOpt[0]
+	}
+
+	encodeInput := NewSingleEncodeInput(NewInputSequence(input))
+
+	return t.Encode(encodeInput, addSpecialTokens, WithDropoutEncodeOpt(dropout))
+}
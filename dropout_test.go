@@ -0,0 +1,68 @@
+package tokenizer_test
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+func TestEncodeWithDropout(t *testing.T) {
+	tk, err := pretrained.FromFile(filepath.Join(compatDir, "gpt2-bytelevel", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk.WithCache(tokenizer.NewLRUCache(16))
+	input := "Hello world! Hello world!"
+
+	plain, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropout 1 applies no merges.
+	en, err := tk.EncodeWithDropout(input, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if en.Len() != len(input) {
+		t.Errorf("want %d byte tokens, got %v", len(input), en.Tokens)
+	}
+
+	// A seeded source gives reproducible segmentations.
+	augment := func(seed int64) [][]string {
+		tk.WithRandSource(rand.NewSource(seed))
+		var out [][]string
+		for i := 0; i < 10; i++ {
+			en, err := tk.EncodeWithDropout(input, 0.3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, en.Tokens)
+		}
+		return out
+	}
+	want := augment(7)
+	if got := augment(7); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// Dropout encodings are not cached and leave the model unchanged.
+	got, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plain, got) {
+		t.Errorf("want %+v, got %+v", plain, got)
+	}
+
+	if _, err := tk.EncodeWithDropout(input, 2); err == nil {
+		t.Error("want an error for dropout 2")
+	}
+	if _, err := getWordLevelBert(t).EncodeWithDropout(input, 0.1); err == nil {
+		t.Error("want an error for a model without dropout")
+	}
+}
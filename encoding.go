@@ -3,7 +3,6 @@ package tokenizer
 import (
 	"fmt"
 	"log"
-	"reflect"
 
 	"github.com/season-studio/tokenizer/util"
 )
@@ -26,6 +25,26 @@ type Encoding struct {
 	Overflowing      []Encoding    // A list of overflowing generated when being truncated
 	Words            []int         // Optional - Indexes of the word associated with each token/ID. None value = -1
 	SequenceRanges   map[int]Range // Range of tokens covered by each sequence. If empty -> only one sequence and covers the entire range.
+	SourceForm       SourceForm    // Whether normalization changed the input
+}
+
+// SourceForm tells whether the input of an Encoding went through normalization
+// unchanged, so that consumers needing the exact input bytes only have to store
+// the original text when it was changed.
+type SourceForm int
+
+const (
+	SourceFormUnknown   SourceForm = iota // not tracked, i.e. Encoding built by hand
+	SourceFormPreserved                   // normalized text equals the input
+	SourceFormChanged                     // normalization changed the input
+)
+
+// mergeSourceForm combines source forms of merged encodings: any change wins.
+func mergeSourceForm(a, b SourceForm) SourceForm {
+	if a > b {
+		return a
+	}
+	return b
 }
 
 type EncodingOpts struct {
@@ -70,6 +89,7 @@ func NewEncoding(ids []int, typeIds []int, tokens []string, offsets [][]int, spe
 		overflowing,
 		o.Words,
 		o.SequenceRange,
+		SourceFormUnknown,
 	}
 }
 
@@ -189,25 +209,19 @@ func (e *Encoding) SetSequenceIds(sequenceId int) {
 	}
 }
 
+// GetSequenceIds returns the index of the sequence of each token, as
+// `sequence_ids` in HuggingFace tokenizers. Tokens not part of a sequence (i.e.
+// special tokens added by the post-processor) have sequence index -1.
 func (e *Encoding) GetSequenceIds() []int {
-	sequences := make([]int, e.Len())
-	for seqId := 0; seqId < e.NSequences(); seqId++ {
-		r := e.SequenceRanges[seqId]
-		seqLen := r.Len()
-		var a []int
-		for i := 0; i < seqLen; i++ {
-			a = append(a, seqId)
+	ids := make([]int, e.Len())
+	for i := range ids {
+		ids[i] = -1
+		if seqId, ok := e.TokenToSequence(i); ok {
+			ids[i] = seqId
 		}
-		// replace items in Range r with seqId
-		start := r[0]
-		end := r[len(r)-1]
-		before := sequences[:start]
-		after := sequences[end:]
-		sequences = append(before, a...)
-		sequences = append(sequences, after...)
 	}
 
-	return sequences
+	return ids
 }
 
 // GetIds returns Ids from encoding
@@ -256,74 +270,126 @@ func (e *Encoding) TakeOverflowing() []Encoding {
 	return o
 }
 
-// Word2Tokens gets the encoded tokens corresponding the word
-// at the given index in the input sequence
-// in the form `(startToken, endToken + 1)`
-//
-// NOTE. e.Words is optional, therefore, there's case of `none` result
-// if `none` result, `ok` will be false.
-func (e *Encoding) Word2Tokens(word int) (startTok, endTok int, ok bool) {
+// tokenRange returns the range `[start, end)` of the tokens of the given
+// sequence, all the tokens with offsets if the encoding has no sequence ranges.
+func (e *Encoding) tokenRange(sequenceId int) (start, end int, ok bool) {
+	if len(e.SequenceRanges) == 0 {
+		return 0, len(e.Offsets), sequenceId == 0
+	}
 
-	var start, end int = -1, -1
+	r, ok := e.SequenceRanges[sequenceId]
+	if !ok || len(r) == 0 {
+		return 0, 0, false
+	}
 
-	var inRangeWords []int
-	for _, w := range e.Words {
-		if w <= word {
-			inRangeWords = append(inRangeWords, w)
-		}
+	return r[0], r[len(r)-1] + 1, true
+}
+
+func sequenceIdOf(sequenceIdOpt []int) int {
+	if len(sequenceIdOpt) > 0 {
+		return sequenceIdOpt[0]
 	}
-	for i, w := range inRangeWords {
-		if w == word && start < 0 {
-			start = i
+	return 0
+}
+
+// WordIds returns the word index of each token in its sequence, as `word_ids`
+// in HuggingFace tokenizers. Tokens not part of a sequence (i.e. special tokens
+// added by the post-processor) have word index -1.
+func (e *Encoding) WordIds() []int {
+	ids := make([]int, e.Len())
+	for i := range ids {
+		ids[i] = -1
+		if _, ok := e.TokenToSequence(i); ok && i < len(e.Words) {
+			ids[i] = e.Words[i]
 		}
 	}
 
-	end = len(inRangeWords)
+	return ids
+}
 
-	if start != -1 && end != -1 {
-		return start, end, true
-	} else {
-		return startTok, endTok, false
+// TokenToSequence returns the index of the sequence containing the given
+// token. It is false for tokens out of range or not part of a sequence.
+func (e *Encoding) TokenToSequence(tokenIdx int) (int, bool) {
+	if tokenIdx < 0 || tokenIdx >= len(e.Offsets) {
+		return -1, false
+	}
+	if len(e.SequenceRanges) == 0 {
+		return 0, true
+	}
+
+	for seqId := range e.SequenceRanges {
+		if start, end, ok := e.tokenRange(seqId); ok && tokenIdx >= start && tokenIdx < end {
+			return seqId, true
+		}
 	}
+
+	return -1, false
 }
 
-// Word2Chars get the offsets of the word at a given index in
-// the input sequence
-func (e *Encoding) Word2Chars(word int) (retVal []int, ok bool) {
-	start, end, ok := e.Word2Tokens(word)
-	if end == 0 {
-		return retVal, false
-	} else {
-		oStart := e.Offsets[start][0]
-		oEnd := e.Offsets[end-1][1]
-		return []int{oStart, oEnd}, true // Should we check whether `ok`?
+// TokenToChars returns the offsets of the given token in the input of its
+// sequence, see `TokenToSequence`. It is false for tokens out of range or not
+// part of a sequence.
+func (e *Encoding) TokenToChars(tokenIdx int) ([]int, bool) {
+	if _, ok := e.TokenToSequence(tokenIdx); !ok {
+		return nil, false
 	}
+
+	return e.Offsets[tokenIdx], true
 }
 
-// Token2Chars get the offsets of the token at the given index
-func (e *Encoding) Token2Chars(tokenIdx int) (retVal []int, ok bool) {
-	if tokenIdx < 0 || tokenIdx > len(e.Offsets) {
-		return retVal, false
-	} else {
-		return e.Offsets[tokenIdx], true
+// TokenToWord returns the index of the word containing the given token in the
+// input of its sequence. It is false for tokens not part of a word.
+func (e *Encoding) TokenToWord(tokenIdx int) (int, bool) {
+	if _, ok := e.TokenToSequence(tokenIdx); !ok || tokenIdx >= len(e.Words) || e.Words[tokenIdx] < 0 {
+		return -1, false
 	}
+
+	return e.Words[tokenIdx], true
 }
 
-// Token2Word get the word index of corresponding token if existing
-func (e *Encoding) Token2Word(tokenIdx int) (retVal int, ok bool) {
-	// naive search. TODO. improve algorithm
-	for i, w := range e.Words {
-		if i == tokenIdx {
-			return w, true
+// WordToTokens returns the range `[start, end)` of the tokens of the given word
+// of a sequence, the first one by default.
+func (e *Encoding) WordToTokens(wordIdx int, sequenceIdOpt ...int) (start, end int, ok bool) {
+	first, last, ok := e.tokenRange(sequenceIdOf(sequenceIdOpt))
+	if !ok {
+		return -1, -1, false
+	}
+
+	start, end = -1, -1
+	for i := first; i < last && i < len(e.Words); i++ {
+		if e.Words[i] == wordIdx {
+			if start < 0 {
+				start = i
+			}
+			end = i + 1
 		}
 	}
-	return retVal, false
+
+	return start, end, start >= 0
 }
 
-// Char2Token returns a token index that contains the given `char` index
-func (e *Encoding) Char2Token(pos int) (retVal int, ok bool) {
-	for i, o := range e.Offsets {
-		if pos >= o[0] && pos < o[1] {
+// WordToChars returns the offsets of the given word of a sequence, the first
+// one by default.
+func (e *Encoding) WordToChars(wordIdx int, sequenceIdOpt ...int) ([]int, bool) {
+	start, end, ok := e.WordToTokens(wordIdx, sequenceIdOpt...)
+	if !ok {
+		return nil, false
+	}
+
+	return []int{e.Offsets[start][0], e.Offsets[end-1][1]}, true
+}
+
+// CharToToken returns the index of the token containing the char at the given
+// offset in the input of a sequence, the first one by default. `pos` is in the
+// unit of the offsets, bytes by default (see `OffsetType`).
+func (e *Encoding) CharToToken(pos int, sequenceIdOpt ...int) (int, bool) {
+	start, end, ok := e.tokenRange(sequenceIdOf(sequenceIdOpt))
+	if !ok {
+		return -1, false
+	}
+
+	for i := start; i < end; i++ {
+		if o := e.Offsets[i]; pos >= o[0] && pos < o[1] {
 			return i, true
 		}
 	}
@@ -331,16 +397,66 @@ func (e *Encoding) Char2Token(pos int) (retVal int, ok bool) {
 	return -1, false
 }
 
-// Char2Word get the word index that contain the given `char` index
-func (e *Encoding) Char2Word(pos int) (retVal int, ok bool) {
-	if idx, ok := e.Char2Token(pos); ok {
-		return e.Token2Word(idx)
+// CharToWord returns the index of the word containing the char at the given
+// offset in the input of a sequence, the first one by default.
+func (e *Encoding) CharToWord(pos int, sequenceIdOpt ...int) (int, bool) {
+	if idx, ok := e.CharToToken(pos, sequenceIdOpt...); ok {
+		return e.TokenToWord(idx)
 	}
 
 	return -1, false
 }
 
-// Truncate truncates the current encoding
+// Word2Tokens gets the encoded tokens corresponding the word
+// at the given index in the input sequence
+// in the form `(startToken, endToken + 1)`
+//
+// Deprecated: use WordToTokens.
+func (e *Encoding) Word2Tokens(word int) (startTok, endTok int, ok bool) {
+	return e.WordToTokens(word)
+}
+
+// Word2Chars get the offsets of the word at a given index in
+// the input sequence
+//
+// Deprecated: use WordToChars.
+func (e *Encoding) Word2Chars(word int) (retVal []int, ok bool) {
+	return e.WordToChars(word)
+}
+
+// Token2Chars get the offsets of the token at the given index
+//
+// Deprecated: use TokenToChars.
+func (e *Encoding) Token2Chars(tokenIdx int) (retVal []int, ok bool) {
+	return e.TokenToChars(tokenIdx)
+}
+
+// Token2Word get the word index of corresponding token if existing
+//
+// Deprecated: use TokenToWord.
+func (e *Encoding) Token2Word(tokenIdx int) (retVal int, ok bool) {
+	return e.TokenToWord(tokenIdx)
+}
+
+// Char2Token returns a token index that contains the given `char` index
+//
+// Deprecated: use CharToToken.
+func (e *Encoding) Char2Token(pos int) (retVal int, ok bool) {
+	return e.CharToToken(pos)
+}
+
+// Char2Word get the word index that contain the given `char` index
+//
+// Deprecated: use CharToWord.
+func (e *Encoding) Char2Word(pos int) (retVal int, ok bool) {
+	return e.CharToWord(pos)
+}
+
+// Truncate truncates the current encoding to its first `maxLen` tokens. The
+// removed tokens are split into the `Overflowing` encodings of at most `maxLen`
+// tokens, each repeating the last `stride` tokens of the previous one, i.e. the
+// windows of a sliding-window chunking. The overflowing encodings own their
+// slices and keep the part of the sequence ranges they cover.
 func (e *Encoding) Truncate(maxLen int, stride int) (retVal *Encoding, err error) {
 
 	if stride >= maxLen || maxLen == 0 {
@@ -352,60 +468,70 @@ func (e *Encoding) Truncate(maxLen int, stride int) (retVal *Encoding, err error
 		return e, nil
 	}
 
-	// Truncating at maxLen (exclusive) to keep.
-	// The rest (overflowing) from maxLen (inclusive)
-	newIds := e.Ids[0:maxLen]
-	oIds := e.Ids[maxLen:len(e.Ids)] // overflowing
-	newTypeIds := e.TypeIds[0:maxLen]
-	oTypeIds := e.TypeIds[maxLen:len(e.TypeIds)]
-	newTokens := e.Tokens[0:maxLen]
-	oTokens := e.Tokens[maxLen:len(e.Tokens)]
-	newOffsets := e.Offsets[0:maxLen]
-	oOffsets := e.Offsets[maxLen:len(e.Offsets)]
-	newSpeToks := e.SpecialTokenMask[0:maxLen]
-	oSpeToks := e.SpecialTokenMask[maxLen:len(e.SpecialTokenMask)]
-	newAttent := e.AttentionMask[0:maxLen]
-	oAttent := e.AttentionMask[maxLen:len(e.AttentionMask)]
-	newWords := e.Words[0:maxLen]
-	oWords := e.Words[maxLen:len(e.Words)]
-
-	e.Ids = newIds
-	e.TypeIds = newTypeIds
-	e.Tokens = newTokens
-	e.Offsets = newOffsets
-	e.SpecialTokenMask = newSpeToks
-	e.AttentionMask = newAttent
-	e.Words = newWords
-
-	// Separate the overflowing part into as many Encoding as needed
+	// Separate the overflowing part into as many Encoding as needed: each part
+	// starts `stride` tokens before the end of the previous one.
 	partSize := maxLen - stride
 	overflowing := make([]Encoding, 0)
-	partId := 0
-	prevEncoding := e
-
-	// while loop
-	for int(partSize)*partId < len(oIds) {
-		o := Encoding{
-			Ids:              reflect.ValueOf(getCurrentPart(prevEncoding.Ids, oIds, partSize, partId, stride)).Interface().([]int),
-			TypeIds:          reflect.ValueOf(getCurrentPart(prevEncoding.TypeIds, oTypeIds, partSize, partId, stride)).Interface().([]int),
-			Tokens:           reflect.ValueOf(getCurrentPart(prevEncoding.Tokens, oTokens, partSize, partId, stride)).Interface().([]string),
-			Offsets:          reflect.ValueOf(getCurrentPart(prevEncoding.Offsets, oOffsets, partSize, partId, stride)).Interface().([][]int),
-			SpecialTokenMask: reflect.ValueOf(getCurrentPart(prevEncoding.SpecialTokenMask, oSpeToks, partSize, partId, stride)).Interface().([]int),
-			AttentionMask:    reflect.ValueOf(getCurrentPart(prevEncoding.AttentionMask, oAttent, partSize, partId, stride)).Interface().([]int),
-			Words:            reflect.ValueOf(getCurrentPart(prevEncoding.Words, oWords, partSize, partId, stride)).Interface().([]int),
-			Overflowing:      make([]Encoding, 0),
+	for start := maxLen - stride; start+stride < len(e.Ids); start += partSize {
+		end := start + maxLen
+		if end > len(e.Ids) {
+			end = len(e.Ids)
 		}
-
-		partId += 1
-		overflowing = append(overflowing, o)
-		prevEncoding = &overflowing[len(overflowing)-1]
+		overflowing = append(overflowing, e.slice(start, end))
 	}
 
+	*e = e.slice(0, maxLen)
 	e.Overflowing = overflowing
 
 	return e, nil
 }
 
+// slice returns a copy of the tokens [start, end) of e, without overflowing
+// encodings. Sequence ranges are clipped to the slice.
+func (e *Encoding) slice(start, end int) Encoding {
+	part := Encoding{
+		Ids:              sliceCopy(e.Ids, start, end),
+		TypeIds:          sliceCopy(e.TypeIds, start, end),
+		Tokens:           sliceCopy(e.Tokens, start, end),
+		SpecialTokenMask: sliceCopy(e.SpecialTokenMask, start, end),
+		AttentionMask:    sliceCopy(e.AttentionMask, start, end),
+		Words:            sliceCopy(e.Words, start, end),
+		Overflowing:      make([]Encoding, 0),
+		SourceForm:       e.SourceForm,
+	}
+	if len(e.Offsets) >= end {
+		part.Offsets = make([][]int, 0, end-start)
+		for _, o := range e.Offsets[start:end] {
+			part.Offsets = append(part.Offsets, append([]int(nil), o...))
+		}
+	}
+
+	if len(e.SequenceRanges) > 0 {
+		part.SequenceRanges = make(map[int]Range)
+		for seqId, r := range e.SequenceRanges {
+			if len(r) == 0 {
+				continue
+			}
+			rStart, rEnd := max(r[0], start), min(r[len(r)-1]+1, end)
+			if rStart < rEnd {
+				part.SequenceRanges[seqId] = NewRange(rStart-start, rEnd-start)
+			}
+		}
+	}
+
+	return part
+}
+
+// sliceCopy returns a copy of vals[start:end], nil if vals does not hold it
+// (i.e. the optional `Words` of an encoding built by hand).
+func sliceCopy[T any](vals []T, start, end int) []T {
+	if len(vals) < end {
+		return nil
+	}
+
+	return append(make([]T, 0, end-start), vals[start:end]...)
+}
+
 // Merge merges all Encodings together
 func (e *Encoding) Merge(encodings []Encoding, growingOffsets bool) (retVal *Encoding) {
 	retVal = e
@@ -454,16 +580,16 @@ func (e *Encoding) MergeWith(pair *Encoding, growingOffsets bool) (retVal *Encod
 	e.Overflowing = overflowings
 
 	// Merging others
+	// As in HuggingFace, the range of a sequence of the pair replaces ours.
 	originalLen := e.Len()
-	if len(pair.SequenceRanges) > 0 {
-		for seqId, r := range pair.SequenceRanges {
-			start := originalLen + r[0]
-			end := originalLen + r[r.Len()-1] + 1
-			newRange := NewRange(start, end)
-			var oldRange Range
-			util.DeepCopy(e.SequenceRanges[seqId], oldRange)
-			e.SequenceRanges[seqId] = util.Merge(oldRange, newRange)
+	if len(pair.SequenceRanges) > 0 && e.SequenceRanges == nil {
+		e.SequenceRanges = make(map[int]Range)
+	}
+	for seqId, r := range pair.SequenceRanges {
+		if len(r) == 0 {
+			continue
 		}
+		e.SequenceRanges[seqId] = NewRange(originalLen+r[0], originalLen+r[len(r)-1]+1)
 	}
 
 	e.Ids = util.Merge(e.Ids, pair.Ids)
@@ -472,10 +598,13 @@ func (e *Encoding) MergeWith(pair *Encoding, growingOffsets bool) (retVal *Encod
 	e.TypeIds = util.Merge(e.TypeIds, pair.TypeIds)
 	e.SpecialTokenMask = util.Merge(e.SpecialTokenMask, pair.SpecialTokenMask)
 	e.AttentionMask = util.Merge(e.AttentionMask, pair.AttentionMask)
+	e.SourceForm = mergeSourceForm(e.SourceForm, pair.SourceForm)
 
 	// Offsets
 	var startingOffset int = 0
-	offsets := e.Offsets
+	// A new slice, as e.Offsets may share its array with other encodings.
+	offsets := make([][]int, len(e.Offsets), len(e.Offsets)+len(pair.Offsets))
+	copy(offsets, e.Offsets)
 	if growingOffsets {
 		if len(offsets) > 0 {
 			last := offsets[len(offsets)-1]
@@ -495,6 +624,73 @@ func (e *Encoding) MergeWith(pair *Encoding, growingOffsets bool) (retVal *Encod
 	return e
 }
 
+// Append appends the tokens of other to e as the continuation of the same
+// sequences, i.e. to stitch the paragraphs of a document encoded separately
+// before chunking it with `Truncate` and `Pad`. Unlike `MergeWith`:
+//   - the offsets of the non-special tokens of other are shifted by
+//     `offsetsBase`, the position of its text in the document,
+//   - its word ids follow the ones of e,
+//   - the sequence ranges of e are extended to the tokens of other.
+//
+// The overflowing encodings of other are dropped. It returns e.
+func (e *Encoding) Append(other *Encoding, offsetsBase int) *Encoding {
+	originalLen := e.Len()
+
+	wordsBase := 0
+	for _, w := range e.Words {
+		if w >= wordsBase {
+			wordsBase = w + 1
+		}
+	}
+	words := make([]int, len(other.Words))
+	for i, w := range other.Words {
+		words[i] = w
+		if w >= 0 {
+			words[i] += wordsBase
+		}
+	}
+	if len(e.Words) < originalLen || len(other.Words) < other.Len() {
+		// Words are not tracked by one of them.
+		words = nil
+		e.Words = nil
+	}
+
+	offsets := make([][]int, len(e.Offsets), len(e.Offsets)+len(other.Offsets))
+	copy(offsets, e.Offsets)
+	for i, o := range other.Offsets {
+		if i < len(other.SpecialTokenMask) && other.SpecialTokenMask[i] == 1 {
+			offsets = append(offsets, []int{o[0], o[1]})
+			continue
+		}
+		offsets = append(offsets, []int{o[0] + offsetsBase, o[1] + offsetsBase})
+	}
+
+	if len(other.SequenceRanges) > 0 && e.SequenceRanges == nil {
+		e.SequenceRanges = make(map[int]Range)
+	}
+	for seqId, r := range other.SequenceRanges {
+		if len(r) == 0 {
+			continue
+		}
+		start := originalLen + r[0]
+		if prev, ok := e.SequenceRanges[seqId]; ok && len(prev) > 0 {
+			start = prev[0]
+		}
+		e.SequenceRanges[seqId] = NewRange(start, originalLen+r[len(r)-1]+1)
+	}
+
+	e.Ids = util.Merge(e.Ids, other.Ids)
+	e.Tokens = util.Merge(e.Tokens, other.Tokens)
+	e.Words = util.Merge(e.Words, words)
+	e.TypeIds = util.Merge(e.TypeIds, other.TypeIds)
+	e.SpecialTokenMask = util.Merge(e.SpecialTokenMask, other.SpecialTokenMask)
+	e.AttentionMask = util.Merge(e.AttentionMask, other.AttentionMask)
+	e.Offsets = offsets
+	e.SourceForm = mergeSourceForm(e.SourceForm, other.SourceForm)
+
+	return e
+}
+
 // mergeEncoding merges 2 encodings those have `Overflowing` field empty.
 // Otherwise, it will be panic.
 func mergeEncoding(en1, en2 Encoding, growingOffsets bool) Encoding {
@@ -510,6 +706,7 @@ func mergeEncoding(en1, en2 Encoding, growingOffsets bool) Encoding {
 	merge.Tokens = util.Merge(en1.Tokens, en2.Tokens)
 	merge.SpecialTokenMask = util.Merge(en1.SpecialTokenMask, en2.SpecialTokenMask)
 	merge.AttentionMask = util.Merge(en1.AttentionMask, en2.AttentionMask)
+	merge.SourceForm = mergeSourceForm(en1.SourceForm, en2.SourceForm)
 
 	// sequence range
 	merge.SequenceRanges = make(map[int]Range)
@@ -556,7 +753,7 @@ func (e *Encoding) Pad(targetLength, padId, padTypeId int, padToken string, dire
 	// 1. Overflowing
 	var overflowing []Encoding
 	for _, o := range e.Overflowing {
-		padded := o.pad(targetLength, padId, padTypeId, padToken, direction)
+		padded := o.Pad(targetLength, padId, padTypeId, padToken, direction)
 		overflowing = append(overflowing, *padded)
 	}
 	e.Overflowing = overflowing
@@ -571,137 +768,120 @@ func (e *Encoding) Pad(targetLength, padId, padTypeId int, padToken string, dire
 	return paddedEn
 }
 
+// pad pads e to targetLength tokens. Padding tokens are special tokens masked
+// out of the attention mask, with {0, 0} offsets and no word. New slices are
+// allocated so that encodings sharing e's slices are left untouched.
 func (e *Encoding) pad(targetLength, padId, padTypeId int, padToken string, direction PaddingDirection) *Encoding {
 	padLength := targetLength - len(e.Ids)
-
-	switch direction {
-	case Left:
-		newIds := make([]int, padLength)
-		for i := 0; i < len(newIds); i++ {
-			newIds[i] = padId
+	left := direction == Left
+
+	e.Ids = padSlice(e.Ids, padLength, left, func() int { return padId })
+	e.TypeIds = padSlice(e.TypeIds, padLength, left, func() int { return padTypeId })
+	e.Tokens = padSlice(e.Tokens, padLength, left, func() string { return padToken })
+	e.SpecialTokenMask = padSlice(e.SpecialTokenMask, padLength, left, func() int { return 1 })
+	e.AttentionMask = padSlice(e.AttentionMask, padLength, left, func() int { return 0 })
+	e.Offsets = padSlice(e.Offsets, padLength, left, func() []int { return []int{0, 0} })
+	e.Words = padSlice(e.Words, padLength, left, func() int { return -1 })
+
+	// Sequences are moved after the left padding.
+	if left && len(e.SequenceRanges) > 0 {
+		ranges := make(map[int]Range, len(e.SequenceRanges))
+		for seqId, r := range e.SequenceRanges {
+			shifted := make(Range, len(r))
+			for i, idx := range r {
+				shifted[i] = idx + padLength
+			}
+			ranges[seqId] = shifted
 		}
-		newIds = append(newIds, e.Ids...)
-		e.Ids = newIds
+		e.SequenceRanges = ranges
+	}
 
-		newTypeIds := make([]int, padLength)
-		for i := 0; i < len(newTypeIds); i++ {
-			newTypeIds[i] = padTypeId
-		}
-		newTypeIds = append(newTypeIds, e.Ids...)
-		e.TypeIds = newTypeIds
+	return e
+}
 
-		newTokens := make([]string, padLength)
-		for i := 0; i < len(newTokens); i++ {
-			newTokens[i] = padToken
-		}
-		newTokens = append(newTokens, e.Tokens...)
-		e.Tokens = newTokens
+// padSlice returns a copy of vals with n padding values on the given side.
+func padSlice[T any](vals []T, n int, left bool, padValue func() T) []T {
+	out := make([]T, 0, len(vals)+n)
+	if !left {
+		out = append(out, vals...)
+	}
+	for i := 0; i < n; i++ {
+		out = append(out, padValue())
+	}
+	if left {
+		out = append(out, vals...)
+	}
 
-		newSpecialTokenMask := make([]int, padLength)
-		for i := 0; i < len(newSpecialTokenMask); i++ {
-			newSpecialTokenMask[i] = 1
-		}
-		newSpecialTokenMask = append(newSpecialTokenMask, e.SpecialTokenMask...)
-		e.SpecialTokenMask = newSpecialTokenMask
+	return out
+}
 
-		newAttentionMask := make([]int, padLength)
-		for i := 0; i < len(newAttentionMask); i++ {
-			newAttentionMask[i] = 0
-		}
-		newAttentionMask = append(newAttentionMask, e.AttentionMask...)
-		e.AttentionMask = newAttentionMask
+// Token2Sequence returns the index of the sequence containing the given token.
+//
+// Deprecated: use TokenToSequence.
+func (e *Encoding) Token2Sequence(token int) (int, bool) {
+	return e.TokenToSequence(token)
+}
 
-		newOffsets := make([][]int, padLength)
-		for i := 0; i < len(newIds); i++ {
-			newOffsets[i] = []int{0, 0}
-		}
-		newOffsets = append(newOffsets, e.Offsets...)
-		e.Offsets = newOffsets
+// ConvertOffsets converts in place the byte offsets of all tokens, including the
+// ones of overflowing encodings, to the given OffsetType. `original` is the input
+// text of the first sequence and, for pair encodings, `pairOpt` the one of the second.
+//
+// Tokens not belonging to any sequence (i.e. special tokens with (0, 0) offsets)
+// are converted using the first sequence.
+func (e *Encoding) ConvertOffsets(target OffsetType, original string, pairOpt ...string) error {
+	return e.ConvertOffsetsFrom(Byte, target, original, pairOpt...)
+}
 
-		newWords := make([]int, padLength)
-		for i := 0; i < len(newWords); i++ {
-			newWords[i] = -1
-		}
-		newWords = append(newWords, e.Words...)
-		e.Words = newWords
-
-	case Right:
-		for i := 0; i < padLength; i++ {
-			e.Ids = append(e.Ids, padId)
-			e.TypeIds = append(e.TypeIds, padTypeId)
-			e.Tokens = append(e.Tokens, padToken)
-			e.SpecialTokenMask = append(e.SpecialTokenMask, 1)
-			e.AttentionMask = append(e.AttentionMask, 0)
-			e.Offsets = append(e.Offsets, []int{0, 0})
-			e.Words = append(e.Words, -1)
-		}
+// ConvertOffsetsFrom converts in place the offsets of all tokens from the source
+// OffsetType to the target one, i.e. char offsets of an encoding made with
+// `WithOffsetTypeEncodeOpt(Char)` back to byte offsets. See `ConvertOffsets`.
+func (e *Encoding) ConvertOffsetsFrom(source, target OffsetType, original string, pairOpt ...string) error {
+	if source == target {
+		return nil
 	}
 
-	return e
-}
+	converters := []OffsetConverter{NewOffsetConverter(original, source, target)}
+	if len(pairOpt) > 0 {
+		converters = append(converters, NewOffsetConverter(pairOpt[0], source, target))
+	}
 
-func getCurrentPart(previous, current interface{}, size, idx, stride int) interface{} {
+	return e.convertOffsets(converters)
+}
 
-	switch current.(type) {
-	case []int:
-		var curr, prev []int
-		if int((idx+1)*size) > reflect.ValueOf(current).Len() {
-			curr = current.([]int)[(idx * size):]
-		} else {
-			curr = current.([]int)[(idx * size) : (idx+1)*size]
-		}
-		prev = previous.([]int)[len(previous.([]int))-stride:]
-		return append(prev, curr...)
-	case []string:
-		var curr, prev []string
-		if (idx+1)*size > reflect.ValueOf(current).Len() {
-			curr = current.([]string)[(idx * size):]
-		} else {
-			curr = current.([]string)[(idx * size) : (idx+1)*size]
+func (e *Encoding) convertOffsets(converters []OffsetConverter) error {
+	for i, offsets := range e.Offsets {
+		seqId, ok := e.TokenToSequence(i)
+		if !ok || seqId >= len(converters) {
+			seqId = 0
 		}
-		prev = previous.([]string)[len(previous.([]string))-stride:]
-		return append(prev, curr...)
-	case [][]int:
-		var curr, prev [][]int
-		if (idx+1)*size > reflect.ValueOf(current).Len() {
-			curr = current.([][]int)[(idx * size):]
-		} else {
-			curr = current.([][]int)[(idx * size) : (idx+1)*size]
+
+		converted, err := converters[seqId].Convert(offsets)
+		if err != nil {
+			return err
 		}
-		prev = previous.([][]int)[len(previous.([][]int))-stride:]
-		return append(prev, curr...)
-	default:
-		log.Fatalf("getCurrentPart method call: invalid type\n")
+		e.Offsets[i] = converted
 	}
 
-	return nil
-}
-
-// Token2Sequence returns the index of the sequence containing the given token.
-func (e *Encoding) Token2Sequence(token int) (int, bool) {
-	if token > e.Len() {
-		return -1, false
-	} else if len(e.SequenceRanges) == 0 {
-		return 0, true
-	} else {
-		for seqId, r := range e.SequenceRanges {
-			if r.Contains(token) {
-				return seqId, true
-			}
+	for i := range e.Overflowing {
+		if err := e.Overflowing[i].convertOffsets(converters); err != nil {
+			return err
 		}
-
-		return -1, false
 	}
+
+	return nil
 }
 
 // SequenceRange returns the range to target to retrieve something (word id, offsets, ...)
 // related to the given sequence id.
 func (e *Encoding) SequenceRange(sequencId int) (Range, error) {
-	r, ok := e.SequenceRanges[sequencId]
+	start, end, ok := e.tokenRange(sequencId)
 	if !ok {
 		err := fmt.Errorf("input 'sequence_id' is out of range.\n")
 		return nil, err
 	}
+	if start == end {
+		return Range{}, nil
+	}
 
-	return r[0:e.Len()], nil
+	return NewRange(start, end), nil
 }
@@ -6,6 +6,11 @@ import (
 	"testing"
 
 	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model"
+	"github.com/season-studio/tokenizer/model/bpe"
+	"github.com/season-studio/tokenizer/pretokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+	"github.com/season-studio/tokenizer/processor"
 )
 
 func TestTokenizer_MergeWith(t *testing.T) {
@@ -224,3 +229,375 @@ func testMapping(t *testing.T, got, want interface{}) {
 		t.Errorf("Got: %v\n", got)
 	}
 }
+
+func TestEncoding_ConvertOffsets(t *testing.T) {
+	vocab := make(model.Vocab)
+	for b := 0; b < 256; b++ {
+		vocab[pretokenizer.BytesChar[uint8(b)]] = b
+	}
+	vocab["Ġh"] = 256
+	merges := bpe.Merges{bpe.Pair{C1: vocab["Ġ"], C2: vocab["h"]}: bpe.PairVal{Rank: 0, NewId: 256}}
+
+	tk := tokenizer.NewTokenizer(bpe.NewBPE(vocab, merges))
+	bl := pretokenizer.NewByteLevel()
+	bl.SetAddPrefixSpace(false)
+	bl.SetTrimOffsets(true)
+	tk.WithPreTokenizer(bl)
+	tk.WithPostProcessor(processor.NewByteLevelProcessing(bl))
+
+	input := "a🚀b héllo"
+	// Tokens: a, 4 bytes of 🚀, b, Ġh (trimmed to h), 2 bytes of é, l, l, o
+	tests := []struct {
+		target tokenizer.OffsetType
+		want   [][]int
+	}{
+		{tokenizer.Byte, [][]int{{0, 1}, {1, 5}, {1, 5}, {1, 5}, {1, 5}, {5, 6}, {7, 8}, {8, 10}, {8, 10}, {10, 11}, {11, 12}, {12, 13}}},
+		{tokenizer.Char, [][]int{{0, 1}, {1, 2}, {1, 2}, {1, 2}, {1, 2}, {2, 3}, {4, 5}, {5, 6}, {5, 6}, {6, 7}, {7, 8}, {8, 9}}},
+		{tokenizer.UTF16, [][]int{{0, 1}, {1, 3}, {1, 3}, {1, 3}, {1, 3}, {3, 4}, {5, 6}, {6, 7}, {6, 7}, {7, 8}, {8, 9}, {9, 10}}},
+	}
+
+	for _, tt := range tests {
+		en, err := tk.EncodeSingle(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := en.ConvertOffsets(tt.target, input); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.want, en.Offsets) {
+			t.Errorf("target %v: want %v, got %v", tt.target, tt.want, en.Offsets)
+		}
+
+		// Same conversion done while encoding
+		en, err = tk.EncodeSingleSequence(tokenizer.NewInputSequence(input), 0, tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		en, err = tk.PostProcess(en, nil, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.want, en.Offsets) {
+			t.Errorf("encoding with %v: want %v, got %v", tt.target, tt.want, en.Offsets)
+		}
+	}
+
+	// Overflowing encodings are converted too.
+	en, err := tk.EncodeSingle(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	en, err = en.Truncate(6, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := en.ConvertOffsets(tokenizer.UTF16, input); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{5, 6}, {6, 7}, {6, 7}, {7, 8}, {8, 9}, {9, 10}}
+	if len(en.Overflowing) != 1 || !reflect.DeepEqual(want, en.Overflowing[0].Offsets) {
+		t.Errorf("want overflowing offsets %v, got %+v", want, en.Overflowing)
+	}
+}
+
+func TestEncoding_ConvertOffsetsFrom(t *testing.T) {
+	input := "a🚀b héllo"
+	// Offsets of "a", "🚀", "b", "h", "é" and "llo" in each unit.
+	offsets := map[tokenizer.OffsetType][][]int{
+		tokenizer.Byte:  {{0, 1}, {1, 5}, {5, 6}, {7, 8}, {8, 10}, {10, 13}, {0, 0}},
+		tokenizer.Char:  {{0, 1}, {1, 2}, {2, 3}, {4, 5}, {5, 6}, {6, 9}, {0, 0}},
+		tokenizer.UTF16: {{0, 1}, {1, 3}, {3, 4}, {5, 6}, {6, 7}, {7, 10}, {0, 0}},
+	}
+
+	for source, sourceOffsets := range offsets {
+		for target, want := range offsets {
+			en := tokenizer.NewEncodingWithCapacity(len(sourceOffsets))
+			for i, o := range sourceOffsets {
+				en.Offsets[i] = append([]int{}, o...)
+			}
+			if err := en.ConvertOffsetsFrom(source, target, input); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(want, en.Offsets) {
+				t.Errorf("%v to %v: want %v, got %v", source, target, want, en.Offsets)
+			}
+		}
+	}
+
+	// Offsets inside a surrogate pair or a multi-byte char cover the whole char.
+	c := tokenizer.NewUnitsToBytesOffsetConverter(input, tokenizer.UTF16)
+	if got, err := c.Convert([]int{2, 3}); err != nil || !reflect.DeepEqual([]int{1, 5}, got) {
+		t.Errorf("want [1 5], got %v, %v", got, err)
+	}
+	if _, err := c.Convert([]int{0, 11}); err == nil {
+		t.Errorf("want out of range error, got nil")
+	}
+}
+
+func TestEncoding_ConvertOffsetsSpecialTokens(t *testing.T) {
+	tk := pretrained.BertBaseUncased()
+
+	// "é" is "e" followed by a combining acute accent, removed by the normalizer.
+	input := "x🚀 e\u0301!"
+	tests := []struct {
+		target tokenizer.OffsetType
+		want   [][]int
+	}{
+		{tokenizer.Byte, [][]int{{0, 0}, {0, 5}, {6, 7}, {9, 10}, {0, 0}}},
+		{tokenizer.Char, [][]int{{0, 0}, {0, 2}, {3, 4}, {5, 6}, {0, 0}}},
+		{tokenizer.UTF16, [][]int{{0, 0}, {0, 3}, {4, 5}, {6, 7}, {0, 0}}},
+	}
+
+	for _, tt := range tests {
+		en, err := tk.EncodeSingle(input, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := en.ConvertOffsets(tt.target, input); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.want, en.Offsets) {
+			t.Errorf("target %v: want %v, got %v", tt.target, tt.want, en.Offsets)
+		}
+	}
+}
+
+func TestPadEncodings(t *testing.T) {
+	tk := getWordLevelBert(t)
+	inputs := []tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("a b c")),
+		tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence("a b c d e"), tokenizer.NewInputSequence("x y")),
+	}
+
+	tests := []struct {
+		strategy        *tokenizer.PaddingStrategy
+		padToMultipleOf int
+		direction       tokenizer.PaddingDirection
+		wantLen         int
+	}{
+		{tokenizer.NewPaddingStrategy(tokenizer.WithBatchLongest()), 0, tokenizer.Right, 10},
+		{tokenizer.NewPaddingStrategy(tokenizer.WithBatchLongest()), 8, tokenizer.Left, 16},
+		{tokenizer.NewPaddingStrategy(tokenizer.WithFixed(12)), 0, tokenizer.Left, 12},
+		{tokenizer.NewPaddingStrategy(tokenizer.WithFixed(12)), 5, tokenizer.Right, 15},
+	}
+
+	for _, tt := range tests {
+		tk.WithPadding(&tokenizer.PaddingParams{
+			Strategy:        *tt.strategy,
+			Direction:       tt.direction,
+			PadToMultipleOf: tt.padToMultipleOf,
+			PadId:           0,
+			PadTypeId:       3,
+			PadToken:        "[PAD]",
+		})
+		encodings, err := tk.EncodeBatch(inputs, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, en := range encodings {
+			nTokens := []int{5, 10}[i]
+			nPad := tt.wantLen - nTokens
+			for _, l := range []int{len(en.Ids), len(en.TypeIds), len(en.Tokens), len(en.Offsets), len(en.SpecialTokenMask), len(en.AttentionMask), len(en.Words)} {
+				if l != tt.wantLen {
+					t.Fatalf("%+v: want length %v, got %+v", tt, tt.wantLen, en)
+				}
+			}
+
+			wantMask := make([]int, tt.wantLen)
+			start := 0
+			if tt.direction == tokenizer.Left {
+				start = nPad
+			}
+			for j := start; j < start+nTokens; j++ {
+				wantMask[j] = 1
+			}
+			if !reflect.DeepEqual(wantMask, en.AttentionMask) {
+				t.Errorf("%+v: want attention mask %v, got %v", tt, wantMask, en.AttentionMask)
+			}
+			if en.Tokens[start] != "[CLS]" {
+				t.Errorf("%+v: want %q at %v, got %q", tt, "[CLS]", start, en.Tokens)
+			}
+			if pad := (start + nTokens) % tt.wantLen; nPad > 0 && (en.Tokens[pad] != "[PAD]" || en.TypeIds[pad] != 3 || en.Words[pad] != -1) {
+				t.Errorf("%+v: want padding at %v, got %q %v", tt, pad, en.Tokens, en.TypeIds)
+			}
+		}
+	}
+}
+
+func TestEncoding_PadOverflowing(t *testing.T) {
+	tk := getWordLevelBert(t)
+	en, err := tk.EncodeSingle("a b c d e f g", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	en, err = en.Truncate(5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := en.Ids
+
+	// The overflowing encoding is padded, the already longer encoding is not.
+	padded := en.Pad(4, 0, 0, "[PAD]", tokenizer.Right)
+	if want := []string{"f", "g", "[PAD]", "[PAD]"}; !reflect.DeepEqual(want, padded.Overflowing[0].Tokens) {
+		t.Errorf("want %q, got %q", want, padded.Overflowing[0].Tokens)
+	}
+	if len(padded.Ids) != 5 || !reflect.DeepEqual(ids, padded.Ids) {
+		t.Errorf("want %v, got %v", ids, padded.Ids)
+	}
+
+	// Sequence ranges follow the left padding.
+	en, err = tk.EncodeSingle("a b", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	en.SetSequenceIds(0)
+	en = en.Pad(4, 0, 0, "[PAD]", tokenizer.Left)
+	if want, r := (tokenizer.Range{2, 3}), en.SequenceRanges[0]; !reflect.DeepEqual(want, r) {
+		t.Errorf("want %v, got %v", want, r)
+	}
+}
+
+func TestEncoding_Alignment(t *testing.T) {
+	tk := getWordLevelBert(t)
+	en, err := tk.EncodePair("a bc d", "e f", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// [CLS] a [UNK] d [SEP] e f [SEP]
+	if want := []int{-1, 0, 1, 2, -1, 0, 1, -1}; !reflect.DeepEqual(want, en.WordIds()) {
+		t.Errorf("want word ids %v, got %v", want, en.WordIds())
+	}
+
+	seqTests := []struct {
+		token int
+		seq   int
+		ok    bool
+	}{{0, -1, false}, {1, 0, true}, {3, 0, true}, {4, -1, false}, {5, 1, true}, {6, 1, true}, {8, -1, false}, {-1, -1, false}}
+	for _, tt := range seqTests {
+		if seq, ok := en.TokenToSequence(tt.token); seq != tt.seq || ok != tt.ok {
+			t.Errorf("token %v: want sequence %v %v, got %v %v", tt.token, tt.seq, tt.ok, seq, ok)
+		}
+	}
+
+	if offsets, ok := en.TokenToChars(2); !ok || !reflect.DeepEqual([]int{2, 4}, offsets) {
+		t.Errorf("want [2 4], got %v %v", offsets, ok)
+	}
+	if _, ok := en.TokenToChars(0); ok {
+		t.Errorf("want no offsets for special token")
+	}
+	if word, ok := en.TokenToWord(6); !ok || word != 1 {
+		t.Errorf("want word 1, got %v %v", word, ok)
+	}
+
+	// Words and chars are looked up in the given sequence.
+	if start, end, ok := en.WordToTokens(1); !ok || start != 2 || end != 3 {
+		t.Errorf("want tokens [2, 3), got [%v, %v) %v", start, end, ok)
+	}
+	if start, end, ok := en.WordToTokens(1, 1); !ok || start != 6 || end != 7 {
+		t.Errorf("want tokens [6, 7), got [%v, %v) %v", start, end, ok)
+	}
+	if _, _, ok := en.WordToTokens(3, 1); ok {
+		t.Errorf("want no tokens for word 3 of the pair")
+	}
+	if chars, ok := en.WordToChars(0, 1); !ok || !reflect.DeepEqual([]int{0, 1}, chars) {
+		t.Errorf("want [0 1], got %v %v", chars, ok)
+	}
+	if token, ok := en.CharToToken(2, 1); !ok || token != 6 {
+		t.Errorf("want token 6, got %v %v", token, ok)
+	}
+	if token, ok := en.CharToToken(3); !ok || token != 2 {
+		t.Errorf("want token 2, got %v %v", token, ok)
+	}
+	if _, ok := en.CharToToken(1); ok {
+		t.Errorf("want no token at whitespace")
+	}
+	if word, ok := en.CharToWord(5, 0); !ok || word != 2 {
+		t.Errorf("want word 2, got %v %v", word, ok)
+	}
+	if _, ok := en.CharToToken(0, 2); ok {
+		t.Errorf("want no token in missing sequence")
+	}
+}
+
+func TestEncoding_AppendAndChunk(t *testing.T) {
+	tk := getWordLevelBert(t)
+	doc := "a b c\n\nd e f g"
+	paragraphs := []struct {
+		text  string
+		start int
+	}{{"a b c", 0}, {"d e f g", 7}}
+
+	var en *tokenizer.Encoding
+	for _, p := range paragraphs {
+		pen, err := tk.EncodeSingle(p.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if en == nil {
+			en = pen
+			continue
+		}
+		en.Append(pen, p.start)
+	}
+	if want := []int{0, 1, 2, 3, 4, 5, 6}; !reflect.DeepEqual(want, en.Words) {
+		t.Errorf("want words %v, got %v", want, en.Words)
+	}
+	for i, o := range en.Offsets {
+		if got := doc[o[0]:o[1]]; got != en.Tokens[i] {
+			t.Errorf("token %d: want %q at %v, got %q", i, en.Tokens[i], o, got)
+		}
+	}
+
+	// Windows of 3 tokens overlapping by 1.
+	if _, err := en.Truncate(3, 1); err != nil {
+		t.Fatal(err)
+	}
+	windows := append([]tokenizer.Encoding{*en}, en.Overflowing...)
+	wantTokens := [][]string{{"a", "b", "c"}, {"c", "d", "e"}, {"e", "f", "g"}}
+	if len(windows) != len(wantTokens) {
+		t.Fatalf("want %d windows, got %d", len(wantTokens), len(windows))
+	}
+	for i, w := range windows {
+		if !reflect.DeepEqual(wantTokens[i], w.Tokens) {
+			t.Errorf("window %d: want %q, got %q", i, wantTokens[i], w.Tokens)
+		}
+		for j, o := range w.Offsets {
+			if got := doc[o[0]:o[1]]; got != w.Tokens[j] {
+				t.Errorf("window %d token %d: want %q at %v, got %q", i, j, w.Tokens[j], o, got)
+			}
+		}
+		if r, err := w.SequenceRange(0); err == nil && r.Len() != w.Len() {
+			t.Errorf("window %d: want sequence range of %d tokens, got %v", i, w.Len(), r)
+		}
+	}
+
+	// Windows own their slices.
+	windows[1].Ids[0] = -1
+	windows[1].Offsets[0][0] = -1
+	if en.Ids[2] == -1 || en.Offsets[2][0] == -1 || windows[2].Ids[0] == -1 {
+		t.Error("want windows not sharing their slices")
+	}
+
+	padded := windows[2].Pad(5, 0, 0, "[PAD]", tokenizer.Right)
+	if want := []int{1, 1, 1, 0, 0}; !reflect.DeepEqual(want, padded.AttentionMask) {
+		t.Errorf("want attention mask %v, got %v", want, padded.AttentionMask)
+	}
+}
+
+func TestEncoding_TruncateWithoutWords(t *testing.T) {
+	en := tokenizer.Encoding{
+		Ids:              []int{1, 2, 3, 4},
+		TypeIds:          []int{0, 0, 0, 0},
+		Tokens:           []string{"a", "b", "c", "d"},
+		Offsets:          [][]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}},
+		SpecialTokenMask: []int{0, 0, 0, 0},
+		AttentionMask:    []int{1, 1, 1, 1},
+	}
+	if _, err := en.Truncate(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(en.Overflowing) != 1 || !reflect.DeepEqual([]int{3, 4}, en.Overflowing[0].Ids) || en.Overflowing[0].Words != nil {
+		t.Errorf("want one overflowing encoding of ids [3 4], got %+v", en.Overflowing)
+	}
+}
@@ -0,0 +1,40 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors wrapped by the errors of the tokenizer, its models and the
+// `pretrained` loaders, to be checked with `errors.Is`.
+var (
+	// ErrUnknownModelType is returned when loading a model of unknown or
+	// unsupported type.
+	ErrUnknownModelType = errors.New("unknown model type")
+	// ErrTokenNotInVocab is returned when a token the model needs, i.e. its
+	// `unk` token or the result of a merge, is not in the vocab.
+	ErrTokenNotInVocab = errors.New("token not in vocab")
+	// ErrInvalidMergeFormat is returned for a merge which is neither an "a b"
+	// line nor an ["a", "b"] pair.
+	ErrInvalidMergeFormat = errors.New("invalid merge format")
+	// ErrTruncationNeeded is returned when an encoding is longer than the max
+	// length and cannot be truncated to fit, i.e. the sequence to truncate is
+	// too short or special tokens leave no room for it.
+	ErrTruncationNeeded = errors.New("truncation needed")
+)
+
+// MergeError is returned for an invalid merge of a BPE model, with its
+// position. It wraps `ErrInvalidMergeFormat` or `ErrTokenNotInVocab`.
+type MergeError struct {
+	Line  int // index of the merge, from 0, comments included
+	Merge string
+	Err   error
+}
+
+func (e *MergeError) Error() string {
+	return fmt.Sprintf("invalid merge %q at line %d: %v", e.Merge, e.Line, e.Err)
+}
+
+func (e *MergeError) Unwrap() error {
+	return e.Err
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/season-studio/tokenizer"
 	"github.com/season-studio/tokenizer/model/unigram"
-	"github.com/season-studio/tokenizer/util"
 )
 
 func main() {
@@ -22,14 +21,8 @@ func main() {
 		{Token: "?", Score: -8.0},
 	}
 
-	// Set unk_id to 0 (the first token)
-	unkID := 0
-	opts := util.NewParams(nil)
-	opts.Set("unk_id", unkID)
-	opts.Set("byte_fallback", false)
-
-	// Create the Unigram model
-	model, err := unigram.New(vocab, opts)
+	// Create the Unigram model, with unk_id 0 (the first token)
+	model, err := unigram.New(vocab, unigram.WithUnkID(0), unigram.WithByteFallback(false))
 	if err != nil {
 		log.Fatalf("Failed to create Unigram model: %v", err)
 	}
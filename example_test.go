@@ -3,7 +3,9 @@ package tokenizer_test
 import (
 	"fmt"
 	"log"
+	"sync"
 
+	"github.com/season-studio/tokenizer"
 	"github.com/season-studio/tokenizer/pretrained"
 )
 
@@ -45,3 +47,79 @@ func ExampleTokenizer_EncodeSingle() {
 	// offsets: [[0 0] [0 5] [5 6] [7 8] [8 9] [9 12] [12 13] [14 17] [18 21] [22 25] [26 30] [31 32] [0 0]]
 	// word Ids: [-1 0 1 2 3 4 5 6 7 8 9 10 -1]
 }
+
+// costStore has the method set of a ristretto cache (`*ristretto.Cache`):
+// values are admitted with a cost and may be dropped by the store.
+type costStore interface {
+	Get(key interface{}) (interface{}, bool)
+	Set(key, value interface{}, cost int64) bool
+}
+
+// ristrettoCache adapts a costStore to tokenizer.Cache, using the number of
+// tokens as the cost of an encoding.
+type ristrettoCache struct {
+	store costStore
+}
+
+func (c *ristrettoCache) Get(key uint64) (tokenizer.Encoding, bool) {
+	v, ok := c.store.Get(key)
+	if !ok {
+		return tokenizer.Encoding{}, false
+	}
+	return v.(tokenizer.Encoding), true
+}
+
+func (c *ristrettoCache) Put(key uint64, enc tokenizer.Encoding) {
+	c.store.Set(key, enc, int64(enc.Len()))
+}
+
+// mapStore is a minimal costStore standing in for ristretto in this example.
+type mapStore struct {
+	mu      sync.Mutex
+	maxCost int64
+	cost    int64
+	items   map[interface{}]interface{}
+}
+
+func (s *mapStore) Get(key interface{}) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.items[key]
+	return v, ok
+}
+
+func (s *mapStore) Set(key, value interface{}, cost int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cost+cost > s.maxCost {
+		return false
+	}
+	s.items[key] = value
+	s.cost += cost
+	return true
+}
+
+func ExampleCache() {
+	tk := pretrained.BertBaseUncased()
+	tk.WithCache(&ristrettoCache{&mapStore{maxCost: 1 << 20, items: make(map[interface{}]interface{})}})
+
+	sink := tokenizer.NewMemoryMetricsSink()
+	tk.WithMetricsSink(sink)
+
+	for i := 0; i < 3; i++ {
+		en, err := tk.EncodeSingle("Yesterday I saw a [MASK] far away")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("tokens: %v\n", en.GetTokens())
+	}
+
+	counters := sink.Counters()
+	fmt.Printf("hits: %v, misses: %v\n", counters[tokenizer.MetricEncodeCacheHit], counters[tokenizer.MetricEncodeCacheMiss])
+
+	// Output:
+	// tokens: [yesterday i saw a [MASK] far away]
+	// tokens: [yesterday i saw a [MASK] far away]
+	// tokens: [yesterday i saw a [MASK] far away]
+	// hits: 2, misses: 1
+}
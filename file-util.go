@@ -1,12 +1,19 @@
 package tokenizer
 
 import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -21,8 +28,63 @@ const (
 
 	// NOTE. URL form := `$HFpath/ModelName/resolve/main/WeightName`
 	HFpath = "https://huggingface.co"
+
+	// ChecksumSuffix is the suffix of the file storing the SHA-256 of a
+	// downloaded file next to it in the cache.
+	ChecksumSuffix = ".sha256"
 )
 
+// ErrChecksumMismatch is returned when the content of a file does not match
+// its expected checksum, i.e. a corrupted or truncated file.
+type ErrChecksumMismatch struct {
+	Path     string
+	Algo     string // "sha256" or "git-sha1" (Hub ETag of non-LFS files)
+	Expected string
+	Actual   string
+}
+
+func (e *ErrChecksumMismatch) Error() string {
+	return fmt.Sprintf("checksum mismatch for %q: expected %s %s, got %s", e.Path, e.Algo, e.Expected, e.Actual)
+}
+
+var (
+	sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	sha1Hex   = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// hubChecksum returns the checksum provided by the Hub for a resolved file.
+// LFS files have their SHA-256 in `X-Linked-Etag`, other files have the git
+// blob SHA-1 in `ETag`. It returns empty strings if there is none.
+func hubChecksum(header http.Header) (algo, checksum string) {
+	etag := header.Get("X-Linked-Etag")
+	if etag == "" {
+		etag = header.Get("ETag")
+	}
+	etag = strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	etag = strings.ToLower(etag)
+
+	switch {
+	case sha256Hex.MatchString(etag):
+		return "sha256", etag
+	case sha1Hex.MatchString(etag):
+		return "git-sha1", etag
+	default:
+		return "", ""
+	}
+}
+
+// StoredChecksum returns the SHA-256 stored alongside a file downloaded to the
+// cache by `CachedPath`.
+func StoredChecksum(file string) (string, error) {
+	data, err := os.ReadFile(file + ChecksumSuffix)
+	if err != nil {
+		err = fmt.Errorf("StoredChecksum() failed: %w", err)
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
 var (
 	DUMMY_INPUT [][]int64 = [][]int64{
 		{7, 6, 0, 0, 1},
@@ -92,6 +154,73 @@ func CachedPath(modelNameOrPath, fileName string) (resolvedPath string, err erro
 	return "", err
 }
 
+// ErrHubFileNotFound is returned when a file does not exist in a Hub repository.
+var ErrHubFileNotFound = errors.New("file not found on the Hub")
+
+// HubFileOptions configures how `CachedHubFile` downloads a file from the Hub.
+type HubFileOptions struct {
+	// Revision is the branch, tag or commit to download from, "main" if empty.
+	Revision string
+	// Token is the Hub access token of private and gated repositories.
+	Token string
+	// CacheDir is the download cache directory, `CachedDir` if empty.
+	CacheDir string
+	// Endpoint is the Hub URL, `HFpath` if empty.
+	Endpoint string
+	// Client is the HTTP client, `http.DefaultClient` if nil.
+	Client *http.Client
+}
+
+// CachedHubFile returns the path to file `fileName` of the Hub repository
+// `modelID`, downloading it to the cache if it is not there yet. Files of the
+// "main" revision are cached as by `CachedPath`, other revisions at
+// "{CacheDir}/{modelID}@{revision}/{fileName}". It returns an error wrapping
+// `ErrHubFileNotFound` if the repository has no such file.
+func CachedHubFile(modelID, fileName string, opts HubFileOptions) (string, error) {
+	revision := opts.Revision
+	if revision == "" {
+		revision = "main"
+	}
+	cacheDir := opts.CacheDir
+	if cacheDir == "" {
+		if CachedDir == "NOT_SETTING" {
+			initCachePath()
+		}
+		cacheDir = CachedDir
+	}
+	endpoint := strings.TrimSuffix(opts.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = HFpath
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	repoDir := modelID
+	if revision != "main" {
+		repoDir = modelID + "@" + url.PathEscape(revision)
+	}
+	cachedFile := path.Join(cacheDir, repoDir, fileName)
+	if _, err := os.Stat(cachedFile); err == nil {
+		return cachedFile, nil
+	}
+
+	fileURL := fmt.Sprintf("%s/%s/resolve/%s/%s", endpoint, modelID, url.PathEscape(revision), fileName)
+	req, err := http.NewRequest(http.MethodGet, fileURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("CachedHubFile() failed: %w", err)
+	}
+	if opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.Token)
+	}
+	if err := downloadRequest(client, req, cachedFile); err != nil {
+		return "", fmt.Errorf("CachedHubFile() failed: %w", err)
+	}
+
+	return cachedFile, nil
+}
+
 func isValidURL(url string) bool {
 
 	// TODO: implement
@@ -103,6 +232,19 @@ func isValidURL(url string) bool {
 // the entire file into memory. An `io.TeeReader` is passed into Copy()
 // to report progress on the download.
 func downloadFile(url string, filepath string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	return downloadRequest(http.DefaultClient, req, filepath)
+}
+
+// downloadRequest downloads the response of req to filepath as `downloadFile`
+// does.
+func downloadRequest(client *http.Client, req *http.Request, filepath string) error {
+	url := req.URL.String()
+
 	// Create path if not existing
 	dir := path.Dir(filepath)
 	filename := path.Base(filepath)
@@ -121,8 +263,10 @@ func downloadFile(url string, filepath string) error {
 	defer out.Close()
 
 	// Get the data
-	resp, err := http.Get(url)
+	resp, err := client.Do(req)
 	if err != nil {
+		out.Close()
+		os.Remove(filepath + ".tmp")
 		return err
 	}
 	defer resp.Body.Close()
@@ -140,10 +284,12 @@ func downloadFile(url string, filepath string) error {
 			// } else {
 			// err = fmt.Errorf("download file not found: %q for downloading", url)
 			// }
-			err = fmt.Errorf("download file not found: %q for downloading", url)
+			err = fmt.Errorf("download file not found: %q for downloading: %w", url, ErrHubFileNotFound)
 		} else {
-			err = fmt.Errorf("download file failed: %q", url)
+			err = fmt.Errorf("download file failed: %q: %s", url, resp.Status)
 		}
+		out.Close()
+		os.Remove(filepath + ".tmp")
 		return err
 	}
 
@@ -151,13 +297,44 @@ func downloadFile(url string, filepath string) error {
 	size, _ := strconv.Atoi(resp.Header.Get("Content-Length"))
 	downloadSize := uint64(size)
 
-	// Create our bytes counter and pass it to be used alongside our writer
+	// Create our bytes counter and pass it to be used alongside our writer.
+	// Checksums are computed while downloading.
 	counter := &writeCounter{FileSize: downloadSize}
-	_, err = io.Copy(out, io.TeeReader(resp.Body, counter))
+	sha256Hasher := sha256.New()
+	writers := []io.Writer{out, sha256Hasher}
+	var gitHasher hash.Hash // git blob SHA-1 needs the size upfront
+	if size > 0 {
+		gitHasher = sha1.New()
+		fmt.Fprintf(gitHasher, "blob %d\x00", size)
+		writers = append(writers, gitHasher)
+	}
+	_, err = io.Copy(io.MultiWriter(writers...), io.TeeReader(resp.Body, counter))
 	if err != nil {
 		return err
 	}
 
+	if size > 0 && counter.Total != downloadSize {
+		out.Close()
+		os.Remove(filepath + ".tmp")
+		err := fmt.Errorf("download file truncated: %q got %v bytes, expected %v", url, counter.Total, downloadSize)
+		return err
+	}
+
+	actual := hex.EncodeToString(sha256Hasher.Sum(nil))
+	algo, expected := hubChecksum(resp.Header)
+	gotChecksum := actual
+	if algo == "git-sha1" {
+		gotChecksum = ""
+		if gitHasher != nil {
+			gotChecksum = hex.EncodeToString(gitHasher.Sum(nil))
+		}
+	}
+	if expected != "" && gotChecksum != "" && gotChecksum != expected {
+		out.Close()
+		os.Remove(filepath + ".tmp")
+		return &ErrChecksumMismatch{Path: url, Algo: algo, Expected: expected, Actual: gotChecksum}
+	}
+
 	fmt.Printf("\r%s... %s/%s completed", filename, byteCountIEC(counter.Total), byteCountIEC(counter.FileSize))
 	// The progress use the same line so print a new line once it's finished downloading
 	fmt.Println()
@@ -168,6 +345,11 @@ func downloadFile(url string, filepath string) error {
 		return err
 	}
 
+	// Store the checksum for later integrity checks (see `StoredChecksum`)
+	if err := os.WriteFile(filepath+ChecksumSuffix, []byte(actual+"\n"), 0644); err != nil {
+		return err
+	}
+
 	return nil
 }
 
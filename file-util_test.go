@@ -0,0 +1,69 @@
+package tokenizer
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadFile_Checksum(t *testing.T) {
+	content := []byte(`{"version": "1.0"}`)
+	sha256Sum := sha256.Sum256(content)
+	gitSum := sha1.Sum(append([]byte(fmt.Sprintf("blob %d\x00", len(content))), content...))
+	bad := hex.EncodeToString(make([]byte, 32))
+
+	tests := []struct {
+		name     string
+		header   string
+		etag     string
+		body     []byte
+		mismatch bool
+	}{
+		{"lfs sha256", "X-Linked-Etag", `"` + hex.EncodeToString(sha256Sum[:]) + `"`, content, false},
+		{"git sha1", "ETag", `W/"` + hex.EncodeToString(gitSum[:]) + `"`, content, false},
+		{"no checksum", "ETag", `"abc"`, content, false},
+		{"lfs corrupted", "X-Linked-Etag", `"` + bad + `"`, content, true},
+		{"git corrupted", "ETag", `"` + hex.EncodeToString(gitSum[:]) + `"`, []byte(`{"version": "1.1"}`), true},
+	}
+
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(tt.header, tt.etag)
+			w.Write(tt.body)
+		}))
+
+		file := filepath.Join(t.TempDir(), "tokenizer.json")
+		err := downloadFile(srv.URL, file)
+		srv.Close()
+
+		if tt.mismatch {
+			var mismatch *ErrChecksumMismatch
+			if !errors.As(err, &mismatch) {
+				t.Errorf("%v: want ErrChecksumMismatch, got %v", tt.name, err)
+			}
+			if _, err := os.Stat(file); !os.IsNotExist(err) {
+				t.Errorf("%v: want corrupted file not cached", tt.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: want no error, got %v", tt.name, err)
+			continue
+		}
+		stored, err := StoredChecksum(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := hex.EncodeToString(sha256Sum[:]); stored != want {
+			t.Errorf("%v: want stored checksum %v, got %v", tt.name, want, stored)
+		}
+	}
+}
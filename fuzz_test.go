@@ -0,0 +1,271 @@
+package tokenizer_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/decoder"
+	"github.com/season-studio/tokenizer/model"
+	"github.com/season-studio/tokenizer/model/bpe"
+	"github.com/season-studio/tokenizer/model/unigram"
+	"github.com/season-studio/tokenizer/model/wordlevel"
+	"github.com/season-studio/tokenizer/normalizer"
+	"github.com/season-studio/tokenizer/pretokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+// fuzzTokenizer is a tokenizer checked by the fuzz targets.
+type fuzzTokenizer struct {
+	name string
+	tk   *tokenizer.Tokenizer
+	// roundTrip tells whether decode(encode(x)) == x for valid UTF-8 inputs,
+	// i.e. for byte-level and byte fallback models.
+	roundTrip bool
+}
+
+// byteTokens returns the "<0xXX>" byte fallback tokens.
+func byteTokens() []string {
+	tokens := make([]string, 256)
+	for b := range tokens {
+		tokens[b] = fmt.Sprintf("<0x%02X>", b)
+	}
+
+	return tokens
+}
+
+// fuzzTokenizers returns a tokenizer of each model: byte-level and byte
+// fallback BPE, byte fallback and Metaspace Unigram, WordPiece and WordLevel.
+func fuzzTokenizers(t testing.TB) []fuzzTokenizer {
+	t.Helper()
+
+	// Byte-level BPE, with merges.
+	vocab := make(model.Vocab)
+	for b := 0; b < 256; b++ {
+		vocab[pretokenizer.BytesChar[uint8(b)]] = b
+	}
+	merges := []string{"Ġ t", "h e", "Ġt he", "l l", "he ll", "o w"}
+	for _, m := range merges {
+		a, b, _ := strings.Cut(m, " ")
+		vocab[a+b] = len(vocab)
+	}
+	byteLevel, err := bpe.New(vocab, merges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byteLevelTk := tokenizer.NewTokenizer(byteLevel)
+	bl := pretokenizer.NewByteLevel()
+	bl.SetAddPrefixSpace(false)
+	byteLevelTk.WithPreTokenizer(bl)
+	byteLevelTk.WithDecoder(bl)
+	byteLevelTk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<|endoftext|>", true)})
+
+	// Byte fallback BPE, Llama style.
+	vocab = model.Vocab{"<unk>": 0}
+	for _, tok := range byteTokens() {
+		vocab[tok] = len(vocab)
+	}
+	for _, tok := range []string{"a", "b", "c", "é", "ab", "abc", " ", "日"} {
+		vocab[tok] = len(vocab)
+	}
+	fallback, err := bpe.New(vocab, []string{"a b", "ab c"}, bpe.WithUnkToken("<unk>"), bpe.WithByteFallback(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallbackTk := tokenizer.NewTokenizer(fallback)
+	fallbackTk.WithDecoder(decoder.NewSequence([]tokenizer.Decoder{decoder.NewByteFallback(), decoder.NewFuse()}))
+
+	// Byte fallback Unigram.
+	pieces := []unigram.TokenScore{{Token: "<unk>", Score: 0}}
+	for _, tok := range byteTokens() {
+		pieces = append(pieces, unigram.TokenScore{Token: tok, Score: -10})
+	}
+	for i, tok := range []string{"a", "b", "ab", "abc", " ", "▁", "é"} {
+		pieces = append(pieces, unigram.TokenScore{Token: tok, Score: -float64(i + 1)})
+	}
+	uni, err := unigram.New(pieces, unigram.WithUnkID(0), unigram.WithByteFallback(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uniTk := tokenizer.NewTokenizer(uni)
+	uniTk.WithDecoder(decoder.NewSequence([]tokenizer.Decoder{decoder.NewByteFallback(), decoder.NewFuse()}))
+
+	// Unigram, with the Metaspace pre-tokenizer.
+	metaspaceTk, err := pretrained.FromFile(filepath.Join(compatDir, "t5-unigram", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// WordPiece, with the BERT normalizer and pre-tokenizer.
+	wordPieceTk, err := pretrained.FromFile(filepath.Join(compatDir, "bert-wordpiece", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// WordLevel.
+	wl, err := wordlevel.New(map[string]int{"[UNK]": 0, "a": 1, "b": 2, "hello": 3, "!": 4}, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wordLevelTk := tokenizer.NewTokenizer(wl)
+	wordLevelTk.WithNormalizer(normalizer.NewNFKC())
+	wordLevelTk.WithPreTokenizer(pretokenizer.NewWhitespace())
+
+	return []fuzzTokenizer{
+		{"bpe-bytelevel", byteLevelTk, true},
+		{"bpe-bytefallback", fallbackTk, true},
+		{"unigram-bytefallback", uniTk, true},
+		{"unigram-metaspace", metaspaceTk, false},
+		{"wordpiece", wordPieceTk, false},
+		{"wordlevel", wordLevelTk, false},
+	}
+}
+
+// checkInvariants checks the encoding invariants of input for ft.
+func checkInvariants(t *testing.T, ft fuzzTokenizer, input string) {
+	t.Helper()
+
+	for _, addSpecialTokens := range []bool{false, true} {
+		en, err := ft.tk.EncodeSingle(input, addSpecialTokens)
+		if err != nil {
+			t.Fatalf("%s: encode %q: %v", ft.name, input, err)
+		}
+
+		n := len(en.Ids)
+		for name, l := range map[string]int{
+			"tokens":              len(en.Tokens),
+			"offsets":             len(en.Offsets),
+			"type ids":            len(en.TypeIds),
+			"attention mask":      len(en.AttentionMask),
+			"special tokens mask": len(en.SpecialTokenMask),
+			"words":               len(en.Words),
+		} {
+			if l != n {
+				t.Fatalf("%s: %q: want %d %s, got %d", ft.name, input, n, name, l)
+			}
+		}
+
+		prevStart, prevEnd := 0, 0
+		for i, o := range en.Offsets {
+			if en.SpecialTokenMask[i] == 1 {
+				continue
+			}
+			start, end := o[0], o[1]
+			if start < 0 || start > end || end > len(input) {
+				t.Fatalf("%s: %q: token %d %q: offsets %v out of [0, %d]", ft.name, input, i, en.Tokens[i], o, len(input))
+			}
+			if start < prevStart || end < prevEnd {
+				t.Fatalf("%s: %q: token %d %q: offsets %v decrease after [%d %d]", ft.name, input, i, en.Tokens[i], o, prevStart, prevEnd)
+			}
+			prevStart, prevEnd = start, end
+		}
+
+		count, err := ft.tk.CountTokens(input, addSpecialTokens)
+		if err != nil {
+			t.Fatalf("%s: count %q: %v", ft.name, input, err)
+		}
+		if count != n {
+			t.Fatalf("%s: %q: want a count of %d tokens, got %d", ft.name, input, n, count)
+		}
+
+		decoded := ft.tk.Decode(en.Ids, false)
+		got, spans := ft.tk.DecodeWithOffsets(en.Ids)
+		if got != decoded {
+			t.Fatalf("%s: %q: want DecodeWithOffsets %q, got %q", ft.name, input, decoded, got)
+		}
+		end := 0
+		for i, span := range spans {
+			if span[0] != end || span[1] < span[0] {
+				t.Fatalf("%s: %q: id %d: span %v does not follow %d", ft.name, input, i, span, end)
+			}
+			end = span[1]
+		}
+		if end != len(got) {
+			t.Fatalf("%s: %q: want spans up to %d, got %d", ft.name, input, len(got), end)
+		}
+
+		if ft.roundTrip && !addSpecialTokens && utf8.ValidString(input) {
+			if decoded != input {
+				t.Fatalf("%s: want decode(encode(%q)) to round-trip, got %q (%q)", ft.name, input, decoded, en.Tokens)
+			}
+		}
+	}
+}
+
+// fuzzSeeds are adversarial inputs: invalid UTF-8, control chars, combining
+// marks, surrogates, spaces only and added tokens.
+var fuzzSeeds = []string{
+	"",
+	" ",
+	"   \t\n ",
+	"Hello, World!",
+	"the hello abc",
+	"abcé日",
+	"\x00",
+	"\xff\xfe",
+	"a\x80b",
+	"\xe6\x97",
+	"\xed\xa0\x80",
+	"é́",
+	"\u200b\ufeff",
+	"ﬁ ＡＢＣ ½",
+	"İ ß ǅ",
+	"😁👍🏽",
+	"\r\n ",
+	"<|endoftext|><|endoftext|",
+	"a<|endoftext|>\xff",
+}
+
+func FuzzEncode(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s)
+	}
+	tokenizers := fuzzTokenizers(f)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		for _, ft := range tokenizers {
+			checkInvariants(t, ft, input)
+		}
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte{0, 1, 2})
+	f.Add([]byte{255, 255, 0, 128})
+	tokenizers := fuzzTokenizers(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Arbitrary ids, including unknown ones, must not panic.
+		ids := make([]int, len(data))
+		for i, b := range data {
+			ids[i] = int(b)*3 - 20
+		}
+		for _, ft := range tokenizers {
+			ft.tk.Decode(ids, false)
+			ft.tk.Decode(ids, true)
+		}
+	})
+}
+
+func TestEncode_Invariants(t *testing.T) {
+	tokenizers := fuzzTokenizers(t)
+
+	property := func(input string) bool {
+		for _, ft := range tokenizers {
+			checkInvariants(t, ft, input)
+		}
+		return !t.Failed()
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+	// Random bytes, mostly invalid UTF-8.
+	bytesProperty := func(data []byte) bool { return property(string(data)) }
+	if err := quick.Check(bytesProperty, &quick.Config{MaxCount: 200}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,340 @@
+package gguf
+
+// This file reads the metadata of GGUF files, the model format of llama.cpp:
+// https://github.com/ggerganov/ggml/blob/master/docs/gguf.md
+//
+// Only the header and the metadata key-values are read, tensors are skipped.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ValueType is the type of a metadata value.
+type ValueType uint32
+
+const (
+	TypeUint8   ValueType = 0
+	TypeInt8    ValueType = 1
+	TypeUint16  ValueType = 2
+	TypeInt16   ValueType = 3
+	TypeUint32  ValueType = 4
+	TypeInt32   ValueType = 5
+	TypeFloat32 ValueType = 6
+	TypeBool    ValueType = 7
+	TypeString  ValueType = 8
+	TypeArray   ValueType = 9
+	TypeUint64  ValueType = 10
+	TypeInt64   ValueType = 11
+	TypeFloat64 ValueType = 12
+)
+
+// magic is "GGUF" read as a little-endian uint32.
+const magic = 0x46554747
+
+// maxLength caps the length of strings and arrays to fail on corrupted files.
+const maxLength = 1 << 28
+
+// chunkLength is the number of items allocated at once while reading strings
+// and arrays, so that the memory allocated for a corrupted length is bounded
+// by the data actually read.
+const chunkLength = 1 << 16
+
+// File is the metadata of a GGUF file.
+//
+// Metadata values are Go values of the matching type: uint8 to float64, bool,
+// string, or a slice of them for arrays (i.e. []string, []float32, []int32),
+// nested arrays being []interface{}.
+type File struct {
+	Version     uint32
+	TensorCount uint64
+	Metadata    map[string]interface{}
+}
+
+// ReadFile reads the metadata of a GGUF file.
+func ReadFile(file string) (*File, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(bufio.NewReaderSize(f, 1<<20))
+}
+
+// Read reads the header and metadata of GGUF data, stopping before the tensor
+// infos.
+func Read(r io.Reader) (*File, error) {
+	d := &reader{r: r, order: binary.LittleEndian}
+
+	var m uint32
+	if err := binary.Read(r, binary.LittleEndian, &m); err != nil {
+		return nil, fmt.Errorf("invalid GGUF file: %w", err)
+	}
+	if m != magic {
+		return nil, errors.New("invalid GGUF file: bad magic")
+	}
+
+	f := &File{Metadata: make(map[string]interface{})}
+	f.Version = d.uint32()
+	// Big-endian files are told by their byte-swapped version.
+	if d.err == nil && f.Version&0xFFFF == 0 {
+		d.order = binary.BigEndian
+		f.Version = f.Version>>24 | f.Version>>8&0xFF00
+	}
+	if d.err == nil && (f.Version < 2 || f.Version > 3) {
+		return nil, fmt.Errorf("invalid GGUF file: unsupported version %d", f.Version)
+	}
+	f.TensorCount = d.uint64()
+	n := d.uint64()
+
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		key := d.string()
+		typ := ValueType(d.uint32())
+		v := d.value(typ)
+		if d.err != nil {
+			return nil, fmt.Errorf("invalid GGUF file: metadata %q: %w", key, d.err)
+		}
+		f.Metadata[key] = v
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid GGUF file: %w", d.err)
+	}
+
+	return f, nil
+}
+
+// String returns the string value of the key.
+func (f *File) String(key string) (string, bool) {
+	v, ok := f.Metadata[key].(string)
+	return v, ok
+}
+
+// Strings returns the string array value of the key.
+func (f *File) Strings(key string) ([]string, bool) {
+	v, ok := f.Metadata[key].([]string)
+	return v, ok
+}
+
+// Bool returns the bool value of the key.
+func (f *File) Bool(key string) (bool, bool) {
+	v, ok := f.Metadata[key].(bool)
+	return v, ok
+}
+
+// Int returns the value of the key if it is an integer of any type.
+func (f *File) Int(key string) (int64, bool) {
+	switch v := f.Metadata[key].(type) {
+	case uint8:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case int64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// Float32s returns the float32 array value of the key.
+func (f *File) Float32s(key string) ([]float32, bool) {
+	v, ok := f.Metadata[key].([]float32)
+	return v, ok
+}
+
+// Int32s returns the value of the key if it is an array of 32 bits integers.
+func (f *File) Int32s(key string) ([]int32, bool) {
+	switch v := f.Metadata[key].(type) {
+	case []int32:
+		return v, true
+	case []uint32:
+		ints := make([]int32, len(v))
+		for i, x := range v {
+			ints[i] = int32(x)
+		}
+		return ints, true
+	default:
+		return nil, false
+	}
+}
+
+// Bytes returns the value of the key if it is an array of 8 bits integers.
+func (f *File) Bytes(key string) ([]byte, bool) {
+	switch v := f.Metadata[key].(type) {
+	case []uint8:
+		return v, true
+	case []int8:
+		b := make([]byte, len(v))
+		for i, x := range v {
+			b[i] = byte(x)
+		}
+		return b, true
+	default:
+		return nil, false
+	}
+}
+
+// reader decodes GGUF values, keeping the first error.
+type reader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	err   error
+}
+
+func (d *reader) read(v interface{}) {
+	if d.err == nil {
+		d.err = binary.Read(d.r, d.order, v)
+	}
+}
+
+func (d *reader) uint32() uint32 {
+	var v uint32
+	d.read(&v)
+	return v
+}
+
+func (d *reader) uint64() uint64 {
+	var v uint64
+	d.read(&v)
+	return v
+}
+
+func (d *reader) length() int {
+	n := d.uint64()
+	if d.err == nil && n > maxLength {
+		d.err = fmt.Errorf("length %d too large", n)
+	}
+	if d.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func (d *reader) string() string {
+	return string(readSlice[byte](d, d.length()))
+}
+
+// readSlice reads n fixed size values, growing the slice by chunks as they are
+// read.
+func readSlice[T any](d *reader, n int) []T {
+	v := make([]T, 0, min(n, chunkLength))
+	for len(v) < n && d.err == nil {
+		chunk := min(n-len(v), chunkLength)
+		v = append(v, make([]T, chunk)...)
+		d.read(v[len(v)-chunk:])
+	}
+	return v
+}
+
+func (d *reader) value(typ ValueType) interface{} {
+	switch typ {
+	case TypeUint8:
+		var v uint8
+		d.read(&v)
+		return v
+	case TypeInt8:
+		var v int8
+		d.read(&v)
+		return v
+	case TypeUint16:
+		var v uint16
+		d.read(&v)
+		return v
+	case TypeInt16:
+		var v int16
+		d.read(&v)
+		return v
+	case TypeUint32:
+		return d.uint32()
+	case TypeInt32:
+		var v int32
+		d.read(&v)
+		return v
+	case TypeFloat32:
+		var v float32
+		d.read(&v)
+		return v
+	case TypeBool:
+		var v bool
+		d.read(&v)
+		return v
+	case TypeString:
+		return d.string()
+	case TypeArray:
+		return d.array()
+	case TypeUint64:
+		return d.uint64()
+	case TypeInt64:
+		var v int64
+		d.read(&v)
+		return v
+	case TypeFloat64:
+		var v float64
+		d.read(&v)
+		return v
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unknown value type %d", typ)
+		}
+		return nil
+	}
+}
+
+func (d *reader) array() interface{} {
+	typ := ValueType(d.uint32())
+	n := d.length()
+	if d.err != nil {
+		return nil
+	}
+
+	switch typ {
+	case TypeUint8:
+		return readSlice[uint8](d, n)
+	case TypeInt8:
+		return readSlice[int8](d, n)
+	case TypeUint16:
+		return readSlice[uint16](d, n)
+	case TypeInt16:
+		return readSlice[int16](d, n)
+	case TypeUint32:
+		return readSlice[uint32](d, n)
+	case TypeInt32:
+		return readSlice[int32](d, n)
+	case TypeFloat32:
+		return readSlice[float32](d, n)
+	case TypeBool:
+		return readSlice[bool](d, n)
+	case TypeUint64:
+		return readSlice[uint64](d, n)
+	case TypeInt64:
+		return readSlice[int64](d, n)
+	case TypeFloat64:
+		return readSlice[float64](d, n)
+	case TypeString:
+		strs := make([]string, 0, min(n, chunkLength))
+		for i := 0; i < n && d.err == nil; i++ {
+			strs = append(strs, d.string())
+		}
+		return strs
+	default:
+		values := make([]interface{}, 0, min(n, chunkLength))
+		for i := 0; i < n && d.err == nil; i++ {
+			values = append(values, d.value(typ))
+		}
+		return values
+	}
+}
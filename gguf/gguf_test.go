@@ -0,0 +1,157 @@
+package gguf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// kv is a metadata key-value to encode, elemType being the type of the
+// elements of arrays.
+type kv struct {
+	key      string
+	typ      ValueType
+	elemType ValueType
+	value    interface{}
+}
+
+// encode builds GGUF data of the given metadata, without tensors.
+func encode(order binary.ByteOrder, version uint32, kvs []kv) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("GGUF")
+	binary.Write(&buf, order, version)
+	binary.Write(&buf, order, uint64(0))
+	binary.Write(&buf, order, uint64(len(kvs)))
+
+	writeString := func(s string) {
+		binary.Write(&buf, order, uint64(len(s)))
+		buf.WriteString(s)
+	}
+	for _, e := range kvs {
+		writeString(e.key)
+		binary.Write(&buf, order, uint32(e.typ))
+		switch {
+		case e.typ == TypeString:
+			writeString(e.value.(string))
+		case e.typ == TypeArray && e.elemType == TypeString:
+			strs := e.value.([]string)
+			binary.Write(&buf, order, uint32(TypeString))
+			binary.Write(&buf, order, uint64(len(strs)))
+			for _, s := range strs {
+				writeString(s)
+			}
+		case e.typ == TypeArray:
+			binary.Write(&buf, order, uint32(e.elemType))
+			binary.Write(&buf, order, uint64(reflect.ValueOf(e.value).Len()))
+			binary.Write(&buf, order, e.value)
+		default:
+			binary.Write(&buf, order, e.value)
+		}
+	}
+	// Tensor infos, never read.
+	buf.WriteString("tensors")
+
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	kvs := []kv{
+		{key: "general.architecture", typ: TypeString, value: "llama"},
+		{key: "tokenizer.ggml.tokens", typ: TypeArray, elemType: TypeString, value: []string{"<unk>", "▁a", "b"}},
+		{key: "tokenizer.ggml.scores", typ: TypeArray, elemType: TypeFloat32, value: []float32{0, -1.5, -2}},
+		{key: "tokenizer.ggml.token_type", typ: TypeArray, elemType: TypeInt32, value: []int32{2, 1, 1}},
+		{key: "tokenizer.ggml.bos_token_id", typ: TypeUint32, value: uint32(1)},
+		{key: "tokenizer.ggml.add_bos_token", typ: TypeBool, value: true},
+		{key: "general.file_type", typ: TypeInt64, value: int64(-7)},
+	}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		f, err := Read(bytes.NewReader(encode(order, 3, kvs)))
+		if err != nil {
+			t.Fatalf("%v: %v", order, err)
+		}
+		if f.Version != 3 || len(f.Metadata) != len(kvs) {
+			t.Errorf("%v: want version 3 and %d keys, got %d and %d", order, len(kvs), f.Version, len(f.Metadata))
+		}
+		if got, _ := f.String("general.architecture"); got != "llama" {
+			t.Errorf("%v: want llama, got %q", order, got)
+		}
+		if got, _ := f.Strings("tokenizer.ggml.tokens"); !reflect.DeepEqual([]string{"<unk>", "▁a", "b"}, got) {
+			t.Errorf("%v: got tokens %q", order, got)
+		}
+		if got, _ := f.Float32s("tokenizer.ggml.scores"); !reflect.DeepEqual([]float32{0, -1.5, -2}, got) {
+			t.Errorf("%v: got scores %v", order, got)
+		}
+		if got, _ := f.Int32s("tokenizer.ggml.token_type"); !reflect.DeepEqual([]int32{2, 1, 1}, got) {
+			t.Errorf("%v: got token types %v", order, got)
+		}
+		if got, ok := f.Int("tokenizer.ggml.bos_token_id"); !ok || got != 1 {
+			t.Errorf("%v: want bos id 1, got %v", order, got)
+		}
+		if got, ok := f.Int("general.file_type"); !ok || got != -7 {
+			t.Errorf("%v: want -7, got %v", order, got)
+		}
+		if got, ok := f.Bool("tokenizer.ggml.add_bos_token"); !ok || !got {
+			t.Errorf("%v: want add bos true", order)
+		}
+		if _, ok := f.Int("general.architecture"); ok {
+			t.Errorf("%v: want no int of a string value", order)
+		}
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	valid := encode(binary.LittleEndian, 3, []kv{{key: "a", typ: TypeString, value: "b"}})
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"magic", []byte("GGML\x03\x00\x00\x00"), "bad magic"},
+		{"version", encode(binary.LittleEndian, 1, nil), "unsupported version 1"},
+		{"truncated", valid[:len(valid)-len("tensors")-1], "metadata \"a\""},
+		{"type", encode(binary.LittleEndian, 3, []kv{{key: "a", typ: 99, value: uint8(0)}}), "unknown value type 99"},
+	}
+
+	for _, tt := range tests {
+		_, err := Read(bytes.NewReader(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: want error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestRead_CorruptedLength(t *testing.T) {
+	// A string and arrays declaring nearly maxLength items, followed by a few
+	// bytes only.
+	header := encode(binary.LittleEndian, 3, nil)
+	header = header[:len(header)-len("tensors")]
+	binary.LittleEndian.PutUint64(header[len(header)-8:], 1)
+	for _, value := range [][]byte{
+		binary.LittleEndian.AppendUint32(nil, uint32(TypeString)),
+		binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, uint32(TypeArray)), uint32(TypeFloat64)),
+		binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, uint32(TypeArray)), uint32(TypeString)),
+		binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, uint32(TypeArray)), uint32(TypeArray)),
+	} {
+		data := append([]byte{}, header...)
+		data = binary.LittleEndian.AppendUint64(data, 1)
+		data = append(data, 'k')
+		data = append(data, value...)
+		data = binary.LittleEndian.AppendUint64(data, maxLength-1)
+		data = append(data, "short"...)
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := Read(bytes.NewReader(data))
+		runtime.ReadMemStats(&after)
+		if err == nil || !strings.Contains(err.Error(), `metadata "k"`) {
+			t.Errorf("%v: want a metadata error, got %v", value, err)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+			t.Errorf("%v: want less than 16MiB allocated, got %d bytes", value, allocated)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+
+	"github.com/season-studio/tokenizer/util"
 )
 
 var (
@@ -18,7 +20,7 @@ func initCachePath() {
 
 	initEnv()
 
-	log.Printf("INFO: CachedDir=%q\n", CachedDir)
+	util.Logf("INFO: CachedDir=%q\n", CachedDir)
 }
 
 func initEnv() {
@@ -33,3 +35,12 @@ func initEnv() {
 		}
 	}
 }
+
+// Logger logs the informational messages of the library, see SetLogger.
+type Logger = util.Logger
+
+// SetLogger sets the logger of the informational messages of the library, i.e.
+// `log.Default()`, nil to discard them. They are discarded by default.
+func SetLogger(l Logger) {
+	util.SetLogger(l)
+}
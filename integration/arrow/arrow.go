@@ -0,0 +1,277 @@
+// Package arrow tokenizes a string column of Arrow record batches and Parquet
+// files into columnar `input_ids` and `attention_mask` lists, as a `datasets`
+// map of a tokenizer does, so that Go data-prep pipelines feed training jobs
+// without a Python tokenization step.
+//
+// It is a module of its own so that the tokenizer does not depend on Arrow.
+package arrow
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	"github.com/season-studio/tokenizer"
+)
+
+// Names of the output columns.
+const (
+	InputIdsName      = "input_ids"
+	AttentionMaskName = "attention_mask"
+	TypeIdsName       = "token_type_ids"
+)
+
+// Opts are the options of the encoding functions, see `DefaultOpts`.
+type Opts struct {
+	AddSpecialTokens bool  // whether to add the special tokens of the post-processor
+	TypeIds          bool  // whether to output the `token_type_ids` column
+	BatchSize        int64 // number of rows of the record batches read from Parquet files
+	Allocator        memory.Allocator
+	EncodeOpts       []tokenizer.EncodeOpt
+}
+
+// Option sets an option of the encoding functions.
+type Option func(o *Opts)
+
+// WithAddSpecialTokens sets whether to add the special tokens of the
+// post-processor, true by default.
+func WithAddSpecialTokens(add bool) Option {
+	return func(o *Opts) {
+		o.AddSpecialTokens = add
+	}
+}
+
+// WithTypeIds adds the `token_type_ids` column to the outputs.
+func WithTypeIds() Option {
+	return func(o *Opts) {
+		o.TypeIds = true
+	}
+}
+
+// WithBatchSize sets the number of rows of the record batches read from
+// Parquet files, 1024 by default.
+func WithBatchSize(size int64) Option {
+	return func(o *Opts) {
+		o.BatchSize = size
+	}
+}
+
+// WithAllocator sets the allocator of the output arrays,
+// `memory.DefaultAllocator` by default.
+func WithAllocator(mem memory.Allocator) Option {
+	return func(o *Opts) {
+		o.Allocator = mem
+	}
+}
+
+// WithEncodeOpts sets the options of `Tokenizer.EncodeBatch`.
+func WithEncodeOpts(opts ...tokenizer.EncodeOpt) Option {
+	return func(o *Opts) {
+		o.EncodeOpts = opts
+	}
+}
+
+// DefaultOpts returns the default options: special tokens, no `token_type_ids`
+// column, Parquet batches of 1024 rows and `memory.DefaultAllocator`.
+func DefaultOpts() *Opts {
+	return &Opts{
+		AddSpecialTokens: true,
+		BatchSize:        1024,
+		Allocator:        memory.DefaultAllocator,
+	}
+}
+
+func newOpts(opts []Option) *Opts {
+	o := DefaultOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// outputFields returns the fields of the output columns.
+func (o *Opts) outputFields() []arrow.Field {
+	names := []string{InputIdsName, AttentionMaskName}
+	if o.TypeIds {
+		names = append(names, TypeIdsName)
+	}
+
+	fields := make([]arrow.Field, len(names))
+	for i, name := range names {
+		fields[i] = arrow.Field{Name: name, Type: arrow.ListOf(arrow.PrimitiveTypes.Int32), Nullable: true}
+	}
+
+	return fields
+}
+
+// outputSchema returns the schema of the records of `EncodeRecord`: the input
+// columns followed by the output ones.
+func outputSchema(schema *arrow.Schema, column string, o *Opts) (*arrow.Schema, error) {
+	indices := schema.FieldIndices(column)
+	if len(indices) != 1 {
+		return nil, fmt.Errorf("EncodeRecord error: want one %q column, got %d", column, len(indices))
+	}
+	switch t := schema.Field(indices[0]).Type; t.ID() {
+	case arrow.STRING, arrow.LARGE_STRING:
+	default:
+		return nil, fmt.Errorf("EncodeRecord error: column %q: want a string column, got %v", column, t)
+	}
+
+	fields := schema.Fields()
+	for _, f := range o.outputFields() {
+		if schema.HasField(f.Name) {
+			return nil, fmt.Errorf("EncodeRecord error: column %q already exists", f.Name)
+		}
+		fields = append(fields, f)
+	}
+	meta := schema.Metadata()
+
+	return arrow.NewSchema(fields, &meta), nil
+}
+
+// EncodeColumn encodes the strings of col, a string or large string array, with
+// the parallel batch encoder of tk. It returns the `input_ids` and
+// `attention_mask` list<int32> arrays, followed by `token_type_ids` with
+// `WithTypeIds`. Null strings give null lists. The arrays must be released.
+func EncodeColumn(tk *tokenizer.Tokenizer, col arrow.Array, opts ...Option) ([]arrow.Array, error) {
+	o := newOpts(opts)
+
+	var value func(i int) string
+	switch a := col.(type) {
+	case *array.String:
+		value = a.Value
+	case *array.LargeString:
+		value = a.Value
+	default:
+		return nil, fmt.Errorf("EncodeColumn error: want a string array, got %v", col.DataType())
+	}
+
+	var inputs []tokenizer.EncodeInput
+	for i := 0; i < col.Len(); i++ {
+		if col.IsValid(i) {
+			inputs = append(inputs, tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(value(i))))
+		}
+	}
+	encodings, err := tk.EncodeBatch(inputs, o.AddSpecialTokens, o.EncodeOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("EncodeColumn error: %w", err)
+	}
+
+	builders := make([]*array.ListBuilder, len(o.outputFields()))
+	for i := range builders {
+		builders[i] = array.NewListBuilder(o.Allocator, arrow.PrimitiveTypes.Int32)
+		defer builders[i].Release()
+	}
+	next := 0
+	for i := 0; i < col.Len(); i++ {
+		if !col.IsValid(i) {
+			for _, b := range builders {
+				b.AppendNull()
+			}
+			continue
+		}
+		en := &encodings[next]
+		next++
+		for j, values := range [][]int{en.Ids, en.AttentionMask, en.TypeIds}[:len(builders)] {
+			appendInt32s(builders[j], values)
+		}
+	}
+
+	arrays := make([]arrow.Array, len(builders))
+	for i, b := range builders {
+		arrays[i] = b.NewArray()
+	}
+
+	return arrays, nil
+}
+
+func appendInt32s(b *array.ListBuilder, values []int) {
+	b.Append(true)
+	vb := b.ValueBuilder().(*array.Int32Builder)
+	vb.Reserve(len(values))
+	for _, v := range values {
+		vb.UnsafeAppend(int32(v))
+	}
+}
+
+// EncodeRecord encodes the given string column of rec, see `EncodeColumn`. It
+// returns a record with the columns of rec followed by the output columns,
+// which must be released.
+func EncodeRecord(tk *tokenizer.Tokenizer, rec arrow.Record, column string, opts ...Option) (arrow.Record, error) {
+	o := newOpts(opts)
+	schema, err := outputSchema(rec.Schema(), column, o)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs, err := EncodeColumn(tk, rec.Column(rec.Schema().FieldIndices(column)[0]), opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, a := range outputs {
+			a.Release()
+		}
+	}()
+
+	return array.NewRecord(schema, append(rec.Columns(), outputs...), rec.NumRows()), nil
+}
+
+// EncodeParquet encodes the given string column of the Parquet file r by
+// record batches, see `EncodeRecord`, and writes the input columns followed by
+// the output columns to w as a Parquet file. w is not closed.
+func EncodeParquet(tk *tokenizer.Tokenizer, r parquet.ReaderAtSeeker, w io.Writer, column string, opts ...Option) error {
+	o := newOpts(opts)
+
+	pr, err := file.NewParquetReader(r, file.WithReadProps(parquet.NewReaderProperties(o.Allocator)))
+	if err != nil {
+		return fmt.Errorf("EncodeParquet error: %w", err)
+	}
+	defer pr.Close()
+	fr, err := pqarrow.NewFileReader(pr, pqarrow.ArrowReadProperties{BatchSize: o.BatchSize}, o.Allocator)
+	if err != nil {
+		return fmt.Errorf("EncodeParquet error: %w", err)
+	}
+	rr, err := fr.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return fmt.Errorf("EncodeParquet error: %w", err)
+	}
+	defer rr.Release()
+
+	schema, err := outputSchema(rr.Schema(), column, o)
+	if err != nil {
+		return err
+	}
+	// The writer closes w if it is an io.Closer.
+	fw, err := pqarrow.NewFileWriter(schema, struct{ io.Writer }{w},
+		parquet.NewWriterProperties(parquet.WithAllocator(o.Allocator)), pqarrow.DefaultWriterProps())
+	if err != nil {
+		return fmt.Errorf("EncodeParquet error: %w", err)
+	}
+	defer fw.Close()
+
+	for rr.Next() {
+		rec, err := EncodeRecord(tk, rr.Record(), column, opts...)
+		if err != nil {
+			return err
+		}
+		err = fw.Write(rec)
+		rec.Release()
+		if err != nil {
+			return fmt.Errorf("EncodeParquet error: %w", err)
+		}
+	}
+	if err := rr.Err(); err != nil && err != io.EOF {
+		return fmt.Errorf("EncodeParquet error: %w", err)
+	}
+
+	return fw.Close()
+}
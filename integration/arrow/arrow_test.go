@@ -0,0 +1,161 @@
+package arrow
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+var texts = []string{"Hello world!", "", "The quick brown fox.", "hello"}
+
+func loadTokenizer(t *testing.T) *tokenizer.Tokenizer {
+	tk, err := pretrained.FromFile(filepath.Join("..", "..", "testdata", "compat", "bert-wordpiece", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tk
+}
+
+// newRecord returns a record of an id column and a text column with texts and a
+// null at index 1.
+func newRecord(mem memory.Allocator) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "text", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	for i, text := range texts {
+		b.Field(0).(*array.Int64Builder).Append(int64(i))
+		if i == 1 {
+			b.Field(1).(*array.StringBuilder).AppendNull()
+		} else {
+			b.Field(1).(*array.StringBuilder).Append(text)
+		}
+	}
+
+	return b.NewRecord()
+}
+
+// listValues returns the values of the rows of a list<int32> array, nil for
+// nulls.
+func listValues(a arrow.Array) [][]int {
+	l := a.(*array.List)
+	values := l.ListValues().(*array.Int32)
+	rows := make([][]int, l.Len())
+	for i := range rows {
+		if l.IsNull(i) {
+			continue
+		}
+		start, end := l.ValueOffsets(i)
+		rows[i] = []int{}
+		for j := start; j < end; j++ {
+			rows[i] = append(rows[i], int(values.Value(int(j))))
+		}
+	}
+
+	return rows
+}
+
+func wantRows(t *testing.T, tk *tokenizer.Tokenizer) (ids, mask, typeIds [][]int) {
+	ids, mask, typeIds = make([][]int, len(texts)), make([][]int, len(texts)), make([][]int, len(texts))
+	for i, text := range texts {
+		if i == 1 {
+			continue
+		}
+		en, err := tk.EncodeSingle(text, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i], mask[i], typeIds[i] = en.Ids, en.AttentionMask, en.TypeIds
+	}
+
+	return ids, mask, typeIds
+}
+
+func TestEncodeRecord(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	tk := loadTokenizer(t)
+
+	rec := newRecord(mem)
+	defer rec.Release()
+	out, err := EncodeRecord(tk, rec, "text", WithTypeIds(), WithAllocator(mem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+
+	var names []string
+	for _, f := range out.Schema().Fields() {
+		names = append(names, f.Name)
+	}
+	if want := []string{"id", "text", InputIdsName, AttentionMaskName, TypeIdsName}; !reflect.DeepEqual(want, names) {
+		t.Errorf("want columns %q, got %q", want, names)
+	}
+
+	ids, mask, typeIds := wantRows(t, tk)
+	for i, want := range [][][]int{ids, mask, typeIds} {
+		if got := listValues(out.Column(2 + i)); !reflect.DeepEqual(want, got) {
+			t.Errorf("%v: want %v, got %v", out.ColumnName(2+i), want, got)
+		}
+	}
+
+	if _, err := EncodeRecord(tk, rec, "id"); err == nil {
+		t.Error("want error for a non-string column")
+	}
+	if _, err := EncodeRecord(tk, rec, "missing"); err == nil {
+		t.Error("want error for a missing column")
+	}
+}
+
+func TestEncodeParquet(t *testing.T) {
+	mem := memory.DefaultAllocator
+	tk := loadTokenizer(t)
+
+	rec := newRecord(mem)
+	defer rec.Release()
+	tbl := array.NewTableFromRecords(rec.Schema(), []arrow.Record{rec})
+	defer tbl.Release()
+	var in bytes.Buffer
+	if err := pqarrow.WriteTable(tbl, &in, 2, nil, pqarrow.DefaultWriterProps()); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := EncodeParquet(tk, bytes.NewReader(in.Bytes()), &out, "text", WithBatchSize(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(out.Bytes()), parquet.NewReaderProperties(mem), pqarrow.ArrowReadProperties{}, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Release()
+	if got.NumRows() != int64(len(texts)) || got.NumCols() != 4 {
+		t.Fatalf("want %d rows of 4 columns, got %d rows of %d", len(texts), got.NumRows(), got.NumCols())
+	}
+
+	ids, mask, _ := wantRows(t, tk)
+	for i, want := range [][][]int{ids, mask} {
+		var rows [][]int
+		for _, chunk := range got.Column(2 + i).Data().Chunks() {
+			rows = append(rows, listValues(chunk)...)
+		}
+		if !reflect.DeepEqual(want, rows) {
+			t.Errorf("%v: want %v, got %v", got.Schema().Field(2+i).Name, want, rows)
+		}
+	}
+}
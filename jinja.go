@@ -0,0 +1,2540 @@
+package tokenizer
+
+// This file implements the subset of the Jinja template language used by chat
+// templates: `{{ }}` expressions, `{% if %}`, `{% for %}`, `{% set %}`,
+// `{% break %}`/`{% continue %}`, comments, filters, tests and whitespace
+// control. Templates are rendered like transformers does, with `trim_blocks`
+// and `lstrip_blocks` enabled.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Template source items.
+type jitemKind int
+
+const (
+	jitemText jitemKind = iota
+	jitemExpr
+	jitemStmt
+)
+
+type jitem struct {
+	kind  jitemKind
+	value string
+	line  int
+}
+
+// lexJinjaTemplate splits a template source into text, expression and
+// statement items, applying whitespace control. Comments are dropped.
+func lexJinjaTemplate(src string) ([]jitem, error) {
+	var items []jitem
+	var (
+		pos          int
+		stripNext    bool // previous tag ended with `-`
+		trimNewline  bool // previous tag was a block, see `trim_blocks`
+		line         = 1
+		lineOf       = func(i int) int { return line + strings.Count(src[pos:i], "\n") }
+		isWhitespace = func(r rune) bool { return unicode.IsSpace(r) }
+	)
+
+	for {
+		start := nextJinjaTag(src, pos)
+		end := start
+		if start < 0 {
+			end = len(src)
+		}
+
+		text := src[pos:end]
+		switch {
+		case stripNext:
+			text = strings.TrimLeftFunc(text, isWhitespace)
+		case trimNewline:
+			if strings.HasPrefix(text, "\r\n") {
+				text = text[2:]
+			} else if strings.HasPrefix(text, "\n") {
+				text = text[1:]
+			}
+		}
+
+		if start < 0 {
+			items = append(items, jitem{kind: jitemText, value: text, line: line})
+			return items, nil
+		}
+
+		kind := src[start+1]
+		inner := start + 2
+		switch {
+		case inner < len(src) && src[inner] == '-':
+			text = strings.TrimRightFunc(text, isWhitespace)
+			inner++
+		case inner < len(src) && src[inner] == '+':
+			inner++
+		case kind != '{':
+			// lstrip_blocks: strip the whitespace between the start of the line
+			// and a block.
+			lineStart := strings.LastIndexByte(src[:start], '\n') + 1
+			if lineStart >= pos && strings.Trim(src[lineStart:start], " \t") == "" {
+				text = text[:len(text)-min(len(text), start-lineStart)]
+			}
+		}
+		items = append(items, jitem{kind: jitemText, value: text, line: line})
+
+		tagLine := lineOf(start)
+		closing := map[byte]string{'{': "}}", '%': "%}", '#': "#}"}[kind]
+		stop := closeJinjaTag(src, inner, closing)
+		if stop < 0 {
+			return nil, fmt.Errorf("line %d: unclosed tag, want %q", tagLine, closing)
+		}
+		content := src[inner:stop]
+		stripNext, trimNewline = false, kind != '{'
+		switch {
+		case strings.HasSuffix(content, "-"):
+			content = content[:len(content)-1]
+			stripNext = true
+		case strings.HasSuffix(content, "+"):
+			content = content[:len(content)-1]
+			trimNewline = false
+		}
+
+		switch kind {
+		case '{':
+			items = append(items, jitem{kind: jitemExpr, value: content, line: tagLine})
+		case '%':
+			items = append(items, jitem{kind: jitemStmt, value: content, line: tagLine})
+		}
+
+		line = lineOf(stop)
+		pos = stop + len(closing)
+	}
+}
+
+// nextJinjaTag returns the index of the next `{{`, `{%` or `{#` of src from
+// pos, -1 if none.
+func nextJinjaTag(src string, pos int) int {
+	for i := pos; i < len(src)-1; i++ {
+		if src[i] == '{' && (src[i+1] == '{' || src[i+1] == '%' || src[i+1] == '#') {
+			return i
+		}
+	}
+	return -1
+}
+
+// closeJinjaTag returns the index of the closing delimiter of a tag starting
+// at pos, skipping string literals of expressions.
+func closeJinjaTag(src string, pos int, closing string) int {
+	if closing == "#}" {
+		if i := strings.Index(src[pos:], closing); i >= 0 {
+			return pos + i
+		}
+		return -1
+	}
+
+	var quote byte
+	for i := pos; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case strings.HasPrefix(src[i:], closing):
+			// `-}}` and `+%}` are whitespace control of the closing delimiter.
+			return i
+		}
+	}
+	return -1
+}
+
+// Expression tokens.
+type jtokKind int
+
+const (
+	jtokEOF jtokKind = iota
+	jtokName
+	jtokString
+	jtokInt
+	jtokFloat
+	jtokOp
+)
+
+type jtok struct {
+	kind jtokKind
+	val  string
+}
+
+var jinjaOps = []string{"==", "!=", "<=", ">=", "//", "**", "+", "-", "*", "/", "%", "~", "(", ")", "[", "]", "{", "}", ".", ":", ",", "|", "=", "<", ">"}
+
+func lexJinjaExpr(src string) ([]jtok, error) {
+	var toks []jtok
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || isASCIILetter(c):
+			j := i
+			for j < len(src) && (src[j] == '_' || isASCIILetter(src[j]) || isASCIIDigit(src[j])) {
+				j++
+			}
+			toks = append(toks, jtok{jtokName, src[i:j]})
+			i = j
+		case isASCIIDigit(c):
+			j := i
+			for j < len(src) && isASCIIDigit(src[j]) {
+				j++
+			}
+			kind := jtokInt
+			if j+1 < len(src) && src[j] == '.' && isASCIIDigit(src[j+1]) {
+				kind = jtokFloat
+				for j++; j < len(src) && isASCIIDigit(src[j]); j++ {
+				}
+			}
+			toks = append(toks, jtok{kind, src[i:j]})
+			i = j
+		case c == '\'' || c == '"':
+			s, n, err := unquoteJinja(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, jtok{jtokString, s})
+			i += n
+		default:
+			var op string
+			for _, o := range jinjaOps {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected char %q", c)
+			}
+			toks = append(toks, jtok{jtokOp, op})
+			i += len(op)
+		}
+	}
+
+	return append(toks, jtok{kind: jtokEOF}), nil
+}
+
+func isASCIILetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isASCIIDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// unquoteJinja reads the string literal at the start of s. It returns its value
+// and its length in s.
+func unquoteJinja(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '\'', '"':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+// Template nodes.
+type jnode interface {
+	render(ctx *jcontext, w *strings.Builder) error
+}
+
+type jtextNode struct{ text string }
+
+type joutputNode struct{ expr jexpr }
+
+type jifNode struct {
+	conds    []jexpr
+	bodies   [][]jnode
+	elseBody []jnode
+}
+
+type jforNode struct {
+	vars     []string
+	iter     jexpr
+	filter   jexpr // optional
+	body     []jnode
+	elseBody []jnode
+}
+
+type jsetNode struct {
+	name string
+	attr string  // optional - `set ns.attr = ...`
+	expr jexpr   // nil for block assignments
+	body []jnode // block assignment
+}
+
+type jloopControlNode struct{ keyword string }
+
+var (
+	errJinjaBreak    = errors.New("break outside of a loop")
+	errJinjaContinue = errors.New("continue outside of a loop")
+)
+
+// jtemplateParser builds the template nodes from its items.
+type jtemplateParser struct {
+	items []jitem
+	pos   int
+}
+
+func parseJinjaTemplate(src string) ([]jnode, error) {
+	items, err := lexJinjaTemplate(src)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := &jtemplateParser{items: items}
+	nodes, _, _, err := tp.parseBody()
+	return nodes, err
+}
+
+// parseBody parses nodes up to a statement starting with one of the `ends`
+// keywords. It returns the keyword and the expression parser positioned after
+// it.
+func (tp *jtemplateParser) parseBody(ends ...string) ([]jnode, string, *jexprParser, error) {
+	var nodes []jnode
+	for tp.pos < len(tp.items) {
+		item := tp.items[tp.pos]
+		tp.pos++
+
+		switch item.kind {
+		case jitemText:
+			if item.value != "" {
+				nodes = append(nodes, &jtextNode{item.value})
+			}
+			continue
+		}
+
+		p, err := newJexprParser(item)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		if item.kind == jitemExpr {
+			expr, err := p.parseExpr()
+			if err == nil {
+				err = p.expectEnd()
+			}
+			if err != nil {
+				return nil, "", nil, err
+			}
+			nodes = append(nodes, &joutputNode{expr})
+			continue
+		}
+
+		keyword := p.next()
+		if keyword.kind != jtokName {
+			return nil, "", nil, p.errorf("want a statement, got %q", keyword.val)
+		}
+		for _, end := range ends {
+			if keyword.val == end {
+				return nodes, end, p, nil
+			}
+		}
+
+		var node jnode
+		switch keyword.val {
+		case "if":
+			node, err = tp.parseIf(p)
+		case "for":
+			node, err = tp.parseFor(p)
+		case "set":
+			node, err = tp.parseSet(p)
+		case "break", "continue":
+			node, err = &jloopControlNode{keyword.val}, p.expectEnd()
+		default:
+			err = p.errorf("unexpected statement %q", keyword.val)
+		}
+		if err != nil {
+			return nil, "", nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(ends) > 0 {
+		line := 0
+		if len(tp.items) > 0 {
+			line = tp.items[len(tp.items)-1].line
+		}
+		return nil, "", nil, fmt.Errorf("line %d: unexpected end of template, want %q", line, ends[len(ends)-1])
+	}
+
+	return nodes, "", nil, nil
+}
+
+func (tp *jtemplateParser) parseIf(p *jexprParser) (jnode, error) {
+	node := &jifNode{}
+	for {
+		cond, err := p.parseExpr()
+		if err == nil {
+			err = p.expectEnd()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		body, end, next, err := tp.parseBody("elif", "else", "endif")
+		if err != nil {
+			return nil, err
+		}
+		node.conds = append(node.conds, cond)
+		node.bodies = append(node.bodies, body)
+
+		switch end {
+		case "elif":
+			p = next
+		case "else":
+			if err := next.expectEnd(); err != nil {
+				return nil, err
+			}
+			node.elseBody, _, next, err = tp.parseBody("endif")
+			if err != nil {
+				return nil, err
+			}
+			return node, next.expectEnd()
+		default:
+			return node, next.expectEnd()
+		}
+	}
+}
+
+func (tp *jtemplateParser) parseFor(p *jexprParser) (jnode, error) {
+	node := &jforNode{}
+	for {
+		tok := p.next()
+		if tok.kind != jtokName {
+			return nil, p.errorf("want a loop variable, got %q", tok.val)
+		}
+		node.vars = append(node.vars, tok.val)
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	if !p.acceptName("in") {
+		return nil, p.errorf("want \"in\", got %q", p.peek().val)
+	}
+
+	var err error
+	// The condition of a `for ... if` is a loop filter, not a ternary.
+	if node.iter, err = p.parseOr(); err != nil {
+		return nil, err
+	}
+	if p.acceptName("if") {
+		if node.filter, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectEnd(); err != nil {
+		return nil, err
+	}
+
+	body, end, next, err := tp.parseBody("else", "endfor")
+	if err != nil {
+		return nil, err
+	}
+	node.body = body
+	if end == "else" {
+		if err := next.expectEnd(); err != nil {
+			return nil, err
+		}
+		if node.elseBody, _, next, err = tp.parseBody("endfor"); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, next.expectEnd()
+}
+
+func (tp *jtemplateParser) parseSet(p *jexprParser) (jnode, error) {
+	tok := p.next()
+	if tok.kind != jtokName {
+		return nil, p.errorf("want a variable name, got %q", tok.val)
+	}
+	node := &jsetNode{name: tok.val}
+	if p.acceptOp(".") {
+		attr := p.next()
+		if attr.kind != jtokName {
+			return nil, p.errorf("want an attribute name, got %q", attr.val)
+		}
+		node.attr = attr.val
+	}
+
+	if !p.acceptOp("=") {
+		// Block assignment: `{% set name %}...{% endset %}`.
+		if err := p.expectEnd(); err != nil {
+			return nil, err
+		}
+		body, _, next, err := tp.parseBody("endset")
+		if err != nil {
+			return nil, err
+		}
+		node.body = body
+		return node, next.expectEnd()
+	}
+
+	var err error
+	if node.expr, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+
+	return node, p.expectEnd()
+}
+
+// Expressions.
+type jexpr interface {
+	eval(ctx *jcontext) (interface{}, error)
+}
+
+type jliteral struct{ value interface{} }
+
+type jname struct{ name string }
+
+type jlist struct{ items []jexpr }
+
+type jdict struct{ keys, values []jexpr }
+
+type jattr struct {
+	obj  jexpr
+	name string
+}
+
+type jindex struct{ obj, index jexpr }
+
+type jslice struct{ obj, start, stop, step jexpr }
+
+type jargs struct {
+	args   []jexpr
+	kwargs map[string]jexpr
+}
+
+type jcall struct {
+	fn jexpr
+	jargs
+}
+
+type jfilter struct {
+	obj  jexpr
+	name string
+	jargs
+}
+
+type jtest struct {
+	obj    jexpr
+	name   string
+	negate bool
+	jargs
+}
+
+type jbinary struct {
+	op          string
+	left, right jexpr
+}
+
+// jchain is a chain of comparisons `a < b < c`, true if all of them are, as
+// `a < b and b < c` with b evaluated once.
+type jchain struct {
+	ops      []string
+	operands []jexpr
+}
+
+type jnot struct{ x jexpr }
+
+type jneg struct{ x jexpr }
+
+type jcond struct{ cond, then, els jexpr }
+
+// jexprParser is a recursive descent parser of the expression of a tag, with
+// the operator precedence of Jinja.
+type jexprParser struct {
+	toks []jtok
+	pos  int
+	line int
+}
+
+func newJexprParser(item jitem) (*jexprParser, error) {
+	toks, err := lexJinjaExpr(item.value)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", item.line, err)
+	}
+	return &jexprParser{toks: toks, line: item.line}, nil
+}
+
+func (p *jexprParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, a...))
+}
+
+func (p *jexprParser) peek() jtok { return p.toks[p.pos] }
+
+func (p *jexprParser) next() jtok {
+	tok := p.toks[p.pos]
+	if tok.kind != jtokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *jexprParser) isOp(op string) bool {
+	tok := p.peek()
+	return tok.kind == jtokOp && tok.val == op
+}
+
+func (p *jexprParser) isName(name string) bool {
+	tok := p.peek()
+	return tok.kind == jtokName && tok.val == name
+}
+
+func (p *jexprParser) acceptOp(op string) bool {
+	if p.isOp(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *jexprParser) acceptName(name string) bool {
+	if p.isName(name) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *jexprParser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return p.errorf("want %q, got %q", op, p.peek().val)
+	}
+	return nil
+}
+
+func (p *jexprParser) expectEnd() error {
+	if tok := p.peek(); tok.kind != jtokEOF {
+		return p.errorf("unexpected %q", tok.val)
+	}
+	return nil
+}
+
+func (p *jexprParser) parseExpr() (jexpr, error) {
+	x, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptName("if") {
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		var els jexpr
+		if p.acceptName("else") {
+			if els, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		x = &jcond{cond: cond, then: x, els: els}
+	}
+	return x, nil
+}
+
+func (p *jexprParser) parseOr() (jexpr, error) {
+	return p.parseBinary(p.parseAnd, func() string {
+		if p.acceptName("or") {
+			return "or"
+		}
+		return ""
+	})
+}
+
+func (p *jexprParser) parseAnd() (jexpr, error) {
+	return p.parseBinary(p.parseNot, func() string {
+		if p.acceptName("and") {
+			return "and"
+		}
+		return ""
+	})
+}
+
+func (p *jexprParser) parseNot() (jexpr, error) {
+	if p.acceptName("not") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &jnot{x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *jexprParser) parseCompare() (jexpr, error) {
+	x, err := p.parseMath1()
+	if err != nil {
+		return nil, err
+	}
+	chain := &jchain{operands: []jexpr{x}}
+	for {
+		op := p.acceptCompareOp()
+		if op == "" {
+			break
+		}
+		right, err := p.parseMath1()
+		if err != nil {
+			return nil, err
+		}
+		chain.ops = append(chain.ops, op)
+		chain.operands = append(chain.operands, right)
+	}
+
+	switch len(chain.ops) {
+	case 0:
+		return x, nil
+	case 1:
+		return &jbinary{op: chain.ops[0], left: x, right: chain.operands[1]}, nil
+	}
+	return chain, nil
+}
+
+func (p *jexprParser) acceptCompareOp() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.acceptOp(op) {
+			return op
+		}
+	}
+	if p.acceptName("in") {
+		return "in"
+	}
+	if p.isName("not") && p.toks[p.pos+1].kind == jtokName && p.toks[p.pos+1].val == "in" {
+		p.pos += 2
+		return "not in"
+	}
+	return ""
+}
+
+func (p *jexprParser) parseMath1() (jexpr, error) {
+	return p.parseBinary(p.parseConcat, p.acceptOps("+", "-"))
+}
+
+func (p *jexprParser) parseConcat() (jexpr, error) {
+	return p.parseBinary(p.parseMath2, p.acceptOps("~"))
+}
+
+func (p *jexprParser) parseMath2() (jexpr, error) {
+	return p.parseBinary(p.parsePow, p.acceptOps("*", "/", "//", "%"))
+}
+
+func (p *jexprParser) parsePow() (jexpr, error) {
+	return p.parseBinary(p.parseUnary, p.acceptOps("**"))
+}
+
+func (p *jexprParser) acceptOps(ops ...string) func() string {
+	return func() string {
+		for _, op := range ops {
+			if p.acceptOp(op) {
+				return op
+			}
+		}
+		return ""
+	}
+}
+
+// parseBinary parses left-associative binary operations of operands parsed by
+// `operand` with operators accepted by `operator`.
+func (p *jexprParser) parseBinary(operand func() (jexpr, error), operator func() string) (jexpr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := operator()
+		if op == "" {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &jbinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *jexprParser) parseUnary() (jexpr, error) {
+	switch {
+	case p.acceptOp("-"):
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &jneg{x}, nil
+	case p.acceptOp("+"):
+		return p.parseUnary()
+	}
+
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if x, err = p.parsePostfix(x); err != nil {
+		return nil, err
+	}
+	return p.parseFilters(x)
+}
+
+func (p *jexprParser) parsePrimary() (jexpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case jtokName:
+		switch tok.val {
+		case "true", "True":
+			return &jliteral{true}, nil
+		case "false", "False":
+			return &jliteral{false}, nil
+		case "none", "None":
+			return &jliteral{nil}, nil
+		}
+		return &jname{tok.val}, nil
+	case jtokString:
+		// Adjacent string literals are concatenated.
+		s := tok.val
+		for p.peek().kind == jtokString {
+			s += p.next().val
+		}
+		return &jliteral{s}, nil
+	case jtokInt:
+		v, err := strconv.Atoi(tok.val)
+		if err != nil {
+			return nil, p.errorf("invalid integer %q", tok.val)
+		}
+		return &jliteral{v}, nil
+	case jtokFloat:
+		v, err := strconv.ParseFloat(tok.val, 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %q", tok.val)
+		}
+		return &jliteral{v}, nil
+	case jtokOp:
+		switch tok.val {
+		case "(":
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if p.isOp(",") {
+				// Tuples are rendered as lists.
+				items := []jexpr{x}
+				for p.acceptOp(",") && !p.isOp(")") {
+					item, err := p.parseExpr()
+					if err != nil {
+						return nil, err
+					}
+					items = append(items, item)
+				}
+				x = &jlist{items}
+			}
+			return x, p.expectOp(")")
+		case "[":
+			list := &jlist{}
+			for !p.acceptOp("]") {
+				if len(list.items) > 0 {
+					if err := p.expectOp(","); err != nil {
+						return nil, err
+					}
+					if p.acceptOp("]") {
+						break
+					}
+				}
+				item, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+			}
+			return list, nil
+		case "{":
+			dict := &jdict{}
+			for !p.acceptOp("}") {
+				if len(dict.keys) > 0 {
+					if err := p.expectOp(","); err != nil {
+						return nil, err
+					}
+					if p.acceptOp("}") {
+						break
+					}
+				}
+				key, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectOp(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				dict.keys = append(dict.keys, key)
+				dict.values = append(dict.values, value)
+			}
+			return dict, nil
+		}
+	case jtokEOF:
+		return nil, p.errorf("unexpected end of expression")
+	}
+
+	return nil, p.errorf("unexpected %q", tok.val)
+}
+
+func (p *jexprParser) parsePostfix(x jexpr) (jexpr, error) {
+	for {
+		switch {
+		case p.acceptOp("."):
+			tok := p.next()
+			switch tok.kind {
+			case jtokName:
+				x = &jattr{obj: x, name: tok.val}
+			case jtokInt:
+				i, _ := strconv.Atoi(tok.val)
+				x = &jindex{obj: x, index: &jliteral{i}}
+			default:
+				return nil, p.errorf("want an attribute name, got %q", tok.val)
+			}
+		case p.acceptOp("["):
+			var err error
+			if x, err = p.parseSubscript(x); err != nil {
+				return nil, err
+			}
+		case p.acceptOp("("):
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			x = &jcall{fn: x, jargs: args}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *jexprParser) parseSubscript(x jexpr) (jexpr, error) {
+	var parts [3]jexpr
+	isSlice := false
+	for i := 0; i < 3; i++ {
+		if !p.isOp(":") && !p.isOp("]") {
+			part, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = part
+		}
+		if !p.acceptOp(":") {
+			break
+		}
+		isSlice = true
+	}
+	if err := p.expectOp("]"); err != nil {
+		return nil, err
+	}
+
+	if !isSlice {
+		if parts[0] == nil {
+			return nil, p.errorf("empty subscript")
+		}
+		return &jindex{obj: x, index: parts[0]}, nil
+	}
+	return &jslice{obj: x, start: parts[0], stop: parts[1], step: parts[2]}, nil
+}
+
+// parseArgs parses call arguments after the opening parenthesis.
+func (p *jexprParser) parseArgs() (jargs, error) {
+	var args jargs
+	for !p.acceptOp(")") {
+		if len(args.args)+len(args.kwargs) > 0 {
+			if err := p.expectOp(","); err != nil {
+				return args, err
+			}
+			if p.acceptOp(")") {
+				break
+			}
+		}
+
+		if tok := p.peek(); tok.kind == jtokName && p.toks[p.pos+1].kind == jtokOp && p.toks[p.pos+1].val == "=" {
+			p.pos += 2
+			value, err := p.parseExpr()
+			if err != nil {
+				return args, err
+			}
+			if args.kwargs == nil {
+				args.kwargs = make(map[string]jexpr)
+			}
+			args.kwargs[tok.val] = value
+			continue
+		}
+
+		arg, err := p.parseExpr()
+		if err != nil {
+			return args, err
+		}
+		args.args = append(args.args, arg)
+	}
+	return args, nil
+}
+
+func (p *jexprParser) parseFilters(x jexpr) (jexpr, error) {
+	for {
+		switch {
+		case p.acceptOp("|"):
+			name := p.next()
+			if name.kind != jtokName {
+				return nil, p.errorf("want a filter name, got %q", name.val)
+			}
+			filter := &jfilter{obj: x, name: name.val}
+			if p.acceptOp("(") {
+				args, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				filter.jargs = args
+			}
+			x = filter
+		case p.acceptName("is"):
+			test := &jtest{obj: x, negate: p.acceptName("not")}
+			name := p.next()
+			if name.kind != jtokName {
+				return nil, p.errorf("want a test name, got %q", name.val)
+			}
+			test.name = name.val
+			switch tok := p.peek(); {
+			case p.acceptOp("("):
+				args, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				test.jargs = args
+			case tok.kind == jtokString || tok.kind == jtokInt || tok.kind == jtokFloat:
+				// i.e. `x is divisibleby 3`
+				arg, err := p.parsePrimary()
+				if err != nil {
+					return nil, err
+				}
+				test.args = []jexpr{arg}
+			}
+			x = test
+		default:
+			return x, nil
+		}
+	}
+}
+
+// Values. Templates handle nil, bool, int, float64, string, []interface{},
+// map[string]interface{}, jnamespace, jundefined and callables.
+
+// jundefined is the value of missing variables and attributes. It renders as
+// an empty string and is falsy.
+type jundefined struct{ name string }
+
+// jnamespace is the mutable object created by `namespace()`.
+type jnamespace map[string]interface{}
+
+// jbuiltin is a function callable from a template with keyword arguments.
+type jbuiltin func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+type jcontext struct {
+	scopes []map[string]interface{}
+}
+
+func newJcontext(vars ...map[string]interface{}) *jcontext {
+	ctx := &jcontext{scopes: []map[string]interface{}{jinjaGlobals()}}
+	for _, v := range vars {
+		ctx.scopes = append(ctx.scopes, v)
+	}
+	ctx.push()
+	return ctx
+}
+
+func (c *jcontext) push() { c.scopes = append(c.scopes, make(map[string]interface{})) }
+func (c *jcontext) pop()  { c.scopes = c.scopes[:len(c.scopes)-1] }
+
+func (c *jcontext) lookup(name string) interface{} {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if v, ok := c.scopes[i][name]; ok {
+			return v
+		}
+	}
+	return jundefined{name}
+}
+
+func (c *jcontext) set(name string, value interface{}) {
+	c.scopes[len(c.scopes)-1][name] = value
+}
+
+func jinjaGlobals() map[string]interface{} {
+	return map[string]interface{}{
+		"raise_exception": jbuiltin(func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			msg := "raise_exception"
+			if len(args) > 0 {
+				msg = jstr(args[0])
+			}
+			return nil, errors.New(msg)
+		}),
+		"range": jbuiltin(func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			var bounds []int
+			for _, a := range args {
+				n, ok := a.(int)
+				if !ok {
+					return nil, fmt.Errorf("range() wants integers, got %s", jtypeName(a))
+				}
+				bounds = append(bounds, n)
+			}
+			start, stop, step := 0, 0, 1
+			switch len(bounds) {
+			case 1:
+				stop = bounds[0]
+			case 2:
+				start, stop = bounds[0], bounds[1]
+			case 3:
+				start, stop, step = bounds[0], bounds[1], bounds[2]
+			default:
+				return nil, fmt.Errorf("range() wants 1 to 3 arguments, got %d", len(bounds))
+			}
+			if step == 0 {
+				return nil, fmt.Errorf("range() step must not be zero")
+			}
+			if n := rangeLen(start, stop, step); n > maxRange {
+				return nil, fmt.Errorf("range() of %d items exceeds the limit of %d", n, maxRange)
+			}
+			var list []interface{}
+			for i := start; step > 0 && i < stop || step < 0 && i > stop; i += step {
+				list = append(list, i)
+			}
+			return list, nil
+		}),
+		"namespace": jbuiltin(func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			ns := make(jnamespace)
+			for _, a := range args {
+				if m, ok := a.(map[string]interface{}); ok {
+					for k, v := range m {
+						ns[k] = v
+					}
+				}
+			}
+			for k, v := range kwargs {
+				ns[k] = v
+			}
+			return ns, nil
+		}),
+	}
+}
+
+func renderJinja(nodes []jnode, ctx *jcontext) (string, error) {
+	var w strings.Builder
+	if err := renderJinjaNodes(nodes, ctx, &w); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+func renderJinjaNodes(nodes []jnode, ctx *jcontext, w *strings.Builder) error {
+	for _, n := range nodes {
+		if err := n.render(ctx, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *jtextNode) render(_ *jcontext, w *strings.Builder) error {
+	w.WriteString(n.text)
+	return nil
+}
+
+func (n *joutputNode) render(ctx *jcontext, w *strings.Builder) error {
+	v, err := n.expr.eval(ctx)
+	if err != nil {
+		return err
+	}
+	w.WriteString(jstr(v))
+	return nil
+}
+
+func (n *jifNode) render(ctx *jcontext, w *strings.Builder) error {
+	for i, cond := range n.conds {
+		v, err := cond.eval(ctx)
+		if err != nil {
+			return err
+		}
+		if jtruthy(v) {
+			return renderJinjaNodes(n.bodies[i], ctx, w)
+		}
+	}
+	return renderJinjaNodes(n.elseBody, ctx, w)
+}
+
+func (n *jforNode) render(ctx *jcontext, w *strings.Builder) error {
+	v, err := n.iter.eval(ctx)
+	if err != nil {
+		return err
+	}
+	items, err := jiterate(v)
+	if err != nil {
+		return err
+	}
+
+	if n.filter != nil {
+		var filtered []interface{}
+		for _, item := range items {
+			ctx.push()
+			err := n.bindVars(ctx, item)
+			var keep interface{}
+			if err == nil {
+				keep, err = n.filter.eval(ctx)
+			}
+			ctx.pop()
+			if err != nil {
+				return err
+			}
+			if jtruthy(keep) {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	if len(items) == 0 {
+		return renderJinjaNodes(n.elseBody, ctx, w)
+	}
+
+	for i, item := range items {
+		loop := map[string]interface{}{
+			"index":     i + 1,
+			"index0":    i,
+			"revindex":  len(items) - i,
+			"revindex0": len(items) - i - 1,
+			"first":     i == 0,
+			"last":      i == len(items)-1,
+			"length":    len(items),
+			"previtem":  jundefined{"previtem"},
+			"nextitem":  jundefined{"nextitem"},
+		}
+		if i > 0 {
+			loop["previtem"] = items[i-1]
+		}
+		if i < len(items)-1 {
+			loop["nextitem"] = items[i+1]
+		}
+
+		ctx.push()
+		ctx.set("loop", loop)
+		err := n.bindVars(ctx, item)
+		if err == nil {
+			err = renderJinjaNodes(n.body, ctx, w)
+		}
+		ctx.pop()
+
+		switch err {
+		case nil, errJinjaContinue:
+		case errJinjaBreak:
+			return nil
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *jforNode) bindVars(ctx *jcontext, item interface{}) error {
+	if len(n.vars) == 1 {
+		ctx.set(n.vars[0], item)
+		return nil
+	}
+	values, ok := item.([]interface{})
+	if !ok || len(values) != len(n.vars) {
+		return fmt.Errorf("cannot unpack %s into %d loop variables", jtypeName(item), len(n.vars))
+	}
+	for i, name := range n.vars {
+		ctx.set(name, values[i])
+	}
+	return nil
+}
+
+func (n *jsetNode) render(ctx *jcontext, _ *strings.Builder) error {
+	var value interface{}
+	if n.expr != nil {
+		var err error
+		if value, err = n.expr.eval(ctx); err != nil {
+			return err
+		}
+	} else {
+		var w strings.Builder
+		if err := renderJinjaNodes(n.body, ctx, &w); err != nil {
+			return err
+		}
+		value = w.String()
+	}
+
+	if n.attr == "" {
+		ctx.set(n.name, value)
+		return nil
+	}
+	ns, ok := ctx.lookup(n.name).(jnamespace)
+	if !ok {
+		return fmt.Errorf("cannot set attribute %q of %s, want a namespace", n.attr, jtypeName(ctx.lookup(n.name)))
+	}
+	ns[n.attr] = value
+	return nil
+}
+
+func (n *jloopControlNode) render(_ *jcontext, _ *strings.Builder) error {
+	if n.keyword == "break" {
+		return errJinjaBreak
+	}
+	return errJinjaContinue
+}
+
+func (e *jliteral) eval(_ *jcontext) (interface{}, error) { return e.value, nil }
+
+func (e *jname) eval(ctx *jcontext) (interface{}, error) { return ctx.lookup(e.name), nil }
+
+func (e *jlist) eval(ctx *jcontext) (interface{}, error) {
+	list := make([]interface{}, 0, len(e.items))
+	for _, item := range e.items {
+		v, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (e *jdict) eval(ctx *jcontext) (interface{}, error) {
+	dict := make(map[string]interface{}, len(e.keys))
+	for i, key := range e.keys {
+		k, err := key.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		v, err := e.values[i].eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		dict[jstr(k)] = v
+	}
+	return dict, nil
+}
+
+func (e *jattr) eval(ctx *jcontext) (interface{}, error) {
+	obj, err := e.obj.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return jgetattr(obj, e.name)
+}
+
+func jgetattr(obj interface{}, name string) (interface{}, error) {
+	switch o := obj.(type) {
+	case jundefined:
+		return nil, fmt.Errorf("%q is undefined", o.name)
+	case map[string]interface{}:
+		if v, ok := o[name]; ok {
+			return v, nil
+		}
+	case jnamespace:
+		if v, ok := o[name]; ok {
+			return v, nil
+		}
+	}
+	return jundefined{name}, nil
+}
+
+func (e *jindex) eval(ctx *jcontext) (interface{}, error) {
+	obj, err := e.obj.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index, err := e.index.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o := obj.(type) {
+	case jundefined:
+		return nil, fmt.Errorf("%q is undefined", o.name)
+	case map[string]interface{}, jnamespace:
+		if key, ok := index.(string); ok {
+			return jgetattr(o, key)
+		}
+		return jundefined{jstr(index)}, nil
+	case []interface{}:
+		if i, ok := jindexOf(index, len(o)); ok {
+			return o[i], nil
+		}
+		return jundefined{jstr(index)}, nil
+	case string:
+		runes := []rune(o)
+		if i, ok := jindexOf(index, len(runes)); ok {
+			return string(runes[i]), nil
+		}
+		return jundefined{jstr(index)}, nil
+	}
+	return nil, fmt.Errorf("%s is not subscriptable", jtypeName(obj))
+}
+
+// jindexOf resolves a possibly negative index of a sequence of length n.
+func jindexOf(index interface{}, n int) (int, bool) {
+	i, ok := index.(int)
+	if !ok {
+		return 0, false
+	}
+	if i < 0 {
+		i += n
+	}
+	return i, i >= 0 && i < n
+}
+
+func (e *jslice) eval(ctx *jcontext) (interface{}, error) {
+	obj, err := e.obj.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var bounds [3]interface{}
+	for i, b := range []jexpr{e.start, e.stop, e.step} {
+		if b == nil {
+			continue
+		}
+		if bounds[i], err = b.eval(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	switch o := obj.(type) {
+	case []interface{}:
+		indices, err := jsliceIndices(bounds, len(o))
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, 0, len(indices))
+		for _, i := range indices {
+			list = append(list, o[i])
+		}
+		return list, nil
+	case string:
+		runes := []rune(o)
+		indices, err := jsliceIndices(bounds, len(runes))
+		if err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		for _, i := range indices {
+			b.WriteRune(runes[i])
+		}
+		return b.String(), nil
+	}
+	return nil, fmt.Errorf("%s cannot be sliced", jtypeName(obj))
+}
+
+// jsliceIndices returns the indices selected by a Python slice of a sequence
+// of length n.
+func jsliceIndices(bounds [3]interface{}, n int) ([]int, error) {
+	var values [3]int
+	var set [3]bool
+	for i, b := range bounds {
+		if b == nil {
+			continue
+		}
+		v, ok := b.(int)
+		if !ok {
+			return nil, fmt.Errorf("slice indices must be integers, got %s", jtypeName(b))
+		}
+		values[i], set[i] = v, true
+	}
+
+	step := 1
+	if set[2] {
+		step = values[2]
+	}
+	if step == 0 {
+		return nil, fmt.Errorf("slice step cannot be zero")
+	}
+
+	clamp := func(i, lo, hi int) int {
+		if i < 0 {
+			i += n
+		}
+		return max(lo, min(i, hi))
+	}
+	var start, stop int
+	if step > 0 {
+		start, stop = 0, n
+		if set[0] {
+			start = clamp(values[0], 0, n)
+		}
+		if set[1] {
+			stop = clamp(values[1], 0, n)
+		}
+	} else {
+		start, stop = n-1, -1
+		if set[0] {
+			start = clamp(values[0], -1, n-1)
+		}
+		if set[1] {
+			stop = clamp(values[1], -1, n-1)
+		}
+	}
+
+	var indices []int
+	for i := start; step > 0 && i < stop || step < 0 && i > stop; i += step {
+		indices = append(indices, i)
+	}
+	return indices, nil
+}
+
+func (a *jargs) eval(ctx *jcontext) ([]interface{}, map[string]interface{}, error) {
+	args := make([]interface{}, 0, len(a.args))
+	for _, arg := range a.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, v)
+	}
+	var kwargs map[string]interface{}
+	for k, arg := range a.kwargs {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if kwargs == nil {
+			kwargs = make(map[string]interface{})
+		}
+		kwargs[k] = v
+	}
+	return args, kwargs, nil
+}
+
+func (e *jcall) eval(ctx *jcontext) (interface{}, error) {
+	args, kwargs, err := e.jargs.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fn interface{}
+	if attr, ok := e.fn.(*jattr); ok {
+		obj, err := attr.obj.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok, err := jcallMethod(obj, attr.name, args); ok || err != nil {
+			return v, err
+		}
+		if fn, err = jgetattr(obj, attr.name); err != nil {
+			return nil, err
+		}
+	} else if fn, err = e.fn.eval(ctx); err != nil {
+		return nil, err
+	}
+
+	switch f := fn.(type) {
+	case jbuiltin:
+		return f(args, kwargs)
+	case ChatTemplateFunc:
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf("keyword arguments are not supported by %T", f)
+		}
+		return f(args...)
+	case jundefined:
+		return nil, fmt.Errorf("%q is undefined", f.name)
+	}
+	return nil, fmt.Errorf("%s is not callable", jtypeName(fn))
+}
+
+// jcallMethod calls the Python method `name` of a string or a dict. It reports
+// whether obj has such method.
+func jcallMethod(obj interface{}, name string, args []interface{}) (interface{}, bool, error) {
+	strArg := func(i int, def string) (string, error) {
+		if i >= len(args) || args[i] == nil {
+			return def, nil
+		}
+		s, ok := args[i].(string)
+		if !ok {
+			return "", fmt.Errorf("%s() wants a string argument, got %s", name, jtypeName(args[i]))
+		}
+		return s, nil
+	}
+
+	switch o := obj.(type) {
+	case string:
+		switch name {
+		case "strip", "lstrip", "rstrip":
+			chars, err := strArg(0, "")
+			if err != nil {
+				return nil, true, err
+			}
+			return jstrip(o, name, chars, len(args) > 0 && args[0] != nil), true, nil
+		case "upper":
+			return strings.ToUpper(o), true, nil
+		case "lower":
+			return strings.ToLower(o), true, nil
+		case "title":
+			return jtitle(o), true, nil
+		case "capitalize":
+			return jcapitalize(o), true, nil
+		case "startswith", "endswith":
+			prefix, err := strArg(0, "")
+			if err != nil {
+				return nil, true, err
+			}
+			if name == "startswith" {
+				return strings.HasPrefix(o, prefix), true, nil
+			}
+			return strings.HasSuffix(o, prefix), true, nil
+		case "replace":
+			old, err := strArg(0, "")
+			if err != nil {
+				return nil, true, err
+			}
+			repl, err := strArg(1, "")
+			if err != nil {
+				return nil, true, err
+			}
+			return strings.ReplaceAll(o, old, repl), true, nil
+		case "split":
+			sep, err := strArg(0, "")
+			if err != nil {
+				return nil, true, err
+			}
+			var parts []string
+			if sep == "" {
+				parts = strings.Fields(o)
+			} else {
+				parts = strings.Split(o, sep)
+			}
+			list := make([]interface{}, len(parts))
+			for i, p := range parts {
+				list[i] = p
+			}
+			return list, true, nil
+		case "join":
+			if len(args) != 1 {
+				return nil, true, fmt.Errorf("join() wants 1 argument, got %d", len(args))
+			}
+			items, err := jiterate(args[0])
+			if err != nil {
+				return nil, true, err
+			}
+			return jjoin(items, o), true, nil
+		}
+	case map[string]interface{}:
+		switch name {
+		case "items":
+			return jitems(o), true, nil
+		case "keys":
+			keys := jsortedKeys(o)
+			list := make([]interface{}, len(keys))
+			for i, k := range keys {
+				list[i] = k
+			}
+			return list, true, nil
+		case "values":
+			var list []interface{}
+			for _, k := range jsortedKeys(o) {
+				list = append(list, o[k])
+			}
+			return list, true, nil
+		case "get":
+			key, err := strArg(0, "")
+			if err != nil {
+				return nil, true, err
+			}
+			if v, ok := o[key]; ok {
+				return v, true, nil
+			}
+			if len(args) > 1 {
+				return args[1], true, nil
+			}
+			return nil, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (e *jfilter) eval(ctx *jcontext) (interface{}, error) {
+	v, err := e.obj.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args, kwargs, err := e.jargs.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	arg := func(i int, name string) interface{} {
+		if i < len(args) {
+			return args[i]
+		}
+		if a, ok := kwargs[name]; ok {
+			return a
+		}
+		return nil
+	}
+
+	switch e.name {
+	case "trim":
+		chars, _ := arg(0, "chars").(string)
+		return jstrip(jstr(v), "strip", chars, chars != ""), nil
+	case "length", "count":
+		return jlength(v)
+	case "upper":
+		return strings.ToUpper(jstr(v)), nil
+	case "lower":
+		return strings.ToLower(jstr(v)), nil
+	case "capitalize":
+		return jcapitalize(jstr(v)), nil
+	case "title":
+		return jtitle(jstr(v)), nil
+	case "string":
+		return jstr(v), nil
+	case "safe", "e", "escape":
+		// Templates are not HTML, nothing to escape.
+		return v, nil
+	case "int":
+		switch n := v.(type) {
+		case int:
+			return n, nil
+		case float64:
+			return int(n), nil
+		case bool:
+			if n {
+				return 1, nil
+			}
+			return 0, nil
+		case string:
+			if i, err := strconv.Atoi(strings.TrimSpace(n)); err == nil {
+				return i, nil
+			}
+		}
+		if def, ok := arg(0, "default").(int); ok {
+			return def, nil
+		}
+		return 0, nil
+	case "default", "d":
+		_, undefined := v.(jundefined)
+		if undefined || jtruthy(arg(1, "boolean")) && !jtruthy(v) {
+			if def := arg(0, "default_value"); def != nil {
+				return def, nil
+			}
+			return "", nil
+		}
+		return v, nil
+	case "first", "last":
+		items, err := jiterate(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return jundefined{e.name}, nil
+		}
+		if e.name == "first" {
+			return items[0], nil
+		}
+		return items[len(items)-1], nil
+	case "join":
+		items, err := jiterate(v)
+		if err != nil {
+			return nil, err
+		}
+		sep, _ := arg(0, "d").(string)
+		return jjoin(items, sep), nil
+	case "list":
+		return jiterate(v)
+	case "items":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("items filter wants a mapping, got %s", jtypeName(v))
+		}
+		return jitems(m), nil
+	case "reverse":
+		if s, ok := v.(string); ok {
+			runes := []rune(s)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			return string(runes), nil
+		}
+		items, err := jiterate(v)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[len(items)-1-i] = item
+		}
+		return list, nil
+	case "replace":
+		old, _ := arg(0, "old").(string)
+		repl, _ := arg(1, "new").(string)
+		return strings.ReplaceAll(jstr(v), old, repl), nil
+	case "tojson":
+		indent, _ := arg(0, "indent").(int)
+		var b strings.Builder
+		if err := jwriteJSON(&b, v, indent, 0); err != nil {
+			return nil, err
+		}
+		return b.String(), nil
+	}
+	return nil, fmt.Errorf("unknown filter %q", e.name)
+}
+
+func (e *jtest) eval(ctx *jcontext) (interface{}, error) {
+	v, err := e.obj.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args, _, err := e.jargs.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	_, undefined := v.(jundefined)
+	switch e.name {
+	case "defined":
+		ok = !undefined
+	case "undefined":
+		ok = undefined
+	case "none":
+		ok = v == nil
+	case "boolean":
+		_, ok = v.(bool)
+	case "true", "false":
+		b, isBool := v.(bool)
+		ok = isBool && b == (e.name == "true")
+	case "string":
+		_, ok = v.(string)
+	case "number":
+		switch v.(type) {
+		case int, float64:
+			ok = true
+		}
+	case "integer":
+		_, ok = v.(int)
+	case "float":
+		_, ok = v.(float64)
+	case "mapping":
+		switch v.(type) {
+		case map[string]interface{}, jnamespace:
+			ok = true
+		}
+	case "sequence", "iterable":
+		switch v.(type) {
+		case []interface{}, string, map[string]interface{}:
+			ok = true
+		}
+	case "even", "odd", "divisibleby":
+		n, isInt := v.(int)
+		if !isInt {
+			return nil, fmt.Errorf("%q test wants an integer, got %s", e.name, jtypeName(v))
+		}
+		switch e.name {
+		case "even":
+			ok = n%2 == 0
+		case "odd":
+			ok = n%2 != 0
+		default:
+			d, isInt := jfirst(args).(int)
+			if !isInt || d == 0 {
+				return nil, fmt.Errorf("divisibleby test wants a non-zero integer")
+			}
+			ok = n%d == 0
+		}
+	case "eq", "equalto", "==", "sameas":
+		ok = jequal(v, jfirst(args))
+	case "ne", "!=":
+		ok = !jequal(v, jfirst(args))
+	case "in":
+		if ok, err = jcontains(jfirst(args), v); err != nil {
+			return nil, err
+		}
+	case "lower", "upper":
+		s, isStr := v.(string)
+		if e.name == "lower" {
+			ok = isStr && s == strings.ToLower(s)
+		} else {
+			ok = isStr && s == strings.ToUpper(s)
+		}
+	default:
+		return nil, fmt.Errorf("unknown test %q", e.name)
+	}
+
+	return ok != e.negate, nil
+}
+
+func jfirst(args []interface{}) interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	return args[0]
+}
+
+func (e *jbinary) eval(ctx *jcontext) (interface{}, error) {
+	left, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// `and` and `or` short-circuit and return one of their operands.
+	switch e.op {
+	case "and":
+		if !jtruthy(left) {
+			return left, nil
+		}
+		return e.right.eval(ctx)
+	case "or":
+		if jtruthy(left) {
+			return left, nil
+		}
+		return e.right.eval(ctx)
+	}
+
+	right, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==", "!=", "<", ">", "<=", ">=", "in", "not in":
+		return jcompareOp(e.op, left, right)
+	case "~":
+		return jstr(left) + jstr(right), nil
+	}
+	return jarith(e.op, left, right)
+}
+
+func (e *jchain) eval(ctx *jcontext) (interface{}, error) {
+	left, err := e.operands[0].eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range e.ops {
+		right, err := e.operands[i+1].eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := jcompareOp(op, left, right)
+		if err != nil || !ok {
+			return false, err
+		}
+		left = right
+	}
+	return true, nil
+}
+
+// jcompareOp returns the result of the comparison `left op right`.
+func jcompareOp(op string, left, right interface{}) (bool, error) {
+	switch op {
+	case "==":
+		return jequal(left, right), nil
+	case "!=":
+		return !jequal(left, right), nil
+	case "in":
+		return jcontains(right, left)
+	case "not in":
+		ok, err := jcontains(right, left)
+		return !ok, err
+	}
+	return jcompare(op, left, right)
+}
+
+func (e *jnot) eval(ctx *jcontext) (interface{}, error) {
+	v, err := e.x.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return !jtruthy(v), nil
+}
+
+func (e *jneg) eval(ctx *jcontext) (interface{}, error) {
+	v, err := e.x.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch n := v.(type) {
+	case int:
+		return -n, nil
+	case float64:
+		return -n, nil
+	}
+	return nil, fmt.Errorf("bad operand type for unary -: %s", jtypeName(v))
+}
+
+func (e *jcond) eval(ctx *jcontext) (interface{}, error) {
+	cond, err := e.cond.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if jtruthy(cond) {
+		return e.then.eval(ctx)
+	}
+	if e.els == nil {
+		return jundefined{}, nil
+	}
+	return e.els.eval(ctx)
+}
+
+// Value helpers, following Python semantics.
+
+func jtypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "NoneType"
+	case bool:
+		return "bool"
+	case int:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "str"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "dict"
+	case jnamespace:
+		return "Namespace"
+	case jundefined:
+		return "Undefined"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func jtruthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil, jundefined:
+		return false
+	case bool:
+		return x
+	case int:
+		return x != 0
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	case []interface{}:
+		return len(x) > 0
+	case map[string]interface{}:
+		return len(x) > 0
+	}
+	return true
+}
+
+// jstr converts a value to a string like Python `str()`.
+func jstr(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case jundefined:
+		return ""
+	}
+	var b strings.Builder
+	jwriteRepr(&b, v)
+	return b.String()
+}
+
+// jwriteRepr writes a value like Python `repr()`.
+func jwriteRepr(b *strings.Builder, v interface{}) {
+	switch x := v.(type) {
+	case nil:
+		b.WriteString("None")
+	case bool:
+		if x {
+			b.WriteString("True")
+		} else {
+			b.WriteString("False")
+		}
+	case int:
+		b.WriteString(strconv.Itoa(x))
+	case float64:
+		b.WriteString(jformatFloat(x))
+	case string:
+		quote := "'"
+		if strings.Contains(x, "'") && !strings.Contains(x, "\"") {
+			quote = "\""
+		}
+		b.WriteString(quote)
+		r := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r", "\t", "\\t", quote, "\\"+quote)
+		b.WriteString(r.Replace(x))
+		b.WriteString(quote)
+	case []interface{}:
+		b.WriteByte('[')
+		for i, item := range x {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			jwriteRepr(b, item)
+		}
+		b.WriteByte(']')
+	case map[string]interface{}:
+		b.WriteByte('{')
+		for i, k := range jsortedKeys(x) {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			jwriteRepr(b, k)
+			b.WriteString(": ")
+			jwriteRepr(b, x[k])
+		}
+		b.WriteByte('}')
+	case jnamespace:
+		b.WriteString("<Namespace ")
+		jwriteRepr(b, map[string]interface{}(x))
+		b.WriteByte('>')
+	case jundefined:
+	default:
+		fmt.Fprint(b, x)
+	}
+}
+
+func jformatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	case f == math.Trunc(f) && math.Abs(f) < 1e16:
+		return strconv.FormatFloat(f, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// jwriteJSON writes a value like the `tojson` filter of transformers, that is
+// `json.dumps` without ASCII escaping. Mapping keys are sorted.
+func jwriteJSON(b *strings.Builder, v interface{}, indent, depth int) error {
+	newline := func(depth int) {
+		if indent > 0 {
+			b.WriteByte('\n')
+			b.WriteString(strings.Repeat(" ", indent*depth))
+		}
+	}
+	sep := ", "
+	if indent > 0 {
+		sep = ","
+	}
+
+	switch x := v.(type) {
+	case nil, jundefined:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(x))
+	case int:
+		b.WriteString(strconv.Itoa(x))
+	case float64:
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return fmt.Errorf("tojson: unsupported value %v", x)
+		}
+		b.WriteString(jformatFloat(x))
+	case string:
+		var buf bytes.Buffer
+		for _, r := range x {
+			switch {
+			case r == '"' || r == '\\':
+				buf.WriteByte('\\')
+				buf.WriteRune(r)
+			case r == '\n':
+				buf.WriteString(`\n`)
+			case r == '\r':
+				buf.WriteString(`\r`)
+			case r == '\t':
+				buf.WriteString(`\t`)
+			case r < 0x20:
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			default:
+				buf.WriteRune(r)
+			}
+		}
+		b.WriteByte('"')
+		b.Write(buf.Bytes())
+		b.WriteByte('"')
+	case []interface{}:
+		b.WriteByte('[')
+		for i, item := range x {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			newline(depth + 1)
+			if err := jwriteJSON(b, item, indent, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(x) > 0 {
+			newline(depth)
+		}
+		b.WriteByte(']')
+	case map[string]interface{}, jnamespace:
+		m, ok := x.(map[string]interface{})
+		if !ok {
+			m = map[string]interface{}(x.(jnamespace))
+		}
+		b.WriteByte('{')
+		for i, k := range jsortedKeys(m) {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			newline(depth + 1)
+			if err := jwriteJSON(b, k, indent, depth+1); err != nil {
+				return err
+			}
+			b.WriteString(": ")
+			if err := jwriteJSON(b, m[k], indent, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(m) > 0 {
+			newline(depth)
+		}
+		b.WriteByte('}')
+	default:
+		return fmt.Errorf("tojson: unsupported value of type %s", jtypeName(v))
+	}
+	return nil
+}
+
+func jsortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jitems(m map[string]interface{}) []interface{} {
+	items := make([]interface{}, 0, len(m))
+	for _, k := range jsortedKeys(m) {
+		items = append(items, []interface{}{k, m[k]})
+	}
+	return items
+}
+
+func jjoin(items []interface{}, sep string) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = jstr(item)
+	}
+	return strings.Join(parts, sep)
+}
+
+// jiterate returns the items of an iterable value. Mappings iterate over their
+// sorted keys and strings over their characters.
+func jiterate(v interface{}) ([]interface{}, error) {
+	switch x := v.(type) {
+	case jundefined:
+		return nil, nil
+	case []interface{}:
+		return x, nil
+	case map[string]interface{}:
+		keys := jsortedKeys(x)
+		items := make([]interface{}, len(keys))
+		for i, k := range keys {
+			items[i] = k
+		}
+		return items, nil
+	case string:
+		var items []interface{}
+		for _, r := range x {
+			items = append(items, string(r))
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%s is not iterable", jtypeName(v))
+}
+
+func jlength(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case jundefined:
+		return 0, nil
+	case string:
+		return utf8.RuneCountInString(x), nil
+	case []interface{}:
+		return len(x), nil
+	case map[string]interface{}:
+		return len(x), nil
+	case jnamespace:
+		return len(x), nil
+	}
+	return nil, fmt.Errorf("object of type %s has no length", jtypeName(v))
+}
+
+func jcontains(container, item interface{}) (bool, error) {
+	switch c := container.(type) {
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("'in <string>' wants a string, got %s", jtypeName(item))
+		}
+		return strings.Contains(c, s), nil
+	case []interface{}:
+		for _, x := range c {
+			if jequal(x, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		s, ok := item.(string)
+		_, found := c[s]
+		return ok && found, nil
+	case jundefined:
+		return false, nil
+	}
+	return false, fmt.Errorf("argument of type %s is not iterable", jtypeName(container))
+}
+
+func jnumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func jequal(a, b interface{}) bool {
+	if x, ok := jnumber(a); ok {
+		y, ok := jnumber(b)
+		return ok && x == y
+	}
+	if _, ok := a.(jundefined); ok {
+		_, ok := b.(jundefined)
+		return ok
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func jcompare(op string, a, b interface{}) (bool, error) {
+	var cmp int
+	x, xok := jnumber(a)
+	y, yok := jnumber(b)
+	sa, saok := a.(string)
+	sb, sbok := b.(string)
+	switch {
+	case xok && yok:
+		switch {
+		case x < y:
+			cmp = -1
+		case x > y:
+			cmp = 1
+		}
+	case saok && sbok:
+		cmp = strings.Compare(sa, sb)
+	default:
+		return false, fmt.Errorf("%q is not supported between %s and %s", op, jtypeName(a), jtypeName(b))
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+// maxRange is the maximum number of items of `range()`, as MAX_RANGE of the
+// Jinja sandbox.
+const maxRange = 100000
+
+// maxRepeatLength is the maximum byte length of a string repeated by `*`.
+const maxRepeatLength = 1 << 24
+
+// rangeLen returns the number of items of `range(start, stop, step)`.
+func rangeLen(start, stop, step int) uint64 {
+	if step > 0 && start < stop {
+		return (uint64(stop-start)-1)/uint64(step) + 1
+	}
+	if step < 0 && start > stop {
+		return (uint64(start-stop)-1)/uint64(-step) + 1
+	}
+	return 0
+}
+
+// jipow returns x ** y for y >= 0, false if it overflows an int.
+func jipow(x, y int) (int, bool) {
+	switch {
+	case y == 0:
+		return 1, true
+	case x == 0 || x == 1:
+		return x, true
+	case x == -1:
+		return 1 - 2*(y%2), true
+	}
+
+	// |x| >= 2 overflows within 64 multiplications.
+	n := 1
+	for ; y > 0; y-- {
+		m := n * x
+		if m/x != n {
+			return 0, false
+		}
+		n = m
+	}
+	return n, true
+}
+
+func jarith(op string, a, b interface{}) (interface{}, error) {
+	switch op {
+	case "+":
+		if x, ok := a.(string); ok {
+			if y, ok := b.(string); ok {
+				return x + y, nil
+			}
+		}
+		if x, ok := a.([]interface{}); ok {
+			if y, ok := b.([]interface{}); ok {
+				return append(append([]interface{}{}, x...), y...), nil
+			}
+		}
+	case "*":
+		if s, ok := a.(string); ok {
+			if n, ok := b.(int); ok {
+				if len(s) > 0 && n > maxRepeatLength/len(s) {
+					return nil, fmt.Errorf("repeating a string of %d bytes %d times exceeds the limit of %d bytes", len(s), n, maxRepeatLength)
+				}
+				return strings.Repeat(s, max(n, 0)), nil
+			}
+		}
+	}
+
+	x, xok := jnumber(a)
+	y, yok := jnumber(b)
+	if !xok || !yok {
+		return nil, fmt.Errorf("unsupported operand types for %s: %s and %s", op, jtypeName(a), jtypeName(b))
+	}
+	_, aFloat := a.(float64)
+	_, bFloat := b.(float64)
+	isInt := !aFloat && !bFloat
+	ix, iy := int(x), int(y)
+
+	switch op {
+	case "+":
+		if isInt {
+			return ix + iy, nil
+		}
+		return x + y, nil
+	case "-":
+		if isInt {
+			return ix - iy, nil
+		}
+		return x - y, nil
+	case "*":
+		if isInt {
+			return ix * iy, nil
+		}
+		return x * y, nil
+	case "**":
+		if isInt && iy >= 0 {
+			n, ok := jipow(ix, iy)
+			if !ok {
+				return nil, fmt.Errorf("integer overflow in %d ** %d", ix, iy)
+			}
+			return n, nil
+		}
+		return math.Pow(x, y), nil
+	}
+
+	if y == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	switch op {
+	case "/":
+		return x / y, nil
+	case "//":
+		if isInt {
+			return int(math.Floor(x / y)), nil
+		}
+		return math.Floor(x / y), nil
+	}
+	// Python modulo has the sign of the divisor.
+	if isInt {
+		m := ix % iy
+		if m != 0 && (m < 0) != (iy < 0) {
+			m += iy
+		}
+		return m, nil
+	}
+	m := math.Mod(x, y)
+	if m != 0 && (m < 0) != (y < 0) {
+		m += y
+	}
+	return m, nil
+}
+
+func jstrip(s, method, chars string, hasChars bool) string {
+	cut := unicode.IsSpace
+	if hasChars {
+		cut = func(r rune) bool { return strings.ContainsRune(chars, r) }
+	}
+	switch method {
+	case "lstrip":
+		return strings.TrimLeftFunc(s, cut)
+	case "rstrip":
+		return strings.TrimRightFunc(s, cut)
+	}
+	return strings.TrimFunc(s, cut)
+}
+
+func jcapitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + strings.ToLower(s[n:])
+}
+
+func jtitle(s string) string {
+	runes := []rune(s)
+	prevLetter := false
+	for i, r := range runes {
+		if prevLetter {
+			runes[i] = unicode.ToLower(r)
+		} else {
+			runes[i] = unicode.ToUpper(r)
+		}
+		prevLetter = unicode.IsLetter(r)
+	}
+	return string(runes)
+}
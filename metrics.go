@@ -0,0 +1,62 @@
+package tokenizer
+
+import (
+	"sync"
+)
+
+// Counter names emitted to a MetricsSink.
+const (
+	MetricSourceFormPreserved = "source_form_preserved" // sequences left unchanged by normalization
+	MetricSourceFormChanged   = "source_form_changed"   // sequences changed by normalization
+)
+
+// MetricsSink receives counters emitted by a Tokenizer while encoding.
+//
+// Implementations must be safe for concurrent use as batch encoding emits from
+// multiple goroutines.
+type MetricsSink interface {
+	Add(name string, delta int64)
+}
+
+// MemoryMetricsSink is a MetricsSink keeping counters in memory.
+type MemoryMetricsSink struct {
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+var _ MetricsSink = new(MemoryMetricsSink)
+
+// NewMemoryMetricsSink creates an empty MemoryMetricsSink.
+func NewMemoryMetricsSink() *MemoryMetricsSink {
+	return &MemoryMetricsSink{
+		counters: make(map[string]int64),
+	}
+}
+
+// Add adds delta to the named counter.
+func (s *MemoryMetricsSink) Add(name string, delta int64) {
+	s.mu.Lock()
+	s.counters[name] += delta
+	s.mu.Unlock()
+}
+
+// Get returns the value of the named counter.
+func (s *MemoryMetricsSink) Get(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.counters[name]
+}
+
+// Counters returns a copy of all counters.
+func (s *MemoryMetricsSink) Counters() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]int64, len(s.counters))
+	for k, v := range s.counters {
+		out[k] = v
+	}
+
+	return out
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -14,6 +15,8 @@ import (
 	// "strconv"
 	"log"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/season-studio/tokenizer"
 	"github.com/season-studio/tokenizer/model"
@@ -33,9 +36,12 @@ type Config struct {
 	merges                  *Merges
 	cacheCapacity           int
 	dropout                 *float32
+	randSource              rand.Source
 	unkToken                *string
 	continuingSubwordPrefix *string
 	endOfWordSuffix         *string
+	fuseUnk                 bool
+	byteFallback            bool
 }
 
 // BpeBuilder can be used to create a `BPE` model with
@@ -85,6 +91,11 @@ func (bb *BpeBuilder) Dropout(dropout float32) {
 	bb.config.dropout = &dropout
 }
 
+// RandSource sets the source of the dropped merges, see `WithRandSource`.
+func (bb *BpeBuilder) RandSource(src rand.Source) {
+	bb.config.randSource = util.NewLockedSource(src)
+}
+
 // UnkToken set the `UNK` token for the vocab
 func (bb *BpeBuilder) UnkToken(unkTok string) {
 	bb.config.unkToken = &unkTok
@@ -100,6 +111,17 @@ func (bb *BpeBuilder) EndOfWordSuffix(endOfWordSuffix string) {
 	bb.config.endOfWordSuffix = &endOfWordSuffix
 }
 
+// FuseUnk set whether consecutive unknown tokens are fused into one `UNK`.
+func (bb *BpeBuilder) FuseUnk(fuseUnk bool) {
+	bb.config.fuseUnk = fuseUnk
+}
+
+// ByteFallback set whether unknown characters fall back to their `<0xNN>`
+// byte tokens.
+func (bb *BpeBuilder) ByteFallback(byteFallback bool) {
+	bb.config.byteFallback = byteFallback
+}
+
 // Build returns a `BPE` model that uses the BpeBuilder configuration
 func (bb *BpeBuilder) Build() (*BPE, error) {
 	var (
@@ -151,9 +173,12 @@ func (bb *BpeBuilder) Build() (*BPE, error) {
 		Merges:                  merges,
 		Cache:                   cache,
 		Dropout:                 bb.config.dropout,
+		RandSource:              bb.config.randSource,
 		UnkToken:                bb.config.unkToken,
 		ContinuingSubwordPrefix: bb.config.continuingSubwordPrefix,
 		EndOfWordSuffix:         bb.config.endOfWordSuffix,
+		FuseUnk:                 bb.config.fuseUnk,
+		ByteFallback:            bb.config.byteFallback,
 	}
 
 	return &bpe, nil
@@ -181,6 +206,10 @@ type BPE struct {
 	// At 1.0, tokenization will perform no merges, so the result will just be characters.
 	Dropout *float32
 
+	// RandSource draws the merges dropped by Dropout, the shared source of
+	// math/rand if nil. It must be safe for concurrent use.
+	RandSource rand.Source
+
 	// UnkToken is the unknown token to be used when we encounter an unknown char
 	UnkToken *string
 
@@ -191,6 +220,17 @@ type BPE struct {
 	// EndOfWordSuffix is an optional suffix
 	// to caracterize and end-of-word subword
 	EndOfWordSuffix *string
+
+	// FuseUnk fuses consecutive unknown tokens into a single `UNK` token.
+	FuseUnk bool
+
+	// ByteFallback encodes characters not in the vocab as their `<0xNN>` byte
+	// tokens (as in LLaMA). It falls back to `UNK` if a byte token is missing.
+	ByteFallback bool
+
+	// compiled looks the vocab and merges up in place for a model loaded by
+	// LoadCompiled, nil otherwise.
+	compiled *compiledIndex
 }
 
 func (b *BPE) builder() *BpeBuilder {
@@ -273,7 +313,7 @@ func (b *BPE) ReadFiles(vocabF string, mergesF string) (*model.Vocab, *Merges, e
 	// end of file or hit any error. The error will be
 	// access by s.Err. If error caused by EOF it's value is nil.
 	var lineNum = 0
-	for s.Scan() {
+	for i := 0; s.Scan(); i++ {
 		line := s.Text()
 
 		// Skip line with `#version`
@@ -284,7 +324,7 @@ func (b *BPE) ReadFiles(vocabF string, mergesF string) (*model.Vocab, *Merges, e
 
 		parts := strings.Split(line, " ")
 		if len(parts) != 2 {
-			err = fmt.Errorf("Read merge file error: invalid data at line %d\n", lineNum)
+			err = &tokenizer.MergeError{Line: i, Merge: line, Err: tokenizer.ErrInvalidMergeFormat}
 			return nil, nil, err
 		}
 
@@ -307,7 +347,7 @@ func (b *BPE) ReadFiles(vocabF string, mergesF string) (*model.Vocab, *Merges, e
 		newToken := fmt.Sprintf("%v%v", parts[0], parts[1])
 		newId, ok := vocab[newToken]
 		if !ok {
-			err = fmt.Errorf("Read merge file error: key value for token: \"%s\" not found.", newToken)
+			err = &tokenizer.MergeError{Line: i, Merge: line, Err: fmt.Errorf("%w: %q", tokenizer.ErrTokenNotInVocab, newToken)}
 			return nil, nil, err
 		}
 
@@ -342,6 +382,7 @@ func (b *BPE) ClearCache() {
 // GetVocab returns BPE vocab
 // func (b *BPE) GetVocab() *model.Vocab {
 func (b BPE) GetVocab() map[string]int {
+	b.materialize()
 	return *b.Vocab
 }
 
@@ -355,10 +396,26 @@ func (b *BPE) GetContinuingSubwordPrfix() *string {
 	return b.ContinuingSubwordPrefix
 }
 
-// MergeWord merges given word
+// MergeWord merges given word. It panics on a char neither in the vocab nor
+// covered by byte fallback when there is no `unk` token.
 func (b *BPE) MergeWord(w string) *Word {
-
 	word := NewWord()
+	if err := b.mergeWord(w, word); err != nil {
+		panic(err)
+	}
+
+	return word
+}
+
+// wordPool holds the symbol buffers of words being merged.
+var wordPool = sync.Pool{
+	New: func() interface{} { return NewWord() },
+}
+
+// mergeWord adds the symbols of `w` to `word` and merges them. It fails on a
+// char neither in the vocab nor covered by byte fallback when there is no `unk`
+// token.
+func (b *BPE) mergeWord(w string, word *Word) error {
 	var (
 		prefix, suffix string
 	)
@@ -375,76 +432,105 @@ func (b *BPE) MergeWord(w string) *Word {
 		suffix = ""
 	}
 
-	chars := []rune(w)
-	currRuneIdx := 0
+	// pending `unk` id and byte length, to fuse consecutive unknown tokens
+	unkId, unkLen := -1, 0
+	flushUnk := func() {
+		if unkId >= 0 {
+			word.Add(unkId, unkLen)
+			unkId, unkLen = -1, 0
+		}
+	}
+
 	for byteIdx, r := range w {
-		var (
-			s       string
-			byteLen int
-		)
-		byteLen = len(string(r))
-
-		// if first rune, add prefix
-		currRuneIdx++
-		if currRuneIdx == len(chars) { // last rune, add suffix
-			s = fmt.Sprintf("%v%v", string(r), suffix)
-		} else if byteIdx == 0 {
-			s = fmt.Sprintf("%v%v", prefix, string(r))
+		byteLen := utf8.RuneLen(r)
+		if r == utf8.RuneError {
+			_, byteLen = utf8.DecodeRuneInString(w[byteIdx:])
+		}
+		char := w[byteIdx : byteIdx+byteLen]
+
+		var s string
+		if byteIdx+byteLen == len(w) { // last rune, add suffix
+			s = char + suffix
+		} else if byteIdx == 0 { // first rune, add prefix
+			s = prefix + char
 		} else { // the rest
-			s = string(r)
+			s = char
 		}
 
-		// If `s` exists in vocab, add its id, otherwise add id of `unk`
-		vocab := *b.Vocab
-		if id, ok := vocab[s]; ok { // found
+		// If `s` exists in vocab, add its id, otherwise add its byte tokens or
+		// id of `unk`
+		if id, ok := b.tokenToId(s); ok { // found
+			flushUnk()
 			word.Add(id, byteLen)
-		} else { // not found, add `unk`
-			if b.UnkToken != nil {
-				// get `unk` id
-				unkId := (*b.Vocab)[*b.UnkToken]
-				// add `unk`
-				word.Add(unkId, byteLen)
-			} else {
-				fmt.Printf("cannot find '%s' in the vocab. \n", s)
-				panic("Can't find `unk` token in the vocab. Have you added one when initiating the model?")
+			continue
+		}
+
+		if b.ByteFallback {
+			if ids, ok := b.byteTokenIds(char); ok {
+				flushUnk()
+				for _, id := range ids {
+					word.Add(id, 1)
+				}
+				continue
 			}
 		}
+
+		// not found, add `unk`
+		if b.UnkToken == nil {
+			return fmt.Errorf("BPE error: cannot find %q nor `unk` token in the vocab", s)
+		}
+		id, _ := b.tokenToId(*b.UnkToken)
+		if b.FuseUnk && unkId >= 0 {
+			unkLen += byteLen
+		} else {
+			flushUnk()
+			unkId, unkLen = id, byteLen
+		}
 	}
+	flushUnk()
 
+	var merges mergeTable = *b.Merges
+	if c := b.index(); c != nil {
+		merges = c.merges
+	}
 	if b.Dropout != nil {
-		word.MergeAll(*b.Merges, *b.Dropout)
+		word.mergeAll(merges, *b.Dropout, b.RandSource)
 	} else {
-		word.MergeAll(*b.Merges)
+		word.mergeAll(merges, 0, nil)
 	}
 
-	return word
+	return nil
 }
 
-// WordToTokens slices word to tokens
-func (b *BPE) WordToTokens(word Word) []tokenizer.Token {
-	var tokens []tokenizer.Token
-	chars := word.GetChars()
-	offsets := word.GetOffsets()
-	type zword struct { // zip id and offsets
-		Id      int
-		Offsets []int
-	}
-	var zWord []zword
-
-	for i, char := range chars {
-		zWord = append(zWord, zword{
-			Id:      char,
-			Offsets: offsets[i],
-		})
+// byteTokenIds returns the ids of the `<0xNN>` byte tokens of s, false if one
+// of them is not in the vocab.
+func (b *BPE) byteTokenIds(s string) ([]int, bool) {
+	ids := make([]int, len(s))
+	for i := 0; i < len(s); i++ {
+		id, ok := b.tokenToId(fmt.Sprintf("<0x%02X>", s[i]))
+		if !ok {
+			return nil, false
+		}
+		ids[i] = id
 	}
 
-	for _, z := range zWord {
-		tok := tokenizer.Token{
-			Id:      z.Id,
-			Value:   (*b.VocabR)[z.Id],
-			Offsets: z.Offsets,
+	return ids, true
+}
+
+// WordToTokens slices word to tokens
+func (b *BPE) WordToTokens(word Word) []tokenizer.Token {
+	tokens := make([]tokenizer.Token, len(word.Symbols))
+	offsets := make([]int, 2*len(word.Symbols))
+
+	var pos int
+	for i, sym := range word.Symbols {
+		offsets[2*i], offsets[2*i+1] = pos, pos+sym.Len
+		tokens[i] = tokenizer.Token{
+			Id:      sym.C,
+			Value:   b.idToToken(sym.C),
+			Offsets: offsets[2*i : 2*i+2 : 2*i+2],
 		}
-		tokens = append(tokens, tok)
+		pos += sym.Len
 	}
 
 	return tokens
@@ -468,45 +554,180 @@ func (b BPE) Tokenize(sequence string) (retVal []tokenizer.Token, err error) {
 	}
 
 	if b.Dropout == nil {
-		return b.TokenizeWithCache(sequence), nil
+		return b.tokenizeWithCache(sequence)
 	}
 
-	word := b.MergeWord(sequence)
+	word := wordPool.Get().(*Word)
+	defer putWord(word)
+	if err := b.mergeWord(sequence, word); err != nil {
+		return nil, err
+	}
 
 	return b.WordToTokens(*word), nil
 }
 
+// putWord puts back the symbol buffer of a merged word to the pool.
+func putWord(word *Word) {
+	word.Symbols = word.Symbols[:0]
+	wordPool.Put(word)
+}
+
+// TokenizeWord tokenizes a single word with the BPE merge loop. Token offsets
+// are byte offsets shifted by `offsetsBase`.
+//
+// It is safe for concurrent use as the cache is synchronized and dropout
+// uses a per-call random source.
+func (b BPE) TokenizeWord(word string, offsetsBase int) (retVal []tokenizer.Token, err error) {
+	toks, err := b.Tokenize(word)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tok := range toks {
+		tok.Offsets = []int{tok.Offsets[0] + offsetsBase, tok.Offsets[1] + offsetsBase}
+		retVal = append(retVal, tok)
+	}
+
+	return retVal, nil
+}
+
+// TokenizeWithCache tokenizes the sequence, looking up and storing its
+// merged word in the cache if any. It panics where `Tokenize` fails.
 func (b BPE) TokenizeWithCache(sequence string) (retVal []tokenizer.Token) {
+	retVal, err := b.tokenizeWithCache(sequence)
+	if err != nil {
+		panic(err)
+	}
 
-	if hit, ok := b.Cache.Get(sequence); ok {
-		return b.WordToTokens(hit)
-	} else {
-		word := b.MergeWord(sequence)
-		retVal = b.WordToTokens(*word)
-		if b.Cache != nil {
-			b.Cache.SetValues([]CacheItem{
-				{sequence, *word},
-			})
+	return retVal
+}
+
+func (b BPE) tokenizeWithCache(sequence string) (retVal []tokenizer.Token, err error) {
+	if b.Cache != nil {
+		if hit, ok := b.Cache.Get(sequence); ok {
+			return b.WordToTokens(hit), nil
+		}
+	}
+
+	word := wordPool.Get().(*Word)
+	defer putWord(word)
+	if err := b.mergeWord(sequence, word); err != nil {
+		return nil, err
+	}
+	retVal = b.WordToTokens(*word)
+	b.cacheWord(sequence, word)
+
+	return retVal, nil
+}
+
+// cacheWord stores a copy of the merged word of sequence in the cache, if any,
+// as the symbol buffer goes back to the pool.
+func (b BPE) cacheWord(sequence string, word *Word) {
+	if b.Cache == nil {
+		return
+	}
+	symbols := make([]Symbol, len(word.Symbols))
+	copy(symbols, word.Symbols)
+	b.Cache.SetValues([]CacheItem{
+		{sequence, Word{Symbols: symbols}},
+	})
+}
+
+var _ tokenizer.TokenCounter = BPE{}
+
+// CountTokens implements tokenizer.TokenCounter: it merges the sequence, or
+// looks it up in the cache, without building its tokens. Without dropout, the
+// merged sequence is cached as by `Tokenize`.
+func (b BPE) CountTokens(sequence string) (int, error) {
+	if len(sequence) == 0 {
+		return 0, nil
+	}
+	if b.Dropout == nil && b.Cache != nil {
+		if hit, ok := b.Cache.Get(sequence); ok {
+			return len(hit.Symbols), nil
 		}
-		return retVal
 	}
+
+	word := wordPool.Get().(*Word)
+	defer putWord(word)
+	if err := b.mergeWord(sequence, word); err != nil {
+		return 0, err
+	}
+	if b.Dropout == nil {
+		b.cacheWord(sequence, word)
+	}
+
+	return len(word.Symbols), nil
+}
+
+var _ tokenizer.DropoutModel = BPE{}
+
+// GetDropout implements tokenizer.DropoutModel.
+func (b BPE) GetDropout() float32 {
+	if b.Dropout == nil {
+		return 0
+	}
+
+	return *b.Dropout
+}
+
+// WithDropout implements tokenizer.DropoutModel: it returns a copy of the model
+// sharing its vocab and merges, with the given dropout and, if not nil, source.
+func (b BPE) WithDropout(dropout float32, src rand.Source) (tokenizer.Model, error) {
+	if dropout < 0 || dropout > 1 {
+		return nil, fmt.Errorf("BPE error: invalid dropout %v, want a value in [0, 1]", dropout)
+	}
+
+	m := b
+	m.Dropout = &dropout
+	if dropout == 0 {
+		m.Dropout = nil
+	}
+	if src != nil {
+		m.RandSource = util.NewLockedSource(src)
+	}
+
+	return &m, nil
 }
 
 func (b BPE) TokenToId(token string) (id int, ok bool) {
-	id, ok = (*b.Vocab)[token]
+	return b.tokenToId(token)
+}
+
+func (b *BPE) tokenToId(token string) (int, bool) {
+	if c := b.index(); c != nil {
+		return c.vocab.TokenToId(token)
+	}
+	id, ok := (*b.Vocab)[token]
 	return id, ok
 }
 
 func (b BPE) IdToToken(id int) (token string, ok bool) {
+	if c := b.index(); c != nil {
+		return c.vocab.IdToToken(id)
+	}
 	token, ok = (*b.VocabR)[id]
 	return token, ok
 }
 
+// idToToken returns the token of id, empty if not in the vocab.
+func (b *BPE) idToToken(id int) string {
+	if c := b.index(); c != nil {
+		token, _ := c.vocab.IdToToken(id)
+		return token
+	}
+	return (*b.VocabR)[id]
+}
+
 func (b BPE) GetVocabSize() int {
+	if c := b.index(); c != nil {
+		return c.vocab.Len()
+	}
 	return len(*b.Vocab)
 }
 
 func (b BPE) Save(dir string, nameOpt ...string) error {
+	b.materialize()
 	var vfile string
 	var mfile string
 	var err error
@@ -605,10 +826,10 @@ func CreateMerges(vocab map[string]int, mergesData []string) (*Merges, error) {
 		lineNum int    = 0
 		merges  Merges = make(map[Pair]PairVal)
 	)
-	for _, line := range mergesData {
+	for i, line := range mergesData {
 		parts := strings.Split(line, " ")
 		if len(parts) != 2 {
-			err := fmt.Errorf("Read merges error: invalid data at line %d\n", lineNum)
+			err := &tokenizer.MergeError{Line: i, Merge: line, Err: tokenizer.ErrInvalidMergeFormat}
 			return nil, err
 		}
 
@@ -631,7 +852,7 @@ func CreateMerges(vocab map[string]int, mergesData []string) (*Merges, error) {
 		newToken := fmt.Sprintf("%v%v", parts[0], parts[1])
 		newId, ok := vocab[newToken]
 		if !ok {
-			err := fmt.Errorf("Read merges error: key value for token: \"%s\" not found.", newToken)
+			err := &tokenizer.MergeError{Line: i, Merge: line, Err: fmt.Errorf("%w: %q", tokenizer.ErrTokenNotInVocab, newToken)}
 			return nil, err
 		}
 
@@ -645,35 +866,129 @@ func CreateMerges(vocab map[string]int, mergesData []string) (*Merges, error) {
 	return &merges, nil
 }
 
-// New create new BPE model.
-func New(
-	// vocab map[string]int,
-	vocab model.Vocab,
-	mergesData []string,
-	dropout *float32,
-	unkToken *string,
-	continuingSubwordPrefix *string,
-	endOfWordSuffix *string,
-) (*BPE, error) {
+// Option sets an optional parameter of a BPE model created with New.
+type Option func(c *Config)
+
+// WithDropout sets the BPE dropout probability, in (0, 1]. No dropout by
+// default.
+func WithDropout(dropout float32) Option {
+	return func(c *Config) { c.dropout = &dropout }
+}
+
+// WithRandSource sets the source of the merges dropped by the dropout, i.e. a
+// seeded `rand.NewSource` for reproducible BPE-dropout. The shared source of
+// math/rand by default. src is locked as the model is used concurrently.
+func WithRandSource(src rand.Source) Option {
+	return func(c *Config) { c.randSource = util.NewLockedSource(src) }
+}
+
+// WithUnkToken sets the `UNK` token, none by default.
+func WithUnkToken(unkToken string) Option {
+	return func(c *Config) { c.unkToken = &unkToken }
+}
+
+// WithContinuingSubwordPrefix sets the prefix of continuing subwords, none by
+// default.
+func WithContinuingSubwordPrefix(prefix string) Option {
+	return func(c *Config) { c.continuingSubwordPrefix = &prefix }
+}
+
+// WithEndOfWordSuffix sets the suffix of the subwords ending a word, none by
+// default.
+func WithEndOfWordSuffix(suffix string) Option {
+	return func(c *Config) { c.endOfWordSuffix = &suffix }
+}
+
+// WithFuseUnk sets whether consecutive unknown tokens are fused, false by
+// default.
+func WithFuseUnk(fuseUnk bool) Option {
+	return func(c *Config) { c.fuseUnk = fuseUnk }
+}
+
+// WithByteFallback sets whether unknown chars are tokenized as their "<0xXX>"
+// byte tokens, false by default.
+func WithByteFallback(byteFallback bool) Option {
+	return func(c *Config) { c.byteFallback = byteFallback }
+}
+
+// WithCacheCapacity sets the capacity of the word cache, 0 to disable it.
+// DefaultCacheCapacity by default.
+func WithCacheCapacity(capacity int) Option {
+	return func(c *Config) { c.cacheCapacity = capacity }
+}
+
+// New create new BPE model from the vocab and merges ("a b" lines), i.e.:
+//
+//	bpe.New(vocab, merges, bpe.WithUnkToken("<unk>"), bpe.WithDropout(0.1))
+func New(vocab model.Vocab, mergesData []string, opts ...Option) (*BPE, error) {
 	merges, err := CreateMerges(vocab, mergesData)
 	if err != nil {
 		return nil, err
 	}
 
-	// vc := interface{}(vocab).(model.Vocab)
-
 	builder := &BpeBuilder{
 		config: Config{
-			files:                   nil,
-			vocab:                   &vocab,
-			merges:                  merges,
-			cacheCapacity:           DefaultCacheCapacity,
-			dropout:                 dropout,
-			unkToken:                unkToken,
-			continuingSubwordPrefix: continuingSubwordPrefix,
-			endOfWordSuffix:         endOfWordSuffix,
+			vocab:         &vocab,
+			merges:        merges,
+			cacheCapacity: DefaultCacheCapacity,
 		},
 	}
+	for _, opt := range opts {
+		opt(&builder.config)
+	}
 
 	return builder.Build()
 }
+
+var _ tokenizer.VocabEditor = BPE{}
+
+// AddToken implements tokenizer.VocabEditor. A new token gets the id following
+// the largest one. It is not the result of any merge, so it is only produced
+// when matched as a whole, i.e. as an added token.
+func (b BPE) AddToken(token string) int {
+	b.edit()
+	id := model.AddToken(*b.Vocab, *b.VocabR, token)
+	b.ClearCache()
+
+	return id
+}
+
+// Resize implements tokenizer.VocabEditor. Merges involving a removed token are
+// removed too. The `unk` token cannot be removed.
+func (b BPE) Resize(newSize int, padTokenPattern string) error {
+	b.edit()
+	if b.UnkToken != nil {
+		if id, ok := (*b.Vocab)[*b.UnkToken]; ok && id >= newSize {
+			return fmt.Errorf("Resize vocab error: cannot remove unk token %q of id %d", *b.UnkToken, id)
+		}
+	}
+
+	if err := model.Resize(*b.Vocab, *b.VocabR, newSize, padTokenPattern); err != nil {
+		return err
+	}
+	for pair, val := range *b.Merges {
+		if pair.C1 >= newSize || pair.C2 >= newSize || val.NewId >= newSize {
+			delete(*b.Merges, pair)
+		}
+	}
+	b.ClearCache()
+
+	return nil
+}
+
+// RemapIds implements tokenizer.VocabEditor. Merges are remapped accordingly.
+func (b BPE) RemapIds(mapping func(id int) int) error {
+	b.edit()
+	if err := model.RemapIds(*b.Vocab, *b.VocabR, mapping); err != nil {
+		return err
+	}
+
+	merges := make(Merges, len(*b.Merges))
+	for pair, val := range *b.Merges {
+		merges[Pair{mapping(pair.C1), mapping(pair.C2)}] = PairVal{val.Rank, mapping(val.NewId)}
+	}
+	*b.Merges = merges
+	b.ClearCache()
+
+	return nil
+}
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
 
 	// "reflect"
 	// "strings"
@@ -164,3 +167,341 @@ func TestBPE_TokenizeWithAndWithoutDropout(t *testing.T) {
 	}
 
 }
+
+func TestBPE_DropoutRandSource(t *testing.T) {
+	vocab := map[string]int{"u": 0, "n": 1, "r": 2, "e": 3, "l": 4, "a": 5, "t": 6, "d": 7,
+		"re": 8, "at": 9, "ed": 10, "un": 11, "ated": 12, "rel": 13, "related": 14, "unrelated": 15}
+	merges := []string{"r e", "a t", "e d", "u n", "at ed", "re l", "rel ated", "un related"}
+
+	tokenize := func(m tokenizer.Model) [][]tokenizer.Token {
+		var out [][]tokenizer.Token
+		for i := 0; i < 20; i++ {
+			toks, err := m.Tokenize("unrelated")
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, toks)
+		}
+		return out
+	}
+	newModel := func(seed int64) *bpe.BPE {
+		m, err := bpe.New(vocab, merges, bpe.WithDropout(0.5), bpe.WithRandSource(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	// The same seed gives the same segmentations, which vary between calls.
+	want := tokenize(newModel(42))
+	if got := tokenize(newModel(42)); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	distinct := make(map[int]bool)
+	for _, toks := range want {
+		distinct[len(toks)] = true
+	}
+	if len(distinct) < 2 {
+		t.Errorf("want varying segmentations, got %v", want)
+	}
+
+	// WithDropout copies the model with another dropout.
+	base, err := bpe.New(vocab, merges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := base.WithDropout(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if toks := tokenize(m)[0]; len(toks) != 9 {
+		t.Errorf("want 9 chars with dropout 1, got %v", toks)
+	}
+	if toks := tokenize(base)[0]; len(toks) != 1 {
+		t.Errorf("want the model unchanged, got %v", toks)
+	}
+	m, err = base.WithDropout(0.5, rand.NewSource(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tokenize(m); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if _, err := base.WithDropout(1.5, nil); err == nil {
+		t.Error("want an error for dropout 1.5")
+	}
+}
+
+func TestBPE_TokenizeWord(t *testing.T) {
+	vocab := map[string]int{"<unk>": 0, "h": 1, "é": 2, "l": 3, "o": 4, "hé": 5, "ll": 6, "llo": 7}
+	var merges bpe.Merges = make(map[bpe.Pair]bpe.PairVal)
+	merges[bpe.Pair{C1: vocab["h"], C2: vocab["é"]}] = bpe.PairVal{Rank: 0, NewId: vocab["hé"]}
+	merges[bpe.Pair{C1: vocab["l"], C2: vocab["l"]}] = bpe.PairVal{Rank: 1, NewId: vocab["ll"]}
+	merges[bpe.Pair{C1: vocab["ll"], C2: vocab["o"]}] = bpe.PairVal{Rank: 2, NewId: vocab["llo"]}
+
+	model := bpe.NewBPE(vocab, merges)
+
+	got, err := model.TokenizeWord("héllo", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 5, Value: "hé", Offsets: []int{7, 10}},
+		{Id: 7, Value: "llo", Offsets: []int{10, 13}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	// No `unk` token: unknown chars are reported as error instead of panicking.
+	if _, err := model.TokenizeWord("🚀", 0); err == nil {
+		t.Errorf("want error for unknown char without unk token, got nil")
+	}
+	if _, err := model.Tokenize("🚀"); err == nil {
+		t.Errorf("want Tokenize error for unknown char without unk token, got nil")
+	}
+	if _, err := model.CountTokens("🚀"); err == nil {
+		t.Errorf("want CountTokens error for unknown char without unk token, got nil")
+	}
+
+	unk := "<unk>"
+	model.UnkToken = &unk
+	got, err = model.TokenizeWord("🚀o", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []tokenizer.Token{
+		{Id: 0, Value: "<unk>", Offsets: []int{2, 6}},
+		{Id: 4, Value: "o", Offsets: []int{6, 7}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestBPE_FuseUnkAndByteFallback(t *testing.T) {
+	vocab := map[string]int{"<unk>": 0, "a": 1, "b": 2, "<0xF0>": 3, "<0x9F>": 4, "<0x9A>": 5, "<0x80>": 6, "<0xC3>": 7}
+	newModel := func(fuseUnk, byteFallback bool) *bpe.BPE {
+		builder := bpe.NewBpeBuilder()
+		builder.VocabAndMerges(vocab, make(map[bpe.Pair]bpe.PairVal))
+		builder.UnkToken("<unk>")
+		builder.FuseUnk(fuseUnk)
+		builder.ByteFallback(byteFallback)
+		model, err := builder.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return model
+	}
+
+	tests := []struct {
+		fuseUnk, byteFallback bool
+		want                  []tokenizer.Token
+	}{
+		{false, false, []tokenizer.Token{
+			{Id: 1, Value: "a", Offsets: []int{0, 1}},
+			{Id: 0, Value: "<unk>", Offsets: []int{1, 5}},
+			{Id: 0, Value: "<unk>", Offsets: []int{5, 7}},
+			{Id: 0, Value: "<unk>", Offsets: []int{7, 8}},
+			{Id: 2, Value: "b", Offsets: []int{8, 9}},
+		}},
+		{true, false, []tokenizer.Token{
+			{Id: 1, Value: "a", Offsets: []int{0, 1}},
+			{Id: 0, Value: "<unk>", Offsets: []int{1, 8}},
+			{Id: 2, Value: "b", Offsets: []int{8, 9}},
+		}},
+		// "é" (0xC3 0xA9) misses a byte token so it falls back to `unk`.
+		{true, true, []tokenizer.Token{
+			{Id: 1, Value: "a", Offsets: []int{0, 1}},
+			{Id: 3, Value: "<0xF0>", Offsets: []int{1, 2}},
+			{Id: 4, Value: "<0x9F>", Offsets: []int{2, 3}},
+			{Id: 5, Value: "<0x9A>", Offsets: []int{3, 4}},
+			{Id: 6, Value: "<0x80>", Offsets: []int{4, 5}},
+			{Id: 0, Value: "<unk>", Offsets: []int{5, 8}},
+			{Id: 2, Value: "b", Offsets: []int{8, 9}},
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := newModel(tt.fuseUnk, tt.byteFallback).Tokenize("a🚀éxb")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("fuseUnk=%v byteFallback=%v: want %+v, got %+v", tt.fuseUnk, tt.byteFallback, tt.want, got)
+		}
+	}
+}
+
+func TestBPE_MergesLeftmostFirst(t *testing.T) {
+	vocab := map[string]int{"a": 0, "aa": 1}
+	var merges bpe.Merges = make(map[bpe.Pair]bpe.PairVal)
+	merges[bpe.Pair{C1: vocab["a"], C2: vocab["a"]}] = bpe.PairVal{Rank: 0, NewId: vocab["aa"]}
+
+	got, err := bpe.NewBPE(vocab, merges).Tokenize("aaaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 1, Value: "aa", Offsets: []int{0, 2}},
+		{Id: 1, Value: "aa", Offsets: []int{2, 4}},
+		{Id: 0, Value: "a", Offsets: []int{4, 5}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+// newGPT2BPE builds a BPE model from the GPT-2 merges, the vocab holding the
+// chars of the merges and the merged tokens.
+func newGPT2BPE(tb testing.TB, cacheCapacity int) *bpe.BPE {
+	tb.Helper()
+
+	data, err := os.ReadFile("../../pretrained/model/gpt2-merges.txt")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")[1:]
+
+	vocab := make(map[string]int)
+	add := func(token string) {
+		if _, ok := vocab[token]; !ok {
+			vocab[token] = len(vocab)
+		}
+	}
+	for _, line := range lines {
+		for _, r := range strings.ReplaceAll(line, " ", "") {
+			add(string(r))
+		}
+	}
+	for _, line := range lines {
+		add(strings.ReplaceAll(line, " ", ""))
+	}
+	merges, err := bpe.CreateMerges(vocab, lines)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	builder := bpe.NewBpeBuilder()
+	builder.VocabAndMerges(vocab, *merges)
+	builder.CacheCapacity(cacheCapacity)
+	model, err := builder.Build()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return model
+}
+
+func TestBPE_Cache(t *testing.T) {
+	model := newGPT2BPE(t, 10)
+	uncached := newGPT2BPE(t, 0)
+
+	want, err := uncached.Tokenize("Ġtokenization")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				got, err := model.Tokenize("Ġtokenization")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !reflect.DeepEqual(want, got) {
+					t.Errorf("want %+v, got %+v", want, got)
+					return
+				}
+				// Modifying tokens must not alter the cached word.
+				got[0].Offsets[1] = 42
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := model.Cache.GetSize(); got != 1 {
+		t.Errorf("want 1 cached word, got %d", got)
+	}
+}
+
+const benchmarkText = `In a chat-serving workload most words repeat from one request to the next,
+so that the merged words of the cache save most of the merge loop. The quick brown
+fox jumps over the lazy dog while tokenizers split the text into words, then words
+into subword tokens by applying the merges of lowest rank first.`
+
+func BenchmarkBPE_Tokenize(b *testing.B) {
+	words := strings.Fields(benchmarkText)
+	for i, word := range words {
+		words[i] = "Ġ" + word
+	}
+
+	for _, capacity := range []int{bpe.DefaultCacheCapacity, 0} {
+		model := newGPT2BPE(b, capacity)
+		b.Run(fmt.Sprintf("cache=%d", capacity), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, word := range words {
+					if _, err := model.Tokenize(word); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBPE_EditVocab(t *testing.T) {
+	vocab := map[string]int{"a": 0, "b": 1, "ab": 2, "abb": 3}
+	var merges bpe.Merges = make(map[bpe.Pair]bpe.PairVal)
+	merges[bpe.Pair{C1: 0, C2: 1}] = bpe.PairVal{Rank: 0, NewId: 2}
+	merges[bpe.Pair{C1: 2, C2: 1}] = bpe.PairVal{Rank: 1, NewId: 3}
+	builder := bpe.NewBpeBuilder()
+	builder.VocabAndMerges(vocab, merges)
+	builder.CacheCapacity(10)
+	model, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenize := func() []int {
+		toks, err := model.Tokenize("abb")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, tok := range toks {
+			ids = append(ids, tok.Id)
+		}
+		return ids
+	}
+	if got := tokenize(); !reflect.DeepEqual([]int{3}, got) {
+		t.Fatalf("want [3], got %v", got)
+	}
+
+	// Remapped ids are used by the merges and not served from the cache.
+	if err := model.RemapIds(func(id int) int { return 3 - id }); err != nil {
+		t.Fatal(err)
+	}
+	if got := tokenize(); !reflect.DeepEqual([]int{0}, got) {
+		t.Errorf("remapped: want [0], got %v", got)
+	}
+
+	// Removing "abb" removes the merge producing it.
+	if err := model.RemapIds(func(id int) int { return 3 - id }); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Resize(3, "<pad_%d>"); err != nil {
+		t.Fatal(err)
+	}
+	if got := tokenize(); !reflect.DeepEqual([]int{2, 1}, got) {
+		t.Errorf("resized: want [2 1], got %v", got)
+	}
+	if got := model.AddToken("abb"); got != 3 {
+		t.Errorf("want added id 3, got %d", got)
+	}
+}
@@ -1,104 +1,101 @@
 package bpe
 
 import (
-	"sync"
+	"hash/maphash"
+
+	"github.com/season-studio/tokenizer/util"
 )
 
-// Cache is a map with read-write mutex included
-// to hold map of `word` strings
-// E.g. https://tour.golang.org/concurrency/9
-// NOTE: can we you sync.Map struct instead???
+// cacheShards is the number of shards of a Cache, to reduce lock contention
+// when tokenizing concurrently.
+const cacheShards = 16
+
+// Cache is a size-capped LRU cache of merged words, safe for concurrent use.
+// It is split into shards so that concurrent lookups of different words rarely
+// wait on each other.
 type Cache struct {
-	mux sync.RWMutex
-	// cmap     map[interface{}]interface{}
-	cmap     map[string]Word
+	shards   []*util.LRU[string, Word]
+	seed     maphash.Seed
 	Capacity int
 }
 
 type CacheItem struct {
-	// Key   interface{}
-	// Value interface{}
 	Key   string
 	Value Word // `word` string
 }
 
 // NewCache create an empty Cache with a specified capacity
 func NewCache(capacity int) *Cache {
-	return &Cache{
-		// cmap:     make(map[interface{}]interface{}, capacity),
-		cmap:     make(map[string]Word, capacity),
+	n := cacheShards
+	if capacity < n {
+		n = 1
+	}
+
+	c := &Cache{
+		shards:   make([]*util.LRU[string, Word], n),
+		seed:     maphash.MakeSeed(),
 		Capacity: capacity,
 	}
+	// Splits the capacity so that the shards hold at most `capacity` words.
+	for i := range c.shards {
+		shardCapacity := capacity / n
+		if i < capacity%n {
+			shardCapacity++
+		}
+		c.shards[i] = util.NewLRU[string, Word](shardCapacity)
+	}
+
+	return c
+}
+
+func (c *Cache) shard(key string) *util.LRU[string, Word] {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	return c.shards[maphash.String(c.seed, key)%uint64(len(c.shards))]
 }
 
 // Clear clears the cache
 func (c *Cache) Clear() {
-	c.mux.Lock()
-	defer c.mux.Unlock()
-
-	// Create a new map instead of using delete loop
-	c.cmap = make(map[string]Word, c.Capacity)
+	for _, s := range c.shards {
+		s.Clear()
+	}
 }
 
 // Get returns the value associated with the given key
 func (c *Cache) Get(key string) (Word, bool) {
-	c.mux.RLock()
-	defer c.mux.RUnlock()
-	word, ok := c.cmap[key]
-
-	return word, ok
+	return c.shard(key).Get(key)
 }
 
 // GetValues returns slices of values associated with input keys
 func (c *Cache) GetValues(keys []string) []Word {
-	c.mux.RLock() // Use read lock for concurrent reads
-	defer c.mux.RUnlock()
-
-	var res []Word
-	res = make([]Word, len(keys)) // Pre-allocate slice for better performance
-
+	res := make([]Word, len(keys))
 	for i, k := range keys {
-		res[i] = c.cmap[k]
+		res[i], _ = c.Get(k)
 	}
 
 	return res
 }
 
-// SetValues sets values in the cache, respecting capacity limits
+// SetValues sets values in the cache, evicting the least recently used words
+// when full. The cached words must not be modified afterwards.
 func (c *Cache) SetValues(values []CacheItem) {
-	c.mux.Lock()
-	defer c.mux.Unlock()
-
-	// Check if we're already at capacity
-	if len(c.cmap) >= c.Capacity {
-		return
-	}
-
-	// Calculate how many items we can add
-	remaining := c.Capacity - len(c.cmap)
-	if remaining <= 0 {
-		return
-	}
-
-	// Add items up to capacity
-	for i, v := range values {
-		if i >= remaining {
-			break
-		}
-		c.cmap[v.Key] = v.Value
+	for _, v := range values {
+		c.shard(v.Key).Add(v.Key, v.Value)
 	}
 }
 
 // GetSize returns the current number of items in the cache
 func (c *Cache) GetSize() int {
-	c.mux.RLock()
-	defer c.mux.RUnlock()
-	return len(c.cmap)
+	var n int
+	for _, s := range c.shards {
+		n += s.Len()
+	}
+	return n
 }
 
-// IsFull returns true if the cache has reached its capacity
+// IsFull returns true if the cache has reached its capacity. As words are
+// spread over shards, words may be evicted before the cache is full.
 func (c *Cache) IsFull() bool {
-	c.mux.RLock()
-	defer c.mux.RUnlock()
-	return len(c.cmap) >= c.Capacity
+	return c.GetSize() >= c.Capacity
 }
@@ -1,6 +1,7 @@
 package bpe
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 )
@@ -13,8 +14,8 @@ func TestNewCache(t *testing.T) {
 		t.Errorf("Expected capacity %d, got %d", capacity, cache.Capacity)
 	}
 
-	if len(cache.cmap) != 0 {
-		t.Errorf("Expected empty map, got map with %d items", len(cache.cmap))
+	if cache.GetSize() != 0 {
+		t.Errorf("Expected empty map, got map with %d items", cache.GetSize())
 	}
 }
 
@@ -29,8 +30,8 @@ func TestCache_Clear(t *testing.T) {
 
 	cache.Clear()
 
-	if len(cache.cmap) != 0 {
-		t.Errorf("Expected empty map after Clear(), got map with %d items", len(cache.cmap))
+	if cache.GetSize() != 0 {
+		t.Errorf("Expected empty map after Clear(), got map with %d items", cache.GetSize())
 	}
 }
 
@@ -133,8 +134,8 @@ func TestCache_SetValues(t *testing.T) {
 			}
 			cache.SetValues(tt.itemsToAdd)
 
-			if len(cache.cmap) != tt.expectedLength {
-				t.Errorf("Expected %d items in cache, got %d", tt.expectedLength, len(cache.cmap))
+			if cache.GetSize() != tt.expectedLength {
+				t.Errorf("Expected %d items in cache, got %d", tt.expectedLength, cache.GetSize())
 			}
 		})
 	}
@@ -231,8 +232,8 @@ func TestCache_ConcurrentWrites(t *testing.T) {
 	wg.Wait()
 
 	// Verify that the cache size is within capacity
-	if len(cache.cmap) > cache.Capacity {
-		t.Errorf("Cache size %d exceeds capacity %d", len(cache.cmap), cache.Capacity)
+	if cache.GetSize() > cache.Capacity {
+		t.Errorf("Cache size %d exceeds capacity %d", cache.GetSize(), cache.Capacity)
 	}
 }
 
@@ -286,7 +287,38 @@ func TestCache_ConcurrentReadsAndWrites(t *testing.T) {
 	wg.Wait()
 
 	// Verify that the cache size is within capacity
-	if len(cache.cmap) > cache.Capacity {
-		t.Errorf("Cache size %d exceeds capacity %d", len(cache.cmap), cache.Capacity)
+	if cache.GetSize() > cache.Capacity {
+		t.Errorf("Cache size %d exceeds capacity %d", cache.GetSize(), cache.Capacity)
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewCache(2)
+	cache.SetValues([]CacheItem{
+		{Key: "a", Value: *NewWord()},
+		{Key: "b", Value: *NewWord()},
+	})
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatalf("want %q cached", "a")
+	}
+	cache.SetValues([]CacheItem{{Key: "c", Value: *NewWord()}})
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := cache.Get(key); ok != want {
+			t.Errorf("%q: want cached %v, got %v", key, want, ok)
+		}
+	}
+}
+
+func TestCache_ShardedCapacity(t *testing.T) {
+	cache := NewCache(100)
+	for i := 0; i < 1000; i++ {
+		cache.SetValues([]CacheItem{{Key: fmt.Sprint(i), Value: *NewWord()}})
+	}
+	if got := cache.GetSize(); got > 100 || got < 50 {
+		t.Errorf("want about 100 cached words, got %d", got)
+	}
+	if _, ok := cache.Get("999"); !ok {
+		t.Errorf("want the last word cached")
 	}
 }
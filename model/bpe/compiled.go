@@ -0,0 +1,158 @@
+package bpe
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/season-studio/tokenizer/model"
+)
+
+// AppendCompiled appends the compiled form of the vocab and merges of the model
+// to b, see `model.AppendVocab`. Merges are stored as ids sorted by pair, so
+// that they are looked up in place. The options of the model are not part of
+// it.
+func (b BPE) AppendCompiled(buf []byte) []byte {
+	b.materialize()
+
+	pairs := make([]Pair, 0, len(*b.Merges))
+	for pair := range *b.Merges {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].less(pairs[j]) })
+
+	merges := make([]int32, 0, 4*len(pairs))
+	for _, pair := range pairs {
+		val := (*b.Merges)[pair]
+		merges = append(merges, int32(pair.C1), int32(pair.C2), int32(val.Rank), int32(val.NewId))
+	}
+
+	buf = model.AppendVocab(buf, *b.Vocab)
+	return model.AppendInt32s(buf, merges)
+}
+
+func (p Pair) less(other Pair) bool {
+	if p.C1 != other.C1 {
+		return p.C1 < other.C1
+	}
+	return p.C2 < other.C2
+}
+
+// compiledMerges are (a, b, rank, id) merges sorted by pair, looked up by
+// binary search.
+type compiledMerges []int32
+
+func (m compiledMerges) lookup(pair Pair) (PairVal, bool) {
+	n := len(m) / 4
+	i := sort.Search(n, func(i int) bool {
+		return !(Pair{int(m[4*i]), int(m[4*i+1])}).less(pair)
+	})
+	if i == n || int(m[4*i]) != pair.C1 || int(m[4*i+1]) != pair.C2 {
+		return PairVal{}, false
+	}
+
+	return PairVal{int(m[4*i+2]), int(m[4*i+3])}, true
+}
+
+// compiledIndex is the vocab and merges of a model loaded by LoadCompiled,
+// looked up in place. The Vocab, VocabR and Merges maps of the model are only
+// built when needed, i.e. by GetVocab, and used instead once the vocab is
+// edited.
+type compiledIndex struct {
+	vocab  *model.CompiledVocab
+	merges compiledMerges
+	once   sync.Once
+	edited atomic.Bool
+}
+
+// index returns the index the model looks tokens and merges up in, nil if it
+// uses its maps.
+func (b *BPE) index() *compiledIndex {
+	if b.compiled == nil || b.compiled.edited.Load() {
+		return nil
+	}
+
+	return b.compiled
+}
+
+// materialize builds the Vocab, VocabR and Merges maps of a model loaded by
+// LoadCompiled.
+func (b *BPE) materialize() {
+	if b.compiled == nil {
+		return
+	}
+
+	b.compiled.once.Do(func() {
+		*b.Vocab = b.compiled.vocab.Vocab()
+		vocabR := make(model.VocabR, len(*b.Vocab))
+		for tok, id := range *b.Vocab {
+			vocabR[id] = tok
+		}
+		*b.VocabR = vocabR
+
+		merges := b.compiled.merges
+		*b.Merges = make(Merges, len(merges)/4)
+		for i := 0; i < len(merges); i += 4 {
+			(*b.Merges)[Pair{int(merges[i]), int(merges[i+1])}] = PairVal{int(merges[i+2]), int(merges[i+3])}
+		}
+	})
+}
+
+// edit switches a model loaded by LoadCompiled to its maps before they are
+// edited.
+func (b *BPE) edit() {
+	if b.compiled != nil {
+		b.materialize()
+		b.compiled.edited.Store(true)
+	}
+}
+
+// LoadCompiled creates a BPE model from its compiled form, see
+// `AppendCompiled`. The tokens and merges are looked up in place, so data must
+// not be modified afterwards, and no map is built: loading only checks the
+// index and the merge ids, in linear time.
+func LoadCompiled(data []byte, opts ...Option) (*BPE, error) {
+	vocab, data, err := model.ReadCompiledVocab(data)
+	if err != nil {
+		return nil, err
+	}
+	values, _, err := model.ReadInt32s(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%4 != 0 {
+		return nil, fmt.Errorf("Compiled vocab error: %d merge values are no (a, b, rank, id) merges", len(values))
+	}
+
+	merges := compiledMerges(values)
+	for i := 0; i < len(merges); i += 4 {
+		for _, id := range []int32{merges[i], merges[i+1], merges[i+3]} {
+			if _, ok := vocab.IdToToken(int(id)); !ok {
+				return nil, fmt.Errorf("Compiled vocab error: merge %d has id %d out of the vocab", i/4, id)
+			}
+		}
+		if i > 0 && !(Pair{int(merges[i-4]), int(merges[i-3])}).less(Pair{int(merges[i]), int(merges[i+1])}) {
+			return nil, fmt.Errorf("Compiled vocab error: merge %d is not sorted by pair", i/4)
+		}
+	}
+
+	builder := &BpeBuilder{
+		config: Config{
+			vocab:         new(model.Vocab),
+			merges:        new(Merges),
+			cacheCapacity: DefaultCacheCapacity,
+		},
+	}
+	for _, opt := range opts {
+		opt(&builder.config)
+	}
+
+	b, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	b.compiled = &compiledIndex{vocab: vocab, merges: merges}
+
+	return b, nil
+}
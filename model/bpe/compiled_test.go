@@ -0,0 +1,92 @@
+package bpe_test
+
+import (
+	"reflect"
+	"testing"
+
+	bpe "github.com/season-studio/tokenizer/model/bpe"
+)
+
+func newCompiledTestBPE(t *testing.T, merges bpe.Merges) *bpe.BPE {
+	t.Helper()
+
+	// Ids have a gap, so that they are not all the index of their token.
+	vocab := map[string]int{"<unk>": 0, "a": 1, "b": 2, "ab": 3, "abb": 4, "c": 6}
+	builder := bpe.NewBpeBuilder()
+	builder.VocabAndMerges(vocab, merges)
+	builder.UnkToken("<unk>")
+	model, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return model
+}
+
+func TestLoadCompiled(t *testing.T) {
+	model := newCompiledTestBPE(t, bpe.Merges{
+		{C1: 1, C2: 2}: {Rank: 0, NewId: 3},
+		{C1: 3, C2: 2}: {Rank: 1, NewId: 4},
+	})
+	loaded, err := bpe.LoadCompiled(model.AppendCompiled(nil), bpe.WithUnkToken("<unk>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{"abb", "ab", "abcab", "bba", "xabbc"} {
+		want, err := model.Tokenize(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := loaded.Tokenize(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%q: want %+v, got %+v", input, want, got)
+		}
+	}
+
+	for tok, id := range model.GetVocab() {
+		if got, ok := loaded.TokenToId(tok); !ok || got != id {
+			t.Errorf("want id %d for %q, got %d, %v", id, tok, got, ok)
+		}
+		if got, ok := loaded.IdToToken(id); !ok || got != tok {
+			t.Errorf("want token %q for %d, got %q, %v", tok, id, got, ok)
+		}
+	}
+	if _, ok := loaded.TokenToId("abc"); ok {
+		t.Errorf("want no id for %q", "abc")
+	}
+	if _, ok := loaded.IdToToken(5); ok {
+		t.Errorf("want no token for id 5")
+	}
+	if got := loaded.GetVocabSize(); got != 6 {
+		t.Errorf("want vocab size 6, got %d", got)
+	}
+	if want, got := model.GetVocab(), loaded.GetVocab(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want vocab %v, got %v", want, got)
+	}
+
+	// An edited model uses its maps.
+	if err := loaded.RemapIds(func(id int) int { return 6 - id }); err != nil {
+		t.Fatal(err)
+	}
+	toks, err := loaded.Tokenize("abb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toks) != 1 || toks[0].Id != 2 || toks[0].Value != "abb" {
+		t.Errorf("remapped: want abb of id 2, got %+v", toks)
+	}
+}
+
+func TestLoadCompiled_InvalidMerge(t *testing.T) {
+	model := newCompiledTestBPE(t, bpe.Merges{
+		{C1: 1, C2: 2}: {Rank: 0, NewId: 3},
+		{C1: 3, C2: 5}: {Rank: 1, NewId: 4},
+	})
+	if _, err := bpe.LoadCompiled(model.AppendCompiled(nil)); err == nil {
+		t.Error("want an error for a merge id out of the vocab")
+	}
+}
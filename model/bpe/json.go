@@ -0,0 +1,68 @@
+package bpe
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/season-studio/tokenizer/model"
+	"github.com/season-studio/tokenizer/util"
+)
+
+var _ json.Marshaler = new(BPE)
+
+// MarshalJSON implements json.Marshaler. The model is serialized as in a
+// HuggingFace `tokenizer.json` file, merges being ordered by rank.
+func (b BPE) MarshalJSON() ([]byte, error) {
+	b.materialize()
+	vocab := b.Vocab
+	if vocab == nil {
+		vocab = &model.Vocab{}
+	}
+
+	var merges [][2]string
+	if b.Merges != nil {
+		type pairRank struct {
+			Pair Pair
+			Rank int
+		}
+		pairRanks := make([]pairRank, 0, len(*b.Merges))
+		for pair, pairVal := range *b.Merges {
+			pairRanks = append(pairRanks, pairRank{pair, pairVal.Rank})
+		}
+		sort.Slice(pairRanks, func(i, j int) bool {
+			return pairRanks[i].Rank < pairRanks[j].Rank
+		})
+
+		merges = make([][2]string, len(pairRanks))
+		for i, p := range pairRanks {
+			c1, _ := b.IdToToken(p.Pair.C1)
+			c2, _ := b.IdToToken(p.Pair.C2)
+			merges[i] = [2]string{c1, c2}
+		}
+	}
+	if merges == nil {
+		merges = [][2]string{}
+	}
+
+	return util.MarshalJSON(struct {
+		Type                    string      `json:"type"`
+		Dropout                 *float32    `json:"dropout"`
+		UnkToken                *string     `json:"unk_token"`
+		ContinuingSubwordPrefix *string     `json:"continuing_subword_prefix"`
+		EndOfWordSuffix         *string     `json:"end_of_word_suffix"`
+		FuseUnk                 bool        `json:"fuse_unk"`
+		ByteFallback            bool        `json:"byte_fallback"`
+		Vocab                   model.Vocab `json:"vocab"`
+		Merges                  [][2]string `json:"merges"`
+	}{
+		Type:                    "BPE",
+		Dropout:                 b.Dropout,
+		UnkToken:                b.UnkToken,
+		ContinuingSubwordPrefix: b.ContinuingSubwordPrefix,
+		EndOfWordSuffix:         b.EndOfWordSuffix,
+		FuseUnk:                 b.FuseUnk,
+		ByteFallback:            b.ByteFallback,
+		Vocab:                   *vocab,
+		Merges:                  merges,
+	})
+}
@@ -41,6 +41,7 @@ type TConfig struct {
 	InitialAlphabet         CharSet
 	ContinuingSubwordPrefix *string
 	EndOfWordSuffix         *string
+	EmitReport              bool
 }
 
 // BpeTrainerBuilder can be used to create a `BpeTrainer`
@@ -59,6 +60,7 @@ func NewBPETrainerBuilder() *BpeTrainerBuilder {
 		InitialAlphabet:         nil,
 		ContinuingSubwordPrefix: nil,
 		EndOfWordSuffix:         nil,
+		EmitReport:              false,
 	}
 	return &BpeTrainerBuilder{
 		Config: &config,
@@ -105,8 +107,18 @@ func (btb *BpeTrainerBuilder) EndOfWordSuffix(suffix string) {
 	btb.Config.EndOfWordSuffix = &suffix
 }
 
+// EmitReport set whether to collect a `TrainingReport` while training
+func (btb *BpeTrainerBuilder) EmitReport(emit bool) {
+	btb.Config.EmitReport = emit
+}
+
 // Build constructs the final BpeTrainer
 func (btb *BpeTrainerBuilder) Build() *BpeTrainer {
+	var report *tokenizer.TrainingReport
+	if btb.Config.EmitReport {
+		report = &tokenizer.TrainingReport{}
+	}
+
 	return &BpeTrainer{
 		MinFrequency:            btb.Config.MinFrequency,
 		VocabSize:               btb.Config.VocabSize,
@@ -116,6 +128,7 @@ func (btb *BpeTrainerBuilder) Build() *BpeTrainer {
 		InitialAlphabet:         btb.Config.InitialAlphabet,
 		ContinuingSubwordPrefix: btb.Config.ContinuingSubwordPrefix,
 		EndOfWordSuffix:         btb.Config.EndOfWordSuffix,
+		report:                  report,
 	}
 }
 
@@ -148,6 +161,9 @@ type BpeTrainer struct {
 	ContinuingSubwordPrefix *string
 	// An optional suffix to characterize and end-of-word subword
 	EndOfWordSuffix *string
+	// Statistics filled while training if enabled with `EmitReport`.
+	// NOTE: it is a pointer so that trainer copies share the same report.
+	report *tokenizer.TrainingReport
 }
 
 func NewBpeTrainer(minFreq int, vocabSize int) *BpeTrainer {
@@ -161,6 +177,16 @@ func NewBpeTrainer(minFreq int, vocabSize int) *BpeTrainer {
 
 }
 
+// Report returns the `TrainingReport` of the last training or nil if reports
+// are not enabled.
+func (bt *BpeTrainer) Report() *tokenizer.TrainingReport {
+	if bt.report == nil || bt.report.TokenFrequencies == nil {
+		return nil
+	}
+
+	return bt.report
+}
+
 func (bt *BpeTrainer) setupProgress() interface{} {
 	if bt.ShowProgress {
 		// TODO: setup progress bar
@@ -185,34 +211,27 @@ func (bt *BpeTrainer) updateProgress(p interface{}, len int, msg string) {
 }
 
 // addSpecialTokens adds the provided special tokens to the initial vocabulary
-func (bt *BpeTrainer) addSpecialTokens(w2id map[string]int, id2w []string) {
+func (bt *BpeTrainer) addSpecialTokens(w2id map[string]int, id2w []string) (map[string]int, []string) {
 	for _, tok := range bt.SpecialTokens {
 		if _, ok := w2id[tok.Content]; !ok {
 			id2w = append(id2w, tok.Content)
 			w2id[tok.Content] = len(id2w) - 1
 		}
 	}
+
+	return w2id, id2w
 }
 
-// computeAlphabet generate maps of `chars` from input words and limit it if relevant
-func (bt *BpeTrainer) computeAlphabet(wc map[string]int) (wordToId map[string]int, IdToWord []string) {
+// computeAlphabet adds the `chars` of input words to the given maps and limit
+// them if relevant
+func (bt *BpeTrainer) computeAlphabet(wc map[string]int, w2id map[string]int, id2w []string) (wordToId map[string]int, IdToWord []string) {
 	// compute the alphabet from seen words
-	var (
-		alphabet map[string]int = make(map[string]int)
-		w2id     map[string]int = make(map[string]int)
-		id2w     []string
-	)
+	var alphabet map[string]int = make(map[string]int)
 
 	for word, count := range wc {
 		chars := strings.Split(word, "")
 		for _, char := range chars {
-			// if char not existing, newCount will be zero
-			if newCount, ok := alphabet[char]; ok {
-				newCount += count
-				alphabet[char] = newCount
-			} else {
-				alphabet[char] = newCount
-			}
+			alphabet[char] += count
 		}
 	}
 
@@ -252,16 +271,13 @@ func (bt *BpeTrainer) computeAlphabet(wc map[string]int) (wordToId map[string]in
 		}
 	}
 
-	// remove the unwanted `chars`
+	// remove the least frequent `chars`, the initial alphabet having the
+	// highest frequency is removed last.
 	if toRemove > 0 {
-		fmt.Println("We are going to remove some chars...")
-		// 1. Sort `kept` by char alphabetically?
-		// TODO: double-check this (sort by char or freq? asc or desc)
-		sort.Slice(kept, func(i, j int) bool {
-			return kept[i].Char < kept[j].Char
+		sort.SliceStable(kept, func(i, j int) bool {
+			return kept[i].Freq > kept[j].Freq
 		})
-		// 2. Remove the unwanted chars
-		kept = kept[:toRemove]
+		kept = kept[:len(kept)-toRemove]
 	}
 
 	// // Keep the initial alphabet (sorted by determinism)
@@ -316,26 +332,16 @@ func (bt *BpeTrainer) tokenizeWords(wc map[string]int, w2id map[string]int, id2w
 		chars := strings.Split(word, "")
 
 		for i, c := range chars {
-			var s string
+			// skip `chars` removed from the alphabet
 			if _, ok := w2id[c]; ok {
+				s := c
 				// Add the `continuingSubwordPrefix` if relevant
-				if i == 0 { // first `char`
-					if prefix := bt.ContinuingSubwordPrefix; prefix != nil {
-						s = fmt.Sprintf("%v%v", &prefix, c)
-					} else {
-						s = c
-					}
-				}
-				if i > 0 && i < len(chars)-1 {
-					s = c
+				if prefix := bt.ContinuingSubwordPrefix; prefix != nil && i > 0 {
+					s = *prefix + s
 				}
 				// Add the `endOfWordSuffix` if relevant
-				if i == len(chars)-1 { // last `char`
-					if suffix := bt.EndOfWordSuffix; suffix != nil {
-						s = fmt.Sprintf("%v%v", &suffix, c)
-					} else {
-						s = c
-					}
+				if suffix := bt.EndOfWordSuffix; suffix != nil && i == len(chars)-1 {
+					s = s + *suffix
 				}
 
 				// Insert the new formed string if neccessary
@@ -425,7 +431,7 @@ func (bt *BpeTrainer) countPairs(words []Word, counts []int, progress interface{
 
 				// fmt.Printf("Word: %v\n", word)
 				var window = 2
-				for x := 0; i < len(word.Symbols)-1; x += window - 1 {
+				for x := 0; x < len(word.Symbols)-1; x += window - 1 {
 					y := x + window
 					if y > len(word.Symbols) {
 						// TODO: should we stop when last chunk < chunk size or we just return it
@@ -487,7 +493,13 @@ func (bt *BpeTrainer) countPairs(words []Word, counts []int, progress interface{
 			}
 
 			for pair, hashSet := range res.WT {
-				whereToUpdate[pair] = hashSet
+				if h, ok := whereToUpdate[pair]; ok {
+					for k := range hashSet {
+						h[k] = struct{}{}
+					}
+				} else {
+					whereToUpdate[pair] = hashSet
+				}
 			}
 		}
 
@@ -568,8 +580,7 @@ func (bt *BpeTrainer) countPairsM(words []Word, counts []int, progress interface
 // 3. ProcessTokens(words map[string]int, tokens []string)
 
 func (bt *BpeTrainer) WithProgressBar() bool {
-	// TODO: implement a progress bar
-	return false
+	return bt.ShowProgress
 }
 
 // Train trains bpe model on input wordCounts and returns
@@ -606,23 +617,23 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 
 	// NOTE: temporary add progress bar for counting trained words ONLY
 	// TODO: setup progress bar for the whole training process.
-	pb := progressbar.New(int(bt.VocabSize))
+	var pb *progressbar.ProgressBar
+	if bt.ShowProgress {
+		pb = progressbar.New(int(bt.VocabSize))
+	}
 
 	// 1. Add all special tokens to the vocabular
-	fmt.Printf("1. Adding special tokens...\n")
-	bt.addSpecialTokens(wordToId, idToWord)
+	wordToId, idToWord = bt.addSpecialTokens(wordToId, idToWord)
 
 	// 2. Compute the initial alphabet (create maps of `chars`)
 	// These maps will be updated if `prefix`, `suffix` are added
 	// in the following steps
-	fmt.Printf("2. Creating maps of 'chars'...\n")
-	wordToId, idToWord = bt.computeAlphabet(wordCounts)
+	wordToId, idToWord = bt.computeAlphabet(wordCounts, wordToId, idToWord)
 	// fmt.Printf("Before id2Word: length %v - values:  %v\n", len(idToWord), idToWord)
 	// fmt.Printf("Before word2Id: length %v - %v\n", len(wordToId), wordToId)
 
 	// 3. Tokenize words (add prefix, suffix to the map if relevant)
 	// NOTE: `char` maps (wordToId, idToWord) will be updated if added prefix and/or suffix
-	fmt.Printf("3. Tokenizing words...\n")
 	bt.updateProgress(progress, len(wordCounts), "Tokenize word")
 
 	words, counts, wordToId, idToWord := bt.tokenizeWords(wordCounts, wordToId, idToWord, progress)
@@ -631,12 +642,26 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 
 	bt.finalizeProgress(progress, len(words))
 
+	// Corpus frequency of each token id, kept up to date while merging and only
+	// collected if a report is requested.
+	var (
+		tokenFreqs  []int
+		mergeCounts []tokenizer.MergeCount
+	)
+	if bt.report != nil {
+		tokenFreqs = make([]int, len(idToWord))
+		for i, w := range words {
+			for _, sym := range w.Symbols {
+				tokenFreqs[sym.C] += counts[i]
+			}
+		}
+	}
+
 	// 4. Count pairs in words
 	// words will be split to `char`, paired and count their frequency.
 	// The result will be a map of (pairs and their frequency) and
 	// a map of (pairs and their int hashset - which is a map of key with no value)
 	// represent a position to update pair.
-	fmt.Printf("4. Pairing and counting co-occurence...\n")
 	bt.updateProgress(progress, len(words), "Count pairs")
 
 	var (
@@ -648,18 +673,21 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 	// pairCounts, whereToUpdate = bt.countPairsM(words, counts, progress)
 
 	// 5. Do merges
-	fmt.Printf("5. Merging pairs from top count down...\n")
 
-	// countComparator sort heap descendingly by `Count` field of TMerge struct
+	// countComparator sort heap descendingly by `Count` field of TMerge struct.
+	// Ties are broken by the smallest pair so that training is deterministic.
 	countComparator := func(a, b interface{}) int {
 		c1 := a.(TMerge).Count
 		c2 := b.(TMerge).Count
 
 		if c1 == c2 {
-			aTime := a.(TMerge).Time
-			bTime := b.(TMerge).Time
+			p1 := a.(TMerge).Pair
+			p2 := b.(TMerge).Pair
+			if p1.C1 != p2.C1 {
+				return utils.IntComparator(p1.C1, p2.C1)
+			}
 
-			return utils.TimeComparator(bTime, aTime)
+			return utils.IntComparator(p1.C2, p2.C2)
 		}
 
 		return utils.IntComparator(c2, c1)
@@ -678,8 +706,6 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 				Count: count,
 				Pos:   pos,
 			})
-		} else {
-			fmt.Println("Something wrong here...")
 		}
 	}
 
@@ -710,13 +736,13 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 		// fmt.Println(len(wordToId))
 		// Stop as soon as we have a big enough vocabulary
 		if len(wordToId) >= bt.VocabSize {
-			pb.Finish()
-			fmt.Printf("\nVocab has enough words (%d)! Done.\n", bt.VocabSize)
+			if pb != nil {
+				pb.Finish()
+			}
 			break
 		}
 
 		if queue.Empty() {
-			fmt.Println("queue is empty")
 			break
 		}
 
@@ -725,8 +751,9 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 
 		// fmt.Printf("Top: count = %v | pair: %v\n", top.Count, top.Pair)
 
+		// The count is outdated: re-queue it with its current count.
 		if top.Count != pairCounts[top.Pair] {
-			pairCounts[top.Pair] = top.Count
+			top.Count = pairCounts[top.Pair]
 			queue.Push(top)
 			// fmt.Println("Not found. Push new one...")
 
@@ -734,7 +761,6 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 		}
 
 		if top.Count < 1 || top.Count < bt.MinFrequency {
-			fmt.Printf("\nWe stop because top count is hit limit\n")
 			break
 		}
 
@@ -777,6 +803,14 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 		idToWord = append(idToWord, newToken)
 		wordToId[newToken] = newTokenId
 		merges = append(merges, TMerges{top.Pair, newTokenId})
+		if bt.report != nil {
+			tokenFreqs = append(tokenFreqs, 0)
+			mergeCounts = append(mergeCounts, tokenizer.MergeCount{
+				Pair:  [2]string{idToWord[top.Pair.C1], idToWord[top.Pair.C2]},
+				Token: newToken,
+				Count: top.Count,
+			})
+		}
 
 		type TChange struct {
 			WChange WChange
@@ -788,10 +822,18 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 			// NOTE: words []Word
 			// TODO: merge each of these words concurrently
 			w := words[i]
+			nSymbols := len(w.Symbols)
 			wChanges, err := w.Merge(top.Pair.C1, top.Pair.C2, newTokenId)
 			if err != nil {
 				fmt.Println(err)
 			}
+			if bt.report != nil {
+				// each merge replaces 2 symbols by 1
+				merged := (nSymbols - len(w.Symbols)) * counts[i]
+				tokenFreqs[top.Pair.C1] -= merged
+				tokenFreqs[top.Pair.C2] -= merged
+				tokenFreqs[newTokenId] += merged
+			}
 			// update back `words` list. Because after merging, word map may be changed.
 			words[i] = w
 			for _, wc := range wChanges {
@@ -803,21 +845,13 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 		// NOTE: reset `whereToUpdate` first
 		whereToUpdate = make(map[Pair]UintSet)
 		for _, tc := range changes {
-			if tc.WChange.Change > 0 {
-				count := tc.WChange.Change * counts[tc.WIndex]
-
-				pair := Pair{tc.WChange.C1, tc.WChange.C2}
+			count := tc.WChange.Change * counts[tc.WIndex]
+			pair := Pair{tc.WChange.C1, tc.WChange.C2}
 
-				if _, ok := pairCounts[pair]; !ok {
-					pairCounts[pair] = count
-					// fmt.Printf("pair: %v - count: %v\n", pair, count)
-				} else {
-					c, _ := pairCounts[pair]
-					c += count
-					pairCounts[pair] = c
-					// fmt.Printf("pair: %v - count: %v\n", pair, c)
-				}
+			// Pairs broken by the merge lose their count
+			pairCounts[pair] += count
 
+			if tc.WChange.Change > 0 {
 				var hs UintSet = make(map[int]struct{})
 				if h, ok := whereToUpdate[pair]; !ok {
 					// if not existing, we create new one anyway
@@ -841,19 +875,25 @@ func (bt *BpeTrainer) train(wordCounts map[string]int) (BPE, []tokenizer.AddedTo
 			// fmt.Printf("pair chars: '%v%v' - pair: %v - count: %v - pos: %v\n", char1, char2, pair, count, pos)
 			if count > 0 {
 				queue.Push(TMerge{
-					pair, count, pos, time.Now(),
+					Pair:  pair,
+					Count: count,
+					Pos:   pos,
 				})
 			}
 		}
 
-		// TODO: update progress bar by 1
-
-		pb.Add(1)
+		if pb != nil {
+			pb.Add(1)
+		}
 
 	} // end of `for` loop
 
 	bt.finalizeProgress(progress, len(merges))
 
+	if bt.report != nil {
+		*bt.report = *tokenizer.NewTrainingReport(idToWord, tokenFreqs, mergeCounts)
+	}
+
 	var builder *BpeBuilder
 	builder = NewBpeBuilder()
 
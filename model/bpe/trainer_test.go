@@ -1,10 +1,12 @@
 package bpe_test
 
 import (
+	"encoding/json"
 	"reflect"
 	"sort"
 	"testing"
 
+	"github.com/season-studio/tokenizer"
 	bpe "github.com/season-studio/tokenizer/model/bpe"
 )
 
@@ -78,6 +80,132 @@ func TestBpeTrainer_Train(t *testing.T) {
 
 }
 
+func TestBpeTrainer_Report(t *testing.T) {
+	wordCounts := map[string]int{"aab": 2, "ab": 3}
+
+	btb := bpe.NewBPETrainerBuilder()
+	btb.VocabSize(4)
+	btb.ShowProgress(false)
+	btb.EmitReport(true)
+	trainer := btb.Build()
+
+	if trainer.Report() != nil {
+		t.Errorf("want nil report before training")
+	}
+
+	model, _ := trainer.Train(wordCounts)
+	report := trainer.Report()
+
+	wantFreqs := []tokenizer.TokenFrequency{
+		{Token: "ab", Frequency: 3},
+		{Token: "aab", Frequency: 2},
+		{Token: "a", Frequency: 0},
+		{Token: "b", Frequency: 0},
+	}
+	if !reflect.DeepEqual(wantFreqs, report.TokenFrequencies) {
+		t.Errorf("want %v, got %v", wantFreqs, report.TokenFrequencies)
+	}
+
+	wantMerges := []tokenizer.MergeCount{
+		{Pair: [2]string{"a", "b"}, Token: "ab", Count: 5},
+		{Pair: [2]string{"a", "ab"}, Token: "aab", Count: 2},
+	}
+	if !reflect.DeepEqual(wantMerges, report.Merges) {
+		t.Errorf("want %v, got %v", wantMerges, report.Merges)
+	}
+
+	wantCoverage := []tokenizer.CoveragePoint{
+		{Tokens: 1, Coverage: 0.6},
+		{Tokens: 2, Coverage: 1},
+		{Tokens: 3, Coverage: 1},
+		{Tokens: 4, Coverage: 1},
+	}
+	if !reflect.DeepEqual(wantCoverage, report.Coverage) {
+		t.Errorf("want %v, got %v", wantCoverage, report.Coverage)
+	}
+
+	// Frequencies must sum to the number of tokens of the segmented corpus.
+	var total int
+	for word, count := range wordCounts {
+		toks, err := model.Tokenize(word)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += len(toks) * count
+	}
+	if report.TotalFrequency != total {
+		t.Errorf("want total frequency %v, got %v", total, report.TotalFrequency)
+	}
+
+	data, err := report.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded tokenizer.TrainingReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*report, decoded) {
+		t.Errorf("want %v, got %v", *report, decoded)
+	}
+}
+
+func TestBpeTrainer_ReportSums(t *testing.T) {
+	wordCounts := map[string]int{
+		"roses": 1, "are": 2, "red": 1, "voilets": 1, "blue": 1, "BERT": 1,
+		"is": 2, "big": 1, "and": 1, "so": 1, "GPT-2": 1,
+	}
+
+	btb := bpe.NewBPETrainerBuilder()
+	btb.MinFrequency(2)
+	btb.VocabSize(30)
+	btb.EmitReport(true)
+	trainer := btb.Build()
+
+	model, _ := trainer.Train(wordCounts)
+	report := trainer.Report()
+
+	want := make(map[string]int)
+	var total int
+	for word, count := range wordCounts {
+		toks, err := model.Tokenize(word)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tok := range toks {
+			want[tok.Value] += count
+		}
+		total += len(toks) * count
+	}
+
+	got := make(map[string]int)
+	var sum int
+	for _, tf := range report.TokenFrequencies {
+		if tf.Frequency > 0 {
+			got[tf.Token] = tf.Frequency
+		}
+		sum += tf.Frequency
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if sum != total || report.TotalFrequency != total {
+		t.Errorf("want total frequency %v, got %v (sum %v)", total, report.TotalFrequency, sum)
+	}
+
+	if len(report.Coverage) != model.GetVocabSize() {
+		t.Errorf("want %v coverage points, got %v", model.GetVocabSize(), len(report.Coverage))
+	}
+	if last := report.Coverage[len(report.Coverage)-1].Coverage; last != 1 {
+		t.Errorf("want full coverage with all tokens, got %v", last)
+	}
+	for i := 1; i < len(report.Coverage); i++ {
+		if report.Coverage[i].Coverage < report.Coverage[i-1].Coverage {
+			t.Errorf("coverage decreases at point %v", i)
+		}
+	}
+}
+
 func sortedKeys(m map[string]int) []string {
 	keys := make([]string, len(m))
 	i := 0
@@ -88,3 +216,87 @@ func sortedKeys(m map[string]int) []string {
 	sort.Strings(keys)
 	return keys
 }
+
+func TestBpeTrainer_SpecialTokensAndAlphabet(t *testing.T) {
+	wordCounts := map[string]int{"aab": 3, "ab": 2, "c": 1}
+
+	btb := bpe.NewBPETrainerBuilder()
+	btb.ShowProgress(false)
+	btb.VocabSize(100)
+	btb.SpecialTokens([]tokenizer.AddedToken{
+		tokenizer.NewAddedToken("<unk>", true),
+		tokenizer.NewAddedToken("<pad>", true),
+	})
+	btb.InitialAlphabet(bpe.CharSet{"z": {}})
+	btb.LimitAlphabet(3)
+	trainer := btb.Build()
+
+	model, specials := trainer.Train(wordCounts)
+	vocab := model.GetVocab()
+
+	if len(specials) != 2 {
+		t.Errorf("want 2 special tokens, got %v", len(specials))
+	}
+	for i, tok := range []string{"<unk>", "<pad>"} {
+		if id, ok := vocab[tok]; !ok || id != i {
+			t.Errorf("want %q with id %v, got %v (%v)", tok, i, id, ok)
+		}
+	}
+
+	// The initial alphabet is always kept, "c" is the least frequent char.
+	for _, c := range []string{"a", "b", "z"} {
+		if _, ok := vocab[c]; !ok {
+			t.Errorf("want %q in vocab", c)
+		}
+	}
+	if _, ok := vocab["c"]; ok {
+		t.Errorf("want %q removed by the alphabet limit", "c")
+	}
+}
+
+func TestBpeTrainer_PrefixSuffix(t *testing.T) {
+	wordCounts := map[string]int{"ab": 2}
+
+	btb := bpe.NewBPETrainerBuilder()
+	btb.ShowProgress(false)
+	btb.VocabSize(100)
+	btb.ContinuingSubwordPrefix("##")
+	btb.EndOfWordSuffix("</w>")
+	trainer := btb.Build()
+
+	model, _ := trainer.Train(wordCounts)
+
+	var got []string
+	for tok := range model.GetVocab() {
+		got = append(got, tok)
+	}
+	sort.Strings(got)
+	want := []string{"##b</w>", "a", "ab</w>", "b"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestBpeTrainer_Deterministic(t *testing.T) {
+	wordCounts := map[string]int{"low": 4, "lower": 1, "lowest": 1, "newer": 1, "newest": 1}
+
+	train := func() bpe.BPE {
+		btb := bpe.NewBPETrainerBuilder()
+		btb.ShowProgress(false)
+		btb.VocabSize(30)
+		btb.MinFrequency(2)
+		model, _ := btb.Build().Train(wordCounts)
+		return model.(bpe.BPE)
+	}
+
+	want := train()
+	if _, ok := want.GetVocab()["low"]; !ok {
+		t.Errorf("want %q merged, got %v", "low", want.GetVocab())
+	}
+	for i := 0; i < 10; i++ {
+		got := train()
+		if !reflect.DeepEqual(want.GetVocab(), got.GetVocab()) || !reflect.DeepEqual(*want.Merges, *got.Merges) {
+			t.Fatalf("want %v, got %v", *want.Merges, *got.Merges)
+		}
+	}
+}
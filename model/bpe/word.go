@@ -1,12 +1,13 @@
 package bpe
 
 import (
+	"container/heap"
 	"errors"
 	"math/rand"
+	"sync"
 	"time"
 
-	"github.com/emirpasic/gods/trees/binaryheap"
-	"github.com/emirpasic/gods/utils"
+	"github.com/season-studio/tokenizer/util"
 )
 
 const DefaultCacheCapacity int = 10000
@@ -109,47 +110,16 @@ func NewWord() *Word {
 }
 
 func (w *Word) Add(c int, byteLen int) {
-
-	var symbols []Symbol
-
 	symLen := len(w.Symbols)
-
-	if symLen == 0 {
-		newSym := Symbol{
-			C:    c,
-			Prev: -1,
-			Next: -1,
-			Len:  byteLen,
-		}
-		symbols = append(symbols, newSym)
-	} else {
-		for i, s := range w.Symbols {
-			// first
-			if i == 0 {
-				sym := &w.Symbols[i]
-				sym.Next = 1
-				sym.Prev = -1
-				symbols = append(symbols, *sym)
-			} else if i == symLen-1 { // last
-				sym := &w.Symbols[i]
-				sym.Next = symLen
-				sym.Prev = symLen - 2
-				symbols = append(symbols, *sym)
-			} else {
-				symbols = append(symbols, s)
-			}
-		}
-
-		newSym := Symbol{
-			C:    c,
-			Prev: symLen - 1,
-			Next: -1,
-			Len:  byteLen,
-		}
-		symbols = append(symbols, newSym)
+	if symLen > 0 {
+		w.Symbols[symLen-1].Next = symLen
 	}
-
-	w.Symbols = symbols
+	w.Symbols = append(w.Symbols, Symbol{
+		C:    c,
+		Prev: symLen - 1,
+		Next: -1,
+		Len:  byteLen,
+	})
 }
 
 type Pair struct {
@@ -227,7 +197,7 @@ func (w *Word) Merge(c1, c2, replacement int) ([]WChange, error) {
 			}
 
 			// If there are other `chars` after the pair
-			if i > 0 && i < len(w.Symbols)-1 {
+			if i < len(w.Symbols)-1 {
 				// fmt.Println("Yes, there some char after the pair")
 				changes = append(changes, WChange{
 					C1:     second.C,
@@ -254,146 +224,167 @@ func (w *Word) Merge(c1, c2, replacement int) ([]WChange, error) {
 	return changes, nil
 }
 
+// mergeQueue is a min-heap of merges, lowest rank then leftmost first, as
+// HuggingFace does.
+type mergeQueue struct {
+	merges []Merge
+	skip   []Merge
+}
+
+var mergeQueuePool = sync.Pool{
+	New: func() interface{} { return new(mergeQueue) },
+}
+
+func (q *mergeQueue) Len() int { return len(q.merges) }
+
+func (q *mergeQueue) Less(i, j int) bool {
+	if q.merges[i].Rank != q.merges[j].Rank {
+		return q.merges[i].Rank < q.merges[j].Rank
+	}
+	return q.merges[i].Pos < q.merges[j].Pos
+}
+
+func (q *mergeQueue) Swap(i, j int) { q.merges[i], q.merges[j] = q.merges[j], q.merges[i] }
+
+func (q *mergeQueue) Push(x interface{}) { q.merges = append(q.merges, x.(Merge)) }
+
+func (q *mergeQueue) Pop() interface{} {
+	last := q.merges[len(q.merges)-1]
+	q.merges = q.merges[:len(q.merges)-1]
+	return last
+}
+
+// MergeAll applies the merges to the word, lowest rank first. With a dropout,
+// each merge is skipped with this probability, drawn from a source of fixed
+// seed so that the output is the same on every run.
 func (w *Word) MergeAll(merges map[Pair]PairVal, dropoutOpt ...float32) {
 	var dropout float32 = 0.0
 	if dropoutOpt != nil {
 		dropout = dropoutOpt[0]
 	}
 
-	// countComaparator return the `smaller` rank value
-	// if both ranks are equal, then return one with smaller timestamp
-	countComparator := func(a, b interface{}) int {
-		c1 := a.(Merge).Rank
-		c2 := b.(Merge).Rank
-
-		if c1 == c2 {
-			aTime := a.(Merge).Time
-			bTime := b.(Merge).Time
+	var src rand.Source
+	if dropout > 0.0 {
+		src = rand.NewSource(99)
+	}
+	w.mergeAll(Merges(merges), dropout, src)
+}
 
-			return utils.TimeComparator(aTime, bTime)
-		}
+// mergeTable looks up the (rank, newId) merge of a pair, see Merges and
+// compiledMerges.
+type mergeTable interface {
+	lookup(pair Pair) (PairVal, bool)
+}
 
-		return utils.IntComparator(c1, c2)
-	}
+func (m Merges) lookup(pair Pair) (PairVal, bool) {
+	val, ok := m[pair]
+	return val, ok
+}
 
-	var queue = binaryheap.NewWith(countComparator)
+// mergeAll applies the merges to the word, skipping each of them with
+// probability dropout drawn from src, see `util.Float32`.
+func (w *Word) mergeAll(merges mergeTable, dropout float32, src rand.Source) {
+	// The queue buffers are reused across calls.
+	queue := mergeQueuePool.Get().(*mergeQueue)
+	defer func() {
+		queue.merges, queue.skip = queue.merges[:0], queue.skip[:0]
+		mergeQueuePool.Put(queue)
+	}()
 
 	// Load items to the heap
-	var window = 2
-
-	for i := 0; i < len(w.Symbols)-1; i += window - 1 {
-		j := i + window
-		if j >= len(w.Symbols) {
-			j = len(w.Symbols)
-		}
-		slice := w.Symbols[i:j]
+	for i := 0; i < len(w.Symbols)-1; i++ {
 		pair := Pair{
-			C1: slice[0].C,
-			C2: slice[1].C,
+			C1: w.Symbols[i].C,
+			C2: w.Symbols[i+1].C,
 		}
 
 		// NOTE: if found, push to the queue. If not, continue
-		m, ok := merges[pair] // m is PairVal type with pair's rank and newId values
-		if ok {
-			// log.Fatalf("Cannot find a 'merge' for the pair: %+v\n", pair)
-			var merge Merge = Merge{
+		if m, ok := merges.lookup(pair); ok { // m is PairVal type with pair's rank and newId values
+			queue.merges = append(queue.merges, Merge{
 				Pos:   i,
 				Rank:  m.Rank,
 				NewId: m.NewId,
-			}
-
-			queue.Push(merge)
+			})
 		}
 	}
-
-	var skip []Merge
-	r := rand.New(rand.NewSource(99)) // use fixed seed to produce same output on every run.
+	heap.Init(queue)
 
 	// Pop the queue until empty
-	for {
-		top, ok := queue.Pop()
-		if !ok { // it's empty
-			break
+	for queue.Len() > 0 {
+		top := heap.Pop(queue).(Merge)
+
+		if dropout > 0.0 && util.Float32(src) < dropout {
+			queue.skip = append(queue.skip, top)
+			continue
+		}
+
+		// Re-insert the skipped elements
+		for _, s := range queue.skip {
+			heap.Push(queue, s)
 		}
+		queue.skip = queue.skip[:0]
+
+		if w.Symbols[top.Pos].Len == 0 || w.Symbols[top.Pos].Next == -1 {
+			// Do nothing if the symbol was merged or is the last one
+			continue
+		}
+
+		nextPos := w.Symbols[top.Pos].Next
+		right := w.Symbols[nextPos]
 
-		if dropout >= 0.0 && r.Float32() < dropout {
-			// if dropout > 0.0 {
-			skip = append(skip, top.(Merge))
-		} else {
-			// Re-insert the skipped elements
-			for _, s := range skip {
-				queue.Push(s)
+		// Make sure we are not processing an expired queue entry
+		targetNewPair := Pair{
+			C1: w.Symbols[top.Pos].C,
+			C2: right.C,
+		}
+		if m, ok := merges.lookup(targetNewPair); !ok || m.NewId != top.NewId {
+			continue
+		}
+
+		// Otherwise, let's merge
+		w.Symbols[top.Pos].MergeWith(&right, top.NewId)
+		// Tag the right part as removed
+		w.Symbols[nextPos].Len = 0
+
+		// Update `prev` on the new `next` to the current pos
+		if right.Next > -1 && right.Next < len(w.Symbols) {
+			w.Symbols[right.Next].Prev = top.Pos
+		}
+
+		// Insert the new pair formed with the previous symbol
+		current := w.Symbols[top.Pos]
+		if current.Prev >= 0 {
+			prevSymbol := w.Symbols[current.Prev]
+			newPair := Pair{
+				C1: prevSymbol.C,
+				C2: current.C,
 			}
+			if m, ok := merges.lookup(newPair); ok {
+				heap.Push(queue, Merge{
+					Pos:   current.Prev,
+					Rank:  m.Rank,
+					NewId: m.NewId,
+				})
+			}
+		}
 
-			if (w.Symbols[top.(Merge).Pos]).Len > 0 {
-				if (w.Symbols[top.(Merge).Pos]).Next == -1 {
-					// Do nothing if the last symbol
-					continue // TODO: do we skip one from outer loop?
-				}
-
-				nextPos := w.Symbols[top.(Merge).Pos].Next
-				right := w.Symbols[nextPos]
-
-				// Make sure we are not processing an expired queue entry
-				targetNewPair := Pair{
-					C1: w.Symbols[top.(Merge).Pos].C,
-					C2: right.C,
-				}
-
-				m, ok := merges[targetNewPair]
-				if !ok || m.NewId != top.(Merge).NewId {
-					continue
-				}
-
-				// Otherwise, let's merge
-				w.Symbols[top.(Merge).Pos].MergeWith(&right, top.(Merge).NewId)
-				// Tag the right part as removed
-				w.Symbols[nextPos].Len = 0
-
-				// Update `prev` on the new `next` to the current pos
-				if right.Next > -1 && right.Next < len(w.Symbols) {
-					// create a variable so that we can asign an address.
-					pos := int(top.(Merge).Pos)
-					w.Symbols[right.Next].Prev = pos
-				}
-
-				// Insert the new pair formed with the previous symbol
-				current := w.Symbols[top.(Merge).Pos]
-				if current.Prev >= 0 {
-					prev := current.Prev
-					prevSymbol := w.Symbols[prev]
-					newPair := Pair{
-						C1: prevSymbol.C,
-						C2: current.C,
-					}
-					if m, ok := merges[newPair]; ok {
-						queue.Push(Merge{
-							Pos:   current.Prev,
-							Rank:  m.Rank,
-							NewId: m.NewId,
-						})
-					}
-				}
-
-				// Insert the new pair formed with the next symbol
-				next := current.Next
-				if int(next) < len(w.Symbols) && next > -1 {
-					nextSymbol := w.Symbols[next]
-					newPair := Pair{
-						C1: current.C,
-						C2: nextSymbol.C,
-					}
-					if m, ok := merges[newPair]; ok {
-						queue.Push(Merge{
-							Pos:   top.(Merge).Pos,
-							Rank:  m.Rank,
-							NewId: m.NewId,
-						})
-					}
-				}
+		// Insert the new pair formed with the next symbol
+		next := current.Next
+		if next < len(w.Symbols) && next > -1 {
+			nextSymbol := w.Symbols[next]
+			newPair := Pair{
+				C1: current.C,
+				C2: nextSymbol.C,
+			}
+			if m, ok := merges.lookup(newPair); ok {
+				heap.Push(queue, Merge{
+					Pos:   top.Pos,
+					Rank:  m.Rank,
+					NewId: m.NewId,
+				})
 			}
 		}
-	} // End of `for` loop
+	}
 
 	// Filter out the `marked to remove` symbols
 	w.removeSymbols()
@@ -401,7 +392,7 @@ func (w *Word) MergeAll(merges map[Pair]PairVal, dropoutOpt ...float32) {
 
 // removeSymbols removes all symbols with lenth == 0
 func (w *Word) removeSymbols() {
-	var filtered []Symbol
+	filtered := w.Symbols[:0]
 	for _, s := range w.Symbols {
 		if s.Len != 0 {
 			filtered = append(filtered, s)
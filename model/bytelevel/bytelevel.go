@@ -0,0 +1,128 @@
+// Package bytelevel implements a model tokenizing each byte of the input to a
+// token, as ByT5.
+package bytelevel
+
+import (
+	"fmt"
+
+	"github.com/season-studio/tokenizer"
+)
+
+// VocabSize is the number of tokens of the model, one per byte value.
+const VocabSize = 256
+
+var _ tokenizer.Model = new(ByteLevel)
+
+// ByteLevel is a model of one token per byte, of a fixed vocab of 256 tokens
+// "<0x00>" to "<0xFF>" and ids `offset` to `offset`+255. The offset leaves room
+// for special tokens of lower ids, i.e. ByT5 has an offset of 3 after `<pad>`,
+// `</s>` and `<unk>`. Use it with the `decoder.ByteFallback` and `decoder.Fuse`
+// decoders to decode tokens back to text.
+//
+// Every input can be tokenized, so the model has no `unk` token.
+type ByteLevel struct {
+	offset int
+}
+
+// New creates a ByteLevel model whose byte b has id offset+b.
+func New(offset int) (*ByteLevel, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("ByteLevel error: invalid negative offset %d", offset)
+	}
+
+	return &ByteLevel{offset: offset}, nil
+}
+
+// Offset returns the id of byte 0.
+func (m *ByteLevel) Offset() int {
+	return m.offset
+}
+
+// ByteToken returns the token of the byte, i.e. "<0x41>" for 'A'.
+func ByteToken(b byte) string {
+	return fmt.Sprintf("<0x%02X>", b)
+}
+
+// parseByteToken returns the byte of a token "<0xXX>".
+func parseByteToken(token string) (byte, bool) {
+	if len(token) != 6 || token[:3] != "<0x" || token[5] != '>' {
+		return 0, false
+	}
+
+	var b byte
+	for _, c := range token[3:5] {
+		switch {
+		case c >= '0' && c <= '9':
+			b = b<<4 | byte(c-'0')
+		case c >= 'A' && c <= 'F':
+			b = b<<4 | byte(c-'A'+10)
+		default:
+			return 0, false
+		}
+	}
+
+	return b, true
+}
+
+// GetVocab returns the vocab of the 256 byte tokens.
+func (m *ByteLevel) GetVocab() map[string]int {
+	vocab := make(map[string]int, VocabSize)
+	for b := 0; b < VocabSize; b++ {
+		vocab[ByteToken(byte(b))] = m.offset + b
+	}
+
+	return vocab
+}
+
+// GetVocabSize returns 256.
+func (m *ByteLevel) GetVocabSize() int {
+	return VocabSize
+}
+
+// Tokenize tokenizes the sequence to a token per byte.
+func (m *ByteLevel) Tokenize(sequence string) ([]tokenizer.Token, error) {
+	return m.TokenizeWord(sequence, 0)
+}
+
+// TokenizeWord tokenizes the word to a token per byte. Token offsets are the
+// byte offsets shifted by `offsetsBase`, so all the bytes of a multi-byte char
+// have offsets within the char.
+//
+// It is safe for concurrent use as the model is read-only.
+func (m *ByteLevel) TokenizeWord(word string, offsetsBase int) ([]tokenizer.Token, error) {
+	tokens := make([]tokenizer.Token, len(word))
+	for i := 0; i < len(word); i++ {
+		tokens[i] = tokenizer.Token{
+			Id:      m.offset + int(word[i]),
+			Value:   ByteToken(word[i]),
+			Offsets: []int{offsetsBase + i, offsetsBase + i + 1},
+		}
+	}
+
+	return tokens, nil
+}
+
+// TokenToId returns the id of a byte token.
+func (m *ByteLevel) TokenToId(token string) (int, bool) {
+	b, ok := parseByteToken(token)
+	if !ok {
+		return 0, false
+	}
+
+	return m.offset + int(b), true
+}
+
+// IdToToken returns the byte token of the id, if in [offset, offset+256).
+func (m *ByteLevel) IdToToken(id int) (string, bool) {
+	if id < m.offset || id >= m.offset+VocabSize {
+		return "", false
+	}
+
+	return ByteToken(byte(id - m.offset)), true
+}
+
+// Save does nothing: the vocab is fixed and the offset is serialized with the
+// tokenizer.
+func (m *ByteLevel) Save(dir string, prefixOpt ...string) error {
+	return nil
+}
@@ -0,0 +1,53 @@
+package bytelevel_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model/bytelevel"
+)
+
+func TestByteLevel(t *testing.T) {
+	m, err := bytelevel.New(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.TokenizeWord("Aé", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 68, Value: "<0x41>", Offsets: []int{1, 2}},
+		{Id: 198, Value: "<0xC3>", Offsets: []int{2, 3}},
+		{Id: 172, Value: "<0xA9>", Offsets: []int{3, 4}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	if id, ok := m.TokenToId("<0xFF>"); !ok || id != 258 {
+		t.Errorf("want id 258, got %d, %v", id, ok)
+	}
+	for _, tok := range []string{"<0xff>", "<0x1>", "a", "<0xGG>"} {
+		if _, ok := m.TokenToId(tok); ok {
+			t.Errorf("want no id of %q", tok)
+		}
+	}
+	if tok, ok := m.IdToToken(3); !ok || tok != "<0x00>" {
+		t.Errorf("want <0x00>, got %q, %v", tok, ok)
+	}
+	for _, id := range []int{2, 259} {
+		if _, ok := m.IdToToken(id); ok {
+			t.Errorf("want no token of id %d", id)
+		}
+	}
+	if vocab := m.GetVocab(); len(vocab) != 256 || vocab["<0x0A>"] != 13 {
+		t.Errorf("want 256 tokens, <0x0A> of id 13, got %d tokens, %d", len(vocab), vocab["<0x0A>"])
+	}
+
+	if _, err := bytelevel.New(-1); err == nil {
+		t.Error("want error for a negative offset")
+	}
+}
@@ -0,0 +1,19 @@
+package bytelevel
+
+import (
+	"encoding/json"
+
+	"github.com/season-studio/tokenizer/util"
+)
+
+var _ json.Marshaler = new(ByteLevel)
+
+// MarshalJSON implements json.Marshaler. The model is serialized in the
+// `tokenizer.json` format with type "ByteLevel" and its offset, the vocab
+// being implicit.
+func (m *ByteLevel) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type   string `json:"type"`
+		Offset int    `json:"offset"`
+	}{"ByteLevel", m.offset})
+}
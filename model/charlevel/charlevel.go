@@ -0,0 +1,145 @@
+// Package charlevel implements a model tokenizing each Unicode char of the
+// input to a token.
+package charlevel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model"
+	"github.com/season-studio/tokenizer/util"
+)
+
+var _ tokenizer.Model = new(CharLevel)
+
+// CharLevel is a model of one token per Unicode char. Chars which are not in
+// the vocab are tokenized to the `unk` token, or are an error if the model has
+// none. Use it with the `decoder.Fuse` decoder to decode tokens back to text.
+type CharLevel struct {
+	vocab    map[string]int
+	vocabR   map[int]string
+	unkToken string
+}
+
+// New creates a CharLevel of the vocab of chars. `unkToken` may be "" for a
+// model without `unk` token, else it must be in the vocab.
+func New(vocab map[string]int, unkToken string) (*CharLevel, error) {
+	if unkToken != "" {
+		if _, ok := vocab[unkToken]; !ok {
+			return nil, fmt.Errorf("CharLevel error: unk token %q: %w", unkToken, tokenizer.ErrTokenNotInVocab)
+		}
+	}
+
+	vocabR := make(map[int]string, len(vocab))
+	for tok, id := range vocab {
+		vocabR[id] = tok
+	}
+
+	return &CharLevel{
+		vocab:    vocab,
+		vocabR:   vocabR,
+		unkToken: unkToken,
+	}, nil
+}
+
+// NewFromText creates a CharLevel of the distinct chars of the texts, of ids
+// in order of first occurrence after the `unk` token, if any, of id 0.
+func NewFromText(texts []string, unkToken string) *CharLevel {
+	vocab := make(map[string]int)
+	if unkToken != "" {
+		vocab[unkToken] = 0
+	}
+	for _, text := range texts {
+		for _, r := range text {
+			if _, ok := vocab[string(r)]; !ok {
+				vocab[string(r)] = len(vocab)
+			}
+		}
+	}
+
+	m, _ := New(vocab, unkToken)
+	return m
+}
+
+// GetVocab returns the model vocab.
+func (m *CharLevel) GetVocab() map[string]int {
+	return m.vocab
+}
+
+// GetVocabSize returns the size of the vocab.
+func (m *CharLevel) GetVocabSize() int {
+	return len(m.vocab)
+}
+
+// GetUnkToken returns the `unk` token, nil if the model has none.
+func (m *CharLevel) GetUnkToken() *string {
+	if m.unkToken == "" {
+		return nil
+	}
+	return &m.unkToken
+}
+
+// Tokenize tokenizes the sequence to a token per char.
+func (m *CharLevel) Tokenize(sequence string) ([]tokenizer.Token, error) {
+	return m.TokenizeWord(sequence, 0)
+}
+
+// TokenizeWord tokenizes the word to a token per char, the value of `unk`
+// tokens being the char. Token offsets are byte offsets shifted by
+// `offsetsBase`. Invalid UTF-8 bytes are a char each.
+//
+// It is safe for concurrent use as the model is read-only.
+func (m *CharLevel) TokenizeWord(word string, offsetsBase int) ([]tokenizer.Token, error) {
+	tokens := make([]tokenizer.Token, 0, utf8.RuneCountInString(word))
+	for i := 0; i < len(word); {
+		_, size := utf8.DecodeRuneInString(word[i:])
+		char := word[i : i+size]
+
+		id, ok := m.vocab[char]
+		if !ok {
+			if m.unkToken == "" {
+				return nil, fmt.Errorf("CharLevel error: char %q without unk token: %w", char, tokenizer.ErrTokenNotInVocab)
+			}
+			id = m.vocab[m.unkToken]
+		}
+		tokens = append(tokens, tokenizer.Token{
+			Id:      id,
+			Value:   char,
+			Offsets: []int{offsetsBase + i, offsetsBase + i + size},
+		})
+		i += size
+	}
+
+	return tokens, nil
+}
+
+// TokenToId returns the id of the token if existing.
+func (m *CharLevel) TokenToId(token string) (int, bool) {
+	id, ok := m.vocab[token]
+	return id, ok
+}
+
+// IdToToken returns the token of the id if existing.
+func (m *CharLevel) IdToToken(id int) (string, bool) {
+	tok, ok := m.vocabR[id]
+	return tok, ok
+}
+
+// Save saves the vocab to "vocab.json" in dir, or "<prefix>-vocab.json" if a
+// prefix is given.
+func (m *CharLevel) Save(dir string, prefixOpt ...string) error {
+	name := "vocab.json"
+	if len(prefixOpt) > 0 {
+		name = prefixOpt[0] + "-vocab.json"
+	}
+
+	data, err := util.MarshalJSON(model.Vocab(m.vocab))
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, name), data, 0644)
+}
@@ -0,0 +1,45 @@
+package charlevel_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model/charlevel"
+)
+
+func TestCharLevelTokenizeWord(t *testing.T) {
+	m, err := charlevel.New(map[string]int{"<unk>": 0, "h": 1, "é": 2, "🚀": 3}, "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.TokenizeWord("hé🚀x", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 1, Value: "h", Offsets: []int{2, 3}},
+		{Id: 2, Value: "é", Offsets: []int{3, 5}},
+		{Id: 3, Value: "🚀", Offsets: []int{5, 9}},
+		{Id: 0, Value: "x", Offsets: []int{9, 10}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	m = charlevel.NewFromText([]string{"abca", "db"}, "")
+	if want := map[string]int{"a": 0, "b": 1, "c": 2, "d": 3}; !reflect.DeepEqual(want, m.GetVocab()) {
+		t.Errorf("want vocab %v, got %v", want, m.GetVocab())
+	}
+	if m.GetUnkToken() != nil {
+		t.Errorf("want no unk token, got %q", *m.GetUnkToken())
+	}
+	if _, err := m.TokenizeWord("ax", 0); err == nil {
+		t.Error("want error for an unknown char without unk token")
+	}
+
+	if _, err := charlevel.New(map[string]int{"a": 0}, "<unk>"); err == nil {
+		t.Error("want error for an unk token not in vocab")
+	}
+}
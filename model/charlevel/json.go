@@ -0,0 +1,26 @@
+package charlevel
+
+import (
+	"encoding/json"
+
+	"github.com/season-studio/tokenizer/model"
+	"github.com/season-studio/tokenizer/util"
+)
+
+var _ json.Marshaler = new(CharLevel)
+
+// MarshalJSON implements json.Marshaler. The model is serialized in the
+// `tokenizer.json` format with type "CharLevel", `unk_token` being null if the
+// model has none.
+func (m *CharLevel) MarshalJSON() ([]byte, error) {
+	vocab := model.Vocab(m.vocab)
+	if vocab == nil {
+		vocab = model.Vocab{}
+	}
+
+	return util.MarshalJSON(struct {
+		Type     string      `json:"type"`
+		Vocab    model.Vocab `json:"vocab"`
+		UnkToken *string     `json:"unk_token"`
+	}{"CharLevel", vocab, m.GetUnkToken()})
+}
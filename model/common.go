@@ -1,4 +1,179 @@
 package model
 
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/season-studio/tokenizer/util"
+)
+
 type Vocab map[string]int
 type VocabR map[int]string
+
+// MarshalJSON implements json.Marshaler. Tokens are ordered by id.
+func (v Vocab) MarshalJSON() ([]byte, error) {
+	tokens := make([]string, 0, len(v))
+	for tok := range v {
+		tokens = append(tokens, tok)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if v[tokens[i]] != v[tokens[j]] {
+			return v[tokens[i]] < v[tokens[j]]
+		}
+		return tokens[i] < tokens[j]
+	})
+
+	m := &util.OrderedMap{}
+	for _, tok := range tokens {
+		m.Set(tok, v[tok])
+	}
+
+	return m.MarshalJSON()
+}
+
+// ReadVocabTxt reads a `vocab.txt` vocab: one token per line, the id of a token
+// being its line number from 0. Trailing whitespace is trimmed and a repeated
+// token takes the id of its last line, as in HuggingFace.
+func ReadVocabTxt(r io.Reader) (Vocab, error) {
+	vocab := make(Vocab)
+	br := bufio.NewReader(r)
+	for id := 0; ; id++ {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		vocab[strings.TrimRight(line, " \t\r\n\v\f")] = id
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return vocab, nil
+}
+
+// ReadVocabTxtFile reads the `vocab.txt` file at path, see `ReadVocabTxt`.
+func ReadVocabTxtFile(path string) (Vocab, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vocab, err := ReadVocabTxt(f)
+	if err != nil {
+		return nil, fmt.Errorf("Read vocab error: %s: %w", path, err)
+	}
+
+	return vocab, nil
+}
+
+// NextId returns the id following the largest id of the vocab.
+func (v Vocab) NextId() int {
+	next := 0
+	for _, id := range v {
+		if id >= next {
+			next = id + 1
+		}
+	}
+
+	return next
+}
+
+// AddToken adds the token with the id following the largest one and returns
+// its id, or the existing id if the token is already in the vocab.
+func AddToken(vocab Vocab, vocabR VocabR, token string) int {
+	if id, ok := vocab[token]; ok {
+		return id
+	}
+
+	id := vocab.NextId()
+	vocab[token] = id
+	vocabR[id] = token
+
+	return id
+}
+
+// Resize grows or shrinks the vocab so that its ids are exactly
+// [0, newSize). Missing ids are filled with `padTokenPattern` formatted with the
+// id, i.e. "<pad_%d>", and tokens of ids >= newSize are removed. The vocab is
+// left unchanged on error.
+func Resize(vocab Vocab, vocabR VocabR, newSize int, padTokenPattern string) error {
+	if newSize < 0 {
+		return fmt.Errorf("Resize vocab error: invalid size %d", newSize)
+	}
+
+	pads := make(map[string]int)
+	for id := 0; id < newSize; id++ {
+		if _, ok := vocabR[id]; ok {
+			continue
+		}
+		pad := fmt.Sprintf(padTokenPattern, id)
+		if strings.Contains(pad, "%!") {
+			return fmt.Errorf("Resize vocab error: invalid pad token pattern %q, it needs one id verb", padTokenPattern)
+		}
+		if _, ok := vocab[pad]; ok {
+			return fmt.Errorf("Resize vocab error: pad token %q of id %d is already in the vocab", pad, id)
+		}
+		if other, ok := pads[pad]; ok {
+			return fmt.Errorf("Resize vocab error: pad token %q is the same for ids %d and %d", pad, other, id)
+		}
+		pads[pad] = id
+	}
+
+	for tok, id := range vocab {
+		if id >= newSize {
+			delete(vocab, tok)
+			delete(vocabR, id)
+		}
+	}
+	for pad, id := range pads {
+		vocab[pad] = id
+		vocabR[id] = pad
+	}
+
+	return nil
+}
+
+// RemapIds changes the id of every token of the vocab to mapping(id). The
+// mapping must give distinct non-negative ids to distinct ids. The vocab is
+// left unchanged on error.
+func RemapIds(vocab Vocab, vocabR VocabR, mapping func(id int) int) error {
+	newIds := make(map[int]int, len(vocabR))
+	oldIds := make(map[int]int, len(vocabR))
+	for _, id := range vocab {
+		if _, ok := newIds[id]; ok {
+			continue
+		}
+		newId := mapping(id)
+		if newId < 0 {
+			return fmt.Errorf("Remap vocab error: invalid id %d for id %d", newId, id)
+		}
+		if other, ok := oldIds[newId]; ok {
+			return fmt.Errorf("Remap vocab error: ids %d and %d both mapped to id %d", other, id, newId)
+		}
+		newIds[id], oldIds[newId] = newId, id
+	}
+
+	remappedR := make(VocabR, len(vocabR))
+	for id, tok := range vocabR {
+		remappedR[newIds[id]] = tok
+	}
+	for tok, id := range vocab {
+		vocab[tok] = newIds[id]
+	}
+	for id := range vocabR {
+		delete(vocabR, id)
+	}
+	for id, tok := range remappedR {
+		vocabR[id] = tok
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package model
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"unsafe"
+)
+
+// Compiled vocabularies
+// =====================
+//
+// The compiled form of a vocabulary is a flat little-endian encoding meant to be
+// read in place, i.e. from a memory mapped file. Each section starts with its
+// uint64 length and is padded to 8 bytes from the start of the buffer, so that
+// strings and numbers of a buffer aligned to 8 bytes are not copied on little
+// endian platforms. Strings read from a buffer share its memory: the buffer
+// must not be modified afterwards.
+
+// nativeLittleEndian is whether the numbers of a compiled buffer can be used in
+// place.
+var nativeLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// pad appends zeros to b up to a multiple of 8 bytes.
+func pad(b []byte) []byte {
+	for len(b)%8 != 0 {
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+// readSection returns the n items of size bytes of the section starting b, and
+// the bytes following its padding. The padding assumes that b starts at a
+// multiple of 8 bytes from the start of the buffer.
+func readSection(b []byte, size int) (data []byte, n int, rest []byte, err error) {
+	if len(b) < 8 {
+		return nil, 0, nil, fmt.Errorf("Compiled vocab error: truncated section header")
+	}
+	count := binary.LittleEndian.Uint64(b)
+	b = b[8:]
+	if count > uint64(len(b)/size) {
+		return nil, 0, nil, fmt.Errorf("Compiled vocab error: section of %d items of %d bytes exceeds the %d bytes left", count, size, len(b))
+	}
+
+	n = int(count)
+	end := n * size
+	padded := (end + 7) &^ 7
+	if padded > len(b) {
+		padded = len(b)
+	}
+
+	return b[:end], n, b[padded:], nil
+}
+
+// aligned returns whether the numbers of data of the given size can be used in
+// place.
+func aligned(data []byte, size int) bool {
+	return nativeLittleEndian && len(data) > 0 && uintptr(unsafe.Pointer(&data[0]))%uintptr(size) == 0
+}
+
+// AppendInt32s appends the int32 section of v to b.
+func AppendInt32s(b []byte, v []int32) []byte {
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(v)))
+	for _, x := range v {
+		b = binary.LittleEndian.AppendUint32(b, uint32(x))
+	}
+
+	return pad(b)
+}
+
+// ReadInt32s reads the int32 section starting b, see AppendInt32s, and returns
+// the bytes following it.
+func ReadInt32s(b []byte) ([]int32, []byte, error) {
+	data, n, rest, err := readSection(b, 4)
+	if err != nil || n == 0 {
+		return nil, rest, err
+	}
+	if aligned(data, 4) {
+		return unsafe.Slice((*int32)(unsafe.Pointer(&data[0])), n), rest, nil
+	}
+
+	v := make([]int32, n)
+	for i := range v {
+		v[i] = int32(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+
+	return v, rest, nil
+}
+
+// AppendFloat64s appends the float64 section of v to b.
+func AppendFloat64s(b []byte, v []float64) []byte {
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(v)))
+	for _, x := range v {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(x))
+	}
+
+	return b
+}
+
+// ReadFloat64s reads the float64 section starting b, see AppendFloat64s, and
+// returns the bytes following it.
+func ReadFloat64s(b []byte) ([]float64, []byte, error) {
+	data, n, rest, err := readSection(b, 8)
+	if err != nil || n == 0 {
+		return nil, rest, err
+	}
+	if aligned(data, 8) {
+		return unsafe.Slice((*float64)(unsafe.Pointer(&data[0])), n), rest, nil
+	}
+
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+	}
+
+	return v, rest, nil
+}
+
+// AppendStrings appends the sections of strs to b: the end offsets of the
+// strings, then their bytes.
+func AppendStrings(b []byte, strs []string) []byte {
+	ends := make([]int32, len(strs))
+	size := 0
+	for i, s := range strs {
+		size += len(s)
+		ends[i] = int32(size)
+	}
+
+	b = AppendInt32s(b, ends)
+	b = binary.LittleEndian.AppendUint64(b, uint64(size))
+	for _, s := range strs {
+		b = append(b, s...)
+	}
+
+	return pad(b)
+}
+
+// ReadStrings reads the strings starting b, see AppendStrings, and returns the
+// bytes following them. The strings share the memory of b.
+func ReadStrings(b []byte) ([]string, []byte, error) {
+	ends, b, err := ReadInt32s(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, _, rest, err := readSection(b, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	strs := make([]string, len(ends))
+	start := 0
+	for i, e := range ends {
+		end := int(e)
+		if end < start || end > len(data) {
+			return nil, nil, fmt.Errorf("Compiled vocab error: invalid end offset %d of string %d", end, i)
+		}
+		if end > start {
+			strs[i] = unsafe.String(&data[start], end-start)
+		}
+		start = end
+	}
+
+	return strs, rest, nil
+}
+
+// AppendVocab appends the tokens of the vocab, ordered by id, their ids, then
+// the index of the tokens sorted by bytes to b, see CompiledVocab.
+func AppendVocab(b []byte, vocab Vocab) []byte {
+	tokens := make([]string, 0, len(vocab))
+	for tok := range vocab {
+		tokens = append(tokens, tok)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if vocab[tokens[i]] != vocab[tokens[j]] {
+			return vocab[tokens[i]] < vocab[tokens[j]]
+		}
+		return tokens[i] < tokens[j]
+	})
+	ids := make([]int32, len(tokens))
+	sorted := make([]int32, len(tokens))
+	for i, tok := range tokens {
+		ids[i] = int32(vocab[tok])
+		sorted[i] = int32(i)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return tokens[sorted[i]] < tokens[sorted[j]]
+	})
+
+	b = AppendStrings(b, tokens)
+	b = AppendInt32s(b, ids)
+	return AppendInt32s(b, sorted)
+}
+
+// CompiledVocab is a vocab read in place from its compiled form, see
+// AppendVocab. Tokens are looked up by binary search of the index of the
+// tokens sorted by bytes, and ids by binary search of the tokens ordered by
+// id, so that reading it builds no map.
+type CompiledVocab struct {
+	tokens []string // ordered by id
+	ids    []int32  // id of each token, non-decreasing
+	sorted []int32  // indexes of the tokens, by increasing bytes
+}
+
+// ReadCompiledVocab reads the vocab starting b, see AppendVocab, and returns
+// the bytes following it. The vocab shares the memory of b. Its index is
+// checked, in linear time, so that lookups of a corrupted vocab cannot fail.
+func ReadCompiledVocab(b []byte) (*CompiledVocab, []byte, error) {
+	tokens, b, err := ReadStrings(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids, b, err := ReadInt32s(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	sorted, rest, err := ReadInt32s(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ids) != len(tokens) || len(sorted) != len(tokens) {
+		return nil, nil, fmt.Errorf("Compiled vocab error: %d ids and %d indexes for %d tokens", len(ids), len(sorted), len(tokens))
+	}
+
+	for i := 1; i < len(ids); i++ {
+		if ids[i] < ids[i-1] {
+			return nil, nil, fmt.Errorf("Compiled vocab error: id %d of token %d follows id %d", ids[i], i, ids[i-1])
+		}
+	}
+	for i, idx := range sorted {
+		if idx < 0 || int(idx) >= len(tokens) {
+			return nil, nil, fmt.Errorf("Compiled vocab error: index %d out of the %d tokens", idx, len(tokens))
+		}
+		if i > 0 && tokens[idx] <= tokens[sorted[i-1]] {
+			return nil, nil, fmt.Errorf("Compiled vocab error: token %q is not sorted after %q", tokens[idx], tokens[sorted[i-1]])
+		}
+	}
+
+	return &CompiledVocab{tokens: tokens, ids: ids, sorted: sorted}, rest, nil
+}
+
+// TokenToId returns the id of token, false if not in the vocab.
+func (v *CompiledVocab) TokenToId(token string) (int, bool) {
+	i := sort.Search(len(v.sorted), func(i int) bool {
+		return v.tokens[v.sorted[i]] >= token
+	})
+	if i == len(v.sorted) || v.tokens[v.sorted[i]] != token {
+		return 0, false
+	}
+
+	return int(v.ids[v.sorted[i]]), true
+}
+
+// IdToToken returns the token of id, false if not in the vocab.
+func (v *CompiledVocab) IdToToken(id int) (string, bool) {
+	// Ids are usually 0 to the vocab size, the index of their token.
+	if id >= 0 && id < len(v.ids) && int(v.ids[id]) == id {
+		return v.tokens[id], true
+	}
+
+	i := sort.Search(len(v.ids), func(i int) bool {
+		return int(v.ids[i]) >= id
+	})
+	if i == len(v.ids) || int(v.ids[i]) != id {
+		return "", false
+	}
+
+	return v.tokens[i], true
+}
+
+// Len returns the number of tokens.
+func (v *CompiledVocab) Len() int {
+	return len(v.tokens)
+}
+
+// Vocab returns the vocab as a map.
+func (v *CompiledVocab) Vocab() Vocab {
+	vocab := make(Vocab, len(v.tokens))
+	for i, tok := range v.tokens {
+		vocab[tok] = int(v.ids[i])
+	}
+
+	return vocab
+}
+
+// ReadVocab reads the vocab starting b, see AppendVocab, into a map and returns
+// the bytes following it. The tokens share the memory of b.
+func ReadVocab(b []byte) (Vocab, []byte, error) {
+	v, rest, err := ReadCompiledVocab(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return v.Vocab(), rest, nil
+}
@@ -0,0 +1,69 @@
+package unigram
+
+import (
+	"fmt"
+
+	"github.com/season-studio/tokenizer/model"
+)
+
+// AppendCompiled appends the compiled form of the pieces of the model to b: its
+// tokens, scores and trie, see `model.AppendStrings`. The options of the model
+// are not part of it.
+func (u *Unigram) AppendCompiled(b []byte) []byte {
+	tokens := make([]string, len(u.vocab))
+	scores := make([]float64, len(u.vocab))
+	for i, ts := range u.vocab {
+		tokens[i], scores[i] = ts.Token, ts.Score
+	}
+
+	b = model.AppendStrings(b, tokens)
+	b = model.AppendFloat64s(b, scores)
+	b = model.AppendInt32s(b, u.trie.base)
+	b = model.AppendInt32s(b, u.trie.check)
+	return model.AppendInt32s(b, u.trie.value)
+}
+
+// LoadCompiled creates a Unigram model from its compiled form, see
+// `AppendCompiled`. The tokens and the trie are read in place, so data must
+// not be modified afterwards, and the trie is not rebuilt: loading a large
+// vocab from a memory mapped file takes a few milliseconds.
+func LoadCompiled(data []byte, opts ...Option) (*Unigram, error) {
+	tokens, data, err := model.ReadStrings(data)
+	if err != nil {
+		return nil, err
+	}
+	scores, data, err := model.ReadFloat64s(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(tokens) {
+		return nil, fmt.Errorf("Compiled vocab error: %d scores for %d tokens", len(scores), len(tokens))
+	}
+
+	var trie doubleArray
+	for _, v := range []*[]int32{&trie.base, &trie.check, &trie.value} {
+		if *v, data, err = model.ReadInt32s(data); err != nil {
+			return nil, err
+		}
+	}
+	if len(trie.check) == 0 || len(trie.base) != len(trie.check) || len(trie.value) != len(trie.check) {
+		return nil, fmt.Errorf("Compiled vocab error: invalid trie of sizes %d, %d and %d", len(trie.base), len(trie.check), len(trie.value))
+	}
+	for _, id := range trie.value {
+		if int(id) >= len(tokens) {
+			return nil, fmt.Errorf("Compiled vocab error: trie id %d out of the %d tokens", id, len(tokens))
+		}
+	}
+
+	vocab := make([]TokenScore, len(tokens))
+	for i := range vocab {
+		vocab[i] = TokenScore{Token: tokens[i], Score: scores[i]}
+	}
+
+	builder := NewUnigramBuilder().Vocab(vocab)
+	for _, opt := range opts {
+		opt(&builder.config)
+	}
+
+	return builder.build(&trie)
+}
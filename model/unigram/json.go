@@ -0,0 +1,25 @@
+package unigram
+
+import (
+	"encoding/json"
+
+	"github.com/season-studio/tokenizer/util"
+)
+
+var _ json.Marshaler = new(Unigram)
+
+// MarshalJSON implements json.Marshaler. The model is serialized as in a
+// HuggingFace `tokenizer.json` file, each piece being a `[token, score]` pair.
+func (u *Unigram) MarshalJSON() ([]byte, error) {
+	vocab := make([][2]interface{}, len(u.vocab))
+	for i, ts := range u.vocab {
+		vocab[i] = [2]interface{}{ts.Token, ts.Score}
+	}
+
+	return util.MarshalJSON(struct {
+		Type         string           `json:"type"`
+		UnkID        *int             `json:"unk_id"`
+		Vocab        [][2]interface{} `json:"vocab"`
+		ByteFallback bool             `json:"byte_fallback"`
+	}{"Unigram", u.unkID, vocab, u.bytesFallback})
+}
@@ -0,0 +1,170 @@
+package unigram
+
+import (
+	"math"
+	"unicode/utf8"
+)
+
+// kUnkPenalty is the score penalty of unknown chars below the minimum score.
+const kUnkPenalty float64 = 10.0
+
+// latticeNode is a piece spanning `sentence[pos:pos+length]`.
+type latticeNode struct {
+	id     int // piece id in the vocab
+	pos    int // byte position in the sentence
+	length int // byte length of the piece
+	score  float64
+	nodeID int // index of the node in the lattice
+}
+
+// lattice holds all the segmentations of a sentence by the pieces of a vocab.
+type lattice struct {
+	sentence   string
+	nodes      []*latticeNode
+	beginNodes [][]*latticeNode // nodes starting at each byte position
+	endNodes   [][]*latticeNode // nodes ending at each byte position
+}
+
+// newLattice creates a lattice of `sentence` holding its BOS and EOS nodes.
+func newLattice(sentence string) *lattice {
+	n := len(sentence)
+	l := &lattice{
+		sentence:   sentence,
+		beginNodes: make([][]*latticeNode, n+1),
+		endNodes:   make([][]*latticeNode, n+1),
+	}
+
+	bos := &latticeNode{id: -1, pos: 0, nodeID: 0}
+	eos := &latticeNode{id: -1, pos: n, nodeID: 1}
+	l.nodes = append(l.nodes, bos, eos)
+	l.endNodes[0] = append(l.endNodes[0], bos)
+	l.beginNodes[n] = append(l.beginNodes[n], eos)
+
+	return l
+}
+
+func (l *lattice) insert(pos, length int, score float64, id int) {
+	node := &latticeNode{id: id, pos: pos, length: length, score: score, nodeID: len(l.nodes)}
+	l.nodes = append(l.nodes, node)
+	l.beginNodes[pos] = append(l.beginNodes[pos], node)
+	l.endNodes[pos+length] = append(l.endNodes[pos+length], node)
+}
+
+// populate inserts all the pieces of `pieces` found in the sentence, pieces of
+// at most `maxLen` chars being looked up. Chars not covered by a single char
+// piece are inserted as `unkID` with `unkScore`. The `skip` piece id is not
+// inserted when spanning the whole sentence.
+func (l *lattice) populate(pieces map[string]int, scores []float64, maxLen int, unkID int, unkScore float64, skip int) {
+	for pos := 0; pos < len(l.sentence); {
+		_, charLen := utf8.DecodeRuneInString(l.sentence[pos:])
+
+		hasSingleNode := false
+		end := pos
+		for n := 0; n < maxLen && end < len(l.sentence); n++ {
+			_, size := utf8.DecodeRuneInString(l.sentence[end:])
+			end += size
+
+			id, ok := pieces[l.sentence[pos:end]]
+			if !ok || (id == skip && pos == 0 && end == len(l.sentence)) {
+				continue
+			}
+			l.insert(pos, end-pos, scores[id], id)
+			if end-pos == charLen {
+				hasSingleNode = true
+			}
+		}
+		if !hasSingleNode {
+			l.insert(pos, charLen, unkScore, unkID)
+		}
+
+		pos += charLen
+	}
+}
+
+// viterbi returns the best segmentation of the sentence, nil if there is none.
+func (l *lattice) viterbi() []*latticeNode {
+	n := len(l.sentence)
+	bestScore := make([]float64, len(l.nodes))
+	prev := make([]*latticeNode, len(l.nodes))
+	for i := range bestScore {
+		bestScore[i] = math.Inf(-1)
+	}
+	bestScore[0] = 0
+
+	for pos := 0; pos <= n; pos++ {
+		for _, rnode := range l.beginNodes[pos] {
+			for _, lnode := range l.endNodes[pos] {
+				if math.IsInf(bestScore[lnode.nodeID], -1) {
+					continue
+				}
+				score := bestScore[lnode.nodeID] + rnode.score
+				if prev[rnode.nodeID] == nil || score > bestScore[rnode.nodeID] {
+					bestScore[rnode.nodeID] = score
+					prev[rnode.nodeID] = lnode
+				}
+			}
+		}
+	}
+
+	if prev[1] == nil {
+		return nil
+	}
+	var path []*latticeNode
+	for node := prev[1]; node.nodeID != 0; node = prev[node.nodeID] {
+		path = append(path, node)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path
+}
+
+// populateMarginal adds the expected frequency of each piece of the lattice,
+// weighted by `freq`, to `expected` (forward-backward algorithm) and returns
+// the log likelihood of the sentence times `freq`.
+func (l *lattice) populateMarginal(freq float64, expected []float64) float64 {
+	n := len(l.sentence)
+	alpha := make([]float64, len(l.nodes))
+	beta := make([]float64, len(l.nodes))
+
+	for pos := 0; pos <= n; pos++ {
+		for _, rnode := range l.beginNodes[pos] {
+			for i, lnode := range l.endNodes[pos] {
+				alpha[rnode.nodeID] = logSumExp(alpha[rnode.nodeID], lnode.score+alpha[lnode.nodeID], i == 0)
+			}
+		}
+	}
+	for pos := n; pos >= 0; pos-- {
+		for _, lnode := range l.endNodes[pos] {
+			for i, rnode := range l.beginNodes[pos] {
+				beta[lnode.nodeID] = logSumExp(beta[lnode.nodeID], rnode.score+beta[rnode.nodeID], i == 0)
+			}
+		}
+	}
+
+	z := alpha[1]
+	for pos := 0; pos < n; pos++ {
+		for _, node := range l.beginNodes[pos] {
+			total := alpha[node.nodeID] + node.score + beta[node.nodeID] - z
+			expected[node.id] += freq * math.Exp(total)
+		}
+	}
+
+	return freq * z
+}
+
+// logSumExp returns log(exp(x) + exp(y)), or y in init mode.
+func logSumExp(x, y float64, init bool) float64 {
+	if init {
+		return y
+	}
+
+	vmin, vmax := math.Min(x, y), math.Max(x, y)
+	const kMinusLogEpsilon = 50.0
+	if vmax > vmin+kMinusLogEpsilon {
+		return vmax
+	}
+
+	return vmax + math.Log(math.Exp(vmin-vmax)+1.0)
+}
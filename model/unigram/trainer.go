@@ -0,0 +1,593 @@
+package unigram
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/season-studio/tokenizer"
+)
+
+// trainingUnk is the unknown piece used while training, whatever `UnkToken`.
+const trainingUnk = "<UNK>"
+
+// kExpectedFrequencyThreshold is the minimum expected frequency of the pieces
+// kept by the M step.
+const kExpectedFrequencyThreshold = 0.5
+
+// UnigramTrainer trains a Unigram model with the EM algorithm of SentencePiece:
+// it starts from the most frequent substrings of the corpus words then,
+// alternating EM steps, prunes the pieces whose removal reduces the corpus
+// likelihood the least until about `VocabSize` pieces are left.
+type UnigramTrainer struct {
+	// Whether to show progress while training
+	ShowProgress bool
+	// Size of the final vocabulary, special tokens included
+	VocabSize int
+	// Number of EM iterations between each pruning
+	NSubIterations int
+	// Ratio of pieces kept by each pruning
+	ShrinkingFactor float64
+	// A list of special tokens that the model should know of
+	SpecialTokens []tokenizer.AddedToken
+	// Chars always included in the vocabulary even if not in the training set
+	InitialAlphabet map[string]struct{}
+	// An optional unknown token, added to the vocabulary if not a special token
+	UnkToken *string
+	// Maximum length in chars of a piece
+	MaxPieceLength int
+	// Number of seed pieces the training starts with
+	SeedSize int
+	// Statistics filled while training if enabled with `EmitReport`.
+	// NOTE: it is a pointer so that trainer copies share the same report.
+	report *tokenizer.TrainingReport
+}
+
+var _ tokenizer.TrainerWithError = new(UnigramTrainer)
+
+// UnigramTrainerBuilder can be used to create a UnigramTrainer with a custom
+// configuration.
+type UnigramTrainerBuilder struct {
+	trainer UnigramTrainer
+}
+
+// NewUnigramTrainerBuilder creates a UnigramTrainerBuilder with the default
+// configuration of HuggingFace tokenizers.
+func NewUnigramTrainerBuilder() *UnigramTrainerBuilder {
+	return &UnigramTrainerBuilder{
+		trainer: UnigramTrainer{
+			ShowProgress:    true,
+			VocabSize:       8000,
+			NSubIterations:  2,
+			ShrinkingFactor: 0.75,
+			InitialAlphabet: make(map[string]struct{}),
+			MaxPieceLength:  16,
+			SeedSize:        1000000,
+		},
+	}
+}
+
+// ShowProgress sets whether to show progress
+func (utb *UnigramTrainerBuilder) ShowProgress(show bool) *UnigramTrainerBuilder {
+	utb.trainer.ShowProgress = show
+	return utb
+}
+
+// VocabSize sets the vocabulary size
+func (utb *UnigramTrainerBuilder) VocabSize(size int) *UnigramTrainerBuilder {
+	utb.trainer.VocabSize = size
+	return utb
+}
+
+// NSubIterations sets the number of EM iterations between each pruning
+func (utb *UnigramTrainerBuilder) NSubIterations(n int) *UnigramTrainerBuilder {
+	utb.trainer.NSubIterations = n
+	return utb
+}
+
+// ShrinkingFactor sets the ratio of pieces kept by each pruning
+func (utb *UnigramTrainerBuilder) ShrinkingFactor(factor float64) *UnigramTrainerBuilder {
+	utb.trainer.ShrinkingFactor = factor
+	return utb
+}
+
+// SpecialTokens sets the special tokens
+func (utb *UnigramTrainerBuilder) SpecialTokens(tokens []tokenizer.AddedToken) *UnigramTrainerBuilder {
+	utb.trainer.SpecialTokens = tokens
+	return utb
+}
+
+// InitialAlphabet sets the chars always included in the vocabulary
+func (utb *UnigramTrainerBuilder) InitialAlphabet(alphabet map[string]struct{}) *UnigramTrainerBuilder {
+	utb.trainer.InitialAlphabet = alphabet
+	return utb
+}
+
+// UnkToken sets the unknown token
+func (utb *UnigramTrainerBuilder) UnkToken(unk string) *UnigramTrainerBuilder {
+	utb.trainer.UnkToken = &unk
+	return utb
+}
+
+// MaxPieceLength sets the maximum length in chars of a piece
+func (utb *UnigramTrainerBuilder) MaxPieceLength(length int) *UnigramTrainerBuilder {
+	utb.trainer.MaxPieceLength = length
+	return utb
+}
+
+// SeedSize sets the number of seed pieces
+func (utb *UnigramTrainerBuilder) SeedSize(size int) *UnigramTrainerBuilder {
+	utb.trainer.SeedSize = size
+	return utb
+}
+
+// EmitReport sets whether to collect a `TrainingReport` while training
+func (utb *UnigramTrainerBuilder) EmitReport(emit bool) *UnigramTrainerBuilder {
+	if emit {
+		utb.trainer.report = new(tokenizer.TrainingReport)
+	} else {
+		utb.trainer.report = nil
+	}
+	return utb
+}
+
+// Build creates the UnigramTrainer
+func (utb *UnigramTrainerBuilder) Build() *UnigramTrainer {
+	trainer := utb.trainer
+	return &trainer
+}
+
+// Report returns the `TrainingReport` of the last training or nil if reports
+// are not enabled.
+func (ut *UnigramTrainer) Report() *tokenizer.TrainingReport {
+	if ut.report == nil || ut.report.TokenFrequencies == nil {
+		return nil
+	}
+
+	return ut.report
+}
+
+// Implement Trainer interface for UnigramTrainer:
+// ===============================================
+
+// WithProgressBar implements tokenizer.Trainer.
+func (ut *UnigramTrainer) WithProgressBar() bool {
+	return ut.ShowProgress
+}
+
+// ProcessTokens implements tokenizer.Trainer.
+func (ut *UnigramTrainer) ProcessTokens(words map[string]int, tokens []string) {
+	for _, token := range tokens {
+		words[token]++
+	}
+}
+
+// Train implements tokenizer.Trainer. It panics if the vocab size is smaller
+// than the number of required chars, use `TrainWithError` or `TrainModel` to
+// get an error instead.
+func (ut *UnigramTrainer) Train(wordCounts map[string]int) (tokenizer.Model, []tokenizer.AddedToken) {
+	model, specialTokens, err := ut.TrainWithError(wordCounts)
+	if err != nil {
+		panic(err)
+	}
+
+	return model, specialTokens
+}
+
+// TrainWithError implements tokenizer.TrainerWithError, so that
+// `Tokenizer.Train` returns the errors of `TrainModel`.
+func (ut *UnigramTrainer) TrainWithError(wordCounts map[string]int) (tokenizer.Model, []tokenizer.AddedToken, error) {
+	model, err := ut.TrainModel(wordCounts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return model, ut.SpecialTokens, nil
+}
+
+// sentence is a word to train on and its count.
+type sentence struct {
+	word  string
+	count int
+}
+
+// TrainModel trains a Unigram model on the given word counts.
+func (ut *UnigramTrainer) TrainModel(wordCounts map[string]int) (*Unigram, error) {
+	sentences := make([]sentence, 0, len(wordCounts))
+	for word, count := range wordCounts {
+		sentences = append(sentences, sentence{word, count})
+	}
+	sort.Slice(sentences, func(i, j int) bool { return sentences[i].word < sentences[j].word })
+
+	requiredChars := ut.requiredChars(sentences)
+	if len(requiredChars) > ut.VocabSize {
+		return nil, fmt.Errorf("UnigramTrainer: vocab size %d is smaller than the %d required chars", ut.VocabSize, len(requiredChars))
+	}
+
+	pieces := []TokenScore{{Token: trainingUnk, Score: math.NaN()}}
+	pieces = append(pieces, ut.seedPieces(sentences)...)
+
+	// Expected frequencies of the pieces of the last E-step, for the report.
+	var (
+		expected       []float64
+		expectedPieces []TokenScore
+	)
+	desiredVocabSize := ut.VocabSize * 11 / 10
+	for {
+		for i := 0; i < ut.NSubIterations; i++ {
+			expected = ut.runEStep(pieces, sentences)
+			expectedPieces = pieces
+			pieces = ut.runMStep(pieces, expected)
+		}
+
+		// Stop when the vocab reached the desired size.
+		if len(pieces) <= desiredVocabSize {
+			break
+		}
+
+		pruned := ut.prunePieces(pieces, sentences)
+		if len(pruned) == len(pieces) {
+			break
+		}
+		pieces = pruned
+	}
+
+	return ut.finalize(pieces, requiredChars, expectedPieces, expected)
+}
+
+// requiredChars returns the sorted chars of the words and initial alphabet.
+func (ut *UnigramTrainer) requiredChars(sentences []sentence) []string {
+	set := make(map[string]struct{})
+	for _, s := range sentences {
+		for _, r := range s.word {
+			set[string(r)] = struct{}{}
+		}
+	}
+	for c := range ut.InitialAlphabet {
+		set[c] = struct{}{}
+	}
+
+	chars := make([]string, 0, len(set))
+	for c := range set {
+		chars = append(chars, c)
+	}
+	sort.Strings(chars)
+
+	return chars
+}
+
+// seedPieces returns the chars of the corpus followed by its most frequent
+// substrings, scored by frequency times length, as log probabilities.
+func (ut *UnigramTrainer) seedPieces(sentences []sentence) []TokenScore {
+	charFreqs := make(map[string]int)
+	substrFreqs := make(map[string]int)
+	for _, s := range sentences {
+		// byte offsets of the chars of the word
+		var offsets []int
+		for i := range s.word {
+			offsets = append(offsets, i)
+		}
+		offsets = append(offsets, len(s.word))
+
+		for i := 0; i < len(offsets)-1; i++ {
+			charFreqs[s.word[offsets[i]:offsets[i+1]]] += s.count
+			for j := i + 2; j < len(offsets) && j-i <= ut.MaxPieceLength; j++ {
+				substrFreqs[s.word[offsets[i]:offsets[j]]] += s.count
+			}
+		}
+	}
+
+	byScore := func(freqs map[string]int, withLength bool) []TokenScore {
+		scored := make([]TokenScore, 0, len(freqs))
+		for piece, freq := range freqs {
+			score := float64(freq)
+			if withLength {
+				score *= float64(utf8.RuneCountInString(piece))
+			}
+			scored = append(scored, TokenScore{Token: piece, Score: score})
+		}
+		sort.Slice(scored, func(i, j int) bool {
+			if scored[i].Score != scored[j].Score {
+				return scored[i].Score > scored[j].Score
+			}
+			return scored[i].Token < scored[j].Token
+		})
+		return scored
+	}
+
+	// Chars always are seed pieces.
+	seeds := byScore(charFreqs, false)
+	for _, ts := range byScore(substrFreqs, true) {
+		if len(seeds) >= ut.SeedSize {
+			break
+		}
+		seeds = append(seeds, ts)
+	}
+
+	var sum float64
+	for _, ts := range seeds {
+		sum += ts.Score
+	}
+	logSum := math.Log(sum)
+	for i := range seeds {
+		seeds[i].Score = math.Log(seeds[i].Score) - logSum
+	}
+
+	return seeds
+}
+
+// trainingModel is the piece lookup used while training.
+type trainingModel struct {
+	ids      map[string]int
+	scores   []float64
+	maxLen   int
+	minScore float64
+	unkScore float64
+}
+
+func newTrainingModel(pieces []TokenScore) *trainingModel {
+	m := &trainingModel{
+		ids:    make(map[string]int, len(pieces)),
+		scores: make([]float64, len(pieces)),
+	}
+	minScore := math.Inf(1)
+	for id, ts := range pieces {
+		m.scores[id] = ts.Score
+		if id == 0 {
+			continue // the training unk never matches
+		}
+		m.ids[ts.Token] = id
+		if n := utf8.RuneCountInString(ts.Token); n > m.maxLen {
+			m.maxLen = n
+		}
+		if ts.Score < minScore {
+			minScore = ts.Score
+		}
+	}
+	m.minScore = minScore
+	m.unkScore = minScore - kUnkPenalty
+
+	return m
+}
+
+func (m *trainingModel) lattice(s string, skip int) *lattice {
+	l := newLattice(s)
+	l.populate(m.ids, m.scores, m.maxLen, 0, m.unkScore, skip)
+
+	return l
+}
+
+// runEStep returns the expected frequency of each piece in the corpus.
+func (ut *UnigramTrainer) runEStep(pieces []TokenScore, sentences []sentence) []float64 {
+	model := newTrainingModel(pieces)
+	expected := make([]float64, len(pieces))
+	for _, s := range sentences {
+		model.lattice(s.word, -1).populateMarginal(float64(s.count), expected)
+	}
+
+	return expected
+}
+
+// runMStep drops the pieces of low expected frequency and re-estimates the
+// scores of the others with a Bayesian (digamma) update.
+func (ut *UnigramTrainer) runMStep(pieces []TokenScore, expected []float64) []TokenScore {
+	newPieces := []TokenScore{pieces[0]} // always keep unk
+	var sum float64
+	for id := 1; id < len(pieces); id++ {
+		freq := expected[id]
+		if freq < kExpectedFrequencyThreshold {
+			continue
+		}
+		newPieces = append(newPieces, TokenScore{Token: pieces[id].Token, Score: freq})
+		sum += freq
+	}
+
+	logSum := digamma(sum)
+	for i := 1; i < len(newPieces); i++ {
+		newPieces[i].Score = digamma(newPieces[i].Score) - logSum
+	}
+
+	return newPieces
+}
+
+// prunePieces keeps the pieces the corpus likelihood would suffer the most
+// from losing, about `ShrinkingFactor` of them.
+func (ut *UnigramTrainer) prunePieces(pieces []TokenScore, sentences []sentence) []TokenScore {
+	model := newTrainingModel(pieces)
+
+	// Segmentation of each piece without itself.
+	alwaysKeep := make([]bool, len(pieces))
+	alternatives := make([][]int, len(pieces))
+	for id := 1; id < len(pieces); id++ {
+		best := model.lattice(pieces[id].Token, -1).viterbi()
+		if len(best) >= 2 {
+			// Not used as a whole by its own segmentation.
+			continue
+		}
+		alwaysKeep[id] = true
+		for _, node := range model.lattice(pieces[id].Token, id).viterbi() {
+			alternatives[id] = append(alternatives[id], node.id)
+		}
+	}
+
+	// Viterbi frequency of each piece and the sentences using it.
+	freqs := make([]float64, len(pieces))
+	inverted := make([][]int, len(pieces))
+	var vsum float64
+	for i, s := range sentences {
+		vsum += float64(s.count)
+		for _, node := range model.lattice(s.word, -1).viterbi() {
+			freqs[node.id] += float64(s.count)
+			inverted[node.id] = append(inverted[node.id], i)
+		}
+	}
+	var sum float64
+	for _, f := range freqs {
+		sum += f
+	}
+	logSum := math.Log(sum)
+
+	type candidate struct {
+		id   int
+		loss float64
+	}
+	var candidates []candidate
+	newPieces := []TokenScore{pieces[0]}
+	for id := 1; id < len(pieces); id++ {
+		switch {
+		case freqs[id] == 0 && !alwaysKeep[id]:
+			// Not used by the Viterbi segmentation.
+			continue
+		case len(alternatives[id]) == 0:
+			newPieces = append(newPieces, pieces[id])
+			continue
+		}
+
+		var f float64
+		for _, i := range inverted[id] {
+			f += float64(sentences[i].count)
+		}
+		if f == 0 {
+			continue
+		}
+		f /= vsum
+
+		// Once removed, the piece is re-segmented into its alternatives,
+		// whose frequencies increase by its own.
+		logProb := math.Log(freqs[id]) - logSum
+		logSumAlt := math.Log(sum + freqs[id]*float64(len(alternatives[id])-1))
+		var logProbAlt float64
+		for _, alt := range alternatives[id] {
+			logProbAlt += math.Log(freqs[alt]+freqs[id]) - logSumAlt
+		}
+		candidates = append(candidates, candidate{id, f * (logProb - logProbAlt)})
+	}
+
+	desiredVocabSize := ut.VocabSize * 11 / 10
+	prunedSize := int(ut.ShrinkingFactor * float64(len(pieces)))
+	if prunedSize < desiredVocabSize {
+		prunedSize = desiredVocabSize
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].loss > candidates[j].loss })
+	for _, c := range candidates {
+		if len(newPieces) >= prunedSize {
+			break
+		}
+		newPieces = append(newPieces, pieces[c.id])
+	}
+
+	return newPieces
+}
+
+// finalize builds the model of `VocabSize` tokens: the special tokens and unk
+// token, the required chars and the best scored pieces. The expected
+// frequencies of `expectedPieces` fill the report.
+func (ut *UnigramTrainer) finalize(pieces []TokenScore, requiredChars []string, expectedPieces []TokenScore, expected []float64) (*Unigram, error) {
+	model := newTrainingModel(pieces)
+
+	var (
+		vocab    []TokenScore
+		inserted = map[string]struct{}{trainingUnk: {}}
+	)
+	const minScorePenaltyDelta = 0.0001
+	var minScorePenalty float64
+	for _, c := range requiredChars {
+		inserted[c] = struct{}{}
+		if id, ok := model.ids[c]; ok {
+			vocab = append(vocab, TokenScore{Token: c, Score: pieces[id].Score})
+			continue
+		}
+		vocab = append(vocab, TokenScore{Token: c, Score: model.minScore + minScorePenalty})
+		minScorePenalty += minScorePenaltyDelta
+	}
+
+	var specials []TokenScore
+	for _, tok := range ut.SpecialTokens {
+		specials = append(specials, TokenScore{Token: tok.Content, Score: 0})
+	}
+	var unkID *int
+	if ut.UnkToken != nil {
+		id := -1
+		for i, tok := range ut.SpecialTokens {
+			if tok.Content == *ut.UnkToken {
+				id = i
+				break
+			}
+		}
+		if id < 0 {
+			id = 0
+			specials = append([]TokenScore{{Token: *ut.UnkToken, Score: 0}}, specials...)
+		}
+		unkID = &id
+	}
+	for _, ts := range specials {
+		inserted[ts.Token] = struct{}{}
+	}
+
+	sizeWithoutSpecials := ut.VocabSize - len(specials)
+	sorted := append([]TokenScore(nil), pieces[1:]...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Score > sorted[j].Score })
+	for _, ts := range sorted {
+		if len(vocab) >= sizeWithoutSpecials {
+			break
+		}
+		if _, ok := inserted[ts.Token]; ok {
+			continue
+		}
+		inserted[ts.Token] = struct{}{}
+		vocab = append(vocab, ts)
+	}
+	sort.SliceStable(vocab, func(i, j int) bool { return vocab[i].Score > vocab[j].Score })
+
+	builder := NewUnigramBuilder().Vocab(append(specials, vocab...))
+	if unkID != nil {
+		builder.UnkID(*unkID)
+	}
+	u, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if ut.report != nil {
+		*ut.report = *ut.newReport(u, expectedPieces, expected)
+	}
+
+	return u, nil
+}
+
+// newReport reports the expected frequencies of the last E-step, rounded, as
+// the token frequencies of the final vocab. Tokens it did not see, i.e. the
+// special tokens, have none.
+func (ut *UnigramTrainer) newReport(u *Unigram, pieces []TokenScore, expected []float64) *tokenizer.TrainingReport {
+	byToken := make(map[string]float64, len(pieces))
+	for id := 1; id < len(pieces); id++ { // skip the training unk
+		byToken[pieces[id].Token] = expected[id]
+	}
+
+	tokens := make([]string, len(u.vocab))
+	freqs := make([]int, len(u.vocab))
+	for i, ts := range u.vocab {
+		tokens[i] = ts.Token
+		freqs[i] = int(math.Round(byToken[ts.Token]))
+	}
+
+	return tokenizer.NewTrainingReport(tokens, freqs, nil)
+}
+
+// digamma approximates the digamma function.
+func digamma(x float64) float64 {
+	var result float64
+	for x < 7 {
+		result -= 1 / x
+		x++
+	}
+	x -= 1.0 / 2.0
+	xx := 1 / x
+	xx2 := xx * xx
+	xx4 := xx2 * xx2
+	result += math.Log(x) + (1.0/24.0)*xx2 - (7.0/960.0)*xx4 + (31.0/8064.0)*xx4*xx2 - (127.0/30720.0)*xx4*xx4
+
+	return result
+}
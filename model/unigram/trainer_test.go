@@ -0,0 +1,128 @@
+package unigram
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+)
+
+func TestUnigramTrainer_Train(t *testing.T) {
+	wordCounts := map[string]int{
+		"▁hello": 10, "▁world": 8, "▁help": 5, "▁held": 4, "▁word": 3,
+		"▁low": 6, "▁lower": 2, "▁hold": 1,
+	}
+
+	trainer := NewUnigramTrainerBuilder().
+		ShowProgress(false).
+		VocabSize(30).
+		SpecialTokens([]tokenizer.AddedToken{
+			tokenizer.NewAddedToken("<pad>", true),
+			tokenizer.NewAddedToken("<unk>", true),
+		}).
+		UnkToken("<unk>").
+		InitialAlphabet(map[string]struct{}{"z": {}}).
+		EmitReport(true).
+		Build()
+
+	m, specials := trainer.Train(wordCounts)
+	u := m.(*Unigram)
+
+	if len(specials) != 2 {
+		t.Errorf("want 2 special tokens, got %v", len(specials))
+	}
+	if got := u.GetVocabSize(); got > 30 {
+		t.Errorf("want at most 30 tokens, got %v", got)
+	}
+	for i, tok := range []string{"<pad>", "<unk>"} {
+		if id, ok := u.TokenToId(tok); !ok || id != i {
+			t.Errorf("want %q with id %v, got %v (%v)", tok, i, id, ok)
+		}
+	}
+	if unk := u.GetUnkToken(); unk == nil || *unk != "<unk>" {
+		t.Errorf("want unk token <unk>, got %v", unk)
+	}
+
+	// All chars are kept, the most frequent words are single pieces.
+	for _, tok := range []string{"▁", "h", "e", "l", "o", "w", "r", "d", "p", "z", "▁hello", "▁world"} {
+		if _, ok := u.TokenToId(tok); !ok {
+			t.Errorf("want %q in vocab", tok)
+		}
+	}
+	if _, ok := u.TokenToId(trainingUnk); ok {
+		t.Errorf("want no training unk in vocab")
+	}
+
+	// Pieces are sorted by score after the special tokens.
+	for i := 3; i < len(u.vocab); i++ {
+		if u.vocab[i].Score > u.vocab[i-1].Score || math.IsNaN(u.vocab[i].Score) {
+			t.Errorf("want pieces sorted by score, got %v after %v", u.vocab[i], u.vocab[i-1])
+		}
+	}
+
+	report := trainer.Report()
+	if report == nil {
+		t.Fatal("want a training report, got nil")
+	}
+	if last := report.Coverage[len(report.Coverage)-1].Coverage; last != 1 {
+		t.Errorf("want full coverage with all tokens, got %v", last)
+	}
+	// Whole words are expected once per occurrence by the last E-step.
+	if want, got := (tokenizer.TokenFrequency{Token: "▁hello", Frequency: 10}), report.TokenFrequencies[0]; got != want {
+		t.Errorf("want most frequent token %v, got %v", want, got)
+	}
+}
+
+func TestUnigramTrainer_Deterministic(t *testing.T) {
+	wordCounts := map[string]int{"▁abc": 5, "▁abd": 4, "▁bcd": 3, "▁cab": 2}
+
+	train := func() []TokenScore {
+		trainer := NewUnigramTrainerBuilder().ShowProgress(false).VocabSize(12).Build()
+		u, err := trainer.TrainModel(wordCounts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.vocab
+	}
+
+	want := train()
+	for i := 0; i < 5; i++ {
+		if got := train(); !reflect.DeepEqual(want, got) {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	}
+}
+
+func TestUnigramTrainer_VocabTooSmall(t *testing.T) {
+	trainer := NewUnigramTrainerBuilder().ShowProgress(false).VocabSize(2).Build()
+	if _, err := trainer.TrainModel(map[string]int{"abc": 1}); err == nil {
+		t.Errorf("want error, got nil")
+	}
+}
+
+func TestLattice(t *testing.T) {
+	pieces := map[string]int{"a": 1, "b": 2, "ab": 3}
+	scores := []float64{0, -1, -1, -1.5}
+
+	l := newLattice("abc")
+	l.populate(pieces, scores, 2, 0, -10, -1)
+
+	var got []int
+	for _, node := range l.viterbi() {
+		got = append(got, node.id)
+	}
+	if want := []int{3, 0}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// Expected frequencies of the "ab" and "a" + "b" paths sum to one.
+	expected := make([]float64, 4)
+	l.populateMarginal(1, expected)
+	if math.Abs(expected[3]+expected[1]-1) > 1e-9 || math.Abs(expected[0]-1) > 1e-9 {
+		t.Errorf("want marginals summing to 1, got %v", expected)
+	}
+	if math.Abs(expected[3]-1/(1+math.Exp(-0.5))) > 1e-9 {
+		t.Errorf("want %v, got %v", 1/(1+math.Exp(-0.5)), expected[3])
+	}
+}
@@ -0,0 +1,179 @@
+package unigram
+
+import (
+	"sort"
+)
+
+// doubleArray is a double-array trie over the bytes of the pieces of a vocab.
+// The child of state `s` by byte `c` is `t = base[s]+c+1` when `check[t] == s`,
+// the root being state 0. `value[t]` is the id of the piece ending at state
+// `t`, or -1.
+type doubleArray struct {
+	base  []int32
+	check []int32
+	value []int32
+
+	nextCheckPos int
+	usedBase     map[int32]bool
+}
+
+// newDoubleArray builds the trie of the pieces, the id of a piece being its
+// index. Duplicated pieces map to the id of their last occurrence. An empty
+// piece is the value of the root, which prefixes nothing.
+func newDoubleArray(pieces []TokenScore) *doubleArray {
+	ids := make(map[string]int32, len(pieces))
+	keys := make([]string, 0, len(pieces))
+	for i, ts := range pieces {
+		if _, ok := ids[ts.Token]; !ok {
+			keys = append(keys, ts.Token)
+		}
+		ids[ts.Token] = int32(i)
+	}
+	sort.Strings(keys)
+
+	da := &doubleArray{usedBase: make(map[int32]bool)}
+	da.resize(len(keys) + 257)
+	da.check[0] = -2 // the root is no child of any state
+	if len(keys) > 0 {
+		da.insert(0, keys, 0, ids)
+	}
+
+	// Trims the unused tail of the arrays.
+	last := len(da.check) - 1
+	for last > 0 && da.check[last] == -1 {
+		last--
+	}
+	da.base = da.base[: last+1 : last+1]
+	da.check = da.check[: last+1 : last+1]
+	da.value = da.value[: last+1 : last+1]
+	da.usedBase = nil
+
+	return da
+}
+
+func (da *doubleArray) resize(n int) {
+	for len(da.check) < n {
+		da.base = append(da.base, 0)
+		da.check = append(da.check, -1)
+		da.value = append(da.value, -1)
+	}
+}
+
+// insert adds the sorted `keys` sharing their first `depth` bytes below the
+// state `s`.
+func (da *doubleArray) insert(s int32, keys []string, depth int, ids map[string]int32) {
+	if len(keys[0]) == depth {
+		da.value[s] = ids[keys[0]]
+		keys = keys[1:]
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	// Groups the keys by their byte at `depth`.
+	var codes []int32
+	var groups [][]string
+	start := 0
+	for i := 1; i <= len(keys); i++ {
+		if i == len(keys) || keys[i][depth] != keys[start][depth] {
+			codes = append(codes, int32(keys[start][depth])+1)
+			groups = append(groups, keys[start:i])
+			start = i
+		}
+	}
+
+	base := da.findBase(codes)
+	da.base[s] = base
+	for _, c := range codes {
+		da.check[base+c] = s
+	}
+	for i, c := range codes {
+		da.insert(base+c, groups[i], depth+1, ids)
+	}
+}
+
+// findBase returns a free base for children labeled by the sorted `codes`.
+func (da *doubleArray) findBase(codes []int32) int32 {
+	pos := da.nextCheckPos
+	if min := int(codes[0]); pos < min {
+		pos = min
+	}
+	pos--
+
+	first := true
+	nonzero := 0
+	for {
+		pos++
+		da.resize(pos + 1)
+		if da.check[pos] != -1 {
+			nonzero++
+			continue
+		}
+		if first {
+			da.nextCheckPos = pos
+			first = false
+		}
+
+		base := int32(pos) - codes[0]
+		if da.usedBase[base] {
+			continue
+		}
+		da.resize(int(base+codes[len(codes)-1]) + 1)
+		free := true
+		for _, c := range codes[1:] {
+			if da.check[base+c] != -1 {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+
+		// Skips the dense head of the arrays in next searches.
+		if float64(nonzero)/float64(pos-da.nextCheckPos+1) >= 0.95 {
+			da.nextCheckPos = pos
+		}
+		da.usedBase[base] = true
+
+		return base
+	}
+}
+
+// commonPrefixSearch calls `fn` with the id and byte length of each piece
+// prefixing `text`, shortest first.
+func (da *doubleArray) commonPrefixSearch(text string, fn func(id, length int)) {
+	s := int32(0)
+	n := int32(len(da.check))
+	for i := 0; i < len(text); i++ {
+		t := da.base[s] + int32(text[i]) + 1
+		if t < 0 || t >= n || da.check[t] != s {
+			return
+		}
+		s = t
+		if id := da.value[s]; id >= 0 {
+			fn(int(id), i+1)
+		}
+	}
+}
+
+// exactMatch returns the id of the piece `key`.
+func (da *doubleArray) exactMatch(key string) (int, bool) {
+	s := int32(0)
+	n := int32(len(da.check))
+	if n == 0 {
+		return 0, false
+	}
+	for i := 0; i < len(key); i++ {
+		t := da.base[s] + int32(key[i]) + 1
+		if t < 0 || t >= n || da.check[t] != s {
+			return 0, false
+		}
+		s = t
+	}
+	if id := da.value[s]; id >= 0 {
+		return int(id), true
+	}
+
+	return 0, false
+}
@@ -3,13 +3,13 @@ package unigram
 import (
 	"encoding/json"
 	"fmt"
-	"math"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
 	"unicode/utf8"
 
 	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model"
 	"github.com/season-studio/tokenizer/util"
 )
 
@@ -32,12 +32,14 @@ type Config struct {
 // Unigram implements the Unigram language model for tokenization
 type Unigram struct {
 	vocab         []TokenScore
-	tokenToIDs    map[string]int
 	unkID         *int
 	bytesFallback bool
 	fuseUnk       bool
 	// Cache for tokenization
-	cache map[string][]string
+	unkScore float64 // score of unknown chars
+	trie     *doubleArray
+	// Cache for tokenization
+	cache *util.LRU[string, []tokenizer.Token]
 }
 
 // UnigramBuilder can be used to create a Unigram model with a custom configuration
@@ -84,12 +86,11 @@ func (ub *UnigramBuilder) FuseUnk(fuseUnk bool) *UnigramBuilder {
 
 // Build creates a new Unigram model with the configured parameters
 func (ub *UnigramBuilder) Build() (*Unigram, error) {
-	// Create token to ID mapping
-	tokenToIDs := make(map[string]int, len(ub.config.vocab))
-	for i, ts := range ub.config.vocab {
-		tokenToIDs[ts.Token] = i
-	}
+	return ub.build(newDoubleArray(ub.config.vocab))
+}
 
+// build creates the Unigram model of the configured pieces, indexed by trie.
+func (ub *UnigramBuilder) build(trie *doubleArray) (*Unigram, error) {
 	// Validate unkID if provided
 	if ub.config.unkID != nil {
 		if *ub.config.unkID >= len(ub.config.vocab) {
@@ -97,55 +98,59 @@ func (ub *UnigramBuilder) Build() (*Unigram, error) {
 		}
 	}
 
-	return &Unigram{
+	u := &Unigram{
 		vocab:         ub.config.vocab,
-		tokenToIDs:    tokenToIDs,
 		unkID:         ub.config.unkID,
 		bytesFallback: ub.config.bytesFallback,
 		fuseUnk:       ub.config.fuseUnk,
-		cache:         make(map[string][]string),
-	}, nil
+		trie:          trie,
+		cache:         util.NewLRU[string, []tokenizer.Token](DefaultCacheCapacity),
+	}
+	u.unkScore = u.getMinScore() - kUnkPenalty
+
+	return u, nil
 }
 
-// New creates a new Unigram model with the given vocabulary and options
-func New(vocab []TokenScore, opts *util.Params) (*Unigram, error) {
-	builder := NewUnigramBuilder().Vocab(vocab)
+// Option sets an optional parameter of a Unigram model created with New.
+type Option func(c *Config)
 
-	if opts != nil {
-		if opts.Has("unk_id") {
-			// Handle different types for unk_id
-			unkIDValue := opts.Get("unk_id")
-			var unkID int
-
-			switch v := unkIDValue.(type) {
-			case float64:
-				unkID = int(v)
-			case int:
-				unkID = v
-			default:
-				return nil, fmt.Errorf("unk_id must be an integer, got %T", unkIDValue)
-			}
+// WithUnkID sets the vocab id of the unknown token, none by default.
+func WithUnkID(unkID int) Option {
+	return func(c *Config) { c.unkID = &unkID }
+}
 
-			builder.UnkID(unkID)
-		}
+// WithByteFallback sets whether unknown chars are tokenized as their "<0xXX>"
+// byte pieces, false by default.
+func WithByteFallback(byteFallback bool) Option {
+	return func(c *Config) { c.bytesFallback = byteFallback }
+}
 
-		if opts.Has("byte_fallback") {
-			bytesFallback := opts.Get("byte_fallback").(bool)
-			builder.BytesFallback(bytesFallback)
-		}
+// WithFuseUnk sets whether consecutive unknown tokens are fused, true by default.
+func WithFuseUnk(fuseUnk bool) Option {
+	return func(c *Config) { c.fuseUnk = fuseUnk }
+}
 
-		if opts.Has("fuse_unk") {
-			fuseUnk := opts.Get("fuse_unk").(bool)
-			builder.FuseUnk(fuseUnk)
-		}
+// New creates a new Unigram model with the given vocabulary and options, i.e.:
+//
+//	unigram.New(vocab, unigram.WithUnkID(0), unigram.WithByteFallback(true))
+func New(vocab []TokenScore, opts ...Option) (*Unigram, error) {
+	builder := NewUnigramBuilder().Vocab(vocab)
+	for _, opt := range opts {
+		opt(&builder.config)
 	}
 
 	return builder.Build()
 }
 
-// GetVocab returns the vocabulary mapping (token -> ID)
+// GetVocab returns the vocabulary mapping (token -> ID). Duplicated tokens map
+// to the id of their last occurrence.
 func (u *Unigram) GetVocab() map[string]int {
-	return u.tokenToIDs
+	vocab := make(map[string]int, len(u.vocab))
+	for i, ts := range u.vocab {
+		vocab[ts.Token] = i
+	}
+
+	return vocab
 }
 
 // GetVocabSize returns the size of the vocabulary
@@ -155,8 +160,7 @@ func (u *Unigram) GetVocabSize() int {
 
 // TokenToId returns the ID for the given token
 func (u *Unigram) TokenToId(token string) (int, bool) {
-	id, ok := u.tokenToIDs[token]
-	return id, ok
+	return u.trie.exactMatch(token)
 }
 
 // getMinScore returns the minimum score in the vocabulary
@@ -184,6 +188,14 @@ func (u *Unigram) IdToToken(id int) (string, bool) {
 	return u.vocab[id].Token, true
 }
 
+// GetUnkToken returns the `unk` token or nil if the model has no unk id
+func (u *Unigram) GetUnkToken() *string {
+	if u.unkID == nil || *u.unkID < 0 || *u.unkID >= len(u.vocab) {
+		return nil
+	}
+	return &u.vocab[*u.unkID].Token
+}
+
 // Save saves the Unigram model to the given directory
 func (u *Unigram) Save(dir string, prefixOpt ...string) error {
 	var prefix string
@@ -239,15 +251,8 @@ func (u *Unigram) Save(dir string, prefixOpt ...string) error {
 // Tokenize tokenizes the given sequence into multiple tokens
 func (u *Unigram) Tokenize(sequence string) ([]tokenizer.Token, error) {
 	// Check cache first
-	if tokens, ok := u.cache[sequence]; ok {
-		return u.tokensToTokenizer(tokens, sequence), nil
-	}
-
-	// If byte fallback is enabled, always use it
-	if u.bytesFallback {
-		tokens := u.tokenizeWithByteFallback(sequence)
-		u.cache[sequence] = tokens
-		return u.tokensToTokenizer(tokens, sequence), nil
+	if cached, ok := u.cache.Get(sequence); ok {
+		return copyTokens(cached), nil
 	}
 
 	// Tokenize using the Viterbi algorithm
@@ -255,257 +260,335 @@ func (u *Unigram) Tokenize(sequence string) ([]tokenizer.Token, error) {
 	if err != nil {
 		return nil, err
 	}
+	if u.bytesFallback {
+		tokens = u.byteFallback(tokens)
+	}
 
-	// Cache the result
-	u.cache[sequence] = tokens
+	// Cache the result of short sequences, long ones being unlikely to repeat.
+	if len(sequence) <= maxCachedLength {
+		u.setCache(sequence, copyTokens(tokens))
+	}
 
-	return u.tokensToTokenizer(tokens, sequence), nil
+	return tokens, nil
 }
 
-// tokensToTokenizer converts string tokens to tokenizer.Token
-func (u *Unigram) tokensToTokenizer(tokens []string, sequence string) []tokenizer.Token {
-	var result []tokenizer.Token
-	var offset int
-
-	for _, token := range tokens {
-		length := len(token)
-		id, ok := u.TokenToId(token)
-		if !ok {
-			// Handle unknown token
-			if u.unkID != nil {
-				id = *u.unkID
-			} else {
-				// Skip if no unkID is defined
-				offset += length
-				continue
-			}
-		}
+// maxCachedLength is the byte length of the longest sequence cached.
+const maxCachedLength = 256
 
-		result = append(result, tokenizer.Token{
-			Id:      id,
-			Value:   token,
-			Offsets: []int{offset, offset + length},
-		})
+// DefaultCacheCapacity is the number of sequences the cache of a Unigram model
+// holds, the least recently used being evicted first.
+const DefaultCacheCapacity = 10000
 
-		offset += length
+func (u *Unigram) setCache(sequence string, tokens []tokenizer.Token) {
+	u.cache.Add(sequence, tokens)
+}
+
+// copyTokens returns a copy of the tokens not sharing their offsets.
+func copyTokens(tokens []tokenizer.Token) []tokenizer.Token {
+	result := make([]tokenizer.Token, len(tokens))
+	offsets := make([]int, 2*len(tokens))
+	for i, tok := range tokens {
+		result[i] = tok
+		result[i].Offsets = offsets[2*i : 2*i+2 : 2*i+2]
+		copy(result[i].Offsets, tok.Offsets)
 	}
 
 	return result
 }
 
-// tokenizeWithViterbi implements the Viterbi algorithm for tokenization
-func (u *Unigram) tokenizeWithViterbi(sequence string) ([]string, error) {
-	if len(sequence) == 0 {
-		return []string{}, nil
+// TokenizeWord tokenizes a single word with the Viterbi algorithm. Token offsets
+// are byte offsets shifted by `offsetsBase`. It is safe for concurrent use.
+func (u *Unigram) TokenizeWord(word string, offsetsBase int) ([]tokenizer.Token, error) {
+	toks, err := u.Tokenize(word)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create a lattice for the Viterbi algorithm
-	// Each position in the lattice represents the best segmentation up to that point
-	type latticeNode struct {
-		bestScore float64
-		bestIndex int
-		id        int // Store the token ID for backtracking
+	for i := range toks {
+		toks[i].Offsets = []int{toks[i].Offsets[0] + offsetsBase, toks[i].Offsets[1] + offsetsBase}
 	}
 
-	// Initialize the lattice with one node per character in the sequence
-	n := len(sequence)
-	lattice := make([]latticeNode, n+1)
-
-	// The first position has a score of 0 (base case)
-	lattice[0] = latticeNode{bestScore: 0, bestIndex: 0, id: -1}
+	return toks, nil
+}
 
-	// Initialize the rest of the lattice with negative infinity
-	for i := 1; i <= n; i++ {
-		lattice[i] = latticeNode{bestScore: math.Inf(-1), bestIndex: -1, id: -1}
-	}
+// bestPathNode is the last piece of the best segmentation of the sentence
+// ending at a byte position.
+type bestPathNode struct {
+	id       int // piece id, `unkID` or -1 for unknown chars
+	score    float64
+	startsAt int // byte position of the piece, -1 if the position is unreached
+}
 
-	// Constant for unknown token penalty, matching the Rust implementation
-	const kUnkPenalty float64 = 10.0
-	unkScore := u.getMinScore() - kUnkPenalty
+// viterbiLattice holds the buffer of a Viterbi pass, reused across calls.
+type viterbiLattice struct {
+	nodes []bestPathNode
+}
 
-	// For each position in the lattice
-	for i := 0; i < n; i++ {
-		if lattice[i].bestScore == math.Inf(-1) {
-			// If we can't reach this position, skip it
-			continue
-		}
+var latticePool = sync.Pool{
+	New: func() interface{} { return new(viterbiLattice) },
+}
 
-		// Try all possible tokens starting at this position
-		// Limit the maximum token length to 20 characters for efficiency
-		maxLen := 20
-		if i+maxLen > n {
-			maxLen = n - i
-		}
+// tokenizeWithViterbi returns the best segmentation of the sequence. The
+// forward pass looks up the pieces starting at each char in the vocab trie and
+// keeps the best path ending at each byte position, as SentencePiece does.
+func (u *Unigram) tokenizeWithViterbi(sequence string) ([]tokenizer.Token, error) {
+	n := len(sequence)
+	if n == 0 {
+		return []tokenizer.Token{}, nil
+	}
 
-		// Track if we've found a single character token at this position
-		hasSingleNode := false
+	l := latticePool.Get().(*viterbiLattice)
+	defer latticePool.Put(l)
+	nodes, err := u.viterbi(sequence, l)
+	if err != nil {
+		return nil, err
+	}
 
-		// Get the length of the current character in UTF-8
-		charLen := 1
-		if i < n {
-			r, _ := utf8.DecodeRuneInString(sequence[i:])
-			charLen = utf8.RuneLen(r)
+	// Backtracks from the end, fusing consecutive unknown pieces if required.
+	fuse := u.fuseUnk && u.unkID != nil
+	count := 0
+	for end := n; end > 0; end = u.pieceStart(nodes, end, fuse) {
+		count++
+	}
+	tokens := make([]tokenizer.Token, count)
+	offsets := make([]int, 2*count)
+	for end := n; end > 0; {
+		start := u.pieceStart(nodes, end, fuse)
+		count--
+		offsets[2*count], offsets[2*count+1] = start, end
+		tokens[count] = tokenizer.Token{
+			Id:      nodes[end].id,
+			Value:   sequence[start:end],
+			Offsets: offsets[2*count : 2*count+2 : 2*count+2],
 		}
+		end = start
+	}
 
-		// Try to match tokens of all lengths
-		// We don't need to iterate from longest to shortest anymore
-		// because the Viterbi algorithm will find the optimal path
-		for j := i + 1; j <= i+maxLen && j <= n; j++ {
-			// Extract the substring from i to j
-			token := sequence[i:j]
-
-			// Find the token in the vocabulary
-			id, ok := u.TokenToId(token)
-
-			if !ok {
-				// Skip if token is not in vocabulary
-				continue
-			}
+	return tokens, nil
+}
 
-			// If the token is in the vocabulary, use its score
-			score := u.vocab[id].Score
+// viterbi fills the best path nodes of the lattice for the sequence: the node
+// at each byte position holds the best piece ending there.
+func (u *Unigram) viterbi(sequence string, l *viterbiLattice) ([]bestPathNode, error) {
+	n := len(sequence)
+	if cap(l.nodes) < n+1 {
+		l.nodes = make([]bestPathNode, n+1)
+	}
+	nodes := l.nodes[:n+1]
+	for i := range nodes {
+		nodes[i] = bestPathNode{id: -1, startsAt: -1}
+	}
+	nodes[0].startsAt = 0
 
-			// Add a bonus for longer tokens to match the Rust implementation
-			// This helps prioritize longer tokens like "ab" over "a" + "b"
-			tokenLength := j - i
-			lengthBonus := float64(tokenLength) * 0.1
+	unkID := -1
+	if u.unkID != nil {
+		unkID = *u.unkID
+	}
 
-			// Calculate the new score for this path
-			newScore := lattice[i].bestScore + score + lengthBonus
+	for pos := 0; pos < n; {
+		charLen := 1
+		if c := sequence[pos]; c >= utf8.RuneSelf {
+			_, charLen = utf8.DecodeRuneInString(sequence[pos:])
+		}
+		if nodes[pos].startsAt < 0 {
+			// Unreachable without unk pieces.
+			pos += charLen
+			continue
+		}
 
-			// If this is a better path to position j, update the lattice
-			if newScore > lattice[j].bestScore {
-				lattice[j].bestScore = newScore
-				lattice[j].bestIndex = i
-				lattice[j].id = id
+		scoreTillHere := nodes[pos].score
+		hasSingleNode := false
+		u.trie.commonPrefixSearch(sequence[pos:], func(id, length int) {
+			target := &nodes[pos+length]
+			score := scoreTillHere + u.vocab[id].Score
+			if target.startsAt < 0 || score > target.score {
+				*target = bestPathNode{id: id, score: score, startsAt: pos}
 			}
-
-			// Check if we've found a single character token
-			if j == i+charLen {
+			if length == charLen {
 				hasSingleNode = true
 			}
-		}
-
-		// If we haven't found a single character token and we have an unknown token ID,
-		// add an unknown token for this character
+		})
 		if !hasSingleNode && u.unkID != nil {
-			j := i + charLen
-			if j <= n {
-				newScore := lattice[i].bestScore + unkScore
-				if newScore > lattice[j].bestScore {
-					lattice[j].bestScore = newScore
-					lattice[j].bestIndex = i
-					lattice[j].id = *u.unkID
-				}
+			target := &nodes[pos+charLen]
+			score := scoreTillHere + u.unkScore
+			if target.startsAt < 0 || score > target.score {
+				*target = bestPathNode{id: unkID, score: score, startsAt: pos}
 			}
 		}
-	}
-
-	// If we couldn't reach the end of the sequence, handle unknown tokens
-	if lattice[n].bestScore == math.Inf(-1) {
-		// If we have an unknown token ID, return the whole sequence as unknown
-		if u.unkID != nil {
-			return []string{sequence}, nil
-		}
 
-		// If we're using byte fallback, handle it here
-		if u.bytesFallback {
-			return u.tokenizeWithByteFallback(sequence), nil
-		}
+		pos += charLen
+	}
 
+	if nodes[n].startsAt < 0 {
 		return nil, fmt.Errorf("could not tokenize sequence with Viterbi algorithm")
 	}
 
-	// Backtrack to find the best segmentation
-	if u.fuseUnk && u.unkID != nil {
-		// When fuseUnk is true, we need to fuse consecutive unknown tokens
-		var tokens []string
-		var unkTokens []string
-		pos := n
+	return nodes, nil
+}
 
-		for pos > 0 {
-			start := lattice[pos].bestIndex
-			if start < 0 {
-				// This shouldn't happen if the algorithm is correct
-				return nil, fmt.Errorf("invalid backtracking index: %d", start)
-			}
+var _ tokenizer.TokenCounter = new(Unigram)
 
-			token := sequence[start:pos]
-			id := lattice[pos].id
-
-			// Check if this is an unknown token
-			if id == *u.unkID {
-				// Add to the list of unknown tokens
-				unkTokens = append([]string{token}, unkTokens...)
-			} else {
-				// If we have accumulated unknown tokens, add them as a single token
-				if len(unkTokens) > 0 {
-					fusedUnk := strings.Join(unkTokens, "")
-					tokens = append([]string{fusedUnk}, tokens...)
-					unkTokens = nil
-				}
-				// Add the current token
-				tokens = append([]string{token}, tokens...)
-			}
+// CountTokens implements tokenizer.TokenCounter: it walks the best path of the
+// sequence, or looks it up in the cache, without building its tokens.
+func (u *Unigram) CountTokens(sequence string) (int, error) {
+	cached, ok := u.cache.Get(sequence)
+	if ok {
+		return len(cached), nil
+	}
+	if len(sequence) == 0 {
+		return 0, nil
+	}
+
+	l := latticePool.Get().(*viterbiLattice)
+	defer latticePool.Put(l)
+	nodes, err := u.viterbi(sequence, l)
+	if err != nil {
+		return 0, err
+	}
 
-			pos = start
+	fuse := u.fuseUnk && u.unkID != nil
+	count := 0
+	for end := len(sequence); end > 0; {
+		start := u.pieceStart(nodes, end, fuse)
+		if u.bytesFallback && u.unkID != nil && nodes[end].id == *u.unkID {
+			// One byte piece per byte.
+			count += end - start
+		} else {
+			count++
 		}
+		end = start
+	}
+
+	return count, nil
+}
 
-		// If we have any remaining unknown tokens, add them
-		if len(unkTokens) > 0 {
-			fusedUnk := strings.Join(unkTokens, "")
-			tokens = append([]string{fusedUnk}, tokens...)
+// pieceStart returns the start of the piece ending at `end` on the best path,
+// consecutive unknown pieces being a single piece if `fuse` is true.
+func (u *Unigram) pieceStart(nodes []bestPathNode, end int, fuse bool) int {
+	start := nodes[end].startsAt
+	if fuse && nodes[end].id == *u.unkID {
+		for start > 0 && nodes[start].id == *u.unkID {
+			start = nodes[start].startsAt
 		}
+	}
 
-		return tokens, nil
-	} else {
-		// Standard backtracking without fusing unknown tokens
-		var tokens []string
-		pos := n
-
-		for pos > 0 {
-			start := lattice[pos].bestIndex
-			if start < 0 {
-				// This shouldn't happen if the algorithm is correct
-				return nil, fmt.Errorf("invalid backtracking index: %d", start)
+	return start
+}
+
+// byteFallback replaces the unknown tokens by the byte pieces "<0xXX>" of
+// their bytes, of offsets the byte. Bytes without piece are the `unk` token.
+func (u *Unigram) byteFallback(tokens []tokenizer.Token) []tokenizer.Token {
+	var result []tokenizer.Token
+	for i, tok := range tokens {
+		if u.unkID == nil || tok.Id != *u.unkID {
+			if result != nil {
+				result = append(result, tok)
 			}
+			continue
+		}
+		if result == nil {
+			result = append(make([]tokenizer.Token, 0, len(tokens)+len(tok.Value)), tokens[:i]...)
+		}
 
-			token := sequence[start:pos]
-			tokens = append([]string{token}, tokens...)
-			pos = start
+		for j := 0; j < len(tok.Value); j++ {
+			byteTok := tokenizer.Token{
+				Id:      *u.unkID,
+				Value:   u.vocab[*u.unkID].Token,
+				Offsets: []int{tok.Offsets[0] + j, tok.Offsets[0] + j + 1},
+			}
+			piece := fmt.Sprintf("<0x%02X>", tok.Value[j])
+			if id, ok := u.trie.exactMatch(piece); ok {
+				byteTok.Id, byteTok.Value = id, piece
+			}
+			result = append(result, byteTok)
 		}
+	}
 
-		return tokens, nil
+	if result == nil {
+		return tokens
 	}
+	return result
 }
 
-// tokenizeWithByteFallback tokenizes a string by representing each byte as a separate token
-func (u *Unigram) tokenizeWithByteFallback(sequence string) []string {
-	var tokens []string
+var _ tokenizer.VocabEditor = new(Unigram)
+
+// AddToken implements tokenizer.VocabEditor. A new token gets the id following
+// the last one and a score of 0, as the user defined symbols of SentencePiece,
+// so that it is preferred to any other segmentation of its text.
+func (u *Unigram) AddToken(token string) int {
+	if id, ok := u.trie.exactMatch(token); ok {
+		return id
+	}
+
+	u.vocab = append(u.vocab, TokenScore{Token: token, Score: 0})
+	u.rebuild()
 
-	// Convert the string to bytes
-	bytes := []byte(sequence)
+	return len(u.vocab) - 1
+}
 
-	// For each byte, create a token in the format "<0xXX>"
-	for _, b := range bytes {
-		// Format the byte as a token
-		token := fmt.Sprintf("<0x%02X>", b)
+// Resize implements tokenizer.VocabEditor. Pad tokens get the lowest score of
+// the vocab. The `unk` token cannot be removed.
+func (u *Unigram) Resize(newSize int, padTokenPattern string) error {
+	if u.unkID != nil && *u.unkID >= newSize {
+		return fmt.Errorf("Resize vocab error: cannot remove unk token %q of id %d", u.vocab[*u.unkID].Token, *u.unkID)
+	}
 
-		// Check if the token is in the vocabulary
-		_, ok := u.TokenToId(token)
-		if ok {
-			// If the token is in the vocabulary, use it
-			tokens = append(tokens, token)
-		} else if u.unkID != nil {
-			// If the token is not in the vocabulary and we have an unknown token ID,
-			// use the unknown token
-			unkToken, _ := u.IdToToken(*u.unkID)
-			tokens = append(tokens, unkToken)
+	vocab := model.Vocab(u.GetVocab())
+	vocabR := make(model.VocabR, len(u.vocab))
+	for id, ts := range u.vocab {
+		vocabR[id] = ts.Token
+	}
+	if err := model.Resize(vocab, vocabR, newSize, padTokenPattern); err != nil {
+		return err
+	}
+
+	minScore := u.getMinScore()
+	pieces := make([]TokenScore, newSize)
+	for id := range pieces {
+		if id < len(u.vocab) {
+			pieces[id] = u.vocab[id]
 		} else {
-			// If we don't have an unknown token ID, use the byte token format
-			tokens = append(tokens, token)
+			pieces[id] = TokenScore{Token: vocabR[id], Score: minScore}
 		}
 	}
+	u.vocab = pieces
+	u.rebuild()
+
+	return nil
+}
+
+// RemapIds implements tokenizer.VocabEditor. As the ids of a Unigram model
+// index its pieces, the mapping must be a permutation of [0, vocab size).
+func (u *Unigram) RemapIds(mapping func(id int) int) error {
+	pieces := make([]TokenScore, len(u.vocab))
+	seen := make([]bool, len(u.vocab))
+	for id, ts := range u.vocab {
+		newId := mapping(id)
+		if newId < 0 || newId >= len(pieces) {
+			return fmt.Errorf("Remap vocab error: invalid id %d for id %d, ids must stay in [0, %d)", newId, id, len(pieces))
+		}
+		if seen[newId] {
+			return fmt.Errorf("Remap vocab error: several ids mapped to id %d", newId)
+		}
+		seen[newId] = true
+		pieces[newId] = ts
+	}
+
+	u.vocab = pieces
+	if u.unkID != nil {
+		unkID := mapping(*u.unkID)
+		u.unkID = &unkID
+	}
+	u.rebuild()
+
+	return nil
+}
+
+// rebuild updates the lookup structures and clears the cache after the pieces
+// have changed.
+func (u *Unigram) rebuild() {
+	u.trie = newDoubleArray(u.vocab)
+	u.unkScore = u.getMinScore() - kUnkPenalty
 
-	return tokens
+	u.cache.Clear()
 }
@@ -1,10 +1,14 @@
 package unigram
 
 import (
-	"testing"
+	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"strings"
+	"testing"
 
-	"github.com/season-studio/tokenizer/util"
+	"github.com/season-studio/tokenizer"
 )
 
 // Test cases ported from Rust implementation:
@@ -35,11 +39,7 @@ func TestEncode(t *testing.T) {
 		{Token: "abc", Score: 5.0},
 		{Token: "abcd", Score: 10.0},
 	}
-	params := util.NewParams(map[string]interface{}{
-		"unk_id":        0,
-		"byte_fallback": false,
-	})
-	model, err := New(pieces, params)
+	model, err := New(pieces, WithUnkID(0), WithByteFallback(false))
 	if err != nil {
 		t.Fatalf("Failed to create model: %v", err)
 	}
@@ -88,12 +88,7 @@ func TestEncode2(t *testing.T) {
 		{Token: "r", Score: 20.5},
 		{Token: "qr", Score: -0.5},
 	}
-	params := util.NewParams(map[string]interface{}{
-		"unk_id":        0,
-		"fuse_unk":      true,
-		"byte_fallback": false,
-	})
-	model, err := New(pieces, params)
+	model, err := New(pieces, WithUnkID(0), WithFuseUnk(true), WithByteFallback(false))
 	if err != nil {
 		t.Fatalf("Failed to create model: %v", err)
 	}
@@ -132,12 +127,7 @@ func TestEncode2(t *testing.T) {
 		}
 	}
 
-	params = util.NewParams(map[string]interface{}{
-		"unk_id":        0,
-		"fuse_unk":      false,
-		"byte_fallback": false,
-	})
-	model, err = New(pieces, params)
+	model, err = New(pieces, WithUnkID(0), WithFuseUnk(false), WithByteFallback(false))
 	if err != nil {
 		t.Fatalf("Failed to create model: %v", err)
 	}
@@ -182,11 +172,7 @@ func TestUnigramByteFallback(t *testing.T) {
 		{Token: "<0xC3>", Score: -0.01},
 		{Token: "<0xA9>", Score: -0.03},
 	}
-	params := util.NewParams(map[string]interface{}{
-		"unk_id":        0,
-		"byte_fallback": true,
-	})
-	model, err := New(pieces, params)
+	model, err := New(pieces, WithUnkID(0), WithByteFallback(true))
 	if err != nil {
 		t.Fatalf("Failed to create model: %v", err)
 	}
@@ -218,3 +204,305 @@ func TestUnigramByteFallback(t *testing.T) {
 		t.Errorf("Wrong first token value: got %q, want %q", got, want)
 	}
 }
+
+func TestByteFallback_Pieces(t *testing.T) {
+	pieces := []TokenScore{
+		{Token: "<unk>", Score: 0.0},
+		{Token: "a", Score: -1.0},
+		{Token: "<0xC3>", Score: -5.0},
+		{Token: "<0xA9>", Score: -5.0},
+	}
+	model, err := New(pieces, WithUnkID(0), WithByteFallback(true))
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	// Only unknown chars fall back to bytes.
+	got, err := model.TokenizeWord("aéa?", 1)
+	if err != nil {
+		t.Fatalf("Failed to tokenize: %v", err)
+	}
+	want := []tokenizer.Token{
+		{Id: 1, Value: "a", Offsets: []int{1, 2}},
+		{Id: 2, Value: "<0xC3>", Offsets: []int{2, 3}},
+		{Id: 3, Value: "<0xA9>", Offsets: []int{3, 4}},
+		{Id: 1, Value: "a", Offsets: []int{4, 5}},
+		{Id: 0, Value: "<unk>", Offsets: []int{5, 6}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	pieces := []TokenScore{
+		{Token: "<unk>", Score: 0.0},
+		{Token: "a", Score: -1.0},
+		{Token: "ab", Score: -1.5},
+		{Token: "<0xC3>", Score: -5.0},
+		{Token: "<0xA9>", Score: -5.0},
+	}
+
+	for _, opts := range [][]Option{
+		{WithUnkID(0)},
+		{WithUnkID(0), WithFuseUnk(false)},
+		{WithUnkID(0), WithByteFallback(true)},
+	} {
+		model, err := New(pieces, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, input := range []string{"", "aab", "aéé?a", "日本ab"} {
+			got, err := model.CountTokens(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Tokenize fills the cache, counted next.
+			toks, err := model.Tokenize(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cached, err := model.CountTokens(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != len(toks) || cached != len(toks) {
+				t.Errorf("%q: want %d tokens, got %d (cached %d)", input, len(toks), got, cached)
+			}
+		}
+	}
+}
+
+func TestUnigram_CacheBounded(t *testing.T) {
+	pieces := []TokenScore{
+		{Token: "<unk>", Score: 0.0},
+		{Token: "a", Score: -1.0},
+		{Token: "b", Score: -1.0},
+	}
+	model, err := New(pieces, WithUnkID(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < DefaultCacheCapacity+100; i++ {
+		if _, err := model.Tokenize(fmt.Sprintf("a%db", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := model.cache.Len(); got != DefaultCacheCapacity {
+		t.Errorf("want %d cached sequences, got %d", DefaultCacheCapacity, got)
+	}
+}
+
+func TestTokenizeWord(t *testing.T) {
+	pieces := []TokenScore{
+		{Token: "<unk>", Score: 0.0},
+		{Token: "h", Score: -1.0},
+		{Token: "é", Score: -1.0},
+		{Token: "llo", Score: -1.0},
+		{Token: "hé", Score: -0.5},
+		{Token: "日本", Score: -0.5},
+	}
+	model, err := New(pieces, WithUnkID(0), WithByteFallback(false))
+	if err != nil {
+		t.Fatalf("Failed to create model: %v", err)
+	}
+
+	got, err := model.TokenizeWord("héllo日本", 4)
+	if err != nil {
+		t.Fatalf("Failed to tokenize: %v", err)
+	}
+	want := []tokenizer.Token{
+		{Id: 4, Value: "hé", Offsets: []int{4, 7}},
+		{Id: 3, Value: "llo", Offsets: []int{7, 10}},
+		{Id: 5, Value: "日本", Offsets: []int{10, 16}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestDoubleArray_CommonPrefixSearch(t *testing.T) {
+	pieces := []TokenScore{
+		{Token: "<unk>"}, {Token: "a"}, {Token: "ab"}, {Token: "abc"}, {Token: "b"},
+		{Token: "東"}, {Token: "東京"}, {Token: "ab"}, {Token: ""},
+	}
+	trie := newDoubleArray(pieces)
+
+	tests := []struct {
+		text string
+		want [][2]int
+	}{
+		{"abcd", [][2]int{{1, 1}, {7, 2}, {3, 3}}},
+		{"b", [][2]int{{4, 1}}},
+		{"東京都", [][2]int{{5, 3}, {6, 6}}},
+		{"c", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got [][2]int
+		trie.commonPrefixSearch(tt.text, func(id, length int) {
+			got = append(got, [2]int{id, length})
+		})
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%q: want %v, got %v", tt.text, tt.want, got)
+		}
+	}
+}
+
+// TestTokenize_Lattice checks the segmentations against the ones of the
+// lattice of the trainer.
+func TestTokenize_Lattice(t *testing.T) {
+	pieces := benchmarkVocab()
+	model, err := New(pieces, WithUnkID(0), WithFuseUnk(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	training := newTrainingModel(pieces)
+
+	sequence := strings.ReplaceAll(benchmarkText, " ", "▁") + "QZ東京!"
+	for start := 0; start < len(sequence); start += 7 {
+		s := strings.ToValidUTF8(sequence[start:], "")
+		tokens, err := model.Tokenize(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got, want []string
+		for _, tok := range tokens {
+			got = append(got, tok.Value)
+		}
+		for _, node := range training.lattice(s, -1).viterbi() {
+			want = append(want, s[node.pos:node.pos+node.length])
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("%q: want %q, got %q", s, want, got)
+		}
+	}
+}
+
+// benchmarkVocab returns pieces made of the substrings of the words of a text
+// scored by their log frequency.
+func benchmarkVocab() []TokenScore {
+	text := strings.Fields(benchmarkText)
+	counts := make(map[string]int)
+	total := 0
+	for _, word := range text {
+		runes := []rune("▁" + word)
+		for i := range runes {
+			for j := i + 1; j <= len(runes) && j-i <= 8; j++ {
+				counts[string(runes[i:j])]++
+				total++
+			}
+		}
+	}
+
+	pieces := []TokenScore{{Token: "<unk>", Score: 0}}
+	for piece, count := range counts {
+		pieces = append(pieces, TokenScore{Token: piece, Score: math.Log(float64(count) / float64(total))})
+	}
+	sort.Slice(pieces[1:], func(i, j int) bool { return pieces[i+1].Token < pieces[j+1].Token })
+
+	return pieces
+}
+
+const benchmarkText = `Tokenization is the process of splitting a text into pieces, the tokens,
+which are mapped to the ids of a vocabulary. The unigram language model picks
+the segmentation maximizing the likelihood of the sentence, each piece being
+independent of the others. Les modèles de langue découpent le texte en unités
+plus petites que les mots. 東京は日本の首都であり、多くの人が住んでいます。`
+
+func BenchmarkUnigram_Tokenize(b *testing.B) {
+	model, err := New(benchmarkVocab(), WithUnkID(0))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, size := range []int{256, 4096, 16384} {
+		// Spaces are replaced as by the Metaspace pre-tokenizer.
+		sequence := strings.ReplaceAll(strings.Join(strings.Fields(benchmarkText), " "), " ", "▁")
+		for len(sequence) < size {
+			sequence += "▁" + sequence
+		}
+		sequence = strings.ToValidUTF8(sequence[:size], "")
+
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(len(sequence)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				// Benchmarks the lattice rather than the cache.
+				model.cache.Clear()
+				if _, err := model.Tokenize(sequence); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestUnigram_EditVocab(t *testing.T) {
+	pieces := []TokenScore{
+		{Token: "<unk>", Score: 0.0},
+		{Token: "a", Score: -1.0},
+		{Token: "b", Score: -1.0},
+		{Token: "ab", Score: -3.0},
+	}
+	model, err := New(pieces, WithUnkID(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := func(text string) []string {
+		toks, err := model.Tokenize(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var vals []string
+		for _, tok := range toks {
+			vals = append(vals, tok.Value)
+		}
+		return vals
+	}
+	if got := values("abc"); !reflect.DeepEqual([]string{"a", "b", "c"}, got) {
+		t.Fatalf("want [a b c], got %v", got)
+	}
+
+	// An added token is preferred and is no longer unknown.
+	if id := model.AddToken("bc"); id != 4 {
+		t.Errorf("want added id 4, got %d", id)
+	}
+	if got := values("abc"); !reflect.DeepEqual([]string{"a", "bc"}, got) {
+		t.Errorf("added: want [a bc], got %v", got)
+	}
+
+	if err := model.Resize(7, "<pad_%d>"); err != nil {
+		t.Fatal(err)
+	}
+	if tok, _ := model.IdToToken(6); tok != "<pad_6>" {
+		t.Errorf("want <pad_6>, got %q", tok)
+	}
+	if err := model.Resize(4, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := model.GetVocabSize(); got != 4 {
+		t.Errorf("want vocab size 4, got %d", got)
+	}
+
+	if err := model.RemapIds(func(id int) int { return id + 1 }); err == nil {
+		t.Error("want error for ids out of the vocab")
+	}
+	if err := model.RemapIds(func(id int) int { return 3 - id }); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := model.TokenToId("a"); id != 2 {
+		t.Errorf("want remapped id 2, got %d", id)
+	}
+	if unk := model.GetUnkToken(); unk == nil || *unk != "<unk>" {
+		t.Errorf("want unk token kept, got %v", unk)
+	}
+	if got := values("abc"); !reflect.DeepEqual([]string{"a", "b", "c"}, got) {
+		t.Errorf("remapped: want [a b c], got %v", got)
+	}
+	if err := model.Resize(3, ""); err == nil {
+		t.Error("want error when removing the unk token")
+	}
+}
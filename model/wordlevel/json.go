@@ -0,0 +1,25 @@
+package wordlevel
+
+import (
+	"encoding/json"
+
+	"github.com/season-studio/tokenizer/model"
+	"github.com/season-studio/tokenizer/util"
+)
+
+var _ json.Marshaler = new(WordLevel)
+
+// MarshalJSON implements json.Marshaler. The model is serialized as in a
+// HuggingFace `tokenizer.json` file.
+func (wl *WordLevel) MarshalJSON() ([]byte, error) {
+	vocab := model.Vocab(wl.vocab)
+	if vocab == nil {
+		vocab = model.Vocab{}
+	}
+
+	return util.MarshalJSON(struct {
+		Type     string      `json:"type"`
+		Vocab    model.Vocab `json:"vocab"`
+		UnkToken string      `json:"unk_token"`
+	}{"WordLevel", vocab, wl.unkToken})
+}
@@ -8,6 +8,7 @@ import (
 	"sort"
 
 	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model"
 )
 
 type config struct {
@@ -74,34 +75,11 @@ type WordLevel struct {
 
 // NewWordLevelFromFile initializes a WordLevel from file
 func NewWorldLevelFromFile(vocabFile string, unkToken string) (*WordLevel, error) {
-	filePath, err := filepath.Abs(vocabFile)
+	vocab, err := model.ReadVocabTxtFile(vocabFile)
 	if err != nil {
 		return nil, err
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var (
-		vocab map[string]int = make(map[string]int)
-		line  string
-		idx   int = 0
-	)
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line = scanner.Text()
-		vocab[line] = idx
-		idx += 1
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
 	wlb := NewWordLevelBuilder()
 	wlb.config.vocab = vocab
 	wlb.config.unkToken = unkToken
@@ -138,6 +116,11 @@ func (wl *WordLevel) GetVocabSize() (retVal int) {
 	return len(wl.vocab)
 }
 
+// GetUnkToken returns `unk` token.
+func (wl *WordLevel) GetUnkToken() *string {
+	return &wl.unkToken
+}
+
 // Tokenize transforms given input to token
 func (wl *WordLevel) Tokenize(token string) ([]tokenizer.Token, error) {
 
@@ -151,8 +134,7 @@ func (wl *WordLevel) Tokenize(token string) ([]tokenizer.Token, error) {
 	if !ok {
 		id, unkOk = wl.vocab[wl.unkToken]
 		if !unkOk {
-			fmt.Printf("token: %q\n", token)
-			err := fmt.Errorf("Missing 'unk' token in vocab.\n")
+			err := fmt.Errorf("WordLevel error: missing unk token %q for token %q: %w", wl.unkToken, token, tokenizer.ErrTokenNotInVocab)
 			return nil, err
 		}
 	}
@@ -166,6 +148,23 @@ func (wl *WordLevel) Tokenize(token string) ([]tokenizer.Token, error) {
 	return output, nil
 }
 
+// TokenizeWord looks up the whole word in the vocab, falling back to `unk` token.
+// Token offsets are byte offsets shifted by `offsetsBase`.
+//
+// It is safe for concurrent use as the model is read-only.
+func (wl *WordLevel) TokenizeWord(word string, offsetsBase int) ([]tokenizer.Token, error) {
+	toks, err := wl.Tokenize(word)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range toks {
+		toks[i].Offsets = []int{toks[i].Offsets[0] + offsetsBase, toks[i].Offsets[1] + offsetsBase}
+	}
+
+	return toks, nil
+}
+
 // TokenToId returns id of a given token if existing
 func (wl *WordLevel) TokenToId(token string) (int, bool) {
 	id, ok := wl.vocab[token]
@@ -261,3 +260,36 @@ func New(vocab map[string]int, unkToken string) (*WordLevel, error) {
 
 	return m, nil
 }
+
+// NewFromFile creates a WordLevel model from a `vocab.txt` file, one token per
+// line, see `model.ReadVocabTxt`.
+func NewFromFile(vocabFile string, unkToken string) (*WordLevel, error) {
+	vocab, err := model.ReadVocabTxtFile(vocabFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(vocab, unkToken)
+}
+
+var _ tokenizer.VocabEditor = new(WordLevel)
+
+// AddToken implements tokenizer.VocabEditor. A new token gets the id following
+// the largest one.
+func (wl *WordLevel) AddToken(token string) int {
+	return model.AddToken(wl.vocab, wl.vocabR, token)
+}
+
+// Resize implements tokenizer.VocabEditor. The `unk` token cannot be removed.
+func (wl *WordLevel) Resize(newSize int, padTokenPattern string) error {
+	if id, ok := wl.vocab[wl.unkToken]; ok && id >= newSize {
+		return fmt.Errorf("Resize vocab error: cannot remove unk token %q of id %d", wl.unkToken, id)
+	}
+
+	return model.Resize(wl.vocab, wl.vocabR, newSize, padTokenPattern)
+}
+
+// RemapIds implements tokenizer.VocabEditor.
+func (wl *WordLevel) RemapIds(mapping func(id int) int) error {
+	return model.RemapIds(wl.vocab, wl.vocabR, mapping)
+}
@@ -0,0 +1,113 @@
+package wordlevel_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model/wordlevel"
+)
+
+func TestWordLevelTokenizeWord(t *testing.T) {
+	m, err := wordlevel.New(map[string]int{"<unk>": 0, "héllo": 1, "🚀": 2}, "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		word string
+		base int
+		want []tokenizer.Token
+	}{
+		{"héllo", 2, []tokenizer.Token{{Id: 1, Value: "héllo", Offsets: []int{2, 8}}}},
+		{"🚀", 9, []tokenizer.Token{{Id: 2, Value: "🚀", Offsets: []int{9, 13}}}},
+		{"wörld", 0, []tokenizer.Token{{Id: 0, Value: "wörld", Offsets: []int{0, 6}}}},
+	}
+
+	for _, tt := range tests {
+		got, err := m.TokenizeWord(tt.word, tt.base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("want %+v, got %+v", tt.want, got)
+		}
+	}
+}
+
+func TestWordLevel_EditVocab(t *testing.T) {
+	m, err := wordlevel.New(map[string]int{"<unk>": 0, "hello": 1, "world": 2}, "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := m.AddToken("hello"); got != 1 {
+		t.Errorf("existing token: want id 1, got %d", got)
+	}
+	if got := m.AddToken("rocket"); got != 3 {
+		t.Errorf("new token: want id 3, got %d", got)
+	}
+
+	if err := m.Resize(6, "<pad_%d>"); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range []string{"<unk>", "hello", "world", "rocket", "<pad_4>", "<pad_5>"} {
+		if got, _ := m.IdToToken(id); got != want {
+			t.Errorf("id %d: want %q, got %q", id, want, got)
+		}
+	}
+	if err := m.Resize(8, "<pad>"); err == nil {
+		t.Error("want error for a pad pattern without id")
+	}
+	if err := m.Resize(3, "<pad_%d>"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetVocabSize(); got != 3 {
+		t.Errorf("want vocab size 3, got %d", got)
+	}
+	if _, ok := m.TokenToId("rocket"); ok {
+		t.Error("want token of removed id not in the vocab")
+	}
+
+	if err := m.RemapIds(func(id int) int { return 10 - id }); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := m.TokenToId("world"); id != 8 {
+		t.Errorf("want remapped id 8, got %d", id)
+	}
+	if tok, _ := m.IdToToken(10); tok != "<unk>" {
+		t.Errorf("want <unk> of id 10, got %q", tok)
+	}
+	if err := m.RemapIds(func(id int) int { return 0 }); err == nil {
+		t.Error("want error for a mapping which is not one-to-one")
+	}
+	if id, _ := m.TokenToId("hello"); id != 9 {
+		t.Errorf("want vocab unchanged on error, got id %d for hello", id)
+	}
+	if err := m.Resize(5, "<pad_%d>"); err == nil {
+		t.Error("want error when removing the unk token")
+	}
+}
+
+func TestNewFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vocab.txt")
+	// CRLF line endings, trailing spaces, a repeated token and no final newline.
+	if err := os.WriteFile(file, []byte("<unk>\r\nhello \nworld\nhello\n\n🚀"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := wordlevel.NewFromFile(file, "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"<unk>": 0, "hello": 3, "world": 2, "": 4, "🚀": 5}
+	if got := m.GetVocab(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if _, err := wordlevel.NewFromFile(filepath.Join(t.TempDir(), "missing.txt"), "<unk>"); err == nil {
+		t.Error("want an error for a missing file")
+	}
+}
@@ -0,0 +1,27 @@
+package wordpiece
+
+import (
+	"encoding/json"
+
+	"github.com/season-studio/tokenizer/model"
+	"github.com/season-studio/tokenizer/util"
+)
+
+var _ json.Marshaler = new(WordPiece)
+
+// MarshalJSON implements json.Marshaler. The model is serialized as in a
+// HuggingFace `tokenizer.json` file.
+func (wp WordPiece) MarshalJSON() ([]byte, error) {
+	vocab := wp.vocab
+	if vocab == nil {
+		vocab = &model.Vocab{}
+	}
+
+	return util.MarshalJSON(struct {
+		Type                    string      `json:"type"`
+		UnkToken                string      `json:"unk_token"`
+		ContinuingSubwordPrefix string      `json:"continuing_subword_prefix"`
+		MaxInputCharsPerWord    int         `json:"max_input_chars_per_word"`
+		Vocab                   model.Vocab `json:"vocab"`
+	}{"WordPiece", wp.unkToken, wp.continueSubwordPrefix, wp.maxInputCharsPerWord, *vocab})
+}
@@ -77,6 +77,13 @@ func (wptb WordPieceTrainerBuilder) EndOfWordSuffix(suffix string) (retVal WordP
 	return wptb
 }
 
+// EmitReport set whether to collect a `TrainingReport` while training
+func (wptb WordPieceTrainerBuilder) EmitReport(emit bool) (retVal WordPieceTrainerBuilder) {
+
+	wptb.bpeTrainerBuilder.EmitReport(emit)
+	return wptb
+}
+
 // Build constructs the final BpeTrainer
 func (wptb WordPieceTrainerBuilder) Build() (retVal WordPieceTrainer) {
 
@@ -98,11 +105,20 @@ func (wpt WordPieceTrainer) Builder() (retVal WordPieceTrainerBuilder) {
 // Implement Trainer interface for WordPieceTrainer:
 // =================================================
 
-func (wpt WordPieceTrainer) Train(wordCounts map[string]int) (retVal tokenizer.Model) {
+var _ tokenizer.Trainer = new(WordPieceTrainer)
 
-	bpeModel, _ := wpt.bpeTrainer.Train(wordCounts)
+// Train trains a WordPiece model and returns it with the special tokens.
+func (wpt WordPieceTrainer) Train(wordCounts map[string]int) (tokenizer.Model, []tokenizer.AddedToken) {
+
+	bpeModel, specialTokens := wpt.bpeTrainer.Train(wordCounts)
+
+	return NewWordPieceFromBPE(bpeModel.(bpe.BPE)), specialTokens
+}
 
-	return NewWordPieceFromBPE(bpeModel.(bpe.BPE))
+// Report returns the `TrainingReport` of the last training or nil if reports
+// are not enabled.
+func (wpt WordPieceTrainer) Report() (retVal *tokenizer.TrainingReport) {
+	return wpt.bpeTrainer.Report()
 }
 
 func (wpt WordPieceTrainer) ProcessTokens(words map[string]int, tokens []string) {
@@ -11,7 +11,6 @@ import (
 	"github.com/season-studio/tokenizer"
 	"github.com/season-studio/tokenizer/model"
 	"github.com/season-studio/tokenizer/model/bpe"
-	"github.com/season-studio/tokenizer/util"
 )
 
 type config struct {
@@ -140,34 +139,11 @@ func (wp WordPiece) Builder() (retVal WordPieceBuilder) {
 
 // ReadFiles reads the given file to extract the vocab
 func (wp WordPiece) ReadFiles(filename string) (retVal model.Vocab) {
-	filePath, err := filepath.Abs(filename)
+	vocab, err := model.ReadVocabTxtFile(filename)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-
-	var (
-		vocab model.Vocab
-		line  string
-		idx   int = 0
-	)
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line = scanner.Text()
-		vocab[line] = idx
-		idx += 1
-	}
-
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
-	}
-
 	return vocab
 }
 
@@ -181,35 +157,11 @@ func NewWordPieceBuilderFromFile(filename string) (retVal WordPieceBuilder) {
 
 // NewWordPieceFromFile initializes a WordPiece model from a mapping file
 func NewWordPieceFromFile(vocabFile string, unkToken string, maxInputCharsPerWordOpt ...int) (retVal WordPiece, err error) {
-
-	filePath, err := filepath.Abs(vocabFile)
+	vocab, err := model.ReadVocabTxtFile(vocabFile)
 	if err != nil {
 		return retVal, err
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return retVal, err
-	}
-	defer file.Close()
-
-	var (
-		vocab model.Vocab = make(map[string]int)
-		line  string
-		idx   int = 0
-	)
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line = scanner.Text()
-		vocab[line] = idx
-		idx += 1
-	}
-
-	if err := scanner.Err(); err != nil {
-		return retVal, err
-	}
-
 	wp := NewWordPiece()
 	builder := wp.Builder().Vocab(&vocab).UnkToken(unkToken)
 	if len(maxInputCharsPerWordOpt) > 0 {
@@ -251,6 +203,11 @@ func (wp WordPiece) GetVocabSize() (retVal int) {
 	return len(*wp.vocab)
 }
 
+// GetUnkToken returns `unk` token.
+func (wp WordPiece) GetUnkToken() *string {
+	return &wp.unkToken
+}
+
 func (wp WordPiece) Tokenize(sequence string) (retVal []tokenizer.Token, err error) {
 
 	// fmt.Printf("input sequence: %v\n", sequence)
@@ -259,16 +216,23 @@ func (wp WordPiece) Tokenize(sequence string) (retVal []tokenizer.Token, err err
 
 	chars := []rune(sequence)
 	charLen := len(chars)
+	// byteOffsets maps rune index to byte offset so that token offsets are in bytes.
+	byteOffsets := make([]int, 0, charLen+1)
+	for i := range sequence {
+		byteOffsets = append(byteOffsets, i)
+	}
+	byteOffsets = append(byteOffsets, len(sequence))
+
 	if charLen > wp.maxInputCharsPerWord {
 		id, ok := (*wp.vocab)[wp.unkToken]
 		if !ok {
-			err := fmt.Errorf("WordPiece error: Missing [UNK] token. Unknown token value %q not found in the vocab\n", wp.unkToken)
+			err := fmt.Errorf("WordPiece error: missing unk token %q: %w", wp.unkToken, tokenizer.ErrTokenNotInVocab)
 			return retVal, err
 		}
 		token := tokenizer.Token{
 			Value:   wp.unkToken,
 			Id:      id,
-			Offsets: []int{0, charLen},
+			Offsets: []int{0, len(sequence)},
 		}
 		outputTokens = append(outputTokens, token)
 
@@ -295,7 +259,7 @@ func (wp WordPiece) Tokenize(sequence string) (retVal []tokenizer.Token, err err
 				currStr = &tokenizer.Token{
 					Id:      id,
 					Value:   substr,
-					Offsets: []int{start, end},
+					Offsets: []int{byteOffsets[start], byteOffsets[end]},
 				}
 
 				break
@@ -314,13 +278,13 @@ func (wp WordPiece) Tokenize(sequence string) (retVal []tokenizer.Token, err err
 	if isBad {
 		id, ok := (*wp.vocab)[wp.unkToken]
 		if !ok {
-			err := fmt.Errorf("WordPiece error: Missing [UNK] token. Unknown token value %q not found in the vocab\n", wp.unkToken)
+			err := fmt.Errorf("WordPiece error: missing unk token %q: %w", wp.unkToken, tokenizer.ErrTokenNotInVocab)
 			return retVal, err
 		}
 		token := tokenizer.Token{
 			Value:   wp.unkToken,
 			Id:      id,
-			Offsets: []int{0, charLen},
+			Offsets: []int{0, len(sequence)},
 		}
 
 		outputTokens = append(outputTokens, token)
@@ -331,6 +295,23 @@ func (wp WordPiece) Tokenize(sequence string) (retVal []tokenizer.Token, err err
 	return outputTokens, nil
 }
 
+// TokenizeWord tokenizes a single word with the greedy longest-match-first
+// algorithm. Token offsets are byte offsets shifted by `offsetsBase`.
+//
+// It is safe for concurrent use as the model is read-only.
+func (wp WordPiece) TokenizeWord(word string, offsetsBase int) ([]tokenizer.Token, error) {
+	toks, err := wp.Tokenize(word)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range toks {
+		toks[i].Offsets = []int{toks[i].Offsets[0] + offsetsBase, toks[i].Offsets[1] + offsetsBase}
+	}
+
+	return toks, nil
+}
+
 func (wp WordPiece) TokenToId(token string) (retVal int, ok bool) {
 	retVal, ok = (*wp.vocab)[token]
 	return
@@ -406,36 +387,79 @@ func makeFilePath(filename string) error {
 	return os.MkdirAll(dirName, os.ModePerm)
 }
 
-// New creates WordPiece model from input data.
-func New(
-	vocab model.Vocab,
-	opts *util.Params,
-) (*WordPiece, error) {
-	// Default values:
-	unkToken := "[UNK]"
-	continuingSubwordPrefix := "##"
-	maxInputCharsPerWord := 100
-	if opts.Has("unk_token") {
-		unkToken = opts.Get("unk_token").(string)
+// Option sets an optional parameter of a WordPiece model created with New.
+type Option func(c *config)
+
+// WithUnkToken sets the `UNK` token, "[UNK]" by default.
+func WithUnkToken(unkToken string) Option {
+	return func(c *config) { c.unkToken = unkToken }
+}
+
+// WithContinuingSubwordPrefix sets the prefix of continuing subwords, "##" by
+// default.
+func WithContinuingSubwordPrefix(prefix string) Option {
+	return func(c *config) { c.continuingSubwordPrefix = prefix }
+}
+
+// WithMaxInputCharsPerWord sets the maximum number of chars of a word, 100 by
+// default. Longer words are tokenized as the `UNK` token.
+func WithMaxInputCharsPerWord(n int) Option {
+	return func(c *config) { c.maxInputCharsPerWord = n }
+}
+
+// NewFromFile creates a WordPiece model from a `vocab.txt` file, one token per
+// line, see `model.ReadVocabTxt`, i.e.:
+//
+//	wordpiece.NewFromFile("bert-base-uncased-vocab.txt", wordpiece.WithUnkToken("[UNK]"))
+func NewFromFile(vocabFile string, opts ...Option) (*WordPiece, error) {
+	vocab, err := model.ReadVocabTxtFile(vocabFile)
+	if err != nil {
+		return nil, err
 	}
-	if opts.Has("continuingSubwordPrefix") {
-		continuingSubwordPrefix = opts.Get("continuing_subword_prefix").(string)
+
+	return New(vocab, opts...)
+}
+
+// New creates WordPiece model from input data, i.e.:
+//
+//	wordpiece.New(vocab, wordpiece.WithUnkToken("<unk>"))
+func New(vocab model.Vocab, opts ...Option) (*WordPiece, error) {
+	c := config{
+		vocab:                   &vocab,
+		unkToken:                "[UNK]",
+		continuingSubwordPrefix: "##",
+		maxInputCharsPerWord:    100,
 	}
-	if opts.Has("max_input_chars_per_word") {
-		maxInputCharsPerWord = opts.Get("max_input_chars_per_word").(int)
+	for _, opt := range opts {
+		opt(&c)
 	}
-
-	builder := WordPieceBuilder{
-		config: config{
-			files:                   "",
-			vocab:                   &vocab,
-			unkToken:                unkToken,
-			continuingSubwordPrefix: continuingSubwordPrefix,
-			maxInputCharsPerWord:    maxInputCharsPerWord,
-		},
+	if c.maxInputCharsPerWord <= 0 {
+		return nil, fmt.Errorf("WordPiece error: want a positive max input chars per word, got %d", c.maxInputCharsPerWord)
 	}
 
-	m := builder.Build()
+	m := WordPieceBuilder{config: c}.Build()
 
 	return &m, nil
 }
+
+var _ tokenizer.VocabEditor = WordPiece{}
+
+// AddToken implements tokenizer.VocabEditor. A new token gets the id following
+// the largest one.
+func (wp WordPiece) AddToken(token string) int {
+	return model.AddToken(*wp.vocab, *wp.vocabR, token)
+}
+
+// Resize implements tokenizer.VocabEditor. The `unk` token cannot be removed.
+func (wp WordPiece) Resize(newSize int, padTokenPattern string) error {
+	if id, ok := (*wp.vocab)[wp.unkToken]; ok && id >= newSize {
+		return fmt.Errorf("Resize vocab error: cannot remove unk token %q of id %d", wp.unkToken, id)
+	}
+
+	return model.Resize(*wp.vocab, *wp.vocabR, newSize, padTokenPattern)
+}
+
+// RemapIds implements tokenizer.VocabEditor.
+func (wp WordPiece) RemapIds(mapping func(id int) int) error {
+	return model.RemapIds(*wp.vocab, *wp.vocabR, mapping)
+}
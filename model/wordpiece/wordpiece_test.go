@@ -1,10 +1,13 @@
 package wordpiece_test
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model"
 	"github.com/season-studio/tokenizer/model/wordpiece"
 )
 
@@ -70,3 +73,178 @@ func TestWordpieceTokenize(t *testing.T) {
 		t.Errorf("\nwant %q,\ngot  %+v", want, got)
 	}
 }
+
+func TestWordpieceTokenizeWord(t *testing.T) {
+	vocab := model.Vocab{"[UNK]": 0, "h": 1, "##é": 2, "##llo": 3, "日本": 4, "##語": 5}
+	m := wordpiece.NewWordPieceBuilder().Vocab(&vocab).Build()
+
+	// "xx héllo" -> word starts at byte 3.
+	got, err := m.TokenizeWord("héllo", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenizer.Token{
+		{Id: 1, Value: "h", Offsets: []int{3, 4}},
+		{Id: 2, Value: "##é", Offsets: []int{4, 6}},
+		{Id: 3, Value: "##llo", Offsets: []int{6, 9}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant %+v,\ngot  %+v", want, got)
+	}
+
+	got, err = m.TokenizeWord("日本語", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []tokenizer.Token{
+		{Id: 4, Value: "日本", Offsets: []int{10, 16}},
+		{Id: 5, Value: "##語", Offsets: []int{16, 19}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant %+v,\ngot  %+v", want, got)
+	}
+
+	got, err = m.TokenizeWord("🚀", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []tokenizer.Token{{Id: 0, Value: "[UNK]", Offsets: []int{1, 5}}}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("\nwant %+v,\ngot  %+v", want, got)
+	}
+}
+
+func TestWordPieceTrainerReport(t *testing.T) {
+	trainer := wordpiece.NewWordPieceTrainerBuilder().
+		VocabSize(10).
+		ShowProgress(false).
+		EmitReport(true).
+		Build()
+
+	wordCounts := map[string]int{"hug": 3, "pug": 2, "hugs": 1}
+	trainer.Train(wordCounts)
+
+	report := trainer.Report()
+	if report == nil {
+		t.Fatal("want a training report, got nil")
+	}
+
+	var sum int
+	for _, tf := range report.TokenFrequencies {
+		sum += tf.Frequency
+	}
+	if sum != report.TotalFrequency {
+		t.Errorf("want total frequency %v, got %v", sum, report.TotalFrequency)
+	}
+	if last := report.Coverage[len(report.Coverage)-1].Coverage; last != 1 {
+		t.Errorf("want full coverage with all tokens, got %v", last)
+	}
+	if len(report.Merges) == 0 {
+		t.Errorf("want merges in report, got none")
+	}
+}
+
+func TestWordPieceTrainer_Train(t *testing.T) {
+	var trainer tokenizer.Trainer = wordpiece.NewWordPieceTrainerBuilder().
+		VocabSize(20).
+		MinFrequency(2).
+		ShowProgress(false).
+		SpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[UNK]", true)}).
+		Build()
+
+	wordCounts := map[string]int{"hug": 3, "pug": 2, "hugs": 1}
+	m, specials := trainer.Train(wordCounts)
+
+	if len(specials) != 1 || specials[0].Content != "[UNK]" {
+		t.Errorf("want special token [UNK], got %v", specials)
+	}
+	if id, ok := m.TokenToId("[UNK]"); !ok || id != 0 {
+		t.Errorf("want [UNK] with id 0, got %v (%v)", id, ok)
+	}
+
+	got, err := m.Tokenize("hugs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotTokens []string
+	for _, tok := range got {
+		gotTokens = append(gotTokens, tok.Value)
+	}
+	want := []string{"hug", "##s"}
+	if !reflect.DeepEqual(want, gotTokens) {
+		t.Errorf("want %q, got %q", want, gotTokens)
+	}
+
+	got, err = m.Tokenize("bug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Value != "[UNK]" {
+		t.Errorf("want [UNK], got %+v", got)
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	vocab := model.Vocab{"<unk>": 0, "hug": 1, "@@s": 2, "s": 3}
+	m, err := wordpiece.New(vocab,
+		wordpiece.WithUnkToken("<unk>"),
+		wordpiece.WithContinuingSubwordPrefix("@@"),
+		wordpiece.WithMaxInputCharsPerWord(5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for input, want := range map[string][]string{
+		"hugs":   {"hug", "@@s"},
+		"hugsss": {"<unk>"}, // longer than 5 chars
+		"bug":    {"<unk>"},
+	} {
+		got, err := m.Tokenize(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotTokens []string
+		for _, tok := range got {
+			gotTokens = append(gotTokens, tok.Value)
+		}
+		if !reflect.DeepEqual(want, gotTokens) {
+			t.Errorf("%q: want %q, got %q", input, want, gotTokens)
+		}
+	}
+
+	if _, err := wordpiece.New(vocab, wordpiece.WithMaxInputCharsPerWord(0)); err == nil {
+		t.Errorf("want error with 0 max input chars per word, got none")
+	}
+}
+
+func TestNewFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vocab.txt")
+	if err := os.WriteFile(file, []byte("[PAD]\n[UNK]\nhug\n##s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := wordpiece.NewFromFile(file, wordpiece.WithMaxInputCharsPerWord(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetVocabSize(); got != 4 {
+		t.Errorf("want 4 tokens, got %d", got)
+	}
+	for input, want := range map[string][]int{
+		"hugs":   {2, 3},
+		"hugsss": {1},
+	} {
+		toks, err := m.Tokenize(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, tok := range toks {
+			got = append(got, tok.Id)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%q: want %v, got %v", input, want, got)
+		}
+	}
+}
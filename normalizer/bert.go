@@ -1,6 +1,7 @@
 package normalizer
 
 import (
+	"strings"
 	"unicode"
 )
 
@@ -8,7 +9,7 @@ type BertNormalizer struct {
 	CleanText          bool `json:"clean_text"`           // Whether to remove Control characters and all sorts of whitespaces replaced with single ` ` space
 	Lowercase          bool `json:"lowercase"`            // Whether to do lowercase
 	HandleChineseChars bool `json:"handle_chinese_chars"` // Whether to put spaces around chinese characters so they get split
-	StripAccents       bool `json:"strip_accents"`        // whether to remove accents
+	StripAccents       bool `json:"strip_accents"`        // whether to remove accents, usually the same as Lowercase
 }
 
 func NewBertNormalizer(cleanText, lowercase, handleChineseChars, stripAccents bool) *BertNormalizer {
@@ -20,18 +21,19 @@ func NewBertNormalizer(cleanText, lowercase, handleChineseChars, stripAccents bo
 	}
 }
 
-// IsWhitespace checks whether rune c is a BERT whitespace character
+// IsWhitespace checks whether rune c is a BERT whitespace character, as Rust
+// `char::is_whitespace`.
 func isWhitespace(c rune) bool {
-	// NOTE. `unicode.IsSpace(c rune)` has more cases
 	switch c {
 	case ' ', '\t', '\n', '\r':
 		return true
 	default:
-		return false
+		return unicode.IsSpace(c)
 	}
 }
 
-// IsControl checks whether rune c is a BERT control character
+// IsControl checks whether rune c is a BERT control character: any "Other"
+// character (Cc, Cf, Co, Cs or unassigned) but tab and newlines.
 func isControl(c rune) bool {
 	switch c {
 	case '\t':
@@ -41,7 +43,7 @@ func isControl(c rune) bool {
 	case '\r':
 		return false
 	}
-	return unicode.In(c, unicode.Cc, unicode.Cf)
+	return unicode.In(c, unicode.C) || !unicode.In(c, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Z)
 }
 
 // bpunc is the BERT extension of the Punctuation character range
@@ -72,15 +74,15 @@ func isPunctuation(c rune) bool {
 var cjk = &unicode.RangeTable{
 
 	R16: []unicode.Range16{
-		{0x4e00, 0x9fff, 1},
 		{0x3400, 0x4dbf, 1},
+		{0x4e00, 0x9fff, 1},
 		{0xf900, 0xfaff, 1},
 	},
 	R32: []unicode.Range32{
 		{Lo: 0x20000, Hi: 0x2a6df, Stride: 1},
 		{Lo: 0x2a700, Hi: 0x2b73f, Stride: 1},
 		{Lo: 0x2b740, Hi: 0x2b81f, Stride: 1},
-		{Lo: 0x2b820, Hi: 0x2ceaf, Stride: 1},
+		{Lo: 0x2b920, Hi: 0x2ceaf, Stride: 1},
 		{Lo: 0x2f800, Hi: 0x2fa1f, Stride: 1},
 	},
 }
@@ -106,66 +108,47 @@ func IsChinese(c rune) bool {
 	return isChinese(c)
 }
 
+// doCleanText removes the NUL, U+FFFD and control characters and replaces
+// whitespaces with ` `.
 func doCleanText(n *NormalizedString) *NormalizedString {
-
-	s := n.normalized
-	var changeMap []ChangeMap
-
-	// Fisrt, reverse the string
-	var oRunes []rune = []rune(s)
-
-	revRunes := make([]rune, 0)
-	for i := len(oRunes) - 1; i >= 0; i-- {
-		revRunes = append(revRunes, oRunes[i])
+	keep := func(r rune) bool {
+		return !(r == 0 || r == 0xfffd || isControl(r))
 	}
-
-	// Then, clean up
-	var removed int = 0
-	for _, r := range revRunes {
-		if r == 0 || r == 0xfffd || isControl(r) {
-			removed += 1
-		} else {
-			if removed > 0 {
-				if isWhitespace(r) {
-					r = ' '
-				}
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: -removed,
-				})
-				removed = 0
-			} else if removed == 0 {
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 0,
-				})
-			}
-		}
+	if strings.IndexFunc(n.normalized, func(r rune) bool { return !keep(r) }) >= 0 {
+		n = n.Filter(keep)
 	}
-
-	// Flip back changeMap
-	var unrevMap []ChangeMap
-	for i := len(changeMap) - 1; i >= 0; i-- {
-		unrevMap = append(unrevMap, changeMap[i])
+	if strings.IndexFunc(n.normalized, func(r rune) bool { return r != ' ' && isWhitespace(r) }) < 0 {
+		return n
 	}
 
-	return n.Transform(unrevMap, removed)
+	return n.Map(func(r rune) rune {
+		if isWhitespace(r) {
+			return ' '
+		}
+		return r
+	})
 }
 
+// doHandleChineseChars pads the CJK characters with spaces. As in HuggingFace,
+// the leading space replaces the character and the character and trailing
+// space are inserted after it, so that all three align to the character.
 func doHandleChineseChars(n *NormalizedString) *NormalizedString {
+	if strings.IndexFunc(n.normalized, isChinese) < 0 {
+		return n
+	}
+
 	var changeMap []ChangeMap
-	runes := []rune(n.normalized)
-	for _, r := range runes {
+	for _, r := range n.normalized {
 		// padding around chinese char
 		if isChinese(r) {
 			changeMap = append(changeMap, []ChangeMap{
 				{
 					RuneVal: string(' '),
-					Changes: 1,
+					Changes: 0,
 				},
 				{
 					RuneVal: string(r),
-					Changes: 0,
+					Changes: 1,
 				},
 				{
 					RuneVal: string(' '),
@@ -184,8 +167,9 @@ func doLowercase(n *NormalizedString) *NormalizedString {
 	return n.Lowercase()
 }
 
+// stripAccents decomposes the characters (NFD) to remove their accents.
 func stripAccents(n *NormalizedString) *NormalizedString {
-	return n.RemoveAccents()
+	return n.NFD().RemoveAccents()
 }
 
 // Normalize implements Normalizer interface for BertNormalizer
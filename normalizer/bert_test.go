@@ -0,0 +1,57 @@
+package normalizer_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer/normalizer"
+)
+
+func TestBertNormalizer(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer *normalizer.BertNormalizer
+		input      string
+		normalized string
+		alignments [][]int
+	}{
+		{
+			"clean text", normalizer.NewBertNormalizer(true, false, false, false),
+			"a\tb\x00​c", "a bc",
+			[][]int{{0, 1}, {1, 2}, {2, 3}, {7, 8}},
+		},
+		{
+			"chinese chars", normalizer.NewBertNormalizer(false, false, true, false),
+			"a野b", "a 野 b",
+			[][]int{{0, 1}, {1, 4}, {1, 4}, {1, 4}, {1, 4}, {1, 4}, {4, 5}},
+		},
+		{
+			"lowercase", normalizer.NewBertNormalizer(false, true, false, false),
+			"AȺB", "aⱥb",
+			[][]int{{0, 1}, {1, 3}, {1, 3}, {1, 3}, {3, 4}},
+		},
+		{
+			"strip accents", normalizer.NewBertNormalizer(false, false, false, true),
+			"Café", "Cafe",
+			[][]int{{0, 1}, {1, 2}, {2, 3}, {3, 5}},
+		},
+		{
+			"uncased", normalizer.NewBertNormalizer(true, true, true, true),
+			"Café 中", "cafe  中 ",
+			[][]int{{0, 1}, {1, 2}, {2, 3}, {3, 5}, {5, 6}, {6, 9}, {6, 9}, {6, 9}, {6, 9}, {6, 9}},
+		},
+	}
+
+	for _, tt := range tests {
+		n, err := tt.normalizer.Normalize(normalizer.NewNormalizedFrom(tt.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := n.GetNormalized(); got != tt.normalized {
+			t.Errorf("%s: want %q, got %q", tt.name, tt.normalized, got)
+		}
+		if got := n.Alignments(); !reflect.DeepEqual(tt.alignments, got) {
+			t.Errorf("%s: want alignments %v, got %v", tt.name, tt.alignments, got)
+		}
+	}
+}
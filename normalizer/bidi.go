@@ -0,0 +1,66 @@
+package normalizer
+
+import (
+	"unicode"
+)
+
+// BidiMode is how the BidiControl normalizer handles bidi control characters.
+type BidiMode int
+
+const (
+	// BidiStrip removes bidi control characters. Tokens then have offsets that
+	// never include them.
+	BidiStrip BidiMode = iota
+	// BidiIsolate puts spaces around bidi control characters so that they get
+	// split from their neighbors.
+	BidiIsolate
+)
+
+// IsBidiControl checks whether rune c is a Unicode bidi control character, i.e.
+// ALM, LRM, RLM, the embeddings and overrides (LRE, RLE, PDF, LRO, RLO) and the
+// isolates (LRI, RLI, FSI, PDI).
+func IsBidiControl(c rune) bool {
+	return unicode.Is(unicode.Bidi_Control, c)
+}
+
+// BidiControl is a normalizer handling the zero-width bidi control characters
+// found in right-to-left or mixed-direction text.
+type BidiControl struct {
+	Mode BidiMode
+}
+
+func NewBidiControl(mode BidiMode) *BidiControl {
+	return &BidiControl{mode}
+}
+
+// Normalize implements Normalizer interface for BidiControl
+func (bc *BidiControl) Normalize(n *NormalizedString) (*NormalizedString, error) {
+	switch bc.Mode {
+	case BidiIsolate:
+		return doIsolateBidiControls(n), nil
+	default:
+		return n.Filter(func(r rune) bool { return !IsBidiControl(r) }), nil
+	}
+}
+
+func doIsolateBidiControls(n *NormalizedString) *NormalizedString {
+	var changeMap []ChangeMap
+	runes := []rune(n.normalized)
+	for i, r := range runes {
+		if !IsBidiControl(r) {
+			changeMap = append(changeMap, ChangeMap{string(r), 0})
+			continue
+		}
+
+		// padding around runs of bidi controls
+		if i == 0 || !IsBidiControl(runes[i-1]) {
+			changeMap = append(changeMap, ChangeMap{string(' '), 1})
+		}
+		changeMap = append(changeMap, ChangeMap{string(r), 0})
+		if i == len(runes)-1 || !IsBidiControl(runes[i+1]) {
+			changeMap = append(changeMap, ChangeMap{string(' '), 1})
+		}
+	}
+
+	return n.Transform(changeMap, 0)
+}
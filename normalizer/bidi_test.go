@@ -0,0 +1,53 @@
+package normalizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBidiControl(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           BidiMode
+		s              string
+		wantNormalized string
+		wantAlignments [][]int
+	}{
+		{
+			name:           "strip",
+			mode:           BidiStrip,
+			s:              "a\u200Fب", // RLM
+			wantNormalized: "aب",
+			wantAlignments: [][]int{{0, 1}, {4, 6}, {4, 6}},
+		},
+		{
+			name:           "strip run",
+			mode:           BidiStrip,
+			s:              "\u2067ab\u200E\u2069", // RLI, LRM, PDI
+			wantNormalized: "ab",
+			wantAlignments: [][]int{{3, 4}, {4, 5}},
+		},
+		{
+			name:           "isolate",
+			mode:           BidiIsolate,
+			s:              "a\u200Eb",
+			wantNormalized: "a \u200E b",
+			wantAlignments: [][]int{{0, 1}, {0, 1}, {1, 4}, {1, 4}, {1, 4}, {1, 4}, {4, 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		n := NewNormalizedFrom(tt.s)
+		out, err := NewBidiControl(tt.mode).Normalize(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := out.GetNormalized(); got != tt.wantNormalized {
+			t.Errorf("%v: want %q, got %q", tt.name, tt.wantNormalized, got)
+		}
+		if got := out.Alignments(); !reflect.DeepEqual(tt.wantAlignments, got) {
+			t.Errorf("%v: want %v, got %v", tt.name, tt.wantAlignments, got)
+		}
+	}
+}
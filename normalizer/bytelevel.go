@@ -0,0 +1,61 @@
+package normalizer
+
+// ByteLevel normalizes each byte of the UTF-8 encoding of the string into its
+// printable char of the GPT-2 byte alphabet, as the ByteLevel pre-tokenizer
+// does but before the pre-tokenization, i.e. for tokenizers which split the
+// byte-level text with their own pre-tokenizer. Each char maps to as many chars
+// as it has bytes, all aligned on it.
+type ByteLevel struct{}
+
+func NewByteLevel() *ByteLevel {
+	return new(ByteLevel)
+}
+
+// byteLevelChars maps each byte to its char of the GPT-2 byte alphabet:
+// printable bytes to themselves, the others to the chars from U+0100.
+var byteLevelChars = func() [256]string {
+	var chars [256]string
+	n := 0
+	for b := 0; b < 256; b++ {
+		if ('!' <= b && b <= '~') || (0xA1 <= b && b <= 0xAC) || (0xAE <= b && b <= 0xFF) {
+			chars[b] = string(rune(b))
+		} else {
+			chars[b] = string(rune(256 + n))
+			n++
+		}
+	}
+	return chars
+}()
+
+// Implement Normalizer interface for ByteLevel:
+// =============================================
+
+func (bl *ByteLevel) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
+	s := normalized.GetNormalized()
+	if s == "" {
+		return normalized, nil
+	}
+
+	changeMap := make([]ChangeMap, 0, len(s))
+	for _, r := range s {
+		for i, b := range []byte(string(r)) {
+			c := ChangeMap{RuneVal: byteLevelChars[b]}
+			if i > 0 {
+				c.Changes = 1
+			}
+			changeMap = append(changeMap, c)
+		}
+	}
+
+	return normalized.Transform(changeMap, 0), nil
+}
+
+// NormalizeString implements StringNormalizer.
+func (bl *ByteLevel) NormalizeString(s string) (string, bool) {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		out = append(out, byteLevelChars[s[i]]...)
+	}
+
+	return string(out), true
+}
@@ -0,0 +1,45 @@
+package normalizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestByteLevel(t *testing.T) {
+	n := NewNormalizedFrom("Hé 日")
+	out, err := NewByteLevel().Normalize(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := out.GetNormalized(), "HÃ©ĠæĹ¥"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// Each byte char is aligned on its original char.
+	var got [][]int
+	for _, r := range []*Range{
+		NewRange(0, 1, NormalizedTarget),   // H
+		NewRange(1, 3, NormalizedTarget),   // Ã
+		NewRange(3, 5, NormalizedTarget),   // ©
+		NewRange(5, 7, NormalizedTarget),   // Ġ
+		NewRange(7, 9, NormalizedTarget),   // æ
+		NewRange(11, 13, NormalizedTarget), // ¥
+	} {
+		o := out.ConvertOffset(r)
+		got = append(got, []int{o.Start(), o.End()})
+	}
+	want := [][]int{{0, 1}, {1, 3}, {1, 3}, {3, 4}, {4, 7}, {4, 7}}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want original offsets %v, got %v", want, got)
+	}
+
+	if s, ok := NewByteLevel().NormalizeString("Hé 日"); !ok || s != out.GetNormalized() {
+		t.Errorf("want NormalizeString %q, got %q", out.GetNormalized(), s)
+	}
+
+	empty, err := NewByteLevel().Normalize(NewNormalizedFrom(""))
+	if err != nil || empty == nil || !empty.IsEmpty() {
+		t.Errorf("want empty string, got %v (%v)", empty, err)
+	}
+}
@@ -0,0 +1,210 @@
+package normalizer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/season-studio/tokenizer/spm"
+	"github.com/season-studio/tokenizer/util"
+)
+
+// This file implements json.Marshaler for all normalizers so that they are
+// serialized as in a HuggingFace `tokenizer.json` file.
+
+type typeOnly struct {
+	Type string `json:"type"`
+}
+
+var (
+	_ json.Marshaler = new(BertNormalizer)
+	_ json.Marshaler = new(BidiControl)
+	_ json.Marshaler = new(ByteLevel)
+	_ json.Marshaler = new(DefaultNormalizer)
+	_ json.Marshaler = new(Nmt)
+	_ json.Marshaler = new(Precompiled)
+	_ json.Marshaler = new(Prepend)
+	_ json.Marshaler = new(Replace)
+	_ json.Marshaler = new(Sequence)
+	_ json.Marshaler = new(Strip)
+	_ json.Marshaler = new(StripAccents)
+	_ json.Marshaler = new(UnicodeNormalizer)
+	_ json.Marshaler = new(NFC)
+	_ json.Marshaler = new(NFD)
+	_ json.Marshaler = new(NFKC)
+	_ json.Marshaler = new(NFKD)
+)
+
+// MarshalJSON implements json.Marshaler.
+func (bn *BertNormalizer) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type               string `json:"type"`
+		CleanText          bool   `json:"clean_text"`
+		HandleChineseChars bool   `json:"handle_chinese_chars"`
+		StripAccents       bool   `json:"strip_accents"`
+		Lowercase          bool   `json:"lowercase"`
+	}{"BertNormalizer", bn.CleanText, bn.HandleChineseChars, bn.StripAccents, bn.Lowercase})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (bc *BidiControl) MarshalJSON() ([]byte, error) {
+	mode := "strip"
+	if bc.Mode == BidiIsolate {
+		mode = "isolate"
+	}
+
+	return util.MarshalJSON(struct {
+		Type string `json:"type"`
+		Mode string `json:"mode"`
+	}{"BidiControl", mode})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (bl *ByteLevel) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(typeOnly{"ByteLevel"})
+}
+
+// MarshalJSON implements json.Marshaler. DefaultNormalizer has no HuggingFace
+// counterpart and is serialized as `Lowercase` and/or `Strip` normalizers.
+func (dn *DefaultNormalizer) MarshalJSON() ([]byte, error) {
+	switch {
+	case dn.lower && !dn.strip:
+		return util.MarshalJSON(typeOnly{"Lowercase"})
+	case dn.strip && !dn.lower:
+		return util.MarshalJSON(NewStrip(true, true))
+	case dn.lower && dn.strip:
+		return util.MarshalJSON(NewSequence([]Normalizer{Lowercase(), NewStrip(true, true)}))
+	default:
+		return util.MarshalJSON(NewSequence(nil))
+	}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n *Nmt) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(typeOnly{"Nmt"})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *Precompiled) MarshalJSON() ([]byte, error) {
+	var charsmap []byte
+	if m.Precompiled != nil {
+		charsmap = m.PrecompiledCharsmap
+	}
+
+	return util.MarshalJSON(struct {
+		Type                string `json:"type"`
+		PrecompiledCharsmap string `json:"precompiled_charsmap"`
+	}{"Precompiled", spm.AsBase64(charsmap)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *Prepend) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type    string `json:"type"`
+		Prepend string `json:"prepend"`
+	}{"Prepend", p.Prepend})
+}
+
+// MarshalJSON implements json.Marshaler. Replace is both a normalizer and a
+// decoder and has the same serialization for both.
+func (r *Replace) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type    string  `json:"type"`
+		Pattern Pattern `json:"pattern"`
+		Content string  `json:"content"`
+	}{"Replace", r.Pattern, r.Content})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Sequence) MarshalJSON() ([]byte, error) {
+	norms := s.Normalizers
+	if norms == nil {
+		norms = []Normalizer{}
+	}
+
+	return util.MarshalJSON(struct {
+		Type        string       `json:"type"`
+		Normalizers []Normalizer `json:"normalizers"`
+	}{"Sequence", norms})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Strip) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type       string `json:"type"`
+		StripLeft  bool   `json:"strip_left"`
+		StripRight bool   `json:"strip_right"`
+	}{"Strip", s.stripLeft, s.stripRight})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (sa *StripAccents) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(typeOnly{"StripAccents"})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (un *UnicodeNormalizer) MarshalJSON() ([]byte, error) {
+	switch un.Form {
+	case norm.NFC:
+		return util.MarshalJSON(typeOnly{"NFC"})
+	case norm.NFD:
+		return util.MarshalJSON(typeOnly{"NFD"})
+	case norm.NFKC:
+		return util.MarshalJSON(typeOnly{"NFKC"})
+	case norm.NFKD:
+		return util.MarshalJSON(typeOnly{"NFKD"})
+	default:
+		return nil, fmt.Errorf("UnicodeNormalizer: unsupported form %v", un.Form)
+	}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n *NFC) MarshalJSON() ([]byte, error) { return util.MarshalJSON(typeOnly{"NFC"}) }
+
+// MarshalJSON implements json.Marshaler.
+func (n *NFD) MarshalJSON() ([]byte, error) { return util.MarshalJSON(typeOnly{"NFD"}) }
+
+// MarshalJSON implements json.Marshaler.
+func (n *NFKC) MarshalJSON() ([]byte, error) { return util.MarshalJSON(typeOnly{"NFKC"}) }
+
+// MarshalJSON implements json.Marshaler.
+func (n *NFKD) MarshalJSON() ([]byte, error) { return util.MarshalJSON(typeOnly{"NFKD"}) }
+
+// Patterns are serialized as `{"String": "..."}` or `{"Regex": "..."}`.
+
+// MarshalJSON implements json.Marshaler.
+func (r *RunePattern) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(map[string]string{"String": string(r.rune)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *StringPattern) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(map[string]string{"String": s.string})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (rp *RegexpPattern) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(map[string]string{"Regex": rp.expr})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b SplitDelimiterBehavior) MarshalJSON() ([]byte, error) {
+	var name string
+	switch b {
+	case RemovedBehavior:
+		name = "Removed"
+	case IsolatedBehavior:
+		name = "Isolated"
+	case MergedWithPreviousBehavior:
+		name = "MergedWithPrevious"
+	case MergedWithNextBehavior:
+		name = "MergedWithNext"
+	case ContiguousBehavior:
+		name = "Contiguous"
+	default:
+		return nil, fmt.Errorf("unsupported SplitDelimiterBehavior %d", b)
+	}
+
+	return util.MarshalJSON(name)
+}
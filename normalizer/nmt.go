@@ -0,0 +1,33 @@
+package normalizer
+
+// Nmt is the normalizer of SentencePiece NMT models. It removes control
+// characters and replaces the other whitespace-like characters with a space.
+type Nmt struct{}
+
+var _ Normalizer = new(Nmt)
+
+func NewNmt() *Nmt {
+	return new(Nmt)
+}
+
+// Normalize implements Normalizer.
+func (n *Nmt) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
+	normalized = normalized.Filter(func(r rune) bool {
+		switch {
+		case r >= 0x0001 && r <= 0x0008, r == 0x000B, r >= 0x000E && r <= 0x001F,
+			r == 0x007F, r == 0x008F, r == 0x009F:
+			return false
+		}
+		return true
+	})
+
+	return normalized.Map(func(r rune) rune {
+		switch {
+		case r == 0x0009, r == 0x000A, r == 0x000C, r == 0x000D, r == 0x1680,
+			r >= 0x200B && r <= 0x200F, r == 0x2028, r == 0x2029, r == 0x2581,
+			r == 0xFEFF, r == 0xFFFD:
+			return ' '
+		}
+		return r
+	}), nil
+}
@@ -0,0 +1,23 @@
+package normalizer
+
+import (
+	"testing"
+)
+
+func TestNmt_Normalize(t *testing.T) {
+	original := "\x01a\tb\u200bc\x7f\ufeffd"
+	n, err := NewNmt().Normalize(NewNormalizedFrom(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := n.GetNormalized(), "a b c d"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// The space replacing U+200B maps back to its 3 bytes.
+	r := n.ConvertOffset(NewRange(3, 4, NormalizedTarget))
+	if got, want := original[r.start:r.end], "\u200b"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -2,13 +2,13 @@ package normalizer
 
 import (
 	"bytes"
+	"fmt"
 	"log"
 	"reflect"
 	"strings"
 	"unicode"
 	"unicode/utf8"
 
-	"github.com/season-studio/tokenizer/util"
 	slice "github.com/season-studio/tokenizer/util/slice"
 
 	// "golang.org/x/text/transform"
@@ -26,13 +26,32 @@ import (
 type SplitDelimiterBehavior int
 
 const (
-	RemovedBehavior = iota
+	RemovedBehavior SplitDelimiterBehavior = iota
 	IsolatedBehavior
 	MergedWithPreviousBehavior
 	MergedWithNextBehavior
 	ContiguousBehavior
 )
 
+// ParseSplitDelimiterBehavior returns the behavior of a HuggingFace name, i.e.
+// "MergedWithPrevious".
+func ParseSplitDelimiterBehavior(name string) (SplitDelimiterBehavior, error) {
+	switch name {
+	case "Removed":
+		return RemovedBehavior, nil
+	case "Isolated":
+		return IsolatedBehavior, nil
+	case "MergedWithPrevious":
+		return MergedWithPreviousBehavior, nil
+	case "MergedWithNext":
+		return MergedWithNextBehavior, nil
+	case "Contiguous":
+		return ContiguousBehavior, nil
+	default:
+		return 0, fmt.Errorf("unsupported SplitDelimiterBehavior %q", name)
+	}
+}
+
 type OffsetsRemove struct {
 	Offsets      []int
 	ShouldRemove bool
@@ -153,8 +172,13 @@ type NormalizedString struct {
 	originalShift int
 }
 
-// NewNormalizedFrom creates a Normalized instance from string input
+// NewNormalizedFrom creates a Normalized instance from string input. Each
+// invalid UTF-8 byte of s is replaced by U+FFFD in the normalized string, as a
+// `range` loop reads it, aligned to its byte in the original string.
 func NewNormalizedFrom(s string) (retVal *NormalizedString) {
+	if !utf8.ValidString(s) {
+		return newNormalizedFromInvalid(s)
+	}
 	/*
 	 *   // NOTE. Really need to make a deep copy, otherwise
 	 *   // `aligments` and `alignmentsOriginal` updates each other later on!
@@ -194,6 +218,34 @@ func createAligns(s string) [][]int {
 	return alignments
 }
 
+// newNormalizedFromInvalid creates a Normalized instance from the invalid UTF-8
+// string s, each U+FFFD replacing an invalid byte being aligned to this byte.
+func newNormalizedFromInvalid(s string) *NormalizedString {
+	var normalized strings.Builder
+	var alignments, alignmentsOriginal [][]int
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		start := normalized.Len()
+		normalized.WriteRune(r)
+		end := normalized.Len()
+		for n := start; n < end; n++ {
+			alignments = append(alignments, []int{i, i + size})
+		}
+		for n := 0; n < size; n++ {
+			alignmentsOriginal = append(alignmentsOriginal, []int{start, end})
+		}
+		i += size
+	}
+
+	return &NormalizedString{
+		original:           s,
+		normalized:         normalized.String(),
+		alignments:         alignments,
+		alignmentsOriginal: alignmentsOriginal,
+		originalShift:      0,
+	}
+}
+
 func NewNormalizedString(original, normalized string, alignments, alignmentsOriginal [][]int, originalShift int) *NormalizedString {
 	return &NormalizedString{
 		original:           original,
@@ -440,15 +492,7 @@ type ChangeMap struct {
 // the beginning of the original one, we need an `initialOffset` which represents the number
 // of removed chars at the very beginning.
 func (n *NormalizedString) TransformRange(inputRange *Range, changeMap []ChangeMap, initialOffset int) (retVal *NormalizedString) {
-
-	// fmt.Printf("normalized: %+v\n", n)
-	// fmt.Printf("inputRange: %v\n", inputRange)
-
-	// I. Update `original` alignments based on `initialOffset`.
-	// If `initialOffset` > 0, there are some chars being replaced.
-
-	// I.1. Determine range on `original` string and `normalized` string
-	// based on `inputRange`
+	// Determine the range on the normalized string
 	var nRange *Range
 	switch inputRange.indexOn {
 	case NormalizedTarget:
@@ -462,361 +506,83 @@ func (n *NormalizedString) TransformRange(inputRange *Range, changeMap []ChangeM
 		nRange.end = len(n.alignments)
 	}
 
-	// fmt.Printf("nRange: %v\n", nRange)
-
-	// I.2. Get removed chars to a slice (`removedChars`); number of removed
-	// bytes (`initialRemoved`); and update original alignments (`n.alignmensOriginal`)
-
-	// log.Printf("===== TransformRange call (initial offset: %v) ====\n", initialOffset)
-	// Retrieve the original characters that are being replaced. This let us
-	// compute the change in byte sizes along the way.
-	nBytes := []byte(n.normalized)[nRange.start:nRange.end]
-	replacedNormalized := util.NewRuneIter(bytes.Runes(nBytes))
-	/*
-	 *   fmt.Printf("replaceddNoramlized len: %v\n", replacedNormalized.Len())
-	 *   fmt.Printf("replacedNormalized: %+q\n", func(it *util.RuneIter) []string {
-	 *     var chars []string
-	 *     for {
-	 *       r, ok := it.Next()
-	 *       if !ok {
-	 *         break
-	 *       }
-	 *       chars = append(chars, string(r))
-	 *     }
-	 *     return chars
-	 *   }(replacedNormalized))
-	 *  */
-	replacedNormalized.Reset()
-
-	// Handle the initial offset in the original alignment. All the characters
-	// that were removed from the normalized one should have their width reduced
-	// by the number of bytes we remove
-	endShiftStart := nRange.end
-	initialRemoved := 0
-	if initialOffset > 0 {
-		// log.Printf("=> Clearing alignment for %v chars\n", initialOffset)
-		removedBytes := 0
-		var removedChars []rune
-		for i := 0; i < initialOffset; i++ {
-			c, ok := replacedNormalized.Next()
-			if !ok {
-				// We want to panic here, because the NormalizedString is in
-				// a bad state if this happens. We already modified a lot of things
-				log.Fatalf("1. Expected to remove %v characters but couldn't find them ...\n", initialOffset)
-			}
-			removedBytes += len([]byte(string(c)))
-			removedChars = append(removedChars, c)
-		}
-		initialRemoved = removedBytes
-		offset := nRange.start
-		oShift := 0
-		// Then we remove all these chars, updating the alignments along the way
-		for _, c := range removedChars {
-			removedORange := expandAlignments(n.alignments[offset : offset+len(string(c))])
-			offset += len(string(c))
-			oShift += len(string(c))
-			alignments := n.alignmentsOriginal[removedORange[0]:removedORange[1]]
-			// log.Printf("Clearing alignments for char: %q - alignments: %+v\n", c, alignments)
-			// 1. Get new alignments
-			var newAlignments [][]int
-			for _, offsets := range alignments {
-				// At the very end we will apply the global shift to the remaining
-				// original offsets. We should start after these to avoid doing it twice
-				if offsets[1] > endShiftStart {
-					endShiftStart = offsets[1]
-				}
-
-				offsets[1] = applySign(offsets[1], -(oShift))
-				// Make sure the starting offset is always smaller or equal to the end
-				if offsets[0] > offsets[1] {
-					offsets[0] = offsets[1]
-				}
-
-				newAlignments = append(newAlignments, offsets)
-			}
-			// 2. Update alignmentOriginal with new alignments in `removedORange` range
-			var aligns [][]int = append(n.alignmentsOriginal[:removedORange[0]], newAlignments...)
-			aligns = append(aligns, n.alignmentsOriginal[removedORange[1]:]...)
-			n.alignmentsOriginal = aligns
-
-			// log.Printf("Cleared: %+v\n", alignments)
+	// Retrieve the normalized characters that are being replaced, skipping the
+	// ones removed at the beginning.
+	replaced := []rune(n.normalized[nRange.start:nRange.end])
+	offset := nRange.start
+	next := 0
+	skip := func(count int) {
+		for i := 0; i < count && next < len(replaced); i++ {
+			offset += utf8.RuneLen(replaced[next])
+			next++
 		}
-	} // end `If` block
-
-	// II.	Do the transformation based on input `changeMap`.
-	// 1. 	Update alignments on normalized string(`n.alignments`) and original
-	// 			string (`n.alignmentsOriginal`);
-	// 2. 	collect transformed `chars` to a string
-	// 			variable (`normalized`)
-
-	// oShift is the shift to be applied to all original alignments along the way
-	// NOTE. `oShift` and `offset` here are different from ones inside previous block
-	oShift := -(initialRemoved)
-	offset := initialRemoved + nRange.start
-	var normalizedAlignments [][]int
+	}
+	skip(initialOffset)
 
-	// log.Printf("Applying transformations...\n")
 	var (
-		normalizedRunes []rune
+		normalizedAlignments [][]int
+		normalized           strings.Builder
 	)
-
-	replacedNormalized.Reset() // make sure iterating from begining
 	for _, item := range changeMap {
-		/*
-		 *     var changeType string
-		 *     switch {
-		 *     case item.Changes == 0:
-		 *       changeType = "Replacing"
-		 *     case item.Changes > 0:
-		 *       changeType = "Adding"
-		 *     case item.Changes < 0:
-		 *       changeType = fmt.Sprintf("Replacing + Removing %v following chars", item.Changes)
-		 *     default:
-		 *       changeType = "Undefined"
-		 *     }
-		 *     // log.Printf("### %+q with size %v : %v with offset %v ###\n", item.RuneVal, len(item.RuneVal), changeType, offset)
-		 *     // log.Printf("### '%v' with size %v : %v with offset %v ###\n", item.RuneVal, len(item.RuneVal), changeType, offset)
-		 *     fmt.Printf("'%v' - changes: %v\n", item.RuneVal, item.Changes)
-		 *  */
-		idx := offset
-		// fmt.Printf("idx: %v\n", idx)
 		var align []int
-		if item.Changes > 0 {
-			if idx < 1 {
-				align = []int{0, 0}
-			} else {
-				// This is a newly inserted character, so it shares the same alignment
-				// as the previous one
-				align = n.alignments[idx-1]
-			}
-		} else {
-			align = n.alignments[idx]
+		switch {
+		case item.Changes <= 0:
+			align = n.alignments[offset]
+		case offset < 1:
+			align = []int{0, 0}
+		default:
+			// This is a newly inserted character, so it shares the same alignment
+			// as the previous one
+			align = n.alignments[offset-1]
 		}
 
-		// If we are replacing a character, find it and compute the change in size
-		var replacedChar rune
+		// Skip the replaced character and the removed ones following it
 		if item.Changes <= 0 {
-			replacedChar, _ = replacedNormalized.Next()
-		} else {
-			replacedChar = 0 // nil rune value
-		}
-		replacedCharSize := 0
-		if replacedChar > 0 {
-			replacedCharSize = len([]byte(string(replacedChar)))
-		}
-		replacedCharSizeChange := len(item.RuneVal) - replacedCharSize
-		if replacedChar > 0 {
-			// log.Printf("Replacing char: '%v' - with a change in size: %v", string(replacedChar), replacedCharSizeChange)
-		}
-
-		// If we are removing some characters, find them too
-		var nChanges int = 0
-		if item.Changes < 0 {
-			nChanges = -item.Changes
-		}
-
-		var (
-			removedChars       []rune
-			totalBytesToRemove int
-		)
-		for i := 0; i < nChanges; i++ {
-			c, ok := replacedNormalized.Next()
-			if !ok {
-				// We want to panic here, because the NormalizedString is in
-				// a bad state if this happens. We already modified a lot of things
-				// log.Fatalf("2. Expected to remove %v characters but couldn't find them ...\n", nChanges)
-			}
-			removedChars = append(removedChars, c)
-			totalBytesToRemove += len(string(c))
-		}
-		// fmt.Printf("removedChars: %+q\n", string(removedChars))
-		// log.Printf("Total bytes to remove: %v\n", totalBytesToRemove)
-
-		// If we are removing characters, there are two possible scenarios:
-		//   1. We remove characters that are part of the original string (most likely)
-		//   2. We remove characters that were previously added (with NFD for example)
-		//      and are just part of the normalized string
-
-		var removingFromOriginal, removingFromNormalized int = 0, 0
-		if totalBytesToRemove > 0 {
-			start := n.alignments[idx][1]
-			end := n.alignments[idx+totalBytesToRemove][1]
-			originalRange := util.MakeRange(start, end)
-			// fmt.Printf("start: %v - end: %v; range: (%+v)\n", start, end, originalRange)
-			removingFromOriginal = len(originalRange)
-			removingFromNormalized = totalBytesToRemove - len(originalRange)
-
-			// log.Printf("Bytes to remove from original alignments: %v\n", removingFromOriginal)
-			// log.Printf("Bytes to remove from normalized alignments: %v\n", removingFromNormalized)
+			skip(1 - item.Changes)
 		}
 
-		// Update the original alignments for the **current** `char`
-		// fmt.Printf("align: %v\n", align)
-		// fmt.Printf("current alignmentsOriginal: %+v\n", n.alignmentsOriginal)
-		alignments := n.alignmentsOriginal[align[0]:align[1]]
-		// fmt.Printf("alignments: %+v\n", alignments)
-		if len(alignments) > 0 {
-			// log.Printf("Updating original alignments: %+v\n", alignments)
-
-			// Let's compute the actual change in size for the original alignment.
-			// This value may be different than `replacedCharSizeChange` because the
-			// char might not exist in the original string. It might have been added.
-			originalRange := expandAlignments(alignments)
-
-			replacedCharSizeChangeOriginal := len(item.RuneVal) - (originalRange[1] - originalRange[0])
-			// log.Printf("Change in size for the current char: %v\n", replacedCharSizeChange)
-			// log.Printf("Change in size in the original alignment for the current char: %v\n", replacedCharSizeChangeOriginal)
-
-			var newAlignments [][]int
-			for _, offsets := range alignments {
-				var newAlign []int = make([]int, 2)
-
-				// A new char is being added, we need to extend the current
-				// alignment. No need to apply the shift in this case as it
-				// should have been applied already (with a previous changes == 0).
-				if item.Changes > 0 {
-					newAlign[0] = offsets[0]
-					newAlign[1] = offsets[1] + len(item.RuneVal)
-				} else {
-					// Otherwise we just apply the shift
-					newAlign[0] = offsets[0]
-					newAlign[1] = applySign(offsets[1], replacedCharSizeChange)
-					newAlign[1] = applySign(newAlign[1], -(removingFromNormalized))
-
-					// NOTE: We only apply oShift if we are:
-					// - Removing characters
-					// - Replacing one, that has the same size in both normalized and
-					//   original alignments.
-					// Otherwise it means we are modifying multiple times the same
-					// original character. This happens when normalized characters
-					// are added (not part of the original), and then replaced.
-					if item.Changes < 0 || replacedCharSizeChange == replacedCharSizeChangeOriginal {
-						newAlign[0] = applySign(newAlign[0], oShift)
-						newAlign[1] = applySign(newAlign[1], oShift)
-					}
-				}
-
-				newAlignments = append(newAlignments, newAlign)
-			}
-
-			// Now, update original alignments for current `char` with new alignments
-			// fmt.Printf("newAlignments: %+v\n", newAlignments)
-			var aligns [][]int
-			if align[0] == 0 {
-				aligns = append(newAlignments, n.alignmentsOriginal[align[1]:]...)
-			} else {
-				aligns = append(n.alignmentsOriginal[:align[0]], newAlignments...)
-				aligns = append(aligns, n.alignmentsOriginal[align[1]:]...)
-			}
-			n.alignmentsOriginal = aligns
-			// log.Printf("Updated to: %+v\n", newAlignments)
-			// fmt.Printf("current n.alignmentsOriginal: %+v\n", n.alignmentsOriginal)
-		}
-
-		// If some were removed, we need to zero them out in the original alignments
-		if removingFromOriginal > 0 {
-			start := n.alignments[idx][1]
-			end := n.alignments[idx+totalBytesToRemove][1]
-			// They should use the original alignment of the current character
-			newIdx := n.alignmentsOriginal[align[0]][1]
-			alignments := n.alignmentsOriginal[start:end]
-			var newAlignments [][]int
-			if len(alignments) > 0 {
-				// log.Printf("Removing original alignments: %v\n", alignments)
-				for _, offsets := range alignments {
-					offsets[0] = newIdx
-					offsets[1] = newIdx
-					newAlignments = append(newAlignments, offsets)
-				}
-
-				aligns := append(n.alignmentsOriginal[:start], newAlignments...)
-				aligns = append(aligns, n.alignmentsOriginal[end:]...)
-				n.alignmentsOriginal = aligns
-			}
-		}
-
-		// Keep track of the changes for next offsets
-		offset += replacedCharSize
-		offset += totalBytesToRemove
-		// For the original only the real modifications count
-		oShift += replacedCharSizeChange
-		oShift -= totalBytesToRemove
-		// log.Printf("New normalized alignment: %vx %v\n", len(item.RuneVal), align)
-
 		for i := 0; i < len(item.RuneVal); i++ {
 			normalizedAlignments = append(normalizedAlignments, align)
 		}
+		normalized.WriteString(item.RuneVal)
+	}
 
-		// Then we keep only the char for string reconstruction
-		normalizedRunes = append(normalizedRunes, []rune(item.RuneVal)...)
-
-		// fmt.Printf("replacedCharSize: %v\n", replacedCharSize)
-		// fmt.Printf("totalBytesToRemove: %v\n", totalBytesToRemove)
-		// fmt.Printf("next offset: %v\n", offset)
-
-	} // End of `For` block
+	// Replace the alignments and the normalized string in range
+	alignments := make([][]int, 0, len(n.alignments)-(nRange.end-nRange.start)+len(normalizedAlignments))
+	alignments = append(alignments, n.alignments[:nRange.start]...)
+	alignments = append(alignments, normalizedAlignments...)
+	alignments = append(alignments, n.alignments[nRange.end:]...)
+	n.alignments = alignments
+	n.normalized = n.normalized[:nRange.start] + normalized.String() + n.normalized[nRange.end:]
+	n.updateAlignmentsOriginal()
 
-	// Apply the changes to the remaining original alignments
-	if oShift != 0 {
-		// log.Printf("Shifting the end  from %v using shift: %v\n", endShiftStart, oShift)
+	return n
+}
 
-		var endShift [][]int
-		for _, item := range n.alignments[endShiftStart:] {
-			endShift = append(endShift, item)
-		}
-		endRange := expandAlignments(endShift)
-
-		// log.Printf("End range: %+v\n", endRange)
-
-		if endRange != nil {
-			alignments := n.alignmentsOriginal[endRange[0]:endRange[1]]
-			var newAlignments [][]int
-			if len(alignments) > 0 {
-				// log.Printf("Alignments before shifting: %+v\n", alignments)
-				for _, offsets := range alignments {
-					newOffset0 := applySign(offsets[0], oShift)
-					newOffset1 := applySign(offsets[1], oShift)
-					newAlignments = append(newAlignments, []int{newOffset0, newOffset1})
-				}
-				aligns := append(n.alignmentsOriginal[:endRange[0]], newAlignments...)
-				aligns = append(aligns, n.alignmentsOriginal[endRange[1]:]...)
-				n.alignmentsOriginal = aligns
-				// log.Printf("After: %+v\n", newAlignments)
+// updateAlignmentsOriginal computes the original alignments from the
+// normalized ones: each original byte aligns to the normalized bytes it
+// produced, or to the empty range where they would be if it was removed.
+func (n *NormalizedString) updateAlignmentsOriginal() {
+	alignments := make([][]int, len(n.original))
+	for i, a := range n.alignments {
+		for j := a[0]; j < a[1] && j < len(alignments); j++ {
+			if alignments[j] == nil {
+				alignments[j] = []int{i, i + 1}
+			} else {
+				alignments[j][1] = i + 1
 			}
 		}
 	}
 
-	// replace alignments with new ones in range
-	var newAlignments [][]int
-	// fmt.Printf("nRange: %v\n", nRange)
-	// fmt.Printf("normalizedAlignments: %+v\n", normalizedAlignments)
-	// fmt.Printf("alignments to be replaced: %v\n", n.alignments[nRange.start:nRange.end])
-	// fmt.Printf("normalized alignments before action: %v\n", n.alignments)
-	if len(n.alignments[:nRange.start]) == 0 { // at the beginning
-		newAlignments = append(normalizedAlignments, n.alignments[nRange.end:]...)
-	} else { // in the middle
-		var beforeAligns, afterAligns [][]int
-		for i, a := range n.alignments {
-			if i < nRange.start {
-				beforeAligns = append(beforeAligns, a)
-			}
-			if i >= nRange.end {
-				afterAligns = append(afterAligns, a)
-			}
+	end := 0
+	for j, a := range alignments {
+		if a == nil {
+			alignments[j] = []int{end, end}
+		} else {
+			end = a[1]
 		}
-		newAlignments = append(beforeAligns, normalizedAlignments...)
-		newAlignments = append(newAlignments, afterAligns...)
 	}
-	n.alignments = newAlignments
-
-	// Finally, change the `normalized` string
-	newNormalized := n.normalized[:nRange.start] + string(normalizedRunes) + n.normalized[nRange.end:]
-	n.normalized = newNormalized
-
-	// log.Printf("New normalized alignments: %+v\nNew original alignments: %+v\n", n.alignments, n.alignmentsOriginal)
-	// log.Printf("New normalized string: %q\n", n.normalized)
-
-	return n
+	n.alignmentsOriginal = alignments
 }
 
 // Transform applies transformations to the current normalized version, updating the current
@@ -855,162 +621,74 @@ func (n *NormalizedString) Transform(m []ChangeMap, initialOffset int) (retVal *
 	return n.TransformRange(wholeRange, m, initialOffset)
 }
 
-func (n *NormalizedString) NFD() (retVal *NormalizedString) {
-
-	s := n.normalized
+// unicodeChangeMap builds the ChangeMap normalizing s to the given form.
+//
+// The string is processed one segment at a time. A segment is a starter rune followed
+// by its non-starters, and maps k input runes to m output runes:
+// - m == k: one to one, no changes
+// - m > k: the extra runes are inserted after the first k ones (i.e. decomposition)
+// - m < k: the last output rune replaces the k - m removed runes (i.e. composition)
+func unicodeChangeMap(s string, form norm.Form) []ChangeMap {
 	var (
 		changeMap []ChangeMap
 		it        norm.Iter
 	)
-	// Create slice of (char, changes) to map changing
-	// if added (inserted) rune, changes = 1; `-N` if char
-	// right before N removed chars
-	// changes = 0 if this represents the old one (even if changed)
-
-	// Iterating over string and apply tranformer (NFD). One character at a time
-	// A `character` is defined as:
-	// - a sequence of runes that starts with a starter,
-	// - a rune that does not modify or combine backwards with any other rune,
-	// - followed by possibly empty sequence of non-starters, that is, runes that do (typically accents).
-	// We will iterate over string and apply transformer to each char
-	// If a char composes of one rune, there no changes
-	// If more than one rune, first is no change, the rest is 1 changes
-	it.InitString(norm.NFD, s)
+
+	it.InitString(form, s)
+	prev := 0
 	for !it.Done() {
-		// runes := []rune(string(it.Next()))
 		runes := bytes.Runes(it.Next())
+		k := utf8.RuneCountInString(s[prev:it.Pos()])
+		prev = it.Pos()
 
 		for i, r := range runes {
-			switch i := i; {
-			case i == 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 0,
-				})
-			case i > 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 1,
-				})
+			change := 0
+			switch {
+			case i >= k:
+				change = 1
+			case i == len(runes)-1 && len(runes) < k:
+				change = -(k - len(runes))
 			}
+			changeMap = append(changeMap, ChangeMap{
+				RuneVal: string(r),
+				Changes: change,
+			})
 		}
 	}
 
-	// for i, c := range changeMap {
-	// fmt.Printf("%v - Char: %+q - changes: %v\n", i, c.RuneVal, c.Changes)
-	// }
-
-	return n.Transform(changeMap, 0)
+	return changeMap
 }
 
-func (n *NormalizedString) NFC() (retVal *NormalizedString) {
-	var (
-		changeMap []ChangeMap
-		it        norm.Iter
-	)
-
-	s := n.normalized
-
-	isNFC := norm.Form.IsNormalString(norm.NFC, s)
-	if isNFC {
+func (n *NormalizedString) NFD() (retVal *NormalizedString) {
+	if norm.NFD.IsNormalString(n.normalized) {
 		return n
 	}
 
-	it.InitString(norm.NFD, s)
-
-	for !it.Done() {
-		// runes := []rune(string(it.Next()))
-		runes := bytes.Runes(it.Next())
+	return n.Transform(unicodeChangeMap(n.normalized, norm.NFD), 0)
+}
 
-		for i, r := range runes {
-			switch i := i; {
-			case i == 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 0,
-				})
-			case i > 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 1,
-				})
-			}
-		}
+func (n *NormalizedString) NFC() (retVal *NormalizedString) {
+	if norm.NFC.IsNormalString(n.normalized) {
+		return n
 	}
 
-	return n.Transform(changeMap, 0)
+	return n.Transform(unicodeChangeMap(n.normalized, norm.NFC), 0)
 }
 
 func (n *NormalizedString) NFKD() (retVal *NormalizedString) {
-
-	s := n.normalized
-	isNFKD := norm.Form.IsNormalString(norm.NFKD, s)
-	if isNFKD {
-		return
-	}
-
-	var (
-		changeMap []ChangeMap
-		it        norm.Iter
-	)
-
-	it.InitString(norm.NFKD, s)
-	for !it.Done() {
-		runes := []rune(string(it.Next()))
-
-		for i, r := range runes {
-			switch i := i; {
-			case i == 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 0,
-				})
-			case i > 0:
-				changeMap = append(changeMap, ChangeMap{
-					RuneVal: string(r),
-					Changes: 1,
-				})
-			}
-		}
+	if norm.NFKD.IsNormalString(n.normalized) {
+		return n
 	}
 
-	return n.Transform(changeMap, 0)
+	return n.Transform(unicodeChangeMap(n.normalized, norm.NFKD), 0)
 }
 
 func (n *NormalizedString) NFKC() (retVal *NormalizedString) {
-
-	var (
-		changeMap []ChangeMap
-		it        norm.Iter
-	)
-
-	s := n.normalized
-
-	isNFKC := norm.Form.IsNormalString(norm.NFKC, s)
-
-	if isNFKC {
+	if norm.NFKC.IsNormalString(n.normalized) {
 		return n
 	}
 
-	it.InitString(norm.NFKD, n.normalized)
-
-	for !it.Done() {
-		runes := []rune(string(it.Next()))
-
-		if len(runes) == 1 {
-			changeMap = append(changeMap, ChangeMap{
-				RuneVal: string(runes),
-				Changes: 0,
-			})
-		} else if len(runes) > 1 {
-			changeMap = append(changeMap, ChangeMap{
-				RuneVal: string(runes),
-				Changes: -1,
-			})
-		}
-	}
-
-	return n.Transform(changeMap, 0)
+	return n.Transform(unicodeChangeMap(n.normalized, norm.NFKC), 0)
 }
 
 // Filter applies filtering on NormalizedString
@@ -1116,7 +794,7 @@ func (n *NormalizedString) Map(nfn NormFn) (retVal *NormalizedString) {
 	s := n.normalized
 	var changeMap []ChangeMap
 	for _, r := range []rune(s) {
-		changeMap = append(changeMap, ChangeMap{string(r), 0})
+		changeMap = append(changeMap, ChangeMap{string(nfn(r)), 0})
 	}
 
 	return n.Transform(changeMap, 0)
@@ -1144,16 +822,34 @@ func (n *NormalizedString) RemoveAccents() (retVal *NormalizedString) {
 
 // Lowercase transforms string to lowercase
 func (n *NormalizedString) Lowercase() (retVal *NormalizedString) {
-	n.normalized = strings.ToLower(n.normalized)
-
-	return n
+	return n.changeCase(strings.ToLower)
 }
 
 // Uppercase transforms string to uppercase
 func (n *NormalizedString) Uppercase() (retVal *NormalizedString) {
-	n.normalized = strings.ToUpper(n.normalized)
+	return n.changeCase(strings.ToUpper)
+}
 
-	return n
+// changeCase applies fn to each `char` of normalized string. The alignments
+// follow chars whose case takes a different number of bytes (i.e. `Ⱥ`
+// lowercased to `ⱥ`).
+func (n *NormalizedString) changeCase(fn func(string) string) *NormalizedString {
+	if fn(n.normalized) == n.normalized {
+		return n
+	}
+
+	var changeMap []ChangeMap
+	for _, r := range n.normalized {
+		for i, c := range fn(string(r)) {
+			change := 0
+			if i > 0 {
+				change = 1
+			}
+			changeMap = append(changeMap, ChangeMap{string(c), change})
+		}
+	}
+
+	return n.Transform(changeMap, 0)
 }
 
 // Clear clears the normalized part of the string
@@ -1181,10 +877,46 @@ func (n *NormalizedString) Clear() {
 //   - MergedWithNextBehavior => `[ "the", "-final", "-", "-countdown" ]`
 //   - Contiguous => `[ "the", "-", "final", "--", "countdown" ]`
 func (n *NormalizedString) Split(pattern Pattern, behavior SplitDelimiterBehavior) (retVal []NormalizedString) {
+	splits := splitOffsets(n.GetNormalized(), pattern, behavior)
 
-	// fmt.Printf("input normalized: %v\n", n)
+	// Then split according to the computed splits
+	var slices []NormalizedString
+	for _, split := range splits {
+		if !split.Match {
+			slice := n.Slice(NewRange(split.Offsets[0], split.Offsets[1], NormalizedTarget))
+			if slice != nil {
+				slices = append(slices, *slice)
+			}
+		}
+	}
 
-	matches := pattern.FindMatches(n.GetNormalized())
+	// log.Printf("output: %+v\n", slices)
+
+	return slices
+}
+
+// SplitString splits s as `NormalizedString.Split` splits its normalized
+// string, without tracking alignments. Empty splits are dropped.
+func SplitString(s string, pattern Pattern, behavior SplitDelimiterBehavior) []string {
+	return AppendSplitString(nil, s, pattern, behavior)
+}
+
+// AppendSplitString appends the splits of `SplitString(s, pattern, behavior)`
+// to dst and returns the extended slice.
+func AppendSplitString(dst []string, s string, pattern Pattern, behavior SplitDelimiterBehavior) []string {
+	for _, split := range splitOffsets(s, pattern, behavior) {
+		if !split.Match && split.Offsets[0] < split.Offsets[1] {
+			dst = append(dst, s[split.Offsets[0]:split.Offsets[1]])
+		}
+	}
+
+	return dst
+}
+
+// splitOffsets returns the splits of s on the pattern, following the behavior.
+// Splits to remove have `Match` set.
+func splitOffsets(s string, pattern Pattern, behavior SplitDelimiterBehavior) []OffsetsMatch {
+	matches := pattern.FindMatches(s)
 
 	// fmt.Printf("length of matches: %v\n", len(matches))
 	// for i, m := range matches {
@@ -1265,20 +997,7 @@ func (n *NormalizedString) Split(pattern Pattern, behavior SplitDelimiterBehavio
 		}
 	}
 
-	// Then split according to the computed splits
-	var slices []NormalizedString
-	for _, split := range splits {
-		if !split.Match {
-			slice := n.Slice(NewRange(split.Offsets[0], split.Offsets[1], NormalizedTarget))
-			if slice != nil {
-				slices = append(slices, *slice)
-			}
-		}
-	}
-
-	// log.Printf("output: %+v\n", slices)
-
-	return slices
+	return splits
 }
 
 // LStrip removes leading spaces
@@ -35,6 +35,55 @@ func TestNormalized_NFDAddsNewChars(t *testing.T) {
 	}
 }
 
+func TestNormalized_NFCComposesChars(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("e\u0301le\u0301gant").NFC()
+
+	if got, want := n.GetNormalized(), "\u00e9l\u00e9gant"; got != want {
+		t.Errorf("Want normalized %q, got %q\n", want, got)
+	}
+
+	// Composed chars are aligned with their starter, the combining marks are removed.
+	wantN := [][]int{{0, 1}, {0, 1}, {3, 4}, {4, 5}, {4, 5}, {7, 8}, {8, 9}, {9, 10}, {10, 11}}
+	gotN := n.Alignments()
+
+	if !reflect.DeepEqual(wantN, gotN) {
+		t.Errorf("Want normalized: %v\n", wantN)
+		t.Errorf("Got normalized: %v\n", gotN)
+	}
+
+	if got := n.NFKC(); got.GetNormalized() != n.GetNormalized() {
+		t.Errorf("Want NFKC of NFC to be unchanged, got %q\n", got.GetNormalized())
+	}
+}
+
+func TestNormalized_InvalidUTF8(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("a\xffb")
+
+	if got, want := n.GetNormalized(), "a\ufffdb"; got != want {
+		t.Errorf("Want normalized %q, got %q\n", want, got)
+	}
+	if got, want := n.GetOriginal(), "a\xffb"; got != want {
+		t.Errorf("Want original %q, got %q\n", want, got)
+	}
+
+	// U+FFFD is aligned to the invalid byte it replaces.
+	wantN := [][]int{{0, 1}, {1, 2}, {1, 2}, {1, 2}, {2, 3}}
+	gotN := n.Alignments()
+
+	wantO := [][]int{{0, 1}, {1, 4}, {4, 5}}
+	gotO := n.AlignmentsOriginal()
+
+	if !reflect.DeepEqual(wantN, gotN) {
+		t.Errorf("Want normalized: %v\n", wantN)
+		t.Errorf("Got normalized: %v\n", gotN)
+	}
+
+	if !reflect.DeepEqual(wantO, gotO) {
+		t.Errorf("Want original: %v\n", wantO)
+		t.Errorf("Got original: %v\n", gotO)
+	}
+}
+
 func TestNormalized_RemoveCharsAddedByNFD(t *testing.T) {
 	n := normalizer.NewNormalizedFrom("élégant").NFD()
 	/*
@@ -237,6 +286,25 @@ func TestNormalized_RemoveAtBeginning(t *testing.T) {
 	}
 }
 
+func TestNormalized_RemoveMultiByteAtBeginning(t *testing.T) {
+	n := normalizer.NewNormalizedFrom("\u200f\u200fab")
+	n.Filter(func(r rune) bool {
+		return r != '\u200f'
+	})
+
+	if got, want := n.GetNormalized(), "ab"; got != want {
+		t.Errorf("Want normalized %q, got %q\n", want, got)
+	}
+
+	wantN := [][]int{{6, 7}, {7, 8}}
+	gotN := n.Alignments()
+
+	if !reflect.DeepEqual(wantN, gotN) {
+		t.Errorf("Want normalized: %v\n", wantN)
+		t.Errorf("Got normalized: %v\n", gotN)
+	}
+}
+
 func TestNormalized_RemoveAtEnd(t *testing.T) {
 	n := normalizer.NewNormalizedFrom("Hello    ")
 	n.Filter(func(r rune) bool {
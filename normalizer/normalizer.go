@@ -8,6 +8,14 @@ type Normalizer interface {
 	Normalize(normalized *NormalizedString) (*NormalizedString, error)
 }
 
+// StringNormalizer is implemented by normalizers which can normalize a plain
+// string without tracking alignments, i.e. to count tokens.
+type StringNormalizer interface {
+	// NormalizeString returns the normalized string of s as Normalize would,
+	// false if it cannot, i.e. for a sequence of other normalizers.
+	NormalizeString(s string) (string, bool)
+}
+
 type normalizer struct {
 	Normalizer Normalizer
 }
@@ -114,7 +114,7 @@ func (s *StringPattern) FindMatches(inside string) []OffsetsMatch {
 	return findMatches(re, inside)
 }
 
-func findMatches(re *regexp.Regexp, inside string) []OffsetsMatch {
+func findMatches(re Regexp, inside string) []OffsetsMatch {
 
 	matches := re.FindAllStringIndex(inside, -1)
 
@@ -185,16 +185,31 @@ func findMatches(re *regexp.Regexp, inside string) []OffsetsMatch {
 }
 
 type RegexpPattern struct {
-	re *regexp.Regexp
+	expr string
+	re   Regexp
 }
 
+// NewRegexpPattern creates a pattern of a Go regexp. It panics if s is
+// invalid.
 func NewRegexpPattern(s string) *RegexpPattern {
 	re := regexp.MustCompile(s)
 	return &RegexpPattern{
-		re: re,
+		expr: s,
+		re:   re,
 	}
 }
 
+// CompileRegexpPattern creates a pattern of an Oniguruma regex of a tokenizer
+// config, see `CompileRegex`.
+func CompileRegexpPattern(expr string) (*RegexpPattern, error) {
+	re, err := CompileRegex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegexpPattern{expr: expr, re: re}, nil
+}
+
 // FindMatches implements Pattern interface for RegexpPattern
 func (rp *RegexpPattern) FindMatches(inside string) []OffsetsMatch {
 	if len(inside) == 0 {
@@ -1,98 +1,108 @@
 package normalizer
 
 import (
-	"strings"
+	"unicode/utf8"
 
 	"github.com/season-studio/tokenizer/spm"
 
 	"github.com/rivo/uniseg"
 )
 
+// replace appends the ChangeMap replacing oldPart with newPart.
 func replace(transformations []ChangeMap, oldPart, newPart string) []ChangeMap {
-	oldCount := len(strings.Split(oldPart, ""))
-	newCount := len(strings.Split(newPart, ""))
+	oldCount := utf8.RuneCountInString(oldPart)
+	newCount := utf8.RuneCountInString(newPart)
 	diff := newCount - oldCount
 
 	// If just replacing characters, all changes should be == 0
-	for _, c := range strings.Split(newPart, "") {
-		t := ChangeMap{
-			RuneVal: c,
+	for _, r := range newPart {
+		transformations = append(transformations, ChangeMap{
+			RuneVal: string(r),
 			Changes: 0,
-		}
-		transformations = append(transformations, t)
+		})
 	}
 
-	// log.Printf("transformations: %+v - diff: %v\n", transformations, diff)
-
 	n := len(transformations)
 	switch {
 	case diff > 0:
 		// If adding some characters, the last diff characters should be == 1
-		for i := n - 1; i >= diff || i >= 0; i-- {
+		for i := n - 1; i >= n-diff && i >= 0; i-- {
 			transformations[i].Changes = 1
 		}
 	case diff < 0:
 		// If removing some characters, the last one should include the diff
-		n := len(transformations)
-		transformations[n-1].Changes += diff
+		if n > 0 {
+			transformations[n-1].Changes += diff
+		}
 	}
 
 	return transformations
 }
 
+// Precompiled is the normalizer of SentencePiece models, i.e. XLM-R or T5. Its
+// rules come from the `precompiled_charsmap` of the model.
 type Precompiled struct {
 	*spm.Precompiled
 }
 
-// Implement Normalizer for spm.Precompiled
+// NewPrecompiled creates a Precompiled normalizer from a precompiled charsmap.
+func NewPrecompiled(precompiledCharsmap []byte) (*Precompiled, error) {
+	m, err := spm.NewPrecompiledFrom(precompiledCharsmap)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Precompiled{m}, nil
+}
+
+// Normalize implements Normalizer. Like SentencePiece, graphemes shorter than
+// 6 bytes are replaced as a whole when a rule matches, the others char by char.
 func (m *Precompiled) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
 	original := normalized.GetNormalized()
-	var transformations []ChangeMap
-	for i := 0; i < len(original); i++ {
-		transformations = append(transformations, ChangeMap{})
+	var (
+		transformations []ChangeMap
+		initialOffset   int
+		modified        bool
+	)
+
+	// apply replaces part, removals before any kept char go to initialOffset.
+	apply := func(part, norm string) {
+		modified = true
+		if len(transformations) == 0 && norm == "" {
+			initialOffset += utf8.RuneCountInString(part)
+			return
+		}
+		transformations = replace(transformations, part, norm)
 	}
 
 	graphemes := uniseg.NewGraphemes(original)
-
-	var modified bool
-
 	for graphemes.Next() {
 		grapheme := graphemes.Str()
 
 		if len(grapheme) < 6 {
-			norm := m.Transform(grapheme)
-			if len(norm) > 0 {
-				modified = true
-				transformations = replace(transformations, grapheme, norm)
-
+			if norm, ok := m.Lookup(grapheme); ok {
+				apply(grapheme, norm)
 				continue
 			}
 		}
 
-		// TT. This is a hacky way to turn non-spacing marks into hexa string
-		// and pass the unit tests.
-		grapheme = spm.NormalizeMn(grapheme)
-		var charIdx int = 0
-		for _, r := range grapheme {
-			part := string(grapheme)[charIdx : charIdx+len(string(r))]
-			norm := m.Transform(part)
-			norm = spm.NormalizeMn(norm)
-			if len(norm) > 0 {
-				modified = true
-				transformations = replace(transformations, part, norm)
+		for i := 0; i < len(grapheme); {
+			r, size := utf8.DecodeRuneInString(grapheme[i:])
+			part := grapheme[i : i+size]
+			if norm, ok := m.Lookup(part); ok {
+				apply(part, norm)
 			} else {
-				t := ChangeMap{
+				transformations = append(transformations, ChangeMap{
 					RuneVal: string(r),
 					Changes: 0,
-				}
-				transformations = append(transformations, t)
+				})
 			}
-			// charIdx += len(string(r))
+			i += size
 		}
 	}
 
 	if modified {
-		normalized = normalized.Transform(transformations, 0)
+		normalized = normalized.Transform(transformations, initialOffset)
 	}
 
 	return normalized, nil
@@ -3,10 +3,12 @@ package normalizer
 import (
 	"reflect"
 	"testing"
+
+	"github.com/season-studio/tokenizer/spm"
 )
 
 func TestPrecompiled_Normalize(t *testing.T) {
-	var transformations []ChangeMap = make([]ChangeMap, 10)
+	var transformations []ChangeMap
 
 	n := NewNormalizedFrom("™\x1eg")
 	transformations = replace(transformations, "™", "TM")
@@ -27,3 +29,50 @@ func TestPrecompiled_Normalize(t *testing.T) {
 		t.Errorf("want %s, got %s\n", want, got)
 	}
 }
+
+func TestPrecompiled_NormalizeCharsmap(t *testing.T) {
+	m, err := NewPrecompiled(spm.NmtNfkc())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		original string
+		want     string
+	}{
+		{"™\x1eg", "TMg"},
+		{"\x1e\x1eab", "ab"},
+		{"\x1e", ""},
+		{"ﬁne 𝔾", "fine G"},
+		{"\u095cी", "ड\u093cी"},
+		{"hello", "hello"},
+	}
+
+	for _, tt := range tests {
+		n, err := m.Normalize(NewNormalizedFrom(tt.original))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := n.GetNormalized(); got != tt.want {
+			t.Errorf("%q: want %q, got %q", tt.original, tt.want, got)
+		}
+		if got := m.NormalizeString(tt.original); got != tt.want {
+			t.Errorf("%q: want NormalizeString %q, got %q", tt.original, tt.want, got)
+		}
+	}
+
+	// "fi" both map back to the ligature, "G" to the 4 bytes of "𝔾".
+	n, err := m.Normalize(NewNormalizedFrom("ﬁne 𝔾"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		start, end int
+		want       string
+	}{{0, 1, "ﬁ"}, {1, 2, "ﬁ"}, {2, 3, "n"}, {5, 6, "𝔾"}} {
+		r := n.ConvertOffset(NewRange(tt.start, tt.end, NormalizedTarget))
+		if got := n.GetOriginal()[r.start:r.end]; got != tt.want {
+			t.Errorf("[%v, %v): want %q, got %q", tt.start, tt.end, tt.want, got)
+		}
+	}
+}
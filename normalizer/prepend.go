@@ -1,6 +1,7 @@
 package normalizer
 
-// Prepend creates a normalizer that strip the normalized string inplace.
+// Prepend creates a normalizer that prepends a string to the normalized string,
+// i.e. "▁" for SentencePiece models. Empty strings are left as is.
 type Prepend struct {
 	Prepend string `json:"prepend"`
 }
@@ -12,7 +13,7 @@ func NewPrepend(prepend string) *Prepend {
 // Implement Normalizer for Prepend
 func (p *Prepend) Normalize(normalized *NormalizedString) (*NormalizedString, error) {
 	if normalized.IsEmpty() {
-		return nil, nil
+		return normalized, nil
 	}
 
 	return normalized.Prepend(p.Prepend), nil
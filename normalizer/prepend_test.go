@@ -54,3 +54,13 @@ func TestPrepend(t *testing.T) {
 	}
 
 }
+
+func TestPrepend_Empty(t *testing.T) {
+	out, err := NewPrepend("▁").Normalize(NewNormalizedFrom(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out == nil || !out.IsEmpty() {
+		t.Errorf("want empty string, got %v", out)
+	}
+}
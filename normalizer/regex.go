@@ -0,0 +1,335 @@
+package normalizer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Regexp is a compiled regular expression, safe for concurrent use.
+// *regexp.Regexp implements it.
+type Regexp interface {
+	// FindAllStringIndex returns the [start, end) byte offsets of the successive
+	// non-overlapping matches of s, at most n if n >= 0, as regexp does.
+	FindAllStringIndex(s string, n int) [][]int
+}
+
+// RegexEngine compiles the Oniguruma patterns of tokenizer configs that Go
+// regexp cannot honor, i.e. a binding of PCRE or Oniguruma. See
+// `SetRegexEngine`.
+type RegexEngine interface {
+	Compile(expr string) (Regexp, error)
+}
+
+// ErrUnsupportedRegex is returned by `CompileRegex` for patterns using
+// constructs of Oniguruma without Go regexp counterpart, i.e. lookbehinds,
+// when no RegexEngine is set.
+var ErrUnsupportedRegex = errors.New("unsupported regex")
+
+var regexEngine RegexEngine
+
+// SetRegexEngine sets the engine compiling the patterns that `CompileRegex`
+// cannot translate to Go regexp, nil to reset. It must be set before loading
+// tokenizers.
+func SetRegexEngine(engine RegexEngine) {
+	regexEngine = engine
+}
+
+// CompileRegex compiles a regex of a tokenizer config. These are Oniguruma
+// patterns, as HuggingFace tokenizers use Oniguruma: the pattern is translated
+// to Go regexp by `TranslateRegex`, falling back to the engine set by
+// `SetRegexEngine` if it cannot be.
+func CompileRegex(expr string) (Regexp, error) {
+	re, err := compileTranslated(expr)
+	if err == nil {
+		return re, nil
+	}
+	if regexEngine != nil {
+		return regexEngine.Compile(expr)
+	}
+
+	return nil, err
+}
+
+// trailingSpaceLookahead is the lookaround of the GPT-2 and tiktoken split
+// patterns. Go regexp has no lookaround and it is emulated by lookaheadRegexp.
+const (
+	trailingSpaceLookahead = `\s+(?!\S)`
+	trailingSpaceGroup     = "trailingws"
+)
+
+func compileTranslated(expr string) (Regexp, error) {
+	translated := strings.Replace(expr, trailingSpaceLookahead, `(?P<`+trailingSpaceGroup+`>\s+)`, 1)
+	translated, err := TranslateRegex(translated)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrUnsupportedRegex, expr, err)
+	}
+
+	re, err := regexp.Compile(translated)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", expr, err)
+	}
+	if group := re.SubexpIndex(trailingSpaceGroup); group > 0 {
+		return &lookaheadRegexp{re: re, group: group}, nil
+	}
+
+	return re, nil
+}
+
+// lookaheadRegexp emulates the `\s+(?!\S)` alternative (a whitespace run not
+// followed by a non-whitespace): it is matched as `\s+` and gives its last
+// whitespace back to the next match when followed by a non-whitespace. Empty
+// matches are skipped.
+type lookaheadRegexp struct {
+	re    *regexp.Regexp
+	group int
+}
+
+func (r *lookaheadRegexp) FindAllStringIndex(s string, n int) [][]int {
+	var matches [][]int
+	for pos := 0; pos < len(s) && (n < 0 || len(matches) < n); {
+		loc := r.re.FindStringSubmatchIndex(s[pos:])
+		if loc == nil {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+
+		if loc[2*r.group] >= 0 && end < len(s) {
+			next, _ := utf8.DecodeRuneInString(s[end:])
+			last, size := utf8.DecodeLastRuneInString(s[start:end])
+			if !unicode.IsSpace(next) && unicode.IsSpace(last) && end-size > start {
+				end -= size
+			}
+		}
+
+		if end == start {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			pos = end + size
+			continue
+		}
+
+		// Reuse loc for the match offsets.
+		loc[0], loc[1] = start, end
+		matches = append(matches, loc[:2:2])
+		pos = end
+	}
+
+	return matches
+}
+
+// Class items of the Oniguruma escapes matching Unicode characters, where Go
+// regexp ones are ASCII only.
+const (
+	spaceClass = `\s\x0B\x{85}\p{Z}`
+	digitClass = `\p{Nd}`
+	wordClass  = `\p{L}\p{M}\p{Nd}\p{Pc}`
+	hexClass   = `0-9a-fA-F`
+)
+
+// TranslateRegex translates an Oniguruma pattern to Go regexp syntax:
+//   - `\s`, `\d`, `\w` match Unicode characters as in Oniguruma, `\h` hex
+//     digits,
+//   - `\p{...}` takes the long names of Unicode categories (i.e.
+//     `\p{Letter}`), case insensitive scripts and `\p{^...}` negations,
+//   - `\uHHHH` is `\x{HHHH}` and `\e` the escape character.
+//
+// It fails for lookarounds, atomic groups, possessive quantifiers,
+// backreferences, subroutines, class intersections and the other constructs Go
+// regexp cannot honor. `\b` stays an ASCII word boundary.
+func TranslateRegex(expr string) (string, error) {
+	var (
+		sb      strings.Builder
+		inClass bool
+		quant   bool // whether the previous byte ends a quantifier
+	)
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		wasQuant := quant
+		quant = !inClass && strings.IndexByte("*+?}", c) >= 0 && !(c == '?' && i > 0 && expr[i-1] == '(')
+		switch {
+		case c == '\\' && i+1 < len(expr):
+			n, err := translateEscape(&sb, expr[i:], inClass)
+			if err != nil {
+				return "", err
+			}
+			i += n - 1
+			continue
+		case c == '[' && !inClass:
+			inClass = true
+			sb.WriteByte(c)
+			// A leading `]` or `^]` is a literal.
+			if strings.HasPrefix(expr[i+1:], "^") {
+				sb.WriteByte('^')
+				i++
+			}
+			if strings.HasPrefix(expr[i+1:], "]") {
+				sb.WriteByte(']')
+				i++
+			}
+			continue
+		case c == '[' && inClass && strings.HasPrefix(expr[i:], "[:"):
+			// POSIX class, i.e. `[:alpha:]`.
+			end := strings.Index(expr[i:], ":]")
+			if end < 0 {
+				return "", errors.New("unterminated POSIX class")
+			}
+			sb.WriteString(expr[i : i+end+2])
+			i += end + 1
+			continue
+		case c == '[' && inClass:
+			return "", errors.New("nested character classes are not supported")
+		case c == '&' && inClass && strings.HasPrefix(expr[i:], "&&"):
+			return "", errors.New("class intersections are not supported")
+		case c == ']' && inClass:
+			inClass = false
+		case c == '(' && !inClass && strings.HasPrefix(expr[i:], "(?"):
+			for _, group := range []string{"(?=", "(?!", "(?<=", "(?<!", "(?>", "(?~", "(?#", "(?'"} {
+				if strings.HasPrefix(expr[i:], group) {
+					return "", fmt.Errorf("group %q is not supported", group)
+				}
+			}
+		case c == '+' && wasQuant:
+			return "", errors.New("possessive quantifiers are not supported")
+		}
+		sb.WriteByte(c)
+	}
+
+	return sb.String(), nil
+}
+
+// translateEscape writes the translation of the escape sequence starting expr
+// and returns its length.
+func translateEscape(sb *strings.Builder, expr string, inClass bool) (int, error) {
+	next := expr[1]
+	switch next {
+	case 's', 'S':
+		return 2, writeClass(sb, spaceClass, next == 'S', inClass)
+	case 'd', 'D':
+		return 2, writeClass(sb, digitClass, next == 'D', inClass)
+	case 'w', 'W':
+		return 2, writeClass(sb, wordClass, next == 'W', inClass)
+	case 'h', 'H':
+		return 2, writeClass(sb, hexClass, next == 'H', inClass)
+	case 'p', 'P':
+		name, n, ok := propertyName(expr)
+		if !ok {
+			return 0, fmt.Errorf("invalid Unicode property %q", expr[:n])
+		}
+		neg := next == 'P'
+		if strings.HasPrefix(name, "^") {
+			name, neg = name[1:], !neg
+		}
+		class, ok := unicodeProperty(name)
+		if !ok {
+			return 0, fmt.Errorf("unknown Unicode property %q", name)
+		}
+		return n, writeClass(sb, class, neg, inClass)
+	case 'u':
+		if len(expr) < 6 || strings.Trim(expr[2:6], hexDigits) != "" {
+			return 0, fmt.Errorf("invalid escape %q", expr[:min(len(expr), 6)])
+		}
+		sb.WriteString(`\x{` + expr[2:6] + `}`)
+		return 6, nil
+	case 'e':
+		sb.WriteString(`\x1B`)
+		return 2, nil
+	case 'G', 'K', 'R', 'X', 'Z', 'g', 'k', 'y', 'Y', 'O', 'N',
+		'1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return 0, fmt.Errorf("escape %q is not supported", expr[:2])
+	}
+
+	sb.WriteString(expr[:2])
+	return 2, nil
+}
+
+const hexDigits = "0123456789abcdefABCDEF"
+
+// writeClass writes the class of the given items, negated if neg. Inside a
+// class, only a single Unicode property can be negated.
+func writeClass(sb *strings.Builder, items string, neg, inClass bool) error {
+	switch {
+	case !inClass && neg:
+		sb.WriteString("[^" + items + "]")
+	case !inClass:
+		sb.WriteString("[" + items + "]")
+	case !neg:
+		sb.WriteString(items)
+	case strings.HasPrefix(items, `\p{`) && strings.Count(items, `\`) == 1:
+		sb.WriteString(`\P` + items[2:])
+	default:
+		return fmt.Errorf("negated class %q inside a class is not supported", items)
+	}
+
+	return nil
+}
+
+// propertyName returns the name of the `\p{Name}` or `\pN` property starting
+// expr, and the length of the escape.
+func propertyName(expr string) (string, int, bool) {
+	if len(expr) < 3 {
+		return "", len(expr), false
+	}
+	if expr[2] != '{' {
+		return expr[2:3], 3, true
+	}
+	end := strings.IndexByte(expr, '}')
+	if end < 0 {
+		return "", len(expr), false
+	}
+
+	return expr[3:end], end + 1, true
+}
+
+// unicodeCategoryNames are the long names of the Unicode general categories.
+var unicodeCategoryNames = map[string]string{
+	"Letter": "L", "Uppercase_Letter": "Lu", "Lowercase_Letter": "Ll", "Titlecase_Letter": "Lt",
+	"Modifier_Letter": "Lm", "Other_Letter": "Lo",
+	"Mark": "M", "Nonspacing_Mark": "Mn", "Spacing_Mark": "Mc", "Enclosing_Mark": "Me",
+	"Number": "N", "Decimal_Number": "Nd", "Letter_Number": "Nl", "Other_Number": "No",
+	"Punctuation": "P", "Connector_Punctuation": "Pc", "Dash_Punctuation": "Pd", "Open_Punctuation": "Ps",
+	"Close_Punctuation": "Pe", "Initial_Punctuation": "Pi", "Final_Punctuation": "Pf", "Other_Punctuation": "Po",
+	"Symbol": "S", "Math_Symbol": "Sm", "Currency_Symbol": "Sc", "Modifier_Symbol": "Sk", "Other_Symbol": "So",
+	"Separator": "Z", "Space_Separator": "Zs", "Line_Separator": "Zl", "Paragraph_Separator": "Zp",
+	"Other": "C", "Control": "Cc", "Format": "Cf", "Private_Use": "Co", "Surrogate": "Cs",
+}
+
+// unicodeProperties maps the loose names of the supported properties (see
+// `looseName`) to their class items.
+var unicodeProperties = func() map[string]string {
+	props := map[string]string{
+		"any":   `\x{0}-\x{10FFFF}`,
+		"digit": digitClass,
+		"space": spaceClass,
+		"word":  wordClass,
+		"punct": `\p{P}`,
+	}
+	for name := range unicode.Categories {
+		props[looseName(name)] = `\p{` + name + `}`
+	}
+	for name := range unicode.Scripts {
+		props[looseName(name)] = `\p{` + name + `}`
+	}
+	for long, short := range unicodeCategoryNames {
+		props[looseName(long)] = `\p{` + short + `}`
+	}
+
+	return props
+}()
+
+// looseName returns the name as Oniguruma compares property names: case,
+// spaces, hyphens and underscores insensitive.
+func looseName(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name))
+}
+
+func unicodeProperty(name string) (string, bool) {
+	if _, ok := unicode.Categories[name]; ok {
+		return `\p{` + name + `}`, true
+	}
+	class, ok := unicodeProperties[looseName(name)]
+
+	return class, ok
+}
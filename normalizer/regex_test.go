@@ -0,0 +1,109 @@
+package normalizer_test
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/season-studio/tokenizer/normalizer"
+)
+
+func TestTranslateRegex(t *testing.T) {
+	tests := []struct {
+		expr string
+		s    string
+		want []string
+	}{
+		{`\d+`, "a12٣4b", []string{"12٣4"}},
+		{`\w+`, "héllo wörld_1", []string{"héllo", "wörld_1"}},
+		{`\s+`, "a　 b c", []string{"　 ", " "}},
+		{`\p{Letter}+`, "abc123Δ", []string{"abc", "Δ"}},
+		{`\p{greek}+|\p{^L}+`, "Δx12", []string{"Δ", "12"}},
+		{`[\p{Lu}\h]+`, "ABx0fG", []string{"AB", "0fG"}},
+		{`[[:digit:]]+`, "ab12", []string{"12"}},
+		{`é+`, "aéé", []string{"éé"}},
+		{`(?<word>\p{L}+)`, "ab cd", []string{"ab", "cd"}},
+	}
+
+	for _, tt := range tests {
+		expr, err := normalizer.TranslateRegex(tt.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		got := re.FindAllString(tt.s, -1)
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%q: want %q, got %q", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestCompileRegex_Unsupported(t *testing.T) {
+	for _, expr := range []string{`(?<=a)b`, `a(?=b)`, `(?>ab)`, `a++`, `\p{L}*+`, `(a)\1`, `[a-z&&[^c]]`, `[^\S\n]`, `\p{Foo}`} {
+		_, err := normalizer.CompileRegex(expr)
+		if !errors.Is(err, normalizer.ErrUnsupportedRegex) {
+			t.Errorf("%q: want ErrUnsupportedRegex, got %v", expr, err)
+		}
+	}
+
+	if _, err := normalizer.CompileRegex(`a(`); err == nil || errors.Is(err, normalizer.ErrUnsupportedRegex) {
+		t.Errorf("want invalid regex error, got %v", err)
+	}
+}
+
+type goEngine struct {
+	exprs []string
+}
+
+func (e *goEngine) Compile(expr string) (normalizer.Regexp, error) {
+	e.exprs = append(e.exprs, expr)
+	return regexp.Compile(`b`)
+}
+
+func TestSetRegexEngine(t *testing.T) {
+	engine := new(goEngine)
+	normalizer.SetRegexEngine(engine)
+	defer normalizer.SetRegexEngine(nil)
+
+	p, err := normalizer.CompileRegexpPattern(`(?<=a)b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []normalizer.OffsetsMatch{
+		{Offsets: []int{0, 1}, Match: false},
+		{Offsets: []int{1, 2}, Match: true},
+	}
+	if got := p.FindMatches("ab"); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// Translated patterns do not use the engine.
+	if _, err := normalizer.CompileRegex(`\s+(?!\S)|\p{L}+`); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`(?<=a)b`}; !reflect.DeepEqual(want, engine.exprs) {
+		t.Errorf("want engine patterns %q, got %q", want, engine.exprs)
+	}
+}
+
+func TestCompileRegex_TrailingSpaceLookahead(t *testing.T) {
+	re, err := normalizer.CompileRegex(`\S+|\s+(?!\S)|\s+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := "a   b  "
+	var got []string
+	for _, m := range re.FindAllStringIndex(s, -1) {
+		got = append(got, s[m[0]:m[1]])
+	}
+	if want := []string{"a", "  ", " ", "b", "  "}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -57,3 +57,58 @@ func TestReplace_Decode(t *testing.T) {
 		t.Errorf("want %v, got %v\n", want, got)
 	}
 }
+
+func TestReplace_Offsets(t *testing.T) {
+	tests := []struct {
+		name     string
+		r        *Replace
+		original string
+		want     string
+		aligned  map[[2]int][2]int // normalized range -> original range
+	}{
+		{
+			name:     "llama",
+			r:        NewReplace(String, " ", "▁"),
+			original: "Hey friend",
+			want:     "Hey▁friend",
+			aligned:  map[[2]int][2]int{{3, 6}: {3, 4}, {6, 12}: {4, 10}, {0, 6}: {0, 4}},
+		},
+		{
+			name:     "regex",
+			r:        NewReplace(Regex, `\s+`, "_"),
+			original: "a   bc  d",
+			want:     "a_bc_d",
+			// As in HuggingFace, a replacement is aligned to the last char it
+			// replaces.
+			aligned: map[[2]int][2]int{{1, 2}: {3, 4}, {2, 4}: {4, 6}, {4, 5}: {7, 8}, {5, 6}: {8, 9}},
+		},
+		{
+			name:     "removal",
+			r:        NewReplace(Regex, `[0-9]+`, ""),
+			original: "ab12cd",
+			want:     "abcd",
+			aligned:  map[[2]int][2]int{{0, 2}: {0, 2}, {2, 4}: {4, 6}},
+		},
+	}
+
+	for _, tt := range tests {
+		n, err := tt.r.Normalize(NewNormalizedFrom(tt.original))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := n.GetNormalized(); got != tt.want {
+			t.Errorf("%v: want %q, got %q", tt.name, tt.want, got)
+			continue
+		}
+		for rng, want := range tt.aligned {
+			r := n.ConvertOffset(NewRange(rng[0], rng[1], NormalizedTarget))
+			if r == nil {
+				t.Errorf("%v: %v: no original range", tt.name, rng)
+				continue
+			}
+			if got := [2]int{r.start, r.end}; got != want {
+				t.Errorf("%v: %v: want %v, got %v", tt.name, rng, want, got)
+			}
+		}
+	}
+}
@@ -1,7 +1,5 @@
 package normalizer
 
-import ()
-
 // Sequence wraps a slice of normalizers to normalize
 // string in sequence.
 type Sequence struct {
@@ -25,3 +23,19 @@ func (s *Sequence) Normalize(normalized *NormalizedString) (*NormalizedString, e
 
 	return input, nil
 }
+
+// NormalizeString implements StringNormalizer if all the normalizers of the
+// sequence do.
+func (s *Sequence) NormalizeString(input string) (string, bool) {
+	for _, n := range s.Normalizers {
+		sn, ok := n.(StringNormalizer)
+		if !ok {
+			return "", false
+		}
+		if input, ok = sn.NormalizeString(input); !ok {
+			return "", false
+		}
+	}
+
+	return input, true
+}
@@ -1,5 +1,7 @@
 package normalizer
 
+// Strip creates a normalizer that strips the white spaces at the left and/or
+// the right of the normalized string, keeping track of the offsets.
 type Strip struct {
 	stripLeft  bool
 	stripRight bool
@@ -0,0 +1,40 @@
+package normalizer
+
+import (
+	"testing"
+)
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		left, right bool
+		want        string
+		start, end  int // original offsets of the normalized string
+	}{
+		{true, true, "Hi you", 2, 8},
+		{true, false, "Hi you \t", 2, 10},
+		{false, true, "  Hi you", 0, 8},
+		{false, false, "  Hi you \t", 0, 10},
+	}
+
+	for _, tt := range tests {
+		out, err := NewStrip(tt.left, tt.right).Normalize(NewNormalizedFrom("  Hi you \t"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.GetNormalized(); got != tt.want {
+			t.Errorf("left %v right %v: want %q, got %q", tt.left, tt.right, tt.want, got)
+		}
+		o := out.ConvertOffset(NewRange(0, out.Len(), NormalizedTarget))
+		if o.Start() != tt.start || o.End() != tt.end {
+			t.Errorf("left %v right %v: want original offsets [%d %d], got [%d %d]", tt.left, tt.right, tt.start, tt.end, o.Start(), o.End())
+		}
+	}
+
+	out, err := NewStrip(true, true).Normalize(NewNormalizedFrom(" \n "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.IsEmpty() {
+		t.Errorf("want empty string, got %q", out.GetNormalized())
+	}
+}
@@ -32,6 +32,11 @@ func (un *UnicodeNormalizer) Normalize(n *NormalizedString) (*NormalizedString,
 	return n, nil
 }
 
+// NormalizeString implements StringNormalizer.
+func (un *UnicodeNormalizer) NormalizeString(s string) (string, bool) {
+	return un.Form.String(s), true
+}
+
 type NFC struct{}
 
 func NewNFC() *NFC {
@@ -42,6 +47,11 @@ func (n *NFC) Normalize(norm *NormalizedString) (*NormalizedString, error) {
 	return norm.NFC(), nil
 }
 
+// NormalizeString implements StringNormalizer.
+func (n *NFC) NormalizeString(s string) (string, bool) {
+	return norm.NFC.String(s), true
+}
+
 type NFKC struct{}
 
 func NewNFKC() *NFKC {
@@ -52,6 +62,11 @@ func (n *NFKC) Normalize(norm *NormalizedString) (*NormalizedString, error) {
 	return norm.NFKC(), nil
 }
 
+// NormalizeString implements StringNormalizer.
+func (n *NFKC) NormalizeString(s string) (string, bool) {
+	return norm.NFKC.String(s), true
+}
+
 type NFD struct{}
 
 func NewNFD() *NFD {
@@ -62,6 +77,11 @@ func (n *NFD) Normalize(norm *NormalizedString) (*NormalizedString, error) {
 	return norm.NFD(), nil
 }
 
+// NormalizeString implements StringNormalizer.
+func (n *NFD) NormalizeString(s string) (string, bool) {
+	return norm.NFD.String(s), true
+}
+
 type NFKD struct{}
 
 func NewNFKD() *NFKD {
@@ -71,3 +91,8 @@ func NewNFKD() *NFKD {
 func (n *NFKD) Normalize(norm *NormalizedString) (*NormalizedString, error) {
 	return norm.NFKD(), nil
 }
+
+// NormalizeString implements StringNormalizer.
+func (n *NFKD) NormalizeString(s string) (string, bool) {
+	return norm.NFKD.String(s), true
+}
@@ -0,0 +1,37 @@
+package tokenizer
+
+// FlattenOverflowing flattens encodings and their overflowing windows (see
+// `TruncationParams.Stride`) into one list of windows: each encoding is
+// followed by its overflowing encodings, whose `Overflowing` is emptied.
+// overflowToSample gives the index in encodings of the sample of each window,
+// as the `overflow_to_sample_mapping` of the `transformers` tokenizers, i.e. to
+// map the answer spans of long-document QA or the chunks of retrieval back to
+// their documents.
+func FlattenOverflowing(encodings []Encoding) (windows []Encoding, overflowToSample []int) {
+	for i, en := range encodings {
+		overflowing := en.Overflowing
+		en.Overflowing = []Encoding{}
+		windows = append(windows, en)
+		overflowToSample = append(overflowToSample, i)
+		for _, o := range overflowing {
+			o.Overflowing = []Encoding{}
+			windows = append(windows, o)
+			overflowToSample = append(overflowToSample, i)
+		}
+	}
+
+	return windows, overflowToSample
+}
+
+// EncodeBatchOverflowing encodes inputs as `EncodeBatch` does and returns
+// their windows flattened by `FlattenOverflowing`. With a truncation stride,
+// the windows of a long input overlap by `stride` tokens.
+func (t *Tokenizer) EncodeBatchOverflowing(inputs []EncodeInput, addSpecialTokens bool, opts ...EncodeOpt) (windows []Encoding, overflowToSample []int, err error) {
+	encodings, err := t.EncodeBatch(inputs, addSpecialTokens, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	windows, overflowToSample = FlattenOverflowing(encodings)
+
+	return windows, overflowToSample, nil
+}
@@ -0,0 +1,87 @@
+package tokenizer_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+func TestEncodeBatchOverflowing(t *testing.T) {
+	tk, err := pretrained.FromFile(filepath.Join(compatDir, "bert-wordpiece", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 6, Stride: 1, Strategy: tokenizer.LongestFirst})
+
+	inputs := []tokenizer.EncodeInput{
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("unaffable running")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("Hello")),
+		tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("Hello, World!")),
+	}
+	windows, overflowToSample, err := tk.EncodeBatchOverflowing(inputs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != len(overflowToSample) {
+		t.Fatalf("want one sample per window, got %d windows and %d samples", len(windows), len(overflowToSample))
+	}
+
+	var samples []int
+	for i, w := range windows {
+		if len(w.Overflowing) != 0 {
+			t.Errorf("window %d: want no overflowing, got %d", i, len(w.Overflowing))
+		}
+		if w.Len() > 6 {
+			t.Errorf("window %d: want at most 6 tokens, got %d", i, w.Len())
+		}
+		if toks := w.Tokens; toks[0] != "[CLS]" || toks[len(toks)-1] != "[SEP]" {
+			t.Errorf("window %d: want [CLS] ... [SEP], got %v", i, toks)
+		}
+		if i > 0 && overflowToSample[i] == overflowToSample[i-1] {
+			// The last content token of the previous window opens this one.
+			prev := windows[i-1].Ids
+			if got, want := w.Ids[1], prev[len(prev)-2]; got != want {
+				t.Errorf("window %d: want an overlap of 1 token %d, got %d", i, want, got)
+			}
+		}
+		if len(samples) == 0 || samples[len(samples)-1] != overflowToSample[i] {
+			samples = append(samples, overflowToSample[i])
+		}
+	}
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(samples, want) {
+		t.Errorf("want samples %v in order, got %v", want, samples)
+	}
+	if len(windows) <= len(inputs) {
+		t.Errorf("want overflowing windows, got %d windows for %d inputs", len(windows), len(inputs))
+	}
+
+	// The windows of a sample are its encoding followed by its overflowing.
+	en, err := tk.EncodeSingle("Hello, World!", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []tokenizer.Encoding
+	for i, w := range windows {
+		if overflowToSample[i] == 2 {
+			got = append(got, w)
+		}
+	}
+	if want := 1 + len(en.Overflowing); len(got) != want {
+		t.Fatalf("want %d windows of sample 2, got %d", want, len(got))
+	}
+	for i, o := range en.Overflowing {
+		if !reflect.DeepEqual(got[i+1].Ids, o.Ids) || !reflect.DeepEqual(got[i+1].Offsets, o.Offsets) {
+			t.Errorf("window %d of sample 2: want %v, got %v", i+1, o.Tokens, got[i+1].Tokens)
+		}
+	}
+}
+
+func TestFlattenOverflowing(t *testing.T) {
+	windows, overflowToSample := tokenizer.FlattenOverflowing(nil)
+	if len(windows) != 0 || len(overflowToSample) != 0 {
+		t.Errorf("want no windows, got %v %v", windows, overflowToSample)
+	}
+}
@@ -4,7 +4,10 @@ package tokenizer
 
 import (
 	"fmt"
-	"log"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
 	// "reflect"
 
 	"github.com/season-studio/tokenizer/normalizer"
@@ -20,8 +23,9 @@ type PreToken struct {
 type OffsetType int
 
 const (
-	Byte OffsetType = iota
-	Char
+	Byte  OffsetType = iota
+	Char             // unicode code points (runes)
+	UTF16            // UTF-16 code units, as used by JavaScript strings
 )
 
 // Split contains the underlying `NormalizedString` as well as
@@ -105,6 +109,8 @@ func (pt *PreTokenizedString) Normalize(nFn func(*normalizer.NormalizedString) *
 			newSplit := split
 			newSplit.normalized = nFn(split.normalized)
 			nSplits = append(nSplits, newSplit)
+		} else {
+			nSplits = append(nSplits, split)
 		}
 	}
 
@@ -133,6 +139,77 @@ func (pt *PreTokenizedString) Tokenize(tokFn func(*normalizer.NormalizedString)
 	return pt, nil
 }
 
+// minShardSplits is the minimum number of splits handled by a single worker in
+// `TokenizeConcurrently`; below that, goroutines cost more than they save.
+const minShardSplits = 64
+
+// TokenizeConcurrently is the same as `Tokenize` but shards the splits into
+// contiguous ranges tokenized by up to `workers` goroutines. Results are kept in
+// split order so the output is identical to `Tokenize`. tokFn must be safe for
+// concurrent use.
+func (pt *PreTokenizedString) TokenizeConcurrently(tokFn func(*normalizer.NormalizedString) ([]Token, error), workers int) (*PreTokenizedString, error) {
+	n := len(pt.splits)
+	if maxWorkers := n / minShardSplits; workers > maxWorkers {
+		workers = maxWorkers
+	}
+	if workers <= 1 {
+		return pt.Tokenize(tokFn)
+	}
+
+	nSplits := make([]Split, n)
+	copy(nSplits, pt.splits)
+
+	shardSize := (n + workers - 1) / workers
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * shardSize
+		end := start + shardSize
+		if end > n {
+			end = n
+		}
+
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				if nSplits[i].tokens != nil {
+					continue
+				}
+				toks, err := tokFn(nSplits[i].normalized)
+				if err != nil {
+					errs[w] = err
+					return
+				}
+				nSplits[i].tokens = toks
+			}
+		}(w, start, end)
+	}
+	wg.Wait()
+
+	// Report the error of the first failing split as the serial path would.
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pt.splits = nSplits
+	return pt, nil
+}
+
+// NormalizationChanged reports whether the normalized form of any split differs
+// from its original.
+func (pt *PreTokenizedString) NormalizationChanged() bool {
+	for _, split := range pt.splits {
+		if split.normalized.GetNormalized() != split.normalized.GetOriginal() {
+			return true
+		}
+	}
+
+	return false
+}
+
 // IntoEncoding transforms the current `PreTokenizedString` into an `Encoding`.
 //
 // If a `wordIdx` is provided, any word in the generated `Encoding`
@@ -153,22 +230,11 @@ func (pt *PreTokenizedString) IntoEncoding(typeId int, wordIdx int, offsetType O
 		}
 	}
 
-	charMap := make(map[int]int, 0) // map[byteIdx]runeIdx
-
-	switch {
-	case offsetType == Char:
-		currRuneIdx := 0
-		for byteIdx, r := range pt.original {
-			n := 0
-			for i := 0; i < len([]byte(string(r))); i++ {
-				charMap[byteIdx+n] = currRuneIdx
-				n += 1
-			}
-			currRuneIdx += 1
-		}
-	case offsetType == Byte:
-		charMap = make(map[int]int, 0)
-
+	var converter OffsetConverter
+	switch offsetType {
+	case Byte:
+	case Char, UTF16:
+		converter = NewBytesToUnitsOffsetConverter(pt.original, offsetType)
 	default:
 		err := fmt.Errorf("Invalid offsetType (%v).\n", offsetType)
 		return nil, err
@@ -187,7 +253,6 @@ func (pt *PreTokenizedString) IntoEncoding(typeId int, wordIdx int, offsetType O
 	for idx, split := range pt.splits {
 		normalized := split.normalized
 		offsets := normalized.OffsetsOriginal()
-		charMapSplit := charMap
 		var convertedOffsets []int
 		for _, tok := range split.tokens {
 			o := normalized.ConvertOffset(normalizer.NewRange(tok.Offsets[0], tok.Offsets[1], normalizer.NormalizedTarget))
@@ -197,31 +262,14 @@ func (pt *PreTokenizedString) IntoEncoding(typeId int, wordIdx int, offsetType O
 				convertedOffsets = []int{offsets[0] + o.Start(), offsets[0] + o.End()}
 			}
 
-			// Convert to char offset if relevant
-			start, ok := charMapSplit[convertedOffsets[0]]
-			if !ok {
-				start = -1
-			}
-			end, ok := charMapSplit[convertedOffsets[1]]
-			if !ok {
-				end = -1
-			}
-
-			var newConvertedOffsets []int
-			switch {
-			case start != -1 && end != -1:
-				newConvertedOffsets = []int{start, end}
-			case start != -1 && end == -1: // If we reached the end, `end` is not in the map
-				// But the one just before should be
-				last, ok := charMapSplit[convertedOffsets[1]-1]
-				if !ok {
-					log.Printf("Something wrong here. Should find from map.\n")
-					last = start + 1
+			// Convert to char or utf-16 offsets if relevant
+			newConvertedOffsets := convertedOffsets
+			if converter != nil {
+				var err error
+				newConvertedOffsets, err = converter.Convert(convertedOffsets)
+				if err != nil {
+					return nil, err
 				}
-				newConvertedOffsets = []int{start, last}
-
-			default:
-				newConvertedOffsets = convertedOffsets
 			}
 
 			var wordIndex int = wordIdx
@@ -334,6 +382,144 @@ func NewBytesToCharOffsetConverter(sequence string) *BytesToCharOffsetConverter
 	return &BytesToCharOffsetConverter{b2c}
 }
 
+// BytesToUnitsOffsetConverter converts byte-indexed offsets to offsets counted in
+// the units of an OffsetType.
+//
+// An offsets start inside a multi-byte character is moved to the start of that
+// character and an end inside it to its end, so that tokens covering part of a
+// character (i.e. byte-level BPE) still map to the whole character.
+type BytesToUnitsOffsetConverter struct {
+	floor []int // byte index -> units before the character containing it
+	ceil  []int // byte index -> units up to the end of the character just before it
+}
+
+// NewBytesToUnitsOffsetConverter creates a converter for the given target unit.
+func NewBytesToUnitsOffsetConverter(sequence string, target OffsetType) *BytesToUnitsOffsetConverter {
+	floor := make([]int, len(sequence)+1)
+	ceil := make([]int, len(sequence)+1)
+
+	units := 0
+	for i := 0; i < len(sequence); {
+		r, size := utf8.DecodeRuneInString(sequence[i:])
+		n := 1 // Char, also invalid utf-8 bytes count as one unit
+		switch target {
+		case Byte:
+			n = size
+		case UTF16:
+			if l := utf16.RuneLen(r); l > 0 {
+				n = l
+			}
+		}
+
+		for j := 0; j < size; j++ {
+			floor[i+j] = units
+			ceil[i+j] = units + n
+		}
+		ceil[i] = units
+		units += n
+		i += size
+	}
+	floor[len(sequence)] = units
+	ceil[len(sequence)] = units
+
+	return &BytesToUnitsOffsetConverter{floor, ceil}
+}
+
+// Convert converts byte-indexed offsets to the target unit offsets.
+func (c *BytesToUnitsOffsetConverter) Convert(offsets []int) ([]int, error) {
+	start, end := offsets[0], offsets[1]
+	if start < 0 || end < start || end >= len(c.floor) {
+		err := fmt.Errorf("Invalid offsets %v for a sequence of %v bytes\n", offsets, len(c.floor)-1)
+		return nil, err
+	}
+
+	if start == end {
+		return []int{c.floor[start], c.floor[start]}, nil
+	}
+
+	return []int{c.floor[start], c.ceil[end]}, nil
+}
+
+// UnitsToBytesOffsetConverter converts offsets counted in the units of an
+// OffsetType back to byte offsets. It is the inverse of
+// BytesToUnitsOffsetConverter: an offsets start inside a character (i.e. inside
+// a UTF-16 surrogate pair) is moved to the start of that character and an end
+// inside it to its end.
+type UnitsToBytesOffsetConverter struct {
+	floor []int // unit index -> start byte of the character containing it
+	ceil  []int // unit index -> end byte of the character just before it
+}
+
+// NewUnitsToBytesOffsetConverter creates a converter from the given source unit.
+func NewUnitsToBytesOffsetConverter(sequence string, source OffsetType) *UnitsToBytesOffsetConverter {
+	var floor, ceil []int
+	for i := 0; i < len(sequence); {
+		r, size := utf8.DecodeRuneInString(sequence[i:])
+		n := 1 // Char, also invalid utf-8 bytes count as one unit
+		switch source {
+		case Byte:
+			n = size
+		case UTF16:
+			if l := utf16.RuneLen(r); l > 0 {
+				n = l
+			}
+		}
+
+		ceil = append(ceil, i)
+		floor = append(floor, i)
+		for j := 1; j < n; j++ {
+			floor = append(floor, i)
+			ceil = append(ceil, i+size)
+		}
+		i += size
+	}
+	floor = append(floor, len(sequence))
+	ceil = append(ceil, len(sequence))
+
+	return &UnitsToBytesOffsetConverter{floor, ceil}
+}
+
+// Convert converts offsets in the source unit to byte offsets.
+func (c *UnitsToBytesOffsetConverter) Convert(offsets []int) ([]int, error) {
+	start, end := offsets[0], offsets[1]
+	if start < 0 || end < start || end >= len(c.floor) {
+		err := fmt.Errorf("Invalid offsets %v for a sequence of %v units\n", offsets, len(c.floor)-1)
+		return nil, err
+	}
+
+	if start == end {
+		return []int{c.floor[start], c.floor[start]}, nil
+	}
+
+	return []int{c.floor[start], c.ceil[end]}, nil
+}
+
+// chainedOffsetConverter applies converters one after the other.
+type chainedOffsetConverter []OffsetConverter
+
+func (c chainedOffsetConverter) Convert(offsets []int) ([]int, error) {
+	var err error
+	for _, converter := range c {
+		if offsets, err = converter.Convert(offsets); err != nil {
+			return nil, err
+		}
+	}
+	return offsets, nil
+}
+
+// NewOffsetConverter creates a converter of offsets of the given sequence from
+// the source unit to the target unit.
+func NewOffsetConverter(sequence string, source, target OffsetType) OffsetConverter {
+	var c chainedOffsetConverter
+	if source != Byte {
+		c = append(c, NewUnitsToBytesOffsetConverter(sequence, source))
+	}
+	if target != Byte {
+		c = append(c, NewBytesToUnitsOffsetConverter(sequence, target))
+	}
+	return c
+}
+
 // Convert converts byte-indexed offsets to character-index offsets.
 func (c *BytesToCharOffsetConverter) Convert(offsets []int) ([]int, error) {
 	start, ok := c.b2c[offsets[0]]
@@ -1,23 +1,23 @@
 package pretokenizer
 
 import (
-	"regexp"
 	"strings"
 
 	"github.com/season-studio/tokenizer"
 	"github.com/season-studio/tokenizer/normalizer"
 )
 
-// Regular epxression to split string to `word` token
-// including prefix whitespace. Contractions and punctuation
-// will be split as well.
-// Ref.https://regex101.com/r/pf5XJv
-// const splitRegStr = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+`
-// const splitRegStr = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
-// TODO: this RE does not cover the case with trailing whitespace!!!
-const splitRegStr = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+`
-
-var splitRE = regexp.MustCompile(splitRegStr)
+// splitPattern splits a string into `word` tokens including their prefix
+// whitespace with the GPT-2 regex. Contractions and punctuation are split as
+// well, and a whitespace run before a word gives its last space to the word
+// (the `\s+(?!\S)` lookahead emulated by TiktokenPattern).
+var splitPattern = func() *TiktokenPattern {
+	p, err := NewTiktokenPattern(R50kPattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}()
 
 var BytesChar map[uint8]string = GenerateBytesChar()
 
@@ -143,14 +143,20 @@ type ByteLevel struct {
 	// Whether the post processing step should trim offsets
 	// to avoid including whitespaces.
 	TrimOffsets bool
+
+	// Whether the pre-tokenization step should split the input with the
+	// GPT-2 regex. When false, the input is only transformed to byte-level,
+	// i.e. after a `Split` pre-tokenizer with a custom pattern.
+	UseRegex bool
 }
 
-// NewByteLevel returns a default ByteLevel with both
-// AddPrefixSpace and TrimOffsets set true
+// NewByteLevel returns a default ByteLevel with
+// AddPrefixSpace, TrimOffsets and UseRegex set true
 func NewByteLevel() *ByteLevel {
 	return &ByteLevel{
 		AddPrefixSpace: true,
 		TrimOffsets:    true,
+		UseRegex:       true,
 	}
 }
 
@@ -174,6 +180,11 @@ func (bl *ByteLevel) SetTrimOffsets(v bool) {
 	bl.TrimOffsets = v
 }
 
+// SetUseRegex set `UseRegex` property
+func (bl *ByteLevel) SetUseRegex(v bool) {
+	bl.UseRegex = v
+}
+
 // Implement `PreTokenizer` methods for `ByteLevel`:
 // =================================================
 
@@ -187,7 +198,10 @@ func (bl *ByteLevel) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*t
 			newNormalized = normalized.Prepend(" ")
 		}
 
-		splitPattern := normalizer.NewRegexpPattern(splitRegStr)
+		if !bl.UseRegex {
+			return []tokenizer.SplitIdx{{Normalized: newNormalized, Tokens: nil}}
+		}
+
 		splits := newNormalized.Split(splitPattern, normalizer.IsolatedBehavior)
 
 		var splitIdx []tokenizer.SplitIdx
@@ -199,26 +213,66 @@ func (bl *ByteLevel) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*t
 		return splitIdx
 	})
 
-	finalPretok := pretok.Normalize(func(normalized *normalizer.NormalizedString) *normalizer.NormalizedString {
-		s := normalized.GetNormalized()
-		var changeMap []normalizer.ChangeMap
-		for _, r := range s {
-			bytes := []byte(string(r))
-			for i, b := range bytes {
-				change := 0
-				if i > 0 {
-					change = 1
-				}
-
-				char := BytesChar[b]
-				changeMap = append(changeMap, normalizer.ChangeMap{RuneVal: char, Changes: change})
-			}
+	return pretok.Normalize(toByteLevel), nil
+}
+
+var _ tokenizer.StringPreTokenizer = new(ByteLevel)
+
+// PreTokenizeString implements tokenizer.StringPreTokenizer.
+func (bl *ByteLevel) PreTokenizeString(dst []string, s string) ([]string, bool) {
+	if s == "" {
+		return dst, true
+	}
+	if bl.AddPrefixSpace && !strings.HasPrefix(s, " ") {
+		s = " " + s
+	}
+
+	n := len(dst)
+	if bl.UseRegex {
+		dst = normalizer.AppendSplitString(dst, s, splitPattern, normalizer.IsolatedBehavior)
+	} else {
+		dst = append(dst, s)
+	}
+
+	// The words are mapped into a single string, then sliced.
+	var b strings.Builder
+	b.Grow(2 * len(s))
+	for _, word := range dst[n:] {
+		for j := 0; j < len(word); j++ {
+			b.WriteString(BytesChar[word[j]])
+		}
+	}
+	mapped := b.String()
+	for i, word := range dst[n:] {
+		size := 0
+		for j := 0; j < len(word); j++ {
+			size += len(BytesChar[word[j]])
 		}
+		dst[n+i], mapped = mapped[:size], mapped[size:]
+	}
 
-		return normalized.Transform(changeMap, 0)
-	})
+	return dst, true
+}
 
-	return finalPretok, nil
+// toByteLevel transforms all the unicode characters of normalized into their
+// byte-level counterpart.
+func toByteLevel(normalized *normalizer.NormalizedString) *normalizer.NormalizedString {
+	s := normalized.GetNormalized()
+	var changeMap []normalizer.ChangeMap
+	for _, r := range s {
+		bytes := []byte(string(r))
+		for i, b := range bytes {
+			change := 0
+			if i > 0 {
+				change = 1
+			}
+
+			char := BytesChar[b]
+			changeMap = append(changeMap, normalizer.ChangeMap{RuneVal: char, Changes: change})
+		}
+	}
+
+	return normalized.Transform(changeMap, 0)
 }
 
 // Implement Decoder for `ByteLevel`:
@@ -229,35 +283,35 @@ var _ tokenizer.Decoder = new(ByteLevel)
 // Decode converts any byte-level characters to their unicode couterpart
 // before merging everything back into a single string
 func (bl *ByteLevel) Decode(tokens []string) string {
-	s := strings.Join(tokens, "")
-	chars := strings.Split(s, "")
+	return strings.Join(bl.DecodeChain(tokens), "")
+}
 
+// DecodeChain merges the bytes of all the tokens into a single string, as a
+// character may span several tokens. Tokens holding characters outside of the
+// byte-level alphabet (i.e. added tokens) are kept as is and invalid UTF-8
+// sequences are replaced by U+FFFD.
+func (bl *ByteLevel) DecodeChain(tokens []string) []string {
 	var bytes []byte
-
-	for _, c := range chars {
-		b := CharBytes[c]
-
-		bytes = append(bytes, b)
+	for _, token := range tokens {
+		bytes = append(bytes, tokenBytes(token)...)
 	}
 
-	return string(bytes)
+	return []string{strings.ToValidUTF8(string(bytes), "\uFFFD")}
 }
 
-func (bl *ByteLevel) DecodeChain(tokens []string) []string {
-	out := make([]string, len(tokens))
-	for i, s := range tokens {
-		chars := strings.Split(s, "")
-		var bytes []byte
-		for _, c := range chars {
-			b := CharBytes[c]
-
-			bytes = append(bytes, b)
+// tokenBytes returns the bytes a byte-level token stands for, or the token
+// itself if it is not made of byte-level characters only.
+func tokenBytes(token string) []byte {
+	bytes := make([]byte, 0, len(token))
+	for _, r := range token {
+		b, ok := CharBytes[string(r)]
+		if !ok {
+			return []byte(token)
 		}
-
-		out[i] = string(bytes)
+		bytes = append(bytes, b)
 	}
 
-	return out
+	return bytes
 }
 
 // Implement PostProcessor for ByteLevel
@@ -269,6 +323,23 @@ func (bl *ByteLevel) AddedToken(isPair bool) int {
 
 func (bl *ByteLevel) Process(encoding, pairEncoding *tokenizer.Encoding, addSpecialTokens bool) *tokenizer.Encoding {
 	encodings := tokenizer.PrepareEncodings(encoding, pairEncoding)
+	newEncodings := bl.ProcessEncodings(encodings, addSpecialTokens)
+
+	for i, enc := range newEncodings {
+		enc.SetSequenceIds(i)
+	}
+
+	if pairEncoding != nil {
+		return tokenizer.MergeEncodings(newEncodings, false)
+	} else {
+		return &newEncodings[0]
+	}
+}
+
+// ProcessEncodings trims the offsets of the given encodings (and their
+// overflowing encodings) if TrimOffsets is set, without merging them. Their
+// sequence ids are left untouched.
+func (bl *ByteLevel) ProcessEncodings(encodings []tokenizer.Encoding, addSpecialTokens bool) []tokenizer.Encoding {
 	var newEncodings []tokenizer.Encoding
 	if bl.TrimOffsets {
 		for _, enc := range encodings {
@@ -286,15 +357,7 @@ func (bl *ByteLevel) Process(encoding, pairEncoding *tokenizer.Encoding, addSpec
 		newEncodings = encodings
 	}
 
-	for i, enc := range newEncodings {
-		enc.SetSequenceIds(i)
-	}
-
-	if pairEncoding != nil {
-		return tokenizer.MergeEncodings(newEncodings, false)
-	} else {
-		return &newEncodings[0]
-	}
+	return newEncodings
 }
 
 func processOffsets(encoding *tokenizer.Encoding, addPrefixSpace bool) *tokenizer.Encoding {
@@ -337,7 +400,10 @@ func processOffsets(encoding *tokenizer.Encoding, addPrefixSpace bool) *tokenize
 		ld := m.LeadingSpaces
 		offset0 = offsets[0]
 		if m.LeadingSpaces > 0 {
-			if i == 0 && addPrefixSpace && m.LeadingSpaces == 1 {
+			// Pre-tokenized input may have tokens starting at 0 that are not
+			// the first one.
+			isFirst := i == 0 || offsets[0] == 0
+			if isFirst && addPrefixSpace && m.LeadingSpaces == 1 {
 				// If we are processing the first pair of offsets, with `addPrefixSpace`,
 				// then we shouldn't remove anything we added. If there are more than one
 				// leading spaces though, it means we didn't add them, and they should be
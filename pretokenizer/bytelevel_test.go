@@ -328,3 +328,49 @@ func TestProcessorTrimsOffsets(t *testing.T) {
 		t.Errorf("Got: %#v\n", pairGot)
 	}
 }
+
+func TestHandlingOfSpacesBeforeNewLines(t *testing.T) {
+	bytelevel := pretokenizer.NewByteLevel()
+	bytelevel.SetAddPrefixSpace(false)
+
+	pretok, err := bytelevel.PreTokenize(tokenizer.NewPreTokenizedString("Hello \nthere  \n dear "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []charidx
+	for _, preTok := range pretok.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+		got = append(got, charidx{s: preTok.Value, o: preTok.Offsets})
+	}
+
+	// As with the `\s+(?!\S)` lookahead of the GPT-2 regex, a whitespace
+	// run gives its last whitespace to the following word.
+	want := []charidx{
+		{"Hello", []int{0, 5}},
+		{"Ġ", []int{5, 6}},
+		{"Ċ", []int{6, 7}},
+		{"there", []int{7, 12}},
+		{"ĠĠĊ", []int{12, 15}},
+		{"Ġdear", []int{15, 20}},
+		{"Ġ", []int{20, 21}},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Want: %v\n", want)
+		t.Errorf("Got: %v\n", got)
+	}
+}
+
+func TestProcessorKeepsPrefixSpaceOfWordsAtStart(t *testing.T) {
+	// Pre-tokenized words all start at 0.
+	tokens := []string{"ĠHello", "Ġworld", "ĠĠ"}
+	offsets := [][]int{{0, 5}, {0, 5}, {0, 2}}
+	wantOffsets := [][]int{{0, 5}, {0, 5}, {2, 2}}
+
+	bytelevel := pretokenizer.NewByteLevel()
+	got := bytelevel.Process(tokenizer.NewEncoding(nil, nil, tokens, offsets, nil, nil, nil), nil, false)
+	if !reflect.DeepEqual(wantOffsets, got.Offsets) {
+		t.Errorf("Want: %v\n", wantOffsets)
+		t.Errorf("Got: %v\n", got.Offsets)
+	}
+}
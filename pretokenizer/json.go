@@ -0,0 +1,146 @@
+package pretokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/normalizer"
+	"github.com/season-studio/tokenizer/util"
+)
+
+// This file implements json.Marshaler for all pre-tokenizers so that they are
+// serialized as in a HuggingFace `tokenizer.json` file.
+
+type typeOnly struct {
+	Type string `json:"type"`
+}
+
+var (
+	_ json.Marshaler = new(BertPreTokenizer)
+	_ json.Marshaler = new(ByteLevel)
+	_ json.Marshaler = new(CharDelimiterSplit)
+	_ json.Marshaler = new(Digits)
+	_ json.Marshaler = new(Metaspace)
+	_ json.Marshaler = new(Punctuation)
+	_ json.Marshaler = new(Sequence)
+	_ json.Marshaler = new(Split)
+	_ json.Marshaler = new(Tiktoken)
+	_ json.Marshaler = new(TiktokenPattern)
+	_ json.Marshaler = new(UnicodeScript)
+	_ json.Marshaler = new(Whitespace)
+	_ json.Marshaler = new(WhitespaceSplit)
+)
+
+// MarshalJSON implements json.Marshaler.
+func (bt *BertPreTokenizer) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(typeOnly{"BertPreTokenizer"})
+}
+
+// MarshalJSON implements json.Marshaler. ByteLevel is a pre-tokenizer, a
+// decoder and a post-processor and has the same serialization for all.
+func (bl *ByteLevel) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type           string `json:"type"`
+		AddPrefixSpace bool   `json:"add_prefix_space"`
+		TrimOffsets    bool   `json:"trim_offsets"`
+		UseRegex       bool   `json:"use_regex"`
+	}{"ByteLevel", bl.AddPrefixSpace, bl.TrimOffsets, bl.UseRegex})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d *CharDelimiterSplit) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type      string `json:"type"`
+		Delimiter string `json:"delimiter"`
+	}{"CharDelimiterSplit", string(d.Delimiter)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d *Digits) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type             string `json:"type"`
+		IndividualDigits bool   `json:"individual_digits"`
+	}{"Digits", d.IndividualDigits})
+}
+
+// MarshalJSON implements json.Marshaler. Metaspace is both a pre-tokenizer
+// and a decoder and has the same serialization for both.
+func (m *Metaspace) MarshalJSON() ([]byte, error) {
+	if m.PrependScheme < Never || m.PrependScheme > Always {
+		return nil, fmt.Errorf("Metaspace: unsupported prepend scheme %d", m.PrependScheme)
+	}
+
+	return util.MarshalJSON(struct {
+		Type          string `json:"type"`
+		Replacement   string `json:"replacement"`
+		PrependScheme string `json:"prepend_scheme"`
+		Split         bool   `json:"split"`
+	}{"Metaspace", m.Replacement, m.PrependScheme.String(), m.Split})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *Punctuation) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type     string                            `json:"type"`
+		Behavior normalizer.SplitDelimiterBehavior `json:"behavior"`
+	}{"Punctuation", p.Behavior})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *Sequence) MarshalJSON() ([]byte, error) {
+	pretoks := p.pretokenizers
+	if pretoks == nil {
+		pretoks = []tokenizer.PreTokenizer{}
+	}
+
+	return util.MarshalJSON(struct {
+		Type          string                   `json:"type"`
+		PreTokenizers []tokenizer.PreTokenizer `json:"pretokenizers"`
+	}{"Sequence", pretoks})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Split) MarshalJSON() ([]byte, error) {
+	pattern, ok := s.Pattern.(json.Marshaler)
+	if !ok {
+		return nil, fmt.Errorf("Split: pattern %T cannot be serialized", s.Pattern)
+	}
+
+	return util.MarshalJSON(struct {
+		Type     string                            `json:"type"`
+		Pattern  json.Marshaler                    `json:"pattern"`
+		Behavior normalizer.SplitDelimiterBehavior `json:"behavior"`
+		Invert   bool                              `json:"invert"`
+	}{"Split", pattern, s.Behavior, s.Invert})
+}
+
+// MarshalJSON implements json.Marshaler. Tiktoken is serialized as HuggingFace
+// does for tiktoken-based tokenizers: a `Split` with the tiktoken pattern
+// followed by a `ByteLevel` not using its own regex.
+func (t *Tiktoken) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(NewSequence([]tokenizer.PreTokenizer{
+		NewSplit(t.pattern, normalizer.IsolatedBehavior, false),
+		&ByteLevel{AddPrefixSpace: false, TrimOffsets: true, UseRegex: false},
+	}))
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *TiktokenPattern) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(map[string]string{"Regex": p.pattern})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (us *UnicodeScript) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(typeOnly{"UnicodeScripts"})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (w *Whitespace) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(typeOnly{"Whitespace"})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (w *WhitespaceSplit) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(typeOnly{"WhitespaceSplit"})
+}
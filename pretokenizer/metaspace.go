@@ -1,7 +1,7 @@
 package pretokenizer
 
 import (
-	// "log"
+	"fmt"
 	"strings"
 
 	"github.com/season-studio/tokenizer"
@@ -20,41 +20,65 @@ const (
 	Always
 )
 
+// String returns the name of the scheme in `tokenizer.json` files: "never",
+// "first" or "always".
+func (s PrependScheme) String() string {
+	switch s {
+	case Never:
+		return "never"
+	case First:
+		return "first"
+	case Always:
+		return "always"
+	default:
+		return fmt.Sprintf("PrependScheme(%d)", int(s))
+	}
+}
+
+// ParsePrependScheme parses the name of a scheme, case insensitive.
+func ParsePrependScheme(name string) (PrependScheme, error) {
+	switch strings.ToLower(name) {
+	case "never":
+		return Never, nil
+	case "first":
+		return First, nil
+	case "always":
+		return Always, nil
+	default:
+		return Never, fmt.Errorf("unknown prepend scheme %q, want always, first or never", name)
+	}
+}
+
 // Metaspace constructs a Metaspace struct.
-// It replaces all the whitespaces by the provided meta character
+// It replaces all the spaces by the provided meta character
 // and then splits on this character.
 type Metaspace struct {
-	Replacement    string
-	PrependScheme  PrependScheme
-	AddPrefixSpace bool // Deprecated: use PrependScheme instead
-	StrRep         string
+	Replacement   string
+	PrependScheme PrependScheme
+	// Split sets whether the input is split into words starting with the
+	// replacement. If false, the input is a single pre-token.
+	Split  bool
+	StrRep string
 }
 
+// NewMetaspace creates a Metaspace of the legacy `add_prefix_space` option,
+// which maps to the `Always` prepend scheme if true, `Never` otherwise.
 func NewMetaspace(replacement string, addPrefixSpace bool) *Metaspace {
-	// Convert the boolean to PrependScheme for backward compatibility
 	scheme := Never
 	if addPrefixSpace {
 		scheme = Always
 	}
-	
-	return &Metaspace{
-		Replacement:    replacement,
-		PrependScheme:  scheme,
-		AddPrefixSpace: addPrefixSpace, // Keep for backward compatibility
-		StrRep:         replacement,
-	}
+
+	return NewMetaspaceWithScheme(replacement, scheme)
 }
 
 // NewMetaspaceWithScheme creates a new Metaspace with a specific prepend scheme
 func NewMetaspaceWithScheme(replacement string, scheme PrependScheme) *Metaspace {
-	// Set AddPrefixSpace for backward compatibility
-	addPrefixSpace := scheme != Never
-	
 	return &Metaspace{
-		Replacement:    replacement,
-		PrependScheme:  scheme,
-		AddPrefixSpace: addPrefixSpace,
-		StrRep:         replacement,
+		Replacement:   replacement,
+		PrependScheme: scheme,
+		Split:         true,
+		StrRep:        replacement,
 	}
 }
 
@@ -73,35 +97,28 @@ func DefaultMetaspace() *Metaspace {
 
 // PreTokenize implements PreTokenizer interface
 func (m *Metaspace) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
-	// func(int, *normalizer.NormalizedString) []SplitIdx
 	splitFn := func(idx int, normalized *normalizer.NormalizedString) []tokenizer.SplitIdx {
-		var splits []normalizer.NormalizedString
-		whitespace := normalizer.NewRegexpPattern(`\s`)
-
-		normalized = normalized.Replace(whitespace, m.StrRep)
-
-		// log.Printf("normalized: %+v\n", normalized)
+		normalized = normalized.Replace(normalizer.NewRunePattern(' '), m.StrRep)
 
 		// Apply the prepend scheme
 		switch m.PrependScheme {
 		case Always:
-			// Always prepend the replacement if it's not already there
 			if !strings.HasPrefix(normalized.GetNormalized(), m.Replacement) {
 				normalized = normalized.Prepend(m.StrRep)
 			}
 		case First:
-			// Only prepend on the first split (idx == 0)
-			if idx == 0 && !strings.HasPrefix(normalized.GetNormalized(), m.Replacement) {
+			// Only prepend to the split at the start of the original input,
+			// i.e. not to the text following an added token.
+			if normalized.Shift() == 0 && !strings.HasPrefix(normalized.GetNormalized(), m.Replacement) {
 				normalized = normalized.Prepend(m.StrRep)
 			}
-		case Never:
-			// Never prepend
 		}
 
-		replacement := normalizer.NewRegexpPattern(m.Replacement)
-		splits = normalized.Split(replacement, normalizer.MergedWithNextBehavior)
+		if !m.Split {
+			return []tokenizer.SplitIdx{{Normalized: normalized}}
+		}
 
-		// log.Printf("splits: %+v\n", splits)
+		splits := normalized.Split(normalizer.NewStringPattern(m.Replacement), normalizer.MergedWithNextBehavior)
 
 		var splitIdxs []tokenizer.SplitIdx
 		for _, s := range splits {
@@ -116,7 +133,8 @@ func (m *Metaspace) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*to
 	return pretokenized.Split(splitFn), nil
 }
 
-// DecodeChain implements Decoder interface.
+// DecodeChain implements Decoder interface. Replacements are decoded to
+// spaces, except in the first token unless the prepend scheme is `Never`.
 func (m *Metaspace) DecodeChain(tokens []string) []string {
 	var toks []string
 	for i, token := range tokens {
@@ -125,7 +143,7 @@ func (m *Metaspace) DecodeChain(tokens []string) []string {
 		var newChars []string
 		for _, c := range chars {
 			if c == m.Replacement {
-				if i == 0 && m.AddPrefixSpace {
+				if i == 0 && m.PrependScheme != Never {
 					// nil
 				} else {
 					newChars = append(newChars, " ")
@@ -2,6 +2,7 @@ package pretokenizer
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/season-studio/tokenizer"
@@ -98,3 +99,75 @@ func TestMetaspace_PreTokenize(t *testing.T) {
 		t.Errorf("Want %v got %v\n", wantOffsets, gotOffsets)
 	}
 }
+
+func TestMetaspace_PrependScheme(t *testing.T) {
+	tests := []struct {
+		scheme PrependScheme
+		split  bool
+		want   []string
+	}{
+		{Always, true, []string{"▁hey", "▁friend", "▁a\nb"}},
+		// Only the split at the start of the input gets a prefix.
+		{First, true, []string{"▁hey", "friend", "a\nb"}},
+		{Never, true, []string{"hey", "friend", "a\nb"}},
+		{Always, false, []string{"▁hey", "▁friend", "▁a\nb"}},
+	}
+
+	for _, tt := range tests {
+		pretokenized := tokenizer.NewPreTokenizedString("hey friend a\nb")
+		// Splits on spaces only, so that the words are separate splits.
+		pretokenized, err := NewCharDelimiterSplit(' ').PreTokenize(pretokenized)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m := NewMetaspaceWithScheme("▁", tt.scheme)
+		m.Split = tt.split
+		out, err := m.PreTokenize(pretokenized)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, pretok := range out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+			got = append(got, pretok.Value)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%v, split %v: want %q, got %q", tt.scheme, tt.split, tt.want, got)
+		}
+	}
+
+	pretokenized := tokenizer.NewPreTokenizedString("hey  friend")
+	m := NewMetaspaceWithScheme("▁", Always)
+	m.Split = false
+	out, err := m.PreTokenize(pretokenized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte); len(got) != 1 || got[0].Value != "▁hey▁▁friend" {
+		t.Errorf("want a single split %q, got %+v", "▁hey▁▁friend", got)
+	}
+}
+
+func TestMetaspace_DecodePrependScheme(t *testing.T) {
+	tokens := []string{"▁Hey", "▁friend"}
+	for scheme, want := range map[PrependScheme]string{
+		Always: "Hey friend",
+		First:  "Hey friend",
+		Never:  " Hey friend",
+	} {
+		if got := NewMetaspaceWithScheme("▁", scheme).Decode(tokens); got != want {
+			t.Errorf("%v: want %q, got %q", scheme, want, got)
+		}
+	}
+
+	for _, name := range []string{"always", "First", "NEVER"} {
+		scheme, err := ParsePrependScheme(name)
+		if err != nil || !strings.EqualFold(scheme.String(), name) {
+			t.Errorf("%q: got %v, %v", name, scheme, err)
+		}
+	}
+	if _, err := ParsePrependScheme("sometimes"); err == nil {
+		t.Error("want error for an unknown scheme")
+	}
+}
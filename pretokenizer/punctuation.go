@@ -59,13 +59,23 @@ impl PreTokenizer for Punctuation {
 func (p *Punctuation) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
 	pretok := pretokenized.Split(func(noop int, sub *normalizer.NormalizedString) []tokenizer.SplitIdx {
 		isPunc := normalizer.NewFnPattern(isPunctuation)
-		splits := sub.Split(isPunc, p.Behavior)
 
+		// Bidi control characters are boundaries: isolate them first so that
+		// they never end up in the offsets of a neighboring split.
+		isBidi := normalizer.NewFnPattern(normalizer.IsBidiControl)
 		var splitIdxs []tokenizer.SplitIdx
-		for _, s := range splits {
-			normalized := s
-			splitIdx := tokenizer.SplitIdx{Normalized: &normalized, Tokens: nil}
-			splitIdxs = append(splitIdxs, splitIdx)
+		for _, part := range sub.Split(isBidi, normalizer.IsolatedBehavior) {
+			part := part
+			if r := []rune(part.GetNormalized()); len(r) > 0 && normalizer.IsBidiControl(r[0]) {
+				splitIdxs = append(splitIdxs, tokenizer.SplitIdx{Normalized: &part, Tokens: nil})
+				continue
+			}
+
+			for _, s := range part.Split(isPunc, p.Behavior) {
+				normalized := s
+				splitIdx := tokenizer.SplitIdx{Normalized: &normalized, Tokens: nil}
+				splitIdxs = append(splitIdxs, splitIdx)
+			}
 		}
 
 		return splitIdxs
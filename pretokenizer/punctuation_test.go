@@ -33,3 +33,28 @@ func TestPunctuation(t *testing.T) {
 		t.Errorf("want: %#v\ngot %#v\n", want, got)
 	}
 }
+
+func TestPunctuationBidiControls(t *testing.T) {
+	pretok := DefaultPunctuation()
+
+	pretokenized := tokenizer.NewPreTokenizedString("שלום\u200F world\u200E!")
+
+	out, err := pretok.PreTokenize(pretokenized)
+	if err != nil {
+		panic(err)
+	}
+
+	got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+
+	want := []tokenizer.PreToken{
+		{Value: "שלום", Offsets: []int{0, 8}, Tokens: nil},
+		{Value: "\u200F", Offsets: []int{8, 11}, Tokens: nil},
+		{Value: " world", Offsets: []int{11, 17}, Tokens: nil},
+		{Value: "\u200E", Offsets: []int{17, 20}, Tokens: nil},
+		{Value: "!", Offsets: []int{20, 21}, Tokens: nil},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %#v\ngot %#v\n", want, got)
+	}
+}
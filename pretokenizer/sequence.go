@@ -28,3 +28,27 @@ func (p *Sequence) PreTokenize(v *tokenizer.PreTokenizedString) (*tokenizer.PreT
 
 	return out, nil
 }
+
+var _ tokenizer.StringPreTokenizer = new(Sequence)
+
+// PreTokenizeString implements tokenizer.StringPreTokenizer if all the
+// pre-tokenizers of the sequence do.
+func (p *Sequence) PreTokenizeString(dst []string, s string) ([]string, bool) {
+	words := []string{s}
+	for _, pretok := range p.pretokenizers {
+		sp, ok := pretok.(tokenizer.StringPreTokenizer)
+		if !ok {
+			return dst, false
+		}
+
+		var next []string
+		for _, word := range words {
+			if next, ok = sp.PreTokenizeString(next, word); !ok {
+				return dst, false
+			}
+		}
+		words = next
+	}
+
+	return append(dst, words...), true
+}
@@ -58,3 +58,15 @@ func (s *Split) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokeni
 		return pretok, nil
 	}
 }
+
+var _ tokenizer.StringPreTokenizer = new(Split)
+
+// PreTokenizeString implements tokenizer.StringPreTokenizer.
+func (s *Split) PreTokenizeString(dst []string, input string) ([]string, bool) {
+	var pattern normalizer.Pattern = s.Pattern
+	if s.Invert {
+		pattern = normalizer.NewInvertPattern(s.Pattern)
+	}
+
+	return normalizer.AppendSplitString(dst, input, pattern, s.Behavior), true
+}
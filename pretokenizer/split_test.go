@@ -142,3 +142,36 @@ func TestSplitInvert(t *testing.T) {
 		t.Errorf("Expected got1 and got1 are equal. But \ngot1: %#v\ngot2: %#v\n", got1, got2)
 	}
 }
+
+func TestSplitBehaviors(t *testing.T) {
+	tests := []struct {
+		behavior normalizer.SplitDelimiterBehavior
+		want     []string
+	}{
+		{normalizer.RemovedBehavior, []string{"the", "final", "countdown"}},
+		{normalizer.IsolatedBehavior, []string{"the", "-", "final", "-", "-", "countdown"}},
+		{normalizer.MergedWithPreviousBehavior, []string{"the-", "final-", "-", "countdown"}},
+		{normalizer.MergedWithNextBehavior, []string{"the", "-final", "-", "-countdown"}},
+		{normalizer.ContiguousBehavior, []string{"the", "-", "final", "--", "countdown"}},
+	}
+
+	for _, tt := range tests {
+		for _, pretok := range []*Split{
+			NewSplit(normalizer.NewStringPattern("-"), tt.behavior, false),
+			NewSplit(normalizer.NewRegexpPattern(`-`), tt.behavior, false),
+		} {
+			out, err := pretok.PreTokenize(tokenizer.NewPreTokenizedString("the-final--countdown"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, split := range out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+				got = append(got, split.Value)
+			}
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("behavior %v: want %q, got %q", tt.behavior, tt.want, got)
+			}
+		}
+	}
+}
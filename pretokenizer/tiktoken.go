@@ -0,0 +1,81 @@
+package pretokenizer
+
+import (
+	"fmt"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/normalizer"
+)
+
+// Split patterns of the OpenAI tiktoken encodings.
+const (
+	// R50kPattern is the pattern of GPT-2 (r50k_base, p50k_base).
+	R50kPattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+	// Cl100kPattern is the pattern of cl100k_base (GPT-3.5, GPT-4).
+	Cl100kPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+	// O200kPattern is the pattern of o200k_base (GPT-4o).
+	O200kPattern = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+)
+
+// TiktokenPattern is a normalizer.Pattern for tiktoken split patterns. Go
+// regexp has no lookaround, so the `\s+(?!\S)` alternative (a whitespace run
+// not followed by a non-whitespace) is emulated, see `normalizer.CompileRegex`.
+type TiktokenPattern struct {
+	pattern string
+	re      *normalizer.RegexpPattern
+}
+
+var _ normalizer.Pattern = new(TiktokenPattern)
+
+// NewTiktokenPattern compiles a tiktoken split pattern.
+func NewTiktokenPattern(pattern string) (*TiktokenPattern, error) {
+	re, err := normalizer.CompileRegexpPattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tiktoken pattern: %w", err)
+	}
+
+	return &TiktokenPattern{pattern: pattern, re: re}, nil
+}
+
+// FindMatches implements normalizer.Pattern.
+func (p *TiktokenPattern) FindMatches(inside string) []normalizer.OffsetsMatch {
+	return p.re.FindMatches(inside)
+}
+
+// Tiktoken is the pre-tokenizer of OpenAI tiktoken encodings: it splits the
+// input with a tiktoken pattern and transforms the splits into their byte-level
+// counterpart as ByteLevel does. ByteLevel is the matching decoder.
+type Tiktoken struct {
+	Pattern string
+	pattern *TiktokenPattern
+}
+
+var _ tokenizer.PreTokenizer = new(Tiktoken)
+
+// NewTiktoken creates a Tiktoken pre-tokenizer with the given split pattern,
+// i.e. `Cl100kPattern`.
+func NewTiktoken(pattern string) (*Tiktoken, error) {
+	p, err := NewTiktokenPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tiktoken{Pattern: pattern, pattern: p}, nil
+}
+
+// PreTokenize implements tokenizer.PreTokenizer.
+func (t *Tiktoken) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
+	pretok := pretokenized.Split(func(noop int, normalized *normalizer.NormalizedString) []tokenizer.SplitIdx {
+		splits := normalized.Split(t.pattern, normalizer.IsolatedBehavior)
+
+		var splitIdx []tokenizer.SplitIdx
+		for _, s := range splits {
+			split := s
+			splitIdx = append(splitIdx, tokenizer.SplitIdx{Normalized: &split, Tokens: nil})
+		}
+
+		return splitIdx
+	})
+
+	return pretok.Normalize(toByteLevel), nil
+}
@@ -0,0 +1,85 @@
+package pretokenizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/normalizer"
+)
+
+func TestTiktokenPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    []string
+	}{
+		{Cl100kPattern, "Hello world", []string{"Hello", " world"}},
+		{Cl100kPattern, "hello   world", []string{"hello", "  ", " world"}},
+		{Cl100kPattern, "trailing   ", []string{"trailing", "   "}},
+		{Cl100kPattern, "12345", []string{"123", "45"}},
+		{Cl100kPattern, "I'M (ok)\n\nnext", []string{"I", "'M", " (", "ok", ")\n\n", "next"}},
+		{Cl100kPattern, "a\u3000\u3000b", []string{"a", "\u3000", "\u3000b"}}, // ideographic spaces
+		{Cl100kPattern, " \n x", []string{" \n", " x"}},
+		{R50kPattern, "I'M  12345!", []string{"I", "'", "M", " ", " 12345", "!"}},
+		{O200kPattern, "HelloWorld's", []string{"Hello", "World's"}},
+	}
+
+	for _, tt := range tests {
+		p, err := NewTiktokenPattern(tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			got  []string
+			prev int
+		)
+		for _, m := range p.FindMatches(tt.s) {
+			if m.Offsets[0] != prev {
+				t.Errorf("%q: matches are not contiguous at %v", tt.s, m.Offsets)
+			}
+			prev = m.Offsets[1]
+			if !m.Match {
+				t.Errorf("%q: want all matched, got unmatched %v", tt.s, m.Offsets)
+			}
+			got = append(got, tt.s[m.Offsets[0]:m.Offsets[1]])
+		}
+		if prev != len(tt.s) {
+			t.Errorf("%q: matches end at %v", tt.s, prev)
+		}
+
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%q: want %q, got %q", tt.s, tt.want, got)
+		}
+	}
+}
+
+func TestTiktokenPattern_Lookaround(t *testing.T) {
+	if _, err := NewTiktokenPattern(`a(?=b)`); err == nil {
+		t.Errorf("want error for a lookahead, got nil")
+	}
+}
+
+func TestTiktoken(t *testing.T) {
+	pretok, err := NewTiktoken(Cl100kPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pretokenized := tokenizer.NewPreTokenizedString("Hello  world")
+	pretokenized, err = pretok.PreTokenize(pretokenized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []tokenizer.PreToken{
+		{Value: "Hello", Offsets: []int{0, 5}, Tokens: nil},
+		{Value: "Ġ", Offsets: []int{5, 6}, Tokens: nil},
+		{Value: "Ġworld", Offsets: []int{6, 12}, Tokens: nil},
+	}
+	got := pretokenized.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
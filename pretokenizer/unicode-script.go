@@ -1,7 +1,6 @@
 package pretokenizer
 
 import (
-	"log"
 	"unicode"
 
 	"github.com/season-studio/tokenizer"
@@ -29,12 +28,15 @@ func DefaultUnicodeScript() *UnicodeScript {
 	return new(UnicodeScript)
 }
 
+// FixedScript returns the script of c as the UnicodeScripts pre-tokenizer sees
+// it: Hiragana, Katakana and U+30FC are Han, and spaces and unassigned
+// characters are "Any", merged into the script of their neighbors.
 func FixedScript(c rune) string {
 	rawScript := GetScript(c)
 
 	if uint32(c) == 0x30FC {
 		return "Han"
-	} else if c == ' ' {
+	} else if c == ' ' || rawScript == "" {
 		return "Any"
 	} else {
 		if rawScript == "Hiragana" || rawScript == "Katakana" {
@@ -49,55 +51,37 @@ func FixedScript(c rune) string {
 
 var _ tokenizer.PreTokenizer = new(UnicodeScript)
 
+// PreTokenize implements tokenizer.PreTokenizer. It splits where the script
+// changes. "Any" characters stay with the preceding script, or with the
+// following one at the beginning of the string.
 func (us *UnicodeScript) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
 	pretok := pretokenized.Split(func(noop int, normalized *normalizer.NormalizedString) []tokenizer.SplitIdx {
+		s := normalized.GetNormalized()
+		if len(s) == 0 {
+			return nil
+		}
+
 		lastScript := ""
-		offset := 0
-		var ranges []int
-		for _, c := range normalized.GetNormalized() {
+		ranges := []int{0}
+		for offset, c := range s {
 			script := FixedScript(c)
-			var result int
-			if script != "Any" && lastScript != "Any" && lastScript != script {
-				result = offset
-			} else {
-				result = -1
-			}
-
-			offset += len(string(c))
-			if script != "Any" {
-				lastScript = script
+			if script == "Any" {
+				continue
 			}
-
-			if result >= 0 {
-				ranges = append(ranges, result)
+			if lastScript != "" && lastScript != script {
+				ranges = append(ranges, offset)
 			}
+			lastScript = script
 		}
+		ranges = append(ranges, len(s))
 
-		ranges = append(ranges, len(normalized.GetNormalized()))
-
-		log.Printf("ranges: %v\n", ranges)
-
-		var splits []normalizer.NormalizedString
+		var splitIdxs []tokenizer.SplitIdx
 		for i := 0; i < len(ranges)-1; i++ { // windows(2)
-			var start, end int
-			if i == 0 {
-				start = 0
-				end = 1
-			} else {
-				start = i
-				end = i + 1
+			split := normalized.Slice(normalizer.NewRange(ranges[i], ranges[i+1], normalizer.NormalizedTarget))
+			if split == nil {
+				continue
 			}
-			inputRange := normalizer.NewRange(ranges[start], ranges[end], normalizer.NormalizedTarget)
-			log.Printf("inputRange: %+v\n", inputRange)
-			split := normalized.Slice(inputRange)
-			splits = append(splits, *split)
-		}
-
-		var splitIdxs []tokenizer.SplitIdx
-		for _, s := range splits {
-			normalized := s
-			splitIdx := tokenizer.SplitIdx{Normalized: &normalized, Tokens: nil}
-			splitIdxs = append(splitIdxs, splitIdx)
+			splitIdxs = append(splitIdxs, tokenizer.SplitIdx{Normalized: split, Tokens: nil})
 		}
 
 		return splitIdxs
@@ -135,3 +135,19 @@ func TestFixedScript(t *testing.T) {
 		}
 	}
 }
+
+func TestUnicodeScriptLeadingSpaces(t *testing.T) {
+	out, err := DefaultUnicodeScript().PreTokenize(tokenizer.NewPreTokenizedString("  abc日本"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+	want := []tokenizer.PreToken{
+		{Value: "  abc", Offsets: []int{0, 5}, Tokens: nil},
+		{Value: "日本", Offsets: []int{5, 11}, Tokens: nil},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want: %#v\ngot %#v\n", want, got)
+	}
+}
@@ -5,6 +5,13 @@ import (
 	"github.com/season-studio/tokenizer/normalizer"
 )
 
+// bidiControls is the regexp class of Unicode bidi control characters (see
+// `normalizer.IsBidiControl`). They are zero-width and must not be glued onto
+// words or punctuation.
+const bidiControls = `\x{061C}\x{200E}\x{200F}\x{202A}-\x{202E}\x{2066}-\x{2069}`
+
+// Whitespace splits on whitespace and between words and punctuation. Bidi
+// control characters are treated as whitespace.
 type Whitespace struct{}
 
 func NewWhitespace() *Whitespace {
@@ -19,12 +26,13 @@ func DefaultWhitespace() *Whitespace {
 
 var _ tokenizer.PreTokenizer = new(Whitespace)
 
+// whitespacePattern matches the whitespace and bidi controls around words and
+// punctuation.
+var whitespacePattern = normalizer.NewInvertPattern(normalizer.NewRegexpPattern(`\w+|[^\w\s` + bidiControls + `]+`))
+
 func (p *Whitespace) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*tokenizer.PreTokenizedString, error) {
 	pretok := pretokenized.Split(func(noop int, normalized *normalizer.NormalizedString) []tokenizer.SplitIdx {
-		s := `\w+|[^\w\s]+`
-		rePattern := normalizer.NewRegexpPattern(s)
-		invert := normalizer.NewInvertPattern(rePattern)
-		splits := normalized.Split(invert, normalizer.RemovedBehavior)
+		splits := normalized.Split(whitespacePattern, normalizer.RemovedBehavior)
 
 		var splitIdxs []tokenizer.SplitIdx
 		for _, s := range splits {
@@ -39,6 +47,13 @@ func (p *Whitespace) PreTokenize(pretokenized *tokenizer.PreTokenizedString) (*t
 	return pretok, nil
 }
 
+var _ tokenizer.StringPreTokenizer = new(Whitespace)
+
+// PreTokenizeString implements tokenizer.StringPreTokenizer.
+func (p *Whitespace) PreTokenizeString(dst []string, s string) ([]string, bool) {
+	return normalizer.AppendSplitString(dst, s, whitespacePattern, normalizer.RemovedBehavior), true
+}
+
 type WhitespaceSplit struct{}
 
 func NewWhitespaceSplit() *WhitespaceSplit {
@@ -66,3 +81,10 @@ func (p *WhitespaceSplit) PreTokenize(pretokenized *tokenizer.PreTokenizedString
 
 	return pretok, nil
 }
+
+var _ tokenizer.StringPreTokenizer = new(WhitespaceSplit)
+
+// PreTokenizeString implements tokenizer.StringPreTokenizer.
+func (p *WhitespaceSplit) PreTokenizeString(dst []string, s string) ([]string, bool) {
+	return normalizer.AppendSplitString(dst, s, normalizer.NewFnPattern(normalizer.IsWhitespace), normalizer.RemovedBehavior), true
+}
@@ -89,3 +89,107 @@ func TestWhitespaceSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestWhitespaceBidiControls(t *testing.T) {
+	pretok := DefaultWhitespace()
+
+	tests := []struct {
+		name  string
+		s     string
+		strip bool
+		res   []tokenizer.PreToken
+	}{
+		{
+			name: "RLM and LRM kept",
+			s:    "שלום\u200F world\u200E!",
+			res: []tokenizer.PreToken{
+				{Value: "שלום", Offsets: []int{0, 8}, Tokens: nil},
+				{Value: "world", Offsets: []int{12, 17}, Tokens: nil},
+				{Value: "!", Offsets: []int{20, 21}, Tokens: nil},
+			},
+		},
+		{
+			name: "ALM kept",
+			s:    "مرحبا\u061C, hi",
+			res: []tokenizer.PreToken{
+				{Value: "مرحبا", Offsets: []int{0, 10}, Tokens: nil},
+				{Value: ",", Offsets: []int{12, 13}, Tokens: nil},
+				{Value: "hi", Offsets: []int{14, 16}, Tokens: nil},
+			},
+		},
+		{
+			name:  "stripped",
+			s:     "\u2067שלום\u200F world\u200E!\u2069",
+			strip: true,
+			res: []tokenizer.PreToken{
+				{Value: "שלום", Offsets: []int{3, 11}, Tokens: nil},
+				{Value: "world", Offsets: []int{15, 20}, Tokens: nil},
+				{Value: "!", Offsets: []int{23, 24}, Tokens: nil},
+			},
+		},
+	}
+
+	for _, data := range tests {
+		n := normalizer.NewNormalizedFrom(data.s)
+		if data.strip {
+			var err error
+			n, err = normalizer.NewBidiControl(normalizer.BidiStrip).Normalize(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		out, err := pretok.PreTokenize(tokenizer.NewPreTokenizedStringFromNS(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := out.GetSplits(normalizer.OriginalTarget, tokenizer.Byte)
+		if !reflect.DeepEqual(data.res, got) {
+			t.Errorf("%v: want %#v\ngot %#v\n", data.name, data.res, got)
+		}
+	}
+}
+
+func TestPreTokenizeString(t *testing.T) {
+	noPrefix := NewByteLevel()
+	noPrefix.SetAddPrefixSpace(false)
+	pretoks := []tokenizer.StringPreTokenizer{
+		DefaultWhitespace(),
+		NewWhitespaceSplit(),
+		NewByteLevel(),
+		noPrefix,
+		NewSplit(normalizer.NewStringPattern("-"), normalizer.MergedWithPreviousBehavior, false),
+		NewSplit(normalizer.NewRegexpPattern("[a-c]"), normalizer.ContiguousBehavior, true),
+		NewSequence([]tokenizer.PreTokenizer{NewWhitespaceSplit(), NewByteLevel()}),
+	}
+	inputs := []string{"", "Hey man!", "  a-b--c  d\n\te ", "Café's 😁?‏ x"}
+
+	for i, pretok := range pretoks {
+		for _, s := range inputs {
+			out, err := pretok.(tokenizer.PreTokenizer).PreTokenize(tokenizer.NewPreTokenizedString(s))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, split := range out.GetSplits(normalizer.NormalizedTarget, tokenizer.Byte) {
+				want = append(want, split.Value)
+			}
+
+			// The pre-tokens are appended after the given ones.
+			got, ok := pretok.PreTokenizeString([]string{"<"}, s)
+			if !ok {
+				t.Fatalf("%d %q: PreTokenizeString failed", i, s)
+			}
+			if got[0] != "<" {
+				t.Fatalf("%d %q: want pre-tokens appended, got %q", i, s, got)
+			}
+			got = got[1:]
+			if len(want) != 0 || len(got) != 0 {
+				if !reflect.DeepEqual(want, got) {
+					t.Errorf("%d %q: want %q, got %q", i, s, want, got)
+				}
+			}
+		}
+	}
+}
@@ -22,3 +22,25 @@ func CreateAddedTokens(data []tokenizer.TokenConfig) (specialToks, toks []tokeni
 
 	return specialToks, toks
 }
+
+// CreateAddedTokensWithId creates the added tokens, special or not, along
+// with their ids in the order of the config.
+func CreateAddedTokensWithId(data []tokenizer.TokenConfig) []tokenizer.AddedTokenWithId {
+	var toks []tokenizer.AddedTokenWithId
+	for _, d := range data {
+		tok := tokenizer.DefaultAddedToken()
+		tok.Content = d.Content
+		tok.LStrip = d.Lstrip
+		tok.Normalized = d.Normalized
+		tok.RStrip = d.Rstrip
+		tok.SingleWord = d.SingleWord
+
+		toks = append(toks, tokenizer.AddedTokenWithId{
+			Id:      int(d.Id),
+			Special: d.Special,
+			Token:   tok,
+		})
+	}
+
+	return toks
+}
@@ -2,6 +2,8 @@ package pretrained
 
 import (
 	"log"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -54,3 +56,36 @@ func TestCreateAddedTokens(t *testing.T) {
 }
 
 // Output:
+
+func TestFromReader_AddedTokens(t *testing.T) {
+	config := `{
+  "added_tokens": [
+    {"id": 0, "content": "<unk>", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 10, "content": "<|im_start|>", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 12, "content": "<mask>", "single_word": false, "lstrip": true, "rstrip": false, "normalized": false, "special": true},
+    {"id": 11, "content": "Hello", "single_word": true, "lstrip": false, "rstrip": false, "normalized": true, "special": false}
+  ],
+  "normalizer": {"type": "Lowercase"},
+  "pre_tokenizer": {"type": "WhitespaceSplit"},
+  "model": {"type": "WordLevel", "unk_token": "<unk>", "vocab": {"<unk>": 0, "a": 1, "b": 2}}
+}`
+
+	tk, err := FromReader(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	en, err := tk.EncodeSingle("<|im_start|>a HELLO  <mask> Helloa", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{10, 1, 11, 12, 0}
+	if !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want %v, got %v (%q)", want, en.Ids, en.Tokens)
+	}
+
+	if got, want := tk.GetVocabSize(true), 6; got != want {
+		t.Errorf("want vocab size %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,183 @@
+package pretrained
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/decoder"
+	"github.com/season-studio/tokenizer/model/bpe"
+	"github.com/season-studio/tokenizer/model/wordpiece"
+	"github.com/season-studio/tokenizer/normalizer"
+	"github.com/season-studio/tokenizer/pretokenizer"
+	"github.com/season-studio/tokenizer/processor"
+)
+
+// SpecialTokenPatterns is the registry of well-known special token forms used
+// to infer special tokens from a bare vocab (see `WithInferSpecialTokens`).
+// Patterns can be appended before loading.
+var SpecialTokenPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^<\|[^|\s]+\|>$`), // i.e. <|endoftext|>
+	regexp.MustCompile(`^\[(CLS|SEP|PAD|MASK|UNK)\]$`),
+	regexp.MustCompile(`^</?s>$`),
+	regexp.MustCompile(`^<(unk|pad|mask)>$`),
+}
+
+// LoadReport describes what a loader did on top of reading the given files.
+type LoadReport struct {
+	// InferredSpecialTokens lists the vocab tokens registered as special tokens
+	// because they matched `SpecialTokenPatterns`.
+	InferredSpecialTokens []string
+}
+
+// LoadOpts are the options of `FromBPEFiles` and `FromVocabTxt`, see
+// `DefaultLoadOpts`.
+type LoadOpts struct {
+	InferSpecialTokens bool // whether to register the vocab tokens matching `SpecialTokenPatterns` as special tokens
+	Lowercase          bool // whether `FromVocabTxt` lowercases and strips accents
+}
+
+// LoadOpt sets an option of `FromBPEFiles` and `FromVocabTxt`.
+type LoadOpt func(o *LoadOpts)
+
+// WithInferSpecialTokens registers vocab tokens matching `SpecialTokenPatterns`
+// as special added tokens so that they are kept whole while encoding and can be
+// skipped while decoding.
+func WithInferSpecialTokens() LoadOpt {
+	return func(o *LoadOpts) {
+		o.InferSpecialTokens = true
+	}
+}
+
+// WithLowercase sets whether `FromVocabTxt` lowercases the input and strips its
+// accents, as uncased BERT checkpoints do (default), or keeps it as is for cased
+// ones.
+func WithLowercase(lowercase bool) LoadOpt {
+	return func(o *LoadOpts) {
+		o.Lowercase = lowercase
+	}
+}
+
+// DefaultLoadOpts returns the default options of `FromBPEFiles` and
+// `FromVocabTxt`: no inferred special tokens, and lowercasing for `FromVocabTxt`.
+func DefaultLoadOpts() *LoadOpts {
+	return &LoadOpts{
+		InferSpecialTokens: false,
+		Lowercase:          true,
+	}
+}
+
+// InferSpecialTokens registers all tokens of the model vocab matching
+// `SpecialTokenPatterns` as special tokens of the given tokenizer. It returns
+// the registered tokens ordered by id.
+func InferSpecialTokens(tk *tokenizer.Tokenizer) []string {
+	vocab := tk.GetModel().GetVocab()
+
+	var found []string
+	for tok := range vocab {
+		for _, re := range SpecialTokenPatterns {
+			if re.MatchString(tok) {
+				found = append(found, tok)
+				break
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return vocab[found[i]] < vocab[found[j]] })
+
+	var addedToks []tokenizer.AddedToken
+	for _, tok := range found {
+		addedToks = append(addedToks, tokenizer.NewAddedToken(tok, true))
+	}
+	if len(addedToks) > 0 {
+		tk.AddSpecialTokens(addedToks)
+	}
+
+	return found
+}
+
+// FromBPEFiles constructs a GPT-2 style byte-level BPE Tokenizer from bare
+// `vocab.json` and `merges.txt` files, i.e. without a `tokenizer.json` config.
+func FromBPEFiles(vocabFile, mergesFile string, opts ...LoadOpt) (*tokenizer.Tokenizer, *LoadReport, error) {
+	o := DefaultLoadOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	model, err := bpe.NewBpeFromFiles(vocabFile, mergesFile)
+	if err != nil {
+		err = fmt.Errorf("FromBPEFiles: %w", err)
+		return nil, nil, err
+	}
+
+	tk := tokenizer.NewTokenizer(model)
+
+	byteLevel := pretokenizer.NewByteLevel()
+	byteLevel.SetAddPrefixSpace(false)
+	byteLevel.SetTrimOffsets(true)
+	tk.WithPreTokenizer(byteLevel)
+	tk.WithPostProcessor(processor.NewByteLevelProcessing(byteLevel))
+	tk.WithDecoder(byteLevel)
+
+	report := &LoadReport{}
+	if o.InferSpecialTokens {
+		report.InferredSpecialTokens = InferSpecialTokens(tk)
+	}
+
+	return tk, report, nil
+}
+
+// bertSpecialTokens are the special tokens of BERT vocabs.
+var bertSpecialTokens = []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "[MASK]"}
+
+// FromVocabTxt constructs a BERT WordPiece Tokenizer from a bare `vocab.txt`
+// file (one token per line), as older BERT checkpoints ship without a
+// `tokenizer.json` config. As HuggingFace `BertWordPieceTokenizer`, it sets up
+// the BERT normalizer (lowercasing unless `WithLowercase(false)`), the BERT
+// pre-tokenizer, a `[CLS] A [SEP] B [SEP]` post-processor and the WordPiece
+// decoder, and registers the BERT special tokens of the vocab.
+func FromVocabTxt(vocabFile string, opts ...LoadOpt) (*tokenizer.Tokenizer, *LoadReport, error) {
+	o := DefaultLoadOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	model, err := wordpiece.NewFromFile(vocabFile, wordpiece.WithUnkToken("[UNK]"))
+	if err != nil {
+		err = fmt.Errorf("FromVocabTxt: %w", err)
+		return nil, nil, err
+	}
+
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithNormalizer(normalizer.NewBertNormalizer(true, o.Lowercase, true, o.Lowercase))
+	tk.WithPreTokenizer(pretokenizer.NewBertPreTokenizer())
+	tk.WithDecoder(decoder.DefaultWordpieceDecoder())
+
+	sepId, ok := model.TokenToId("[SEP]")
+	if !ok {
+		return nil, nil, fmt.Errorf("FromVocabTxt: %s has no [SEP] token: %w", vocabFile, tokenizer.ErrTokenNotInVocab)
+	}
+	clsId, ok := model.TokenToId("[CLS]")
+	if !ok {
+		return nil, nil, fmt.Errorf("FromVocabTxt: %s has no [CLS] token: %w", vocabFile, tokenizer.ErrTokenNotInVocab)
+	}
+	tk.WithPostProcessor(processor.NewBertProcessing(
+		processor.PostToken{Id: sepId, Value: "[SEP]"},
+		processor.PostToken{Id: clsId, Value: "[CLS]"},
+	))
+
+	var specialToks []tokenizer.AddedToken
+	for _, tok := range bertSpecialTokens {
+		if _, ok := model.TokenToId(tok); ok {
+			specialToks = append(specialToks, tokenizer.NewAddedToken(tok, true))
+		}
+	}
+	tk.AddSpecialTokens(specialToks)
+
+	report := &LoadReport{}
+	if o.InferSpecialTokens {
+		report.InferredSpecialTokens = InferSpecialTokens(tk)
+	}
+
+	return tk, report, nil
+}
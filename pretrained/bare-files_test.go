@@ -0,0 +1,136 @@
+package pretrained
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer/pretokenizer"
+)
+
+// writeBPEFiles writes a tiny GPT-2 style byte-level vocab and merges.
+func writeBPEFiles(t *testing.T) (vocabFile, mergesFile string) {
+	dir := t.TempDir()
+
+	vocab := make(map[string]int)
+	for b := 0; b < 256; b++ {
+		vocab[pretokenizer.BytesChar[uint8(b)]] = b
+	}
+	vocab["Ġw"] = 256
+	vocab["<|endoftext|>"] = 257
+
+	data, err := json.Marshal(vocab)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vocabFile = filepath.Join(dir, "vocab.json")
+	if err := os.WriteFile(vocabFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mergesFile = filepath.Join(dir, "merges.txt")
+	if err := os.WriteFile(mergesFile, []byte("#version: 0.2\nĠ w\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return vocabFile, mergesFile
+}
+
+func TestFromBPEFiles_InferSpecialTokens(t *testing.T) {
+	vocabFile, mergesFile := writeBPEFiles(t)
+	doc := "Hello world<|endoftext|>"
+
+	tk, report, err := FromBPEFiles(vocabFile, mergesFile, WithInferSpecialTokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantInferred := []string{"<|endoftext|>"}
+	if !reflect.DeepEqual(wantInferred, report.InferredSpecialTokens) {
+		t.Errorf("want inferred %q, got %q", wantInferred, report.InferredSpecialTokens)
+	}
+
+	en, err := tk.EncodeSingle(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := en.Ids[len(en.Ids)-1]; got != 257 {
+		t.Errorf("want end-of-text id 257, got %v (ids %v)", got, en.Ids)
+	}
+
+	if got, want := tk.Decode(en.Ids, true), "Hello world"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got := tk.Decode(en.Ids, false); got != doc {
+		t.Errorf("want %q, got %q", doc, got)
+	}
+}
+
+func TestFromBPEFiles_Default(t *testing.T) {
+	vocabFile, mergesFile := writeBPEFiles(t)
+	doc := "Hello world<|endoftext|>"
+
+	tk, report, err := FromBPEFiles(vocabFile, mergesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.InferredSpecialTokens) != 0 {
+		t.Errorf("want no inferred special tokens, got %q", report.InferredSpecialTokens)
+	}
+	if got := tk.GetSpecialTokens(); len(got) != 0 {
+		t.Errorf("want no special tokens, got %q", got)
+	}
+
+	en, err := tk.EncodeSingle(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The marker is split into bytes and is not skippable.
+	if got := tk.Decode(en.Ids, true); got != doc {
+		t.Errorf("want %q, got %q", doc, got)
+	}
+}
+
+func TestFromVocabTxt(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vocab.txt")
+	vocab := "[PAD]\n[UNK]\n[CLS]\n[SEP]\n[MASK]\nhello\nworld\n##s\n,\nHello\n"
+	if err := os.WriteFile(file, []byte(vocab), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		opts       []LoadOpt
+		input      string
+		wantTokens []string
+	}{
+		{"uncased", nil, "Héllo, Worlds", []string{"[CLS]", "hello", ",", "world", "##s", "[SEP]", "[MASK]", "[SEP]"}},
+		{"cased", []LoadOpt{WithLowercase(false)}, "Hello, Worlds", []string{"[CLS]", "Hello", ",", "[UNK]", "[SEP]", "[MASK]", "[SEP]"}},
+	}
+	for _, tt := range tests {
+		tk, _, err := FromVocabTxt(file, tt.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		en, err := tk.EncodePair(tt.input, "[MASK]", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.wantTokens, en.Tokens) {
+			t.Errorf("%s: want %q, got %q", tt.name, tt.wantTokens, en.Tokens)
+		}
+		if got := tk.Decode(en.Ids, true); got != "hello, worlds" && tt.name == "uncased" {
+			t.Errorf("%s: want decoded %q, got %q", tt.name, "hello, worlds", got)
+		}
+	}
+
+	if err := os.WriteFile(file, []byte("[UNK]\nhello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := FromVocabTxt(file); err == nil {
+		t.Error("want an error for a vocab without [CLS] and [SEP]")
+	}
+}
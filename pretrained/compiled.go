@@ -0,0 +1,132 @@
+package pretrained
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model"
+	"github.com/season-studio/tokenizer/util"
+)
+
+// compiledMagic starts the files written by `CompileVocab`. Its last digits are
+// the version of the format.
+const compiledMagic = "GOTKCV02"
+
+// compiledModel is implemented by models with a compiled form.
+type compiledModel interface {
+	AppendCompiled(b []byte) []byte
+}
+
+// CompileVocab writes the tokenizer to file in a binary form which
+// `LoadCompiled` loads without parsing the vocab: the file is the tokenizer
+// config without the vocab and merges of its model, followed by their compiled
+// form, see `model.AppendVocab`. Only BPE, WordPiece, WordLevel and Unigram
+// vocabs are compiled, other models are kept in the config.
+//
+// The file is written to a temporary file renamed to file, so that processes
+// which mapped a previous version keep reading it unchanged.
+func CompileVocab(tk *tokenizer.Tokenizer, file string) error {
+	data, err := tk.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var config tokenizer.Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	var blob []byte
+	switch m := tk.GetModel().(type) {
+	case compiledModel:
+		blob = m.AppendCompiled(nil)
+	default:
+		switch config.Model["type"] {
+		case "WordPiece", "WordLevel":
+			blob = model.AppendVocab(nil, m.GetVocab())
+		}
+	}
+	if blob != nil {
+		delete(config.Model, "vocab")
+		delete(config.Model, "merges")
+	}
+
+	data, err = json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(compiledMagic)
+	binary.Write(&buf, binary.LittleEndian, uint64(len(data)))
+	buf.Write(data)
+	// Align the compiled vocab to 8 bytes, see `model.AppendInt32s`.
+	for buf.Len()%8 != 0 {
+		buf.WriteByte(0)
+	}
+	buf.Write(blob)
+
+	return writeFileAtomic(file, buf.Bytes())
+}
+
+// writeFileAtomic writes data to a temporary file of the directory of file,
+// then renames it to file.
+func writeFileAtomic(file string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), file)
+}
+
+// LoadCompiled constructs a new Tokenizer from a file written by
+// `CompileVocab`. The file is memory mapped and its memory is shared between
+// the processes loading it. BPE tokens and merges are looked up in place and
+// Unigram pieces are read with their trie, so that loading them builds no map;
+// WordPiece and WordLevel vocabs are read into their maps.
+func LoadCompiled(file string) (*tokenizer.Tokenizer, error) {
+	data, err := util.MapFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	header := len(compiledMagic) + 8
+	if len(data) < header || string(data[:len(compiledMagic)]) != compiledMagic {
+		return nil, fmt.Errorf("LoadCompiled error: %s is not a compiled vocab file", file)
+	}
+	size := binary.LittleEndian.Uint64(data[len(compiledMagic):])
+	if size > uint64(len(data)-header) {
+		return nil, fmt.Errorf("LoadCompiled error: config of %d bytes exceeds the %d bytes of %s", size, len(data), file)
+	}
+	end := header + int(size)
+
+	var config *tokenizer.Config
+	if err := json.Unmarshal(data[header:end], &config); err != nil {
+		return nil, fmt.Errorf("LoadCompiled error: %w", err)
+	}
+
+	var compiled []byte
+	if start := (end + 7) &^ 7; start < len(data) {
+		compiled = data[start:]
+	}
+
+	return fromConfig(config, compiled)
+}
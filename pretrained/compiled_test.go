@@ -0,0 +1,122 @@
+package pretrained
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompileVocab(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "compat", "*", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no compat tokenizers")
+	}
+
+	for _, file := range files {
+		name := filepath.Base(filepath.Dir(file))
+		t.Run(name, func(t *testing.T) {
+			tk, err := FromFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compiled := filepath.Join(t.TempDir(), "tokenizer.bin")
+			if err := CompileVocab(tk, compiled); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadCompiled(compiled)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := tk.Serialize(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := loaded.Serialize(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("want %s, got %s", want, got)
+			}
+
+			data, err := os.ReadFile(filepath.Join(filepath.Dir(file), "expected.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cases []struct {
+				Input string `json:"input"`
+			}
+			if err := json.Unmarshal(data, &cases); err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range cases {
+				want, err := tk.EncodeSingle(c.Input, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := loaded.EncodeSingle(c.Input, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(want, got) {
+					t.Errorf("%q: want %+v, got %+v", c.Input, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileVocab_Replace(t *testing.T) {
+	tk, err := FromFile(filepath.Join("..", "testdata", "compat", "gpt2-bytelevel", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled := filepath.Join(t.TempDir(), "tokenizer.bin")
+	if err := CompileVocab(tk, compiled); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCompiled(compiled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := loaded.EncodeSingle("Hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Compiling another tokenizer to the file leaves the mapped one unchanged.
+	other, err := FromFile(filepath.Join("..", "testdata", "compat", "t5-unigram", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompileVocab(other, compiled); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loaded.EncodeSingle("Hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(compiled))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("want only the compiled file, got %d files", len(entries))
+	}
+}
+
+func TestLoadCompiled_NotCompiled(t *testing.T) {
+	if _, err := LoadCompiled(filepath.Join("..", "testdata", "compat", "t5-unigram", "tokenizer.json")); err == nil {
+		t.Error("want an error for a json file")
+	}
+}
@@ -0,0 +1,79 @@
+package pretrained
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/season-studio/tokenizer/util"
+)
+
+// ConfigError is returned when a field of a `tokenizer.json` file is missing
+// or has an invalid value.
+type ConfigError struct {
+	// Field is the path of the offending field, i.e. "model.merges[3]".
+	Field string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid tokenizer config field %q: %v", e.Field, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func configErrorf(field, format string, args ...interface{}) *ConfigError {
+	return &ConfigError{Field: field, Err: fmt.Errorf(format, args...)}
+}
+
+// decodeConfig decodes the config data of `section` (i.e. "model") into v, a
+// pointer to a typed config struct. Type mismatches are reported as
+// ConfigError naming the offending field.
+func decodeConfig(section string, params *util.Params, v interface{}) error {
+	data, err := json.Marshal(params.Values())
+	if err != nil {
+		return &ConfigError{Field: section, Err: err}
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			field := section
+			if typeErr.Field != "" {
+				field += "." + typeErr.Field
+			}
+			return configErrorf(field, "want %v, got %v", jsonTypeName(typeErr.Type), typeErr.Value)
+		}
+		return &ConfigError{Field: section, Err: err}
+	}
+
+	return nil
+}
+
+// jsonTypeName names a Go type by its JSON counterpart.
+func jsonTypeName(typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return typ.String()
+	}
+}
@@ -31,7 +31,7 @@ func CreateDecoder(config map[string]interface{}) (tokenizer.Decoder, error) {
 	typ := params.Get("type").(string)
 
 	switch typ {
-	case "BPE":
+	case "BPEDecoder", "BPE":
 		return createBPEDecoder(params)
 	case "ByteLevel":
 		return createByteLevelDecoder(params)
@@ -64,28 +64,11 @@ func createBPEDecoder(params *util.Params) (*decoder.BpeDecoder, error) {
 }
 
 func createByteLevelDecoder(params *util.Params) (*pretokenizer.ByteLevel, error) {
-	if params == nil {
-		return nil, nil
-	}
-
-	addPrefixSpace := params.Get("add_prefix_space", false).(bool)
-	trimOffsets := params.Get("trim_offsets", false).(bool)
-
-	return &pretokenizer.ByteLevel{
-		AddPrefixSpace: addPrefixSpace,
-		TrimOffsets:    trimOffsets,
-	}, nil
+	return createByteLevel("decoder", params)
 }
 
 func createMetaspaceDecoder(params *util.Params) (*pretokenizer.Metaspace, error) {
-	if params == nil {
-		return nil, nil
-	}
-
-	replacement := params.Get("replacement", "").(string)
-	addPrefixSpace := params.Get("add_prefix_space", false).(bool)
-
-	return pretokenizer.NewMetaspace(replacement, addPrefixSpace), nil
+	return createMetaspace("decoder", params)
 }
 
 func createCTCDecoder(params *util.Params) (*decoder.CTC, error) {
@@ -94,7 +77,8 @@ func createCTCDecoder(params *util.Params) (*decoder.CTC, error) {
 	}
 
 	padToken := params.Get("pad_token").(string)
-	wordDelimiter := params.Get("word_delimiter").(string)
+	// HuggingFace names it `word_delimiter_token`.
+	wordDelimiter := params.Get("word_delimiter_token", params.Get("word_delimiter")).(string)
 	cleanup := params.Get("cleanup").(bool)
 
 	return decoder.NewCTC(padToken, wordDelimiter, cleanup), nil
@@ -154,27 +138,7 @@ func createSequenceDecoder(params *util.Params) (*decoder.Sequence, error) {
 }
 
 func createReplaceDecoder(params *util.Params) (*normalizer.Replace, error) {
-	if params == nil {
-		return nil, nil
-	}
-
-	var pattern string
-	var patternType normalizer.ReplacePattern
-	patternParams := params.Get("pattern").(map[string]interface{})
-	pparams := util.NewParams(patternParams)
-	switch {
-	case pparams.Has("String"):
-		pattern = pparams.Get("String").(string)
-		patternType = normalizer.String
-
-	case params.Has("Regex"):
-		pattern = pparams.Get("Regex").(string)
-		patternType = normalizer.String
-	}
-
-	content := params.Get("content").(string)
-
-	return normalizer.NewReplace(patternType, pattern, content), nil
+	return createReplace("decoder", params)
 }
 
 func createFuseDecoder(params *util.Params) (*decoder.Fuse, error) {
@@ -0,0 +1,306 @@
+package pretrained
+
+import (
+	"fmt"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/gguf"
+	"github.com/season-studio/tokenizer/model/bpe"
+	"github.com/season-studio/tokenizer/pretokenizer"
+	"github.com/season-studio/tokenizer/processor"
+	"github.com/season-studio/tokenizer/spm"
+)
+
+// ggufPreTokenizerPatterns maps the `tokenizer.ggml.pre` names of llama.cpp to
+// the split patterns of byte-level BPE models.
+var ggufPreTokenizerPatterns = map[string]string{
+	"":          pretokenizer.R50kPattern,
+	"default":   pretokenizer.R50kPattern,
+	"gpt-2":     pretokenizer.R50kPattern,
+	"gpt2":      pretokenizer.R50kPattern,
+	"llama3":    pretokenizer.Cl100kPattern,
+	"llama-bpe": pretokenizer.Cl100kPattern,
+	"llama-v3":  pretokenizer.Cl100kPattern,
+	"dbrx":      pretokenizer.Cl100kPattern,
+	"smaug-bpe": pretokenizer.Cl100kPattern,
+	"gpt-4o":    pretokenizer.O200kPattern,
+}
+
+// FromGGUF constructs a new Tokenizer from the tokenizer metadata embedded in a
+// llama.cpp GGUF model file, so that a single file serves both the model and
+// its tokenizer.
+//
+// The `tokenizer.ggml.model` key selects the model: "llama" is a SentencePiece
+// BPE model and "t5" a SentencePiece Unigram model, both loaded as by
+// `FromSentencePieceModel`, and "gpt2" a byte-level BPE model whose split
+// pattern is selected by `tokenizer.ggml.pre`. Control tokens are registered as
+// special tokens and user defined tokens as added tokens. BOS and EOS tokens are
+// added by a post-processor as llama.cpp does, and the chat template, if any, is
+// set.
+func FromGGUF(path string) (*tokenizer.Tokenizer, error) {
+	f, err := gguf.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromGGUFMetadata(f)
+}
+
+// FromGGUFMetadata constructs a new Tokenizer from the metadata of a GGUF file.
+// See `FromGGUF`.
+func FromGGUFMetadata(f *gguf.File) (*tokenizer.Tokenizer, error) {
+	kind, _ := f.String("tokenizer.ggml.model")
+	tokens, ok := f.Strings("tokenizer.ggml.tokens")
+	if !ok {
+		return nil, fmt.Errorf("FromGGUF error: missing tokenizer.ggml.tokens")
+	}
+	types, err := ggufTokenTypes(f, len(tokens))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		tk             *tokenizer.Tokenizer
+		addBos, addEos bool
+	)
+	switch kind {
+	case "llama", "t5":
+		tk, err = ggufSentencePiece(f, kind, tokens, types)
+		addBos, addEos = kind == "llama", kind == "t5"
+	case "gpt2":
+		tk, err = ggufByteLevelBPE(f, tokens, types)
+	default:
+		err = fmt.Errorf("FromGGUF error: unsupported tokenizer model %q: %w", kind, tokenizer.ErrUnknownModelType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := f.Bool("tokenizer.ggml.add_bos_token"); ok {
+		addBos = v
+	}
+	if v, ok := f.Bool("tokenizer.ggml.add_eos_token"); ok {
+		addEos = v
+	}
+	bos := ggufTokenId(f, "tokenizer.ggml.bos_token_id", tokens)
+	eos := ggufTokenId(f, "tokenizer.ggml.eos_token_id", tokens)
+	if !addBos {
+		bos = -1
+	}
+	if !addEos {
+		eos = -1
+	}
+	if bos >= 0 || eos >= 0 {
+		var bosToken, eosToken string
+		if bos >= 0 {
+			bosToken = tokens[bos]
+		}
+		if eos >= 0 {
+			eosToken = tokens[eos]
+		}
+		tp, err := bosEosTemplateProcessing(bosToken, bos, eosToken, eos)
+		if err != nil {
+			return nil, fmt.Errorf("FromGGUF error: %w", err)
+		}
+		tk.WithPostProcessor(tp)
+	}
+
+	if source, ok := f.String("tokenizer.chat_template"); ok && source != "" {
+		template, err := tokenizer.NewChatTemplate(source)
+		if err != nil {
+			return nil, fmt.Errorf("FromGGUF error: %w", err)
+		}
+		for name, key := range map[string]string{"bos_token": "tokenizer.ggml.bos_token_id", "eos_token": "tokenizer.ggml.eos_token_id"} {
+			if id := ggufTokenId(f, key, tokens); id >= 0 {
+				if err := template.WithVariable(name, tokens[id]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		tk.WithChatTemplate(template)
+	}
+
+	return tk, nil
+}
+
+// ggufTokenTypes returns the `tokenizer.ggml.token_type` of each token, which
+// are SentencePiece piece types, all normal if missing.
+func ggufTokenTypes(f *gguf.File, n int) ([]spm.PieceType, error) {
+	types := make([]spm.PieceType, n)
+	for i := range types {
+		types[i] = spm.PieceNormal
+	}
+
+	raw, ok := f.Int32s("tokenizer.ggml.token_type")
+	if !ok {
+		return types, nil
+	}
+	if len(raw) != n {
+		return nil, fmt.Errorf("FromGGUF error: got %d token types for %d tokens", len(raw), n)
+	}
+	for i, t := range raw {
+		types[i] = spm.PieceType(t)
+	}
+
+	return types, nil
+}
+
+// ggufTokenId returns the token id of the key, -1 if missing or out of the
+// vocab.
+func ggufTokenId(f *gguf.File, key string, tokens []string) int {
+	id, ok := f.Int(key)
+	if !ok || id < 0 || id >= int64(len(tokens)) {
+		return -1
+	}
+	return int(id)
+}
+
+// ggufSentencePiece rebuilds the SentencePiece model of the "llama" and "t5"
+// tokenizers.
+func ggufSentencePiece(f *gguf.File, kind string, tokens []string, types []spm.PieceType) (*tokenizer.Tokenizer, error) {
+	scores, ok := f.Float32s("tokenizer.ggml.scores")
+	if !ok || len(scores) != len(tokens) {
+		return nil, fmt.Errorf("FromGGUF error: want %d tokenizer.ggml.scores, got %d", len(tokens), len(scores))
+	}
+
+	m := &spm.ModelProto{
+		Pieces: make([]spm.Piece, len(tokens)),
+		TrainerSpec: spm.TrainerSpec{
+			ModelType: spm.ModelBPE,
+			UnkID:     ggufTokenId(f, "tokenizer.ggml.unknown_token_id", tokens),
+			BosID:     ggufTokenId(f, "tokenizer.ggml.bos_token_id", tokens),
+			EosID:     ggufTokenId(f, "tokenizer.ggml.eos_token_id", tokens),
+			PadID:     ggufTokenId(f, "tokenizer.ggml.padding_token_id", tokens),
+		},
+		NormalizerSpec: spm.NormalizerSpec{
+			AddDummyPrefix:    true,
+			EscapeWhitespaces: true,
+		},
+	}
+	if kind == "t5" {
+		m.TrainerSpec.ModelType = spm.ModelUnigram
+	}
+	for i, tok := range tokens {
+		m.Pieces[i] = spm.Piece{Piece: tok, Score: scores[i], Type: types[i]}
+		switch types[i] {
+		case spm.PieceByte:
+			m.TrainerSpec.ByteFallback = true
+		case spm.PieceUnknown:
+			if m.TrainerSpec.UnkID < 0 {
+				m.TrainerSpec.UnkID = i
+			}
+		}
+	}
+	if kind == "t5" && m.TrainerSpec.UnkID < 0 {
+		return nil, fmt.Errorf("FromGGUF error: missing unknown token of Unigram model")
+	}
+
+	if v, ok := f.Bool("tokenizer.ggml.add_space_prefix"); ok {
+		m.NormalizerSpec.AddDummyPrefix = v
+	}
+	if v, ok := f.Bool("tokenizer.ggml.remove_extra_whitespaces"); ok {
+		m.NormalizerSpec.RemoveExtraWhitespaces = v
+	}
+	if charsmap, ok := f.Bytes("tokenizer.ggml.precompiled_charsmap"); ok {
+		m.NormalizerSpec.PrecompiledCharsmap = charsmap
+	}
+
+	tk, err := FromSentencePieceModel(m)
+	if err != nil {
+		return nil, fmt.Errorf("FromGGUF error: %w", err)
+	}
+
+	return tk, nil
+}
+
+// ggufByteLevelBPE builds the byte-level BPE tokenizer of the "gpt2" tokenizers,
+// whose tokens are already mapped to byte-level chars.
+func ggufByteLevelBPE(f *gguf.File, tokens []string, types []spm.PieceType) (*tokenizer.Tokenizer, error) {
+	merges, ok := f.Strings("tokenizer.ggml.merges")
+	if !ok {
+		return nil, fmt.Errorf("FromGGUF error: missing tokenizer.ggml.merges")
+	}
+	pre, _ := f.String("tokenizer.ggml.pre")
+	pattern, ok := ggufPreTokenizerPatterns[pre]
+	if !ok {
+		return nil, fmt.Errorf("FromGGUF error: unsupported pre-tokenizer %q", pre)
+	}
+
+	vocab := make(map[string]int, len(tokens))
+	for i, tok := range tokens {
+		vocab[tok] = i
+	}
+	model, err := bpe.New(vocab, merges)
+	if err != nil {
+		return nil, fmt.Errorf("FromGGUF error: %w", err)
+	}
+
+	var pretok tokenizer.PreTokenizer
+	if pattern == pretokenizer.R50kPattern {
+		bl := pretokenizer.NewByteLevel()
+		bl.AddPrefixSpace = false
+		pretok = bl
+	} else {
+		pretok, err = pretokenizer.NewTiktoken(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithPreTokenizer(pretok)
+	tk.WithDecoder(pretokenizer.NewByteLevel())
+
+	var specialToks, addedToks []tokenizer.AddedToken
+	for i, tok := range tokens {
+		switch types[i] {
+		case spm.PieceControl, spm.PieceUnknown:
+			specialToks = append(specialToks, tokenizer.NewAddedToken(tok, true, tokenizer.WithNormalized(false)))
+		case spm.PieceUserDefined:
+			addedToks = append(addedToks, tokenizer.NewAddedToken(tok, false, tokenizer.WithNormalized(false)))
+		}
+	}
+	if len(specialToks) > 0 {
+		tk.AddSpecialTokens(specialToks)
+	}
+	if len(addedToks) > 0 {
+		tk.AddTokens(addedToks)
+	}
+
+	return tk, nil
+}
+
+// bosEosTemplateProcessing adds the BOS and EOS tokens of non-negative ids
+// around each sequence, as HuggingFace LLaMA tokenizers do.
+func bosEosTemplateProcessing(bosToken string, bos int, eosToken string, eos int) (*processor.TemplateProcessing, error) {
+	template := func(seq string, typeId string) []string {
+		var pieces []string
+		if bos >= 0 {
+			pieces = append(pieces, bosToken+typeId)
+		}
+		pieces = append(pieces, seq+typeId)
+		if eos >= 0 {
+			pieces = append(pieces, eosToken+typeId)
+		}
+		return pieces
+	}
+
+	var specials []processor.SpecialToken
+	if bos >= 0 {
+		specials = append(specials, *processor.NewSpecialTokenFrom(bosToken, bos))
+	}
+	if eos >= 0 && eos != bos {
+		specials = append(specials, *processor.NewSpecialTokenFrom(eosToken, eos))
+	}
+
+	single, err := processor.NewTemplateFromMulti(template("$A", ""))
+	if err != nil {
+		return nil, err
+	}
+	pair, err := processor.NewTemplateFromMulti(append(template("$A", ""), template("$B", ":1")...))
+	if err != nil {
+		return nil, err
+	}
+
+	return processor.NewTemplateProcessing(single, pair, processor.NewTokensFrom(specials)), nil
+}
@@ -0,0 +1,209 @@
+package pretrained
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/gguf"
+	"github.com/season-studio/tokenizer/spm"
+)
+
+// ggufKV is a metadata key-value of a GGUF fixture. Values are string, uint32,
+// bool, []string, []float32 or []int32.
+type ggufKV struct {
+	key   string
+	value interface{}
+}
+
+// writeGGUF writes a little-endian GGUF v3 file of the metadata, without
+// tensors.
+func writeGGUF(t *testing.T, kvs []ggufKV) string {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	buf.WriteString("GGUF")
+	binary.Write(&buf, le, uint32(3))
+	binary.Write(&buf, le, uint64(0))
+	binary.Write(&buf, le, uint64(len(kvs)))
+
+	writeString := func(s string) {
+		binary.Write(&buf, le, uint64(len(s)))
+		buf.WriteString(s)
+	}
+	for _, kv := range kvs {
+		writeString(kv.key)
+		switch v := kv.value.(type) {
+		case string:
+			binary.Write(&buf, le, uint32(gguf.TypeString))
+			writeString(v)
+		case uint32:
+			binary.Write(&buf, le, uint32(gguf.TypeUint32))
+			binary.Write(&buf, le, v)
+		case bool:
+			binary.Write(&buf, le, uint32(gguf.TypeBool))
+			binary.Write(&buf, le, v)
+		case []string:
+			binary.Write(&buf, le, uint32(gguf.TypeArray))
+			binary.Write(&buf, le, uint32(gguf.TypeString))
+			binary.Write(&buf, le, uint64(len(v)))
+			for _, s := range v {
+				writeString(s)
+			}
+		case []float32:
+			binary.Write(&buf, le, uint32(gguf.TypeArray))
+			binary.Write(&buf, le, uint32(gguf.TypeFloat32))
+			binary.Write(&buf, le, uint64(len(v)))
+			binary.Write(&buf, le, v)
+		case []int32:
+			binary.Write(&buf, le, uint32(gguf.TypeArray))
+			binary.Write(&buf, le, uint32(gguf.TypeInt32))
+			binary.Write(&buf, le, uint64(len(v)))
+			binary.Write(&buf, le, v)
+		default:
+			t.Fatalf("unsupported value %T of %q", v, kv.key)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "model.gguf")
+	if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestFromGGUF_Llama(t *testing.T) {
+	pieces := append(spmSpecialPieces(),
+		spm.Piece{Piece: "▁h", Score: -1, Type: spm.PieceNormal},
+		spm.Piece{Piece: "ll", Score: -2, Type: spm.PieceNormal},
+		spm.Piece{Piece: "▁he", Score: -3, Type: spm.PieceNormal},
+		spm.Piece{Piece: "llo", Score: -4, Type: spm.PieceNormal},
+		spm.Piece{Piece: "▁hello", Score: -5, Type: spm.PieceNormal},
+		spm.Piece{Piece: "<0x21>", Type: spm.PieceByte},
+	)
+	for _, r := range "▁helo" {
+		pieces = append(pieces, spm.Piece{Piece: string(r), Score: -10, Type: spm.PieceNormal})
+	}
+	var (
+		tokens []string
+		scores []float32
+		types  []int32
+	)
+	for _, p := range pieces {
+		tokens = append(tokens, p.Piece)
+		scores = append(scores, p.Score)
+		types = append(types, int32(p.Type))
+	}
+
+	file := writeGGUF(t, []ggufKV{
+		{"general.architecture", "llama"},
+		{"tokenizer.ggml.model", "llama"},
+		{"tokenizer.ggml.tokens", tokens},
+		{"tokenizer.ggml.scores", scores},
+		{"tokenizer.ggml.token_type", types},
+		{"tokenizer.ggml.bos_token_id", uint32(1)},
+		{"tokenizer.ggml.eos_token_id", uint32(2)},
+		{"tokenizer.ggml.unknown_token_id", uint32(0)},
+		{"tokenizer.chat_template", "{{ bos_token }}{% for m in messages %}{{ m['content'] }}{{ eos_token }}{% endfor %}"},
+	})
+
+	tk, err := FromGGUF(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// BOS is added by default, EOS is not.
+	en, err := tk.EncodeSingle("hello hell!??<sep>", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTokens := []string{"<s>", "▁hello", "▁he", "ll", "<0x21>", "<unk>", "<sep>"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want tokens %q, got %q", wantTokens, en.Tokens)
+	}
+	if got := tk.Decode(en.Ids, true); got != "hello hell!<sep>" {
+		t.Errorf("want %q, got %q", "hello hell!<sep>", got)
+	}
+
+	got, err := tk.ApplyChatTemplate([]tokenizer.ChatMessage{{Role: "user", Content: "hi"}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<s>hi</s>"; got != want {
+		t.Errorf("want chat %q, got %q", want, got)
+	}
+}
+
+func TestFromGGUF_GPT2(t *testing.T) {
+	tokens := []string{"h", "e", "l", "o", "Ġ", "he", "ll", "hell", "Ġhell", "<|begin_of_text|>", "<|end_of_text|>", "<tool>"}
+	types := make([]int32, len(tokens))
+	for i := range types {
+		types[i] = int32(spm.PieceNormal)
+	}
+	types[9], types[10], types[11] = int32(spm.PieceControl), int32(spm.PieceControl), int32(spm.PieceUserDefined)
+
+	file := writeGGUF(t, []ggufKV{
+		{"tokenizer.ggml.model", "gpt2"},
+		{"tokenizer.ggml.pre", "llama-bpe"},
+		{"tokenizer.ggml.tokens", tokens},
+		{"tokenizer.ggml.token_type", types},
+		{"tokenizer.ggml.merges", []string{"h e", "l l", "he ll", "Ġ hell"}},
+		{"tokenizer.ggml.bos_token_id", uint32(9)},
+		{"tokenizer.ggml.eos_token_id", uint32(10)},
+		{"tokenizer.ggml.add_bos_token", true},
+	})
+
+	tk, err := FromGGUF(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	en, err := tk.EncodeSingle("hello hell<tool><|end_of_text|>", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIds := []int{9, 7, 3, 8, 11, 10}
+	if !reflect.DeepEqual(wantIds, en.Ids) {
+		t.Errorf("want ids %v, got %v (%q)", wantIds, en.Ids, en.Tokens)
+	}
+	if got := tk.Decode(en.Ids, true); got != "hello hell<tool>" {
+		t.Errorf("want %q, got %q", "hello hell<tool>", got)
+	}
+
+	en, err = tk.EncodePair("hell", "hell", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{9, 7, 9, 7}; !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want pair ids %v, got %v", want, en.Ids)
+	}
+	if want := []int{0, 0, 1, 1}; !reflect.DeepEqual(want, en.TypeIds) {
+		t.Errorf("want pair type ids %v, got %v", want, en.TypeIds)
+	}
+}
+
+func TestFromGGUF_Invalid(t *testing.T) {
+	tokens := []string{"<unk>", "a"}
+	tests := []struct {
+		name string
+		kvs  []ggufKV
+		want string
+	}{
+		{"no tokens", []ggufKV{{"tokenizer.ggml.model", "llama"}}, "missing tokenizer.ggml.tokens"},
+		{"model", []ggufKV{{"tokenizer.ggml.model", "rwkv"}, {"tokenizer.ggml.tokens", tokens}}, "unsupported tokenizer model \"rwkv\""},
+		{"scores", []ggufKV{{"tokenizer.ggml.model", "t5"}, {"tokenizer.ggml.tokens", tokens}}, "tokenizer.ggml.scores"},
+		{"types", []ggufKV{{"tokenizer.ggml.model", "llama"}, {"tokenizer.ggml.tokens", tokens}, {"tokenizer.ggml.token_type", []int32{1}}}, "got 1 token types for 2 tokens"},
+		{"pre", []ggufKV{{"tokenizer.ggml.model", "gpt2"}, {"tokenizer.ggml.tokens", tokens}, {"tokenizer.ggml.merges", []string{}}, {"tokenizer.ggml.pre", "unknown"}}, "unsupported pre-tokenizer \"unknown\""},
+	}
+
+	for _, tt := range tests {
+		_, err := FromGGUF(writeGGUF(t, tt.kvs))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: want error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package pretrained
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/season-studio/tokenizer"
+)
+
+// Files downloaded by `FromHub`. Only `tokenizer.json` is required.
+const (
+	TokenizerConfigName  = "tokenizer_config.json"
+	SpecialTokensMapName = "special_tokens_map.json"
+)
+
+// HubOpts are the options of `FromHub`, see `DefaultHubOpts`.
+type HubOpts struct {
+	Revision string       // branch, tag or commit to download
+	Token    string       // access token of private and gated models
+	CacheDir string       // download cache directory, `tokenizer.CachedDir` if empty
+	Endpoint string       // Hub URL, `tokenizer.HFpath` if empty
+	Client   *http.Client // HTTP client, `http.DefaultClient` if nil
+}
+
+// HubOption sets an option of `FromHub`.
+type HubOption func(o *HubOpts)
+
+// WithRevision pins the branch, tag or commit to download, "main" by default.
+func WithRevision(revision string) HubOption {
+	return func(o *HubOpts) {
+		o.Revision = revision
+	}
+}
+
+// WithAuthToken sets the Hub access token of private and gated models. It
+// defaults to the `HF_TOKEN` environment variable.
+func WithAuthToken(token string) HubOption {
+	return func(o *HubOpts) {
+		o.Token = token
+	}
+}
+
+// WithCacheDir sets the download cache directory, `tokenizer.CachedDir` by
+// default.
+func WithCacheDir(dir string) HubOption {
+	return func(o *HubOpts) {
+		o.CacheDir = dir
+	}
+}
+
+// WithHubEndpoint sets the Hub URL, i.e. a mirror. It defaults to the
+// `HF_ENDPOINT` environment variable, or `tokenizer.HFpath`.
+func WithHubEndpoint(endpoint string) HubOption {
+	return func(o *HubOpts) {
+		o.Endpoint = endpoint
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to download files.
+func WithHTTPClient(client *http.Client) HubOption {
+	return func(o *HubOpts) {
+		o.Client = client
+	}
+}
+
+// DefaultHubOpts returns the default options of `FromHub`: the "main"
+// revision, and the token and endpoint of the `HF_TOKEN` (or
+// `HUGGING_FACE_HUB_TOKEN`) and `HF_ENDPOINT` environment variables.
+func DefaultHubOpts() *HubOpts {
+	token := os.Getenv("HF_TOKEN")
+	if token == "" {
+		token = os.Getenv("HUGGING_FACE_HUB_TOKEN")
+	}
+
+	return &HubOpts{
+		Revision: "main",
+		Token:    token,
+		Endpoint: os.Getenv("HF_ENDPOINT"),
+	}
+}
+
+// FromHub constructs a Tokenizer from a Hugging Face Hub model ID, i.e.
+// "bert-base-uncased". It downloads the `tokenizer.json` file of the model to
+// the cache, along with its `tokenizer_config.json` and
+// `special_tokens_map.json` files when it has them, which are applied to the
+// tokenizer as AutoTokenizer does, see `TokenizerConfig.Apply`. Cached files
+// are not downloaded again. The `chat_template` of
+// `tokenizer_config.json` is set as the chat template of the tokenizer.
+func FromHub(modelID string, opts ...HubOption) (*tokenizer.Tokenizer, error) {
+	o := DefaultHubOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+	fileOpts := tokenizer.HubFileOptions{
+		Revision: o.Revision,
+		Token:    o.Token,
+		CacheDir: o.CacheDir,
+		Endpoint: o.Endpoint,
+		Client:   o.Client,
+	}
+
+	file, err := tokenizer.CachedHubFile(modelID, tokenizer.TokenizerName, fileOpts)
+	if err != nil {
+		return nil, fmt.Errorf("FromHub(%q): %w", modelID, err)
+	}
+	tk, err := FromFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("FromHub(%q): %w", modelID, err)
+	}
+
+	var files []string
+	for _, name := range []string{TokenizerConfigName, SpecialTokensMapName} {
+		file, err := tokenizer.CachedHubFile(modelID, name, fileOpts)
+		if errors.Is(err, tokenizer.ErrHubFileNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("FromHub(%q): %w", modelID, err)
+		}
+		files = append(files, file)
+	}
+	if err := applyConfigFiles(tk, files); err != nil {
+		return nil, fmt.Errorf("FromHub(%q): %w", modelID, err)
+	}
+
+	return tk, nil
+}
+
+// LoadChatTemplate loads the `chat_template` of a `tokenizer_config.json` file,
+// nil if it has none. Of a list of named templates, the "default" one is
+// loaded. The `*_token` entries of the file, i.e. `bos_token`, are set as
+// variables of the template.
+func LoadChatTemplate(file string) (*tokenizer.ChatTemplate, error) {
+	config, err := readJSONConfig(file)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := chatTemplateSource(config["chat_template"])
+	if err != nil {
+		return nil, fmt.Errorf("Read %v error: chat_template: %w", filepath.Base(file), err)
+	}
+	if source == "" {
+		return nil, nil
+	}
+
+	template, err := tokenizer.NewChatTemplate(source)
+	if err != nil {
+		return nil, fmt.Errorf("Read %v error: %w", filepath.Base(file), err)
+	}
+	for key, value := range config {
+		if !strings.HasSuffix(key, "_token") {
+			continue
+		}
+		if tok := specialTokenContent(value); tok != "" {
+			if err := template.WithVariable(key, tok); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return template, nil
+}
+
+// chatTemplateSource returns the source of a `chat_template` entry, either a
+// string or a list of `{"name": ..., "template": ...}` objects.
+func chatTemplateSource(value json.RawMessage) (string, error) {
+	if len(value) == 0 || string(value) == "null" {
+		return "", nil
+	}
+
+	var source string
+	if err := json.Unmarshal(value, &source); err == nil {
+		return source, nil
+	}
+
+	var named []struct {
+		Name     string `json:"name"`
+		Template string `json:"template"`
+	}
+	if err := json.Unmarshal(value, &named); err != nil {
+		return "", fmt.Errorf("want a string or a list of named templates")
+	}
+	for _, t := range named {
+		if t.Name == "default" {
+			return t.Template, nil
+		}
+	}
+
+	return "", fmt.Errorf("no \"default\" template")
+}
+
+// readJSONConfig reads the entries of a JSON object file.
+func readJSONConfig(file string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("Read %v error: %w", filepath.Base(file), err)
+	}
+
+	return config, nil
+}
+
+func specialTokenContent(value json.RawMessage) string {
+	var content string
+	if err := json.Unmarshal(value, &content); err == nil {
+		return content
+	}
+	var tok struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(value, &tok); err == nil {
+		return tok.Content
+	}
+
+	return ""
+}
+
+// addSpecialTokens registers the given vocab tokens not yet special as special
+// tokens of tk.
+func addSpecialTokens(tk *tokenizer.Tokenizer, tokens []string) {
+	vocab := tk.GetVocab(true)
+	specials := make(map[string]bool)
+	for _, tok := range tk.GetSpecialTokens() {
+		specials[tok] = true
+	}
+
+	var addedToks []tokenizer.AddedToken
+	for _, tok := range tokens {
+		if _, ok := vocab[tok]; ok && !specials[tok] {
+			specials[tok] = true
+			addedToks = append(addedToks, tokenizer.NewAddedToken(tok, true))
+		}
+	}
+	if len(addedToks) > 0 {
+		tk.AddSpecialTokens(addedToks)
+	}
+}
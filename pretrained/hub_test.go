@@ -0,0 +1,115 @@
+package pretrained
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+)
+
+func TestFromHub(t *testing.T) {
+	files := map[string]string{
+		"/org/model/resolve/v1.0/tokenizer.json":          serializationConfig,
+		"/org/model/resolve/v1.0/tokenizer_config.json":   `{"add_bos_token": true, "unk_token": {"content": "[UNK]", "lstrip": false}, "pad_token": null, "model_max_length": 512, "chat_template": "{{ unk_token }}{% for m in messages %}{{ m.content }}{% endfor %}"}`,
+		"/org/model/resolve/v1.0/special_tokens_map.json": `{"cls_token": "[CLS]", "additional_special_tokens": ["hello", "<missing>"]}`,
+	}
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("want auth header, got %q", got)
+		}
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer srv.Close()
+
+	cacheDir := t.TempDir()
+	opts := []HubOption{WithHubEndpoint(srv.URL), WithRevision("v1.0"), WithAuthToken("secret"), WithCacheDir(cacheDir)}
+	tk, err := FromHub("org/model", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := tk.GetSpecialTokens()
+	sort.Strings(got)
+	if want := []string{"[CLS]", "[PAD]", "[SEP]", "[UNK]", "hello"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want special tokens %q, got %q", want, got)
+	}
+	if got := tk.Decode([]int{2, 4, 5, 3}, true); got != "world" {
+		t.Errorf("want %q, got %q", "world", got)
+	}
+	prompt, err := tk.ApplyChatTemplate([]tokenizer.ChatMessage{{Role: "user", Content: "hello"}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[UNK]hello"; prompt != want {
+		t.Errorf("want prompt %q, got %q", want, prompt)
+	}
+
+	// Cached files are not downloaded again.
+	n := len(requests)
+	if _, err := FromHub("org/model", opts...); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != n {
+		t.Errorf("want cached files, got requests %q", requests[n:])
+	}
+}
+
+func TestFromHub_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	_, err := FromHub("org/missing", WithHubEndpoint(srv.URL), WithCacheDir(t.TempDir()))
+	if err == nil || !strings.Contains(err.Error(), "tokenizer.json") {
+		t.Errorf("want tokenizer.json not found error, got %v", err)
+	}
+}
+
+func TestLoadChatTemplate(t *testing.T) {
+	dir := t.TempDir()
+	configs := map[string]string{
+		"string.json": `{"bos_token": {"content": "<s>"}, "eos_token": "</s>", "chat_template": "{{ bos_token }}{% for m in messages %}{{ m.content }}{% endfor %}{{ eos_token }}"}`,
+		"named.json":  `{"bos_token": "<s>", "eos_token": "</s>", "chat_template": [{"name": "tool_use", "template": "tools"}, {"name": "default", "template": "{{ bos_token }}{{ messages[0].content }}{{ eos_token }}"}]}`,
+		"none.json":   `{"bos_token": "<s>"}`,
+		"bad.json":    `{"chat_template": [{"name": "tool_use", "template": "tools"}]}`,
+	}
+	for name, data := range configs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages := []tokenizer.ChatMessage{{Role: "user", Content: "hi"}}
+	for _, name := range []string{"string.json", "named.json"} {
+		template, err := LoadChatTemplate(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		got, err := template.Render(map[string]interface{}{"messages": messages})
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if want := "<s>hi</s>"; got != want {
+			t.Errorf("%v: want %q, got %q", name, want, got)
+		}
+	}
+
+	if template, err := LoadChatTemplate(filepath.Join(dir, "none.json")); template != nil || err != nil {
+		t.Errorf("want no template, got %v, %v", template, err)
+	}
+	if _, err := LoadChatTemplate(filepath.Join(dir, "bad.json")); err == nil {
+		t.Errorf("want no default template error, got nil")
+	}
+}
@@ -1,12 +1,15 @@
 package pretrained
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 
 	"github.com/season-studio/tokenizer"
 	"github.com/season-studio/tokenizer/model"
 	"github.com/season-studio/tokenizer/model/bpe"
+	"github.com/season-studio/tokenizer/model/bytelevel"
+	"github.com/season-studio/tokenizer/model/charlevel"
 	"github.com/season-studio/tokenizer/model/unigram"
 	"github.com/season-studio/tokenizer/model/wordlevel"
 	"github.com/season-studio/tokenizer/model/wordpiece"
@@ -16,6 +19,13 @@ import (
 // This file provides functions to create tokenizer.Model from input data.
 
 func CreateModel(config *tokenizer.Config) (tokenizer.Model, error) {
+	return createModel(config, nil)
+}
+
+// createModel creates the model of config. compiled, if not nil, is the
+// compiled vocab of a BPE, WordPiece, WordLevel or Unigram model, which
+// replaces the vocab and merges of config, see `CompileVocab`.
+func createModel(config *tokenizer.Config, compiled []byte) (tokenizer.Model, error) {
 	if config == nil {
 		return nil, nil
 	}
@@ -24,26 +34,28 @@ func CreateModel(config *tokenizer.Config) (tokenizer.Model, error) {
 
 	var typ string
 	if params.Has("type") {
-		typ = params.Get("type").(string)
+		v, ok := params.Get("type").(string)
+		if !ok {
+			return nil, configErrorf("model.type", "want string, got %T", params.Get("type"))
+		}
+		typ = v
 	} else {
 		// Guessing from `decoder.type`
 		dparams := util.NewParams(config.Decoder)
-		if dparams.Has("type") {
-			dtyp := dparams.Get("type").(string)
-			switch dtyp {
-			case "ByteLevel":
-				typ = "BPE"
-			case "WordPiece":
-				typ = "WordPiece"
-			case "WordLevel":
-				typ = "WordLevel"
-			case "Unigram":
-				typ = "Unigram"
-			default: // default to "BPE"
-			}
+		dtyp, _ := dparams.Get("type", "").(string)
+		switch dtyp {
+		case "ByteLevel":
+			typ = "BPE"
+		case "WordPiece":
+			typ = "WordPiece"
+		case "WordLevel":
+			typ = "WordLevel"
+		case "Unigram":
+			typ = "Unigram"
+		default: // default to "BPE"
 		}
 		if typ == "" {
-			log.Printf("INFO: there is no field 'type' in model json data, a default 'BPE' model will be trying to create...\n")
+			util.Logf("INFO: there is no field 'type' in model json data, a default 'BPE' model will be trying to create...\n")
 			// typ = "WordPiece" // Default to `WordPiece` model as in BERT "tokenizer.json", there's not field "type"
 			typ = "BPE" // Default to `WordPiece` model as in BERT "tokenizer.json", there's not field "type"
 		}
@@ -51,17 +63,20 @@ func CreateModel(config *tokenizer.Config) (tokenizer.Model, error) {
 
 	switch typ {
 	case "BPE":
-		return createBPE(params)
+		return createBPE(params, compiled)
 	case "WordPiece":
-		return createWordPiece(params)
+		return createWordPiece(params, compiled)
 	case "WordLevel":
-		return createWordLevel(params)
+		return createWordLevel(params, compiled)
 	case "Unigram":
-		return createUnigram(params)
+		return createUnigram(params, compiled)
+	case "CharLevel":
+		return createCharLevel(params)
+	case "ByteLevel":
+		return createByteLevelModel(params)
 
 	default:
-		err := fmt.Errorf("Could not construct tokenizer.Model from input data: %#v\n", config)
-		return nil, err
+		return nil, configErrorf("model.type", "%w %q", tokenizer.ErrUnknownModelType, typ)
 	}
 }
 
@@ -77,39 +92,66 @@ func CreateModel(config *tokenizer.Config) (tokenizer.Model, error) {
 // "vocab": {}
 // "merges": []
 
-func createBPE(params *util.Params) (tokenizer.Model, error) {
-	var dropout *float32
-	if params.Has("dropout") {
-		val := float32(params.Get("dropout").(float64))
-		dropout = &val
+type bpeModelConfig struct {
+	Dropout                 *float64          `json:"dropout"`
+	UnkToken                *string           `json:"unk_token"`
+	ContinuingSubwordPrefix *string           `json:"continuing_subword_prefix"`
+	EndOfWordSuffix         *string           `json:"end_of_word_suffix"`
+	FuseUnk                 bool              `json:"fuse_unk"`
+	ByteFallback            bool              `json:"byte_fallback"`
+	Vocab                   model.Vocab       `json:"vocab"`
+	Merges                  []json.RawMessage `json:"merges"`
+}
+
+func createBPE(params *util.Params, compiled []byte) (tokenizer.Model, error) {
+	var config bpeModelConfig
+	if err := decodeConfig("model", params, &config); err != nil {
+		return nil, err
+	}
+	if config.Vocab == nil && compiled == nil {
+		return nil, configErrorf("model.vocab", "missing")
 	}
 
-	var unkToken *string
-	if params.Has("unk_token") {
-		v := params.Get("unk_token").(string)
-		unkToken = &v
+	var opts []bpe.Option
+	if config.Dropout != nil {
+		p := *config.Dropout
+		if p < 0 || p > 1 {
+			return nil, configErrorf("model.dropout", "want a value in [0, 1], got %v", p)
+		}
+		// 0 means no dropout.
+		if p > 0 {
+			opts = append(opts, bpe.WithDropout(float32(p)))
+		}
+	}
+	if config.UnkToken != nil {
+		opts = append(opts, bpe.WithUnkToken(*config.UnkToken))
+	}
+	if config.ContinuingSubwordPrefix != nil {
+		opts = append(opts, bpe.WithContinuingSubwordPrefix(*config.ContinuingSubwordPrefix))
 	}
-	var continuingSubwordPrefix *string
-	if params.Has("continuing_subword_prefix") {
-		v := params.Get("continuing_subword_prefix").(string)
-		continuingSubwordPrefix = &v
+	if config.EndOfWordSuffix != nil {
+		opts = append(opts, bpe.WithEndOfWordSuffix(*config.EndOfWordSuffix))
+	}
+	opts = append(opts, bpe.WithFuseUnk(config.FuseUnk), bpe.WithByteFallback(config.ByteFallback))
+	if compiled != nil {
+		return bpe.LoadCompiled(compiled, opts...)
 	}
 
-	var endOfWordSuffix *string
-	if params.Has("end_of_word_suffix") {
-		v := params.Get("end_of_word_suffix").(string)
-		endOfWordSuffix = &v
+	merges, err := castMerge(config.Merges)
+	if err != nil {
+		return nil, err
 	}
-	// fuseUnk := params.Get("use_unk").(bool)
-	// byteFallback := params.Get("byte_fallback").(bool)
 
-	vocab := castVocab(params.Get("vocab").(map[string]interface{}))
-	merges, err := castMerge(params.Get("merges").([]interface{}))
+	m, err := bpe.New(config.Vocab, merges, opts...)
 	if err != nil {
+		var mergeErr *tokenizer.MergeError
+		if errors.As(err, &mergeErr) {
+			return nil, &ConfigError{Field: fmt.Sprintf("model.merges[%d]", mergeErr.Line), Err: err}
+		}
 		return nil, err
 	}
 
-	return bpe.New(vocab, merges, dropout, unkToken, continuingSubwordPrefix, endOfWordSuffix)
+	return m, nil
 }
 
 // WordPiece json format:
@@ -120,119 +162,195 @@ func createBPE(params *util.Params) (tokenizer.Model, error) {
 // "vocab": {}
 // "decoder":{"type":"WordPiece","prefix":"##","cleanup":true},
 
-func createWordPiece(params *util.Params) (tokenizer.Model, error) {
-	opts := util.NewParams(nil)
-	if params.Has("unk_token") {
-		v := params.Get("unk_token").(string)
-		opts.Set("unk_token", v)
+type wordPieceModelConfig struct {
+	UnkToken                *string     `json:"unk_token"`
+	ContinuingSubwordPrefix *string     `json:"continuing_subword_prefix"`
+	MaxInputCharsPerWord    *int        `json:"max_input_chars_per_word"`
+	Vocab                   model.Vocab `json:"vocab"`
+}
+
+func createWordPiece(params *util.Params, compiled []byte) (tokenizer.Model, error) {
+	var config wordPieceModelConfig
+	if err := decodeConfig("model", params, &config); err != nil {
+		return nil, err
 	}
-	if params.Has("continuing_subword_prefix") {
-		v := params.Get("continuing_subword_prefix").(string)
-		opts.Get("continuing_subword_prefix", v)
+	if compiled != nil {
+		vocab, _, err := model.ReadVocab(compiled)
+		if err != nil {
+			return nil, err
+		}
+		config.Vocab = vocab
+	}
+	if config.Vocab == nil {
+		return nil, configErrorf("model.vocab", "missing")
 	}
 
-	if params.Has("max_input_chars_per_word") {
-		v := int(params.Get("max_input_chars_per_word").(float64))
-		opts.Set("max_input_chars_per_word", v)
+	var opts []wordpiece.Option
+	if config.UnkToken != nil {
+		opts = append(opts, wordpiece.WithUnkToken(*config.UnkToken))
+	}
+	if config.ContinuingSubwordPrefix != nil {
+		opts = append(opts, wordpiece.WithContinuingSubwordPrefix(*config.ContinuingSubwordPrefix))
+	}
+	if config.MaxInputCharsPerWord != nil {
+		if *config.MaxInputCharsPerWord <= 0 {
+			return nil, configErrorf("model.max_input_chars_per_word", "want a positive value, got %v", *config.MaxInputCharsPerWord)
+		}
+		opts = append(opts, wordpiece.WithMaxInputCharsPerWord(*config.MaxInputCharsPerWord))
 	}
 
-	vocab := castVocab(params.Get("vocab").(map[string]interface{}))
+	return wordpiece.New(config.Vocab, opts...)
+}
 
-	return wordpiece.New(vocab, opts)
+type wordLevelModelConfig struct {
+	UnkToken *string     `json:"unk_token"`
+	Vocab    model.Vocab `json:"vocab"`
 }
 
-func createWordLevel(params *util.Params) (tokenizer.Model, error) {
+func createWordLevel(params *util.Params, compiled []byte) (tokenizer.Model, error) {
+	var config wordLevelModelConfig
+	if err := decodeConfig("model", params, &config); err != nil {
+		return nil, err
+	}
+	if compiled != nil {
+		vocab, _, err := model.ReadVocab(compiled)
+		if err != nil {
+			return nil, err
+		}
+		config.Vocab = vocab
+	}
+	if config.Vocab == nil {
+		return nil, configErrorf("model.vocab", "missing")
+	}
+
 	var unkToken string
-	if params.Has("unk_token") {
-		v := params.Get("unk_token").(string)
-		unkToken = v
+	if config.UnkToken != nil {
+		unkToken = *config.UnkToken
 	}
 
-	vocab := castVocab(params.Get("vocab").(map[string]interface{}))
+	return wordlevel.New(config.Vocab, unkToken)
+}
 
-	return wordlevel.New(vocab, unkToken)
+type charLevelModelConfig struct {
+	UnkToken *string     `json:"unk_token"`
+	Vocab    model.Vocab `json:"vocab"`
 }
 
-func createUnigram(params *util.Params) (tokenizer.Model, error) {
-	// Extract parameters from the JSON configuration
-	var unkID *int
-	if params.Has("unk_id") {
-		id := int(params.Get("unk_id").(float64))
-		unkID = &id
+func createCharLevel(params *util.Params) (tokenizer.Model, error) {
+	var config charLevelModelConfig
+	if err := decodeConfig("model", params, &config); err != nil {
+		return nil, err
+	}
+	if config.Vocab == nil {
+		return nil, configErrorf("model.vocab", "missing")
 	}
 
-	bytesFallback := false
-	if params.Has("byte_fallback") {
-		bytesFallback = params.Get("byte_fallback").(bool)
+	var unkToken string
+	if config.UnkToken != nil {
+		unkToken = *config.UnkToken
+		if _, ok := config.Vocab[unkToken]; !ok {
+			return nil, configErrorf("model.unk_token", "%q: %w", unkToken, tokenizer.ErrTokenNotInVocab)
+		}
 	}
 
-	fuseUnk := true
-	if params.Has("fuse_unk") {
-		fuseUnk = params.Get("fuse_unk").(bool)
+	return charlevel.New(config.Vocab, unkToken)
+}
+
+type byteLevelModelConfig struct {
+	Offset int `json:"offset"`
+}
+
+// createByteLevelModel creates the ByteLevel model, not to be confused with
+// the ByteLevel pre-tokenizer.
+func createByteLevelModel(params *util.Params) (tokenizer.Model, error) {
+	var config byteLevelModelConfig
+	if err := decodeConfig("model", params, &config); err != nil {
+		return nil, err
+	}
+	if config.Offset < 0 {
+		return nil, configErrorf("model.offset", "want a non-negative offset, got %d", config.Offset)
 	}
 
-	// Extract the vocabulary
-	var vocab []unigram.TokenScore
-	if params.Has("vocab") {
-		vocabData := params.Get("vocab").([]interface{})
-		vocab = make([]unigram.TokenScore, len(vocabData))
+	return bytelevel.New(config.Offset)
+}
 
-		for i, entry := range vocabData {
-			pair := entry.([]interface{})
-			if len(pair) != 2 {
-				return nil, fmt.Errorf("invalid vocabulary entry format: %v", pair)
-			}
+type unigramModelConfig struct {
+	UnkID        *int              `json:"unk_id"`
+	ByteFallback bool              `json:"byte_fallback"`
+	FuseUnk      *bool             `json:"fuse_unk"`
+	Vocab        []json.RawMessage `json:"vocab"`
+}
 
-			token := pair[0].(string)
-			score := pair[1].(float64)
+func createUnigram(params *util.Params, compiled []byte) (tokenizer.Model, error) {
+	var config unigramModelConfig
+	if err := decodeConfig("model", params, &config); err != nil {
+		return nil, err
+	}
 
-			vocab[i] = unigram.TokenScore{
-				Token: token,
-				Score: score,
-			}
+	opts := []unigram.Option{unigram.WithByteFallback(config.ByteFallback)}
+	if config.UnkID != nil {
+		opts = append(opts, unigram.WithUnkID(*config.UnkID))
+	}
+	if config.FuseUnk != nil {
+		opts = append(opts, unigram.WithFuseUnk(*config.FuseUnk))
+	}
+	if compiled != nil {
+		if config.UnkID != nil && *config.UnkID < 0 {
+			return nil, configErrorf("model.unk_id", "want a vocab id, got %d", *config.UnkID)
 		}
-	} else {
-		return nil, fmt.Errorf("unigram model requires a vocabulary")
+		return unigram.LoadCompiled(compiled, opts...)
 	}
 
-	// Create options for the Unigram model
-	opts := util.NewParams(nil)
-	if unkID != nil {
-		opts.Set("unk_id", *unkID)
+	if config.Vocab == nil {
+		return nil, configErrorf("model.vocab", "unigram model requires a vocabulary")
 	}
-	opts.Set("byte_fallback", bytesFallback)
-	opts.Set("fuse_unk", fuseUnk)
 
-	// Create and return the Unigram model
-	return unigram.New(vocab, opts)
-}
+	// Extract the vocabulary
+	vocab := make([]unigram.TokenScore, len(config.Vocab))
+	for i, entry := range config.Vocab {
+		field := fmt.Sprintf("model.vocab[%d]", i)
+		var pair []interface{}
+		if err := json.Unmarshal(entry, &pair); err != nil || len(pair) != 2 {
+			return nil, configErrorf(field, "want [token, score], got %s", entry)
+		}
+		token, ok := pair[0].(string)
+		if !ok {
+			return nil, configErrorf(field, "want string token, got %s", entry)
+		}
+		score, ok := pair[1].(float64)
+		if !ok {
+			return nil, configErrorf(field, "want number score, got %s", entry)
+		}
 
-func castVocab(input map[string]interface{}) model.Vocab {
-	out := make(map[string]int)
-	for k, v := range input {
-		out[k] = int(v.(float64))
+		vocab[i] = unigram.TokenScore{
+			Token: token,
+			Score: score,
+		}
 	}
 
-	return out
+	if config.UnkID != nil && (*config.UnkID < 0 || *config.UnkID >= len(vocab)) {
+		return nil, configErrorf("model.unk_id", "want a vocab id in [0, %d), got %d", len(vocab), *config.UnkID)
+	}
+
+	return unigram.New(vocab, opts...)
 }
 
-func castMerge(input []interface{}) ([]string, error) {
+// castMerge converts the merges of a BPE config, either "a b" strings or
+// ["a", "b"] pairs, to "a b" strings.
+func castMerge(input []json.RawMessage) ([]string, error) {
 	out := make([]string, len(input))
 	for i, v := range input {
-		switch vTyped := v.(type) {
-		case []interface{}:
-			if len(vTyped) != 2 {
-				return nil, fmt.Errorf("invalid merge format: %#v should be of length 2", vTyped)
-			}
-			out[i] = vTyped[0].(string) + " " + vTyped[1].(string)
-		case []string:
-			if len(vTyped) != 2 {
-				return nil, fmt.Errorf("invalid merge format: %#v should be of length 2", vTyped)
-			}
-			out[i] = vTyped[0] + " " + vTyped[1]
-		case string:
-			out[i] = vTyped
+		var merge string
+		if err := json.Unmarshal(v, &merge); err == nil {
+			out[i] = merge
+			continue
+		}
+
+		var pair []string
+		if err := json.Unmarshal(v, &pair); err != nil || len(pair) != 2 {
+			return nil, configErrorf(fmt.Sprintf("model.merges[%d]", i), "%w: want \"a b\" string or [\"a\", \"b\"] pair, got %s", tokenizer.ErrInvalidMergeFormat, v)
 		}
+		out[i] = pair[0] + " " + pair[1]
 	}
 
 	return out, nil
@@ -1,9 +1,14 @@
 package pretrained
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/season-studio/tokenizer"
 	"github.com/season-studio/tokenizer/util"
 )
 
@@ -15,7 +20,7 @@ func TestCreateBPE(t *testing.T) {
 	}
 
 	modelParams := util.NewParams(config.Model)
-	m, err := createBPE(modelParams)
+	m, err := createBPE(modelParams, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -36,7 +41,7 @@ func TestCreateWordPiece(t *testing.T) {
 	}
 
 	modelParams := util.NewParams(config.Model)
-	m, err := createWordPiece(modelParams)
+	m, err := createWordPiece(modelParams, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -68,3 +73,150 @@ func TestCreateModel(t *testing.T) {
 		t.Errorf("want %v, got %v\n", want, got)
 	}
 }
+
+func modelConfig(t *testing.T, data string) *tokenizer.Config {
+	t.Helper()
+
+	var model map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &model); err != nil {
+		t.Fatal(err)
+	}
+
+	return &tokenizer.Config{Model: model}
+}
+
+func TestCreateModel_OffSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int
+	}{
+		{"int dropout", `{"type": "BPE", "dropout": 0, "unk_token": null, "vocab": {"a": 0, "b": 1, "ab": 2}, "merges": ["a b"]}`, 3},
+		{"null fields", `{"type": "BPE", "dropout": null, "continuing_subword_prefix": null, "end_of_word_suffix": null, "vocab": {"a": 0, "b": 1, "ab": 2}, "merges": [["a", "b"]]}`, 3},
+		{"no merges", `{"type": "BPE", "vocab": {"a": 0}}`, 1},
+		{"null unk_token", `{"type": "WordPiece", "unk_token": null, "vocab": {"[UNK]": 0, "a": 1}}`, 2},
+		{"null unk_id", `{"type": "Unigram", "unk_id": null, "vocab": [["a", 0], ["b", -1.5]]}`, 2},
+		{"float vocab id", `{"type": "WordLevel", "unk_token": "a", "vocab": {"a": 0.0, "b": 1}}`, 2},
+		{"char level", `{"type": "CharLevel", "unk_token": null, "vocab": {"a": 0, "b": 1}}`, 2},
+		{"byte level", `{"type": "ByteLevel", "offset": 3}`, 256},
+	}
+
+	for _, tt := range tests {
+		m, err := CreateModel(modelConfig(t, tt.data))
+		if err != nil {
+			t.Errorf("%v: want no error, got %v", tt.name, err)
+			continue
+		}
+		if got := m.GetVocabSize(); got != tt.want {
+			t.Errorf("%v: want %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCreateWordPiece_Prefix(t *testing.T) {
+	m, err := CreateModel(modelConfig(t, `{"type": "WordPiece", "unk_token": "[UNK]", "continuing_subword_prefix": "@@", "vocab": {"[UNK]": 0, "hug": 1, "@@s": 2, "##s": 3}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	toks, err := m.Tokenize("hugs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, tok := range toks {
+		got = append(got, tok.Value)
+	}
+	want := []string{"hug", "@@s"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestCreateModel_Malformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		field string
+	}{
+		{"type", `{"type": 1, "vocab": {}}`, "model.type"},
+		{"unknown type", `{"type": "Foo", "vocab": {}}`, "model.type"},
+		{"string dropout", `{"type": "BPE", "dropout": "0.1", "vocab": {}, "merges": []}`, "model.dropout"},
+		{"dropout range", `{"type": "BPE", "dropout": 2, "vocab": {}, "merges": []}`, "model.dropout"},
+		{"unk_token", `{"type": "BPE", "unk_token": 1, "vocab": {}, "merges": []}`, "model.unk_token"},
+		{"bpe vocab", `{"type": "BPE", "merges": []}`, "model.vocab"},
+		{"vocab id", `{"type": "BPE", "vocab": {"a": "0"}, "merges": []}`, "model.vocab.a"},
+		{"merges", `{"type": "BPE", "vocab": {}, "merges": {}}`, "model.merges"},
+		{"merge pair", `{"type": "BPE", "vocab": {}, "merges": ["a b", ["a"]]}`, "model.merges[1]"},
+		{"merge type", `{"type": "BPE", "vocab": {}, "merges": [1]}`, "model.merges[0]"},
+		{"merge token", `{"type": "BPE", "vocab": {"a": 0, "b": 1}, "merges": ["a b"]}`, "model.merges[0]"},
+		{"wordpiece max chars", `{"type": "WordPiece", "max_input_chars_per_word": "100", "vocab": {}}`, "model.max_input_chars_per_word"},
+		{"wordpiece prefix", `{"type": "WordPiece", "continuing_subword_prefix": false, "vocab": {}}`, "model.continuing_subword_prefix"},
+		{"wordlevel vocab", `{"type": "WordLevel", "unk_token": "a", "vocab": []}`, "model.vocab"},
+		{"unigram vocab", `{"type": "Unigram"}`, "model.vocab"},
+		{"unigram entry", `{"type": "Unigram", "vocab": [["a", 0], ["b"]]}`, "model.vocab[1]"},
+		{"unigram score", `{"type": "Unigram", "vocab": [["a", "0"]]}`, "model.vocab[0]"},
+		{"unigram unk_id", `{"type": "Unigram", "unk_id": 3, "vocab": [["a", 0]]}`, "model.unk_id"},
+		{"fuse_unk", `{"type": "Unigram", "fuse_unk": 1, "vocab": [["a", 0]]}`, "model.fuse_unk"},
+		{"charlevel vocab", `{"type": "CharLevel", "unk_token": "<unk>"}`, "model.vocab"},
+		{"charlevel unk_token", `{"type": "CharLevel", "unk_token": "<unk>", "vocab": {"a": 0}}`, "model.unk_token"},
+		{"bytelevel offset", `{"type": "ByteLevel", "offset": -1}`, "model.offset"},
+	}
+
+	for _, tt := range tests {
+		_, err := CreateModel(modelConfig(t, tt.data))
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("%v: want ConfigError, got %v", tt.name, err)
+			continue
+		}
+		if configErr.Field != tt.field {
+			t.Errorf("%v: want field %q, got %q (%v)", tt.name, tt.field, configErr.Field, err)
+		}
+	}
+}
+
+func TestCreateModel_Errors(t *testing.T) {
+	tests := []struct {
+		data string
+		want error
+	}{
+		{`{"type": "Foo", "vocab": {}}`, tokenizer.ErrUnknownModelType},
+		{`{"type": "CharLevel", "unk_token": "<unk>", "vocab": {"a": 0}}`, tokenizer.ErrTokenNotInVocab},
+		{`{"type": "BPE", "vocab": {}, "merges": [["a"]]}`, tokenizer.ErrInvalidMergeFormat},
+		{`{"type": "BPE", "vocab": {}, "merges": ["a b c"]}`, tokenizer.ErrInvalidMergeFormat},
+		{`{"type": "BPE", "vocab": {"a": 0, "b": 1}, "merges": ["a b"]}`, tokenizer.ErrTokenNotInVocab},
+	}
+
+	for _, tt := range tests {
+		_, err := CreateModel(modelConfig(t, tt.data))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%v: want %v, got %v", tt.data, tt.want, err)
+		}
+	}
+
+	_, err := CreateModel(modelConfig(t, `{"type": "BPE", "vocab": {"a": 0, "b": 1, "c": 2, "ac": 3}, "merges": ["a c", "a b"]}`))
+	var mergeErr *tokenizer.MergeError
+	if !errors.As(err, &mergeErr) || mergeErr.Line != 1 || mergeErr.Merge != "a b" {
+		t.Errorf("want MergeError of merge %q at line 1, got %v", "a b", err)
+	}
+}
+
+type recordLogger []string
+
+func (l *recordLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestCreateModel_Logger(t *testing.T) {
+	var logs recordLogger
+	tokenizer.SetLogger(&logs)
+	defer tokenizer.SetLogger(nil)
+
+	if _, err := CreateModel(modelConfig(t, `{"vocab": {"a": 0}, "merges": []}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || !strings.Contains(logs[0], "default 'BPE' model") {
+		t.Errorf("want a log of the default BPE model, got %q", logs)
+	}
+}
@@ -10,10 +10,12 @@ package pretrained
 // 7. NFKD
 // 8. Sequence
 // 9. Lowercase
-// 10. Nmt (TODO)
+// 10. Nmt
 // 11. Precompiled
 // 12. Replace
 // 13. Prepend
+// 14. BidiControl
+// 15. ByteLevel
 
 import (
 	"fmt"
@@ -32,7 +34,10 @@ func CreateNormalizer(config map[string]interface{}) (normalizer.Normalizer, err
 
 	params := util.NewParams(config)
 
-	typ := params.Get("type").(string)
+	typ, ok := params.Get("type").(string)
+	if !ok {
+		return nil, configErrorf("normalizer.type", "want string, got %T", params.Get("type"))
+	}
 
 	switch typ {
 	case "BertNormalizer":
@@ -59,7 +64,7 @@ func CreateNormalizer(config map[string]interface{}) (normalizer.Normalizer, err
 		return normalizer.Lowercase(), nil
 
 	case "Nmt":
-		return createNmtNormalizer(params)
+		return normalizer.NewNmt(), nil
 
 	case "Precompiled":
 		return createPrecompiledNormalizer(params)
@@ -70,6 +75,12 @@ func CreateNormalizer(config map[string]interface{}) (normalizer.Normalizer, err
 	case "Prepend":
 		return createPrependNormalizer(params)
 
+	case "BidiControl":
+		return createBidiControlNormalizer(params)
+
+	case "ByteLevel":
+		return normalizer.NewByteLevel(), nil
+
 	default:
 		msg := fmt.Errorf("Could not create Normalizer from config: %#v", config)
 		return nil, msg
@@ -83,111 +94,186 @@ func CreateNormalizer(config map[string]interface{}) (normalizer.Normalizer, err
 // "handle_chinese_chars":true
 // "strip_accents":null
 // "lowercase":true
+type bertNormalizerConfig struct {
+	CleanText          *bool `json:"clean_text"`
+	HandleChineseChars *bool `json:"handle_chinese_chars"`
+	StripAccents       *bool `json:"strip_accents"`
+	Lowercase          *bool `json:"lowercase"`
+}
+
+// createBertNormalizer creates the BertNormalizer of params. As in HuggingFace,
+// `strip_accents: null` strips accents only when lowercasing, as uncased BERT
+// models do.
 func createBertNormalizer(params *util.Params) (normalizer.Normalizer, error) {
 	if params == nil {
 		return nil, nil
 	}
 
-	cleanText := params.Get("clean_text", false).(bool)
-	handleChineseChars := params.Get("handle_chinese_chars", false).(bool)
-	stripAccents := params.Get("strip_accents", false).(bool)
-	lowercase := params.Get("lowercase", false).(bool)
+	var config bertNormalizerConfig
+	if err := decodeConfig("normalizer", params, &config); err != nil {
+		return nil, err
+	}
 
-	return normalizer.NewBertNormalizer(cleanText, lowercase, handleChineseChars, stripAccents), nil
+	get := func(v *bool) bool { return v != nil && *v }
+	lowercase := get(config.Lowercase)
+	stripAccents := lowercase
+	if config.StripAccents != nil {
+		stripAccents = *config.StripAccents
+	}
+
+	return normalizer.NewBertNormalizer(get(config.CleanText), lowercase, get(config.HandleChineseChars), stripAccents), nil
 }
 
-func createReplaceNormalizer(params *util.Params) (normalizer.Normalizer, error) {
-	if params == nil {
-		return nil, nil
+type replaceConfig struct {
+	Pattern *struct {
+		String *string `json:"String"`
+		Regex  *string `json:"Regex"`
+	} `json:"pattern"`
+	Content *string `json:"content"`
+}
+
+// createReplace creates the Replace normalizer or decoder of section, i.e.
+// "normalizer". The pattern is either a literal `String` or a `Regex`.
+func createReplace(section string, params *util.Params) (*normalizer.Replace, error) {
+	var config replaceConfig
+	if err := decodeConfig(section, params, &config); err != nil {
+		return nil, err
+	}
+	if config.Content == nil {
+		return nil, configErrorf(section+".content", "missing")
 	}
 
-	var pattern string
-	var patternType normalizer.ReplacePattern
-	patternParams := params.Get("pattern").(map[string]interface{})
-	pparams := util.NewParams(patternParams)
 	switch {
-	case pparams.Has("String"):
-		pattern = pparams.Get("String").(string)
-		patternType = normalizer.String
-
-	case params.Has("Regex"):
-		pattern = pparams.Get("Regex").(string)
-		patternType = normalizer.String
+	case config.Pattern == nil:
+		return nil, configErrorf(section+".pattern", "missing")
+	case config.Pattern.String != nil:
+		return normalizer.NewReplace(normalizer.String, *config.Pattern.String, *config.Content), nil
+	case config.Pattern.Regex != nil:
+		pattern, err := normalizer.CompileRegexpPattern(*config.Pattern.Regex)
+		if err != nil {
+			return nil, configErrorf(section+".pattern.Regex", "%w", err)
+		}
+		return &normalizer.Replace{PatternType: normalizer.Regex, Pattern: pattern, Content: *config.Content}, nil
+	default:
+		return nil, configErrorf(section+".pattern", "want String or Regex pattern")
 	}
+}
 
-	content := params.Get("content").(string)
+func createReplaceNormalizer(params *util.Params) (normalizer.Normalizer, error) {
+	return createReplace("normalizer", params)
+}
 
-	return normalizer.NewReplace(patternType, pattern, content), nil
+// Prepend json data:
+// ------------------
+// "normalizer": {"type": "Prepend", "prepend": "▁"}
+type prependConfig struct {
+	Prepend string `json:"prepend"`
 }
 
 func createPrependNormalizer(params *util.Params) (normalizer.Normalizer, error) {
-	var prepend string
-	if params.Has("prepend") {
-		prepend = params.Get("prepend").(string)
+	var config prependConfig
+	if err := decodeConfig("normalizer", params, &config); err != nil {
+		return nil, err
 	}
 
-	return normalizer.NewPrepend(prepend), nil
+	return normalizer.NewPrepend(config.Prepend), nil
+}
+
+// BidiControl json data:
+// -----------------------
+// "normalizer": {"type": "BidiControl", "mode": "strip"}
+// mode is either "strip" (default) or "isolate".
+type bidiControlConfig struct {
+	Mode *string `json:"mode"`
+}
+
+func createBidiControlNormalizer(params *util.Params) (normalizer.Normalizer, error) {
+	var config bidiControlConfig
+	if err := decodeConfig("normalizer", params, &config); err != nil {
+		return nil, err
+	}
+
+	if config.Mode == nil {
+		return normalizer.NewBidiControl(normalizer.BidiStrip), nil
+	}
+	switch *config.Mode {
+	case "strip":
+		return normalizer.NewBidiControl(normalizer.BidiStrip), nil
+	case "isolate":
+		return normalizer.NewBidiControl(normalizer.BidiIsolate), nil
+	default:
+		return nil, configErrorf("normalizer.mode", "want \"strip\" or \"isolate\", got %q", *config.Mode)
+	}
+}
+
+// Strip json data:
+// ----------------
+// "normalizer": {"type": "Strip", "strip_left": true, "strip_right": false}
+type stripConfig struct {
+	StripLeft  bool `json:"strip_left"`
+	StripRight bool `json:"strip_right"`
 }
 
 func createStripNormalizer(params *util.Params) (normalizer.Normalizer, error) {
-	stripLeft := params.Get("strip_left", false).(bool)
-	stripRight := params.Get("strip_right", false).(bool)
+	var config stripConfig
+	if err := decodeConfig("normalizer", params, &config); err != nil {
+		return nil, err
+	}
 
-	return normalizer.NewStrip(stripLeft, stripRight), nil
+	return normalizer.NewStrip(config.StripLeft, config.StripRight), nil
 }
 
 func createStripAccents(params *util.Params) (normalizer.Normalizer, error) {
 	return normalizer.NewStripAccents(), nil
 }
 
+type precompiledNormalizerConfig struct {
+	PrecompiledCharsmap *string `json:"precompiled_charsmap"`
+}
+
 func createPrecompiledNormalizer(params *util.Params) (normalizer.Normalizer, error) {
-	if params == nil {
-		return nil, nil
+	var config precompiledNormalizerConfig
+	if err := decodeConfig("normalizer", params, &config); err != nil {
+		return nil, err
+	}
+	if config.PrecompiledCharsmap == nil {
+		return nil, configErrorf("normalizer.precompiled_charsmap", "missing")
 	}
 
-	// Get the precompiled data from the parameters
-	var precompiledData []byte
-	if params.Has("precompiled_charsmap") {
-		// The data could be in base64 format
-		dataStr := params.Get("precompiled_charsmap").(string)
-		var err error
-		precompiledData, err = spm.FromBase64(dataStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode precompiled_charsmap from base64: %v", err)
-		}
-	} else {
-		return nil, fmt.Errorf("precompiled_charsmap parameter is required for Precompiled normalizer")
+	// The charsmap is stored in base64.
+	data, err := spm.FromBase64(*config.PrecompiledCharsmap)
+	if err != nil {
+		return nil, configErrorf("normalizer.precompiled_charsmap", "decode base64 error: %w", err)
 	}
 
-	// Create the spm.Precompiled instance
-	spmPrecompiled, err := spm.NewPrecompiledFrom(precompiledData)
+	n, err := normalizer.NewPrecompiled(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create precompiled normalizer: %v", err)
+		return nil, configErrorf("normalizer.precompiled_charsmap", "%w", err)
 	}
 
-	// Create and return the normalizer.Precompiled wrapper
-	return &normalizer.Precompiled{
-		Precompiled: spmPrecompiled,
-	}, nil
+	return n, nil
 }
 
-func createNmtNormalizer(params *util.Params) (normalizer.Normalizer, error) {
-	// TODO
-	panic("NotImplementedError")
+type sequenceNormalizerConfig struct {
+	Normalizers []map[string]interface{} `json:"normalizers"`
 }
 
 func createSequenceNormalizer(params *util.Params) (normalizer.Normalizer, error) {
-	var data []interface{}
-	if params.Has("normalizers") {
-		data = params.Get("normalizers").([]interface{})
+	var config sequenceNormalizerConfig
+	if err := decodeConfig("normalizer", params, &config); err != nil {
+		return nil, err
 	}
 
 	var norms []normalizer.Normalizer
-	for _, d := range data {
-		n, err := CreateNormalizer(d.(map[string]interface{}))
+	for _, d := range config.Normalizers {
+		n, err := CreateNormalizer(d)
 		if err != nil {
 			return nil, err
 		}
+		// Skip `null` entries.
+		if n == nil {
+			continue
+		}
 		norms = append(norms, n)
 	}
 
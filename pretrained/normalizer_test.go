@@ -1,7 +1,14 @@
 package pretrained
 
 import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/season-studio/tokenizer/normalizer"
+	"github.com/season-studio/tokenizer/spm"
 )
 
 func TestCreateSequenceNormalizer(t *testing.T) {
@@ -30,6 +37,50 @@ func TestCreateBertNormalizer(t *testing.T) {
 	}
 }
 
+func TestCreateBertNormalizer_Options(t *testing.T) {
+	tests := []struct {
+		data string
+		want normalizer.BertNormalizer
+	}{
+		{
+			`{"type": "BertNormalizer", "clean_text": true, "handle_chinese_chars": true, "strip_accents": null, "lowercase": true}`,
+			normalizer.BertNormalizer{CleanText: true, HandleChineseChars: true, StripAccents: true, Lowercase: true},
+		},
+		{
+			`{"type": "BertNormalizer", "clean_text": true, "handle_chinese_chars": true, "strip_accents": null, "lowercase": false}`,
+			normalizer.BertNormalizer{CleanText: true, HandleChineseChars: true},
+		},
+		{
+			`{"type": "BertNormalizer", "clean_text": false, "handle_chinese_chars": false, "strip_accents": false, "lowercase": true}`,
+			normalizer.BertNormalizer{Lowercase: true},
+		},
+		{
+			`{"type": "BertNormalizer", "strip_accents": true}`,
+			normalizer.BertNormalizer{StripAccents: true},
+		},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+		n, err := CreateNormalizer(config)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.data, err)
+		}
+		if got := *n.(*normalizer.BertNormalizer); got != tt.want {
+			t.Errorf("%v: want %+v, got %+v", tt.data, tt.want, got)
+		}
+	}
+
+	_, err := CreateNormalizer(map[string]interface{}{"type": "BertNormalizer", "lowercase": "yes"})
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "normalizer.lowercase" {
+		t.Errorf("want ConfigError on %q, got %v", "normalizer.lowercase", err)
+	}
+}
+
 func TestCreateNFCNormalizer(t *testing.T) {
 	modelName := "mosaicml/mpt-7b"
 	config, err := loadConfig(modelName)
@@ -68,3 +119,292 @@ func TestCreatePrecompiledNormalizer(t *testing.T) {
 		panic(err)
 	}
 }
+
+func TestCreateNestedSequenceNormalizer(t *testing.T) {
+	var config map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"type": "Sequence",
+		"normalizers": [
+			{"type": "Sequence", "normalizers": [{"type": "NFC"}, {"type": "Lowercase"}]},
+			null,
+			{"type": "Strip", "strip_left": true, "strip_right": true}
+		]
+	}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := CreateNormalizer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	normalized, err := n.Normalize(normalizer.NewNormalizedFrom("  Hello  "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := normalized.GetNormalized(), "hello"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestCreateSequenceNormalizer_Malformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		field string
+	}{
+		{"normalizers", `{"type": "Sequence", "normalizers": {}}`, "normalizer.normalizers"},
+		{"type", `{"type": "Sequence", "normalizers": [{"lowercase": true}]}`, "normalizer.type"},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := CreateNormalizer(config)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != tt.field {
+			t.Errorf("%v: want ConfigError on %q, got %v", tt.name, tt.field, err)
+		}
+	}
+}
+
+func TestCreatePrecompiledNormalizer_Offline(t *testing.T) {
+	config := map[string]interface{}{
+		"type":                 "Precompiled",
+		"precompiled_charsmap": spm.AsBase64(spm.NmtNfkc()),
+	}
+	n, err := CreateNormalizer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalized, err := n.Normalize(normalizer.NewNormalizedFrom("ﬁ™"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := normalized.GetNormalized(), "fiTM"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	for _, data := range []string{
+		`{"type": "Precompiled"}`,
+		`{"type": "Precompiled", "precompiled_charsmap": 1}`,
+		`{"type": "Precompiled", "precompiled_charsmap": "%%"}`,
+		`{"type": "Precompiled", "precompiled_charsmap": "/wAAAA=="}`,
+	} {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &config); err != nil {
+			t.Fatal(err)
+		}
+		_, err := CreateNormalizer(config)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != "normalizer.precompiled_charsmap" {
+			t.Errorf("%v: want ConfigError on precompiled_charsmap, got %v", data, err)
+		}
+	}
+}
+
+func TestCreateReplaceAndNmtNormalizer(t *testing.T) {
+	var config map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"type": "Sequence",
+		"normalizers": [
+			{"type": "Nmt"},
+			{"type": "NFKC"},
+			{"type": "Replace", "pattern": {"Regex": " {2,}"}, "content": " "},
+			{"type": "Replace", "pattern": {"String": " "}, "content": "▁"}
+		]
+	}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := CreateNormalizer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	normalized, err := n.Normalize(normalizer.NewNormalizedFrom("ﬁne\t\x01  day"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := normalized.GetNormalized(), "fine▁day"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestCreateReplaceNormalizer_Malformed(t *testing.T) {
+	tests := []struct {
+		data  string
+		field string
+	}{
+		{`{"type": "Replace", "content": " "}`, "normalizer.pattern"},
+		{`{"type": "Replace", "pattern": {}, "content": " "}`, "normalizer.pattern"},
+		{`{"type": "Replace", "pattern": {"String": 1}, "content": " "}`, "normalizer.pattern.String"},
+		{`{"type": "Replace", "pattern": {"Regex": "("}, "content": " "}`, "normalizer.pattern.Regex"},
+		{`{"type": "Replace", "pattern": {"String": " "}}`, "normalizer.content"},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+		_, err := CreateNormalizer(config)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != tt.field {
+			t.Errorf("%v: want ConfigError on %q, got %v", tt.data, tt.field, err)
+		}
+	}
+}
+
+func TestCreateStripPrependByteLevelNormalizer(t *testing.T) {
+	tests := []struct {
+		data  string
+		input string
+		want  string
+	}{
+		{`{"type": "Sequence", "normalizers": [
+			{"type": "Strip", "strip_left": true, "strip_right": true},
+			{"type": "Prepend", "prepend": "▁"},
+			{"type": "Replace", "pattern": {"String": " "}, "content": "▁"}
+		]}`, "  Hey you \t", "▁Hey▁you"},
+		{`{"type": "Strip", "strip_left": false, "strip_right": true}`, " Hey ", " Hey"},
+		{`{"type": "Prepend", "prepend": "▁"}`, "", ""},
+		{`{"type": "ByteLevel"}`, "Hé you", "HÃ©Ġyou"},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+		n, err := CreateNormalizer(config)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.data, err)
+		}
+
+		// The serialized normalizer loads back the same normalizer.
+		data, err := json.Marshal(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reloadedConfig map[string]interface{}
+		if err := json.Unmarshal(data, &reloadedConfig); err != nil {
+			t.Fatal(err)
+		}
+		reloaded, err := CreateNormalizer(reloadedConfig)
+		if err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+
+		for _, n := range []normalizer.Normalizer{n, reloaded} {
+			normalized, err := n.Normalize(normalizer.NewNormalizedFrom(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := normalized.GetNormalized(); got != tt.want {
+				t.Errorf("%v: want %q, got %q", tt.data, tt.want, got)
+			}
+		}
+	}
+}
+
+func TestCreateStripPrependNormalizer_Malformed(t *testing.T) {
+	tests := []struct {
+		data  string
+		field string
+	}{
+		{`{"type": "Strip", "strip_left": "yes"}`, "normalizer.strip_left"},
+		{`{"type": "Prepend", "prepend": 1}`, "normalizer.prepend"},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+		_, err := CreateNormalizer(config)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != tt.field {
+			t.Errorf("%v: want ConfigError on %q, got %v", tt.data, tt.field, err)
+		}
+	}
+}
+
+func TestCreateBidiControlNormalizer(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"type": "BidiControl"}`, "ab"},
+		{`{"type": "BidiControl", "mode": "strip"}`, "ab"},
+		{`{"type": "BidiControl", "mode": "isolate"}`, "a \u200e b"},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+		n, err := CreateNormalizer(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		normalized, err := n.Normalize(normalizer.NewNormalizedFrom("a\u200eb"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := normalized.GetNormalized(); got != tt.want {
+			t.Errorf("%v: want %q, got %q", tt.data, tt.want, got)
+		}
+	}
+
+	for _, data := range []string{
+		`{"type": "BidiControl", "mode": 1}`,
+		`{"type": "BidiControl", "mode": "drop"}`,
+	} {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &config); err != nil {
+			t.Fatal(err)
+		}
+		_, err := CreateNormalizer(config)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != "normalizer.mode" {
+			t.Errorf("%v: want ConfigError on %q, got %v", data, "normalizer.mode", err)
+		}
+	}
+}
+
+func TestFromReader_ByteLevelNormalizer(t *testing.T) {
+	config := `{
+  "normalizer": {"type": "ByteLevel"},
+  "pre_tokenizer": {"type": "Split", "pattern": {"Regex": "Ġ?[^Ġ]+"}, "behavior": "Isolated", "invert": false},
+  "decoder": {"type": "ByteLevel", "add_prefix_space": false, "trim_offsets": false, "use_regex": false},
+  "model": {"type": "BPE", "vocab": {"H": 0, "Ã": 1, "©": 2, "Ġ": 3, "y": 4, "o": 5, "u": 6, "Ã©": 7, "Ġy": 8, "Ġyo": 9, "Ġyou": 10},
+    "merges": ["Ã ©", "Ġ y", "Ġy o", "Ġyo u"]}
+}`
+
+	tk, err := FromReader(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	en, err := tk.EncodeSingle("Hé you", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"H", "Ã©", "Ġyou"}; !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want tokens %q, got %q", want, en.Tokens)
+	}
+	if want := [][]int{{0, 1}, {1, 3}, {3, 7}}; !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want offsets %v, got %v", want, en.Offsets)
+	}
+	if got := tk.Decode(en.Ids, false); got != "Hé you" {
+		t.Errorf("want decoded %q, got %q", "Hé you", got)
+	}
+}
@@ -118,11 +118,17 @@ func CreatePaddingParams(config map[string]interface{}) (*tokenizer.PaddingParam
 		token = "[PAD]" // Default pad token
 	}
 
+	var padToMultipleOf int
+	if v, ok := params.Get("pad_to_multiple_of").(float64); ok {
+		padToMultipleOf = int(v)
+	}
+
 	return &tokenizer.PaddingParams{
-		Strategy:  *strategy,
-		Direction: direction,
-		PadId:     id,
-		PadTypeId: typeId,
-		PadToken:  token,
+		Strategy:        *strategy,
+		Direction:       direction,
+		PadToMultipleOf: padToMultipleOf,
+		PadId:           id,
+		PadTypeId:       typeId,
+		PadToken:        token,
 	}, nil
 }
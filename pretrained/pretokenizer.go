@@ -3,7 +3,7 @@ package pretrained
 // This file provides functions to create tokenizer.PreTokenizer
 // 1. BertPreTokenizer
 // 2. ByteLevel
-// 3. Delimiter
+// 3. CharDelimiterSplit (Delimiter)
 // 4. Metaspace
 // 5. Whitespace
 // 6. Sequence
@@ -15,7 +15,6 @@ package pretrained
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/season-studio/tokenizer"
 	"github.com/season-studio/tokenizer/normalizer"
@@ -29,14 +28,17 @@ func CreatePreTokenizer(config map[string]interface{}) (tokenizer.PreTokenizer,
 	}
 
 	params := util.NewParams(config)
-	typ := params.Get("type").(string)
+	typ, ok := params.Get("type").(string)
+	if !ok {
+		return nil, configErrorf("pre_tokenizer.type", "want string, got %T", params.Get("type"))
+	}
 
 	switch typ {
 	case "BertPreTokenizer":
 		return pretokenizer.NewBertPreTokenizer(), nil
 	case "ByteLevel":
 		return createByteLevelPreTokenizer(params)
-	case "Delimiter":
+	case "CharDelimiterSplit", "Delimiter":
 		return createDelimiterPreTokenizer(params)
 	case "Metaspace":
 		return createMetaspacePreTokenizer(params)
@@ -61,54 +63,99 @@ func CreatePreTokenizer(config map[string]interface{}) (tokenizer.PreTokenizer,
 	}
 }
 
-func createByteLevelPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
-	if params == nil {
-		return nil, nil
+// byteLevelConfig is the config of ByteLevel pre-tokenizers, decoders and
+// post-processors.
+type byteLevelConfig struct {
+	AddPrefixSpace bool  `json:"add_prefix_space"`
+	TrimOffsets    bool  `json:"trim_offsets"`
+	UseRegex       *bool `json:"use_regex"`
+}
+
+// createByteLevel creates the ByteLevel of section, i.e. "pre_tokenizer".
+// `use_regex` defaults to true.
+func createByteLevel(section string, params *util.Params) (*pretokenizer.ByteLevel, error) {
+	var config byteLevelConfig
+	if err := decodeConfig(section, params, &config); err != nil {
+		return nil, err
 	}
 
-	addPrefixSpace := params.Get("add_prefix_space", false).(bool)
-	trimOffsets := params.Get("trim_offsets", false).(bool)
+	useRegex := true
+	if config.UseRegex != nil {
+		useRegex = *config.UseRegex
+	}
 
 	return &pretokenizer.ByteLevel{
-		AddPrefixSpace: addPrefixSpace,
-		TrimOffsets:    trimOffsets,
+		AddPrefixSpace: config.AddPrefixSpace,
+		TrimOffsets:    config.TrimOffsets,
+		UseRegex:       useRegex,
 	}, nil
 }
 
+func createByteLevelPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
+	return createByteLevel("pre_tokenizer", params)
+}
+
 func createDelimiterPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
 	// TODO. verify key `delimiter`
 	delimiter := []rune(params.Get("delimiter").(string))[0]
 	return pretokenizer.NewCharDelimiterSplit(delimiter), nil
 }
 
-func createMetaspacePreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
-	if params == nil {
-		return nil, nil
+// metaspaceConfig is the config of Metaspace pre-tokenizers and decoders.
+// `add_prefix_space` and `str_rep` are legacy fields.
+type metaspaceConfig struct {
+	Replacement    *string `json:"replacement"`
+	StrRep         *string `json:"str_rep"`
+	PrependScheme  *string `json:"prepend_scheme"`
+	Split          *bool   `json:"split"`
+	AddPrefixSpace *bool   `json:"add_prefix_space"`
+}
+
+// createMetaspace creates the Metaspace of section, i.e. "pre_tokenizer". As
+// in HuggingFace tokenizers, the replacement defaults to "▁", the prepend
+// scheme to "always" and split to true. A legacy `add_prefix_space: false`
+// maps to the "never" scheme.
+func createMetaspace(section string, params *util.Params) (*pretokenizer.Metaspace, error) {
+	var config metaspaceConfig
+	if err := decodeConfig(section, params, &config); err != nil {
+		return nil, err
 	}
 
-	replacement := params.Get("replacement", "").(string)
-	
-	// Check for prepend_scheme parameter
-	var scheme pretokenizer.PrependScheme
-	if params.Has("prepend_scheme") {
-		schemeStr := params.Get("prepend_scheme").(string)
-		switch strings.ToLower(schemeStr) {
-		case "always":
-			scheme = pretokenizer.Always
-		case "first":
-			scheme = pretokenizer.First
-		case "never":
-			scheme = pretokenizer.Never
-		default:
-			return nil, fmt.Errorf("unknown prepend_scheme: %s", schemeStr)
+	replacement := "▁"
+	switch {
+	case config.Replacement != nil:
+		replacement = *config.Replacement
+	case config.StrRep != nil:
+		replacement = *config.StrRep
+	}
+	if replacement == "" {
+		return nil, configErrorf(section+".replacement", "want a char, got \"\"")
+	}
+
+	scheme := pretokenizer.Always
+	if config.PrependScheme != nil {
+		var err error
+		if scheme, err = pretokenizer.ParsePrependScheme(*config.PrependScheme); err != nil {
+			return nil, &ConfigError{Field: section + ".prepend_scheme", Err: err}
 		}
-		
-		return pretokenizer.NewMetaspaceWithScheme(replacement, scheme), nil
 	}
-	
-	// Fallback to add_prefix_space for backward compatibility
-	addPrefixSpace := params.Get("add_prefix_space", false).(bool)
-	return pretokenizer.NewMetaspace(replacement, addPrefixSpace), nil
+	if config.AddPrefixSpace != nil && !*config.AddPrefixSpace {
+		if config.PrependScheme != nil && scheme != pretokenizer.Never {
+			return nil, configErrorf(section+".add_prefix_space", "false does not match prepend_scheme %q", *config.PrependScheme)
+		}
+		scheme = pretokenizer.Never
+	}
+
+	m := pretokenizer.NewMetaspaceWithScheme(replacement, scheme)
+	if config.Split != nil {
+		m.Split = *config.Split
+	}
+
+	return m, nil
+}
+
+func createMetaspacePreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
+	return createMetaspace("pre_tokenizer", params)
 }
 
 func createWhitespacePreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
@@ -125,26 +172,23 @@ func createWhitespaceSplitPreTokenizer(params *util.Params) (tokenizer.PreTokeni
 	       "behavior": "Contiguous"
 	     },
 */
-func createPunctuationPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
-	behaviorVal := params.Get("behavior").(string)
-
-	var b normalizer.SplitDelimiterBehavior
-	switch behaviorVal {
-	case "Removed":
-		b = normalizer.RemovedBehavior
-	case "Isolated":
-		b = normalizer.IsolatedBehavior
-	case "MergedWithNext":
-		b = normalizer.MergedWithNextBehavior
-	case "MergedWithPrevious":
-		b = normalizer.MergedWithPreviousBehavior
-	case "Contiguous":
-		b = normalizer.ContiguousBehavior
+type punctuationPreTokenizerConfig struct {
+	Behavior *string `json:"behavior"`
+}
 
-	default:
-		err := fmt.Errorf("Unsupported behavior: %#v\n", behaviorVal)
+func createPunctuationPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
+	var config punctuationPreTokenizerConfig
+	if err := decodeConfig("pre_tokenizer", params, &config); err != nil {
 		return nil, err
 	}
+	if config.Behavior == nil {
+		return pretokenizer.DefaultPunctuation(), nil
+	}
+
+	b, err := normalizer.ParseSplitDelimiterBehavior(*config.Behavior)
+	if err != nil {
+		return nil, configErrorf("pre_tokenizer.behavior", "%w", err)
+	}
 
 	return pretokenizer.NewPunctuation(b), nil
 }
@@ -155,10 +199,17 @@ func createPunctuationPreTokenizer(params *util.Params) (tokenizer.PreTokenizer,
 	        "individual_digits": false
 	      },
 */
+type digitsPreTokenizerConfig struct {
+	IndividualDigits bool `json:"individual_digits"`
+}
+
 func createDigitsPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
-	individualDigits := params.Get("individual_digits").(bool)
+	var config digitsPreTokenizerConfig
+	if err := decodeConfig("pre_tokenizer", params, &config); err != nil {
+		return nil, err
+	}
 
-	return pretokenizer.NewDigits(individualDigits), nil
+	return pretokenizer.NewDigits(config.IndividualDigits), nil
 }
 
 func createUnicodeScriptsPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
@@ -175,55 +226,68 @@ func createUnicodeScriptsPreTokenizer(params *util.Params) (tokenizer.PreTokeniz
 	    "invert": false
 	  }
 */
+type splitPreTokenizerConfig struct {
+	Pattern *struct {
+		String *string `json:"String"`
+		Regex  *string `json:"Regex"`
+	} `json:"pattern"`
+	Behavior *string `json:"behavior"`
+	Invert   bool    `json:"invert"`
+}
+
 func createSplitPreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
-	if params == nil {
-		return nil, nil
+	var config splitPreTokenizerConfig
+	if err := decodeConfig("pre_tokenizer", params, &config); err != nil {
+		return nil, err
 	}
 
-	patternMap := params.Get("pattern").(map[string]interface{})
-	behaviorVal := params.Get("behavior").(string)
-	invert := params.Get("invert").(bool)
-
 	var pattern normalizer.Pattern
-	if v, ok := patternMap["Regex"]; ok {
-		pattern = normalizer.NewRegexpPattern(v.(string))
-	} else if v, ok := patternMap["String"]; ok {
-		pattern = normalizer.NewRegexpPattern(v.(string))
-	} else {
-		err := fmt.Errorf("Unsupported pattern: %#v\n", patternMap)
-		return nil, err
+	switch {
+	case config.Pattern == nil:
+		return nil, configErrorf("pre_tokenizer.pattern", "missing")
+	case config.Pattern.String != nil:
+		pattern = normalizer.NewStringPattern(*config.Pattern.String)
+	case config.Pattern.Regex != nil:
+		p, err := normalizer.CompileRegexpPattern(*config.Pattern.Regex)
+		if err != nil {
+			return nil, configErrorf("pre_tokenizer.pattern.Regex", "%w", err)
+		}
+		pattern = p
+	default:
+		return nil, configErrorf("pre_tokenizer.pattern", "want String or Regex pattern")
 	}
 
-	var b normalizer.SplitDelimiterBehavior
-	switch behaviorVal {
-	case "Removed":
-		b = normalizer.RemovedBehavior
-	case "Isolated":
-		b = normalizer.IsolatedBehavior
-	case "MergedWithNext":
-		b = normalizer.MergedWithNextBehavior
-	case "MergedWithPrevious":
-		b = normalizer.MergedWithPreviousBehavior
-	case "Contiguous":
-		b = normalizer.ContiguousBehavior
-
-	default:
-		err := fmt.Errorf("Unsupported behavior: %#v\n", behaviorVal)
-		return nil, err
+	if config.Behavior == nil {
+		return nil, configErrorf("pre_tokenizer.behavior", "missing")
+	}
+	b, err := normalizer.ParseSplitDelimiterBehavior(*config.Behavior)
+	if err != nil {
+		return nil, configErrorf("pre_tokenizer.behavior", "%w", err)
 	}
 
-	return pretokenizer.NewSplit(pattern, b, invert), nil
+	return pretokenizer.NewSplit(pattern, b, config.Invert), nil
+}
+
+type sequencePreTokenizerConfig struct {
+	PreTokenizers []map[string]interface{} `json:"pretokenizers"`
 }
 
 func createSequencePreTokenizer(params *util.Params) (tokenizer.PreTokenizer, error) {
-	var pretoks []tokenizer.PreTokenizer
+	var config sequencePreTokenizerConfig
+	if err := decodeConfig("pre_tokenizer", params, &config); err != nil {
+		return nil, err
+	}
 
-	data := params.Get("pretokenizers").([]interface{})
-	for _, d := range data {
-		pretok, err := CreatePreTokenizer(d.(map[string]interface{}))
+	var pretoks []tokenizer.PreTokenizer
+	for _, d := range config.PreTokenizers {
+		pretok, err := CreatePreTokenizer(d)
 		if err != nil {
 			return nil, err
 		}
+		// Skip `null` entries.
+		if pretok == nil {
+			continue
+		}
 		pretoks = append(pretoks, pretok)
 	}
 
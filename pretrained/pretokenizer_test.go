@@ -1,7 +1,14 @@
 package pretrained
 
 import (
+	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/normalizer"
+	"github.com/season-studio/tokenizer/pretokenizer"
 )
 
 func TestCreatePreTokenizer(t *testing.T) {
@@ -29,3 +36,234 @@ func TestNullPreTokenizer(t *testing.T) {
 		panic(err)
 	}
 }
+
+func TestCreateNestedSequencePreTokenizer(t *testing.T) {
+	var config map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"type": "Sequence",
+		"pretokenizers": [
+			{"type": "WhitespaceSplit"},
+			{"type": "Sequence", "pretokenizers": [{"type": "Digits", "individual_digits": true}]}
+		]
+	}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pretok, err := CreatePreTokenizer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pretokenized, err := pretok.PreTokenize(tokenizer.NewPreTokenizedString("ab 12"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, split := range pretokenized.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+		got = append(got, split.Value)
+	}
+
+	want := []string{"ab", "1", "2"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	_, err = CreatePreTokenizer(map[string]interface{}{"type": "Sequence", "pretokenizers": "Whitespace"})
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "pre_tokenizer.pretokenizers" {
+		t.Errorf("want ConfigError on %q, got %v", "pre_tokenizer.pretokenizers", err)
+	}
+}
+
+func TestCreateSplitPreTokenizer(t *testing.T) {
+	tests := []struct {
+		data  string
+		input string
+		want  []string
+	}{
+		{
+			// LLaMA 3 / GPT-4 style, with the emulated `\s+(?!\S)` lookahead.
+			data:  `{"type": "Split", "pattern": {"Regex": "(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\\r\\n\\p{L}\\p{N}]?\\p{L}+|\\p{N}{1,3}| ?[^\\s\\p{L}\\p{N}]+[\\r\\n]*|\\s*[\\r\\n]+|\\s+(?!\\S)|\\s+"}, "behavior": "Isolated", "invert": false}`,
+			input: "Hello world's  1234!",
+			want:  []string{"Hello", " world", "'s", " ", " ", "123", "4", "!"},
+		},
+		{
+			data:  `{"type": "Split", "pattern": {"String": "-"}, "behavior": "MergedWithPrevious"}`,
+			input: "a-b--c",
+			want:  []string{"a-", "b-", "-", "c"},
+		},
+		{
+			data:  `{"type": "Split", "pattern": {"Regex": "[a-c]"}, "behavior": "Contiguous", "invert": true}`,
+			input: "a-b--c",
+			want:  []string{"a", "-", "b", "--", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+		pretok, err := CreatePreTokenizer(config)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.data, err)
+		}
+
+		pretokenized, err := pretok.PreTokenize(tokenizer.NewPreTokenizedString(tt.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, split := range pretokenized.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+			got = append(got, split.Value)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%v: want %q, got %q", tt.data, tt.want, got)
+		}
+	}
+}
+
+func TestCreateSplitPreTokenizer_Malformed(t *testing.T) {
+	tests := []struct {
+		data  string
+		field string
+	}{
+		{`{"type": "Split", "behavior": "Isolated"}`, "pre_tokenizer.pattern"},
+		{`{"type": "Split", "pattern": {"Regex": "("}, "behavior": "Isolated"}`, "pre_tokenizer.pattern.Regex"},
+		{`{"type": "Split", "pattern": {"String": " "}}`, "pre_tokenizer.behavior"},
+		{`{"type": "Split", "pattern": {"String": " "}, "behavior": "Merged"}`, "pre_tokenizer.behavior"},
+		{`{"type": "Split", "pattern": {"String": " "}, "behavior": "Removed", "invert": "no"}`, "pre_tokenizer.invert"},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+		_, err := CreatePreTokenizer(config)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != tt.field {
+			t.Errorf("%v: want ConfigError on %q, got %v", tt.data, tt.field, err)
+		}
+	}
+}
+
+func TestCreateDigitsPunctuationUnicodeScripts(t *testing.T) {
+	tests := []struct {
+		data  string
+		input string
+		want  []string
+	}{
+		{`{"type": "Digits", "individual_digits": true}`, "a123b", []string{"a", "1", "2", "3", "b"}},
+		{`{"type": "Digits", "individual_digits": false}`, "a123b", []string{"a", "123", "b"}},
+		{`{"type": "Digits"}`, "a12", []string{"a", "12"}},
+		{`{"type": "Punctuation", "behavior": "Contiguous"}`, "a?!b", []string{"a", "?!", "b"}},
+		{`{"type": "Punctuation"}`, "a?!b", []string{"a", "?", "!", "b"}},
+		{`{"type": "UnicodeScripts"}`, "Apples are りんご 林檎", []string{"Apples are ", "りんご 林檎"}},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+		pretok, err := CreatePreTokenizer(config)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.data, err)
+		}
+
+		pretokenized, err := pretok.PreTokenize(tokenizer.NewPreTokenizedString(tt.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, split := range pretokenized.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+			got = append(got, split.Value)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%v: want %q, got %q", tt.data, tt.want, got)
+		}
+	}
+
+	for data, field := range map[string]string{
+		`{"type": "Digits", "individual_digits": "yes"}`: "pre_tokenizer.individual_digits",
+		`{"type": "Punctuation", "behavior": "Merged"}`:  "pre_tokenizer.behavior",
+	} {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &config); err != nil {
+			t.Fatal(err)
+		}
+		_, err := CreatePreTokenizer(config)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != field {
+			t.Errorf("%v: want ConfigError on %q, got %v", data, field, err)
+		}
+	}
+}
+
+func TestCreateMetaspace(t *testing.T) {
+	tests := []struct {
+		data   string
+		scheme pretokenizer.PrependScheme
+		split  bool
+		want   []string
+	}{
+		{`{"type": "Metaspace", "replacement": "▁", "prepend_scheme": "first", "split": false}`, pretokenizer.First, false, []string{"▁hey▁friend"}},
+		{`{"type": "Metaspace", "replacement": "▁", "prepend_scheme": "never", "split": true}`, pretokenizer.Never, true, []string{"hey", "▁friend"}},
+		// Legacy configs.
+		{`{"type": "Metaspace", "replacement": "▁", "add_prefix_space": true}`, pretokenizer.Always, true, []string{"▁hey", "▁friend"}},
+		{`{"type": "Metaspace", "str_rep": "_", "add_prefix_space": false}`, pretokenizer.Never, true, []string{"hey", "_friend"}},
+		{`{"type": "Metaspace"}`, pretokenizer.Always, true, []string{"▁hey", "▁friend"}},
+	}
+
+	for _, tt := range tests {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.data), &config); err != nil {
+			t.Fatal(err)
+		}
+		pretok, err := CreatePreTokenizer(config)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.data, err)
+		}
+		dec, err := CreateDecoder(config)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.data, err)
+		}
+		for _, m := range []*pretokenizer.Metaspace{pretok.(*pretokenizer.Metaspace), dec.(*pretokenizer.Metaspace)} {
+			if m.PrependScheme != tt.scheme || m.Split != tt.split {
+				t.Errorf("%v: want scheme %v and split %v, got %v and %v", tt.data, tt.scheme, tt.split, m.PrependScheme, m.Split)
+			}
+		}
+
+		pretokenized, err := pretok.PreTokenize(tokenizer.NewPreTokenizedString("hey friend"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, split := range pretokenized.GetSplits(normalizer.OriginalTarget, tokenizer.Byte) {
+			got = append(got, split.Value)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("%v: want %q, got %q", tt.data, tt.want, got)
+		}
+	}
+
+	for data, field := range map[string]string{
+		`{"type": "Metaspace", "prepend_scheme": "sometimes"}`:                        "pre_tokenizer.prepend_scheme",
+		`{"type": "Metaspace", "prepend_scheme": "first", "add_prefix_space": false}`: "pre_tokenizer.add_prefix_space",
+		`{"type": "Metaspace", "split": "yes"}`:                                       "pre_tokenizer.split",
+		`{"type": "Metaspace", "replacement": ""}`:                                    "pre_tokenizer.replacement",
+	} {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &config); err != nil {
+			t.Fatal(err)
+		}
+		_, err := CreatePreTokenizer(config)
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != field {
+			t.Errorf("%v: want ConfigError on %q, got %v", data, field, err)
+		}
+	}
+}
@@ -9,9 +9,9 @@ package pretrained
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/season-studio/tokenizer"
-	"github.com/season-studio/tokenizer/pretokenizer"
 	"github.com/season-studio/tokenizer/processor"
 	"github.com/season-studio/tokenizer/util"
 )
@@ -23,17 +23,20 @@ func CreatePostProcessor(config map[string]interface{}) (tokenizer.PostProcessor
 
 	params := util.NewParams(config)
 
-	typ := params.Get("type").(string)
+	typ, ok := params.Get("type").(string)
+	if !ok {
+		return nil, configErrorf("post_processor.type", "want string, got %T", params.Get("type"))
+	}
 
 	switch typ {
 	case "RobertaProcessing": // Bart
-		return createRobertaProcessing(params), nil
+		return createRobertaProcessing(params)
 	case "BertProcessing": // Bert
 		return createBertProcessing(params), nil
 	case "ByteLevel":
-		return createByteLevel(params)
-	case "TemplateProcessing": // T5
-		return createTemplateProcessing(params), nil
+		return createByteLevelProcessing(params)
+	case "TemplateProcessing": // T5, LLaMA
+		return createTemplateProcessing(params)
 	case "Sequence":
 		return createSequence(params)
 
@@ -48,13 +51,30 @@ func CreatePostProcessor(config map[string]interface{}) (tokenizer.PostProcessor
 // "cls":["<s>",0],
 // "trim_offsets":true,
 // "add_prefix_space":false
-func createRobertaProcessing(params *util.Params) tokenizer.PostProcessor {
+type robertaProcessingConfig struct {
+	TrimOffsets    *bool `json:"trim_offsets"`
+	AddPrefixSpace *bool `json:"add_prefix_space"`
+}
+
+// createRobertaProcessing creates a RobertaProcessing, `trim_offsets` and
+// `add_prefix_space` default to true as in HuggingFace.
+func createRobertaProcessing(params *util.Params) (tokenizer.PostProcessor, error) {
+	var config robertaProcessingConfig
+	if err := decodeConfig("post_processor", params, &config); err != nil {
+		return nil, err
+	}
+
+	trimOffsets, addPrefixSpace := true, true
+	if config.TrimOffsets != nil {
+		trimOffsets = *config.TrimOffsets
+	}
+	if config.AddPrefixSpace != nil {
+		addPrefixSpace = *config.AddPrefixSpace
+	}
 	sep := getPostToken(params, "sep")
 	cls := getPostToken(params, "cls")
-	trimOffsets := params.Get("trim_offsets").(bool)
-	addPrefixSpace := params.Get("add_prefix_space").(bool)
 
-	return processor.NewRobertaProcessing(sep, cls, trimOffsets, addPrefixSpace)
+	return processor.NewRobertaProcessing(sep, cls, trimOffsets, addPrefixSpace), nil
 }
 
 func getPostToken(params *util.Params, name string) processor.PostToken {
@@ -75,12 +95,12 @@ func createBertProcessing(params *util.Params) tokenizer.PostProcessor {
 	return processor.NewBertProcessing(sep, cls)
 }
 
-func createByteLevel(params *util.Params) (tokenizer.PostProcessor, error) {
-	pretok, err := createByteLevelPreTokenizer(params)
+func createByteLevelProcessing(params *util.Params) (tokenizer.PostProcessor, error) {
+	pretok, err := createByteLevel("post_processor", params)
 	if err != nil {
 		return nil, err
 	}
-	return processor.NewByteLevelProcessing(pretok.(*pretokenizer.ByteLevel)), nil
+	return processor.NewByteLevelProcessing(pretok), nil
 }
 
 // e.g. `TheBloke/guanaco-7B-HF`
@@ -135,89 +155,116 @@ func createByteLevel(params *util.Params) (tokenizer.PostProcessor, error) {
     }
   },
 */
-func createTemplateProcessing(params *util.Params) tokenizer.PostProcessor {
-	var (
-		single        processor.Template
-		pair          processor.Template
-		specialTokens *processor.Tokens
-	)
-
-	// Single
-	if params.Has("single") {
-		singleData := params.Get("single").([]interface{})
-		for _, p := range singleData {
-			ps := util.NewParams(p.(map[string]interface{}))
-			if ps.Has("Sequence") {
-				item := ps.Get("Sequence").(map[string]interface{})
-				id := item["id"].(string) // always "A"
-				typeId := int(item["type_id"].(float64))
-				piece := processor.NewSequencePiece(id, typeId)
-				single = append(single, piece)
-			}
+type templatePieceConfig struct {
+	Sequence *struct {
+		Id     string `json:"id"`
+		TypeId int    `json:"type_id"`
+	} `json:"Sequence"`
+	SpecialToken *struct {
+		Id     string `json:"id"`
+		TypeId int    `json:"type_id"`
+	} `json:"SpecialToken"`
+}
 
-			if ps.Has("SpecialToken") {
-				item := ps.Get("SpecialToken").(map[string]interface{})
-				id := item["id"].(string)
-				typeId := int(item["type_id"].(float64))
-				piece := processor.NewSpecialTokenPiece(id, typeId)
-				single = append(single, piece)
-			}
-		}
+type templateSpecialTokenConfig struct {
+	Id     string   `json:"id"`
+	Ids    []int    `json:"ids"`
+	Tokens []string `json:"tokens"`
+}
+
+type templateProcessingConfig struct {
+	Single        []templatePieceConfig                 `json:"single"`
+	Pair          []templatePieceConfig                 `json:"pair"`
+	SpecialTokens map[string]templateSpecialTokenConfig `json:"special_tokens"`
+}
+
+func createTemplateProcessing(params *util.Params) (tokenizer.PostProcessor, error) {
+	var config templateProcessingConfig
+	if err := decodeConfig("post_processor", params, &config); err != nil {
+		return nil, err
 	}
 
-	// Pair
-	if params.Has("pair") {
-		pairData := params.Get("pair").([]interface{})
-		for _, p := range pairData {
-			ps := util.NewParams(p.(map[string]interface{}))
-			if ps.Has("Sequence") {
-				item := ps.Get("Sequence").(map[string]interface{})
-				id := item["id"].(string)
-				typeId := int(item["type_id"].(float64))
-				piece := processor.NewSequencePiece(id, typeId)
-				pair = append(pair, piece)
-			}
+	single, err := createTemplate("post_processor.single", config.Single)
+	if err != nil {
+		return nil, err
+	}
 
-			if ps.Has("SpecialToken") {
-				item := ps.Get("SpecialToken").(map[string]interface{})
-				id := item["id"].(string)
-				typeId := int(item["type_id"].(float64))
-				piece := processor.NewSpecialTokenPiece(id, typeId)
-				pair = append(pair, piece)
-			}
-		}
+	pair, err := createTemplate("post_processor.pair", config.Pair)
+	if err != nil {
+		return nil, err
 	}
 
-	// SpecialTokens
-	if params.Has("special_tokens") {
-		data := params.Get("special_tokens").(map[string]interface{})
-		var toks []processor.SpecialToken
-		for _, v := range data {
-			d := v.(map[string]interface{})
-			id := d["id"].(string)
-			ids := util.ConvertSlice[float64, int](util.CastSlice[float64](d["ids"].([]interface{})))
+	// Sort by id so that the special tokens keep the order of the file, which
+	// HuggingFace writes sorted.
+	keys := make([]string, 0, len(config.SpecialTokens))
+	for k := range config.SpecialTokens {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-			tokens := util.CastSlice[string](d["tokens"].([]interface{}))
-			tok := processor.NewSpecialToken(id, ids, tokens)
-			toks = append(toks, *tok)
+	var toks []processor.SpecialToken
+	for _, k := range keys {
+		tok := config.SpecialTokens[k]
+		field := fmt.Sprintf("post_processor.special_tokens[%q]", k)
+		if tok.Id == "" {
+			tok.Id = k
 		}
+		if tok.Id != k {
+			return nil, configErrorf(field+".id", "want %q, got %q", k, tok.Id)
+		}
+		if len(tok.Ids) != len(tok.Tokens) {
+			return nil, configErrorf(field, "got %v ids for %v tokens", len(tok.Ids), len(tok.Tokens))
+		}
+		toks = append(toks, *processor.NewSpecialToken(tok.Id, tok.Ids, tok.Tokens))
+	}
+
+	tp := processor.NewTemplateProcessing(single, pair, processor.NewTokensFrom(toks))
+	if err := tp.Validate(); err != nil {
+		return nil, &ConfigError{Field: "post_processor", Err: err}
+	}
 
-		specialTokens = processor.NewTokensFrom(toks)
+	return tp, nil
+}
+
+func createTemplate(field string, pieces []templatePieceConfig) (processor.Template, error) {
+	var tpl processor.Template
+	for i, p := range pieces {
+		switch {
+		case p.Sequence != nil:
+			if p.Sequence.Id != "A" && p.Sequence.Id != "B" {
+				return nil, configErrorf(fmt.Sprintf("%s[%d].Sequence.id", field, i), "want \"A\" or \"B\", got %q", p.Sequence.Id)
+			}
+			tpl = append(tpl, processor.NewSequencePiece(p.Sequence.Id, p.Sequence.TypeId))
+		case p.SpecialToken != nil:
+			tpl = append(tpl, processor.NewSpecialTokenPiece(p.SpecialToken.Id, p.SpecialToken.TypeId))
+		default:
+			return nil, configErrorf(fmt.Sprintf("%s[%d]", field, i), "want a Sequence or SpecialToken piece")
+		}
 	}
 
-	return processor.NewTemplateProcessing(single, pair, specialTokens)
+	return tpl, nil
+}
+
+type sequenceProcessorConfig struct {
+	Processors []map[string]interface{} `json:"processors"`
 }
 
 func createSequence(params *util.Params) (tokenizer.PostProcessor, error) {
-	// TODO. check whether key is "processors" or "post_processors"
-	data := params.Get("processors").([]interface{})
+	var config sequenceProcessorConfig
+	if err := decodeConfig("post_processor", params, &config); err != nil {
+		return nil, err
+	}
+
 	var processors []tokenizer.PostProcessor
-	for _, v := range data {
-		d := v.(map[string]interface{})
+	for _, d := range config.Processors {
 		processor, err := CreatePostProcessor(d)
 		if err != nil {
 			return nil, err
 		}
+		// Skip `null` entries.
+		if processor == nil {
+			continue
+		}
 
 		processors = append(processors, processor)
 	}
@@ -1,7 +1,10 @@
 package pretrained
 
 import (
+	"encoding/json"
+	"errors"
 	"log"
+	"reflect"
 	"testing"
 
 	"github.com/season-studio/tokenizer"
@@ -92,3 +95,160 @@ func TestCreatePostProcessor(t *testing.T) {
 	}
 
 }
+
+func postProcessorConfig(t *testing.T, data string) map[string]interface{} {
+	t.Helper()
+
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+func TestCreateTemplateProcessing(t *testing.T) {
+	config := postProcessorConfig(t, `{
+		"type": "TemplateProcessing",
+		"single": [{"SpecialToken": {"id": "<s>", "type_id": 0}}, {"Sequence": {"id": "A", "type_id": 0}}, {"SpecialToken": {"id": "</s>", "type_id": 0}}],
+		"pair": [
+			{"SpecialToken": {"id": "<s>", "type_id": 0}}, {"Sequence": {"id": "A", "type_id": 0}}, {"SpecialToken": {"id": "</s>", "type_id": 0}},
+			{"SpecialToken": {"id": "</s>", "type_id": 1}}, {"Sequence": {"id": "B", "type_id": 1}}, {"SpecialToken": {"id": "</s>", "type_id": 1}}
+		],
+		"special_tokens": {
+			"<s>": {"id": "<s>", "ids": [0], "tokens": ["<s>"]},
+			"</s>": {"id": "</s>", "ids": [2], "tokens": ["</s>"]}
+		}
+	}`)
+
+	p, err := CreatePostProcessor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := p.AddedTokens(false), 2; got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if got, want := p.AddedTokens(true), 4; got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	encoding := tokenizer.NewEncodingFromTokens([]tokenizer.Token{{Id: 10, Value: "a", Offsets: []int{0, 1}}}, 0)
+	pair := tokenizer.NewEncodingFromTokens([]tokenizer.Token{{Id: 11, Value: "b", Offsets: []int{0, 1}}}, 0)
+	got := p.Process(encoding, pair, true)
+
+	wantIds := []int{0, 10, 2, 2, 11, 2}
+	wantTypeIds := []int{0, 0, 0, 1, 1, 1}
+	if !reflect.DeepEqual(wantIds, got.Ids) || !reflect.DeepEqual(wantTypeIds, got.TypeIds) {
+		t.Errorf("want %v/%v, got %v/%v", wantIds, wantTypeIds, got.Ids, got.TypeIds)
+	}
+}
+
+func TestCreateTemplateProcessing_Malformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		field string
+	}{
+		{"type_id", `{"type": "TemplateProcessing", "single": [{"Sequence": {"id": "A", "type_id": "0"}}]}`, "post_processor.single.0.Sequence.type_id"},
+		{"sequence id", `{"type": "TemplateProcessing", "single": [{"Sequence": {"id": "C", "type_id": 0}}]}`, "post_processor.single[0].Sequence.id"},
+		{"piece", `{"type": "TemplateProcessing", "single": [{"Foo": {}}]}`, "post_processor.single[0]"},
+		{"ids", `{"type": "TemplateProcessing", "special_tokens": {"<s>": {"id": "<s>", "ids": [1, 2], "tokens": ["<s>"]}}}`, `post_processor.special_tokens["<s>"]`},
+		{"missing special token", `{"type": "TemplateProcessing", "single": [{"SpecialToken": {"id": "<s>", "type_id": 0}}, {"Sequence": {"id": "A", "type_id": 0}}]}`, "post_processor"},
+		{"pair", `{"type": "TemplateProcessing", "pair": [{"Sequence": {"id": "A", "type_id": 0}}]}`, "post_processor"},
+	}
+
+	for _, tt := range tests {
+		_, err := CreatePostProcessor(postProcessorConfig(t, tt.data))
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("%v: want ConfigError, got %v", tt.name, err)
+			continue
+		}
+		if configErr.Field != tt.field {
+			t.Errorf("%v: want field %q, got %q (%v)", tt.name, tt.field, configErr.Field, err)
+		}
+	}
+}
+
+// e.g. `meta-llama/Meta-Llama-3-8B`
+func TestCreateSequenceProcessor(t *testing.T) {
+	config := postProcessorConfig(t, `{
+		"type": "Sequence",
+		"processors": [
+			{"type": "ByteLevel", "add_prefix_space": true, "trim_offsets": false, "use_regex": true},
+			{
+				"type": "TemplateProcessing",
+				"single": [{"SpecialToken": {"id": "<|begin_of_text|>", "type_id": 0}}, {"Sequence": {"id": "A", "type_id": 0}}],
+				"pair": [
+					{"SpecialToken": {"id": "<|begin_of_text|>", "type_id": 0}}, {"Sequence": {"id": "A", "type_id": 0}},
+					{"SpecialToken": {"id": "<|begin_of_text|>", "type_id": 1}}, {"Sequence": {"id": "B", "type_id": 1}}
+				],
+				"special_tokens": {"<|begin_of_text|>": {"id": "<|begin_of_text|>", "ids": [128000], "tokens": ["<|begin_of_text|>"]}}
+			}
+		]
+	}`)
+
+	p, err := CreatePostProcessor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoding := tokenizer.NewEncodingFromTokens([]tokenizer.Token{{Id: 10, Value: "a", Offsets: []int{0, 1}}}, 0)
+	pair := tokenizer.NewEncodingFromTokens([]tokenizer.Token{{Id: 11, Value: "b", Offsets: []int{0, 1}}}, 0)
+	got := p.Process(encoding, pair, true)
+
+	wantIds := []int{128000, 10, 128000, 11}
+	wantTypeIds := []int{0, 0, 1, 1}
+	if !reflect.DeepEqual(wantIds, got.Ids) || !reflect.DeepEqual(wantTypeIds, got.TypeIds) {
+		t.Errorf("want %v/%v, got %v/%v", wantIds, wantTypeIds, got.Ids, got.TypeIds)
+	}
+
+	_, err = CreatePostProcessor(postProcessorConfig(t, `{"type": "Sequence", "processors": [{"type": 1}]}`))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "post_processor.type" {
+		t.Errorf("want ConfigError on %q, got %v", "post_processor.type", err)
+	}
+}
+
+func TestCreateByteLevelAndRobertaProcessing(t *testing.T) {
+	tokens := []tokenizer.Token{
+		{Id: 1, Value: "ĠHello", Offsets: []int{0, 6}},
+		{Id: 2, Value: "Ġworld", Offsets: []int{6, 12}},
+	}
+
+	tests := []struct {
+		data string
+		want [][]int
+	}{
+		{`{"type": "ByteLevel", "add_prefix_space": false, "trim_offsets": true}`, [][]int{{1, 6}, {7, 12}}},
+		{`{"type": "ByteLevel", "add_prefix_space": true, "trim_offsets": true}`, [][]int{{0, 6}, {7, 12}}},
+		{`{"type": "ByteLevel", "add_prefix_space": true, "trim_offsets": false}`, [][]int{{0, 6}, {6, 12}}},
+		// trim_offsets and add_prefix_space default to true.
+		{`{"type": "RobertaProcessing", "sep": ["</s>", 2], "cls": ["<s>", 0]}`, [][]int{{0, 0}, {0, 6}, {7, 12}, {0, 0}}},
+		{`{"type": "RobertaProcessing", "sep": ["</s>", 2], "cls": ["<s>", 0], "add_prefix_space": false}`, [][]int{{0, 0}, {1, 6}, {7, 12}, {0, 0}}},
+	}
+
+	for _, tt := range tests {
+		p, err := CreatePostProcessor(postProcessorConfig(t, tt.data))
+		if err != nil {
+			t.Fatalf("%v: %v", tt.data, err)
+		}
+		got := p.Process(tokenizer.NewEncodingFromTokens(tokens, 0), nil, true)
+		if !reflect.DeepEqual(tt.want, got.Offsets) {
+			t.Errorf("%v: want %v, got %v", tt.data, tt.want, got.Offsets)
+		}
+	}
+
+	for data, field := range map[string]string{
+		`{"type": "ByteLevel", "trim_offsets": 1}`:                                                   "post_processor.trim_offsets",
+		`{"type": "ByteLevel", "use_regex": "yes"}`:                                                  "post_processor.use_regex",
+		`{"type": "RobertaProcessing", "sep": ["</s>", 2], "cls": ["<s>", 0], "trim_offsets": "no"}`: "post_processor.trim_offsets",
+	} {
+		_, err := CreatePostProcessor(postProcessorConfig(t, data))
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != field {
+			t.Errorf("%v: want ConfigError on %q, got %v", data, field, err)
+		}
+	}
+}
@@ -55,7 +55,8 @@ func RobertaBase(addPrefixSpace, trimOffsets bool) *tokenizer.Tokenizer {
 	tk.WithPreTokenizer(blPreTokenizer)
 
 	postProcess := processor.DefaultRobertaProcessing()
-	// postProcess.TrimOffsets(trimOffsets)
+	postProcess.TrimOffsets(trimOffsets)
+	postProcess.AddPrefixSpace(addPrefixSpace)
 	tk.WithPostProcessor(postProcess)
 
 	bpeDecoder := decoder.NewBpeDecoder("Ġ")
@@ -101,6 +102,8 @@ func RobertaBaseSquad2(addPrefixSpace, trimOffsets bool) *tokenizer.Tokenizer {
 	tk.WithPreTokenizer(blPreTokenizer)
 
 	postProcess := processor.DefaultRobertaProcessing()
+	postProcess.TrimOffsets(trimOffsets)
+	postProcess.AddPrefixSpace(addPrefixSpace)
 	tk.WithPostProcessor(postProcess)
 
 	bpeDecoder := decoder.NewBpeDecoder("Ġ")
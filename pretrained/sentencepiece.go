@@ -0,0 +1,228 @@
+package pretrained
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/decoder"
+	"github.com/season-studio/tokenizer/model/bpe"
+	"github.com/season-studio/tokenizer/model/unigram"
+	"github.com/season-studio/tokenizer/normalizer"
+	"github.com/season-studio/tokenizer/pretokenizer"
+	"github.com/season-studio/tokenizer/spm"
+)
+
+// spmSpace is the meta symbol SentencePiece uses for whitespaces.
+const spmSpace = "▁"
+
+// FromSentencePieceFile constructs a new Tokenizer from a SentencePiece model
+// file (normally 'tokenizer.model') as shipped with i.e. LLaMA, T5 or ALBERT.
+//
+// Unigram models get a Metaspace pre-tokenizer and decoder (T5 style), BPE
+// models get a normalizer replacing spaces by `▁` and a byte fallback decoder
+// (LLaMA style). The precompiled charsmap of the model, if any, is used as
+// normalizer. Control and unknown pieces (i.e. <s>, </s>, <unk>) are registered
+// as special tokens and user defined pieces as added tokens. No post-processor
+// is set: SentencePiece does not add BOS/EOS tokens by itself.
+func FromSentencePieceFile(file string) (*tokenizer.Tokenizer, error) {
+	m, err := spm.LoadModel(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromSentencePieceModel(m)
+}
+
+// FromSentencePieceModel constructs a new Tokenizer from a parsed SentencePiece
+// model. See `FromSentencePieceFile`.
+func FromSentencePieceModel(m *spm.ModelProto) (*tokenizer.Tokenizer, error) {
+	var (
+		model tokenizer.Model
+		err   error
+	)
+	switch m.TrainerSpec.ModelType {
+	case spm.ModelUnigram:
+		model, err = createSpmUnigram(m)
+	case spm.ModelBPE:
+		model, err = createSpmBPE(m)
+	default:
+		err = fmt.Errorf("unsupported SentencePiece model type %v: %w", m.TrainerSpec.ModelType, tokenizer.ErrUnknownModelType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tk := tokenizer.NewTokenizer(model)
+
+	addDummyPrefix := m.NormalizerSpec.AddDummyPrefix
+	norms, err := spmNormalizers(m, m.TrainerSpec.ModelType == spm.ModelUnigram && addDummyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m.TrainerSpec.ModelType {
+	case spm.ModelUnigram:
+		scheme := pretokenizer.Never
+		if addDummyPrefix {
+			scheme = pretokenizer.First
+		}
+		metaspace := pretokenizer.NewMetaspaceWithScheme(spmSpace, scheme)
+		tk.WithPreTokenizer(metaspace)
+		if m.TrainerSpec.ByteFallback {
+			tk.WithDecoder(spmByteFallbackDecoder(addDummyPrefix))
+		} else {
+			tk.WithDecoder(metaspace)
+		}
+
+	case spm.ModelBPE:
+		if addDummyPrefix {
+			norms = append(norms, normalizer.NewPrepend(spmSpace))
+		}
+		norms = append(norms, normalizer.NewReplace(normalizer.String, " ", spmSpace))
+		tk.WithDecoder(spmByteFallbackDecoder(addDummyPrefix))
+	}
+
+	if len(norms) > 0 {
+		tk.WithNormalizer(normalizer.NewSequence(norms))
+	}
+
+	var specialToks, addedToks []tokenizer.AddedToken
+	for _, p := range m.Pieces {
+		switch p.Type {
+		case spm.PieceControl, spm.PieceUnknown:
+			specialToks = append(specialToks, tokenizer.NewAddedToken(p.Piece, true, tokenizer.WithNormalized(false)))
+		case spm.PieceUserDefined:
+			addedToks = append(addedToks, tokenizer.NewAddedToken(p.Piece, false, tokenizer.WithNormalized(false)))
+		}
+	}
+	if len(specialToks) > 0 {
+		tk.AddSpecialTokens(specialToks)
+	}
+	if len(addedToks) > 0 {
+		tk.AddTokens(addedToks)
+	}
+
+	return tk, nil
+}
+
+func createSpmUnigram(m *spm.ModelProto) (tokenizer.Model, error) {
+	vocab := make([]unigram.TokenScore, len(m.Pieces))
+	for i, p := range m.Pieces {
+		vocab[i] = unigram.TokenScore{Token: p.Piece, Score: float64(p.Score)}
+	}
+
+	return unigram.New(vocab,
+		unigram.WithUnkID(m.TrainerSpec.UnkID),
+		unigram.WithByteFallback(m.TrainerSpec.ByteFallback),
+		unigram.WithFuseUnk(true),
+	)
+}
+
+// createSpmBPE builds a BPE model from the pieces. SentencePiece BPE models do
+// not store merges: pieces are merged by descending score, so merges are
+// rebuilt from every split of each piece into two pieces, ranked by the score
+// of the merged piece.
+func createSpmBPE(m *spm.ModelProto) (tokenizer.Model, error) {
+	vocab := make(map[string]int, len(m.Pieces))
+	for i, p := range m.Pieces {
+		vocab[p.Piece] = i
+	}
+
+	type merge struct {
+		left, right string
+		score       float32
+	}
+	var merges []merge
+	for _, p := range m.Pieces {
+		if p.Type != spm.PieceNormal {
+			continue
+		}
+		runes := []rune(p.Piece)
+		var local []merge
+		for i := 1; i < len(runes); i++ {
+			left, right := string(runes[:i]), string(runes[i:])
+			if _, ok := vocab[left]; !ok {
+				continue
+			}
+			if _, ok := vocab[right]; !ok {
+				continue
+			}
+			local = append(local, merge{left, right, p.Score})
+		}
+		sort.SliceStable(local, func(i, j int) bool {
+			if vocab[local[i].left] != vocab[local[j].left] {
+				return vocab[local[i].left] < vocab[local[j].left]
+			}
+			return vocab[local[i].right] < vocab[local[j].right]
+		})
+		merges = append(merges, local...)
+	}
+	sort.SliceStable(merges, func(i, j int) bool { return merges[i].score > merges[j].score })
+
+	mergesData := make([]string, len(merges))
+	for i, mg := range merges {
+		mergesData[i] = mg.left + " " + mg.right
+	}
+
+	opts := []bpe.Option{bpe.WithFuseUnk(true), bpe.WithByteFallback(m.TrainerSpec.ByteFallback)}
+	if id := m.TrainerSpec.UnkID; id >= 0 && id < len(m.Pieces) {
+		opts = append(opts, bpe.WithUnkToken(m.Pieces[id].Piece))
+	}
+
+	bpeModel, err := bpe.New(vocab, mergesData, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return bpeModel, nil
+}
+
+// spmNormalizers returns the normalizers of the SentencePiece normalizer spec:
+// the precompiled charsmap and the removal of extra whitespaces. With
+// `metaspace`, runs of whitespaces are replaced by `▁` and leading ones are not
+// stripped, as in HuggingFace converted tokenizers, so that the input still
+// starts at offset 0 for the `First` prepend scheme of the Metaspace
+// pre-tokenizer.
+func spmNormalizers(m *spm.ModelProto, metaspace bool) ([]normalizer.Normalizer, error) {
+	var norms []normalizer.Normalizer
+
+	spec := m.NormalizerSpec
+	if len(spec.PrecompiledCharsmap) > 0 {
+		precompiled, err := normalizer.NewPrecompiled(spec.PrecompiledCharsmap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create precompiled normalizer: %w", err)
+		}
+		norms = append(norms, precompiled)
+	}
+
+	switch {
+	case spec.RemoveExtraWhitespaces && metaspace:
+		norms = append(norms,
+			normalizer.NewStrip(false, true),
+			normalizer.NewReplace(normalizer.Regex, " {2,}", spmSpace),
+		)
+	case spec.RemoveExtraWhitespaces:
+		norms = append(norms,
+			normalizer.NewStrip(true, true),
+			normalizer.NewReplace(normalizer.Regex, " {2,}", " "),
+		)
+	}
+
+	return norms, nil
+}
+
+// spmByteFallbackDecoder decodes `▁` to spaces and byte pieces (<0x41>) to their
+// bytes, dropping the dummy prefix space if any.
+func spmByteFallbackDecoder(addDummyPrefix bool) tokenizer.Decoder {
+	decs := []tokenizer.Decoder{
+		normalizer.NewReplace(normalizer.String, spmSpace, " "),
+		decoder.NewByteFallback(),
+		decoder.NewFuse(),
+	}
+	if addDummyPrefix {
+		decs = append(decs, decoder.NewStrip(" ", 1, 0))
+	}
+
+	return decoder.NewSequence(decs)
+}
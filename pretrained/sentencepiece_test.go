@@ -0,0 +1,198 @@
+package pretrained
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer/spm"
+)
+
+// Minimal protobuf encoding helpers to build SentencePiece model fixtures.
+
+func pbKey(buf []byte, num, typ int) []byte {
+	return binary.AppendUvarint(buf, uint64(num<<3|typ))
+}
+
+func pbVarint(buf []byte, num int, v int64) []byte {
+	return binary.AppendUvarint(pbKey(buf, num, 0), uint64(v))
+}
+
+func pbBytes(buf []byte, num int, b []byte) []byte {
+	buf = binary.AppendUvarint(pbKey(buf, num, 2), uint64(len(b)))
+	return append(buf, b...)
+}
+
+func pbFloat(buf []byte, num int, f float32) []byte {
+	return binary.LittleEndian.AppendUint32(pbKey(buf, num, 5), math.Float32bits(f))
+}
+
+type spmFixture struct {
+	modelType      spm.ModelType
+	byteFallback   bool
+	addDummyPrefix bool
+	pieces         []spm.Piece
+}
+
+func (f spmFixture) bytes() []byte {
+	var buf []byte
+	for _, p := range f.pieces {
+		var pb []byte
+		pb = pbBytes(pb, 1, []byte(p.Piece))
+		pb = pbFloat(pb, 2, p.Score)
+		if p.Type != spm.PieceNormal {
+			pb = pbVarint(pb, 3, int64(p.Type))
+		}
+		buf = pbBytes(buf, 1, pb)
+	}
+
+	var trainer []byte
+	trainer = pbVarint(trainer, 3, int64(f.modelType))
+	if f.byteFallback {
+		trainer = pbVarint(trainer, 35, 1)
+	}
+	trainer = pbVarint(trainer, 43, -1) // pad_id, negative int32
+	buf = pbBytes(buf, 2, trainer)
+
+	var norm []byte
+	norm = pbBytes(norm, 1, []byte("identity"))
+	if !f.addDummyPrefix {
+		norm = pbVarint(norm, 3, 0)
+	}
+	buf = pbBytes(buf, 3, norm)
+
+	return buf
+}
+
+func (f spmFixture) write(t *testing.T) string {
+	file := filepath.Join(t.TempDir(), "tokenizer.model")
+	if err := os.WriteFile(file, f.bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func spmSpecialPieces() []spm.Piece {
+	return []spm.Piece{
+		{Piece: "<unk>", Type: spm.PieceUnknown},
+		{Piece: "<s>", Type: spm.PieceControl},
+		{Piece: "</s>", Type: spm.PieceControl},
+		{Piece: "<sep>", Type: spm.PieceUserDefined},
+	}
+}
+
+func TestFromSentencePieceFile_Unigram(t *testing.T) {
+	pieces := append(spmSpecialPieces(),
+		spm.Piece{Piece: "▁hello", Score: -1, Type: spm.PieceNormal},
+		spm.Piece{Piece: "▁world", Score: -1.5, Type: spm.PieceNormal},
+		spm.Piece{Piece: "▁", Score: -2, Type: spm.PieceNormal},
+	)
+	for _, r := range "helowrd" {
+		pieces = append(pieces, spm.Piece{Piece: string(r), Score: -3, Type: spm.PieceNormal})
+	}
+	file := spmFixture{modelType: spm.ModelUnigram, addDummyPrefix: true, pieces: pieces}.write(t)
+
+	tk, err := FromSentencePieceFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	en, err := tk.EncodeSingle("  hello   world<sep>held ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTokens := []string{"▁hello", "▁world", "<sep>", "h", "e", "l", "d"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want tokens %q, got %q", wantTokens, en.Tokens)
+	}
+	wantIds := []int{4, 5, 3, 7, 8, 9, 13}
+	if !reflect.DeepEqual(wantIds, en.Ids) {
+		t.Errorf("want ids %v, got %v", wantIds, en.Ids)
+	}
+
+	en, err = tk.EncodeSingle("hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := append([]int{1}, en.Ids...)
+	ids = append(ids, 2)
+	if got := tk.Decode(ids, true); got != "hello world" {
+		t.Errorf("want %q, got %q", "hello world", got)
+	}
+	// As in HF, only a dummy prefix of the first token is dropped.
+	if got := tk.Decode(ids, false); got != "<s> hello world</s>" {
+		t.Errorf("want %q, got %q", "<s> hello world</s>", got)
+	}
+}
+
+func TestFromSentencePieceFile_BPE(t *testing.T) {
+	pieces := append(spmSpecialPieces(),
+		spm.Piece{Piece: "▁h", Score: -1, Type: spm.PieceNormal},
+		spm.Piece{Piece: "ll", Score: -2, Type: spm.PieceNormal},
+		spm.Piece{Piece: "▁he", Score: -3, Type: spm.PieceNormal},
+		spm.Piece{Piece: "llo", Score: -4, Type: spm.PieceNormal},
+		spm.Piece{Piece: "▁hello", Score: -5, Type: spm.PieceNormal},
+		spm.Piece{Piece: "<0x21>", Type: spm.PieceByte},
+	)
+	for _, r := range "▁helo" {
+		pieces = append(pieces, spm.Piece{Piece: string(r), Score: -10, Type: spm.PieceNormal})
+	}
+	file := spmFixture{modelType: spm.ModelBPE, byteFallback: true, addDummyPrefix: true, pieces: pieces}.write(t)
+
+	tk, err := FromSentencePieceFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	en, err := tk.EncodeSingle("hello hell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTokens := []string{"▁hello", "▁he", "ll"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want tokens %q, got %q", wantTokens, en.Tokens)
+	}
+
+	ids := append([]int{1}, en.Ids...)
+	ids = append(ids, 9) // <0x21>
+	if got := tk.Decode(ids, true); got != "hello hell!" {
+		t.Errorf("want %q, got %q", "hello hell!", got)
+	}
+
+	// Unknown chars fall back to their byte pieces, or to a single `<unk>`.
+	en, err = tk.EncodeSingle("hell!??")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTokens = []string{"▁he", "ll", "<0x21>", "<unk>"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want tokens %q, got %q", wantTokens, en.Tokens)
+	}
+}
+
+func TestFromSentencePieceFile_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	valid := spmFixture{modelType: spm.ModelUnigram, pieces: spmSpecialPieces()}.bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated", valid[:len(valid)-3]},
+		{"empty", []byte{}},
+		{"unsupported model type", spmFixture{modelType: spm.ModelWord, pieces: spmSpecialPieces()}.bytes()},
+	}
+
+	for _, tt := range tests {
+		file := filepath.Join(dir, tt.name+".model")
+		if err := os.WriteFile(file, tt.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := FromSentencePieceFile(file); err == nil {
+			t.Errorf("%v: want error, got nil", tt.name)
+		}
+	}
+}
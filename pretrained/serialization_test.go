@@ -0,0 +1,273 @@
+package pretrained
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/decoder"
+	"github.com/season-studio/tokenizer/model/charlevel"
+	"github.com/season-studio/tokenizer/spm"
+)
+
+// assertRoundTrip serializes tk, loads it back and checks that both tokenizers
+// serialize and encode the same.
+func assertRoundTrip(t *testing.T, tk *tokenizer.Tokenizer, docs ...string) *tokenizer.Tokenizer {
+	t.Helper()
+
+	data, err := tk.Serialize(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := FromReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("load serialized tokenizer: %v\n%s", err, data)
+	}
+	got, err := loaded.Serialize(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != data {
+		t.Errorf("want %s, got %s", data, got)
+	}
+
+	for _, doc := range docs {
+		want, err := tk.EncodePair(doc, doc, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := loaded.EncodePair(doc, doc, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%q: want %+v, got %+v", doc, want, got)
+		}
+		if w, g := tk.Decode(want.Ids, false), loaded.Decode(got.Ids, false); w != g {
+			t.Errorf("%q: want decoded %q, got %q", doc, w, g)
+		}
+	}
+
+	return loaded
+}
+
+const serializationConfig = `{
+  "version": "1.0",
+  "truncation": {"direction": "Right", "max_length": 32, "strategy": "OnlySecond", "stride": 2},
+  "padding": {"strategy": {"Fixed": 40}, "direction": "Left", "pad_to_multiple_of": null, "pad_id": 0, "pad_type_id": 1, "pad_token": "[PAD]"},
+  "added_tokens": [
+    {"id": 0, "content": "[PAD]", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 1, "content": "[UNK]", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 2, "content": "[CLS]", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 3, "content": "[SEP]", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 13, "content": "<new>", "single_word": true, "lstrip": false, "rstrip": true, "normalized": true, "special": false}
+  ],
+  "normalizer": {"type": "Sequence", "normalizers": [
+    {"type": "BertNormalizer", "clean_text": true, "handle_chinese_chars": true, "strip_accents": false, "lowercase": true},
+    {"type": "NFC"},
+    {"type": "StripAccents"},
+    {"type": "Replace", "pattern": {"Regex": "[0-9]+"}, "content": "0"},
+    {"type": "Replace", "pattern": {"String": "&"}, "content": " and "},
+    {"type": "BidiControl", "mode": "isolate"},
+    {"type": "Strip", "strip_left": true, "strip_right": false},
+    {"type": "Lowercase"}
+  ]},
+  "pre_tokenizer": {"type": "Sequence", "pretokenizers": [
+    {"type": "Split", "pattern": {"String": "-"}, "behavior": "Removed", "invert": false},
+    {"type": "CharDelimiterSplit", "delimiter": "_"},
+    {"type": "Punctuation", "behavior": "Isolated"},
+    {"type": "Digits", "individual_digits": true},
+    {"type": "WhitespaceSplit"}
+  ]},
+  "post_processor": {"type": "TemplateProcessing",
+    "single": [{"SpecialToken": {"id": "[CLS]", "type_id": 0}}, {"Sequence": {"id": "A", "type_id": 0}}, {"SpecialToken": {"id": "[SEP]", "type_id": 0}}],
+    "pair": [{"SpecialToken": {"id": "[CLS]", "type_id": 0}}, {"Sequence": {"id": "A", "type_id": 0}}, {"SpecialToken": {"id": "[SEP]", "type_id": 0}}, {"Sequence": {"id": "B", "type_id": 1}}, {"SpecialToken": {"id": "[SEP]", "type_id": 1}}],
+    "special_tokens": {
+      "[CLS]": {"id": "[CLS]", "ids": [2], "tokens": ["[CLS]"]},
+      "[SEP]": {"id": "[SEP]", "ids": [3], "tokens": ["[SEP]"]}
+    }
+  },
+  "decoder": {"type": "WordPiece", "prefix": "##", "cleanup": true},
+  "model": {"type": "WordPiece", "unk_token": "[UNK]", "continuing_subword_prefix": "##", "max_input_chars_per_word": 100,
+    "vocab": {"[PAD]": 0, "[UNK]": 1, "[CLS]": 2, "[SEP]": 3, "hello": 4, "world": 5, "##s": 6, "and": 7, "0": 8, ",": 9, "wor": 10, "##ld": 11, "1": 12}
+  }
+}`
+
+func TestSerialize_RoundTrip(t *testing.T) {
+	tk, err := FromReader(strings.NewReader(serializationConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := assertRoundTrip(t, tk, "Hello-worlds, 2024 & 1_world <new>", "  H\u00e9llo\u200fworld")
+
+	en, err := loaded.EncodeSingle("Hello-worlds & 12", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"[CLS]", "hello", "world", "##s", "and", "0", "[SEP]"}
+	if got := en.Tokens[len(en.Tokens)-len(want):]; !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestSerialize_Format(t *testing.T) {
+	tk, err := FromReader(strings.NewReader(serializationConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := tk.Serialize(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(data, "\n  \"version\": \"1.0\",\n") {
+		t.Errorf("want indented json, got %s", data)
+	}
+
+	// The serialized tokenizer matches its source config.
+	var want, got map[string]interface{}
+	if err := json.Unmarshal([]byte(serializationConfig), &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"truncation", "padding", "added_tokens", "normalizer", "pre_tokenizer", "post_processor", "decoder", "model"} {
+		if !reflect.DeepEqual(want[key], got[key]) {
+			t.Errorf("%v: want %v, got %v", key, want[key], got[key])
+		}
+	}
+}
+
+func TestSerialize_SentencePiece(t *testing.T) {
+	unigramPieces := append(spmSpecialPieces(),
+		spm.Piece{Piece: "▁hello", Score: -1, Type: spm.PieceNormal},
+		spm.Piece{Piece: "▁world", Score: -1.5, Type: spm.PieceNormal},
+		spm.Piece{Piece: "▁", Score: -2, Type: spm.PieceNormal},
+	)
+	bpePieces := append(spmSpecialPieces(),
+		spm.Piece{Piece: "▁h", Score: -1, Type: spm.PieceNormal},
+		spm.Piece{Piece: "ll", Score: -2, Type: spm.PieceNormal},
+		spm.Piece{Piece: "▁he", Score: -3, Type: spm.PieceNormal},
+		spm.Piece{Piece: "<0x21>", Type: spm.PieceByte},
+	)
+	for _, r := range "▁helowrd" {
+		unigramPieces = append(unigramPieces, spm.Piece{Piece: string(r), Score: -3, Type: spm.PieceNormal})
+		bpePieces = append(bpePieces, spm.Piece{Piece: string(r), Score: -10, Type: spm.PieceNormal})
+	}
+
+	fixtures := []spmFixture{
+		{modelType: spm.ModelUnigram, addDummyPrefix: true, pieces: unigramPieces},
+		{modelType: spm.ModelBPE, byteFallback: true, addDummyPrefix: true, pieces: bpePieces},
+	}
+	for _, f := range fixtures {
+		tk, err := FromSentencePieceFile(f.write(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertRoundTrip(t, tk, "  hello   world<sep>held ", "hello hell!")
+	}
+}
+
+func TestSerialize_Tiktoken(t *testing.T) {
+	enc := TiktokenEncoding{Name: "test", Pattern: Cl100kBase.Pattern, SpecialTokens: map[string]int{"<|endoftext|>": 260}}
+	tk, err := FromTiktokenReader(strings.NewReader(tiktokenRanks("he", "ll", "hell", " hell")), enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRoundTrip(t, tk, "hello  hell \n\n world<|endoftext|>")
+}
+
+// byT5Config is a ByT5 tokenizer: a byte-level model of ids shifted by the
+// `<pad>`, `</s>` and `<unk>` special tokens.
+const byT5Config = `{
+  "version": "1.0",
+  "added_tokens": [
+    {"id": 0, "content": "<pad>", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 1, "content": "</s>", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true},
+    {"id": 2, "content": "<unk>", "single_word": false, "lstrip": false, "rstrip": false, "normalized": false, "special": true}
+  ],
+  "post_processor": {
+    "type": "TemplateProcessing",
+    "single": [{"Sequence": {"id": "A", "type_id": 0}}, {"SpecialToken": {"id": "</s>", "type_id": 0}}],
+    "pair": [{"Sequence": {"id": "A", "type_id": 0}}, {"Sequence": {"id": "B", "type_id": 0}}, {"SpecialToken": {"id": "</s>", "type_id": 0}}],
+    "special_tokens": {"</s>": {"id": "</s>", "ids": [1], "tokens": ["</s>"]}}
+  },
+  "decoder": {"type": "Sequence", "decoders": [{"type": "ByteFallback"}, {"type": "Fuse"}]},
+  "model": {"type": "ByteLevel", "offset": 3}
+}`
+
+func TestSerialize_CharAndByteLevel(t *testing.T) {
+	tk, err := FromReader(strings.NewReader(byT5Config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	en, err := tk.EncodeSingle("hé</s>", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{107, 198, 172, 1, 1}; !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want ids %v, got %v", want, en.Ids)
+	}
+	if got := tk.Decode(en.Ids, true); got != "hé" {
+		t.Errorf("want %q, got %q", "hé", got)
+	}
+	assertRoundTrip(t, tk, "hello wörld 🚀")
+
+	tk = tokenizer.NewTokenizer(charlevel.NewFromText([]string{"hello world"}, "<unk>"))
+	tk.WithDecoder(decoder.NewFuse())
+	en, err = tk.EncodeSingle("hello wörld", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 3, 4, 5, 6, 0, 7, 3, 8}; !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want ids %v, got %v", want, en.Ids)
+	}
+	if got := tk.Decode(en.Ids, false); got != "hello w<unk>rld" {
+		t.Errorf("want %q, got %q", "hello w<unk>rld", got)
+	}
+	assertRoundTrip(t, tk, "hello wörld")
+}
+
+func TestSave(t *testing.T) {
+	tk, err := FromReader(strings.NewReader(serializationConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := tk.Save(file, true); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := FromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := tk.GetVocab(true), loaded.GetVocab(true); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if err := tk.Save(filepath.Join(t.TempDir(), "missing", "tokenizer.json"), false); err == nil {
+		t.Errorf("want error, got nil")
+	}
+}
+
+func TestSerialize_PadToMultipleOf(t *testing.T) {
+	tk, err := FromReader(strings.NewReader(serializationConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := *tk.GetPadding()
+	padding.PadToMultipleOf = 8
+	tk.WithPadding(&padding)
+
+	loaded := assertRoundTrip(t, tk, "hello world")
+	if got := loaded.GetPadding().PadToMultipleOf; got != 8 {
+		t.Errorf("want 8, got %v", got)
+	}
+}
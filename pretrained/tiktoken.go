@@ -0,0 +1,223 @@
+package pretrained
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model/bpe"
+	"github.com/season-studio/tokenizer/pretokenizer"
+)
+
+// TiktokenEncoding describes an OpenAI tiktoken encoding: its split pattern and
+// its special tokens, which are not part of the mergeable ranks files.
+type TiktokenEncoding struct {
+	Name          string
+	Pattern       string
+	SpecialTokens map[string]int
+}
+
+// Well-known tiktoken encodings.
+var (
+	R50kBase = TiktokenEncoding{
+		Name:          "r50k_base",
+		Pattern:       pretokenizer.R50kPattern,
+		SpecialTokens: map[string]int{"<|endoftext|>": 50256},
+	}
+	Cl100kBase = TiktokenEncoding{
+		Name:    "cl100k_base",
+		Pattern: pretokenizer.Cl100kPattern,
+		SpecialTokens: map[string]int{
+			"<|endoftext|>":   100257,
+			"<|fim_prefix|>":  100258,
+			"<|fim_middle|>":  100259,
+			"<|fim_suffix|>":  100260,
+			"<|endofprompt|>": 100276,
+		},
+	}
+	O200kBase = TiktokenEncoding{
+		Name:    "o200k_base",
+		Pattern: pretokenizer.O200kPattern,
+		SpecialTokens: map[string]int{
+			"<|endoftext|>":   199999,
+			"<|endofprompt|>": 200018,
+		},
+	}
+)
+
+// FromTiktokenFile constructs a byte-level BPE Tokenizer from a tiktoken
+// mergeable ranks file (i.e. 'cl100k_base.tiktoken'), each line being a base64
+// encoded token and its rank. The split pattern and special tokens come from
+// `enc`, i.e. `Cl100kBase`.
+func FromTiktokenFile(file string, enc TiktokenEncoding) (*tokenizer.Tokenizer, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return FromTiktokenReader(f, enc)
+}
+
+// FromTiktokenReader constructs a byte-level BPE Tokenizer from tiktoken
+// mergeable ranks data. See `FromTiktokenFile`.
+func FromTiktokenReader(r io.Reader, enc TiktokenEncoding) (*tokenizer.Tokenizer, error) {
+	ranks, err := readTiktokenRanks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	vocab := make(map[string]int, len(ranks)+len(enc.SpecialTokens))
+	for tok, rank := range ranks {
+		vocab[toByteLevelChars(tok)] = rank
+	}
+	for tok, id := range enc.SpecialTokens {
+		vocab[tok] = id
+	}
+
+	model, err := bpe.New(vocab, tiktokenMerges(ranks))
+	if err != nil {
+		return nil, fmt.Errorf("FromTiktokenReader: %w", err)
+	}
+
+	return newTiktokenTokenizer(model, enc)
+}
+
+// FromEncoderJSON constructs a GPT-2 Tokenizer from the original OpenAI
+// `encoder.json` and `vocab.bpe` files, with the r50k_base split pattern and
+// special tokens.
+func FromEncoderJSON(encoderFile, vocabBPEFile string) (*tokenizer.Tokenizer, error) {
+	model, err := bpe.NewBpeFromFiles(encoderFile, vocabBPEFile)
+	if err != nil {
+		return nil, fmt.Errorf("FromEncoderJSON: %w", err)
+	}
+
+	return newTiktokenTokenizer(model, R50kBase)
+}
+
+func newTiktokenTokenizer(model tokenizer.Model, enc TiktokenEncoding) (*tokenizer.Tokenizer, error) {
+	pretok, err := pretokenizer.NewTiktoken(enc.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	tk := tokenizer.NewTokenizer(model)
+	tk.WithPreTokenizer(pretok)
+	tk.WithDecoder(pretokenizer.NewByteLevel())
+
+	// Register special tokens by id for determinism.
+	specials := make([]string, 0, len(enc.SpecialTokens))
+	for tok := range enc.SpecialTokens {
+		specials = append(specials, tok)
+	}
+	sort.Slice(specials, func(i, j int) bool { return enc.SpecialTokens[specials[i]] < enc.SpecialTokens[specials[j]] })
+
+	var addedToks []tokenizer.AddedToken
+	for _, tok := range specials {
+		if _, ok := model.TokenToId(tok); !ok {
+			return nil, fmt.Errorf("special token %q of %v is not in the vocab", tok, enc.Name)
+		}
+		addedToks = append(addedToks, tokenizer.NewAddedToken(tok, true))
+	}
+	if len(addedToks) > 0 {
+		tk.AddSpecialTokens(addedToks)
+	}
+
+	return tk, nil
+}
+
+// readTiktokenRanks reads the raw byte tokens and their ranks of a tiktoken file.
+func readTiktokenRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+
+	s := bufio.NewScanner(r)
+	var lineNum int
+	for s.Scan() {
+		lineNum++
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Read tiktoken file error: invalid data at line %d", lineNum)
+		}
+		tok, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("Read tiktoken file error: invalid token at line %d: %w", lineNum, err)
+		}
+		rank, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("Read tiktoken file error: invalid rank at line %d: %w", lineNum, err)
+		}
+		ranks[string(tok)] = rank
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return ranks, nil
+}
+
+// tiktokenMerges rebuilds the BPE merges of tiktoken ranks, which only list
+// tokens: each token is split into the two parts tiktoken merges last when
+// encoding it with the lower ranked tokens. Merges are ordered by the rank of
+// the merged token.
+func tiktokenMerges(ranks map[string]int) []string {
+	type merge struct {
+		left, right string
+		rank        int
+	}
+	var merges []merge
+	for tok, rank := range ranks {
+		if len(tok) < 2 {
+			continue
+		}
+
+		parts := make([]string, len(tok))
+		for i := 0; i < len(tok); i++ {
+			parts[i] = tok[i : i+1]
+		}
+		for len(parts) > 2 {
+			best, bestRank := -1, rank
+			for i := 0; i < len(parts)-1; i++ {
+				if r, ok := ranks[parts[i]+parts[i+1]]; ok && r < bestRank {
+					best, bestRank = i, r
+				}
+			}
+			if best < 0 {
+				break
+			}
+			parts[best] += parts[best+1]
+			parts = append(parts[:best+1], parts[best+2:]...)
+		}
+		if len(parts) == 2 {
+			merges = append(merges, merge{parts[0], parts[1], rank})
+		}
+	}
+	sort.Slice(merges, func(i, j int) bool { return merges[i].rank < merges[j].rank })
+
+	out := make([]string, len(merges))
+	for i, m := range merges {
+		out[i] = toByteLevelChars(m.left) + " " + toByteLevelChars(m.right)
+	}
+
+	return out
+}
+
+// toByteLevelChars maps the bytes of s to their byte-level chars.
+func toByteLevelChars(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		sb.WriteString(pretokenizer.BytesChar[s[i]])
+	}
+
+	return sb.String()
+}
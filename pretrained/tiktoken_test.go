@@ -0,0 +1,108 @@
+package pretrained
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer/pretokenizer"
+)
+
+// tiktokenRanks builds tiktoken ranks data with all bytes followed by tokens.
+func tiktokenRanks(tokens ...string) string {
+	var sb strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	for i, tok := range tokens {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), 256+i)
+	}
+
+	return sb.String()
+}
+
+func TestFromTiktokenReader(t *testing.T) {
+	enc := TiktokenEncoding{
+		Name:          "test",
+		Pattern:       pretokenizer.Cl100kPattern,
+		SpecialTokens: map[string]int{"<|endoftext|>": 300, "<|endofprompt|>": 302},
+	}
+	data := tiktokenRanks("he", "ll", "hell", " hell", "é") // é is 2 bytes
+
+	tk, err := FromTiktokenReader(strings.NewReader(data), enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := "hello  hell é<|endoftext|>"
+	en, err := tk.EncodeSingle(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTokens := []string{"hell", "o", "Ġ", "Ġhell", "Ġ", "Ã©", "<|endoftext|>"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want tokens %q, got %q", wantTokens, en.Tokens)
+	}
+	wantIds := []int{258, 'o', ' ', 259, ' ', 260, 300}
+	if !reflect.DeepEqual(wantIds, en.Ids) {
+		t.Errorf("want ids %v, got %v", wantIds, en.Ids)
+	}
+
+	if got := tk.Decode(en.Ids, false); got != doc {
+		t.Errorf("want %q, got %q", doc, got)
+	}
+	if got, want := tk.Decode(en.Ids, true), "hello  hell é"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	got := tk.GetSpecialTokens()
+	sort.Strings(got)
+	if want := []string{"<|endofprompt|>", "<|endoftext|>"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("want special tokens %q, got %q", want, got)
+	}
+}
+
+func TestFromTiktokenReader_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		enc  TiktokenEncoding
+	}{
+		{"missing rank", "aGVsbG8=\n", Cl100kBase},
+		{"invalid base64", "!!! 1\n", Cl100kBase},
+		{"invalid rank", "aGVsbG8= x\n", Cl100kBase},
+		{"invalid pattern", tiktokenRanks(), TiktokenEncoding{Name: "test", Pattern: `(?=a)`}},
+	}
+
+	for _, tt := range tests {
+		if _, err := FromTiktokenReader(strings.NewReader(tt.data), tt.enc); err == nil {
+			t.Errorf("%v: want error, got nil", tt.name)
+		}
+	}
+}
+
+func TestFromEncoderJSON(t *testing.T) {
+	encoderFile, vocabBPEFile := writeBPEFiles(t)
+
+	tk, err := FromEncoderJSON(encoderFile, vocabBPEFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := "Hello  world<|endoftext|>"
+	en, err := tk.EncodeSingle(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTokens := []string{"H", "e", "l", "l", "o", "Ġ", "Ġw", "o", "r", "l", "d", "<|endoftext|>"}
+	if !reflect.DeepEqual(wantTokens, en.Tokens) {
+		t.Errorf("want tokens %q, got %q", wantTokens, en.Tokens)
+	}
+	if got, want := tk.Decode(en.Ids, true), "Hello  world"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,303 @@
+package pretrained
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/season-studio/tokenizer"
+)
+
+// TokenizerConfig holds the settings of the `tokenizer_config.json` and
+// `special_tokens_map.json` files shipped along `tokenizer.json`, which
+// transformers' AutoTokenizer applies on top of it. See `Apply`.
+type TokenizerConfig struct {
+	TokenizerClass string // i.e. "LlamaTokenizer", "" if unset
+	ModelMaxLength int    // max number of tokens of the model, 0 if unset
+	PaddingSide    string // "right" or "left", "" if unset
+	TruncationSide string // "right" or "left", "" if unset
+
+	// SpecialTokens are the `*_token` entries, i.e. "bos_token": "<s>".
+	SpecialTokens           map[string]string
+	AdditionalSpecialTokens []string
+
+	// AddBosToken and AddEosToken tell whether the post-processor adds the BOS
+	// and EOS tokens, nil if unset.
+	AddBosToken *bool
+	AddEosToken *bool
+}
+
+// maxModelMaxLength is the largest meaningful `model_max_length`;
+// transformers writes int(1e30) when the model has no limit.
+const maxModelMaxLength = 1 << 40
+
+// LoadTokenizerConfig reads the given `tokenizer_config.json` and
+// `special_tokens_map.json` files, the entries of later files overriding the
+// ones of earlier files as in transformers. Unknown entries are ignored.
+func LoadTokenizerConfig(files ...string) (*TokenizerConfig, error) {
+	c := &TokenizerConfig{SpecialTokens: make(map[string]string)}
+	for _, file := range files {
+		config, err := readJSONConfig(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.decode(config); err != nil {
+			return nil, fmt.Errorf("Read %v error: %w", filepath.Base(file), err)
+		}
+	}
+
+	return c, nil
+}
+
+// decode sets the entries of config.
+func (c *TokenizerConfig) decode(config map[string]json.RawMessage) error {
+	for key, value := range config {
+		if string(value) == "null" {
+			if strings.HasSuffix(key, "_token") {
+				delete(c.SpecialTokens, key)
+			}
+			continue
+		}
+
+		var err error
+		switch {
+		case key == "tokenizer_class":
+			err = json.Unmarshal(value, &c.TokenizerClass)
+		case key == "model_max_length":
+			var n float64
+			if err = json.Unmarshal(value, &n); err == nil {
+				c.ModelMaxLength = 0
+				if n > 0 && n < maxModelMaxLength {
+					c.ModelMaxLength = int(n)
+				}
+			}
+		case key == "padding_side", key == "truncation_side":
+			var side string
+			if err = json.Unmarshal(value, &side); err == nil && side != "left" && side != "right" {
+				err = fmt.Errorf("want \"left\" or \"right\", got %q", side)
+			}
+			if key == "padding_side" {
+				c.PaddingSide = side
+			} else {
+				c.TruncationSide = side
+			}
+		case key == "add_bos_token", key == "add_eos_token":
+			var add bool
+			if err = json.Unmarshal(value, &add); err == nil {
+				if key == "add_bos_token" {
+					c.AddBosToken = &add
+				} else {
+					c.AddEosToken = &add
+				}
+			}
+		case key == "additional_special_tokens":
+			var values []json.RawMessage
+			if err = json.Unmarshal(value, &values); err == nil {
+				c.AdditionalSpecialTokens = nil
+				for _, v := range values {
+					if tok := specialTokenContent(v); tok != "" {
+						c.AdditionalSpecialTokens = append(c.AdditionalSpecialTokens, tok)
+					}
+				}
+			}
+		case strings.HasSuffix(key, "_token"):
+			if tok := specialTokenContent(value); tok != "" {
+				c.SpecialTokens[key] = tok
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// specialTokens returns the special tokens of the config, sorted.
+func (c *TokenizerConfig) specialTokens() []string {
+	var tokens []string
+	for _, tok := range c.SpecialTokens {
+		tokens = append(tokens, tok)
+	}
+	tokens = append(tokens, c.AdditionalSpecialTokens...)
+	sort.Strings(tokens)
+
+	return tokens
+}
+
+// bosEosTokenizerClasses are the tokenizers whose post-processor follows
+// `add_bos_token` and `add_eos_token` in transformers.
+var bosEosTokenizerClasses = map[string]bool{
+	"LlamaTokenizer":         true,
+	"LlamaTokenizerFast":     true,
+	"CodeLlamaTokenizer":     true,
+	"CodeLlamaTokenizerFast": true,
+	"GemmaTokenizer":         true,
+	"GemmaTokenizerFast":     true,
+}
+
+// Apply applies the config to tk as AutoTokenizer does:
+//   - the special tokens of the vocab are registered as special tokens,
+//   - the padding side and pad token are set on the padding params, if any,
+//   - for LLaMA and Gemma tokenizers, the post-processor adds the BOS and EOS
+//     tokens following `add_bos_token` (true by default) and `add_eos_token`.
+//
+// As AutoTokenizer, it does not enable truncation nor padding, see
+// `Truncation` and `Padding`. The truncation side is not supported.
+func (c *TokenizerConfig) Apply(tk *tokenizer.Tokenizer) error {
+	addSpecialTokens(tk, c.specialTokens())
+
+	if padding := tk.GetPadding(); padding != nil {
+		params := *padding
+		if err := c.setPadding(tk, &params); err != nil {
+			return err
+		}
+		tk.WithPadding(&params)
+	}
+
+	if bosEosTokenizerClasses[c.TokenizerClass] && (c.AddBosToken != nil || c.AddEosToken != nil) {
+		addBos := c.AddBosToken == nil || *c.AddBosToken
+		addEos := c.AddEosToken != nil && *c.AddEosToken
+		bosToken, bos, err := c.specialTokenId(tk, "bos_token", addBos)
+		if err != nil {
+			return err
+		}
+		eosToken, eos, err := c.specialTokenId(tk, "eos_token", addEos)
+		if err != nil {
+			return err
+		}
+		tp, err := bosEosTemplateProcessing(bosToken, bos, eosToken, eos)
+		if err != nil {
+			return fmt.Errorf("TokenizerConfig error: %w", err)
+		}
+		tk.WithPostProcessor(tp)
+	}
+
+	return nil
+}
+
+// specialTokenId returns the token of the given special token entry and its id,
+// -1 if not wanted.
+func (c *TokenizerConfig) specialTokenId(tk *tokenizer.Tokenizer, key string, want bool) (string, int, error) {
+	if !want {
+		return "", -1, nil
+	}
+	tok := c.SpecialTokens[key]
+	id, ok := tk.TokenToId(tok)
+	if !ok {
+		return "", -1, fmt.Errorf("TokenizerConfig error: %v %q: %w", key, tok, tokenizer.ErrTokenNotInVocab)
+	}
+
+	return tok, id, nil
+}
+
+// setPadding sets the padding side and the pad token of the config on params.
+func (c *TokenizerConfig) setPadding(tk *tokenizer.Tokenizer, params *tokenizer.PaddingParams) error {
+	switch c.PaddingSide {
+	case "left":
+		params.Direction = tokenizer.Left
+	case "right":
+		params.Direction = tokenizer.Right
+	}
+	if tok, ok := c.SpecialTokens["pad_token"]; ok {
+		id, ok := tk.TokenToId(tok)
+		if !ok {
+			return fmt.Errorf("TokenizerConfig error: pad_token %q: %w", tok, tokenizer.ErrTokenNotInVocab)
+		}
+		params.PadToken, params.PadId = tok, id
+	}
+
+	return nil
+}
+
+// Truncation returns the truncation params of AutoTokenizer's
+// `truncation=True`: the longest sequence first down to `model_max_length`.
+// It returns nil if the model has no max length.
+func (c *TokenizerConfig) Truncation() *tokenizer.TruncationParams {
+	if c.ModelMaxLength <= 0 {
+		return nil
+	}
+
+	return &tokenizer.TruncationParams{
+		MaxLength: c.ModelMaxLength,
+		Strategy:  tokenizer.LongestFirst,
+	}
+}
+
+// Padding returns the padding params of AutoTokenizer's `padding=True`: to
+// the longest encoding of the batch, on the padding side with the pad token of
+// the config. It fails if the config has no pad token in the vocab of tk.
+func (c *TokenizerConfig) Padding(tk *tokenizer.Tokenizer) (*tokenizer.PaddingParams, error) {
+	if _, ok := c.SpecialTokens["pad_token"]; !ok {
+		return nil, errors.New("TokenizerConfig error: no pad_token")
+	}
+	params := &tokenizer.PaddingParams{
+		Strategy:  *tokenizer.NewPaddingStrategy(tokenizer.WithBatchLongest()),
+		Direction: tokenizer.Right,
+	}
+	if err := c.setPadding(tk, params); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// FromDir constructs a new Tokenizer from the `tokenizer.json` file of a
+// model directory, applying its `tokenizer_config.json` and
+// `special_tokens_map.json` files if any, see `TokenizerConfig.Apply`, and the
+// chat template of `tokenizer_config.json`.
+func FromDir(dir string) (*tokenizer.Tokenizer, error) {
+	tk, err := FromFile(filepath.Join(dir, tokenizer.TokenizerName))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range []string{TokenizerConfigName, SpecialTokensMapName} {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+	}
+	if err := applyConfigFiles(tk, files); err != nil {
+		return nil, err
+	}
+
+	return tk, nil
+}
+
+// applyConfigFiles applies the given `tokenizer_config.json` and
+// `special_tokens_map.json` files to tk, along with the chat template of the
+// former.
+func applyConfigFiles(tk *tokenizer.Tokenizer, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	config, err := LoadTokenizerConfig(files...)
+	if err != nil {
+		return err
+	}
+	if err := config.Apply(tk); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if filepath.Base(file) != TokenizerConfigName {
+			continue
+		}
+		template, err := LoadChatTemplate(file)
+		if err != nil {
+			return err
+		}
+		if template != nil {
+			tk.WithChatTemplate(template)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package pretrained
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLoadTokenizerConfig(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		TokenizerConfigName:  `{"tokenizer_class": "LlamaTokenizer", "model_max_length": 1000000000000000019884624838656, "padding_side": "left", "bos_token": {"content": "<s>"}, "eos_token": "</s>", "unk_token": "<unk>", "add_eos_token": true, "additional_special_tokens": ["<a>"]}`,
+		SpecialTokensMapName: `{"eos_token": "<eos>", "unk_token": null, "additional_special_tokens": ["<b>"]}`,
+	})
+
+	c, err := LoadTokenizerConfig(filepath.Join(dir, TokenizerConfigName), filepath.Join(dir, SpecialTokensMapName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addEos := true
+	want := &TokenizerConfig{
+		TokenizerClass:          "LlamaTokenizer",
+		PaddingSide:             "left",
+		SpecialTokens:           map[string]string{"bos_token": "<s>", "eos_token": "<eos>"},
+		AdditionalSpecialTokens: []string{"<b>"},
+		AddEosToken:             &addEos,
+	}
+	if !reflect.DeepEqual(want, c) {
+		t.Errorf("want %+v, got %+v", want, c)
+	}
+	if got := c.Truncation(); got != nil {
+		t.Errorf("want no truncation without max length, got %+v", got)
+	}
+
+	bad := writeFiles(t, map[string]string{TokenizerConfigName: `{"padding_side": "up"}`})
+	if _, err := LoadTokenizerConfig(filepath.Join(bad, TokenizerConfigName)); err == nil {
+		t.Error("want padding_side error")
+	}
+}
+
+func TestFromDir(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		tokenizer.TokenizerName: serializationConfig,
+		TokenizerConfigName:     `{"tokenizer_class": "LlamaTokenizerFast", "model_max_length": 8, "padding_side": "right", "bos_token": "[CLS]", "eos_token": "[SEP]", "add_eos_token": true}`,
+		SpecialTokensMapName:    `{"pad_token": "[UNK]", "additional_special_tokens": ["hello"]}`,
+	})
+
+	tk, err := FromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk.WithTruncation(nil)
+	tk.WithPadding(nil)
+
+	en, err := tk.EncodeSingle("world hello", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"[CLS]", "world", "hello", "[SEP]"}; !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want %q, got %q", want, en.Tokens)
+	}
+	if got := tk.Decode(en.Ids, true); got != "world" {
+		t.Errorf("want special tokens skipped, got %q", got)
+	}
+
+	config, err := LoadTokenizerConfig(filepath.Join(dir, TokenizerConfigName), filepath.Join(dir, SpecialTokensMapName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&tokenizer.TruncationParams{MaxLength: 8, Strategy: tokenizer.LongestFirst}); !reflect.DeepEqual(want, config.Truncation()) {
+		t.Errorf("want truncation %+v, got %+v", want, config.Truncation())
+	}
+	padding, err := config.Padding(tk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if padding.Direction != tokenizer.Right || padding.PadToken != "[UNK]" || padding.PadId != 1 {
+		t.Errorf("want right padding with [UNK], got %+v", padding)
+	}
+
+	config.SpecialTokens["bos_token"] = "<s>"
+	if err := config.Apply(tk); !errors.Is(err, tokenizer.ErrTokenNotInVocab) {
+		t.Errorf("want ErrTokenNotInVocab, got %v", err)
+	}
+}
+
+func TestFromDir_PaddingSide(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		tokenizer.TokenizerName: serializationConfig,
+		TokenizerConfigName:     `{"padding_side": "right", "pad_token": "[UNK]"}`,
+	})
+
+	tk, err := FromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := tk.GetPadding()
+	if padding.Direction != tokenizer.Right || padding.PadToken != "[UNK]" || padding.PadId != 1 {
+		t.Errorf("want right padding with [UNK], got %+v", padding)
+	}
+	if _, ok := padding.Strategy.Value.(int); !ok {
+		t.Errorf("want the fixed padding kept, got %+v", padding.Strategy)
+	}
+}
@@ -1,10 +1,13 @@
 package pretrained
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/season-studio/tokenizer"
 )
@@ -24,6 +27,56 @@ func FromFile(file string) (*tokenizer.Tokenizer, error) {
 	return tk, nil
 }
 
+// FromFileVerified constructs a new Tokenizer from json data file as `FromFile`
+// and checks that the SHA-256 of the file is `expectedSHA256` (hex). The file
+// is hashed while parsing, not read twice. It returns a
+// `*tokenizer.ErrChecksumMismatch` if the file is corrupted, including when the
+// corruption also breaks parsing.
+func FromFileVerified(file, expectedSHA256 string) (*tokenizer.Tokenizer, error) {
+	tk, sum, err := FromFileWithSHA256(file)
+	if sum != "" && !strings.EqualFold(sum, expectedSHA256) {
+		return nil, &tokenizer.ErrChecksumMismatch{
+			Path:     file,
+			Algo:     "sha256",
+			Expected: strings.ToLower(expectedSHA256),
+			Actual:   sum,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tk, nil
+}
+
+// FromFileWithSHA256 constructs a new Tokenizer from json data file as
+// `FromFile` and also returns the SHA-256 (hex) of the file computed while
+// parsing. The checksum is returned even if parsing fails.
+func FromFileWithSHA256(file string) (*tokenizer.Tokenizer, string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	r := io.TeeReader(f, h)
+	tk, parseErr := FromReader(r)
+
+	// Hash what the json decoder did not consume.
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	if parseErr != nil {
+		err := fmt.Errorf("FromReader: %w", parseErr)
+		return nil, sum, err
+	}
+
+	return tk, sum, nil
+}
+
 // FromReader constructs a new Tokenizer from json data reader.
 func FromReader(r io.Reader) (*tokenizer.Tokenizer, error) {
 	var config *tokenizer.Config
@@ -31,7 +84,14 @@ func FromReader(r io.Reader) (*tokenizer.Tokenizer, error) {
 		return nil, err
 	}
 
-	model, err := CreateModel(config)
+	return fromConfig(config, nil)
+}
+
+// fromConfig constructs a new Tokenizer from its config. compiled, if not nil,
+// is the compiled vocab of its model, see `createModel`.
+func fromConfig(config *tokenizer.Config, compiled []byte) (*tokenizer.Tokenizer, error) {
+	// 1. Model
+	model, err := createModel(config, compiled)
 	if err != nil {
 		err = fmt.Errorf("CreateModel: %w", err)
 		return nil, err
@@ -72,12 +132,8 @@ func FromReader(r io.Reader) (*tokenizer.Tokenizer, error) {
 	tk.WithDecoder(decoder)
 
 	// 6. AddedVocabulary
-	specialAddedTokens, addedTokens := CreateAddedTokens(config.AddedTokens)
-	if len(specialAddedTokens) > 0 {
-		tk.AddSpecialTokens(specialAddedTokens)
-	}
-	if len(addedTokens) > 0 {
-		tk.AddTokens(addedTokens)
+	if addedTokens := CreateAddedTokensWithId(config.AddedTokens); len(addedTokens) > 0 {
+		tk.AddTokensWithId(addedTokens)
 	}
 
 	// 7. TruncationParams
@@ -1,8 +1,14 @@
 package pretrained
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"log"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/season-studio/tokenizer"
@@ -402,3 +408,65 @@ func TestSBert(t *testing.T) {
 		t.Errorf("TypeIds should be padded to 128, got %d", len(en.TypeIds))
 	}
 }
+
+const wordLevelConfig = `{
+  "version": "1.0",
+  "added_tokens": [],
+  "normalizer": null,
+  "pre_tokenizer": {"type": "Whitespace"},
+  "post_processor": null,
+  "decoder": null,
+  "model": {
+    "type": "WordLevel",
+    "vocab": {"[UNK]": 0, "hello": 1, "world": 2, "integrity": 3, "check": 4},
+    "unk_token": "[UNK]"
+  }
+}`
+
+func TestFromFileVerified(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := os.WriteFile(file, []byte(wordLevelConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(wordLevelConfig))
+	want := hex.EncodeToString(sum[:])
+
+	tk, got, err := FromFileWithSHA256(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if tk.GetVocabSize(false) != 5 {
+		t.Errorf("want vocab size 5, got %v", tk.GetVocabSize(false))
+	}
+
+	if _, err := FromFileVerified(file, strings.ToUpper(want)); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+
+	// Corrupt the fixture mid-file, breaking parsing or not.
+	mid := strings.Index(wordLevelConfig, `"world": 2`)
+	corruptions := map[string]string{
+		"valid json":   wordLevelConfig[:mid] + `"world": 7` + wordLevelConfig[mid+len(`"world": 2`):],
+		"invalid json": wordLevelConfig[:mid] + "\x00" + wordLevelConfig[mid+1:],
+		"truncated":    wordLevelConfig[:mid],
+	}
+	for name, data := range corruptions {
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := FromFileVerified(file, want)
+		var mismatch *tokenizer.ErrChecksumMismatch
+		if !errors.As(err, &mismatch) {
+			t.Errorf("%v: want ErrChecksumMismatch, got %v", name, err)
+			continue
+		}
+		sum := sha256.Sum256([]byte(data))
+		if mismatch.Expected != want || mismatch.Actual != hex.EncodeToString(sum[:]) {
+			t.Errorf("%v: want expected %v and actual %x, got %v and %v", name, want, sum, mismatch.Expected, mismatch.Actual)
+		}
+	}
+}
@@ -112,7 +112,8 @@ func (bp *BertProcessing) addSpecialToken(encoding *tokenizer.Encoding) *tokeniz
 	}
 
 	wordsOpt := tokenizer.WithWordsEncodingOpt(words)
-	return tokenizer.NewEncoding(ids, typeIds, tokens, offsets, specialTokens, attentionMask, []tokenizer.Encoding{}, wordsOpt)
+	rangeOpt := sequenceRangeOpt(0, 1, encoding.Len())
+	return tokenizer.NewEncoding(ids, typeIds, tokens, offsets, specialTokens, attentionMask, []tokenizer.Encoding{}, wordsOpt, rangeOpt)
 }
 
 // pairAddSpecialToken adds special token "[SEP]" to input encoding. It ignores
@@ -151,6 +152,17 @@ func (bp *BertProcessing) pairAddSpecialToken(pairEncoding *tokenizer.Encoding)
 	pairAttentionMask = append(pairAttentionMask, 1)
 
 	pairWordsOpt := tokenizer.WithWordsEncodingOpt(pairWords)
+	pairRangeOpt := sequenceRangeOpt(1, 0, pairEncoding.Len())
 
-	return tokenizer.NewEncoding(pairIds, pairTypeIds, pairTokens, pairOffsets, pairSpecialTokens, pairAttentionMask, []tokenizer.Encoding{}, pairWordsOpt)
+	return tokenizer.NewEncoding(pairIds, pairTypeIds, pairTokens, pairOffsets, pairSpecialTokens, pairAttentionMask, []tokenizer.Encoding{}, pairWordsOpt, pairRangeOpt)
+}
+
+// sequenceRangeOpt sets the range of the `n` tokens of sequence `sequenceId`
+// starting at token `start`.
+func sequenceRangeOpt(sequenceId, start, n int) tokenizer.EncodingOpt {
+	ranges := make(map[int]tokenizer.Range)
+	if n > 0 {
+		ranges[sequenceId] = tokenizer.NewRange(start, start+n)
+	}
+	return tokenizer.WithSequenceRangeEncodingOpt(ranges)
 }
@@ -25,3 +25,7 @@ func (blp *ByteLevelProcessing) AddedTokens(isPair bool) (retVal int) {
 func (blp *ByteLevelProcessing) Process(encoding, pairEncoding *tokenizer.Encoding, addSpecialTokens bool) (retVal *tokenizer.Encoding) {
 	return blp.pretok.Process(encoding, pairEncoding, addSpecialTokens)
 }
+
+func (blp *ByteLevelProcessing) ProcessEncodings(encodings []tokenizer.Encoding, addSpecialTokens bool) []tokenizer.Encoding {
+	return blp.pretok.ProcessEncodings(encodings, addSpecialTokens)
+}
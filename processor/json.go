@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/util"
+)
+
+// This file implements json.Marshaler for all post-processors so that they are
+// serialized as in a HuggingFace `tokenizer.json` file.
+
+var (
+	_ json.Marshaler = new(BertProcessing)
+	_ json.Marshaler = new(ByteLevelProcessing)
+	_ json.Marshaler = new(RobertaProcessing)
+	_ json.Marshaler = new(Sequence)
+	_ json.Marshaler = new(TemplateProcessing)
+)
+
+// MarshalJSON implements json.Marshaler. A PostToken is serialized as a
+// `[token, id]` pair.
+func (pt PostToken) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON([]interface{}{pt.Value, pt.Id})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (bp *BertProcessing) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type string    `json:"type"`
+		Sep  PostToken `json:"sep"`
+		Cls  PostToken `json:"cls"`
+	}{"BertProcessing", bp.sep, bp.cls})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (bl *ByteLevelProcessing) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(bl.pretok)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (rp *RobertaProcessing) MarshalJSON() ([]byte, error) {
+	return util.MarshalJSON(struct {
+		Type           string    `json:"type"`
+		Sep            PostToken `json:"sep"`
+		Cls            PostToken `json:"cls"`
+		TrimOffsets    bool      `json:"trim_offsets"`
+		AddPrefixSpace bool      `json:"add_prefix_space"`
+	}{"RobertaProcessing", rp.sep, rp.cls, rp.trimOffsets, rp.addPrefixSpace})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Sequence) MarshalJSON() ([]byte, error) {
+	procs := s.processors
+	if procs == nil {
+		procs = []tokenizer.PostProcessor{}
+	}
+
+	return util.MarshalJSON(struct {
+		Type       string                    `json:"type"`
+		Processors []tokenizer.PostProcessor `json:"processors"`
+	}{"Sequence", procs})
+}
+
+// MarshalJSON implements json.Marshaler. A SequencePiece is serialized as
+// `{"Sequence": {"id": "A", "type_id": 0}}`.
+func (p SequencePiece) MarshalJSON() ([]byte, error) {
+	var id string
+	switch p.Id {
+	case A:
+		id = "A"
+	case B:
+		id = "B"
+	default:
+		return nil, fmt.Errorf("SequencePiece: unsupported id %d", p.Id)
+	}
+
+	type sequence struct {
+		Id     string `json:"id"`
+		TypeId int    `json:"type_id"`
+	}
+
+	return util.MarshalJSON(map[string]sequence{"Sequence": {id, p.TypeId}})
+}
+
+// MarshalJSON implements json.Marshaler. A SpecialTokenPiece is serialized as
+// `{"SpecialToken": {"id": "[CLS]", "type_id": 0}}`.
+func (p SpecialTokenPiece) MarshalJSON() ([]byte, error) {
+	type specialToken SpecialTokenPiece
+
+	return util.MarshalJSON(map[string]specialToken{"SpecialToken": specialToken(p)})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (st SpecialToken) MarshalJSON() ([]byte, error) {
+	ids, tokens := st.Ids, st.Tokens
+	if ids == nil {
+		ids = []int{}
+	}
+	if tokens == nil {
+		tokens = []string{}
+	}
+
+	return util.MarshalJSON(struct {
+		Id     string   `json:"id"`
+		Ids    []int    `json:"ids"`
+		Tokens []string `json:"tokens"`
+	}{st.Id, ids, tokens})
+}
+
+// MarshalJSON implements json.Marshaler. Special tokens are sorted by id as
+// HuggingFace does.
+func (t *Tokens) MarshalJSON() ([]byte, error) {
+	tokens := t.TokenMap
+	if tokens == nil {
+		tokens = map[string]SpecialToken{}
+	}
+
+	return util.MarshalJSON(tokens)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (tp *TemplateProcessing) MarshalJSON() ([]byte, error) {
+	single, pair := tp.Single, tp.Pair
+	if single == nil {
+		single = Template{}
+	}
+	if pair == nil {
+		pair = Template{}
+	}
+	specialTokens := tp.SpecialTokens
+	if specialTokens == nil {
+		specialTokens = &Tokens{}
+	}
+
+	return util.MarshalJSON(struct {
+		Type          string   `json:"type"`
+		Single        Template `json:"single"`
+		Pair          Template `json:"pair"`
+		SpecialTokens *Tokens  `json:"special_tokens"`
+	}{"TemplateProcessing", single, pair, specialTokens})
+}
@@ -2,6 +2,21 @@ package processor
 
 import "github.com/season-studio/tokenizer"
 
+// EncodingsProcessor is implemented by post-processors which can process the
+// encodings of a sequence and its pair without merging them. Sequence chains
+// them so that, i.e. a ByteLevel followed by a TemplateProcessing still sees
+// both sequences.
+type EncodingsProcessor interface {
+	ProcessEncodings(encodings []tokenizer.Encoding, addSpecialTokens bool) []tokenizer.Encoding
+}
+
+var (
+	_ EncodingsProcessor = new(ByteLevelProcessing)
+	_ EncodingsProcessor = new(Sequence)
+	_ EncodingsProcessor = new(TemplateProcessing)
+)
+
+// Sequence applies its post-processors one after the other.
 type Sequence struct {
 	processors []tokenizer.PostProcessor
 }
@@ -12,6 +27,11 @@ func NewSequence(processors []tokenizer.PostProcessor) *Sequence {
 	return &Sequence{processors}
 }
 
+// Processors returns the post-processors of the sequence.
+func (seq *Sequence) Processors() []tokenizer.PostProcessor {
+	return seq.processors
+}
+
 // Implement tokenizer.PostProcessor for Sequence
 
 func (seq *Sequence) AddedTokens(isPair bool) (retVal int) {
@@ -24,10 +44,35 @@ func (seq *Sequence) AddedTokens(isPair bool) (retVal int) {
 }
 
 func (seq *Sequence) Process(encoding, pairEncoding *tokenizer.Encoding, addSpecialTokens bool) (retVal *tokenizer.Encoding) {
-	// return blp.pretok.Process(encoding, pairEncoding, addSpecialTokens)
-	var encodings *tokenizer.Encoding = encoding
+	encodings := tokenizer.PrepareEncodings(encoding, pairEncoding)
+	encodings = seq.ProcessEncodings(encodings, addSpecialTokens)
+
+	return tokenizer.MergeEncodings(encodings, false)
+}
+
+// ProcessEncodings passes the encodings through each post-processor. A
+// post-processor which is not an EncodingsProcessor receives the encodings
+// as a sequence and its pair and hands over the merged result.
+func (seq *Sequence) ProcessEncodings(encodings []tokenizer.Encoding, addSpecialTokens bool) []tokenizer.Encoding {
 	for _, p := range seq.processors {
-		encodings = p.Process(encodings, pairEncoding, addSpecialTokens)
+		if ep, ok := p.(EncodingsProcessor); ok {
+			encodings = ep.ProcessEncodings(encodings, addSpecialTokens)
+			continue
+		}
+
+		var merged *tokenizer.Encoding
+		switch len(encodings) {
+		case 0:
+			continue
+		case 1:
+			merged = p.Process(&encodings[0], nil, addSpecialTokens)
+		case 2:
+			merged = p.Process(&encodings[0], &encodings[1], addSpecialTokens)
+		default:
+			first := tokenizer.MergeEncodings(encodings, false)
+			merged = p.Process(first, nil, addSpecialTokens)
+		}
+		encodings = []tokenizer.Encoding{*merged}
 	}
 
 	return encodings
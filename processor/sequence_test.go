@@ -116,3 +116,47 @@ func TestSequence(t *testing.T) {
 		t.Errorf("want %#v\n, got %#v\n", wantPair, gotPair2)
 	}
 }
+
+func TestSequenceByteLevelTemplate(t *testing.T) {
+	blPreTokenizer := pretokenizer.NewByteLevel()
+	blPreTokenizer.TrimOffsets = true
+	blPreTokenizer.AddPrefixSpace = false
+	bl := NewByteLevelProcessing(blPreTokenizer)
+
+	builder := DefaultTemplateProcessing().Builder()
+	builder.NewSingle("<s> $A")
+	builder.NewPair("<s> $A <s>:1 $B:1")
+	builder.NewSpecialTokens([]tokenizer.Token{{Id: 1, Value: "<s>"}})
+	template := builder.Build()
+
+	sequence := NewSequence([]tokenizer.PostProcessor{bl, template})
+
+	if got, want := sequence.AddedTokens(true), 2; got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	encoding := tokenizer.NewEncodingFromTokens([]tokenizer.Token{
+		{Id: 10, Value: "ĠHello", Offsets: []int{0, 6}},
+	}, 0)
+	pair := tokenizer.NewEncodingFromTokens([]tokenizer.Token{
+		{Id: 11, Value: "Ġthere", Offsets: []int{0, 6}},
+	}, 0)
+
+	got := sequence.Process(encoding, pair, true)
+
+	wantIds := []int{1, 10, 1, 11}
+	wantTypeIds := []int{0, 0, 1, 1}
+	wantOffsets := [][]int{{0, 0}, {1, 6}, {0, 0}, {1, 6}}
+	if !reflect.DeepEqual(wantIds, got.Ids) {
+		t.Errorf("want ids %v, got %v", wantIds, got.Ids)
+	}
+	if !reflect.DeepEqual(wantTypeIds, got.TypeIds) {
+		t.Errorf("want type ids %v, got %v", wantTypeIds, got.TypeIds)
+	}
+	if !reflect.DeepEqual(wantOffsets, got.Offsets) {
+		t.Errorf("want offsets %v, got %v", wantOffsets, got.Offsets)
+	}
+	if seq, ok := got.Token2Sequence(3); !ok || seq != 1 {
+		t.Errorf("want token 3 in sequence 1, got %v", seq)
+	}
+}
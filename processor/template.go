@@ -336,6 +336,9 @@ func countAdded(container Template, specialTokens *Tokens) int {
 		case "*SpecialTokenPiece":
 			spt := p.(*SpecialTokenPiece)
 			id := spt.Id
+			if specialTokens == nil {
+				continue
+			}
 			specialToken, ok := specialTokens.GetItemByKey(id)
 			if ok {
 				count += len(specialToken.Ids)
@@ -366,24 +369,32 @@ func (b *TemplateProcessingBuilder) updateAddedTokens() {
 	b.AddedPair = countAdded(b.Pair, b.SpecialTokens)
 }
 
-func (b *TemplateProcessingBuilder) NewSingle(v interface{}) {
+// NewSingle sets the template used for single sequences, i.e. "[CLS] $A [SEP]"
+// or []string{"[CLS]", "$A", "[SEP]"}.
+func (b *TemplateProcessingBuilder) NewSingle(v interface{}) error {
 	tpl, err := NewTemplate(v)
 	if err != nil {
-		panic("err")
+		return err
 	}
 
 	b.Single = tpl
 	b.updateAddedTokens()
+
+	return nil
 }
 
-func (b *TemplateProcessingBuilder) NewPair(v interface{}) {
+// NewPair sets the template used for pairs of sequences, i.e.
+// "[CLS] $A [SEP] $B:1 [SEP]:1".
+func (b *TemplateProcessingBuilder) NewPair(v interface{}) error {
 	tpl, err := NewTemplate(v)
 	if err != nil {
-		panic("err")
+		return err
 	}
 
 	b.Pair = tpl
 	b.updateAddedTokens()
+
+	return nil
 }
 
 func (b *TemplateProcessingBuilder) NewSpecialTokens(tokens []tokenizer.Token) {
@@ -402,70 +413,53 @@ func (b *TemplateProcessingBuilder) DefaultAdded(isSingle bool) int {
 	return countAdded(t, b.SpecialTokens)
 }
 
-func (b *TemplateProcessingBuilder) Validate() error {
-	var pairHasBoth bool = true
-	var (
-		hasA bool
-		hasB bool
-	)
-
-	for _, piece := range b.Pair {
-		if piece.(*SequencePiece).Id == A {
-			hasA = true
-		}
-
-		if piece.(*SequencePiece).Id == B {
-			hasB = true
+// Validate checks that the single template only uses sequence A, that the
+// pair template (if any) uses both sequences and that every special token of
+// the templates is defined in SpecialTokens.
+func (tp *TemplateProcessing) Validate() error {
+	for _, piece := range tp.Single {
+		if sp, ok := piece.(*SequencePiece); ok && sp.Id != A {
+			return fmt.Errorf("Template for 'single' must only use sequence A.")
 		}
 	}
 
-	pairHasBoth = hasA && hasB
+	if len(tp.Pair) > 0 {
+		var hasA, hasB bool
+		for _, piece := range tp.Pair {
+			if sp, ok := piece.(*SequencePiece); ok {
+				switch sp.Id {
+				case A:
+					hasA = true
+				case B:
+					hasB = true
+				}
+			}
+		}
 
-	if !pairHasBoth {
-		err := fmt.Errorf("Template for 'pair' must use both sequences.")
-		return err
+		if !(hasA && hasB) {
+			return fmt.Errorf("Template for 'pair' must use both sequences.")
+		}
 	}
 
-	check := func(sp string) string {
-		var exist bool
-		tok, ok := b.SpecialTokens.GetItemByOrder(0)
+	var missing []string
+	pieces := append(append(Template{}, tp.Single...), tp.Pair...)
+	for _, piece := range pieces {
+		spt, ok := piece.(*SpecialTokenPiece)
 		if !ok {
-			exist = false
-		} else {
-			exist = util.Contains(tok.Tokens, sp)
+			continue
 		}
 
-		if exist {
-			return sp
-		} else {
-			return ""
+		var exist bool
+		if tp.SpecialTokens != nil {
+			_, exist = tp.SpecialTokens.GetItemByKey(spt.Id)
 		}
-	}
-
-	var missing []string
-	var pieces []Piece = append(b.Single, b.Pair...)
-	for _, p := range pieces {
-		typ := getType(p)
-		switch typ {
-		case "*SequencePeice":
-			// None
-		case "*SpecialToken":
-			id := p.(*SpecialTokenPiece).Id
-			s := check(id)
-			if s != "" {
-				missing = append(missing, s)
-			}
+		if !exist && !util.Contains(missing, spt.Id) {
+			missing = append(missing, spt.Id)
 		}
 	}
 
 	if len(missing) > 0 {
-		var msg string
-		for _, s := range missing {
-			v := fmt.Sprintf("Missing SpecialToken %q", s)
-			msg = fmt.Sprintf("%s, %s", msg, v)
-		}
-
-		return fmt.Errorf(msg)
+		return fmt.Errorf("Missing SpecialToken(s) with id(s) %q", missing)
 	}
 
 	return nil
@@ -504,6 +498,15 @@ func (tp *TemplateProcessingBuilder) Build() *TemplateProcessing {
 func (tp *TemplateProcessing) ApplyTemplate(template []Piece, encodings []tokenizer.Encoding, addSpecialTokens bool) []tokenizer.Encoding {
 	var finalEncodings []tokenizer.Encoding
 
+	// Special tokens get no word index, as long as the sequences have some. An
+	// empty sequence has as many words as tokens: none.
+	var hasWords bool
+	for _, encoding := range encodings {
+		if len(encoding.Words) > 0 || encoding.Len() == 0 {
+			hasWords = true
+		}
+	}
+
 	for _, piece := range template {
 		typ := getType(piece)
 
@@ -528,7 +531,13 @@ func (tp *TemplateProcessing) ApplyTemplate(template []Piece, encodings []tokeni
 			id := spt.Id
 			typeId := spt.TypeId
 			if addSpecialTokens {
-				tok, ok := tp.SpecialTokens.GetItemByKey(id)
+				var (
+					tok SpecialToken
+					ok  bool
+				)
+				if tp.SpecialTokens != nil {
+					tok, ok = tp.SpecialTokens.GetItemByKey(id)
+				}
 				if !ok {
 					msg := fmt.Sprintf("Token not found with key %q", id)
 					panic(msg)
@@ -542,7 +551,11 @@ func (tp *TemplateProcessing) ApplyTemplate(template []Piece, encodings []tokeni
 				specialTokenMask := util.Repeat(1, length)
 				attentionMask := util.Repeat(1, length)
 				var overflowing []tokenizer.Encoding = nil
-				encoding := tokenizer.NewEncoding(ids, typeIds, tokens, offsets, specialTokenMask, attentionMask, overflowing)
+				var opts []tokenizer.EncodingOpt
+				if hasWords {
+					opts = append(opts, tokenizer.WithWordsEncodingOpt(util.Repeat(-1, length)))
+				}
+				encoding := tokenizer.NewEncoding(ids, typeIds, tokens, offsets, specialTokenMask, attentionMask, overflowing, opts...)
 
 				finalEncodings = append(finalEncodings, *encoding)
 			}
@@ -567,6 +580,14 @@ func (tp *TemplateProcessing) AddedTokens(isPair bool) int {
 
 func (tp *TemplateProcessing) Process(encoding, pairEncoding *tokenizer.Encoding, addSpecialTokens bool) *tokenizer.Encoding {
 	encodings := tokenizer.PrepareEncodings(encoding, pairEncoding)
+	appliedEncodings := tp.ProcessEncodings(encodings, addSpecialTokens)
+
+	return tokenizer.MergeEncodings(appliedEncodings, false)
+}
+
+// ProcessEncodings applies the single or pair template to the encodings of a
+// sequence or a pair of sequences without merging the result.
+func (tp *TemplateProcessing) ProcessEncodings(encodings []tokenizer.Encoding, addSpecialTokens bool) []tokenizer.Encoding {
 	var template Template
 	switch len(encodings) {
 	case 2:
@@ -577,7 +598,5 @@ func (tp *TemplateProcessing) Process(encoding, pairEncoding *tokenizer.Encoding
 		panic("Shouldn't be here. 'encoding' must be != nil")
 	}
 
-	appliedEncodings := tp.ApplyTemplate(template, encodings, addSpecialTokens)
-
-	return tokenizer.MergeEncodings(appliedEncodings, false)
+	return tp.ApplyTemplate(template, encodings, addSpecialTokens)
 }
@@ -422,3 +422,113 @@ func TestTemplateProcessingOverflowing(t *testing.T) {
 		t.Errorf("\nwant %#v, \ngot %#v", wantPairEncoding, gotPairEncoding)
 	}
 }
+
+func TestTemplateProcessingCustom(t *testing.T) {
+	tests := []struct {
+		name     string
+		single   string
+		pair     string
+		tokens   []tokenizer.Token
+		wantIds  []int
+		wantPair []int
+		wantType []int
+	}{
+		{
+			name:     "roberta",
+			single:   "<s> $A </s>",
+			pair:     "<s> $A </s> </s> $B </s>",
+			tokens:   []tokenizer.Token{{Id: 0, Value: "<s>"}, {Id: 2, Value: "</s>"}},
+			wantIds:  []int{0, 12, 14, 2},
+			wantPair: []int{0, 12, 14, 2, 2, 15, 2},
+			wantType: []int{0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:     "llama",
+			single:   "<s> $A",
+			pair:     "<s> $A <s>:1 $B:1",
+			tokens:   []tokenizer.Token{{Id: 1, Value: "<s>"}},
+			wantIds:  []int{1, 12, 14},
+			wantPair: []int{1, 12, 14, 1, 15},
+			wantType: []int{0, 0, 0, 1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		builder := DefaultTemplateProcessing().Builder()
+		if err := builder.NewSingle(tt.single); err != nil {
+			t.Fatal(err)
+		}
+		if err := builder.NewPair(tt.pair); err != nil {
+			t.Fatal(err)
+		}
+		builder.NewSpecialTokens(tt.tokens)
+		if err := builder.Validate(); err != nil {
+			t.Fatalf("%v: want no error, got %v", tt.name, err)
+		}
+		processor := builder.Build()
+
+		encoding := tokenizer.NewEncodingFromTokens([]tokenizer.Token{
+			{Id: 12, Value: "Hello", Offsets: []int{0, 5}},
+			{Id: 14, Value: "there", Offsets: []int{6, 11}},
+		}, 0)
+		encoding.Words = []int{0, 1}
+		pair := tokenizer.NewEncodingFromTokens([]tokenizer.Token{
+			{Id: 15, Value: "pair", Offsets: []int{0, 4}},
+		}, 0)
+		pair.Words = []int{0}
+
+		single := processor.Process(encoding, nil, true)
+		if !reflect.DeepEqual(tt.wantIds, single.Ids) {
+			t.Errorf("%v: want ids %v, got %v", tt.name, tt.wantIds, single.Ids)
+		}
+		if len(single.Words) != single.Len() {
+			t.Errorf("%v: want %v words, got %v", tt.name, single.Len(), single.Words)
+		}
+
+		got := processor.Process(encoding, pair, true)
+		if !reflect.DeepEqual(tt.wantPair, got.Ids) {
+			t.Errorf("%v: want ids %v, got %v", tt.name, tt.wantPair, got.Ids)
+		}
+		if !reflect.DeepEqual(tt.wantType, got.TypeIds) {
+			t.Errorf("%v: want type ids %v, got %v", tt.name, tt.wantType, got.TypeIds)
+		}
+		if len(got.Words) != got.Len() {
+			t.Errorf("%v: want %v words, got %v", tt.name, got.Len(), got.Words)
+		}
+		if n := len(tt.wantPair) - 3; processor.AddedTokens(true) != n {
+			t.Errorf("%v: want %v added tokens, got %v", tt.name, n, processor.AddedTokens(true))
+		}
+	}
+}
+
+func TestTemplateProcessingValidate(t *testing.T) {
+	tokens := []tokenizer.Token{{Id: 1, Value: "[CLS]"}, {Id: 0, Value: "[SEP]"}}
+
+	tests := []struct {
+		name    string
+		single  string
+		pair    string
+		wantErr bool
+	}{
+		{"valid", "[CLS] $A [SEP]", "[CLS] $A [SEP] $B:1 [SEP]:1", false},
+		{"pair only A", "[CLS] $A [SEP]", "[CLS] $A [SEP]", true},
+		{"single with B", "[CLS] $B [SEP]", "[CLS] $A [SEP] $B:1 [SEP]:1", true},
+		{"missing special token", "[CLS] $A [SEP]", "[CLS] $A [MID] $B:1 [SEP]:1", true},
+	}
+
+	for _, tt := range tests {
+		builder := DefaultTemplateProcessing().Builder()
+		builder.NewSingle(tt.single)
+		builder.NewPair(tt.pair)
+		builder.NewSpecialTokens(tokens)
+
+		err := builder.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: want error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	if err := DefaultTemplateProcessing().Builder().NewSingle("$C"); err == nil {
+		t.Errorf("want error on invalid piece, got nil")
+	}
+}
@@ -0,0 +1,184 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/season-studio/tokenizer/util"
+)
+
+// serializedVersion is the version of the HuggingFace `tokenizer.json` format.
+const serializedVersion = "1.0"
+
+// MarshalJSON implements json.Marshaler. The Tokenizer is serialized in the
+// HuggingFace `tokenizer.json` format, so it can be loaded back with
+// `pretrained.FromFile` or with the HuggingFace tokenizers library.
+// All its components must implement json.Marshaler.
+func (t *Tokenizer) MarshalJSON() ([]byte, error) {
+	if t.model == nil {
+		return nil, fmt.Errorf("Serialize tokenizer error: no model")
+	}
+
+	var components [5]json.RawMessage
+	for i, c := range []interface{}{t.normalizer, t.preTokenizer, t.postProcessor, t.decoder, t.model} {
+		data, err := marshalComponent(c)
+		if err != nil {
+			return nil, fmt.Errorf("Serialize tokenizer error: %w", err)
+		}
+		components[i] = data
+	}
+
+	return util.MarshalJSON(struct {
+		Version       string            `json:"version"`
+		Truncation    *TruncationParams `json:"truncation"`
+		Padding       *PaddingParams    `json:"padding"`
+		AddedTokens   []TokenConfig     `json:"added_tokens"`
+		Normalizer    json.RawMessage   `json:"normalizer"`
+		PreTokenizer  json.RawMessage   `json:"pre_tokenizer"`
+		PostProcessor json.RawMessage   `json:"post_processor"`
+		Decoder       json.RawMessage   `json:"decoder"`
+		Model         json.RawMessage   `json:"model"`
+	}{
+		Version:       serializedVersion,
+		Truncation:    t.trunc,
+		Padding:       t.padding,
+		AddedTokens:   t.addedTokenConfigs(),
+		Normalizer:    components[0],
+		PreTokenizer:  components[1],
+		PostProcessor: components[2],
+		Decoder:       components[3],
+		Model:         components[4],
+	})
+}
+
+// marshalComponent serializes a tokenizer component, `null` if it is not set.
+func marshalComponent(c interface{}) (json.RawMessage, error) {
+	if c == nil || isNil(c) {
+		return json.RawMessage("null"), nil
+	}
+	if _, ok := c.(json.Marshaler); !ok {
+		return nil, fmt.Errorf("%T does not implement json.Marshaler", c)
+	}
+
+	return util.MarshalJSON(c)
+}
+
+// isNil reports whether c holds a nil pointer.
+func isNil(c interface{}) bool {
+	v := reflect.ValueOf(c)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// addedTokenConfigs returns the added tokens, special or not, ordered by id.
+func (t *Tokenizer) addedTokenConfigs() []TokenConfig {
+	av := &t.addedVocabulary
+	tokens := make([]TokenConfig, 0, len(av.addedTokens)+len(av.specialTokens))
+	for _, group := range [][]AddedToken{av.specialTokens, av.addedTokens} {
+		for _, tok := range group {
+			id, ok := av.TokenToId(tok.Content, t.model)
+			if !ok {
+				continue
+			}
+			tokens = append(tokens, TokenConfig{
+				Id:         int64(id),
+				Content:    tok.Content,
+				SingleWord: tok.SingleWord,
+				Lstrip:     tok.LStrip,
+				Rstrip:     tok.RStrip,
+				Normalized: tok.Normalized,
+				Special:    av.IsSpecialToken(tok.Content),
+			})
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].Id < tokens[j].Id })
+
+	return tokens
+}
+
+// Serialize serializes current Tokenizer to a `tokenizer.json` string,
+// indented if `pretty` is true.
+func (t *Tokenizer) Serialize(pretty bool) (string, error) {
+	data, err := t.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	if !pretty {
+		return string(data), nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Save saves the current tokenizer at the given path as a `tokenizer.json`
+// file, indented if `pretty` is true.
+func (t *Tokenizer) Save(path string, pretty bool) error {
+	data, err := t.Serialize(pretty)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(data), 0644)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (tp *TruncationParams) MarshalJSON() ([]byte, error) {
+	var strategy string
+	switch tp.Strategy {
+	case LongestFirst:
+		strategy = "LongestFirst"
+	case OnlyFirst:
+		strategy = "OnlyFirst"
+	case OnlySecond:
+		strategy = "OnlySecond"
+	default:
+		return nil, fmt.Errorf("unsupported truncation strategy %d", tp.Strategy)
+	}
+
+	return util.MarshalJSON(struct {
+		Direction string `json:"direction"`
+		MaxLength int    `json:"max_length"`
+		Strategy  string `json:"strategy"`
+		Stride    int    `json:"stride"`
+	}{"Right", tp.MaxLength, strategy, tp.Stride})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (pp *PaddingParams) MarshalJSON() ([]byte, error) {
+	var strategy interface{}
+	switch pp.Strategy.Name {
+	case "BatchLongest":
+		strategy = "BatchLongest"
+	case "Fixed":
+		strategy = map[string]interface{}{"Fixed": pp.Strategy.Value}
+	default:
+		return nil, fmt.Errorf("unsupported padding strategy %q", pp.Strategy.Name)
+	}
+
+	direction := "Right"
+	if pp.Direction == Left {
+		direction = "Left"
+	}
+
+	var padToMultipleOf *int
+	if pp.PadToMultipleOf > 0 {
+		padToMultipleOf = &pp.PadToMultipleOf
+	}
+
+	return util.MarshalJSON(struct {
+		Strategy        interface{} `json:"strategy"`
+		Direction       string      `json:"direction"`
+		PadToMultipleOf *int        `json:"pad_to_multiple_of"`
+		PadId           int         `json:"pad_id"`
+		PadTypeId       int         `json:"pad_type_id"`
+		PadToken        string      `json:"pad_token"`
+	}{strategy, direction, padToMultipleOf, pp.PadId, pp.PadTypeId, pp.PadToken})
+}
@@ -0,0 +1,115 @@
+// Command tokenizer-server serves tokenizers over gRPC and REST, see the server
+// package.
+//
+// Usage:
+//
+//	tokenizer-server [flags] name=source ...
+//
+// Each argument loads a model of the given name from a source: a
+// `tokenizer.json`, a SentencePiece `.model` or a GGUF `.gguf` file, or a Hugging
+// Face Hub model ID prefixed by "hub:". The first model is the default one,
+// i.e.
+//
+//	tokenizer-server -http :8080 bert=hub:bert-base-uncased gpt2=gpt2/tokenizer.json
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+	"github.com/season-studio/tokenizer/server"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run runs the command line and returns the exit code.
+func run(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tokenizer-server", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	grpcAddr := fs.String("grpc", ":50051", "gRPC listen address, none if empty")
+	httpAddr := fs.String("http", ":8080", "REST listen address, none if empty")
+	maxBatch := fs.Int("max-batch", 1024, "max number of inputs of a request, unlimited if 0")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "tokenizer-server: missing name=source models")
+		return 2
+	}
+
+	srv := server.New(server.WithMaxBatchSize(*maxBatch))
+	for _, arg := range fs.Args() {
+		name, tk, err := loadModel(arg)
+		if err != nil {
+			fmt.Fprintf(stderr, "tokenizer-server: %v\n", err)
+			return 1
+		}
+		srv.AddModel(name, tk)
+	}
+
+	errs := make(chan error, 2)
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fmt.Fprintf(stderr, "tokenizer-server: %v\n", err)
+			return 1
+		}
+		g := grpc.NewServer()
+		srv.RegisterGRPC(g)
+		go func() { errs <- g.Serve(lis) }()
+	}
+	if *httpAddr != "" {
+		go func() { errs <- http.ListenAndServe(*httpAddr, srv.Handler()) }()
+	}
+	if *grpcAddr == "" && *httpAddr == "" {
+		fmt.Fprintln(stderr, "tokenizer-server: no -grpc nor -http address")
+		return 2
+	}
+
+	fmt.Fprintf(stderr, "tokenizer-server: %v\n", <-errs)
+	return 1
+}
+
+// loadModel loads the model of a name=source argument.
+func loadModel(arg string) (string, *tokenizer.Tokenizer, error) {
+	name, source, ok := strings.Cut(arg, "=")
+	if !ok || name == "" || source == "" {
+		return "", nil, fmt.Errorf("invalid model %q, want name=source", arg)
+	}
+
+	var (
+		tk  *tokenizer.Tokenizer
+		err error
+	)
+	switch {
+	case strings.HasPrefix(source, "hub:"):
+		tk, err = pretrained.FromHub(strings.TrimPrefix(source, "hub:"))
+	case strings.EqualFold(filepath.Ext(source), ".gguf"):
+		tk, err = pretrained.FromGGUF(source)
+	case strings.EqualFold(filepath.Ext(source), ".model"):
+		tk, err = pretrained.FromSentencePieceFile(source)
+	default:
+		tk, err = pretrained.FromFile(source)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("model %v: %w", name, err)
+	}
+
+	return name, tk, nil
+}
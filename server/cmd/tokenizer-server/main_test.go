@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadModel(t *testing.T) {
+	name, tk, err := loadModel("bert=" + filepath.Join("..", "..", "..", "testdata", "compat", "bert-wordpiece", "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "bert" || tk == nil {
+		t.Errorf("want bert tokenizer, got %q %v", name, tk)
+	}
+
+	for _, arg := range []string{"bert", "=file.json", "bert="} {
+		if _, _, err := loadModel(arg); err == nil {
+			t.Errorf("%q: want error", arg)
+		}
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	var stderr bytes.Buffer
+	if code := run(nil, &stderr); code != 2 || !strings.Contains(stderr.String(), "missing") {
+		t.Errorf("want exit 2 for missing models, got %d: %s", code, stderr.String())
+	}
+}
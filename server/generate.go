@@ -0,0 +1,5 @@
+package server
+
+// The tokenizerpb package is generated from proto/tokenizer/v1/tokenizer.proto
+// by protoc-gen-go and protoc-gen-go-grpc.
+//go:generate protoc -I proto --go_out=. --go_opt=module=github.com/season-studio/tokenizer/server --go-grpc_out=. --go-grpc_opt=module=github.com/season-studio/tokenizer/server tokenizer/v1/tokenizer.proto
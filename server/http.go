@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/season-studio/tokenizer/server/tokenizerpb"
+)
+
+// maxRequestBytes is the max size of the body of REST requests.
+const maxRequestBytes = 32 << 20
+
+// Handler returns the REST API of s. Requests and responses are the JSON
+// forms of the messages of the service (i.e. `{"texts": ["Hello"],
+// "addSpecialTokens": true}`):
+//
+//	POST /v1/encode                  EncodeRequest  -> EncodeResponse
+//	POST /v1/decode                  DecodeRequest  -> DecodeResponse
+//	POST /v1/count                   CountRequest   -> CountResponse
+//	GET  /v1/models                                 -> ListModelsResponse
+//	POST /v1/models/{model}/encode   as /v1/encode, for the given model
+//	POST /v1/models/{model}/decode   as /v1/decode, for the given model
+//	POST /v1/models/{model}/count    as /v1/count, for the given model
+//
+// Errors are returned with the HTTP status of their gRPC code and a
+// `{"code": ..., "message": ...}` body.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	handle(mux, "encode", s.Encode, func(req *tokenizerpb.EncodeRequest, model string) { req.Model = model })
+	handle(mux, "decode", s.Decode, func(req *tokenizerpb.DecodeRequest, model string) { req.Model = model })
+	handle(mux, "count", s.Count, func(req *tokenizerpb.CountRequest, model string) { req.Model = model })
+	mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := s.ListModels(r.Context(), &tokenizerpb.ListModelsRequest{})
+		writeResponse(w, resp, err)
+	})
+
+	return mux
+}
+
+// handle registers the routes of the given method on mux.
+func handle[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp proto.Message](mux *http.ServeMux, name string, method func(context.Context, PReq) (Resp, error), setModel func(PReq, string)) {
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		req := PReq(new(Req))
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+		if err == nil {
+			err = protojson.Unmarshal(data, req)
+		}
+		if err != nil {
+			writeResponse(w, nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err))
+			return
+		}
+		if model := r.PathValue("model"); model != "" {
+			setModel(req, model)
+		}
+
+		resp, err := method(r.Context(), req)
+		writeResponse(w, resp, err)
+	}
+
+	mux.HandleFunc("POST /v1/"+name, serve)
+	mux.HandleFunc("POST /v1/models/{model}/"+name, serve)
+}
+
+// writeResponse writes the JSON form of resp, or the error.
+func writeResponse(w http.ResponseWriter, resp proto.Message, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		st := status.Convert(err)
+		w.WriteHeader(httpStatus(st.Code()))
+		data, _ := protojson.Marshal(st.Proto())
+		w.Write(data)
+		return
+	}
+
+	data, err := protojson.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
+
+// httpStatus returns the HTTP status of a gRPC code, as gRPC-gateway does.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,250 @@
+// Package server exposes the encode, decode and count functions of tokenizers
+// over gRPC and REST, so that stacks in other languages reuse the tokenizers
+// as a sidecar. The service is defined by proto/tokenizer/v1/tokenizer.proto;
+// the REST API takes and returns the JSON form of its messages, see `Handler`.
+//
+// A Server routes each request to one of its models by name. Requests carry
+// batches of inputs, processed in parallel by the batch functions of the
+// tokenizer.
+//
+// It is a module of its own so that the tokenizer does not depend on gRPC.
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/server/tokenizerpb"
+)
+
+// Opts are the options of a Server, see `DefaultOpts`.
+type Opts struct {
+	DefaultModel string // the model of requests without model, the first added by default
+	MaxBatchSize int    // the max number of inputs of a request, unlimited if 0
+}
+
+// Option sets an option of a Server.
+type Option func(o *Opts)
+
+// WithDefaultModel sets the model of the requests without model, the first
+// added model by default.
+func WithDefaultModel(name string) Option {
+	return func(o *Opts) {
+		o.DefaultModel = name
+	}
+}
+
+// WithMaxBatchSize sets the max number of inputs of a request, 1024 by
+// default. Larger requests fail with `codes.InvalidArgument`.
+func WithMaxBatchSize(n int) Option {
+	return func(o *Opts) {
+		o.MaxBatchSize = n
+	}
+}
+
+// DefaultOpts returns the default options of a Server: the first added model
+// as default and batches of at most 1024 inputs.
+func DefaultOpts() *Opts {
+	return &Opts{
+		MaxBatchSize: 1024,
+	}
+}
+
+// Server serves the tokenizers of its models. It implements
+// `tokenizerpb.TokenizerServiceServer`.
+type Server struct {
+	tokenizerpb.UnimplementedTokenizerServiceServer
+
+	opts   *Opts
+	mu     sync.RWMutex
+	models map[string]*tokenizer.Tokenizer
+}
+
+var _ tokenizerpb.TokenizerServiceServer = new(Server)
+
+// New creates a Server without models, see `AddModel`.
+func New(opts ...Option) *Server {
+	o := DefaultOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Server{opts: o, models: make(map[string]*tokenizer.Tokenizer)}
+}
+
+// AddModel adds or replaces the tokenizer of the model of the given name. It
+// can be called while serving, i.e. to reload a model.
+func (s *Server) AddModel(name string, tk *tokenizer.Tokenizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.opts.DefaultModel == "" {
+		s.opts.DefaultModel = name
+	}
+	s.models[name] = tk
+}
+
+// RemoveModel removes the model of the given name.
+func (s *Server) RemoveModel(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.models, name)
+}
+
+// RegisterGRPC registers the TokenizerService of s on g, i.e. a
+// `grpc.Server`.
+func (s *Server) RegisterGRPC(g grpc.ServiceRegistrar) {
+	tokenizerpb.RegisterTokenizerServiceServer(g, s)
+}
+
+// model returns the tokenizer of the model of the given name, the default one
+// if empty.
+func (s *Server) model(name string) (*tokenizer.Tokenizer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if name == "" {
+		name = s.opts.DefaultModel
+	}
+	tk, ok := s.models[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown model %q", name)
+	}
+
+	return tk, nil
+}
+
+// checkBatch checks the size of a batch of n inputs.
+func (s *Server) checkBatch(n int) error {
+	if max := s.opts.MaxBatchSize; max > 0 && n > max {
+		return status.Errorf(codes.InvalidArgument, "batch of %d inputs exceeds the max of %d", n, max)
+	}
+
+	return nil
+}
+
+// Encode implements tokenizerpb.TokenizerServiceServer.
+func (s *Server) Encode(ctx context.Context, req *tokenizerpb.EncodeRequest) (*tokenizerpb.EncodeResponse, error) {
+	tk, err := s.model(req.GetModel())
+	if err != nil {
+		return nil, err
+	}
+	texts, pairs := req.GetTexts(), req.GetPairTexts()
+	if err := s.checkBatch(len(texts)); err != nil {
+		return nil, err
+	}
+	if len(pairs) > 0 && len(pairs) != len(texts) {
+		return nil, status.Errorf(codes.InvalidArgument, "want %d pair texts, got %d", len(texts), len(pairs))
+	}
+
+	inputs := make([]tokenizer.EncodeInput, len(texts))
+	for i, text := range texts {
+		if len(pairs) > 0 {
+			inputs[i] = tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence(text), tokenizer.NewInputSequence(pairs[i]))
+		} else {
+			inputs[i] = tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(text))
+		}
+	}
+	encodings, err := tk.EncodeBatch(inputs, req.GetAddSpecialTokens())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &tokenizerpb.EncodeResponse{Encodings: make([]*tokenizerpb.Encoding, len(encodings))}
+	for i := range encodings {
+		resp.Encodings[i] = newEncoding(&encodings[i])
+	}
+
+	return resp, nil
+}
+
+// newEncoding converts an Encoding to its message.
+func newEncoding(en *tokenizer.Encoding) *tokenizerpb.Encoding {
+	m := &tokenizerpb.Encoding{
+		Ids:               int32s(en.Ids),
+		Tokens:            en.Tokens,
+		TypeIds:           int32s(en.TypeIds),
+		AttentionMask:     int32s(en.AttentionMask),
+		SpecialTokensMask: int32s(en.SpecialTokenMask),
+		WordIds:           int32s(en.Words),
+		Offsets:           make([]*tokenizerpb.Offset, len(en.Offsets)),
+	}
+	for i, o := range en.Offsets {
+		m.Offsets[i] = &tokenizerpb.Offset{Start: int32(o[0]), End: int32(o[1])}
+	}
+
+	return m
+}
+
+func int32s(values []int) []int32 {
+	if values == nil {
+		return nil
+	}
+	out := make([]int32, len(values))
+	for i, v := range values {
+		out[i] = int32(v)
+	}
+
+	return out
+}
+
+// Decode implements tokenizerpb.TokenizerServiceServer.
+func (s *Server) Decode(ctx context.Context, req *tokenizerpb.DecodeRequest) (*tokenizerpb.DecodeResponse, error) {
+	tk, err := s.model(req.GetModel())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkBatch(len(req.GetSequences())); err != nil {
+		return nil, err
+	}
+
+	sentences := make([][]int, len(req.GetSequences()))
+	for i, seq := range req.GetSequences() {
+		ids := make([]int, len(seq.GetIds()))
+		for j, id := range seq.GetIds() {
+			ids[j] = int(id)
+		}
+		sentences[i] = ids
+	}
+
+	return &tokenizerpb.DecodeResponse{Texts: tk.DecodeBatch(sentences, req.GetSkipSpecialTokens())}, nil
+}
+
+// Count implements tokenizerpb.TokenizerServiceServer.
+func (s *Server) Count(ctx context.Context, req *tokenizerpb.CountRequest) (*tokenizerpb.CountResponse, error) {
+	tk, err := s.model(req.GetModel())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkBatch(len(req.GetTexts())); err != nil {
+		return nil, err
+	}
+
+	counts, err := tk.CountTokensBatch(req.GetTexts(), req.GetAddSpecialTokens())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &tokenizerpb.CountResponse{Counts: int32s(counts)}, nil
+}
+
+// ListModels implements tokenizerpb.TokenizerServiceServer.
+func (s *Server) ListModels(ctx context.Context, req *tokenizerpb.ListModelsRequest) (*tokenizerpb.ListModelsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp := &tokenizerpb.ListModelsResponse{DefaultModel: s.opts.DefaultModel}
+	for name := range s.models {
+		resp.Models = append(resp.Models, name)
+	}
+	sort.Strings(resp.Models)
+
+	return resp, nil
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+	"github.com/season-studio/tokenizer/server/tokenizerpb"
+)
+
+func loadTokenizer(t *testing.T, name string) *tokenizer.Tokenizer {
+	tk, err := pretrained.FromFile(filepath.Join("..", "testdata", "compat", name, "tokenizer.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tk
+}
+
+func newServer(t *testing.T) (*Server, *tokenizer.Tokenizer, *tokenizer.Tokenizer) {
+	bert, gpt2 := loadTokenizer(t, "bert-wordpiece"), loadTokenizer(t, "gpt2-bytelevel")
+	s := New(WithMaxBatchSize(3))
+	s.AddModel("bert", bert)
+	s.AddModel("gpt2", gpt2)
+
+	return s, bert, gpt2
+}
+
+func dialGRPC(t *testing.T, s *Server) tokenizerpb.TokenizerServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	s.RegisterGRPC(g)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return tokenizerpb.NewTokenizerServiceClient(conn)
+}
+
+func TestServer_GRPC(t *testing.T) {
+	s, bert, gpt2 := newServer(t)
+	client := dialGRPC(t, s)
+	ctx := context.Background()
+
+	texts := []string{"Hello world", "world!"}
+	resp, err := client.Encode(ctx, &tokenizerpb.EncodeRequest{Texts: texts, AddSpecialTokens: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, text := range texts {
+		want, err := bert.EncodeSingle(text, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(newEncoding(want), resp.Encodings[i]) {
+			t.Errorf("%q: want %v, got %v", text, newEncoding(want), resp.Encodings[i])
+		}
+	}
+
+	// Routed to the gpt2 model.
+	counts, err := client.Count(ctx, &tokenizerpb.CountRequest{Model: "gpt2", Texts: texts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := gpt2.CountTokensBatch(texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := counts.GetCounts(); !reflect.DeepEqual(int32s(want), got) {
+		t.Errorf("want counts %v, got %v", want, got)
+	}
+
+	en, err := gpt2.EncodeSingle(texts[0], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := client.Decode(ctx, &tokenizerpb.DecodeRequest{Model: "gpt2", Sequences: []*tokenizerpb.TokenIds{{Ids: int32s(en.Ids)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{texts[0]}; !reflect.DeepEqual(want, decoded.GetTexts()) {
+		t.Errorf("want %q, got %q", want, decoded.GetTexts())
+	}
+
+	models, err := client.ListModels(ctx, &tokenizerpb.ListModelsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"bert", "gpt2"}; !reflect.DeepEqual(want, models.GetModels()) || models.GetDefaultModel() != "bert" {
+		t.Errorf("want models %q with default bert, got %v", want, models)
+	}
+
+	tests := []struct {
+		req  *tokenizerpb.EncodeRequest
+		code codes.Code
+	}{
+		{&tokenizerpb.EncodeRequest{Model: "missing", Texts: texts}, codes.NotFound},
+		{&tokenizerpb.EncodeRequest{Texts: []string{"a", "b", "c", "d"}}, codes.InvalidArgument},
+		{&tokenizerpb.EncodeRequest{Texts: texts, PairTexts: texts[:1]}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		if _, err := client.Encode(ctx, tt.req); status.Code(err) != tt.code {
+			t.Errorf("%v: want %v, got %v", tt.req, tt.code, err)
+		}
+	}
+}
+
+func TestServer_Handler(t *testing.T) {
+	s, _, gpt2 := newServer(t)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	post := func(path, body string) (int, string) {
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := post("/v1/models/gpt2/encode", `{"texts": ["Hello world"], "pairTexts": ["Hello"]}`)
+	if code != http.StatusOK {
+		t.Fatalf("want 200, got %d: %s", code, body)
+	}
+	var resp tokenizerpb.EncodeResponse
+	if err := protojson.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want, err := gpt2.EncodePair("Hello world", "Hello", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Encodings) != 1 || !proto.Equal(newEncoding(want), resp.Encodings[0]) {
+		t.Errorf("want %v, got %v", newEncoding(want), resp.Encodings)
+	}
+
+	code, body = post("/v1/count", `{"model": "gpt2", "texts": ["Hello world"]}`)
+	if code != http.StatusOK || !strings.Contains(body, `"counts":[2]`) {
+		t.Errorf("want count 2, got %d: %s", code, body)
+	}
+
+	for _, tt := range []struct {
+		path, body string
+		code       int
+	}{
+		{"/v1/models/missing/decode", `{}`, http.StatusNotFound},
+		{"/v1/encode", `{"texts": "not a list"}`, http.StatusBadRequest},
+	} {
+		if code, body := post(tt.path, tt.body); code != tt.code || !strings.Contains(body, `"message"`) {
+			t.Errorf("%v: want %d with a message, got %d: %s", tt.path, tt.code, code, body)
+		}
+	}
+
+	res, err := http.Get(srv.URL + "/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("want 200, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,290 @@
+package spm
+
+// This file parses SentencePiece model files (normally `tokenizer.model`),
+// serialized `sentencepiece.ModelProto` protobuf messages:
+// https://github.com/google/sentencepiece/blob/master/src/sentencepiece_model.proto
+//
+// Only the fields needed to rebuild a tokenizer are decoded, others are skipped.
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// PieceType is the type of a SentencePiece piece.
+type PieceType int
+
+const (
+	PieceNormal      PieceType = 1 // normal symbol
+	PieceUnknown     PieceType = 2 // unknown symbol, only one in a model
+	PieceControl     PieceType = 3 // control symbol, i.e. <s>, </s>
+	PieceUserDefined PieceType = 4 // user defined symbol, always kept whole
+	PieceUnused      PieceType = 5 // unused symbol
+	PieceByte        PieceType = 6 // byte symbol, i.e. <0x41>, used by byte fallback
+)
+
+// ModelType is the algorithm of a SentencePiece model.
+type ModelType int
+
+const (
+	ModelUnigram ModelType = 1
+	ModelBPE     ModelType = 2
+	ModelWord    ModelType = 3
+	ModelChar    ModelType = 4
+)
+
+func (t ModelType) String() string {
+	switch t {
+	case ModelUnigram:
+		return "UNIGRAM"
+	case ModelBPE:
+		return "BPE"
+	case ModelWord:
+		return "WORD"
+	case ModelChar:
+		return "CHAR"
+	default:
+		return fmt.Sprintf("ModelType(%d)", int(t))
+	}
+}
+
+// Piece is a vocab entry of a SentencePiece model.
+type Piece struct {
+	Piece string
+	Score float32
+	Type  PieceType
+}
+
+// TrainerSpec holds the training settings of a SentencePiece model that matter
+// at encoding time.
+type TrainerSpec struct {
+	ModelType    ModelType
+	ByteFallback bool
+	UnkID        int
+	BosID        int
+	EosID        int
+	PadID        int
+	UnkPiece     string
+	BosPiece     string
+	EosPiece     string
+	PadPiece     string
+}
+
+// NormalizerSpec holds the normalization settings of a SentencePiece model.
+type NormalizerSpec struct {
+	Name                   string
+	PrecompiledCharsmap    []byte
+	AddDummyPrefix         bool
+	RemoveExtraWhitespaces bool
+	EscapeWhitespaces      bool
+}
+
+// ModelProto is a parsed SentencePiece model.
+type ModelProto struct {
+	Pieces         []Piece
+	TrainerSpec    TrainerSpec
+	NormalizerSpec NormalizerSpec
+}
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// defaultTrainerSpec returns a TrainerSpec with the defaults of
+// `sentencepiece_model.proto`.
+func defaultTrainerSpec() TrainerSpec {
+	return TrainerSpec{
+		ModelType: ModelUnigram,
+		UnkID:     0,
+		BosID:     1,
+		EosID:     2,
+		PadID:     -1,
+		UnkPiece:  "<unk>",
+		BosPiece:  "<s>",
+		EosPiece:  "</s>",
+		PadPiece:  "<pad>",
+	}
+}
+
+func defaultNormalizerSpec() NormalizerSpec {
+	return NormalizerSpec{
+		AddDummyPrefix:         true,
+		RemoveExtraWhitespaces: true,
+		EscapeWhitespaces:      true,
+	}
+}
+
+// LoadModel reads and parses a SentencePiece model file.
+func LoadModel(file string) (*ModelProto, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseModel(data)
+}
+
+// ParseModel parses a serialized SentencePiece ModelProto.
+func ParseModel(data []byte) (*ModelProto, error) {
+	m := &ModelProto{
+		TrainerSpec:    defaultTrainerSpec(),
+		NormalizerSpec: defaultNormalizerSpec(),
+	}
+
+	err := walkFields(data, func(num int, typ int, v uint64, b []byte) error {
+		switch {
+		case num == 1 && typ == wireBytes: // pieces
+			p, err := parsePiece(b)
+			if err != nil {
+				return fmt.Errorf("piece %d: %w", len(m.Pieces), err)
+			}
+			m.Pieces = append(m.Pieces, p)
+		case num == 2 && typ == wireBytes: // trainer_spec
+			if err := parseTrainerSpec(b, &m.TrainerSpec); err != nil {
+				return fmt.Errorf("trainer_spec: %w", err)
+			}
+		case num == 3 && typ == wireBytes: // normalizer_spec
+			if err := parseNormalizerSpec(b, &m.NormalizerSpec); err != nil {
+				return fmt.Errorf("normalizer_spec: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid SentencePiece model: %w", err)
+	}
+	if len(m.Pieces) == 0 {
+		return nil, errors.New("invalid SentencePiece model: no pieces")
+	}
+
+	return m, nil
+}
+
+func parsePiece(data []byte) (Piece, error) {
+	p := Piece{Type: PieceNormal}
+	err := walkFields(data, func(num int, typ int, v uint64, b []byte) error {
+		switch {
+		case num == 1 && typ == wireBytes:
+			p.Piece = string(b)
+		case num == 2 && typ == wireFixed32:
+			p.Score = math.Float32frombits(uint32(v))
+		case num == 3 && typ == wireVarint:
+			p.Type = PieceType(v)
+		}
+		return nil
+	})
+
+	return p, err
+}
+
+func parseTrainerSpec(data []byte, s *TrainerSpec) error {
+	return walkFields(data, func(num int, typ int, v uint64, b []byte) error {
+		if typ == wireVarint {
+			switch num {
+			case 3:
+				s.ModelType = ModelType(v)
+			case 35:
+				s.ByteFallback = v != 0
+			case 40:
+				s.UnkID = int(int32(v))
+			case 41:
+				s.BosID = int(int32(v))
+			case 42:
+				s.EosID = int(int32(v))
+			case 43:
+				s.PadID = int(int32(v))
+			}
+		}
+		if typ == wireBytes {
+			switch num {
+			case 45:
+				s.UnkPiece = string(b)
+			case 46:
+				s.BosPiece = string(b)
+			case 47:
+				s.EosPiece = string(b)
+			case 48:
+				s.PadPiece = string(b)
+			}
+		}
+		return nil
+	})
+}
+
+func parseNormalizerSpec(data []byte, s *NormalizerSpec) error {
+	return walkFields(data, func(num int, typ int, v uint64, b []byte) error {
+		switch {
+		case num == 1 && typ == wireBytes:
+			s.Name = string(b)
+		case num == 2 && typ == wireBytes:
+			s.PrecompiledCharsmap = append([]byte{}, b...)
+		case num == 3 && typ == wireVarint:
+			s.AddDummyPrefix = v != 0
+		case num == 4 && typ == wireVarint:
+			s.RemoveExtraWhitespaces = v != 0
+		case num == 5 && typ == wireVarint:
+			s.EscapeWhitespaces = v != 0
+		}
+		return nil
+	})
+}
+
+// walkFields calls fn for each field of a protobuf message with its number,
+// wire type and either its numeric value (varint, fixed) or its bytes.
+func walkFields(data []byte, fn func(num int, typ int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("truncated field key")
+		}
+		data = data[n:]
+
+		num, typ := int(key>>3), int(key&7)
+		var (
+			v uint64
+			b []byte
+		)
+		switch typ {
+		case wireVarint:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("field %d: truncated varint", num)
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return fmt.Errorf("field %d: truncated fixed64", num)
+			}
+			v = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return fmt.Errorf("field %d: truncated fixed32", num)
+			}
+			v = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return fmt.Errorf("field %d: truncated bytes", num)
+			}
+			b = data[n : n+int(l)]
+			data = data[n+int(l):]
+		default:
+			return fmt.Errorf("field %d: unsupported wire type %d", num, typ)
+		}
+
+		if err := fn(num, typ, v, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package spm
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseModel(t *testing.T) {
+	// piece {piece: "▁a", score: -1.5, type: USER_DEFINED}
+	piece := []byte{0x0a, 0x04}
+	piece = append(piece, "▁a"...)
+	piece = append(piece, 0x15)
+	piece = binary.LittleEndian.AppendUint32(piece, math.Float32bits(-1.5))
+	piece = append(piece, 0x18, 0x04)
+
+	var data []byte
+	data = append(data, 0x0a, byte(len(piece)))
+	data = append(data, piece...)
+	// trainer_spec {model_type: BPE, byte_fallback: true, pad_id: -1, unknown field 99: 7}
+	trainer := []byte{0x18, 0x02, 0x98, 0x02, 0x01, 0xd8, 0x02}
+	trainer = binary.AppendUvarint(trainer, uint64(0xffffffffffffffff))
+	trainer = append(trainer, 0x98, 0x06, 0x07)
+	data = append(data, 0x12, byte(len(trainer)))
+	data = append(data, trainer...)
+	// normalizer_spec {add_dummy_prefix: false}
+	data = append(data, 0x1a, 0x02, 0x18, 0x00)
+
+	got, err := ParseModel(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &ModelProto{
+		Pieces:      []Piece{{Piece: "▁a", Score: -1.5, Type: PieceUserDefined}},
+		TrainerSpec: defaultTrainerSpec(),
+		NormalizerSpec: NormalizerSpec{
+			AddDummyPrefix:         false,
+			RemoveExtraWhitespaces: true,
+			EscapeWhitespaces:      true,
+		},
+	}
+	want.TrainerSpec.ModelType = ModelBPE
+	want.TrainerSpec.ByteFallback = true
+	want.TrainerSpec.PadID = -1
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	if _, err := ParseModel(data[:len(data)-1]); err == nil {
+		t.Errorf("want error on truncated model, got nil")
+	}
+}
@@ -9,10 +9,10 @@ package spm
 import (
 	"encoding/base64"
 	"encoding/binary"
-	"unicode"
-
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/rivo/uniseg"
 )
@@ -90,6 +90,10 @@ func (da *DoubleArray) CommonPrefixSearch(key []byte) []int {
 	nodePos := 0
 	var results []int
 
+	if len(da.Array) == 0 {
+		return results
+	}
+
 	unit := da.Array[nodePos]
 	nodePos ^= int(unit.Offset()) // bitwise XOR
 	for _, c := range key {
@@ -98,6 +102,9 @@ func (da *DoubleArray) CommonPrefixSearch(key []byte) []int {
 		}
 
 		nodePos ^= int(c)
+		if nodePos >= len(da.Array) {
+			return results
+		}
 		unit = da.Array[nodePos]
 
 		if unit.Label() != uint(c) {
@@ -105,6 +112,9 @@ func (da *DoubleArray) CommonPrefixSearch(key []byte) []int {
 		}
 
 		nodePos ^= int(unit.Offset())
+		if nodePos >= len(da.Array) {
+			return results
+		}
 		if unit.HasLeaf() {
 			results = append(results, int(da.Array[nodePos].Value()))
 		}
@@ -113,6 +123,8 @@ func (da *DoubleArray) CommonPrefixSearch(key []byte) []int {
 	return results
 }
 
+// Parse splits a precompiled charsmap into its normalized strings blob and its
+// trie. The charsmap must be valid, see `NewPrecompiledFrom`.
 func Parse(precompiledCharsmap []byte) ([]byte, Array) {
 	trieSize := binary.LittleEndian.Uint32(precompiledCharsmap[:4])
 	rest := precompiledCharsmap[4:]
@@ -131,7 +143,22 @@ func Parse(precompiledCharsmap []byte) ([]byte, Array) {
 	return normalizedBlob, trieBlob
 }
 
+// NewPrecompiledFrom decodes a precompiled charsmap. An empty charsmap
+// normalizes nothing.
 func NewPrecompiledFrom(data []byte) (*Precompiled, error) {
+	if len(data) == 0 {
+		return &Precompiled{
+			PrecompiledCharsmap: data,
+			Trie:                NewDoubleArrayFrom(nil),
+		}, nil
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("Invalid precompiled charsmap: %v bytes are missing the trie size", len(data))
+	}
+	if trieSize := binary.LittleEndian.Uint32(data[:4]); uint64(trieSize) > uint64(len(data)-4) {
+		return nil, fmt.Errorf("Invalid precompiled charsmap: trie size %v exceeds the %v bytes of data", trieSize, len(data)-4)
+	}
+
 	normalizedBlob, trieBlob := Parse(data)
 
 	normalized := string(normalizedBlob)
@@ -144,37 +171,45 @@ func NewPrecompiledFrom(data []byte) (*Precompiled, error) {
 	}, nil
 }
 
+// Transform returns the replacement of the first rule matching a prefix of
+// chunk, or an empty string if no rule matches. Use `Lookup` to tell a rule
+// removing chunk from no rule.
 func (m *Precompiled) Transform(chunk string) string {
+	normalized, _ := m.Lookup(chunk)
+	return normalized
+}
+
+// Lookup returns the replacement of the first rule matching a prefix of chunk
+// and whether a rule matches.
+func (m *Precompiled) Lookup(chunk string) (string, bool) {
 	results := m.Trie.CommonPrefixSearch([]byte(chunk))
 	if len(results) == 0 {
-		return ""
+		return "", false
 	}
 
 	index := results[0]
+	if index >= len(m.Normalized) {
+		return "", false
+	}
 	index2 := index
 	for index2 < len(m.Normalized) {
-		if []byte(m.Normalized)[index2] == byte(0) {
+		if m.Normalized[index2] == byte(0) {
 			break
 		}
 		index2 += 1
 	}
 
-	normalized := m.Normalized[index:index2]
-
-	return string(normalized)
+	return m.Normalized[index:index2], true
 }
 
+// NormalizeMn turns the non-spacing marks of input into their "U+XXXX" code.
+//
+// Deprecated: precompiled normalization keeps non-spacing marks as they are.
 func NormalizeMn(input string) string {
-	return normalizeMn(input)
-}
-
-func normalizeMn(input string) string {
 	var out []string
-	runes := []rune(input)
-	for _, r := range runes {
+	for _, r := range input {
 		if unicode.Is(unicode.Mn, r) {
-			v := fmt.Sprintf("%U", r)
-			out = append(out, v)
+			out = append(out, fmt.Sprintf("%U", r))
 		} else {
 			out = append(out, string(r))
 		}
@@ -183,8 +218,10 @@ func normalizeMn(input string) string {
 	return strings.Join(out, "")
 }
 
+// NormalizeString normalizes original with the charsmap rules. Short graphemes
+// are replaced as a whole when a rule matches, the others char by char.
 func (m *Precompiled) NormalizeString(original string) string {
-	var chars []string
+	var b strings.Builder
 
 	graphemes := uniseg.NewGraphemes(original)
 
@@ -202,33 +239,23 @@ func (m *Precompiled) NormalizeString(original string) string {
 		// break a single test.
 		// You don't pass.
 		if len(grapheme) < 6 {
-			norm := m.Transform(grapheme)
-			if len(norm) > 0 {
-				for _, c := range strings.Split(norm, "") {
-					chars = append(chars, c)
-				}
-				return strings.Join(chars, "")
+			if norm, ok := m.Lookup(grapheme); ok {
+				b.WriteString(norm)
+				continue
 			}
 		}
 
-		// TT. This is a hacky way to turn non-spacing marks into hexa string
-		// and pass the unit tests.
-		grapheme = normalizeMn(grapheme)
-		var charIdx int = 0
-		for _, r := range grapheme {
-			part := string(grapheme)[charIdx : charIdx+len(string(r))]
-			norm := m.Transform(part)
-			norm = normalizeMn(norm)
-			if len(norm) > 0 {
-				for _, c := range strings.Split(norm, "") {
-					chars = append(chars, c)
-				}
+		for i := 0; i < len(grapheme); {
+			_, size := utf8.DecodeRuneInString(grapheme[i:])
+			part := grapheme[i : i+size]
+			if norm, ok := m.Lookup(part); ok {
+				b.WriteString(norm)
 			} else {
-				chars = append(chars, string(r))
+				b.WriteString(part)
 			}
-			charIdx += len(string(r))
+			i += size
 		}
 	}
 
-	return strings.Join(chars, "")
+	return b.String()
 }
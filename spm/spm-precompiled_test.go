@@ -111,8 +111,7 @@ func TestPrecompiled_NormalizeString(t *testing.T) {
 
 	// Thai
 	original = "เขาไม่ได้พูดสักคำ"
-	// normalized := `เขาไม\u{e48}ได\u{e49}พ\u{e39}ดส\u{e31}กค\u{e4d}า`
-	normalized = `เขาไมU+0E48ไดU+0E49พU+0E39ดสU+0E31กคU+0E4Dา`
+	normalized = "เขาไม\u0e48ได\u0e49พ\u0e39ดส\u0e31กค\u0e4dา"
 
 	got = m.NormalizeString(original)
 	want = normalized
@@ -122,11 +121,26 @@ func TestPrecompiled_NormalizeString(t *testing.T) {
 
 	// Hindi
 	original = `ड़ी दुख`
-	// normalized = `ड\u{93c}ी द\u{941}ख`
-	normalized = `डU+093Cी दU+0941ख`
+	normalized = "ड\u093cी द\u0941ख"
 	got = m.NormalizeString(original)
 	want = normalized
 	if !reflect.DeepEqual(want, got) {
 		t.Errorf("want %s, got %s\n", want, got)
 	}
 }
+
+func TestNewPrecompiledFrom_Invalid(t *testing.T) {
+	for _, data := range [][]byte{{1, 0}, {8, 0, 0, 0, 1, 2, 3}} {
+		if _, err := NewPrecompiledFrom(data); err == nil {
+			t.Errorf("%v: want error, got nil", data)
+		}
+	}
+
+	m, err := NewPrecompiledFrom(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.NormalizeString("ﬁ"), "ﬁ"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
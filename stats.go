@@ -0,0 +1,143 @@
+package tokenizer
+
+import (
+	"sync/atomic"
+)
+
+// EncodeStats are the statistics of an `Encode` call, see `Tokenizer.WithStats`.
+// Special tokens added by the post-processor and overflowing tokens are not
+// counted as unknown or byte fallback tokens.
+type EncodeStats struct {
+	Tokens             int  // tokens of the encoding, special tokens included
+	UnkTokens          int  // tokens of the model `unk` token id
+	ByteFallbackTokens int  // byte tokens "<0xXX>", as produced by byte fallback
+	Truncated          bool // whether truncation moved tokens to `Overflowing`
+}
+
+// StatsSnapshot are the counters of a Stats at some point in time.
+type StatsSnapshot struct {
+	Encodes            int64 // `Encode` calls, cached ones included
+	Tokens             int64
+	UnkTokens          int64
+	ByteFallbackTokens int64
+	Truncations        int64 // truncated encodings
+}
+
+// UnkRate returns the ratio of unknown tokens to tokens, 0 if there are none.
+func (s StatsSnapshot) UnkRate() float64 {
+	if s.Tokens == 0 {
+		return 0
+	}
+
+	return float64(s.UnkTokens) / float64(s.Tokens)
+}
+
+// Stats aggregates the EncodeStats of the encodings of a Tokenizer, i.e. to
+// monitor the drift of the tokenizer on new data. It is safe for concurrent use
+// and can be shared between tokenizers.
+type Stats struct {
+	encodes            atomic.Int64
+	tokens             atomic.Int64
+	unkTokens          atomic.Int64
+	byteFallbackTokens atomic.Int64
+	truncations        atomic.Int64
+
+	onEncode func(s EncodeStats)
+}
+
+// NewStats creates a Stats whose counters are zero. onEncode, if not nil, is
+// called with the statistics of each encoding, by the goroutine encoding it.
+func NewStats(onEncode func(s EncodeStats)) *Stats {
+	return &Stats{onEncode: onEncode}
+}
+
+// Snapshot returns the current counters. Counters are read one after another,
+// so a snapshot taken while encoding may be off by the encodings in progress.
+func (s *Stats) Snapshot() StatsSnapshot {
+	return StatsSnapshot{
+		Encodes:            s.encodes.Load(),
+		Tokens:             s.tokens.Load(),
+		UnkTokens:          s.unkTokens.Load(),
+		ByteFallbackTokens: s.byteFallbackTokens.Load(),
+		Truncations:        s.truncations.Load(),
+	}
+}
+
+// Reset sets the counters to zero.
+func (s *Stats) Reset() {
+	s.encodes.Store(0)
+	s.tokens.Store(0)
+	s.unkTokens.Store(0)
+	s.byteFallbackTokens.Store(0)
+	s.truncations.Store(0)
+}
+
+func (s *Stats) add(es EncodeStats) {
+	s.encodes.Add(1)
+	s.tokens.Add(int64(es.Tokens))
+	s.unkTokens.Add(int64(es.UnkTokens))
+	s.byteFallbackTokens.Add(int64(es.ByteFallbackTokens))
+	if es.Truncated {
+		s.truncations.Add(1)
+	}
+
+	if s.onEncode != nil {
+		s.onEncode(es)
+	}
+}
+
+// WithStats sets the Stats counting the unknown tokens, byte fallback tokens
+// and truncations of each `Encode` call, nil to disable them.
+func (t *Tokenizer) WithStats(stats *Stats) {
+	t.configure("WithStats")
+	t.stats = stats
+}
+
+// GetStats returns the Stats of the tokenizer, nil if not set.
+func (t *Tokenizer) GetStats() *Stats {
+	return t.stats
+}
+
+// recordStats adds the statistics of the encoding to the tokenizer Stats.
+func (t *Tokenizer) recordStats(en *Encoding) {
+	unkId := -1
+	if m, ok := t.model.(unkTokenModel); ok {
+		if tok := m.GetUnkToken(); tok != nil {
+			if id, ok := t.model.TokenToId(*tok); ok {
+				unkId = id
+			}
+		}
+	}
+
+	es := EncodeStats{
+		Tokens:    len(en.Ids),
+		Truncated: len(en.Overflowing) > 0,
+	}
+	for i, id := range en.Ids {
+		if i < len(en.SpecialTokenMask) && en.SpecialTokenMask[i] == 1 {
+			continue
+		}
+		switch {
+		case id == unkId:
+			es.UnkTokens++
+		case isByteToken(en.Tokens[i]):
+			es.ByteFallbackTokens++
+		}
+	}
+
+	t.stats.add(es)
+}
+
+// isByteToken returns whether tok is a byte token "<0xXX>".
+func isByteToken(tok string) bool {
+	if len(tok) != 6 || tok[:3] != "<0x" || tok[5] != '>' {
+		return false
+	}
+	for _, c := range tok[3:5] {
+		if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'F' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
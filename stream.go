@@ -0,0 +1,242 @@
+package tokenizer
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// DefaultStreamChunkSize is the default size in bytes of the chunks read by
+// `EncodeStream`.
+const DefaultStreamChunkSize = 64 << 10
+
+// maxChunkFactor bounds a chunk to `maxChunkFactor * ChunkSize` bytes when it
+// has no whitespace to be cut at.
+const maxChunkFactor = 4
+
+// StreamOpts are the options of `Tokenizer.EncodeStream`, see
+// `DefaultStreamOpts`.
+type StreamOpts struct {
+	ChunkSize int // size in bytes of the chunks read from the stream
+}
+
+// StreamOpt sets an option of `Tokenizer.EncodeStream`.
+type StreamOpt func(o *StreamOpts)
+
+// WithChunkSizeStreamOpt sets the size in bytes of the chunks read from the
+// stream, `DefaultStreamChunkSize` by default.
+func WithChunkSizeStreamOpt(v int) StreamOpt {
+	return func(o *StreamOpts) {
+		o.ChunkSize = v
+	}
+}
+
+// DefaultStreamOpts returns the default options of `Tokenizer.EncodeStream`:
+// chunks of `DefaultStreamChunkSize` bytes.
+func DefaultStreamOpts() *StreamOpts {
+	return &StreamOpts{
+		ChunkSize: DefaultStreamChunkSize,
+	}
+}
+
+// EncodingIterator iterates over the encodings of the chunks of a stream. See
+// `Tokenizer.EncodeStream`.
+//
+//	it, err := tk.EncodeStream(file)
+//	...
+//	for it.Next() {
+//		en := it.Encoding()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type EncodingIterator struct {
+	tokenizer *Tokenizer
+	reader    io.Reader
+	chunkSize int
+
+	buf    []byte // read bytes not encoded yet
+	eof    bool
+	offset int // stream byte offset of buf[0]
+	words  int // number of words of the previous chunks
+
+	current *Encoding
+	err     error
+}
+
+// EncodeStream encodes the text read from r chunk by chunk, so that neither the
+// full text nor its full encoding are held in memory. Chunks of about
+// `StreamOpts.ChunkSize` bytes are cut before whitespace and encoded as single
+// sequences without special tokens, truncation nor padding. The offsets and
+// word indexes of the chunk encodings are relative to the whole stream. Offsets
+// are in bytes whatever the `Tokenizer.WithOffsetType` unit.
+//
+// The concatenated chunk encodings match the encoding of the whole text as long
+// as no pre-token nor added token spans whitespace, and as normalizers depending
+// on the whole text (i.e. `Strip`, `Prepend`) are applied to each chunk.
+func (t *Tokenizer) EncodeStream(r io.Reader, opts ...StreamOpt) (*EncodingIterator, error) {
+	o := DefaultStreamOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if t.model == nil {
+		return nil, fmt.Errorf("EncodeStream failed: there's no 'Tokenizer Model' setup")
+	}
+	if r == nil {
+		return nil, fmt.Errorf("EncodeStream failed: nil reader")
+	}
+	if o.ChunkSize <= 0 {
+		return nil, fmt.Errorf("EncodeStream failed: invalid chunk size %d", o.ChunkSize)
+	}
+
+	return &EncodingIterator{
+		tokenizer: t,
+		reader:    r,
+		chunkSize: o.ChunkSize,
+	}, nil
+}
+
+// Next encodes the next chunk of the stream. It returns false at the end of the
+// stream or on error, see `Err`.
+func (it *EncodingIterator) Next() bool {
+	it.current = nil
+	for it.err == nil {
+		chunk, start, ok := it.nextChunk()
+		if !ok {
+			return false
+		}
+
+		en, err := it.tokenizer.EncodeSingleSequence(NewInputSequence(chunk), 0, Byte)
+		if err != nil {
+			it.err = fmt.Errorf("EncodeStream failed at byte %d: %w", start, err)
+			return false
+		}
+		if en.Len() == 0 {
+			continue
+		}
+
+		it.current = it.shift(en, start)
+		return true
+	}
+
+	return false
+}
+
+// Encoding returns the encoding of the current chunk.
+func (it *EncodingIterator) Encoding() *Encoding {
+	return it.current
+}
+
+// Err returns the first read or encoding error.
+func (it *EncodingIterator) Err() error {
+	return it.err
+}
+
+// shift makes offsets and word indexes of the chunk encoding relative to the
+// stream.
+func (it *EncodingIterator) shift(en *Encoding, start int) *Encoding {
+	for i, offsets := range en.Offsets {
+		en.Offsets[i] = []int{offsets[0] + start, offsets[1] + start}
+	}
+
+	words := it.words
+	for i, w := range en.Words {
+		if w < 0 {
+			continue
+		}
+		en.Words[i] = w + it.words
+		if w+it.words+1 > words {
+			words = w + it.words + 1
+		}
+	}
+	it.words = words
+	en.SequenceRanges = make(map[int]Range)
+
+	return en
+}
+
+// nextChunk returns the next chunk to encode and its stream byte offset.
+func (it *EncodingIterator) nextChunk() (string, int, bool) {
+	if err := it.fill(it.chunkSize); err != nil {
+		it.err = err
+		return "", 0, false
+	}
+
+	var cut int
+	for {
+		if it.eof {
+			cut = len(it.buf)
+			break
+		}
+		if cut = lastBoundary(it.buf); cut > 0 {
+			break
+		}
+		if len(it.buf) >= maxChunkFactor*it.chunkSize {
+			// No whitespace: cut before the last rune.
+			cut = len(it.buf) - 1
+			for cut > 0 && !utf8.RuneStart(it.buf[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = len(it.buf)
+			}
+			break
+		}
+		if err := it.fill(len(it.buf) + it.chunkSize); err != nil {
+			it.err = err
+			return "", 0, false
+		}
+	}
+	if cut == 0 {
+		return "", 0, false
+	}
+
+	chunk, start := string(it.buf[:cut]), it.offset
+	it.buf = append([]byte(nil), it.buf[cut:]...)
+	it.offset += cut
+
+	return chunk, start, true
+}
+
+// fill reads from the stream until the buffer holds n bytes or the stream ends.
+func (it *EncodingIterator) fill(n int) error {
+	for !it.eof && len(it.buf) < n {
+		if cap(it.buf) < n {
+			buf := make([]byte, len(it.buf), n)
+			copy(buf, it.buf)
+			it.buf = buf
+		}
+		m, err := it.reader.Read(it.buf[len(it.buf):n])
+		it.buf = it.buf[:len(it.buf)+m]
+		if err == io.EOF {
+			it.eof = true
+		} else if err != nil {
+			return fmt.Errorf("EncodeStream failed at byte %d: %w", it.offset+len(it.buf), err)
+		}
+	}
+
+	return nil
+}
+
+// lastBoundary returns the index of the last whitespace run of b following a
+// non-whitespace byte, 0 if there is none.
+func lastBoundary(b []byte) int {
+	for i := len(b) - 1; i > 0; i-- {
+		if isASCIISpace(b[i]) && !isASCIISpace(b[i-1]) {
+			return i
+		}
+	}
+
+	return 0
+}
+
+func isASCIISpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+package tokenizer_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+)
+
+func TestEncodeStream(t *testing.T) {
+	doc := genDocument(64<<10) + "  trailing  "
+
+	tests := []struct {
+		name string
+		tk   func() *tokenizer.Tokenizer
+	}{
+		{"byte-level BPE", getOfflineByteLevelBPE},
+		{"bert", func() *tokenizer.Tokenizer {
+			tk := pretrained.BertBaseUncased()
+			tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[NEW]", false, tokenizer.WithNormalized(false))})
+			return tk
+		}},
+	}
+
+	for _, tt := range tests {
+		tk := tt.tk()
+		want, err := tk.EncodeSingle(doc, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// One byte reads make sure chunks do not depend on read sizes.
+		it, err := tk.EncodeStream(iotest.OneByteReader(strings.NewReader(doc)), tokenizer.WithChunkSizeStreamOpt(1000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			ids, words []int
+			tokens     []string
+			offsets    [][]int
+			chunks     int
+		)
+		for it.Next() {
+			en := it.Encoding()
+			ids = append(ids, en.Ids...)
+			words = append(words, en.Words...)
+			tokens = append(tokens, en.Tokens...)
+			offsets = append(offsets, en.Offsets...)
+			chunks++
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		if chunks < 60 {
+			t.Errorf("%v: want chunks of about 1000 bytes, got %v chunks", tt.name, chunks)
+		}
+		if !reflect.DeepEqual(want.Ids, ids) {
+			t.Errorf("%v: stream ids differ from the whole text ids", tt.name)
+		}
+		if !reflect.DeepEqual(want.Tokens, tokens) {
+			t.Errorf("%v: stream tokens differ from the whole text tokens", tt.name)
+		}
+		if !reflect.DeepEqual(want.Offsets, offsets) {
+			t.Errorf("%v: stream offsets differ from the whole text offsets", tt.name)
+		}
+		if !reflect.DeepEqual(want.Words, words) {
+			t.Errorf("%v: stream words differ from the whole text words", tt.name)
+		}
+	}
+}
+
+func TestEncodeStream_NoWhitespace(t *testing.T) {
+	// Chunks without whitespace are cut at rune boundaries.
+	doc := "ab" + strings.Repeat("🚀", 5000)
+	it, err := getOfflineByteLevelBPE().EncodeStream(strings.NewReader(doc), tokenizer.WithChunkSizeStreamOpt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	end := 0
+	for it.Next() {
+		en := it.Encoding()
+		if start := en.Offsets[0][0]; start != end {
+			t.Fatalf("want chunk starting at %v, got %v", end, start)
+		}
+		end = en.Offsets[len(en.Offsets)-1][1]
+		if size := end - en.Offsets[0][0]; size > 4000 {
+			t.Errorf("want chunks of at most 4000 bytes, got %v", size)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if end != len(doc) {
+		t.Errorf("want %v bytes encoded, got %v", len(doc), end)
+	}
+}
+
+func TestEncodeStream_Errors(t *testing.T) {
+	tk := getOfflineByteLevelBPE()
+	if _, err := tk.EncodeStream(nil); err == nil {
+		t.Errorf("want nil reader error, got nil")
+	}
+	if _, err := tk.EncodeStream(strings.NewReader("a"), tokenizer.WithChunkSizeStreamOpt(0)); err == nil {
+		t.Errorf("want chunk size error, got nil")
+	}
+
+	readErr := errors.New("read error")
+	it, err := tk.EncodeStream(iotest.ErrReader(readErr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.Next() {
+		t.Errorf("want no encoding, got %+v", it.Encoding())
+	}
+	if err := it.Err(); !errors.Is(err, readErr) {
+		t.Errorf("want %v, got %v", readErr, err)
+	}
+
+	it, err = tk.EncodeStream(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.Next() || it.Err() != nil {
+		t.Errorf("want empty stream, got %+v, %v", it.Encoding(), it.Err())
+	}
+}
@@ -0,0 +1,100 @@
+#!/usr/bin/env python3
+"""Regenerates the expected.json files of the encoding fixtures.
+
+For each fixture directory, encodes the inputs of its expected.json with the
+tokenizer.json of the directory and rewrites the expectations from the output
+of the HuggingFace `tokenizers` library:
+
+    pip install tokenizers
+    python3 testdata/compat/generate.py
+
+With --hub, the tokenizer.json of the HUB_MODELS are first downloaded from the
+HuggingFace Hub into a directory per model, with the INPUTS as cases if the
+directory has no expected.json yet:
+
+    python3 testdata/compat/generate.py --hub
+"""
+
+import json
+import os
+import sys
+
+from tokenizers import Tokenizer
+
+ROOT = os.path.dirname(os.path.abspath(__file__))
+
+# HUB_MODELS are the real model configs fetched by --hub, one per model type.
+HUB_MODELS = ["bert-base-uncased", "gpt2", "roberta-base", "t5-small", "xlnet-base-cased"]
+
+# INPUTS are the cases of a new fixture: (input, pair, add_special_tokens).
+INPUTS = [
+    ("Hello, world!", None, True),
+    ("Hello, world!", None, False),
+    ("The quick brown fox", "jumps over the lazy dog.", True),
+    ("  leading and  double spaces ", None, True),
+    ("naïve café déjà vu", None, True),
+    ("ﬁ ＡＢＣ ½ İ ß", None, True),
+    ("日本語のテキスト", None, True),
+    ("😁👍🏽 emoji", None, True),
+    ("don't stop-believing 1,234.56", None, True),
+    ("", None, True),
+]
+
+
+def fetch_hub_models():
+    for model in HUB_MODELS:
+        path = os.path.join(ROOT, model.replace("/", "--"))
+        os.makedirs(path, exist_ok=True)
+        Tokenizer.from_pretrained(model).save(os.path.join(path, "tokenizer.json"))
+
+        expected = os.path.join(path, "expected.json")
+        if not os.path.isfile(expected):
+            cases = []
+            for text, pair, add_special_tokens in INPUTS:
+                case = {"input": text, "add_special_tokens": add_special_tokens}
+                if pair is not None:
+                    case["pair"] = pair
+                cases.append(case)
+            with open(expected, "w", encoding="utf-8") as f:
+                json.dump(cases, f, ensure_ascii=False, indent=2)
+                f.write("\n")
+
+
+def main():
+    if "--hub" in sys.argv[1:]:
+        fetch_hub_models()
+
+    for name in sorted(os.listdir(ROOT)):
+        path = os.path.join(ROOT, name)
+        if not os.path.isfile(os.path.join(path, "tokenizer.json")):
+            continue
+
+        tk = Tokenizer.from_file(os.path.join(path, "tokenizer.json"))
+        with open(os.path.join(path, "expected.json"), encoding="utf-8") as f:
+            cases = json.load(f)
+
+        out = []
+        for c in cases:
+            en = tk.encode(c["input"], c.get("pair"), add_special_tokens=c["add_special_tokens"])
+            case = {"input": c["input"]}
+            if c.get("pair") is not None:
+                case["pair"] = c["pair"]
+            case.update(
+                add_special_tokens=c["add_special_tokens"],
+                ids=en.ids,
+                tokens=en.tokens,
+                offsets=[list(o) for o in en.offsets],
+                type_ids=en.type_ids,
+                attention_mask=en.attention_mask,
+                special_tokens_mask=en.special_tokens_mask,
+            )
+            out.append(case)
+
+        with open(os.path.join(path, "expected.json"), "w", encoding="utf-8") as f:
+            json.dump(out, f, ensure_ascii=False, indent=2)
+            f.write("\n")
+        print("%s: %d cases" % (name, len(out)))
+
+
+if __name__ == "__main__":
+    main()
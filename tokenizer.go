@@ -5,14 +5,17 @@ import (
 	"bufio"
 	// "context"
 	"fmt"
+	"io"
 	"log"
-	"math"
+	"math/rand"
 	"os"
 	"reflect"
+	"runtime"
 	"strings"
 
 	// "regexp"
 	"sync"
+	"sync/atomic"
 
 	progressbar "github.com/schollz/progressbar/v2"
 	// "golang.org/x/sync/errgroup"
@@ -21,11 +24,6 @@ import (
 	"github.com/season-studio/tokenizer/util"
 )
 
-const (
-	mb = 1024 * 1024
-	gb = 1024 * mb
-)
-
 type Token struct {
 	Id      int
 	Value   string
@@ -42,11 +40,18 @@ type PreTokenizer interface {
 }
 
 // Model represents a model used during tokenization (i.e., BPE, Word, or Unigram)
+//
+// Models are shared by the encodings of a Tokenizer running concurrently, so
+// their methods must be safe for concurrent use.
 type Model interface {
 	// Tokenize tokenizes the given sequence into multiple underlying `Token`
 	// The `offsets` on the `Token` are expected to be relative to the given
 	// sequence
 	Tokenize(sequence string) ([]Token, error)
+	// TokenizeWord tokenizes a single, already split word. `offsetsBase` is the
+	// byte offset of the word in the original text so that the `offsets` on
+	// the returned `Token` are absolute.
+	TokenizeWord(word string, offsetsBase int) ([]Token, error)
 	// TokenToId finds the ID associated with a string token
 	TokenToId(token string) (id int, ok bool)
 	// IdToToken find the string token associated with an ID
@@ -60,6 +65,24 @@ type Model interface {
 	Save(path string, prefixOpt ...string) error
 }
 
+// VocabEditor is implemented by models whose vocab can be changed at runtime,
+// i.e. to add domain-specific tokens to a pretrained model and match the size
+// of a resized embedding table. Use `Tokenizer.EditModelVocab` to edit the
+// model of a tokenizer.
+type VocabEditor interface {
+	// AddToken adds the token to the vocab and returns its id, the existing
+	// id if the token is already in the vocab.
+	AddToken(token string) int
+	// Resize grows or shrinks the vocab so that its ids are exactly
+	// [0, newSize). Missing ids are filled with `padTokenPattern` formatted
+	// with their id (i.e. "<pad_%d>") and the tokens of ids >= newSize are
+	// removed.
+	Resize(newSize int, padTokenPattern string) error
+	// RemapIds changes each id of the vocab to mapping(id). The mapping must be
+	// one-to-one.
+	RemapIds(mapping func(id int) int) error
+}
+
 // PostProcessor is in charge of post-processing an encoded output of
 // the `Tokenizer`.
 // It adds any special tokens that a language model would require.
@@ -72,17 +95,21 @@ type PostProcessor interface {
 }
 
 // DefaultProcess is a helper function of PostProcessor's Process method
-// It helps to fast track by just merging encoding and its pair.
+// It helps to fast track by just merging encoding and its pair, marking their
+// tokens as of sequence 0 and 1.
 func DefaultProcess(encoding, pairEncoding *Encoding, addSpecialTokens bool) *Encoding {
 	if pairEncoding == nil {
 		return encoding
 	}
 
-	if pairEncoding != nil {
-		return encoding.MergeWith(pairEncoding, false)
+	for i, e := range []*Encoding{encoding, pairEncoding} {
+		e.SetSequenceIds(i)
+		for j := range e.Overflowing {
+			e.Overflowing[j].SetSequenceIds(i)
+		}
 	}
 
-	return encoding
+	return encoding.MergeWith(pairEncoding, false)
 }
 
 // PrepareEncodings prepares encoding and pairEncoding if any before `ProcessEncodings` call.
@@ -167,6 +194,14 @@ type Trainer interface {
 	ProcessTokens(words map[string]int, tokens []string)
 }
 
+// TrainerWithError is implemented by trainers which can fail, i.e. on a vocab
+// size too small for the corpus. `Tokenizer.Train` uses `TrainWithError`
+// instead of `Train` to return the error.
+type TrainerWithError interface {
+	Trainer
+	TrainWithError(words map[string]int) (Model, []AddedToken, error)
+}
+
 // Implement methods for `Token`
 // NewToken generate new token from input data
 func NewToken(id int, value string, offsets []int) Token {
@@ -218,6 +253,18 @@ func NewInputSequence(input interface{}) (retVal InputSequence) {
 	return
 }
 
+// NewPreTokenizedInputSequence creates an InputSequence from words already
+// split, i.e. the tokens of a CoNLL dataset. Each word is normalized and
+// pre-tokenized on its own, so that no token spans two words: the word ids of
+// the encoding are the indexes of the words and the offsets are relative to
+// the word of each token.
+func NewPreTokenizedInputSequence(words []string) InputSequence {
+	return InputSequence{
+		input:     words,
+		inputType: PretokenizedInput,
+	}
+}
+
 type Single struct {
 	Sentence InputSequence
 }
@@ -245,6 +292,15 @@ func NewDualEncodeInput(sentence, pairSentence InputSequence) (retVal EncodeInpu
 
 // Tokenizer represents a tokenization pipeline.
 // It can implement any encoding or decoding of any text.
+//
+// A configured Tokenizer is safe for concurrent use: the encode and decode
+// methods and the getters can be called from many goroutines at once, there is
+// no need for a tokenizer per goroutine. The configuration methods (`With...`,
+// `AddTokens`, `AddSpecialTokens`, `Train`...) are not synchronized and must
+// happen before sharing the tokenizer; calling them while an encoding or a
+// decoding is in progress panics. Custom pipeline components, caches and
+// metrics sinks must themselves be safe for concurrent use, as the built-in
+// ones are.
 type Tokenizer struct {
 	// Parts
 	normalizer    normalizer.Normalizer // optional
@@ -259,6 +315,32 @@ type Tokenizer struct {
 	// General processing parameters
 	trunc   *TruncationParams // optional
 	padding *PaddingParams    // optional
+
+	metrics MetricsSink // optional
+	stats   *Stats      // optional
+
+	intraDocWorkers int // optional - <= 1 means serial
+	batchWorkers    int // optional - <= 0 means GOMAXPROCS
+
+	offsetType OffsetType // unit of the encoding offsets, Byte by default
+
+	chatTemplate *ChatTemplate // optional
+
+	randSource rand.Source // optional - source of the dropout of the encode calls
+
+	cache         Cache   // optional - encode-level cache
+	fingerprint   *uint64 // memoized configuration fingerprint used in cache keys
+	fingerprintMu sync.Mutex
+
+	inUse atomic.Int32 // number of encodings and decodings in progress
+}
+
+// configure panics if the tokenizer is encoding or decoding, as configuration
+// changes are not synchronized with them. `method` names the caller.
+func (t *Tokenizer) configure(method string) {
+	if t.inUse.Load() > 0 {
+		panic(fmt.Sprintf("tokenizer: %v called while encoding or decoding; configure the tokenizer before sharing it between goroutines", method))
+	}
 }
 
 // Implementing methods for Tokenizer
@@ -275,7 +357,58 @@ func NewTokenizer(model Model) *Tokenizer {
 	}
 }
 
+// WithOffsetType sets the unit of the offsets of the encodings, as the
+// `offset_type` of HuggingFace tokenizers. Offsets are UTF-8 bytes of the input
+// by default.
+func (t *Tokenizer) WithOffsetType(offsetType OffsetType) {
+	t.configure("WithOffsetType")
+	t.offsetType = offsetType
+}
+
+func (t *Tokenizer) GetOffsetType() OffsetType {
+	return t.offsetType
+}
+
+// WithMetricsSink sets the sink receiving encoding counters.
+func (t *Tokenizer) WithMetricsSink(sink MetricsSink) {
+	t.configure("WithMetricsSink")
+	t.metrics = sink
+}
+
+func (t *Tokenizer) GetMetricsSink() MetricsSink {
+	return t.metrics
+}
+
+// WithIntraDocParallelism shards the model tokenization of the pre-tokens of a
+// single sequence across up to `workers` goroutines. It helps with very long
+// documents where batch parallelism does not apply. Added tokens extraction,
+// pre-tokenization and post-processing remain serial and the output is
+// identical to the serial path. The model must be safe for concurrent use.
+// A value <= 1 disables it (default).
+func (t *Tokenizer) WithIntraDocParallelism(workers int) {
+	t.configure("WithIntraDocParallelism")
+	t.intraDocWorkers = workers
+}
+
+func (t *Tokenizer) GetIntraDocParallelism() int {
+	return t.intraDocWorkers
+}
+
+// WithBatchParallelism sets the number of goroutines `EncodeBatch` and
+// `DecodeBatch` spread their inputs across. A value <= 0 uses
+// `runtime.GOMAXPROCS(0)` goroutines (default) and 1 encodes serially.
+func (t *Tokenizer) WithBatchParallelism(workers int) {
+	t.configure("WithBatchParallelism")
+	t.batchWorkers = workers
+}
+
+func (t *Tokenizer) GetBatchParallelism() int {
+	return t.batchWorkers
+}
+
 func (t *Tokenizer) WithNormalizer(n normalizer.Normalizer) {
+	t.configure("WithNormalizer")
+	t.resetFingerprint()
 	t.normalizer = n
 }
 
@@ -284,6 +417,8 @@ func (t *Tokenizer) GetNormalizer() normalizer.Normalizer {
 }
 
 func (t *Tokenizer) WithPreTokenizer(preTokenizer PreTokenizer) {
+	t.configure("WithPreTokenizer")
+	t.resetFingerprint()
 	t.preTokenizer = preTokenizer
 }
 
@@ -292,6 +427,8 @@ func (t *Tokenizer) GetPreTokenizer() PreTokenizer {
 }
 
 func (t *Tokenizer) WithPostProcessor(postProcessor PostProcessor) {
+	t.configure("WithPostProcessor")
+	t.resetFingerprint()
 	t.postProcessor = postProcessor
 }
 
@@ -300,6 +437,7 @@ func (t *Tokenizer) GetPostProcessor() PostProcessor {
 }
 
 func (t *Tokenizer) WithDecoder(decoder Decoder) {
+	t.configure("WithDecoder")
 	t.decoder = decoder
 }
 
@@ -308,6 +446,8 @@ func (t *Tokenizer) GetDecoder() Decoder {
 }
 
 func (t *Tokenizer) WithModel(model Model) {
+	t.configure("WithModel")
+	t.resetFingerprint()
 	t.model = model
 }
 
@@ -315,7 +455,26 @@ func (t *Tokenizer) GetModel() Model {
 	return t.model
 }
 
+// EditModelVocab calls `fn` to edit the vocab of the model, which must implement
+// VocabEditor. Added tokens are then updated: those now in the model vocab take
+// their model id and those whose id is now taken get the next free id.
+func (t *Tokenizer) EditModelVocab(fn func(v VocabEditor) error) error {
+	t.configure("EditModelVocab")
+	editor, ok := t.model.(VocabEditor)
+	if !ok {
+		return fmt.Errorf("EditModelVocab error: model %T does not support vocab editing", t.model)
+	}
+
+	t.resetFingerprint()
+	err := fn(editor)
+	t.addedVocabulary.syncWithModel(t.model, t.normalizer)
+
+	return err
+}
+
 func (t *Tokenizer) WithTruncation(trunc *TruncationParams) {
+	t.configure("WithTruncation")
+	t.resetFingerprint()
 	t.trunc = trunc
 }
 
@@ -324,6 +483,8 @@ func (t *Tokenizer) GetTruncation() *TruncationParams {
 }
 
 func (t *Tokenizer) WithPadding(padding *PaddingParams) {
+	t.configure("WithPadding")
+	t.resetFingerprint()
 	t.padding = padding
 }
 
@@ -333,7 +494,10 @@ func (t *Tokenizer) GetPadding() (retVal *PaddingParams) {
 
 // GetVocab get the vocabulary
 func (t *Tokenizer) GetVocab(withAddedTokens bool) map[string]int {
-	finalVocab := t.model.GetVocab()
+	finalVocab := make(map[string]int)
+	for k, v := range t.model.GetVocab() {
+		finalVocab[k] = v
+	}
 	if withAddedTokens {
 		addedVocab := t.addedVocabulary.GetVocab()
 		if len(addedVocab) > 0 {
@@ -379,8 +543,17 @@ func (t *Tokenizer) IdToToken(id int) (token string, ok bool) {
 
 // EncodeSingleSequence encodes a single sequence
 func (t *Tokenizer) EncodeSingleSequence(sequence InputSequence, typeId int, offsetType OffsetType) (*Encoding, error) {
+	return t.encodeSingleSequence(t.model, sequence, typeId, offsetType)
+}
+
+// encodeSingleSequence encodes a single sequence with the given model.
+func (t *Tokenizer) encodeSingleSequence(model Model, sequence InputSequence, typeId int, offsetType OffsetType) (*Encoding, error) {
 	encode := func(isPreTokenized bool, subseqIdx int, subseq string) (*Encoding, error) {
 		normalized := t.addedVocabulary.ExtractAndNormalize(subseq, t.normalizer)
+		sourceForm := SourceFormPreserved
+		if normalized.NormalizationChanged() {
+			sourceForm = SourceFormChanged
+		}
 		var (
 			pretokenized *PreTokenizedString = normalized
 			err          error
@@ -403,7 +576,10 @@ func (t *Tokenizer) EncodeSingleSequence(sequence InputSequence, typeId int, off
 			wordIdx = subseqIdx
 		}
 
-		subseqEncoding, err := t.doTokenize(pretokenized, typeId, wordIdx, offsetType)
+		subseqEncoding, err := t.doTokenize(model, pretokenized, typeId, wordIdx, offsetType)
+		if err == nil {
+			subseqEncoding.SourceForm = sourceForm
+		}
 
 		// fmt.Printf("==========doTokenizer result: =====================\n")
 		// fmt.Printf("encoding: %+v\n", subseqEncoding)
@@ -435,68 +611,154 @@ func (t *Tokenizer) EncodeSingleSequence(sequence InputSequence, typeId int, off
 	finalEncoding := DefaultEncoding()
 	finalEncoding.Merge(encodings, false)
 
+	if t.metrics != nil {
+		if finalEncoding.SourceForm == SourceFormChanged {
+			t.metrics.Add(MetricSourceFormChanged, 1)
+		} else {
+			t.metrics.Add(MetricSourceFormPreserved, 1)
+		}
+	}
+
 	return finalEncoding, nil
 }
 
+// EncodeOpts are the per-call options of `Tokenizer.Encode` and the functions
+// built on it, overriding the tokenizer settings. See `DefaultEncodeOpts`.
+type EncodeOpts struct {
+	Truncation         *TruncationParams // truncation of the call if OverrideTruncation is set, nil for none
+	OverrideTruncation bool              // whether Truncation overrides the tokenizer truncation
+	OffsetType         OffsetType        // offsets unit of the call if OverrideOffsetType is set
+	OverrideOffsetType bool              // whether OffsetType overrides the tokenizer offsets unit
+	Dropout            float32           // merges dropout probability of the call, 0 for the model one (see DropoutModel)
+}
+
+// EncodeOpt sets a per-call option of `Tokenizer.Encode`.
+type EncodeOpt func(o *EncodeOpts)
+
+// WithTruncationEncodeOpt truncates the encoded input with the given params
+// instead of the tokenizer ones. A nil value disables truncation.
+func WithTruncationEncodeOpt(v *TruncationParams) EncodeOpt {
+	return func(o *EncodeOpts) {
+		o.Truncation = v
+		o.OverrideTruncation = true
+	}
+}
+
+// WithOffsetTypeEncodeOpt reports the offsets of the encoded input in the given
+// unit instead of the tokenizer one.
+func WithOffsetTypeEncodeOpt(v OffsetType) EncodeOpt {
+	return func(o *EncodeOpts) {
+		o.OffsetType = v
+		o.OverrideOffsetType = true
+	}
+}
+
+// DefaultEncodeOpts returns the options of a call without EncodeOpt: the
+// truncation, offsets unit and dropout of the tokenizer.
+func DefaultEncodeOpts() *EncodeOpts {
+	return &EncodeOpts{
+		Truncation:         nil,
+		OverrideTruncation: false,
+		OffsetType:         Byte,
+		OverrideOffsetType: false,
+		Dropout:            0,
+	}
+}
+
+// truncation returns the truncation params of the call.
+func (t *Tokenizer) truncation(o *EncodeOpts) *TruncationParams {
+	if o.OverrideTruncation {
+		return o.Truncation
+	}
+
+	return t.trunc
+}
+
+// offsetTypeOf returns the offsets unit of the call.
+func (t *Tokenizer) offsetTypeOf(o *EncodeOpts) OffsetType {
+	if o.OverrideOffsetType {
+		return o.OffsetType
+	}
+
+	return t.offsetType
+}
+
 // Encode the given input. This method accepts both single sequences, as well as pair
 // sequences. Also, a sequence can be a string, or already pre-tokenized input directly:
-func (t *Tokenizer) Encode(input EncodeInput, addSpecialTokens bool) (retVal *Encoding, err error) {
-	var encoding, pairEncoding *Encoding
-
-	// Encode and Postprocess
-	switch reflect.TypeOf(input).Name() {
-	case "Single":
-		seq := input.(Single).Sentence
-		encoding, err = t.EncodeSingleSequence(seq, 0, Byte)
-		if err != nil {
-			return retVal, err
-		}
+//
+// The tokenizer truncation can be overridden for this call with
+// `WithTruncationEncodeOpt`. Tokens removed by truncation are returned as the
+// `Overflowing` encodings of the result. Offsets are in the unit set by
+// `WithOffsetType`, bytes by default, or by `WithOffsetTypeEncodeOpt`. The
+// encoding is counted in the tokenizer Stats, if any (see `WithStats`).
+func (t *Tokenizer) Encode(input EncodeInput, addSpecialTokens bool, opts ...EncodeOpt) (retVal *Encoding, err error) {
+	t.inUse.Add(1)
+	defer t.inUse.Add(-1)
+
+	o := DefaultEncodeOpts()
+	for _, opt := range opts {
+		opt(o)
+	}
+	offsetType := t.offsetTypeOf(o)
 
-	case "Dual":
-		seq := input.(Dual).Sentence
-		encoding, err = t.EncodeSingleSequence(seq, 0, Byte)
-		if err != nil {
-			return retVal, err
-		}
-		pairSeq := input.(Dual).Pair
-		pairEncoding, err = t.EncodeSingleSequence(pairSeq, 1, Byte)
-		if err != nil {
-			return retVal, err
+	if o.Dropout != 0 {
+		// Dropout encodings vary between calls, they are not cached.
+		en, err := t.encode(input, addSpecialTokens, offsetType, o)
+		if err == nil && t.stats != nil {
+			t.recordStats(en)
 		}
+		return en, err
+	}
 
-	default:
-		log.Fatalf("Invalid input type - '%v'. \n", reflect.TypeOf(input).Name())
+	en, err := t.cachedEncode(input, addSpecialTokens, offsetType, o, func() (*Encoding, error) {
+		return t.encode(input, addSpecialTokens, offsetType, o)
+	})
+	if err == nil && t.stats != nil {
+		t.recordStats(en)
 	}
 
-	return t.PostProcess(encoding, pairEncoding, addSpecialTokens), nil
+	return en, err
 }
 
 // EncodeCharOffsets encodes the given input, using offsets relative to chars instead of bytes.
 // This method accepts both single sequences, as well as pair sequences. Also,
 // a sequence can be a string, or already pre-tokenized input directly:
-func (t *Tokenizer) EncodeCharOffsets(input EncodeInput, addSpecialTokens bool) (*Encoding, error) {
+func (t *Tokenizer) EncodeCharOffsets(input EncodeInput, addSpecialTokens bool, opts ...EncodeOpt) (*Encoding, error) {
+	return t.Encode(input, addSpecialTokens, append(opts, WithOffsetTypeEncodeOpt(Char))...)
+}
+
+// encode encodes and post-processes the input with offsets of the given type.
+func (t *Tokenizer) encode(input EncodeInput, addSpecialTokens bool, offsetType OffsetType, o *EncodeOpts) (*Encoding, error) {
 	var (
 		encoding, pairEncoding *Encoding
 		err                    error
 	)
 
+	model := t.model
+	if o.Dropout != 0 {
+		model, err = t.dropoutModel(o.Dropout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Encode and Postprocess
 	switch reflect.TypeOf(input).Name() {
 	case "Single":
 		seq := input.(Single).Sentence
-		encoding, err = t.EncodeSingleSequence(seq, 0, Char)
+		encoding, err = t.encodeSingleSequence(model, seq, 0, offsetType)
 		if err != nil {
 			return nil, err
 		}
 
 	case "Dual":
 		seq := input.(Dual).Sentence
-		encoding, err = t.EncodeSingleSequence(seq, 0, Char)
+		encoding, err = t.encodeSingleSequence(model, seq, 0, offsetType)
 		if err != nil {
 			return nil, err
 		}
 		pairSeq := input.(Dual).Pair
-		pairEncoding, err = t.EncodeSingleSequence(pairSeq, 1, Char)
+		pairEncoding, err = t.encodeSingleSequence(model, pairSeq, 1, offsetType)
 		if err != nil {
 			return nil, err
 		}
@@ -505,38 +767,181 @@ func (t *Tokenizer) EncodeCharOffsets(input EncodeInput, addSpecialTokens bool)
 		log.Fatalf("Invalid input type - '%v'. \n", reflect.TypeOf(input).Name())
 	}
 
-	return t.PostProcess(encoding, pairEncoding, addSpecialTokens), nil
+	return t.postProcess(encoding, pairEncoding, addSpecialTokens, t.truncation(o))
+}
+
+// DecodeOpts are the options of `Tokenizer.Decode` and the functions built on
+// it, see `DefaultDecodeOpts`.
+type DecodeOpts struct {
+	SkipSpecialTokens         bool // drop tokens registered as special in the added vocabulary
+	CleanUpTokenizationSpaces bool // remove spaces before punctuation and English contractions
+	DropUnknown               bool // drop unknown ids instead of rendering them as the model `unk` token
+}
+
+// DecodeOpt sets an option of `Tokenizer.Decode`.
+type DecodeOpt func(o *DecodeOpts)
+
+// WithSkipSpecialTokensDecodeOpt sets whether to drop the special tokens of
+// the added vocabulary.
+func WithSkipSpecialTokensDecodeOpt(v bool) DecodeOpt {
+	return func(o *DecodeOpts) {
+		o.SkipSpecialTokens = v
+	}
+}
+
+// WithCleanUpTokenizationSpacesDecodeOpt sets whether to remove the spaces
+// before punctuation and English contractions, see `CleanUpTokenization`.
+func WithCleanUpTokenizationSpacesDecodeOpt(v bool) DecodeOpt {
+	return func(o *DecodeOpts) {
+		o.CleanUpTokenizationSpaces = v
+	}
+}
+
+// WithDropUnknownDecodeOpt sets whether ids unknown to both the added
+// vocabulary and the model are dropped, as in HuggingFace, instead of being
+// rendered as the model `unk` token.
+func WithDropUnknownDecodeOpt(v bool) DecodeOpt {
+	return func(o *DecodeOpts) {
+		o.DropUnknown = v
+	}
+}
+
+// DefaultDecodeOpts returns the default options of `Tokenizer.Decode`: special
+// tokens are kept, spaces are not cleaned up and unknown ids are rendered as
+// the model `unk` token.
+func DefaultDecodeOpts() *DecodeOpts {
+	return &DecodeOpts{
+		SkipSpecialTokens:         false,
+		CleanUpTokenizationSpaces: false,
+		DropUnknown:               false,
+	}
+}
+
+// unkTokenModel is implemented by models which can report their `unk` token.
+type unkTokenModel interface {
+	GetUnkToken() *string
 }
 
-// Decode decodes the given ids, back to a String
-func (t *Tokenizer) Decode(ids []int, skipSpecialTokens bool) (retVal string) {
+// Decode decodes the given ids, back to a String.
+//
+// `skipSpecialTokens` sets the default for `DecodeOpts.SkipSpecialTokens` and
+// can be overridden by the given options. Ids which are neither in the added
+// vocabulary nor in the model are rendered as the model `unk` token if any, or
+// dropped with `WithDropUnknownDecodeOpt`.
+func (t *Tokenizer) Decode(ids []int, skipSpecialTokens bool, opts ...DecodeOpt) (retVal string) {
+	t.inUse.Add(1)
+	defer t.inUse.Add(-1)
+
+	o := DefaultDecodeOpts()
+	o.SkipSpecialTokens = skipSpecialTokens
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	unkToken := t.unkToken(o)
 	var tokens []string
 	for _, id := range ids {
-		if tok, ok := t.addedVocabulary.IdToToken(id, t.model); ok {
-			if !skipSpecialTokens || !t.addedVocabulary.IsSpecialToken(tok) {
-				tokens = append(tokens, tok)
-			}
+		if tok, ok := t.decodeToken(id, unkToken, o.SkipSpecialTokens); ok {
+			tokens = append(tokens, tok)
 		}
 	}
 
+	retVal = t.decodeTokens(tokens)
+	if o.CleanUpTokenizationSpaces {
+		retVal = CleanUpTokenization(retVal)
+	}
+
+	return retVal
+}
+
+// unkToken returns the `unk` token rendering unknown ids with the options o,
+// nil if none.
+func (t *Tokenizer) unkToken(o *DecodeOpts) *string {
+	if o.DropUnknown {
+		return nil
+	}
+	if m, ok := t.model.(unkTokenModel); ok {
+		return m.GetUnkToken()
+	}
+
+	return nil
+}
+
+// decodeToken returns the token of id to decode, false if it is skipped: an
+// unknown id without `unk` token or, with skipSpecialTokens, a special token.
+func (t *Tokenizer) decodeToken(id int, unkToken *string, skipSpecialTokens bool) (string, bool) {
+	tok, ok := t.addedVocabulary.IdToToken(id, t.model)
+	if !ok {
+		if unkToken == nil {
+			return "", false
+		}
+		tok = *unkToken
+	}
+	if skipSpecialTokens && t.addedVocabulary.IsSpecialToken(tok) {
+		return "", false
+	}
+
+	return tok, true
+}
+
+// decodeTokens decodes tokens with the decoder, or joins them with spaces if
+// there is none.
+func (t *Tokenizer) decodeTokens(tokens []string) string {
 	if t.decoder != nil {
-		return (t.decoder).Decode(tokens)
+		return t.decoder.Decode(tokens)
 	}
 
 	return strings.Join(tokens, " ")
 }
 
+// CleanUpTokenization removes spaces before punctuation and abbreviated forms
+// the same way as `clean_up_tokenization` of Python transformers.
+func CleanUpTokenization(s string) string {
+	return strings.NewReplacer(
+		" .", ".",
+		" ?", "?",
+		" !", "!",
+		" ,", ",",
+		" ' ", "'",
+		" n't", "n't",
+		" 'm", "'m",
+		" 's", "'s",
+		" 've", "'ve",
+		" 're", "'re",
+	).Replace(s)
+}
+
 // AddSpecialTokens registers the given tokens as special tokens. This is especially useful for removing
 // these special tokens while decoding
 func (t *Tokenizer) AddSpecialTokens(tokens []AddedToken) (retVal int) {
+	t.configure("AddSpecialTokens")
+	defer t.resetFingerprint()
 	return t.addedVocabulary.AddSpecialTokens(tokens, t.model, t.normalizer)
 }
 
+// SetAddedTokenCacheCapacity sets the size of the cache of added-token matches.
+// A capacity <= 0 disables it.
+func (t *Tokenizer) SetAddedTokenCacheCapacity(capacity int) {
+	t.configure("SetAddedTokenCacheCapacity")
+	t.addedVocabulary.SetMatchCacheCapacity(capacity)
+}
+
 // AddTokens adds the given tokens to the added vocabulary
 func (t *Tokenizer) AddTokens(tokens []AddedToken) (retVal int) {
+	t.configure("AddTokens")
+	defer t.resetFingerprint()
 	return t.addedVocabulary.AddTokens(tokens, t.model, t.normalizer)
 }
 
+// AddTokensWithId adds the given tokens, special or not, to the added vocabulary
+// keeping their ids if they are free. It is used to restore the `added_tokens`
+// of a `tokenizer.json` file.
+func (t *Tokenizer) AddTokensWithId(tokens []AddedTokenWithId) (retVal int) {
+	t.configure("AddTokensWithId")
+	defer t.resetFingerprint()
+	return t.addedVocabulary.AddTokensWithId(tokens, t.model, t.normalizer)
+}
+
 // doNormalize does Normalization logic, go through all normalizers
 func (t *Tokenizer) doNormalize(s string) (retVal *normalizer.NormalizedString, err error) {
 	normalized := normalizer.NewNormalizedFrom(s)
@@ -561,14 +966,24 @@ func (t *Tokenizer) doPreTokenize(pretokenized *PreTokenizedString) (*PreTokeniz
 
 // doTokenize does Tokenization logic, makes the bridge between the pre-tokenization phase and the real
 // tokenization phase, and converting offsets back to the original referential.
-func (t *Tokenizer) doTokenize(pretokenized *PreTokenizedString, typeId int, wordIdx int, offsetType OffsetType) (*Encoding, error) {
-	pretok, err := pretokenized.Tokenize(func(normalized *normalizer.NormalizedString) ([]Token, error) {
-		if t.model == nil {
+func (t *Tokenizer) doTokenize(model Model, pretokenized *PreTokenizedString, typeId int, wordIdx int, offsetType OffsetType) (*Encoding, error) {
+	tokFn := func(normalized *normalizer.NormalizedString) ([]Token, error) {
+		if model == nil {
 			err := fmt.Errorf("Tokenizer.doTokenize() failed: there's no 'Tokenizer Model' setup. You have to include a 'Tokenizer Model' at the time of creating 'Tokenizer'.")
 			return nil, err
 		}
-		return (t.model).Tokenize(normalized.GetNormalized())
-	})
+		return model.Tokenize(normalized.GetNormalized())
+	}
+
+	var (
+		pretok *PreTokenizedString
+		err    error
+	)
+	if t.intraDocWorkers > 1 {
+		pretok, err = pretokenized.TokenizeConcurrently(tokFn, t.intraDocWorkers)
+	} else {
+		pretok, err = pretokenized.Tokenize(tokFn)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -582,30 +997,45 @@ func (t *Tokenizer) doTokenize(pretokenized *PreTokenizedString, typeId int, wor
 }
 
 // PostProcess does post-processing logic, handling the case where there is no PostProcessor set
-func (t *Tokenizer) PostProcess(encoding, pairEncoding *Encoding, addSpecialTokens bool) (retVal *Encoding) {
-	var tEncoding, tPairEncoding *Encoding
+//
+// It returns the truncation errors like `Encode`.
+func (t *Tokenizer) PostProcess(encoding, pairEncoding *Encoding, addSpecialTokens bool) (*Encoding, error) {
+	return t.postProcess(encoding, pairEncoding, addSpecialTokens, t.trunc)
+}
+
+// postProcess truncates with the given params, post-processes and pads the
+// encodings.
+func (t *Tokenizer) postProcess(encoding, pairEncoding *Encoding, addSpecialTokens bool, trunc *TruncationParams) (*Encoding, error) {
+	tEncoding, tPairEncoding := encoding, pairEncoding
 
 	// 1. Truncate if needed
-	if t.trunc == nil {
-		tEncoding, tPairEncoding = encoding, pairEncoding
-	} else {
-		trunc := t.trunc
+	if trunc != nil {
+		if err := trunc.Validate(); err != nil {
+			return nil, err
+		}
+
+		params := trunc
 		var nAddedTokens int = 0 // number of AddedToken
 		if t.postProcessor != nil {
-			processor := t.postProcessor
-			nAddedTokens = processor.AddedTokens(pairEncoding != nil)
+			nAddedTokens = t.postProcessor.AddedTokens(pairEncoding != nil)
 		}
 
-		if addSpecialTokens && nAddedTokens > 0 {
+		if addSpecialTokens && nAddedTokens > 0 && trunc.MaxLength > 0 {
 			maxLength := trunc.MaxLength - nAddedTokens
-			params := &TruncationParams{
+			if maxLength <= trunc.Stride {
+				return nil, fmt.Errorf("Truncation error: max length %d leaves no room for %d special tokens with stride %d: %w", trunc.MaxLength, nAddedTokens, trunc.Stride, ErrTruncationNeeded)
+			}
+			params = &TruncationParams{
 				MaxLength: maxLength,
 				Strategy:  trunc.Strategy,
 				Stride:    trunc.Stride,
 			}
-			tEncoding, tPairEncoding = TruncateEncodings(encoding, pairEncoding, params)
-		} else {
-			tEncoding, tPairEncoding = TruncateEncodings(encoding, pairEncoding, trunc)
+		}
+
+		var err error
+		tEncoding, tPairEncoding, err = TruncateEncodings(encoding, pairEncoding, params)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -618,48 +1048,49 @@ func (t *Tokenizer) PostProcess(encoding, pairEncoding *Encoding, addSpecialToke
 		finalEncoding = DefaultProcess(tEncoding, tPairEncoding, addSpecialTokens)
 	}
 
+	// Post-processors may build a new Encoding, keep track of the source form.
+	sourceForm := encoding.SourceForm
+	if pairEncoding != nil {
+		sourceForm = mergeSourceForm(sourceForm, pairEncoding.SourceForm)
+	}
+	finalEncoding.SourceForm = sourceForm
+
 	// 3. Pad if needed
 	if t.padding == nil {
-		return finalEncoding
+		return finalEncoding, nil
 	}
 
 	var padEncodings []Encoding
 	encodings := []Encoding{*finalEncoding}
 	padEncodings = PadEncodings(encodings, *t.padding)
 	if len(padEncodings) == 1 {
-		return &padEncodings[0]
+		return &padEncodings[0], nil
 	} else {
-		return padEncodings[0].Merge(padEncodings[1:], true)
+		return padEncodings[0].Merge(padEncodings[1:], true), nil
 	}
 }
 
-// EncodeBatch encodes all sentences in concurrency
-func (t *Tokenizer) EncodeBatch(inputs []EncodeInput, addSpecialTokens bool) (retVal []Encoding, err error) {
-	var (
-		encodings []Encoding = make([]Encoding, len(inputs))
-		wg        sync.WaitGroup
-		mu        = &sync.Mutex{}
-	)
-
-	wg.Add(len(inputs))
+// EncodeBatch encodes all inputs across a pool of goroutines (see
+// `WithBatchParallelism`) and pads them if padding is set. The output order
+// matches the input order. It returns the error of the first failing input.
+func (t *Tokenizer) EncodeBatch(inputs []EncodeInput, addSpecialTokens bool, opts ...EncodeOpt) (retVal []Encoding, err error) {
+	t.inUse.Add(1)
+	defer t.inUse.Add(-1)
 
-	// Encoding concurrently
-	for i := 0; i < len(inputs); i++ {
-		go func(i int) {
-			defer wg.Done()
+	encodings := make([]Encoding, len(inputs))
 
-			e, err := t.Encode(inputs[i], addSpecialTokens)
-			if err != nil {
-				log.Fatal(err)
-			}
-			mu.Lock()
-			encodings[i] = *e
-			mu.Unlock()
-		}(i)
+	err = t.runBatch(len(inputs), func(i int) error {
+		e, err := t.Encode(inputs[i], addSpecialTokens, opts...)
+		if err != nil {
+			return fmt.Errorf("EncodeBatch error at input %d: %w", i, err)
+		}
+		encodings[i] = *e
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	wg.Wait()
-
 	// Do padding if included
 	if t.padding != nil {
 		encodings = PadEncodings(encodings, *t.padding)
@@ -668,26 +1099,62 @@ func (t *Tokenizer) EncodeBatch(inputs []EncodeInput, addSpecialTokens bool) (re
 	return encodings, nil
 }
 
-// DecodeBatch decodes all sentences in concurrency
-func (t *Tokenizer) DecodeBatch(sentences [][]int, skipSpecialTokens bool) []string {
-	var decodings []string
-	var wg sync.WaitGroup
+// DecodeBatch decodes all sentences in concurrency. The output order matches
+// the input order.
+func (t *Tokenizer) DecodeBatch(sentences [][]int, skipSpecialTokens bool, opts ...DecodeOpt) []string {
+	decodings := make([]string, len(sentences))
 
-	wg.Add(len(sentences))
+	t.runBatch(len(sentences), func(i int) error {
+		decodings[i] = t.Decode(sentences[i], skipSpecialTokens, opts...)
+		return nil
+	})
 
-	// Decoding concurrently
-	for i := 0; i < len(sentences); i++ {
-		go func(i int) {
-			defer wg.Done()
+	return decodings
+}
 
-			s := t.Decode(sentences[i], skipSpecialTokens)
-			decodings = append(decodings, s)
-		}(i)
+// runBatch calls fn for each index in [0, n) across the batch workers. Once a
+// call fails, the remaining indices are skipped and the error of the lowest
+// failing index is returned.
+func (t *Tokenizer) runBatch(n int, fn func(i int) error) error {
+	workers := t.batchWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
 	}
 
+	var (
+		next   int64 = -1
+		failed int32
+		errs   = make([]error, n)
+		wg     sync.WaitGroup
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&failed) == 0 {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				if err := fn(i); err != nil {
+					errs[i] = err
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
 	wg.Wait()
 
-	return decodings
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // wordCount returns a map of word and its count
@@ -718,222 +1185,124 @@ func NewTokenizerFromFile(file string) (retVal *Tokenizer) {
 	return
 }
 
-// Serialize serializes current Tokenizer to string
-func (t *Tokenizer) Serialize(pretty bool) (retVal string) {
-	// TODO: implement
-	return
-}
-
-// Save saves the current tokenizer at the given path
-func (t *Tokenizer) Save(path string, pretty bool) (err error) {
-	// TODO: implement
-	return
-}
-
 // Train trains a model and replaces the current model using a given trainer
 // The tokenizer does the following steps
-//  1. Concurrently, reads training data (text) from files, normalizes text using
-//     specified normalizer, and generates a slice of words and their frequency (count)
-//  2. Train tokenizer model using specified tokenizer configuration on slice of word-count
-//     generated from previous step to create `vocab` and `merges` data (files)
-//  3. Update current tokenizer with newly generated model (`vocab` and `merges` data)
+//  1. Concurrently (one goroutine per file), reads training data (text) from files line
+//     by line, normalizes and pre-tokenizes lines using the tokenizer configuration and
+//     generates a map of words and their frequency (count)
+//  2. Train tokenizer model using specified tokenizer configuration on the word-count
+//     generated from previous step to create `vocab` and `merges` data
+//  3. Update current tokenizer with newly generated model and the special tokens of
+//     the trainer.
+//
+// The errors of trainers implementing `TrainerWithError` are returned and the
+// tokenizer is left unchanged. The trained model can then be saved with
+// `Model.Save`.
 func (t *Tokenizer) Train(trainer Trainer, files []string) error {
-	type Job struct {
-		File     string
-		Progress *progressbar.ProgressBar
+	var (
+		wg     sync.WaitGroup
+		counts = make([]map[string]int, len(files))
+		errs   = make([]error, len(files))
+	)
+	for i, file := range files {
+		wg.Add(1)
+		go func(i int, file string) {
+			defer wg.Done()
+			counts[i], errs[i] = t.countWords(trainer, file)
+		}(i, file)
 	}
+	wg.Wait()
 
-	var jobs []Job
-	wChan := make(chan map[string]int)
-
-	// channel to signal the main thread that all the words have been
-	doneChan := make(chan (bool), 1)
 	dict := make(map[string]int)
-
-	scanWG := new(sync.WaitGroup)
-
-	for _, f := range files {
-		fsize, err := util.FileSize(f)
-		if err != nil {
-			log.Fatal(err)
-		}
-		bar := progressbar.New(int(fsize))
-
-		jobs = append(jobs, Job{f, bar})
-	}
-
-	// Step 1. scan text files by chunks in goroutines. In each goroutine,
-	// scan line by line, chop into tokens with (value, count) and
-	// queue them up in a channel for next step.
-	// We will set up a wait group to wait for all done.
-	// For each file do:
-	// 1. Create a goroutine to read file by chunks
-	// 2. Read line by line
-	// 3. Pre-tokenize line of text to tokens
-	// 4. Process tokens into its value and count
-	// 5. Send result to a channel for further processing.
-	for i := 0; i < len(jobs); i++ {
-		currentJob := i
-
-		file := jobs[currentJob].File
-		// current is the counter for bytes of the file.
-		var current int64 = 0
-		var limit int64 = 100 * mb
-
-		fi, err := os.Stat(file)
-		if err != nil {
-			return err
+	for i, words := range counts {
+		if errs[i] != nil {
+			return fmt.Errorf("Train: %w", errs[i])
 		}
-		fsize := float64(fi.Size())
-
-		chunkNum := int(math.Ceil(fsize / float64(limit)))
-
-		// Setup some workers to process
-		for n := 1; n <= chunkNum; n++ {
-			scanWG.Add(1)
-
-			go func(n int, file string) {
-				// start reading file chunk by chunk
-				current = t.processChunk(current, limit, file, wChan, trainer)
-				fmt.Printf("File chunk %d has been completed\n", n)
-				scanWG.Done()
-			}(n, file)
+		for w, c := range words {
+			dict[w] += c
 		}
 	}
 
-	// Read all incoming words from the channel and add to the dict
-	go func() {
-		fmt.Println("Start collecting words...")
-		for words := range wChan {
-			for w, c := range words {
-				count, ok := dict[w]
-				// word exists, sum up frequency
-				if ok {
-					dict[w] = count + c
-				} else {
-					// word not exist, let add it
-					dict[w] = c
-				}
-			}
-		}
-
-		// signal the main thread all done with this goroutine
-		doneChan <- true
-	}()
-
-	// wait for all goroutines to complete
-	scanWG.Wait()
-	close(wChan)
-
-	// Wait for dictionary to process all words then close
-	<-doneChan
-	close(doneChan)
-
-	fmt.Printf("Dictionary length: %v words\n", len(dict))
-	// // Print out some samples
-	// var count = 0
-	// for k, _ := range dict {
-	// if count <= 5 {
-	// fmt.Println(k)
-	// count++
-	// }
-	// }
-
 	// Training model
-	fmt.Println("Start training...")
-	model, specialTokens := trainer.Train(dict)
+	var (
+		model         Model
+		specialTokens []AddedToken
+	)
+	if et, ok := trainer.(TrainerWithError); ok {
+		var err error
+		if model, specialTokens, err = et.TrainWithError(dict); err != nil {
+			return fmt.Errorf("Train: %w", err)
+		}
+	} else {
+		model, specialTokens = trainer.Train(dict)
+	}
 
 	// Replace with trained model
-	t.model = model
-	t.AddSpecialTokens(specialTokens)
+	t.WithModel(model)
+	if len(specialTokens) > 0 {
+		t.AddSpecialTokens(specialTokens)
+	}
 
 	return nil
 }
 
-// processChunk reads file chunk and processes it to word-count and sends off to channel
-// offset: start bound
-// limit: end bound
-// filename: file path includes file name
-// channel: channel to send proccessed words to.
-// current: cummulative point where the file processing stops.
-// trainer: Trainer to process tokens
-func (t *Tokenizer) processChunk(offset int64, limit int64, filename string, channel chan (map[string]int), trainer Trainer) (current int64) {
+// countWords reads a training file line by line and counts the words produced
+// by the normalizer and pre-tokenizer with the trainer.
+func (t *Tokenizer) countWords(trainer Trainer, filename string) (map[string]int, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	defer file.Close()
 
-	// move the pointer of the file to the start of designated chunk
-	file.Seek(offset, 0) // 0 means relative to the origin of file
-
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 1*gb) // initial buffer
-	scanner.Buffer(buf, 2*gb)    // max buffer size = 2GB
-
-	var cummulativeSize int64
-
-	for scanner.Scan() {
-		// Stop if read size has exceed the chunk size
-		cummulativeSize += int64(len(scanner.Bytes()))
-		if cummulativeSize > limit {
-			break
-		}
-
-		// line words
-		lwords := make(map[string]int)
-		var line string
-		line = scanner.Text()
-		// NOTE: io.scanner returns line w/o `\n`. We add it back manually.
-		// line = fmt.Sprintf("%v\n", line)
-
-		/* input := NewSingleEncodeInput(NewInputSequence(line))
-		 * encoding, err := t.Encode(input, false)
-		 * if err != nil {
-		 *   log.Fatalf("call 'Encode' method error: %v\n", err)
-		 * } */
-
-		normalized, err := t.doNormalize(line)
+	var bar *progressbar.ProgressBar
+	if trainer.WithProgressBar() {
+		fsize, err := util.FileSize(filename)
 		if err != nil {
-			log.Fatalf("call 'doNormalize' method error: %v\n", err)
+			return nil, err
 		}
+		bar = progressbar.New(int(fsize))
+	}
 
-		pretok := NewPreTokenizedStringFromNS(normalized)
-		pretokenized, err := t.doPreTokenize(pretok)
-		if err != nil {
-			log.Fatalf("call 'doPreTokenize' method error: %v\n", err)
+	words := make(map[string]int)
+	reader := bufio.NewReader(file)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
 		}
-
-		// NOTE. should we get OffsetType as input parameter: either Byte or Char?
-		pretoks := pretokenized.GetSplits(normalizer.OriginalTarget, Byte)
-		var tokens []string
-		for _, pretok := range pretoks {
-			tokens = append(tokens, pretok.Value)
+		if bar != nil {
+			bar.Add(len(line))
 		}
 
-		/*
-		 *     normalized := t.normalize(line)
-		 *     // NOTE: if there are no preTokenizer, the default `preTokenize`
-		 *     // will return the whole line without modification. Hence,
-		 *     // token will be a line string. In that case, we may need to strip
-		 *     // white spaces in the next step.
-		 *     preTokenized := t.preTokenize(normalized.GetNormalized())
-		 *     var tokens []string
-		 *     for _, tok := range preTokenized {
-		 *       tokens = append(tokens, tok.Value)
-		 *     }
-		 *
-		 *  */
+		// NOTE: lines are trained on without their line break.
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" {
+			normalized, err := t.doNormalize(line)
+			if err != nil {
+				return nil, err
+			}
+
+			pretok := NewPreTokenizedStringFromNS(normalized)
+			pretokenized, err := t.doPreTokenize(pretok)
+			if err != nil {
+				return nil, err
+			}
 
-		// process tokens
-		// trainer.ProcessTokens(lwords, tokens)
-		trainer.ProcessTokens(lwords, tokens)
-		// send to channel for further process
-		channel <- lwords
+			pretoks := pretokenized.GetSplits(normalizer.OriginalTarget, Byte)
+			tokens := make([]string, len(pretoks))
+			for i, pretok := range pretoks {
+				tokens[i] = pretok.Value
+			}
+			trainer.ProcessTokens(words, tokens)
+		}
 
+		if readErr == io.EOF {
+			break
+		}
 	}
 
-	return cummulativeSize
+	return words, nil
 }
 
 /*
@@ -1080,7 +1449,11 @@ func (t *Tokenizer) EncodeSingle(input string, addSpecialTokensOpt ...bool) (*En
 	return t.Encode(encodeInput, addSpecialTokens)
 }
 
-// EncodePair encodes a pair of string sequences.
+// EncodePair encodes a pair of string sequences, i.e. for cross-encoder or
+// question answering models. Tokens of the pair have type id 1 unless the
+// post-processor sets them otherwise, and `Encoding.GetSequenceIds` tells the
+// sequence of each token. Truncation applies to both sequences according to
+// the truncation strategy.
 //
 // Params:
 // - input: the sequence string to be tokenized
@@ -1100,6 +1473,48 @@ func (t *Tokenizer) EncodePair(input, pair string, addSpecialTokensOpt ...bool)
 	return t.Encode(encodeInput, addSpecialTokens)
 }
 
+// EncodeWords encodes a sequence already split into words, as
+// `is_split_into_words=True` in HuggingFace transformers, i.e. to label the
+// tokens of a token classification dataset. `Encoding.GetWords` gives the
+// index of the word of each token and offsets are relative to that word. See
+// `NewPreTokenizedInputSequence`.
+//
+// Params:
+// - words: the words of the sequence
+// - addSpecialTokensOpt: optional (default = false) whether adding special tokens
+// e.g. in BERT model `[CLS]` `[UNK]` or `[SEP]`
+func (t *Tokenizer) EncodeWords(words []string, addSpecialTokensOpt ...bool) (*Encoding, error) {
+	addSpecialTokens := false
+	if len(addSpecialTokensOpt) > 0 {
+		addSpecialTokens = addSpecialTokensOpt[0]
+	}
+
+	encodeInput := NewSingleEncodeInput(NewPreTokenizedInputSequence(words))
+
+	return t.Encode(encodeInput, addSpecialTokens)
+}
+
+// EncodeWordsPair encodes a pair of sequences already split into words, see
+// `EncodeWords` and `EncodePair`. Word indexes restart at 0 in the pair.
+//
+// Params:
+// - words: the words of the sequence
+// - pair: the words of the pair sequence
+// - addSpecialTokensOpt: optional (default = false) whether adding special tokens
+// e.g. in BERT model `[CLS]` `[UNK]` or `[SEP]`
+func (t *Tokenizer) EncodeWordsPair(words, pair []string, addSpecialTokensOpt ...bool) (*Encoding, error) {
+	addSpecialTokens := false
+	if len(addSpecialTokensOpt) > 0 {
+		addSpecialTokens = addSpecialTokensOpt[0]
+	}
+
+	seq := NewPreTokenizedInputSequence(words)
+	pseq := NewPreTokenizedInputSequence(pair)
+	encodeInput := NewDualEncodeInput(seq, pseq)
+
+	return t.Encode(encodeInput, addSpecialTokens)
+}
+
 // Tokenize slices input string into tokens.
 //
 // Params:
@@ -0,0 +1,1043 @@
+package tokenizer_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/season-studio/tokenizer"
+	"github.com/season-studio/tokenizer/model"
+	"github.com/season-studio/tokenizer/model/bpe"
+	"github.com/season-studio/tokenizer/model/unigram"
+	"github.com/season-studio/tokenizer/model/wordlevel"
+	"github.com/season-studio/tokenizer/normalizer"
+	"github.com/season-studio/tokenizer/pretokenizer"
+	"github.com/season-studio/tokenizer/pretrained"
+	"github.com/season-studio/tokenizer/processor"
+)
+
+// getOfflineByteLevelBPE builds a GPT-2 style tokenizer with a pure byte vocab
+// so that tests do not need to download `gpt2` files.
+func getOfflineByteLevelBPE() *tokenizer.Tokenizer {
+	vocab := make(model.Vocab)
+	for b := 0; b < 256; b++ {
+		vocab[pretokenizer.BytesChar[uint8(b)]] = b
+	}
+	tk := tokenizer.NewTokenizer(bpe.NewBPE(vocab, make(bpe.Merges)))
+
+	bl := pretokenizer.NewByteLevel()
+	bl.SetAddPrefixSpace(false)
+	tk.WithPreTokenizer(bl)
+	tk.WithDecoder(bl)
+
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<|endoftext|>", true)})
+	tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<custom>", false)})
+
+	return tk
+}
+
+func TestDecode_ByteLevel(t *testing.T) {
+	tk := getOfflineByteLevelBPE()
+
+	en, err := tk.EncodeSingle("Hello <custom> world , it 's ok ?<|endoftext|>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		skip    bool
+		cleanUp bool
+		want    string
+	}{
+		{false, false, "Hello <custom> world , it 's ok ?<|endoftext|>"},
+		{true, false, "Hello <custom> world , it 's ok ?"},
+		{false, true, "Hello <custom> world, it's ok?<|endoftext|>"},
+		{true, true, "Hello <custom> world, it's ok?"},
+	}
+
+	for _, tt := range tests {
+		got := tk.Decode(en.Ids, tt.skip, tokenizer.WithCleanUpTokenizationSpacesDecodeOpt(tt.cleanUp))
+		if got != tt.want {
+			t.Errorf("skip=%v cleanUp=%v: want %q, got %q", tt.skip, tt.cleanUp, tt.want, got)
+		}
+	}
+}
+
+func TestDecode_ByteLevelAddedAndPartialChars(t *testing.T) {
+	tk := getOfflineByteLevelBPE()
+	tk.AddSpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<|思考|>", true)})
+
+	en, err := tk.EncodeSingle("<|思考|>héllo 🚀")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := tk.Decode(en.Ids, false), "<|思考|>héllo 🚀"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got, want := tk.Decode(en.Ids, true), "héllo 🚀"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// The last character is cut in the middle of its bytes.
+	if got, want := tk.Decode(en.Ids[:len(en.Ids)-1], true), "héllo \uFFFD"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestDecode_Bert(t *testing.T) {
+	tk := pretrained.BertBaseUncased()
+	tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[NEW]", false, tokenizer.WithNormalized(false))})
+
+	en, err := tk.EncodeSingle("Hello [NEW] world, it's ok?", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		skip    bool
+		cleanUp bool
+		want    string
+	}{
+		{false, false, "[CLS] hello [NEW] world, it ' s ok? [SEP]"},
+		{true, false, "hello [NEW] world, it ' s ok?"},
+		{false, true, "[CLS] hello [NEW] world, it's ok? [SEP]"},
+		{true, true, "hello [NEW] world, it's ok?"},
+	}
+
+	for _, tt := range tests {
+		got := tk.Decode(en.Ids, false,
+			tokenizer.WithSkipSpecialTokensDecodeOpt(tt.skip),
+			tokenizer.WithCleanUpTokenizationSpacesDecodeOpt(tt.cleanUp),
+		)
+		if got != tt.want {
+			t.Errorf("skip=%v cleanUp=%v: want %q, got %q", tt.skip, tt.cleanUp, tt.want, got)
+		}
+	}
+}
+
+func TestDecode_UnknownId(t *testing.T) {
+	tk := pretrained.BertBaseUncased()
+
+	id, _ := tk.TokenToId("hello")
+	if got, want := tk.Decode([]int{id, 1 << 30}, true), "hello [UNK]"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	got := tk.Decode([]int{id, 1 << 30}, true, tokenizer.WithDropUnknownDecodeOpt(true))
+	if want := "hello"; got != want {
+		t.Errorf("drop unknown: want %q, got %q", want, got)
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	tk := getOfflineByteLevelBPE()
+
+	inputs := []string{"first", "second ,", "third<|endoftext|>", "", "fifth !"}
+	var ids [][]int
+	for _, input := range inputs {
+		en, err := tk.EncodeSingle(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, en.Ids)
+	}
+
+	got := tk.DecodeBatch(ids, true, tokenizer.WithCleanUpTokenizationSpacesDecodeOpt(true))
+	want := []string{"first", "second,", "third", "", "fifth!"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func getNFKCUnigram(t *testing.T) *tokenizer.Tokenizer {
+	pieces := []unigram.TokenScore{{Token: "<unk>", Score: 0}}
+	for _, r := range "abcdeéhló " {
+		pieces = append(pieces, unigram.TokenScore{Token: string(r), Score: -1})
+	}
+	m, err := unigram.New(pieces, unigram.WithUnkID(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(m)
+	tk.WithNormalizer(normalizer.NewNFKC())
+
+	return tk
+}
+
+func TestEncode_SourceForm(t *testing.T) {
+	nfd := "he\u0301llo" // decomposed é
+	nfc := "h\u00e9llo"  // composed é
+	ascii := "hello abc"
+
+	tests := []struct {
+		name  string
+		tk    *tokenizer.Tokenizer
+		input string
+		want  tokenizer.SourceForm
+	}{
+		{"byte-level NFD", getOfflineByteLevelBPE(), nfd, tokenizer.SourceFormPreserved},
+		{"byte-level NFC", getOfflineByteLevelBPE(), nfc, tokenizer.SourceFormPreserved},
+		{"unigram+NFKC NFD", getNFKCUnigram(t), nfd, tokenizer.SourceFormChanged},
+		{"unigram+NFKC NFC", getNFKCUnigram(t), nfc, tokenizer.SourceFormPreserved},
+		{"unigram+NFKC ascii", getNFKCUnigram(t), ascii, tokenizer.SourceFormPreserved},
+	}
+
+	for _, tt := range tests {
+		sink := tokenizer.NewMemoryMetricsSink()
+		tt.tk.WithMetricsSink(sink)
+
+		en, err := tt.tk.EncodeSingle(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if en.SourceForm != tt.want {
+			t.Errorf("%v: want source form %v, got %v", tt.name, tt.want, en.SourceForm)
+		}
+
+		wantCounters := map[string]int64{tokenizer.MetricSourceFormPreserved: 1}
+		if tt.want == tokenizer.SourceFormChanged {
+			wantCounters = map[string]int64{tokenizer.MetricSourceFormChanged: 1}
+		}
+		if got := sink.Counters(); !reflect.DeepEqual(wantCounters, got) {
+			t.Errorf("%v: want counters %v, got %v", tt.name, wantCounters, got)
+		}
+	}
+
+	// Pairs are changed if any sequence is.
+	tk := getNFKCUnigram(t)
+	sink := tokenizer.NewMemoryMetricsSink()
+	tk.WithMetricsSink(sink)
+	en, err := tk.EncodePair(ascii, nfd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if en.SourceForm != tokenizer.SourceFormChanged {
+		t.Errorf("pair: want source form %v, got %v", tokenizer.SourceFormChanged, en.SourceForm)
+	}
+	wantCounters := map[string]int64{tokenizer.MetricSourceFormPreserved: 1, tokenizer.MetricSourceFormChanged: 1}
+	if got := sink.Counters(); !reflect.DeepEqual(wantCounters, got) {
+		t.Errorf("pair: want counters %v, got %v", wantCounters, got)
+	}
+}
+
+// genDocument generates a deterministic document of about `size` bytes mixing
+// ascii, accented and CJK words, punctuation and added tokens.
+func genDocument(size int) string {
+	words := []string{
+		"hello", "world", "tokenizer", "parallel", "unaffable", "naïve", "café",
+		"日本語", "🚀", ",", ".", "?", "it's", "<custom>", "<|endoftext|>", "[NEW]", "12345",
+	}
+	r := rand.New(rand.NewSource(42))
+
+	var sb strings.Builder
+	for sb.Len() < size {
+		sb.WriteString(words[r.Intn(len(words))])
+		if r.Intn(8) == 0 {
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString(" ")
+		}
+	}
+
+	return sb.String()
+}
+
+func TestEncode_IntraDocParallelism(t *testing.T) {
+	doc := genDocument(128 << 10)
+
+	tests := []struct {
+		name string
+		tk   func() *tokenizer.Tokenizer
+	}{
+		{"byte-level BPE", getOfflineByteLevelBPE},
+		{"bert", func() *tokenizer.Tokenizer {
+			tk := pretrained.BertBaseUncased()
+			tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[NEW]", false, tokenizer.WithNormalized(false))})
+			return tk
+		}},
+		{"unigram+NFKC", func() *tokenizer.Tokenizer {
+			tk := getNFKCUnigram(t)
+			tk.WithPreTokenizer(pretokenizer.NewWhitespace())
+			return tk
+		}},
+	}
+
+	for _, tt := range tests {
+		want, err := tt.tk().EncodeSingle(doc, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, workers := range []int{2, 3, 8} {
+			tk := tt.tk()
+			tk.WithIntraDocParallelism(workers)
+			got, err := tk.EncodeSingle(doc, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("%v with %v workers: encoding differs from the serial one", tt.name, workers)
+			}
+		}
+	}
+}
+
+func BenchmarkEncode_IntraDocParallelism(b *testing.B) {
+	doc := genDocument(10 << 20)
+
+	for _, workers := range []int{1, runtime.NumCPU()} {
+		tk := pretrained.BertBaseUncased()
+		tk.WithIntraDocParallelism(workers)
+
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(doc)))
+			for i := 0; i < b.N; i++ {
+				if _, err := tk.EncodeSingle(doc, true); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeBatch(t *testing.T) {
+	var inputs []tokenizer.EncodeInput
+	var want []tokenizer.Encoding
+	ref := getOfflineByteLevelBPE()
+	for i := 0; i < 50; i++ {
+		doc := genDocument(i * 37)
+		input := tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(doc))
+		if i%3 == 0 {
+			input = tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence(doc), tokenizer.NewInputSequence("a pair"))
+		}
+		en, err := ref.Encode(input, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, input)
+		want = append(want, *en)
+	}
+
+	for _, workers := range []int{0, 1, 4, 100} {
+		tk := getOfflineByteLevelBPE()
+		tk.WithBatchParallelism(workers)
+		got, err := tk.EncodeBatch(inputs, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%v workers: batch encodings differ from the serial ones", workers)
+		}
+	}
+
+	// The error of the first failing input is returned instead of exiting.
+	wl, err := wordlevel.New(map[string]int{"a": 0, "aa": 1}, "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(wl)
+	tk.WithBatchParallelism(2)
+	var bad []tokenizer.EncodeInput
+	for _, doc := range []string{"a", "aa", "ab", "b"} {
+		bad = append(bad, tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence(doc)))
+	}
+	if _, err := tk.EncodeBatch(bad, false); err == nil || !strings.Contains(err.Error(), "input 2") {
+		t.Errorf("want error at input 2, got %v", err)
+	}
+	if got, err := tk.EncodeBatch(nil, false); err != nil || len(got) != 0 {
+		t.Errorf("want no encodings, got %v, %v", got, err)
+	}
+}
+
+// getWordLevelBert builds a BERT style tokenizer with one token per letter word.
+func getWordLevelBert(t *testing.T) *tokenizer.Tokenizer {
+	vocab := map[string]int{"[UNK]": 0, "[CLS]": 1, "[SEP]": 2}
+	for r := 'a'; r <= 'z'; r++ {
+		vocab[string(r)] = len(vocab)
+	}
+	wl, err := wordlevel.New(vocab, "[UNK]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := tokenizer.NewTokenizer(wl)
+	tk.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+	tk.WithPostProcessor(processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 2}, processor.PostToken{Value: "[CLS]", Id: 1}))
+	return tk
+}
+
+func TestEncode_Stats(t *testing.T) {
+	var got []tokenizer.EncodeStats
+	stats := tokenizer.NewStats(func(s tokenizer.EncodeStats) {
+		got = append(got, s)
+	})
+	tk := getWordLevelBert(t)
+	tk.WithStats(stats)
+	tk.WithCache(tokenizer.NewLRUCache(16))
+
+	for i := 0; i < 2; i++ { // cached encodings are counted too
+		if _, err := tk.EncodeSingle("a bb c [UNK]", true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	single := tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("a b c d"))
+	if _, err := tk.Encode(single, true, tokenizer.WithTruncationEncodeOpt(&tokenizer.TruncationParams{MaxLength: 4})); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []tokenizer.EncodeStats{
+		{Tokens: 6, UnkTokens: 2},
+		{Tokens: 6, UnkTokens: 2},
+		{Tokens: 4, Truncated: true},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+	wantSnapshot := tokenizer.StatsSnapshot{Encodes: 3, Tokens: 16, UnkTokens: 4, Truncations: 1}
+	if got := stats.Snapshot(); got != wantSnapshot {
+		t.Errorf("want %+v, got %+v", wantSnapshot, got)
+	}
+	if got := stats.Snapshot().UnkRate(); got != 0.25 {
+		t.Errorf("want unk rate 0.25, got %v", got)
+	}
+	stats.Reset()
+	if got := stats.Snapshot(); got != (tokenizer.StatsSnapshot{}) {
+		t.Errorf("want zero counters after reset, got %+v", got)
+	}
+
+	// Byte fallback tokens of a Unigram model.
+	pieces := []unigram.TokenScore{{Token: "<unk>", Score: 0}, {Token: "a", Score: -1}, {Token: "<0xC3>", Score: -5}, {Token: "<0xA9>", Score: -5}}
+	m, err := unigram.New(pieces, unigram.WithUnkID(0), unigram.WithByteFallback(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk = tokenizer.NewTokenizer(m)
+	stats = tokenizer.NewStats(nil)
+	tk.WithStats(stats)
+	en, err := tk.EncodeSingle("aéa", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want2 := tokenizer.StatsSnapshot{Encodes: 1, Tokens: 4, ByteFallbackTokens: 2}
+	if got := stats.Snapshot(); got != want2 {
+		t.Errorf("want %+v, got %+v (%q)", want2, got, en.Tokens)
+	}
+}
+
+func TestEncode_Truncation(t *testing.T) {
+	tk := getWordLevelBert(t)
+	tk.WithCache(tokenizer.NewLRUCache(16)) // overrides are part of the cache key
+	input := tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence("a b c d e f"), tokenizer.NewInputSequence("x y z"))
+
+	// LongestFirst removes tokens from the longest sequence first.
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 8, Strategy: tokenizer.LongestFirst, Stride: 1})
+	en, err := tk.Encode(input, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"[CLS]", "a", "b", "c", "[SEP]", "x", "y", "[SEP]"}; !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want %q, got %q", want, en.Tokens)
+	}
+	if want := []int{0, 0, 0, 0, 0, 1, 1, 1}; !reflect.DeepEqual(want, en.TypeIds) {
+		t.Errorf("want type ids %v, got %v", want, en.TypeIds)
+	}
+	if len(en.Overflowing) == 0 {
+		t.Errorf("want overflowing encodings, got none")
+	}
+
+	// Per-call overrides with overflowing tokens, stride and offsets.
+	en, err = tk.Encode(input, true, tokenizer.WithTruncationEncodeOpt(&tokenizer.TruncationParams{MaxLength: 8, Strategy: tokenizer.OnlyFirst, Stride: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"[CLS]", "a", "b", "[SEP]", "x", "y", "z", "[SEP]"}; !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want %q, got %q", want, en.Tokens)
+	}
+	var overflowing [][]string
+	var overflowingOffsets [][]int
+	for _, o := range en.Overflowing {
+		overflowing = append(overflowing, o.Tokens)
+		overflowingOffsets = append(overflowingOffsets, o.Offsets[1])
+	}
+	wantOverflowing := [][]string{
+		{"[CLS]", "b", "c", "[SEP]", "x", "y", "z", "[SEP]"},
+		{"[CLS]", "c", "d", "[SEP]", "x", "y", "z", "[SEP]"},
+		{"[CLS]", "d", "e", "[SEP]", "x", "y", "z", "[SEP]"},
+		{"[CLS]", "e", "f", "[SEP]", "x", "y", "z", "[SEP]"},
+	}
+	if !reflect.DeepEqual(wantOverflowing, overflowing) {
+		t.Errorf("want overflowing %q, got %q", wantOverflowing, overflowing)
+	}
+	if want := [][]int{{2, 3}, {4, 5}, {6, 7}, {8, 9}}; !reflect.DeepEqual(want, overflowingOffsets) {
+		t.Errorf("want overflowing offsets %v, got %v", want, overflowingOffsets)
+	}
+
+	en, err = tk.Encode(input, true, tokenizer.WithTruncationEncodeOpt(&tokenizer.TruncationParams{MaxLength: 11, Strategy: tokenizer.OnlySecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"[CLS]", "a", "b", "c", "d", "e", "f", "[SEP]", "x", "y", "[SEP]"}; !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want %q, got %q", want, en.Tokens)
+	}
+
+	// A nil override disables truncation.
+	en, err = tk.Encode(input, true, tokenizer.WithTruncationEncodeOpt(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if en.Len() != 12 || len(en.Overflowing) != 0 {
+		t.Errorf("want untruncated encoding, got %q", en.Tokens)
+	}
+
+	// Truncation errors are returned.
+	errTests := []tokenizer.TruncationParams{
+		{MaxLength: 5, Strategy: tokenizer.OnlySecond},
+		{MaxLength: 2, Strategy: tokenizer.OnlyFirst},
+		{MaxLength: 4, Strategy: tokenizer.LongestFirst, Stride: 4},
+		{MaxLength: 4, Strategy: tokenizer.TruncationStrategy(9)},
+	}
+	single := tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("a b c d e f"))
+	for _, params := range errTests {
+		params := params
+		if en, err := tk.Encode(single, true, tokenizer.WithTruncationEncodeOpt(&params)); err == nil {
+			t.Errorf("%+v: want error, got %q", params, en.Tokens)
+		}
+	}
+
+	// Encodings which cannot fit the max length wrap ErrTruncationNeeded.
+	for _, tt := range []struct {
+		input  tokenizer.EncodeInput
+		params tokenizer.TruncationParams
+	}{
+		{input, tokenizer.TruncationParams{MaxLength: 9, Strategy: tokenizer.OnlySecond}},
+		{single, tokenizer.TruncationParams{MaxLength: 2, Strategy: tokenizer.OnlyFirst}},
+	} {
+		params := tt.params
+		if _, err := tk.Encode(tt.input, true, tokenizer.WithTruncationEncodeOpt(&params)); !errors.Is(err, tokenizer.ErrTruncationNeeded) {
+			t.Errorf("%+v: want ErrTruncationNeeded, got %v", params, err)
+		}
+	}
+
+	// PostProcess returns them too.
+	en, err = tk.EncodeSingleSequence(tokenizer.NewInputSequence("a b c d e f"), 0, tokenizer.Byte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 2, Strategy: tokenizer.OnlyFirst})
+	if _, err := tk.PostProcess(en, nil, true); !errors.Is(err, tokenizer.ErrTruncationNeeded) {
+		t.Errorf("PostProcess: want ErrTruncationNeeded, got %v", err)
+	}
+}
+
+func TestEncode_Cache(t *testing.T) {
+	tk := getOfflineByteLevelBPE()
+	want, err := tk.EncodeSingle("Hello <custom> world<|endoftext|>", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPair, err := tk.EncodePair("Hello", "world", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChar, err := tk.EncodeCharOffsets(tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("naïve café")), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := tokenizer.NewLRUCache(128)
+	tk.WithCache(cache)
+	sink := tokenizer.NewMemoryMetricsSink()
+	tk.WithMetricsSink(sink)
+
+	for i := 0; i < 2; i++ {
+		got, err := tk.EncodeSingle("Hello <custom> world<|endoftext|>", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("single #%v: want %+v, got %+v", i, want, got)
+		}
+
+		got, err = tk.EncodePair("Hello", "world", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(wantPair, got) {
+			t.Errorf("pair #%v: want %+v, got %+v", i, wantPair, got)
+		}
+
+		got, err = tk.EncodeCharOffsets(tokenizer.NewSingleEncodeInput(tokenizer.NewInputSequence("naïve café")), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(wantChar, got) {
+			t.Errorf("char offsets #%v: want %+v, got %+v", i, wantChar, got)
+		}
+	}
+
+	// Byte and char offsets of the same input are cached separately.
+	if _, err := tk.EncodeSingle("naïve café", true); err != nil {
+		t.Fatal(err)
+	}
+
+	wantCounters := map[string]int64{
+		tokenizer.MetricEncodeCacheHit:      3,
+		tokenizer.MetricEncodeCacheMiss:     4,
+		tokenizer.MetricSourceFormPreserved: 5,
+	}
+	if got := sink.Counters(); !reflect.DeepEqual(wantCounters, got) {
+		t.Errorf("want counters %v, got %v", wantCounters, got)
+	}
+	if cache.Len() != 4 {
+		t.Errorf("want 4 cached encodings, got %v", cache.Len())
+	}
+}
+
+func TestEncode_CacheCopies(t *testing.T) {
+	tk := getOfflineByteLevelBPE()
+	tk.WithCache(tokenizer.NewLRUCache(128))
+
+	want, err := tk.EncodeSingle("Hello world", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := tk.EncodeSingle("Hello world", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Modifying both the encoding cached by a miss and the one returned by a
+	// hit must not change later cached results.
+	for _, en := range []*tokenizer.Encoding{want, snapshot} {
+		en.Ids[0] = -1
+		en.Tokens[0] = "modified"
+		en.Offsets[0][0] = -1
+		en.Words = append(en.Words[:0], 42)
+	}
+
+	got, err := tk.EncodeSingle("Hello world", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk.WithCache(nil)
+	uncached, err := tk.EncodeSingle("Hello world", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(uncached, got) {
+		t.Errorf("want %+v, got %+v", uncached, got)
+	}
+}
+
+func TestEncode_CacheConfigChange(t *testing.T) {
+	tk := getOfflineByteLevelBPE()
+	cache := tokenizer.NewLRUCache(128)
+	tk.WithCache(cache)
+
+	input := "Hello <new> world"
+	before, err := tk.EncodeSingle(input, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<new>", false)})
+	got, err := tk.EncodeSingle(input, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(before, got) {
+		t.Errorf("added token: got a stale cached encoding %v", got.Tokens)
+	}
+	if !strings.Contains(strings.Join(got.Tokens, "|"), "|<new>|") {
+		t.Errorf("added token: want <new> as a single token, got %v", got.Tokens)
+	}
+
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 2, Strategy: tokenizer.LongestFirst})
+	got, err = tk.EncodeSingle(input, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Ids) != 2 {
+		t.Errorf("truncation: want 2 ids, got %v", got.Ids)
+	}
+
+	// Tokenizers with the same configuration share cache entries.
+	other := getOfflineByteLevelBPE()
+	other.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<new>", false)})
+	other.WithTruncation(&tokenizer.TruncationParams{MaxLength: 2, Strategy: tokenizer.LongestFirst})
+	sink := tokenizer.NewMemoryMetricsSink()
+	other.WithMetricsSink(sink)
+	other.WithCache(cache)
+	if _, err := other.EncodeSingle(input, true); err != nil {
+		t.Fatal(err)
+	}
+	if hits := sink.Counters()[tokenizer.MetricEncodeCacheHit]; hits != 1 {
+		t.Errorf("want 1 hit from an equally configured tokenizer, got %v", hits)
+	}
+}
+
+func TestEncode_CacheModelConfig(t *testing.T) {
+	vocab := model.Vocab{"a": 0, "b": 1, "c": 2, "ab": 3, "bc": 4}
+	newTokenizer := func(merges []string, opts ...bpe.Option) *tokenizer.Tokenizer {
+		m, err := bpe.New(vocab, merges, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tokenizer.NewTokenizer(m)
+	}
+
+	// Tokenizers of the same vocab with other merges do not share entries.
+	cache := tokenizer.NewLRUCache(128)
+	for _, tt := range []struct {
+		merges []string
+		want   []string
+	}{
+		{[]string{"a b"}, []string{"ab", "c"}},
+		{[]string{"b c"}, []string{"a", "bc"}},
+	} {
+		tk := newTokenizer(tt.merges)
+		tk.WithCache(cache)
+		en, err := tk.EncodeSingle("abc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.want, en.Tokens) {
+			t.Errorf("merges %v: want %v, got %v", tt.merges, tt.want, en.Tokens)
+		}
+	}
+
+	// Encodings of a model with dropout are not cached.
+	tk := newTokenizer([]string{"a b"}, bpe.WithDropout(0.5))
+	cache = tokenizer.NewLRUCache(128)
+	tk.WithCache(cache)
+	if _, err := tk.EncodeSingle("abc"); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("want no cached encodings, got %v", cache.Len())
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := tokenizer.NewLRUCache(4, 1)
+	for i := 0; i < 6; i++ {
+		cache.Put(uint64(i), tokenizer.Encoding{Ids: []int{i}})
+	}
+	if cache.Len() != 4 {
+		t.Errorf("want 4 cached encodings, got %v", cache.Len())
+	}
+	if _, ok := cache.Get(0); ok {
+		t.Errorf("want key 0 evicted")
+	}
+	if en, ok := cache.Get(5); !ok || !reflect.DeepEqual([]int{5}, en.Ids) {
+		t.Errorf("want key 5 cached, got %v, %v", en, ok)
+	}
+}
+
+func TestTokenizer_Train(t *testing.T) {
+	dir := t.TempDir()
+	corpus := map[string]string{
+		"a.txt": "low lower lowest\nlow low\n",
+		"b.txt": "newer newest\r\nlow",
+	}
+	var files []string
+	for name, data := range corpus {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	btb := bpe.NewBPETrainerBuilder()
+	btb.ShowProgress(false)
+	btb.VocabSize(30)
+	btb.MinFrequency(2)
+	btb.SpecialTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("[UNK]", true)})
+	trainer := btb.Build()
+
+	vocab := model.Vocab{"l": 0, "o": 1, "w": 2}
+	tk := tokenizer.NewTokenizer(bpe.NewBPE(vocab, make(bpe.Merges)))
+	tk.WithPreTokenizer(pretokenizer.NewWhitespace())
+	tk.WithCache(tokenizer.NewLRUCache(10))
+
+	// Warm the cache with the initial model.
+	if _, err := tk.EncodeSingle("low"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tk.Train(trainer, files); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, ok := tk.TokenToId("[UNK]"); !ok || id != 0 {
+		t.Errorf("want special token id 0, got %v (%v)", id, ok)
+	}
+	if got := tk.GetSpecialTokens(); !reflect.DeepEqual([]string{"[UNK]"}, got) {
+		t.Errorf("want special tokens %q, got %q", []string{"[UNK]"}, got)
+	}
+
+	en, err := tk.EncodeSingle("low")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"low"}; !reflect.DeepEqual(want, en.Tokens) {
+		t.Errorf("want %q, got %q", want, en.Tokens)
+	}
+
+	// The saved model encodes as the trained one.
+	en, err = tk.EncodeSingle("lowest newer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tk.GetModel().Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	m, err := bpe.NewBpeFromFiles(filepath.Join(dir, "vocab.json"), filepath.Join(dir, "merges.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := tokenizer.NewTokenizer(m)
+	reloaded.WithPreTokenizer(pretokenizer.NewWhitespace())
+	got, err := reloaded.EncodeSingle("lowest newer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(en.Ids, got.Ids) {
+		t.Errorf("want %v, got %v", en.Ids, got.Ids)
+	}
+
+	if err := tk.Train(trainer, []string{filepath.Join(dir, "missing.txt")}); err == nil {
+		t.Errorf("want error for a missing file, got nil")
+	}
+
+	// A vocab smaller than the alphabet of the corpus is an error, not a panic.
+	small := unigram.NewUnigramTrainerBuilder().ShowProgress(false).VocabSize(3).Build()
+	before := tk.GetModel()
+	if err := tk.Train(small, files); err == nil {
+		t.Errorf("want error for a vocab smaller than the alphabet, got nil")
+	}
+	if tk.GetModel() != before {
+		t.Errorf("want the model unchanged after a failed training")
+	}
+}
+
+func TestEncode_OffsetType(t *testing.T) {
+	tk := getOfflineByteLevelBPE()
+	input := tokenizer.NewDualEncodeInput(tokenizer.NewInputSequence("a🚀b"), tokenizer.NewInputSequence("é!"))
+
+	// One token per byte: "a", 4 bytes of "🚀", "b", then 2 bytes of "é" and "!".
+	byteOffsets := [][]int{{0, 1}, {1, 5}, {1, 5}, {1, 5}, {1, 5}, {5, 6}, {0, 2}, {0, 2}, {2, 3}}
+	utf16Offsets := [][]int{{0, 1}, {1, 3}, {1, 3}, {1, 3}, {1, 3}, {3, 4}, {0, 1}, {0, 1}, {1, 2}}
+	charOffsets := [][]int{{0, 1}, {1, 2}, {1, 2}, {1, 2}, {1, 2}, {2, 3}, {0, 1}, {0, 1}, {1, 2}}
+
+	en, err := tk.Encode(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(byteOffsets, en.Offsets) {
+		t.Errorf("want byte offsets %v, got %v", byteOffsets, en.Offsets)
+	}
+
+	tk.WithOffsetType(tokenizer.UTF16)
+	en, err = tk.Encode(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(utf16Offsets, en.Offsets) {
+		t.Errorf("want utf-16 offsets %v, got %v", utf16Offsets, en.Offsets)
+	}
+
+	// The per-call option overrides the tokenizer unit.
+	en, err = tk.Encode(input, false, tokenizer.WithOffsetTypeEncodeOpt(tokenizer.Char))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(charOffsets, en.Offsets) {
+		t.Errorf("want char offsets %v, got %v", charOffsets, en.Offsets)
+	}
+
+	// And back to bytes.
+	en, err = tk.EncodeSingle("a🚀b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := en.ConvertOffsetsFrom(tokenizer.UTF16, tokenizer.Byte, "a🚀b"); err != nil {
+		t.Fatal(err)
+	}
+	if want := byteOffsets[:6]; !reflect.DeepEqual(want, en.Offsets) {
+		t.Errorf("want converted byte offsets %v, got %v", want, en.Offsets)
+	}
+}
+
+func TestTokenizer_EditModelVocab(t *testing.T) {
+	tk := getWordLevelBert(t)
+	tk.AddTokens([]tokenizer.AddedToken{tokenizer.NewAddedToken("<new>", false), tokenizer.NewAddedToken("hello", false)})
+	if id, _ := tk.TokenToId("<new>"); id != 29 {
+		t.Fatalf("want added id 29, got %d", id)
+	}
+
+	// Pads take the ids of the added tokens, which get the next free ids, but
+	// a token added to the model takes its model id.
+	err := tk.EditModelVocab(func(v tokenizer.VocabEditor) error {
+		v.AddToken("hello")
+		return v.Resize(32, "<pad_%d>")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for tok, want := range map[string]int{"hello": 29, "<pad_31>": 31, "<new>": 32} {
+		if id, ok := tk.TokenToId(tok); !ok || id != want {
+			t.Errorf("%s: want id %d, got %d", tok, want, id)
+		}
+	}
+
+	en, err := tk.EncodeSingle("a <new> hello", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{3, 32, 29}; !reflect.DeepEqual(want, en.Ids) {
+		t.Errorf("want ids %v, got %v", want, en.Ids)
+	}
+	if got := tk.Decode(en.Ids, false); got != "a <new> hello" {
+		t.Errorf("want decoded %q, got %q", "a <new> hello", got)
+	}
+
+	if err := tk.EditModelVocab(func(v tokenizer.VocabEditor) error {
+		return v.Resize(1, "<pad_%d>")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := tk.TokenToId("hello"); id != 33 {
+		t.Errorf("want token removed from the model to get id 33, got %d", id)
+	}
+}
+
+func TestEncodeWords(t *testing.T) {
+	tk := getWordLevelBert(t)
+	tk.WithCache(tokenizer.NewLRUCache(16))
+
+	tests := []struct {
+		name    string
+		encode  func() (*tokenizer.Encoding, error)
+		tokens  []string
+		words   []int
+		offsets [][]int
+		seqIds  []int
+	}{
+		{
+			"single", func() (*tokenizer.Encoding, error) { return tk.EncodeWords([]string{"a", "b c", "", "d"}, true) },
+			[]string{"[CLS]", "a", "b", "c", "d", "[SEP]"},
+			[]int{-1, 0, 1, 1, 3, -1},
+			[][]int{{0, 0}, {0, 1}, {0, 1}, {2, 3}, {0, 1}, {0, 0}},
+			[]int{-1, 0, 0, 0, 0, -1},
+		},
+		{
+			"raw", func() (*tokenizer.Encoding, error) { return tk.EncodeSingle("b c", true) },
+			[]string{"[CLS]", "b", "c", "[SEP]"},
+			[]int{-1, 0, 1, -1},
+			[][]int{{0, 0}, {0, 1}, {2, 3}, {0, 0}},
+			[]int{-1, 0, 0, -1},
+		},
+		{
+			"words of raw", func() (*tokenizer.Encoding, error) { return tk.EncodeWords([]string{"b c"}, true) },
+			[]string{"[CLS]", "b", "c", "[SEP]"},
+			[]int{-1, 0, 0, -1},
+			[][]int{{0, 0}, {0, 1}, {2, 3}, {0, 0}},
+			[]int{-1, 0, 0, -1},
+		},
+		{
+			"pair", func() (*tokenizer.Encoding, error) {
+				return tk.EncodeWordsPair([]string{"a"}, []string{"x", "y"}, true)
+			},
+			[]string{"[CLS]", "a", "[SEP]", "x", "y", "[SEP]"},
+			[]int{-1, 0, -1, 0, 1, -1},
+			[][]int{{0, 0}, {0, 1}, {0, 0}, {0, 1}, {0, 1}, {0, 0}},
+			[]int{-1, 0, -1, 1, 1, -1},
+		},
+	}
+
+	for _, tt := range tests {
+		en, err := tt.encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.tokens, en.Tokens) {
+			t.Errorf("%s: want tokens %q, got %q", tt.name, tt.tokens, en.Tokens)
+		}
+		if !reflect.DeepEqual(tt.words, en.GetWords()) {
+			t.Errorf("%s: want words %v, got %v", tt.name, tt.words, en.GetWords())
+		}
+		if !reflect.DeepEqual(tt.offsets, en.GetOffsets()) {
+			t.Errorf("%s: want offsets %v, got %v", tt.name, tt.offsets, en.GetOffsets())
+		}
+		if got := en.GetSequenceIds(); !reflect.DeepEqual(tt.seqIds, got) {
+			t.Errorf("%s: want sequence ids %v, got %v", tt.name, tt.seqIds, got)
+		}
+	}
+}
+
+func TestEncodePair_SequenceIds(t *testing.T) {
+	tk := getWordLevelBert(t)
+
+	tests := []struct {
+		name      string
+		processor tokenizer.PostProcessor
+		tokens    []string
+		typeIds   []int
+		seqIds    []int
+	}{
+		{
+			"bert", processor.NewBertProcessing(processor.PostToken{Value: "[SEP]", Id: 2}, processor.PostToken{Value: "[CLS]", Id: 1}),
+			[]string{"[CLS]", "a", "b", "[SEP]", "x", "[SEP]"},
+			[]int{0, 0, 0, 0, 1, 1},
+			[]int{-1, 0, 0, -1, 1, -1},
+		},
+		{
+			"none", nil,
+			[]string{"a", "b", "x"},
+			[]int{0, 0, 1},
+			[]int{0, 0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		tk.WithPostProcessor(tt.processor)
+		en, err := tk.EncodePair("a b", "x", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tt.tokens, en.Tokens) {
+			t.Errorf("%s: want tokens %q, got %q", tt.name, tt.tokens, en.Tokens)
+		}
+		if !reflect.DeepEqual(tt.typeIds, en.TypeIds) {
+			t.Errorf("%s: want type ids %v, got %v", tt.name, tt.typeIds, en.TypeIds)
+		}
+		if got := en.GetSequenceIds(); !reflect.DeepEqual(tt.seqIds, got) {
+			t.Errorf("%s: want sequence ids %v, got %v", tt.name, tt.seqIds, got)
+		}
+	}
+
+	// Overflowing encodings of both sequences keep their sequence ids.
+	tk.WithPostProcessor(nil)
+	tk.WithTruncation(&tokenizer.TruncationParams{MaxLength: 3, Strategy: tokenizer.LongestFirst})
+	en, err := tk.EncodePair("a b c", "x y", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(en.Overflowing) == 0 {
+		t.Fatal("want overflowing encodings, got none")
+	}
+	for _, o := range append([]tokenizer.Encoding{*en}, en.Overflowing...) {
+		for i, seqId := range o.GetSequenceIds() {
+			if want := o.TypeIds[i]; seqId != want {
+				t.Errorf("%q: want sequence id %d of token %d, got %d", o.Tokens, want, i, seqId)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// TokenFrequency is the number of occurrences of a vocab token in the training
+// corpus segmented by the trained model.
+type TokenFrequency struct {
+	Token     string `json:"token"`
+	Frequency int    `json:"frequency"`
+}
+
+// MergeCount records a merge selected while training along with the count of
+// its pair at selection time.
+type MergeCount struct {
+	Pair  [2]string `json:"pair"`
+	Token string    `json:"token"`
+	Count int       `json:"count"`
+}
+
+// CoveragePoint is a point of the coverage curve: the `Tokens` most frequent
+// tokens account for `Coverage` (0-1) of all token occurrences in the corpus.
+type CoveragePoint struct {
+	Tokens   int     `json:"tokens"`
+	Coverage float64 `json:"coverage"`
+}
+
+// TrainingReport holds the statistics collected by a trainer while training so
+// that the resulting vocabulary can be audited.
+type TrainingReport struct {
+	// Total number of token occurrences in the segmented corpus.
+	TotalFrequency int `json:"total_frequency"`
+	// All vocab tokens sorted by descending frequency.
+	TokenFrequencies []TokenFrequency `json:"token_frequencies"`
+	// Merges in selection order. Empty for trainers not merging pairs.
+	Merges []MergeCount `json:"merges"`
+	// Coverage curve, one point per token of `TokenFrequencies`.
+	Coverage []CoveragePoint `json:"coverage"`
+}
+
+// NewTrainingReport creates a TrainingReport from the final frequency of each
+// token of the vocab, `freqs[i]` being the frequency of `vocab[i]`.
+func NewTrainingReport(vocab []string, freqs []int, merges []MergeCount) *TrainingReport {
+	r := &TrainingReport{
+		TokenFrequencies: make([]TokenFrequency, len(vocab)),
+		Merges:           merges,
+		Coverage:         make([]CoveragePoint, len(vocab)),
+	}
+	if r.Merges == nil {
+		r.Merges = []MergeCount{}
+	}
+
+	for i, tok := range vocab {
+		var freq int
+		if i < len(freqs) {
+			freq = freqs[i]
+		}
+		r.TokenFrequencies[i] = TokenFrequency{tok, freq}
+		r.TotalFrequency += freq
+	}
+
+	// Keep vocab order between tokens having the same frequency for determinism.
+	sort.SliceStable(r.TokenFrequencies, func(i, j int) bool {
+		return r.TokenFrequencies[i].Frequency > r.TokenFrequencies[j].Frequency
+	})
+
+	var cumulative int
+	for i, tf := range r.TokenFrequencies {
+		cumulative += tf.Frequency
+		var coverage float64
+		if r.TotalFrequency > 0 {
+			coverage = float64(cumulative) / float64(r.TotalFrequency)
+		}
+		r.Coverage[i] = CoveragePoint{i + 1, coverage}
+	}
+
+	return r
+}
+
+// ToJSON serializes the report to JSON.
+func (r *TrainingReport) ToJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
@@ -2,21 +2,22 @@ package tokenizer
 
 import (
 	"errors"
-	"log"
+	"fmt"
 )
 
 type TruncationParams struct {
-	MaxLength int
-	Strategy  TruncationStrategy
-	Stride    int
+	MaxLength int                // max number of tokens, special tokens included. 0 means no truncation
+	Strategy  TruncationStrategy // which sequence of a pair is truncated
+	Stride    int                // number of tokens repeated from the previous part in overflowing encodings
 }
 
 type PaddingParams struct {
-	Strategy  PaddingStrategy
-	Direction PaddingDirection
-	PadId     int
-	PadTypeId int
-	PadToken  string
+	Strategy        PaddingStrategy
+	Direction       PaddingDirection
+	PadToMultipleOf int // if > 0, the padding length is rounded up to a multiple of it
+	PadId           int
+	PadTypeId       int
+	PadToken        string
 }
 
 // PaddingStrategy is a enum of either
@@ -80,77 +81,110 @@ const (
 	SequenceTooShort          = "Truncation error: Sequence to truncate too short to respect the provided max_length"
 )
 
-func TruncateEncodings(encoding, pairEncoding *Encoding, params *TruncationParams) (tEncoding, tPairEncoding *Encoding) {
-	var (
-		totalLength int
-		toRemove    int
-		err         error
-	)
+// Validate checks the truncation params: lengths must not be negative and the
+// stride must be less than the max length.
+func (tp *TruncationParams) Validate() error {
+	switch {
+	case tp.MaxLength < 0:
+		return fmt.Errorf("Truncation error: invalid max length %d", tp.MaxLength)
+	case tp.Stride < 0:
+		return fmt.Errorf("Truncation error: invalid stride %d", tp.Stride)
+	case tp.MaxLength > 0 && tp.Stride >= tp.MaxLength:
+		return fmt.Errorf("Truncation error: stride %d must be less than max length %d", tp.Stride, tp.MaxLength)
+	}
+
+	switch tp.Strategy {
+	case LongestFirst, OnlyFirst, OnlySecond:
+		return nil
+	default:
+		return fmt.Errorf("Truncation error: unsupported strategy %d", tp.Strategy)
+	}
+}
 
+// TruncateEncodings truncates the encodings so that their total length is at
+// most `params.MaxLength` (0 means no truncation), following
+// `params.Strategy`. The removed tokens are kept in the `Overflowing`
+// encodings of the truncated encodings, overlapping by `params.Stride` tokens.
+func TruncateEncodings(encoding, pairEncoding *Encoding, params *TruncationParams) (tEncoding, tPairEncoding *Encoding, err error) {
 	if params.MaxLength == 0 {
-		return encoding, pairEncoding
+		return encoding, pairEncoding, nil
 	}
 
-	totalLength = len(encoding.GetIds())
+	totalLength := encoding.Len()
 	if pairEncoding != nil {
-		totalLength = len(encoding.GetIds()) + len(pairEncoding.GetIds())
+		totalLength += pairEncoding.Len()
 	}
-
-	if totalLength < params.MaxLength {
-		return encoding, pairEncoding
+	if totalLength <= params.MaxLength {
+		return encoding, pairEncoding, nil
 	}
 
-	toRemove = totalLength - params.MaxLength
+	toRemove := totalLength - params.MaxLength
 
 	switch params.Strategy {
 	case LongestFirst:
-		nFirst := len(encoding.GetIds())
+		nFirst := encoding.Len()
 		nSecond := 0
 		if pairEncoding != nil {
-			nSecond = len(pairEncoding.GetIds())
+			nSecond = pairEncoding.Len()
 		}
 
+		// Remove tokens from the longest sequence, the second one on ties.
 		for i := 0; i < toRemove; i++ {
 			if nFirst > nSecond {
 				nFirst -= 1
+			} else {
+				nSecond -= 1
 			}
-			nSecond -= 1
 		}
 
-		encoding.Truncate(nFirst, params.Stride)
+		if err := truncateEncoding(encoding, nFirst, params.Stride); err != nil {
+			return nil, nil, err
+		}
 		if pairEncoding != nil {
-			pairEncoding.Truncate(nSecond, params.Stride)
+			if err := truncateEncoding(pairEncoding, nSecond, params.Stride); err != nil {
+				return nil, nil, err
+			}
 		}
 
 	case OnlyFirst, OnlySecond:
-		var truncateFunc = func(target *Encoding) (*Encoding, error) {
-			targetLength := len(target.GetIds())
-			if targetLength > toRemove {
-				target.Truncate(targetLength-toRemove, params.Stride)
-				return target, nil
-			} else {
-				err := errors.New(SequenceTooShort)
-				return nil, err
+		target := encoding
+		if params.Strategy == OnlySecond {
+			if pairEncoding == nil {
+				return nil, nil, errors.New(SecondSequenceNotProvided)
 			}
+			target = pairEncoding
 		}
 
-		if params.Strategy == OnlyFirst {
-			encoding, err = truncateFunc(encoding)
-		} else if pairEncoding != nil {
-			pairEncoding, err = truncateFunc(pairEncoding)
-		} else {
-			err = errors.New(SecondSequenceNotProvided)
+		if target.Len() <= toRemove {
+			return nil, nil, fmt.Errorf("%s: %w", SequenceTooShort, ErrTruncationNeeded)
+		}
+		if err := truncateEncoding(target, target.Len()-toRemove, params.Stride); err != nil {
+			return nil, nil, err
 		}
 
+	default:
+		return nil, nil, fmt.Errorf("Truncation error: unsupported strategy %d", params.Strategy)
 	}
 
-	if err != nil {
-		log.Fatal(err)
+	return encoding, pairEncoding, nil
+}
+
+// truncateEncoding truncates e to maxLen tokens if it is longer.
+func truncateEncoding(e *Encoding, maxLen, stride int) error {
+	if e.Len() <= maxLen {
+		return nil
+	}
+	if _, err := e.Truncate(maxLen, stride); err != nil {
+		return fmt.Errorf("Truncation error: %w", err)
 	}
 
-	return encoding, pairEncoding
+	return nil
 }
 
+// PadEncodings pads the encodings, and their overflowing encodings, to the
+// length given by the padding strategy: the longest encoding of the batch or a
+// fixed length, rounded up to `PadToMultipleOf`. Encodings already longer are
+// left as they are.
 func PadEncodings(encodings []Encoding, params PaddingParams) []Encoding {
 	if len(encodings) == 0 {
 		return encodings
@@ -171,12 +205,14 @@ func PadEncodings(encodings []Encoding, params PaddingParams) []Encoding {
 		padLength = max
 	}
 
-	// TODO: implement concurrency with for loop
-	var newEncodings []Encoding
-	for _, e := range encodings {
+	if m := params.PadToMultipleOf; m > 0 && padLength%m != 0 {
+		padLength += m - padLength%m
+	}
+
+	newEncodings := make([]Encoding, len(encodings))
+	for i, e := range encodings {
 		en := e
-		paddedEn := en.Pad(padLength, params.PadId, params.PadTypeId, params.PadToken, params.Direction)
-		newEncodings = append(newEncodings, *paddedEn)
+		newEncodings[i] = *en.Pad(padLength, params.PadId, params.PadTypeId, params.PadToken, params.Direction)
 	}
 
 	return newEncodings
@@ -3,7 +3,6 @@ package util
 import (
 	"errors"
 	"fmt"
-	"log"
 	"runtime"
 	"strings"
 )
@@ -48,13 +47,13 @@ func TraceError(err error) error {
 	return nil
 }
 
-// LogError logs error with the function name as well.
+// LogError logs error with the function name as well, see SetLogger.
 func LogError(err error) {
 	if err != nil {
 		// notice that we're using 1, so it will actually log the where
 		// the error happened, 0 = this function, we don't want that.
 		pc, fn, line, _ := runtime.Caller(1)
 
-		log.Printf("[error] in %s \n[%s:%d] \n%v", runtime.FuncForPC(pc).Name(), fn, line, err)
+		Logf("[error] in %s \n[%s:%d] \n%v", runtime.FuncForPC(pc).Name(), fn, line, err)
 	}
 }
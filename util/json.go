@@ -0,0 +1,56 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MarshalJSON returns the JSON encoding of v as json.Marshal does, except that
+// `<`, `>` and `&` are not escaped so that tokens like `<s>` stay readable.
+func MarshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// OrderedMap is a JSON object whose keys are encoded in order.
+type OrderedMap struct {
+	Keys   []string
+	Values []interface{}
+}
+
+// Set appends the key and its value to the map.
+func (m *OrderedMap) Set(key string, value interface{}) {
+	m.Keys = append(m.Keys, key)
+	m.Values = append(m.Values, value)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.Keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := MarshalJSON(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := MarshalJSON(m.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"sync/atomic"
+)
+
+// Logger logs the informational messages of the library, i.e. a `*log.Logger`.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// loggerBox holds a Logger so that loggers of different types can be stored in
+// the same atomic.Value.
+type loggerBox struct {
+	Logger
+}
+
+var logger atomic.Value // loggerBox
+
+// SetLogger sets the logger of the library, nil to discard messages, which is
+// the default.
+func SetLogger(l Logger) {
+	logger.Store(loggerBox{l})
+}
+
+// Logf logs a message to the logger set by SetLogger, if any.
+func Logf(format string, v ...interface{}) {
+	if box, ok := logger.Load().(loggerBox); ok && box.Logger != nil {
+		box.Printf(format, v...)
+	}
+}
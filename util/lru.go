@@ -0,0 +1,90 @@
+package util
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRU is a size-capped, concurrent-safe cache evicting the least recently
+// used entry when full.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[K]*list.Element
+	order    *list.List // front is most recently used
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// NewLRU creates a new LRU cache holding at most `capacity` entries.
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	return &LRU[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// Get returns the value for key if existing and marks it as recently used.
+func (c *LRU[K, V]) Get(key K) (retVal V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return retVal, false
+	}
+	c.order.MoveToFront(el)
+
+	return el.Value.(*lruEntry[K, V]).value, true
+}
+
+// Add inserts or updates the value for key, evicting the least recently used
+// entry if the cache is full.
+func (c *LRU[K, V]) Add(key K, value V) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[K, V]).key)
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key, value})
+}
+
+// Clear removes all entries.
+func (c *LRU[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[K]*list.Element, c.capacity)
+	c.order.Init()
+}
+
+// Len returns number of cached entries.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// Capacity returns maximum number of entries.
+func (c *LRU[K, V]) Capacity() int {
+	return c.capacity
+}
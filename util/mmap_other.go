@@ -0,0 +1,18 @@
+//go:build !unix
+
+package util
+
+import (
+	"os"
+)
+
+// MapFile reads the file into memory, as memory mapping is not supported on
+// this platform.
+func MapFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if len(data) == 0 {
+		data = nil
+	}
+
+	return data, err
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// lockedSource is a rand.Source safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+// NewLockedSource wraps src into a rand.Source safe for concurrent use, as the
+// sources of math/rand are not. It returns nil if src is nil.
+func NewLockedSource(src rand.Source) rand.Source {
+	switch src.(type) {
+	case nil, *lockedSource:
+		return src
+	}
+
+	return &lockedSource{src: src}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.src.Seed(seed)
+}
+
+// Float32 returns a number in [0, 1) drawn from src, or from the shared source
+// of math/rand if src is nil.
+func Float32(src rand.Source) float32 {
+	if src == nil {
+		return rand.Float32()
+	}
+
+	// The 24 high bits of the 63 random bits, as many as a float32 mantissa.
+	return float32(src.Int63()>>39) / (1 << 24)
+}